@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/dekarrin/morc"
@@ -16,11 +17,12 @@ var capsCmd = &cobra.Command{
 	Annotations: map[string]string{
 		annotationKeyHelpUsages: "" +
 			"caps REQ\n" +
+			"caps REQ --validate-all\n" +
 			"caps REQ --delete VAR\n" +
-			"caps REQ --new VAR -s SPEC\n" +
+			"caps REQ --new VAR -s SPEC [--transform T] [--default-value VAL] [--env ENV | --default]\n" +
 			"caps REQ VAR\n" +
 			"caps REQ VAR --get ATTR\n" +
-			"caps REQ VAR [-sV]",
+			"caps REQ VAR [-sV] [--transform T] [--default-value VAL | --no-default-value] [--env ENV | --default | --current]",
 	},
 	GroupID: projMetaCommands.ID,
 	Short:   "Get or modify variable captures on a request template.",
@@ -34,13 +36,40 @@ var capsCmd = &cobra.Command{
 		"attribute to view. The available names are: " + strings.Join(capAttrKeyNames(), ", ") + ".\n\n" +
 		"To modify a capture, use one of the -s or -V flags when giving the VAR of the capture; -s will alter the spec, " +
 		"and -V will change the captured-to variable.\n\n" +
+		"A captured value can be transformed before it is stored by giving --transform with the name of the " +
+		"transform when creating or modifying the capture. The available transforms are: " + strings.Join(captureTransformNames(), ", ") +
+		". Give --transform none to remove the transform from a capture.\n\n" +
+		"Normally, if a capture cannot find its value in a response, such as when a key in its path is missing, the " +
+		"send fails. To instead store a fallback value when that happens, give --default-value with the value to " +
+		"store; it may be the empty string. Give --no-default-value to remove it so that failing to capture is an " +
+		"error again.\n\n" +
+		"By default, a captured value is stored in whichever variable environment is current when the request is " +
+		"sent. To instead always store it in a particular environment, give --env with the name of the environment " +
+		"when creating or modifying the capture, or give --default to store it in the default environment, where it " +
+		"is used by every environment that does not define the variable itself. Give --current to go back to " +
+		"storing it in the current environment. If the environment does not exist when a value is captured, it is " +
+		"created.\n\n" +
 		"A capture is removed from a request by providing --delete and the VAR of the capture to be deleted.\n\n" +
+		"To check that every capture on a request is still valid, such as after the project file was edited by " +
+		"hand, give --validate-all with only REQ. Each invalid capture is listed along with the reason it is " +
+		"invalid, and the command fails if any were found.\n\n" +
+		"Anywhere that the VAR of an existing capture is given, its number from the listing of captures may be given " +
+		"instead. Captures are listed and numbered in alphabetical order of VAR starting from 0, so the number of a " +
+		"capture can shift when captures are added, removed, or renamed. If a capture exists whose VAR is exactly " +
+		"the given number, that capture is used instead.\n\n" +
 		"Capture specifications can be given in one of three formats. They can be in format ':START,END' for a byte " +
 		"offset (ex: \":4,20\"). In this format, either START or END may be omitted to indicate '0'; if 0 is used for " +
-		"END, it includes all bytes from START to end of the response, and if a negative number is used for END, it " +
-		"refers to that many bytes from the end of the response. Alternatively, the keyword format 'raw' may be " +
+		"END, it includes all bytes from START to end of the response, and if a negative number is used for START or " +
+		"END, it refers to that many bytes from the end of the response (ex: \":,-1\" for all but the last byte). " +
+		"Once resolved against the response, END must be after START. To count UTF-8 characters instead of bytes so " +
+		"that multi-byte characters are never split, use format ':rSTART,END' (ex: \":r4,20\"), which is listed as a " +
+		"rune offset. Alternatively, the keyword format 'raw' may be " +
 		"used as shorthand for :0,0, and will capture the entire response body. Finally, the spec may be a jq-ish path " +
-		"with only keys and array indexes (ex: \".records[1].auth.token\"); this must start with a . character.",
+		"with only keys and array indexes (ex: \".records[1].auth.token\"); this must start with a . character.\n\n" +
+		"Multiple specs can be joined with '||' to give alternatives for responses whose shape varies (ex: " +
+		"\".data.id || .result.id || .id\"). Each is tried in order and the value from the first one that succeeds " +
+		"is captured. A path fails if any key or index along it does not exist. If all of them fail, the error from " +
+		"the last one is reported.",
 	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, posArgs []string) error {
 		var args capsArgs
@@ -66,6 +95,8 @@ var capsCmd = &cobra.Command{
 			return invokeCapsGet(io, args.projFile, args.request, args.capture, args.getItem)
 		case capsActionEdit:
 			return invokeCapsEdit(io, args.projFile, args.request, args.capture, args.sets)
+		case capsActionValidate:
+			return invokeCapsValidate(io, args.projFile, args.request)
 		default:
 			return fmt.Errorf("unknown action %d", args.action)
 		}
@@ -77,17 +108,34 @@ func init() {
 	capsCmd.PersistentFlags().StringVarP(&flags.New, "new", "N", "", "Create a new capture on REQ that saves captured data to `VAR`. If given, the specification of the new capture must also be given with --spec/-s.")
 	capsCmd.PersistentFlags().StringVarP(&flags.Delete, "delete", "D", "", "Delete the given variable capture `VAR` from the request.")
 	capsCmd.PersistentFlags().StringVarP(&flags.Get, "get", "G", "", "Get the value of a specific attribute `ATTR` of the capture. Can only be used if giving REQ and CAP and no other arguments.")
-	capsCmd.PersistentFlags().StringVarP(&flags.Spec, "spec", "s", "", "Specify where in responses that data should be captured from. `SPEC` is a specially-formatted string of form :FROM,TO to specify a byte-offset or a jq-ish syntax string to specify a path to a value within a JSON response body.")
+	capsCmd.PersistentFlags().StringVarP(&flags.Spec, "spec", "s", "", "Specify where in responses that data should be captured from. `SPEC` is a specially-formatted string of form :FROM,TO to specify a byte-offset, :rFROM,TO to specify a UTF-8 character offset, or a jq-ish syntax string to specify a path to a value within a JSON response body.")
 	capsCmd.PersistentFlags().StringVarP(&flags.VarName, "var", "V", "", "Set the variable that the capture saves to to `VAR`.")
+	capsCmd.PersistentFlags().StringVarP(&flags.Transform, "transform", "", "", "Apply transform `T` to captured values before they are stored. T must be one of "+strings.Join(captureTransformNames(), ", ")+", or none to store values as captured.")
+	capsCmd.PersistentFlags().StringVarP(&flags.DefaultValue, "default-value", "", "", "Store `VAL` in the variable instead of failing when the capture cannot find its value in a response.")
+	capsCmd.PersistentFlags().BoolVarP(&flags.BNoDefaultValue, "no-default-value", "", false, "Remove the default value of the capture so that failing to find its value is an error.")
+	capsCmd.PersistentFlags().StringVarP(&flags.Env, "env", "e", "", "Store captured values in environment `ENV` instead of the current one.")
+	capsCmd.PersistentFlags().BoolVarP(&flags.BDefault, "default", "", false, "Store captured values in the default environment instead of the current one.")
+	capsCmd.PersistentFlags().BoolVarP(&flags.BCurrent, "current", "", false, "Store captured values in whichever environment is current when they are captured. This is the default for new captures.")
+	capsCmd.PersistentFlags().BoolVarP(&flags.BValidateAll, "validate-all", "", false, "Check that every capture on REQ has a valid name and spec and report any that do not.")
 	capsCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
 
 	// cannot delete while doing new
-	capsCmd.MarkFlagsMutuallyExclusive("new", "delete", "get")
+	capsCmd.MarkFlagsMutuallyExclusive("new", "delete", "get", "validate-all")
+	capsCmd.MarkFlagsMutuallyExclusive("validate-all", "spec")
+	capsCmd.MarkFlagsMutuallyExclusive("validate-all", "var")
 	capsCmd.MarkFlagsMutuallyExclusive("delete", "spec")
 	capsCmd.MarkFlagsMutuallyExclusive("delete", "var")
 	capsCmd.MarkFlagsMutuallyExclusive("get", "spec")
 	capsCmd.MarkFlagsMutuallyExclusive("get", "var")
 	capsCmd.MarkFlagsMutuallyExclusive("new", "var")
+	capsCmd.MarkFlagsMutuallyExclusive("validate-all", "transform", "delete", "get")
+	capsCmd.MarkFlagsMutuallyExclusive("validate-all", "default-value", "delete", "get")
+	capsCmd.MarkFlagsMutuallyExclusive("validate-all", "no-default-value", "delete", "get", "new")
+	capsCmd.MarkFlagsMutuallyExclusive("default-value", "no-default-value")
+	capsCmd.MarkFlagsMutuallyExclusive("env", "default", "current")
+	capsCmd.MarkFlagsMutuallyExclusive("validate-all", "env", "delete", "get")
+	capsCmd.MarkFlagsMutuallyExclusive("validate-all", "default", "delete", "get")
+	capsCmd.MarkFlagsMutuallyExclusive("validate-all", "current", "delete", "get")
 
 	rootCmd.AddCommand(capsCmd)
 }
@@ -107,7 +155,7 @@ func invokeCapsDelete(io cmdio.IO, projFile string, reqName, varName string) err
 	}
 
 	// var name normalized to upper case
-	varUpper := strings.ToUpper(varName)
+	varUpper := resolveCapRef(req, varName)
 	if len(req.Captures) == 0 {
 		// TODO: standardize "not-found" error messages
 		return fmt.Errorf("no capture defined for %s%s in %s", p.VarPrefix(), varUpper, reqName)
@@ -146,7 +194,7 @@ func invokeCapsEdit(io cmdio.IO, projFile, reqName, varName string, attrs capAtt
 	}
 
 	// case doesn't matter for var names
-	varUpper := strings.ToUpper(varName)
+	varUpper := resolveCapRef(req, varName)
 	if len(req.Captures) == 0 {
 		return fmt.Errorf("no capture to variable %s%s exists in request %s", p.VarPrefix(), varUpper, reqName)
 	}
@@ -156,7 +204,7 @@ func invokeCapsEdit(io cmdio.IO, projFile, reqName, varName string, attrs capAtt
 	}
 
 	// okay did the user actually ask to change somefin
-	if !attrs.capVar.set && !attrs.spec.set {
+	if !attrs.capVar.set && !attrs.spec.set && !attrs.transform.set && !attrs.defaultValue.set && !attrs.env.set {
 		return fmt.Errorf("no changes requested")
 	}
 
@@ -190,15 +238,48 @@ func invokeCapsEdit(io cmdio.IO, projFile, reqName, varName string, attrs capAtt
 	// if we have a spec change, apply that next
 	if attrs.spec.set {
 		if !cap.EqualSpec(attrs.spec.v) {
-			existingName := cap.Name
+			existing := cap
 			cap = attrs.spec.v
-			cap.Name = existingName
+			cap.Name = existing.Name
+			cap.Env = existing.Env
+			cap.Transform = existing.Transform
+			cap.Default = existing.Default
 			modifiedVals[capKeySpec] = attrs.spec.v.Spec()
 		} else {
 			noChangeVals[capKeySpec] = cap.Spec()
 		}
 	}
 
+	// then the transform
+	if attrs.transform.set {
+		if cap.Transform != attrs.transform.v {
+			cap.Transform = attrs.transform.v
+			modifiedVals[capKeyTransform] = capTransformName(cap.Transform)
+		} else {
+			noChangeVals[capKeyTransform] = capTransformName(cap.Transform)
+		}
+	}
+
+	// then the default value
+	if attrs.defaultValue.set {
+		if !equalDefaultValues(cap.Default, attrs.defaultValue.v) {
+			cap.Default = attrs.defaultValue.v
+			modifiedVals[capKeyDefaultValue] = capDefaultValueName(cap.Default)
+		} else {
+			noChangeVals[capKeyDefaultValue] = capDefaultValueName(cap.Default)
+		}
+	}
+
+	// and finally, the env
+	if attrs.env.set {
+		if cap.Env != attrs.env.v {
+			cap.Env = attrs.env.v
+			modifiedVals[capKeyEnv] = capEnvName(cap.Env)
+		} else {
+			noChangeVals[capKeyEnv] = capEnvName(cap.Env)
+		}
+	}
+
 	// update the request
 	req.Captures[strings.ToUpper(cap.Name)] = cap
 	p.Templates[reqName] = req
@@ -209,6 +290,7 @@ func invokeCapsEdit(io cmdio.IO, projFile, reqName, varName string, attrs capAtt
 		return err
 	}
 
+	warnIfCapEnvMissing(io, p, cap)
 	cmdio.OutputLoudEditAttrsResult(io, modifiedVals, noChangeVals, capAttrKeys)
 
 	return nil
@@ -228,7 +310,7 @@ func invokeCapsGet(io cmdio.IO, projFile, reqName, capName string, getItem capKe
 		return fmt.Errorf("no request template %s", reqName)
 	}
 
-	capName = strings.ToUpper(capName)
+	capName = resolveCapRef(req, capName)
 	cap, ok := req.Captures[capName]
 	if !ok {
 		return fmt.Errorf("no capture to %s%s exists on request template %s", p.VarPrefix(), capName, reqName)
@@ -239,6 +321,12 @@ func invokeCapsGet(io cmdio.IO, projFile, reqName, capName string, getItem capKe
 		io.Printf("%s\n", cap.Name)
 	case capKeySpec:
 		io.Printf("%s\n", cap.Spec())
+	case capKeyTransform:
+		io.Printf("%s\n", capTransformName(cap.Transform))
+	case capKeyDefaultValue:
+		io.Printf("%s\n", capDefaultValueName(cap.Default))
+	case capKeyEnv:
+		io.Printf("%s\n", capEnvName(cap.Env))
 	default:
 		return fmt.Errorf("unknown item %q", getItem)
 	}
@@ -277,6 +365,9 @@ func invokeCapsNew(io cmdio.IO, projFile, reqName, varName string, attrs capAttr
 
 	cap := attrs.spec.v
 	cap.Name = varUpper
+	cap.Transform = attrs.transform.v
+	cap.Default = attrs.defaultValue.v
+	cap.Env = attrs.env.v
 
 	// otherwise, we have a valid capture, so add it to the request.
 	if req.Captures == nil {
@@ -298,6 +389,7 @@ func invokeCapsNew(io cmdio.IO, projFile, reqName, varName string, attrs capAttr
 		scrapeSource = cap.Spec()
 	}
 
+	warnIfCapEnvMissing(io, p, cap)
 	io.PrintLoudf("Added capture from %s to %s%s on %s\n", scrapeSource, p.VarPrefix(), varUpper, reqName)
 
 	return nil
@@ -321,22 +413,56 @@ func invokeCapsList(io cmdio.IO, projFile string, reqName string) error {
 	if len(req.Captures) == 0 {
 		io.PrintLoudln("(none)")
 	} else {
-		// sort the output for consistency
-		sortedKeys := make([]string, 0, len(req.Captures))
-		for key := range req.Captures {
-			sortedKeys = append(sortedKeys, key)
+		for i, capName := range sortedCapNames(req) {
+			cap := req.Captures[capName]
+			io.Printf("%d: %s\n", i, cap)
 		}
-		sort.Strings(sortedKeys)
+	}
 
-		for _, capName := range sortedKeys {
-			cap := req.Captures[capName]
-			io.Printf("%s\n", cap)
+	return nil
+}
+
+func invokeCapsValidate(io cmdio.IO, projFile string, reqName string) error {
+	// load the project file
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	// case doesn't matter for request template names
+	reqName = strings.ToLower(reqName)
+
+	req, ok := p.Templates[reqName]
+	if !ok {
+		return fmt.Errorf("no request template %s", reqName)
+	}
+
+	var invalid int
+	for _, capName := range sortedCapNames(req) {
+		if err := checkCapture(capName, req.Captures[capName]); err != nil {
+			io.Printf("%s%s: %v\n", p.VarPrefix(), capName, err)
+			invalid++
 		}
 	}
 
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d captures on %s are invalid", invalid, len(req.Captures), reqName)
+	}
+
+	io.PrintLoudf("All %d captures on %s are valid\n", len(req.Captures), reqName)
+
 	return nil
 }
 
+// checkCapture returns an error describing what is wrong with cap if it is
+// invalid or if it is saved under a name other than the one it captures to.
+func checkCapture(capName string, cap morc.VarScraper) error {
+	if !strings.EqualFold(cap.Name, capName) {
+		return fmt.Errorf("saved under %s but captures to %s", capName, cap.Name)
+	}
+	return cap.Validate()
+}
+
 func invokeCapsShow(io cmdio.IO, projFile, reqName, capName string) error {
 	// load the project file
 	p, err := readProject(projFile, true)
@@ -351,7 +477,7 @@ func invokeCapsShow(io cmdio.IO, projFile, reqName, capName string) error {
 		return fmt.Errorf("no request template %s", reqName)
 	}
 
-	capName = strings.ToUpper(capName)
+	capName = resolveCapRef(req, capName)
 	cap, ok := req.Captures[capName]
 	if !ok {
 		return fmt.Errorf("no capture to %s%s exists on request template %s", p.VarPrefix(), capName, reqName)
@@ -362,6 +488,95 @@ func invokeCapsShow(io cmdio.IO, projFile, reqName, capName string) error {
 	return nil
 }
 
+// capTransformName returns the name of the transform applied by a capture, for
+// display.
+func capTransformName(ct morc.CaptureTransform) string {
+	if ct == morc.TransformNone {
+		return "(none)"
+	}
+	return string(ct)
+}
+
+// capDefaultValueName returns the default value of a capture, for display.
+func capDefaultValueName(def *string) string {
+	if def == nil {
+		return "(none)"
+	}
+	return fmt.Sprintf("%q", *def)
+}
+
+// equalDefaultValues returns whether two capture default values are the same,
+// including whether both are unset.
+func equalDefaultValues(def1, def2 *string) bool {
+	if def1 == nil || def2 == nil {
+		return def1 == nil && def2 == nil
+	}
+	return *def1 == *def2
+}
+
+// captureTransformNames returns the names of all transforms that can be given
+// to --transform, for use in help text.
+func captureTransformNames() []string {
+	var names []string
+	for _, ct := range morc.CaptureTransforms() {
+		names = append(names, string(ct))
+	}
+	return names
+}
+
+// capEnvName returns the name of the environment that a capture with the given
+// Env stores values in, for display.
+func capEnvName(env string) string {
+	if env == "" {
+		return "(current)"
+	}
+	return strings.ToUpper(env)
+}
+
+// warnIfCapEnvMissing warns that the environment cap stores values in will be
+// created when a value is first captured, if it does not yet exist in p.
+func warnIfCapEnvMissing(io cmdio.IO, p morc.Project, cap morc.VarScraper) {
+	if cap.Env == "" || cap.Env == morc.DefaultEnvName {
+		return
+	}
+	for _, name := range p.Vars.EnvNames() {
+		if name == strings.ToUpper(cap.Env) {
+			return
+		}
+	}
+	io.PrintErrf("WARN: environment %s does not exist; it will be created when a value is captured\n", strings.ToUpper(cap.Env))
+}
+
+// sortedCapNames returns the names of the captures in req in the order they are
+// listed and numbered in.
+func sortedCapNames(req morc.RequestTemplate) []string {
+	names := make([]string, 0, len(req.Captures))
+	for key := range req.Captures {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveCapRef gets the name of the capture in req referred to by ref, which is
+// either its var name or its number in the listing. If ref is both the name of
+// a capture and a valid number, the name takes precedence. If ref does not
+// refer to any capture, it is returned uppercased so that callers can report
+// it as not found.
+func resolveCapRef(req morc.RequestTemplate, ref string) string {
+	varUpper := strings.ToUpper(ref)
+	if _, ok := req.Captures[varUpper]; ok {
+		return varUpper
+	}
+
+	idx, err := strconv.Atoi(ref)
+	if err != nil || idx < 0 || idx >= len(req.Captures) {
+		return varUpper
+	}
+
+	return sortedCapNames(req)[idx]
+}
+
 type capsArgs struct {
 	projFile string
 	action   capsAction
@@ -374,6 +589,15 @@ type capsArgs struct {
 type capAttrValues struct {
 	capVar optional[string]
 	spec   optional[morc.VarScraper]
+
+	// transform is the Transform to set on the capture.
+	transform optional[morc.CaptureTransform]
+
+	// defaultValue is the Default to set on the capture; nil to remove it.
+	defaultValue optional[*string]
+
+	// env is the Env to set on the capture; "" for the current environment.
+	env optional[string]
 }
 
 func parseCapsArgs(cmd *cobra.Command, posArgs []string, args *capsArgs) error {
@@ -394,7 +618,7 @@ func parseCapsArgs(cmd *cobra.Command, posArgs []string, args *capsArgs) error {
 
 	// do action-specific arg and flag parsing
 	switch args.action {
-	case capsActionList:
+	case capsActionList, capsActionValidate:
 		// nothing else to do; all args already gathered
 	case capsActionShow:
 		// set arg 2 as the capture name
@@ -452,9 +676,16 @@ func parseCapsActionFromFlags(cmd *cobra.Command, posArgs []string) (capsAction,
 	// present.
 	// * mut-exc enforced by cobra: --get and setOpts will not both be set
 	// * mut-exc enforced by cobra: --new and --var setOpt will not be set
+	// * mut-exc enforced by cobra: --validate-all will not be present with
+	// --new, --delete, --get, or setOpts.
 	// * Min args 1.
 
-	if flags.Delete != "" {
+	if flags.BValidateAll {
+		if len(posArgs) > 1 {
+			return capsActionValidate, fmt.Errorf("unknown 2nd positional argument: %q", posArgs[1])
+		}
+		return capsActionValidate, nil
+	} else if flags.Delete != "" {
 		if len(posArgs) < 1 {
 			return capsActionDelete, fmt.Errorf("missing request REQ to delete capture from")
 		}
@@ -487,7 +718,7 @@ func parseCapsActionFromFlags(cmd *cobra.Command, posArgs []string) (capsAction,
 			return capsActionGet, fmt.Errorf("unknown 3rd positional argument: %q", posArgs[2])
 		}
 		return capsActionGet, nil
-	} else if capsSetFlagIsPresent() {
+	} else if capsSetFlagIsPresent(cmd) {
 		if len(posArgs) < 1 {
 			return capsActionEdit, fmt.Errorf("missing request REQ and capture VAR to edit")
 		}
@@ -529,11 +760,42 @@ func parseCapsSetFlags(cmd *cobra.Command, attrs *capAttrValues) error {
 		attrs.capVar = optional[string]{set: true, v: name}
 	}
 
+	if cmd.Flags().Lookup("transform").Changed {
+		ct, err := morc.ParseCaptureTransform(flags.Transform)
+		if err != nil {
+			return fmt.Errorf("--transform: %w", err)
+		}
+		attrs.transform = optional[morc.CaptureTransform]{set: true, v: ct}
+	}
+
+	if cmd.Flags().Lookup("default-value").Changed {
+		def := flags.DefaultValue
+		attrs.defaultValue = optional[*string]{set: true, v: &def}
+	} else if flags.BNoDefaultValue {
+		attrs.defaultValue = optional[*string]{set: true, v: nil}
+	}
+
+	if cmd.Flags().Lookup("env").Changed {
+		if flags.Env == "" {
+			return fmt.Errorf("cannot specify env \"\"; use --default to store in default env")
+		}
+		if flags.Env == reservedDefaultEnvName {
+			return fmt.Errorf("cannot specify reserved env name %q; use --default to store in default env", reservedDefaultEnvName)
+		}
+		attrs.env = optional[string]{set: true, v: strings.ToUpper(flags.Env)}
+	} else if flags.BDefault {
+		attrs.env = optional[string]{set: true, v: morc.DefaultEnvName}
+	} else if flags.BCurrent {
+		attrs.env = optional[string]{set: true, v: ""}
+	}
+
 	return nil
 }
 
-func capsSetFlagIsPresent() bool {
-	return flags.VarName != "" || flags.Spec != ""
+func capsSetFlagIsPresent(cmd *cobra.Command) bool {
+	// --default-value may legitimately be given the empty string, so check it
+	// by whether it was given at all.
+	return flags.VarName != "" || flags.Spec != "" || flags.Transform != "" || cmd.Flags().Changed("default-value") || flags.BNoDefaultValue || flags.Env != "" || flags.BDefault || flags.BCurrent
 }
 
 type capsAction int
@@ -545,13 +807,17 @@ const (
 	capsActionDelete
 	capsActionNew
 	capsActionEdit
+	capsActionValidate
 )
 
 type capKey string
 
 const (
-	capKeyVar  capKey = "VAR"
-	capKeySpec capKey = "SPEC"
+	capKeyVar          capKey = "VAR"
+	capKeySpec         capKey = "SPEC"
+	capKeyTransform    capKey = "TRANSFORM"
+	capKeyDefaultValue capKey = "DEFAULT-VALUE"
+	capKeyEnv          capKey = "ENV"
 )
 
 // Human prints the human-readable description of the key.
@@ -561,6 +827,12 @@ func (ck capKey) Human() string {
 		return "captured-to variable"
 	case capKeySpec:
 		return "capture specification"
+	case capKeyTransform:
+		return "capture transform"
+	case capKeyDefaultValue:
+		return "capture default value"
+	case capKeyEnv:
+		return "capture environment"
 	default:
 		return fmt.Sprintf("unknown capture key %q", ck)
 	}
@@ -576,6 +848,9 @@ var (
 	capAttrKeys = []capKey{
 		capKeyVar,
 		capKeySpec,
+		capKeyTransform,
+		capKeyDefaultValue,
+		capKeyEnv,
 	}
 )
 
@@ -593,6 +868,12 @@ func parseCapAttrKey(s string) (capKey, error) {
 		return capKeyVar, nil
 	case capKeySpec.Name():
 		return capKeySpec, nil
+	case capKeyTransform.Name():
+		return capKeyTransform, nil
+	case capKeyDefaultValue.Name():
+		return capKeyDefaultValue, nil
+	case capKeyEnv.Name():
+		return capKeyEnv, nil
 	default:
 		return "", fmt.Errorf("invalid attribute %q; must be one of %s", s, strings.Join(capAttrKeyNames(), ", "))
 	}
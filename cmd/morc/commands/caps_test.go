@@ -60,7 +60,7 @@ func Test_Caps_List(t *testing.T) {
 					},
 				},
 			}),
-			expectStdoutOutput: "TROLL from .data.people[0].name.first\n",
+			expectStdoutOutput: "0: TROLL from .data.people[0].name.first\n",
 		},
 		{
 			name: "req has 1 cap, full request",
@@ -73,7 +73,21 @@ func Test_Caps_List(t *testing.T) {
 					},
 				},
 			}),
-			expectStdoutOutput: "TROLL from entire response\n",
+			expectStdoutOutput: "0: TROLL from entire response\n",
+		},
+		{
+			name: "req has 1 cap, into default env",
+			args: []string{"caps", "req1"},
+			p: testProject_withRequests(morc.RequestTemplate{
+				Name: "req1",
+				Captures: map[string]morc.VarScraper{
+					"troll": {
+						Name: "troll",
+						Env:  morc.DefaultEnvName,
+					},
+				},
+			}),
+			expectStdoutOutput: "0: TROLL from entire response into default env\n",
 		},
 		{
 			name: "req has 1 cap, negative end",
@@ -87,7 +101,7 @@ func Test_Caps_List(t *testing.T) {
 					},
 				},
 			}),
-			expectStdoutOutput: "TROLL from offset 0,<END-1>\n",
+			expectStdoutOutput: "0: TROLL from offset 0,<END-1>\n",
 		},
 		{
 			name: "req has 1 cap, omitted end",
@@ -101,7 +115,7 @@ func Test_Caps_List(t *testing.T) {
 					},
 				},
 			}),
-			expectStdoutOutput: "TROLL from offset 12,<END>\n",
+			expectStdoutOutput: "0: TROLL from offset 12,<END>\n",
 		},
 		{
 			name: "req has 1 cap, quiet still prints",
@@ -121,7 +135,7 @@ func Test_Caps_List(t *testing.T) {
 					},
 				},
 			}),
-			expectStdoutOutput: "TROLL from .data.people[0].name.first\n",
+			expectStdoutOutput: "0: TROLL from .data.people[0].name.first\n",
 		},
 		{
 			name: "req has multiple caps",
@@ -156,9 +170,9 @@ func Test_Caps_List(t *testing.T) {
 				},
 			}),
 			expectStdoutOutput: "" +
-				"LAKHS from .data.people[13].salary\n" +
-				"TROLL from .data.people[0].name.first\n" +
-				"VILLAIN from offset 28,36\n",
+				"0: LAKHS from .data.people[13].salary\n" +
+				"1: TROLL from .data.people[0].name.first\n" +
+				"2: VILLAIN from offset 28,36\n",
 		},
 		{
 			name: "req has multiple caps, quiet still prints",
@@ -193,9 +207,9 @@ func Test_Caps_List(t *testing.T) {
 				},
 			}),
 			expectStdoutOutput: "" +
-				"LAKHS from .data.people[13].salary\n" +
-				"TROLL from .data.people[0].name.first\n" +
-				"VILLAIN from offset 28,36\n",
+				"0: LAKHS from .data.people[13].salary\n" +
+				"1: TROLL from .data.people[0].name.first\n" +
+				"2: VILLAIN from offset 28,36\n",
 		},
 	}
 
@@ -559,6 +573,147 @@ func Test_Caps_New(t *testing.T) {
 			),
 			expectStdoutOutput: "",
 		},
+		{
+			name: "rune offset",
+			args: []string{"caps", "req1", "-N", "troll", "-s", ":r28,32"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name:     "req1",
+					Captures: map[string]morc.VarScraper{},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {
+							Name:        "TROLL",
+							OffsetStart: 28,
+							OffsetEnd:   32,
+							RuneOffsets: true,
+						},
+					},
+				},
+			),
+			expectStdoutOutput: "Added capture from rune offset 28,32 to $TROLL on req1\n",
+		},
+		{
+			name: "with transform",
+			args: []string{"caps", "req1", "-N", "token", "-s", ".token", "--transform", "base64decode"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name:     "req1",
+					Captures: map[string]morc.VarScraper{},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TOKEN": {
+							Name:      "TOKEN",
+							Steps:     []morc.TraversalStep{{Key: "token"}},
+							Transform: morc.TransformBase64Decode,
+						},
+					},
+				},
+			),
+			expectStdoutOutput: "Added capture from path .token to $TOKEN on req1\n",
+		},
+		{
+			name: "with empty default value",
+			args: []string{"caps", "req1", "-N", "next", "-s", ".next", "--default-value", ""},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name:     "req1",
+					Captures: map[string]morc.VarScraper{},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"NEXT": {
+							Name:    "NEXT",
+							Steps:   []morc.TraversalStep{{Key: "next"}},
+							Default: capDefaultPtr(""),
+						},
+					},
+				},
+			),
+			expectStdoutOutput: "Added capture from path .next to $NEXT on req1\n",
+		},
+		{
+			name: "with unknown transform",
+			args: []string{"caps", "req1", "-N", "token", "-s", ".token", "--transform", "rot13"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name:     "req1",
+					Captures: map[string]morc.VarScraper{},
+				},
+			),
+			expectErr: `--transform: unknown transform "rot13"`,
+		},
+		{
+			name: "into default env",
+			args: []string{"caps", "req1", "-N", "troll", "-s", ":28,32", "--default"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name:     "req1",
+					Captures: map[string]morc.VarScraper{},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {
+							Name:        "TROLL",
+							OffsetStart: 28,
+							OffsetEnd:   32,
+							Env:         morc.DefaultEnvName,
+						},
+					},
+				},
+			),
+			expectStdoutOutput: "Added capture from offset 28,32 to $TROLL on req1\n",
+		},
+		{
+			name: "into env that does not exist",
+			args: []string{"caps", "req1", "-N", "troll", "-s", ":28,32", "--env", "prod"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name:     "req1",
+					Captures: map[string]morc.VarScraper{},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {
+							Name:        "TROLL",
+							OffsetStart: 28,
+							OffsetEnd:   32,
+							Env:         "PROD",
+						},
+					},
+				},
+			),
+			expectStderrOutput: "WARN: environment PROD does not exist; it will be created when a value is captured\n",
+			expectStdoutOutput: "Added capture from offset 28,32 to $TROLL on req1\n",
+		},
+		{
+			name: "into reserved env name",
+			args: []string{"caps", "req1", "-N", "troll", "-s", ":28,32", "--env", morc.DefaultEnvName},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name:     "req1",
+					Captures: map[string]morc.VarScraper{},
+				},
+			),
+			expectErr: "cannot specify reserved env name",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -656,6 +811,64 @@ func Test_Caps_Delete(t *testing.T) {
 			),
 			expectErr: "no capture defined for $TROLL in req1",
 		},
+		{
+			name: "delete by number",
+			args: []string{"caps", "req1", "-D", "1"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL":   {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+						"VILLAIN": {Name: "VILLAIN", OffsetStart: 28, OffsetEnd: 36},
+					},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+					},
+				},
+			),
+			expectStdoutOutput: "Deleted capture to $VILLAIN from req1\n",
+		},
+		{
+			name: "delete by number out of range",
+			args: []string{"caps", "req1", "-D", "2"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL":   {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+						"VILLAIN": {Name: "VILLAIN", OffsetStart: 28, OffsetEnd: 36},
+					},
+				},
+			),
+			expectErr: "no capture defined for $2 in req1",
+		},
+		{
+			name: "delete var named like a number takes precedence over number",
+			args: []string{"caps", "req1", "-D", "1"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"1":     {Name: "1", OffsetStart: 28, OffsetEnd: 32},
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+					},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+					},
+				},
+			),
+			expectStdoutOutput: "Deleted capture to $1 from req1\n",
+		},
 		{
 			name: "var exists",
 			args: []string{"caps", "REQ1", "-D", "troll"},
@@ -1012,6 +1225,175 @@ func Test_Caps_Edit(t *testing.T) {
 			),
 			expectStdoutOutput: "",
 		},
+		{
+			name: "set transform",
+			args: []string{"caps", "req1", "troll", "--transform", "TRIM"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+					},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32, Transform: morc.TransformTrim},
+					},
+				},
+			),
+			expectStdoutOutput: "Set capture transform to trim\n",
+		},
+		{
+			name: "remove transform",
+			args: []string{"caps", "req1", "troll", "--transform", "none"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32, Transform: morc.TransformTrim},
+					},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+					},
+				},
+			),
+			expectStdoutOutput: "Set capture transform to (none)\n",
+		},
+		{
+			name: "set default value",
+			args: []string{"caps", "req1", "troll", "--default-value", ""},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+					},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32, Default: capDefaultPtr("")},
+					},
+				},
+			),
+			expectStdoutOutput: "Set capture default value to \"\"\n",
+		},
+		{
+			name: "remove default value",
+			args: []string{"caps", "req1", "troll", "--no-default-value"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32, Default: capDefaultPtr("none")},
+					},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+					},
+				},
+			),
+			expectStdoutOutput: "Set capture default value to (none)\n",
+		},
+		{
+			name: "alter spec keeps transform",
+			args: []string{"caps", "req1", "troll", "-s", ":1,4"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32, Transform: morc.TransformLower},
+					},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 1, OffsetEnd: 4, Transform: morc.TransformLower},
+					},
+				},
+			),
+			expectStdoutOutput: "Set capture specification to offset 1,4\n",
+		},
+		{
+			name: "set env",
+			args: []string{"caps", "req1", "troll", "--default"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+					},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32, Env: morc.DefaultEnvName},
+					},
+				},
+			),
+			expectStdoutOutput: "Set capture environment to <DEFAULT>\n",
+		},
+		{
+			name: "alter spec keeps env",
+			args: []string{"caps", "req1", "troll", "-s", ":1,4"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32, Env: "PROD"},
+					},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 1, OffsetEnd: 4, Env: "PROD"},
+					},
+				},
+			),
+			expectStderrOutput: "WARN: environment PROD does not exist; it will be created when a value is captured\n",
+			expectStdoutOutput: "Set capture specification to offset 1,4\n",
+		},
+		{
+			name: "back to current env",
+			args: []string{"caps", "req1", "troll", "--current"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32, Env: "PROD"},
+					},
+				},
+			),
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+					},
+				},
+			),
+			expectStdoutOutput: "Set capture environment to (current)\n",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1103,6 +1485,20 @@ func Test_Caps_Show(t *testing.T) {
 			),
 			expectErr: "no capture to $TROLL exists on request template req1",
 		},
+		{
+			name: "show by number",
+			args: []string{"caps", "req1", "0"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL":   {Name: "TROLL", OffsetStart: 28, OffsetEnd: 32},
+						"VILLAIN": {Name: "VILLAIN", OffsetStart: 28, OffsetEnd: 36},
+					},
+				},
+			),
+			expectStdoutOutput: "$TROLL from offset 28,32\n",
+		},
 		{
 			name: "happy path - json path",
 			args: []string{"caps", "req1", "troll"},
@@ -1289,6 +1685,45 @@ func Test_Caps_Get(t *testing.T) {
 			),
 			expectStdoutOutput: "TROLL\n",
 		},
+		{
+			name: "get transform",
+			args: []string{"caps", "req1", "troll", "-G", "transform"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", Transform: morc.TransformURLDecode},
+					},
+				},
+			),
+			expectStdoutOutput: "urldecode\n",
+		},
+		{
+			name: "get default value",
+			args: []string{"caps", "req1", "troll", "-G", "default-value"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL", Default: capDefaultPtr("n/a")},
+					},
+				},
+			),
+			expectStdoutOutput: "\"n/a\"\n",
+		},
+		{
+			name: "get transform, none set",
+			args: []string{"caps", "req1", "troll", "-G", "transform"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {Name: "TROLL"},
+					},
+				},
+			),
+			expectStdoutOutput: "(none)\n",
+		},
 		{
 			name: "get var name, quiet still prints",
 			args: []string{"caps", "req1", "troll", "-G", "var", "-q"},
@@ -1328,6 +1763,22 @@ func Test_Caps_Get(t *testing.T) {
 			),
 			expectStdoutOutput: ".data.people[0].name.first\n",
 		},
+		{
+			name: "get var spec, key needing quotes",
+			args: []string{"caps", "req1", "troll", "-G", "spec"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{
+					Name: "req1",
+					Captures: map[string]morc.VarScraper{
+						"TROLL": {
+							Name:  "TROLL",
+							Steps: []morc.TraversalStep{{Key: "data"}, {Key: "first.name"}},
+						},
+					},
+				},
+			),
+			expectStdoutOutput: ".data.\"first.name\"\n",
+		},
 		{
 			name: "get var spec, path spec, quiet still prints",
 			args: []string{"caps", "req1", "troll", "-G", "SpEc", "-q"},
@@ -1437,12 +1888,137 @@ func Test_Caps_Get(t *testing.T) {
 	}
 }
 
+func Test_Caps_ValidateAll(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:      "req does not exist",
+			args:      []string{"caps", "req1", "--validate-all"},
+			p:         morc.Project{},
+			expectErr: "no request template req1",
+		},
+		{
+			name:      "extra positional arg",
+			args:      []string{"caps", "req1", "troll", "--validate-all"},
+			p:         testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectErr: `unknown 2nd positional argument: "troll"`,
+		},
+		{
+			name:      "with --new",
+			args:      []string{"caps", "req1", "--validate-all", "--new", "troll", "-s", "raw"},
+			p:         testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectErr: "[new validate-all] were all set",
+		},
+		{
+			name:               "req has no caps",
+			args:               []string{"caps", "req1", "--validate-all"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectStdoutOutput: "All 0 captures on req1 are valid\n",
+		},
+		{
+			name: "all caps valid",
+			args: []string{"caps", "req1", "--validate-all"},
+			p: testProject_withRequests(morc.RequestTemplate{
+				Name: "req1",
+				Captures: map[string]morc.VarScraper{
+					"troll":  {Name: "troll", Steps: []morc.TraversalStep{{Key: "name"}}},
+					"aradia": {Name: "aradia", OffsetStart: 2, OffsetEnd: 8},
+				},
+			}),
+			expectStdoutOutput: "All 2 captures on req1 are valid\n",
+		},
+		{
+			name: "all caps valid, quiet",
+			args: []string{"caps", "req1", "--validate-all", "-q"},
+			p: testProject_withRequests(morc.RequestTemplate{
+				Name: "req1",
+				Captures: map[string]morc.VarScraper{
+					"troll": {Name: "troll", Steps: []morc.TraversalStep{{Key: "name"}}},
+				},
+			}),
+			expectStdoutOutput: "",
+		},
+		{
+			name: "some caps invalid",
+			args: []string{"caps", "req1", "--validate-all"},
+			p: testProject_withRequests(morc.RequestTemplate{
+				Name: "req1",
+				Captures: map[string]morc.VarScraper{
+					"troll":  {Name: "troll", Steps: []morc.TraversalStep{{Key: "name"}}},
+					"aradia": {Name: "aradia", OffsetStart: 8, OffsetEnd: 2},
+					"sollux": {Name: "sollux", OffsetStart: -1, OffsetEnd: -4},
+					"nepeta": {Name: "equius"},
+					"kanaya": {
+						Name:         "kanaya",
+						Alternatives: []morc.VarScraper{{OffsetStart: 4, OffsetEnd: 4}},
+					},
+				},
+			}),
+			expectStdoutOutput: "" +
+				"$ARADIA: end offset 2 is less than or equal to start offset 8\n" +
+				"$KANAYA: alternative #1: end offset 4 is less than or equal to start offset 4\n" +
+				"$NEPETA: saved under NEPETA but captures to EQUIUS\n" +
+				"$SOLLUX: end offset -4 is less than or equal to start offset -1\n",
+			expectErr: "4 of 5 captures on req1 are invalid",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetCapsFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(capsCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got none", tc.expectErr)
+			}
+
+			// invalid captures are still reported on stdout when it fails, so
+			// always check output
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_noProjectMutations(assert)
+		})
+	}
+}
+
+func capDefaultPtr(s string) *string {
+	return &s
+}
+
 func resetCapsFlags() {
 	flags.New = ""
 	flags.Delete = ""
 	flags.Get = ""
 	flags.Spec = ""
+	flags.Transform = ""
+	flags.DefaultValue = ""
+	flags.BNoDefaultValue = false
 	flags.VarName = ""
+	flags.Env = ""
+	flags.BDefault = false
+	flags.BCurrent = false
+	flags.BValidateAll = false
 	flags.BQuiet = false
 
 	capsCmd.Flags().VisitAll(func(fl *pflag.Flag) {
@@ -1,12 +1,14 @@
 package commands
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/dekarrin/morc"
 	"github.com/dekarrin/morc/cmd/morc/cmdio"
@@ -22,8 +24,9 @@ var (
 )
 
 const (
-	reservedDefaultEnvName = "<DEFAULT>"
+	reservedDefaultEnvName = morc.DefaultEnvName
 	morcProjectPointerFile = ".MORC_PROJECT"
+	envVarProjectFile      = "MORC_PROJECT_FILE"
 )
 
 const (
@@ -31,12 +34,26 @@ const (
 	annotationKeyHelpUsages = "morc_help_usages"
 )
 
+// projPathFromFlagsOrFile gives the path to the project file to use. In order
+// of precedence, it is taken from the -F flag, the project of the running REPL,
+// the MORC_PROJECT_FILE environment variable, the contents of a .MORC_PROJECT
+// file in the current directory, and finally the default project path.
 func projPathFromFlagsOrFile(cmd *cobra.Command) string {
 	if cmd.Flags().Changed("project-file") {
 		// if it's changed, this has priority no matter what
 		return flags.ProjectFile
 	}
 
+	// commands run in a REPL use the project it was opened with
+	if activeRepl != nil {
+		return activeRepl.projFile
+	}
+
+	// next, an explicitly-set env var beats anything found on disk
+	if envPath := strings.TrimSpace(os.Getenv(envVarProjectFile)); envPath != "" {
+		return envPath
+	}
+
 	io := cmdio.From(cmd)
 
 	// if it's not changed, check if there is a file called .MORC_PROJECT and if
@@ -194,7 +211,8 @@ func addRequestOutputFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().BoolVarP(&flags.BCaptures, "captures", "", false, "(Output flag) Output the captures from the response")
 	cmd.PersistentFlags().BoolVarP(&flags.BNoBody, "no-body", "", false, "(Output flag) Suppress the output of the response body")
 	cmd.PersistentFlags().BoolVarP(&flags.BRequest, "request", "", false, "(Output flag) Output the filled request prior to sending it")
-	cmd.PersistentFlags().StringVarP(&flags.Format, "format", "f", "pretty", "(Output flag) Set output format. `FMT` must be one of 'pretty', 'line', or 'sr')")
+	cmd.PersistentFlags().BoolVarP(&flags.BVerbose, "verbose", "v", false, "(Output flag) Output the request, the headers of the response, and the captures; the same as giving --request, --headers, and --captures")
+	cmd.PersistentFlags().StringVarP(&flags.Format, "format", "f", "pretty", "(Output flag) Set output format. `FMT` must be one of 'pretty', 'line', 'sr', or 'json')")
 }
 
 func gatherRequestOutputFlags(cmd *cobra.Command) (morc.OutputControl, error) {
@@ -209,26 +227,330 @@ func gatherRequestOutputFlags(cmd *cobra.Command) (morc.OutputControl, error) {
 			oc.Format = morc.FormatLine
 
 			// check if user is trying to turn on things that aren't allowed
-			if flags.BRequest || flags.BHeaders || flags.BNoBody || flags.BCaptures {
+			if flags.BRequest || flags.BHeaders || flags.BNoBody || flags.BCaptures || flags.BTimings || flags.BVerbose || flags.BShowRedirects {
 				return oc, fmt.Errorf("format 'sr' only allows status line and response body; use format 'line' for control over output")
 			}
 		case "line":
 			oc.Format = morc.FormatLine
+		case "json":
+			oc.Format = morc.FormatJSON
+
+			// everything but the body is always included
+			if flags.BRequest || flags.BHeaders || flags.BCaptures || flags.BTimings || flags.BVerbose || flags.BShowRedirects {
+				return oc, fmt.Errorf("format 'json' always includes the request, headers, captures, redirects, and timings; only --no-body can be given with it")
+			}
 		default:
-			return oc, fmt.Errorf("invalid format %q; must be one of pretty, line, or sr", flags.Format)
+			return oc, fmt.Errorf("invalid format %q; must be one of pretty, line, sr, or json", flags.Format)
 		}
 	} else {
 		oc.Format = morc.FormatPretty
 	}
 
-	oc.Request = flags.BRequest
-	oc.Headers = flags.BHeaders
-	oc.Captures = flags.BCaptures
+	oc.Request = flags.BRequest || flags.BVerbose
+	oc.Headers = flags.BHeaders || flags.BVerbose
+	oc.Captures = flags.BCaptures || flags.BVerbose
 	oc.SuppressResponseBody = flags.BNoBody
 
 	return oc, nil
 }
 
+// transportOptions holds options gathered from CLI flags that control how
+// connections to remote hosts are made when sending requests.
+type transportOptions struct {
+	ipVersion   int
+	httpVersion int
+	localAddr   string
+	resolve     []morc.ResolveOverride
+	pool        morc.PoolOptions
+	certFile    string
+	keyFile     string
+	caCertFile  string
+	timeout     time.Duration
+	envFallback bool
+
+	// record is only set by send. It is kept with the other options given for
+	// a single invocation so that it also applies to the steps of an auth flow.
+	record recordOverrides
+}
+
+// applyTo sets the options in opts that correspond to those in to. Options
+// that override a project setting are set from to.applyToConfig(cfg).
+func (to transportOptions) applyTo(opts *morc.SendOptions, cfg morc.Settings) {
+	opts.IPVersion = to.ipVersion
+	opts.HTTPVersion = to.httpVersion
+	opts.LocalAddr = to.localAddr
+	opts.Resolve = to.resolve
+	opts.Pool = to.pool
+
+	cfg = to.applyToConfig(cfg)
+	opts.ClientCertFile = cfg.ClientCertFile
+	opts.ClientKeyFile = cfg.ClientKeyFile
+	opts.CACertFile = cfg.CACertFile
+	opts.Timeout = cfg.RequestTimeout
+	opts.EnvFallback = cfg.EnvFallback
+}
+
+// applyToConfig returns cfg with the settings that are overridden by to
+// replaced. It is the last layer of resolving the settings used to send a
+// request, after the project file and the defaults of p.EffectiveConfig().
+func (to transportOptions) applyToConfig(cfg morc.Settings) morc.Settings {
+	// a certificate given for a single invocation replaces the project's
+	// certificate and key together
+	if to.certFile != "" {
+		cfg.ClientCertFile = to.certFile
+		cfg.ClientKeyFile = to.keyFile
+	}
+	if to.caCertFile != "" {
+		cfg.CACertFile = to.caCertFile
+	}
+	if to.timeout != 0 {
+		cfg.RequestTimeout = to.timeout
+	}
+	if to.envFallback {
+		cfg.EnvFallback = true
+	}
+	return cfg
+}
+
+func addTransportFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVarP(&flags.BIPv4, "ipv4", "4", false, "Connect to remote hosts using only IPv4 addresses.")
+	cmd.PersistentFlags().BoolVarP(&flags.BIPv6, "ipv6", "6", false, "Connect to remote hosts using only IPv6 addresses.")
+	cmd.PersistentFlags().BoolVarP(&flags.BHTTP1, "http1", "", false, "Send requests using only HTTP/1.1, even to remote hosts that support HTTP/2.")
+	cmd.PersistentFlags().BoolVarP(&flags.BHTTP2, "http2", "", false, "Require requests to be sent using HTTP/2. The URL must be https, and it is an error if the remote host does not respond with HTTP/2.")
+	cmd.PersistentFlags().StringVarP(&flags.LocalAddr, "local-addr", "", "", "Connect to remote hosts from local source address `ADDR`, given as an IP address or the name of a network interface. If --ipv4 or --ipv6 is also given, ADDR must be of that version; otherwise, the version of ADDR is used for the connection. If a proxy is in use, this applies to the connection to the proxy.")
+	cmd.PersistentFlags().StringArrayVarP(&flags.Resolve, "resolve", "", []string{}, "Connect to IP address ADDR whenever a connection would be made to HOST on PORT, like curl's --resolve. The Host header and TLS server name are still taken from the URL. The argument must be in `HOST:PORT:ADDR` format. May be given multiple times.")
+
+	cmd.PersistentFlags().StringVarP(&flags.CertFile, "cert", "", "", "Present the PEM-encoded client certificate in `FILE` to servers that ask for one, for mutual TLS. If --key is not given, the private key is read from FILE as well. Overrides the CLIENT-CERT and CLIENT-KEY settings of the project.")
+	cmd.PersistentFlags().StringVarP(&flags.KeyFile, "key", "", "", "Read the PEM-encoded private key of the certificate given with --cert from `FILE`.")
+	cmd.PersistentFlags().StringVarP(&flags.CACertFile, "cacert", "", "", "Verify servers using only the PEM-encoded CA certificates in `FILE` instead of those of the system. Overrides the CA-CERT setting of the project.")
+
+	cmd.PersistentFlags().IntVarP(&flags.MaxIdleConns, "max-idle-conns", "", 0, "Keep at most `N` idle connections open, both in total and to any one host. Defaults to 100 in total and 2 per host. Only matters when many requests are sent, such as in a flow.")
+	cmd.PersistentFlags().IntVarP(&flags.MaxConnsPerHost, "max-conns-per-host", "", 0, "Open at most `N` connections to any one host at a time. Defaults to no limit. Only matters when many requests are sent, such as in a flow.")
+	cmd.PersistentFlags().StringVarP(&flags.IdleTimeout, "idle-timeout", "", "", "Close idle connections after they have been unused for `DURATION`. Defaults to 90s. Only matters when many requests are sent, such as in a flow.")
+
+	cmd.PersistentFlags().StringVarP(&flags.Timeout, "timeout", "", "", "Give up on a request if it has not completed within `DURATION`. Defaults to the request timeout of the project, or 30s if no project is used.")
+
+	cmd.PersistentFlags().BoolVarP(&flags.BEnvFallback, "env-fallback", "", false, "Fill in any var that is not otherwise defined from the OS environment variable with the same name. Always on for request templates in a project whose ENV-FALLBACK setting is ON.")
+
+	cmd.MarkFlagsMutuallyExclusive("ipv4", "ipv6")
+	cmd.MarkFlagsMutuallyExclusive("http1", "http2")
+}
+
+func gatherTransportFlags(cmd *cobra.Command) (transportOptions, error) {
+	to := transportOptions{}
+
+	if flags.BIPv4 {
+		to.ipVersion = 4
+	} else if flags.BIPv6 {
+		to.ipVersion = 6
+	}
+
+	if flags.BHTTP1 {
+		to.httpVersion = 1
+	} else if flags.BHTTP2 {
+		to.httpVersion = 2
+	}
+
+	if cmd.Flags().Changed("local-addr") {
+		if flags.LocalAddr == "" {
+			return to, fmt.Errorf("--local-addr cannot be empty")
+		}
+		to.localAddr = flags.LocalAddr
+	}
+
+	if cmd.Flags().Changed("key") && !cmd.Flags().Changed("cert") {
+		return to, fmt.Errorf("--key can only be used with --cert")
+	}
+	for _, name := range []string{"cert", "key", "cacert"} {
+		if cmd.Flags().Changed(name) && cmd.Flags().Lookup(name).Value.String() == "" {
+			return to, fmt.Errorf("--%s cannot be empty", name)
+		}
+	}
+	to.certFile = flags.CertFile
+	to.keyFile = flags.KeyFile
+	to.caCertFile = flags.CACertFile
+
+	for idx, r := range flags.Resolve {
+		ro, err := morc.ParseResolveOverride(r)
+		if err != nil {
+			return to, fmt.Errorf("resolve #%d (%q): %w", idx+1, r, err)
+		}
+		to.resolve = append(to.resolve, ro)
+	}
+
+	if cmd.Flags().Changed("max-idle-conns") {
+		if flags.MaxIdleConns < 1 {
+			return to, fmt.Errorf("--max-idle-conns must be at least 1")
+		}
+		to.pool.MaxIdleConns = flags.MaxIdleConns
+	}
+
+	if cmd.Flags().Changed("max-conns-per-host") {
+		if flags.MaxConnsPerHost < 1 {
+			return to, fmt.Errorf("--max-conns-per-host must be at least 1")
+		}
+		to.pool.MaxConnsPerHost = flags.MaxConnsPerHost
+	}
+
+	if cmd.Flags().Changed("idle-timeout") {
+		timeout, err := time.ParseDuration(flags.IdleTimeout)
+		if err != nil {
+			return to, fmt.Errorf("--idle-timeout: %w", err)
+		}
+		if timeout <= 0 {
+			return to, fmt.Errorf("--idle-timeout must be a positive duration")
+		}
+		to.pool.IdleConnTimeout = timeout
+	}
+
+	to.envFallback = flags.BEnvFallback
+
+	if cmd.Flags().Changed("timeout") {
+		timeout, err := time.ParseDuration(flags.Timeout)
+		if err != nil {
+			return to, fmt.Errorf("--timeout: %w", err)
+		}
+		if timeout <= 0 {
+			return to, fmt.Errorf("--timeout must be a positive duration")
+		}
+		to.timeout = timeout
+	}
+
+	return to, nil
+}
+
+// parseDataURLEncodeFlags parses every --data-urlencode flag given. Each one
+// is in one of the forms accepted by curl's --data-urlencode: 'content',
+// '=content', 'name=content', '@filename', or 'name@filename'. Files are read
+// immediately, with morc.ProjDirVar in their names resolved against the project
+// file that cmd would use.
+func parseDataURLEncodeFlags(cmd *cobra.Command) ([]morc.FormField, error) {
+	var fields []morc.FormField
+	for idx, arg := range flags.DataURLEncode {
+		field, err := parseDataURLEncode(cmd, arg)
+		if err != nil {
+			return nil, fmt.Errorf("data-urlencode #%d (%q): %w", idx+1, arg, err)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func parseDataURLEncode(cmd *cobra.Command, arg string) (morc.FormField, error) {
+	sep := strings.IndexAny(arg, "=@")
+	if sep < 0 {
+		return morc.FormField{Value: arg}, nil
+	}
+
+	name := arg[:sep]
+	if arg[sep] == '=' {
+		return morc.FormField{Name: name, Value: arg[sep+1:]}, nil
+	}
+
+	// otherwise, it's a file to read the value from
+	filename := arg[sep+1:]
+	if filename == "" {
+		return morc.FormField{}, fmt.Errorf("missing filename after '@'")
+	}
+	filename, err := projDirPath(cmd, filename)
+	if err != nil {
+		return morc.FormField{}, err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return morc.FormField{}, fmt.Errorf("read %q: %w", filename, err)
+	}
+	return morc.FormField{Name: name, Value: string(data)}, nil
+}
+
+// projDirPath returns path with any occurrence of morc.ProjDirVar replaced with
+// the directory of the project file that cmd would use. Paths that do not
+// contain morc.ProjDirVar are returned as-is.
+func projDirPath(cmd *cobra.Command, path string) (string, error) {
+	if !strings.Contains(path, morc.ProjDirVar) {
+		return path, nil
+	}
+
+	resolved := morc.ExpandProjDir(path, projPathFromFlagsOrFile(cmd))
+	if resolved == "" {
+		return "", fmt.Errorf("%q does not refer to a file in the project directory", path)
+	}
+	return resolved, nil
+}
+
+// maskedValue is output in place of a value that is being kept secret.
+const maskedValue = "***"
+
+// secretNameParts are the parts of a variable name that indicate it probably
+// holds a secret.
+var secretNameParts = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "APIKEY", "API_KEY", "AUTH", "CREDENTIAL", "PRIVATE"}
+
+// looksSecret returns whether the variable with the given name looks like it
+// holds a secret based on its name alone.
+func looksSecret(name string) bool {
+	name = strings.ToUpper(name)
+	for _, part := range secretNameParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSecretVar returns whether the variable with the given name holds a secret,
+// either because it is marked as one in vars or because its name looks like it
+// does.
+func isSecretVar(vars morc.VarStore, name string) bool {
+	return vars.IsSecret(name) || looksSecret(name)
+}
+
+// listFormat is the format that a listing of resources is output in.
+type listFormat int
+
+const (
+	listFormatText listFormat = iota
+	listFormatJSON
+)
+
+func addListOutputFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&flags.ListOutput, "output", "", "text", "Set the format of listings and of shown details. `FMT` must be one of 'text' or 'json'.")
+}
+
+// gatherListOutputFlag returns the listFormat selected by --output.
+// isListOrShow is whether the action is listing resources or showing one of
+// them. what is the name of the resource and is used in error output when the
+// flag is given for any other action.
+func gatherListOutputFlag(cmd *cobra.Command, isListOrShow bool, what string) (listFormat, error) {
+	if !cmd.Flags().Changed("output") {
+		return listFormatText, nil
+	}
+
+	if !isListOrShow {
+		return listFormatText, fmt.Errorf("--output can only be used when listing or showing %s", what)
+	}
+
+	switch strings.ToLower(flags.ListOutput) {
+	case "text":
+		return listFormatText, nil
+	case "json":
+		return listFormatJSON, nil
+	default:
+		return listFormatText, fmt.Errorf("invalid list output format %q; must be one of text or json", flags.ListOutput)
+	}
+}
+
+// printJSON writes v to the output of io as indented JSON followed by a
+// newline. It is always printed, even if io is in quiet mode.
+func printJSON(io cmdio.IO, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal JSON: %w", err)
+	}
+
+	io.Printf("%s\n", data)
+	return nil
+}
+
 // if set, will override loading project from disk.
 var (
 	projReader io.Reader
@@ -242,6 +564,9 @@ var (
 )
 
 func readProject(filename string, all bool) (morc.Project, error) {
+	if p, ok := activeRepl.loaded(filename); ok {
+		return p, nil
+	}
 	if projReader != nil {
 		return morc.LoadProject(projReader, seshReader, histReader)
 	}
@@ -249,6 +574,9 @@ func readProject(filename string, all bool) (morc.Project, error) {
 }
 
 func writeProject(p morc.Project, all bool) error {
+	// anything only kept in memory by a REPL is not persisted
+	p = activeRepl.remember(p)
+
 	if projWriter != nil {
 		err := p.Dump(projWriter)
 		if err != nil {
@@ -257,7 +585,7 @@ func writeProject(p morc.Project, all bool) error {
 
 		if all {
 			if p.Config.SessionFSPath() != "" && seshWriter != nil {
-				if err := p.Session.Dump(seshWriter); err != nil {
+				if err := p.DumpSession(seshWriter); err != nil {
 					return fmt.Errorf("persist session: %w", err)
 				}
 			}
@@ -275,6 +603,9 @@ func writeProject(p morc.Project, all bool) error {
 }
 
 func writeHistory(p morc.Project) error {
+	// anything only kept in memory by a REPL is not persisted
+	p = activeRepl.remember(p)
+
 	if histWriter != nil {
 		return p.DumpHistory(histWriter)
 	}
@@ -283,8 +614,11 @@ func writeHistory(p morc.Project) error {
 }
 
 func writeSession(p morc.Project) error {
+	// anything only kept in memory by a REPL is not persisted
+	p = activeRepl.remember(p)
+
 	if seshWriter != nil {
-		return p.Session.Dump(seshWriter)
+		return p.DumpSession(seshWriter)
 	}
 
 	return p.PersistSessionToDisk()
@@ -403,16 +737,65 @@ type cliFlags struct {
 	// RemoveHeaders is a list of headers to be removed.
 	RemoveHeaders []string
 
+	// DefaultHeaders is a list of default headers to be added to a project.
+	DefaultHeaders []string
+
+	// RemoveDefaultHeaders is a list of keys of default headers to be removed
+	// from a project.
+	RemoveDefaultHeaders []string
+
 	// Name is the name of the resource in question.
 	Name string
 
 	// VarName is identical to Name but is named differently for readability.
 	VarName string
 
+	// AssertHeaders is a list of 'KEY: VALUE' headers that the response must
+	// have.
+	AssertHeaders []string
+
+	// AssertHeadersPresent is a list of keys of headers that the response must
+	// have, regardless of value.
+	AssertHeadersPresent []string
+
+	// HeaderMatch is how the values in AssertHeaders are compared to the
+	// actual values. It is the name of a morc.HeaderMatch.
+	HeaderMatch string
+
+	// OutputFile is the path to a file that the body of a response is written
+	// to instead of stdout.
+	OutputFile string
+
+	// RetryBodyMatch is text that causes a request to be sent again if it is
+	// in the body of the response.
+	RetryBodyMatch string
+
+	// RetryMaxAttempts is the most times a retried request is sent.
+	RetryMaxAttempts int
+
+	// RetryBackoff is how long to wait before the first retry of a request. It
+	// is parsed with time.ParseDuration.
+	RetryBackoff string
+
 	// BodyData is the bytes of the body of a request. This is either the bytes
 	// of the body directly or a filename prepended with an '@' character.
 	BodyData string
 
+	// BodyFile is the path to a file that the body of a request is read from
+	// each time it is sent.
+	BodyFile string
+
+	// WrapBody is the key of a JSON object that the existing body of a request
+	// is to be wrapped in.
+	WrapBody string
+
+	// AuthFlow is the name of a flow to run before a request is sent.
+	AuthFlow string
+
+	// DataURLEncode is a list of form fields to URL-encode and add to the body
+	// of a request, in any of the forms accepted by curl's --data-urlencode.
+	DataURLEncode []string
+
 	// Headers is a list of headers to be added to the request.
 	Headers []string
 
@@ -444,6 +827,29 @@ type cliFlags struct {
 	// recording should be "ON" or "OFF".
 	RecordCookies string
 
+	// CompactFiles is a toggle-string flag that indicates whether project
+	// files should be written as compact JSON, "ON" or "OFF".
+	CompactFiles string
+
+	// EnvFallback is a toggle-string flag that indicates whether vars not
+	// defined in a project are taken from the OS environment, "ON" or "OFF".
+	EnvFallback string
+
+	// MaxHistory is the most entries that project history is allowed to hold,
+	// as a string. "0" means unlimited.
+	MaxHistory string
+
+	// ClientCert is the path to a project's client certificate for mutual
+	// TLS.
+	ClientCert string
+
+	// ClientKey is the path to the private key of a project's client
+	// certificate.
+	ClientKey string
+
+	// CACert is the path to a project's bundle of CA certificates.
+	CACert string
+
 	// WriteStateFile is a flag used in one-off commands that gives the path to
 	// a state file to write out cookies and variables to.
 	WriteStateFile string
@@ -459,6 +865,23 @@ type cliFlags struct {
 	// Spec is a flag that gives the specification for a variable capture.
 	Spec string
 
+	// Transform is a flag that gives the transform applied to values captured
+	// by a variable capture.
+	Transform string
+
+	// DefaultValue is a flag that gives the value a variable capture stores
+	// when it fails to scrape one from a response.
+	DefaultValue string
+
+	// BNoDefaultValue is a switch flag that, when set, indicates that a
+	// variable capture's default value is to be removed.
+	BNoDefaultValue bool
+
+	// CaptureOverrides is a flag used in send that gives a variable capture in
+	// NAME:SPEC format to use for the current send only. It can be specified
+	// multiple times.
+	CaptureOverrides []string
+
 	// StepRemovals is a flag indicating that the given step index is to be
 	// removed. It can be specified multiple times.
 	StepRemovals []int
@@ -467,24 +890,153 @@ type cliFlags struct {
 	// is in format [IDX]:REQ. It can be specified multiple times.
 	StepAdds []string
 
+	// StepAddsBefore is a flag indicating that the given request is to be
+	// added before the first step that calls another request. It is in format
+	// REF:REQ. It can be specified multiple times.
+	StepAddsBefore []string
+
+	// StepAddsAfter is a flag indicating that the given request is to be
+	// added after the first step that calls another request. It is in format
+	// REF:REQ. It can be specified multiple times.
+	StepAddsAfter []string
+
 	// StepMoves is a flag indicating that the given step is to be moved to the
 	// given index. It is in format FROM:[TO]. It can be specified multiple
 	// times.
 	StepMoves []string
 
+	// StepReorder is a flag indicating that all steps of a flow are to be
+	// rearranged at once. It is a comma-separated list of the current index of
+	// each step, in the order they are to be put in.
+	StepReorder string
+
 	// StepReplaces is a flag indicating that the request called at the given
 	// step is to be updated to the given request. It is in format IDX:REQ.
 	// It can be specified multiple times.
 	StepReplaces []string
 
+	// StepRequires is a flag indicating that the step at the given index is to
+	// have its required variables set to the given list. It is in format
+	// IDX:[VAR1,VAR2,...]. It can be specified multiple times.
+	StepRequires []string
+
+	// StepGroups is a flag indicating that the step at the given index is to
+	// be put in the given parallel group. It is in format IDX:[GROUP]. It can
+	// be specified multiple times.
+	StepGroups []string
+
 	// Format is a request output control flag that gives the format of the
 	// output.
 	Format string
 
+	// ListOutput is the format that listings and details of resources are
+	// output in.
+	ListOutput string
+
+	// Tail is the number of entries at the end of a listing to show.
+	Tail int
+
+	// FilterStatus is the response status code that history entries must have
+	// to be listed.
+	FilterStatus int
+
+	// FilterTemplate is the name of the request template that history entries
+	// must have been sent from to be listed.
+	FilterTemplate string
+
+	// Since is the earliest request time of history entries to be listed, as
+	// either a date or an RFC 3339 timestamp.
+	Since string
+
+	// ClearBefore is the time that history entries sent before are to be
+	// deleted, as either a date or an RFC 3339 timestamp.
+	ClearBefore string
+
+	// ClearKeepLast is the number of most recent history entries to keep when
+	// deleting the rest.
+	ClearKeepLast int
+
+	// Replay is the index of the history entry whose request is to be sent
+	// again.
+	Replay int
+
+	// Host is the host to send a replayed request to instead of the one it was
+	// originally sent to.
+	Host string
+
+	// Cookies is a list of cookies to send with a request, each in the format
+	// of a Set-Cookie header value.
+	Cookies []string
+
+	// Import is the argument to --import. It is the path to a file to import
+	// data from.
+	Import string
+
+	// BaseURL is the base URL to set for an environment.
+	BaseURL string
+
+	// BGetBaseURL is a switch flag that, when set, indicates that the base URL
+	// of an environment should be printed.
+	BGetBaseURL bool
+
+	// Export is the argument to --export. For vars it is the path to a file to
+	// export to; for env it is the name of the environment to export.
+	Export string
+
+	// ExportHTTP is the argument to --export-http. It is the path to a .http
+	// file to export a request template to.
+	ExportHTTP string
+
+	// ImportHTTP is the argument to --import-http. It is the path to a .http
+	// file to create request templates from.
+	ImportHTTP string
+
+	// Copy is the argument to --copy. It is the name of the request template
+	// to copy.
+	Copy string
+
+	// FromHistory is the argument to --from-history. It is the index of the
+	// history entry whose request a new request template is created from, or
+	// -1 if not given.
+	FromHistory int
+
+	// BEditorVars is a switch flag that, when set, indicates that var
+	// references should be converted to the {{NAME}} syntax of editors when
+	// exporting to a .http file.
+	BEditorVars bool
+
+	// BResolveVars is a switch flag that, when set, indicates that a request
+	// template should be printed with all vars filled in instead of sent.
+	BResolveVars bool
+
+	// Rename is the argument to --rename. It is the name of the variable to
+	// rename.
+	Rename string
+
+	// Usages is the argument to --usages. It is the name of the variable to
+	// find references to.
+	Usages string
+
+	// BUnused is a switch flag that, when set, indicates that the variables
+	// that are not referenced anywhere in the project should be listed.
+	BUnused bool
+
+	// BExcludeSecrets is a switch flag that, when set, indicates that values
+	// that look like secrets should be left out of exported data.
+	BExcludeSecrets bool
+
+	// StateFile is the path to a oneshot state file to operate on.
+	StateFile string
+
 	// BRequest is a request output control switch flag that indicates that the
 	// request should be printed in addition to any other output.
 	BRequest bool
 
+	// BVerbose is a request output control switch flag that indicates that the
+	// request, response headers, and captures should all be printed. It is the
+	// same as giving BRequest, BHeaders, and BCaptures together.
+	BVerbose bool
+
 	// BCaptures is a request output control switch flag that indicates that the
 	// captures retrieved from a response should be printed in addition to any
 	// other output.
@@ -499,6 +1051,21 @@ type cliFlags struct {
 	// body of the response should not be printed.
 	BNoBody bool
 
+	// BTimings is a request output control switch flag that indicates that the
+	// time taken by each phase of the request should be printed in addition to
+	// any other output.
+	BTimings bool
+
+	// BShowRedirects is a request output control switch flag that indicates
+	// that the chain of redirects followed to get the response should be
+	// printed before it.
+	BShowRedirects bool
+
+	// BStats is a request output control switch flag that indicates that a
+	// one-line summary of the response status, body size, and elapsed time
+	// should be printed after any other output.
+	BStats bool
+
 	// BNoDates is a historical request output control switch flag that
 	// indicates that dates of historical events should not be printed when they
 	// otherwise would.
@@ -558,4 +1125,197 @@ type cliFlags struct {
 	// BQuiet is a switch flag that, when set, suppresses all output except for
 	// output that was specifically requested.
 	BQuiet bool
+
+	// BProfileTiming is a switch flag that, when set, indicates that a summary
+	// of the time taken by a flow should be printed once it completes.
+	BProfileTiming bool
+
+	// BValidateAll is a switch flag that, when set, indicates that every item
+	// of the applicable type should be checked for validity.
+	BValidateAll bool
+
+	// BClearExpired is a switch flag that, when set, indicates that only
+	// expired items should be cleared.
+	BClearExpired bool
+
+	// BNameFromDir is a switch flag that, when set, indicates that the project
+	// name should be set to the name of the current directory.
+	BNameFromDir bool
+
+	// BCheck is a switch flag that, when set, indicates that the project should
+	// be checked for problems instead of shown or modified.
+	BCheck bool
+
+	// BDumpEffectiveConfig is a switch flag that, when set, indicates that the
+	// settings of the project should be printed as they are actually used, with
+	// all defaults applied.
+	BDumpEffectiveConfig bool
+
+	// BFollow is a switch flag that, when set, indicates that a listing should
+	// continue to be updated as new entries are added.
+	BFollow bool
+
+	// BNoSaveCaptures is a switch flag that, when set, indicates that values
+	// captured from a response should not be saved to the project.
+	BNoSaveCaptures bool
+
+	// SetJSON is fields to set in the JSON object body of a request, each given
+	// in KEY=VALUE or KEY:=JSON format.
+	SetJSON []string
+
+	// AssertStatus is the status code that a response must have.
+	AssertStatus int
+
+	// AssertBodyContains is text that the body of a response must contain.
+	AssertBodyContains []string
+
+	// Asserts is assertion expressions that are checked against the JSON body
+	// of a response.
+	Asserts []string
+
+	// StepAssertJSON is assertion expressions that are checked against the
+	// JSON bodies of responses to steps of a flow, each given in IDX:[EXPR]
+	// format.
+	StepAssertJSON []string
+
+	// StepAssertStatus is the status codes that responses to steps of a flow
+	// must have, each given in IDX:[CODE] format.
+	StepAssertStatus []string
+
+	// StepAssertBodyContains is text that the bodies of responses to steps of
+	// a flow must contain, each given in IDX:[TEXT] format.
+	StepAssertBodyContains []string
+
+	// BNoHistory is a switch flag that, when set, indicates that a request
+	// should not be recorded in history even if the project records history.
+	BNoHistory bool
+
+	// BNoSaveSession is a switch flag that, when set, indicates that cookies
+	// from a response should not be saved to the session even if the project
+	// records the session.
+	BNoSaveSession bool
+
+	// BRecord is a switch flag that, when set, indicates that a request should
+	// be recorded in history and its cookies saved to the session even if the
+	// project does not record them.
+	BRecord bool
+
+	// BDeferCaptureErrors is a switch flag that, when set, indicates that
+	// captures that fail should not stop the response from being output and
+	// recorded, and should instead cause an error once that is done.
+	BDeferCaptureErrors bool
+
+	// BNoSubstituteHeaders is a switch flag that, when set, indicates that
+	// variables in request headers should be left as they are.
+	BNoSubstituteHeaders bool
+
+	// BIPv4 is a switch flag that, when set, indicates that only IPv4 should
+	// be used to connect to remote hosts.
+	BIPv4 bool
+
+	// BIPv6 is a switch flag that, when set, indicates that only IPv6 should
+	// be used to connect to remote hosts.
+	BIPv6 bool
+
+	// BHTTP1 is a switch flag that, when set, indicates that only HTTP/1.1
+	// should be used to send requests.
+	BHTTP1 bool
+
+	// BHTTP2 is a switch flag that, when set, indicates that HTTP/2 must be
+	// used to send requests.
+	BHTTP2 bool
+
+	// CertFile is the path to a client certificate to present for mutual
+	// TLS.
+	CertFile string
+
+	// KeyFile is the path to the private key of the client certificate in
+	// CertFile.
+	KeyFile string
+
+	// CACertFile is the path to a bundle of CA certificates to verify servers
+	// with.
+	CACertFile string
+
+	// Resolve is addresses to connect to in place of the ones that hosts
+	// resolve to, each given in HOST:PORT:ADDR format.
+	Resolve []string
+
+	// BEnvFallback is a switch flag that, when set, indicates that vars that
+	// are not otherwise defined are to be taken from the OS environment.
+	BEnvFallback bool
+
+	// Description is a flag that gives the description of a request
+	// template.
+	Description string
+
+	// AddTags is the tags to add to a request template.
+	AddTags []string
+
+	// RemoveTags is the tags to remove from a request template.
+	RemoveTags []string
+
+	// Tag is the tag that request templates must have to be listed.
+	Tag string
+
+	// BPrompt is a switch flag that, when set, indicates that the user is to
+	// be asked for the value of each var that a request needs but that is not
+	// defined.
+	BPrompt bool
+
+	// HeaderOrder is the order that the headers of a request template are
+	// output in.
+	HeaderOrder string
+
+	// LocalAddr is the local source address that connections to remote hosts
+	// are made from.
+	LocalAddr string
+
+	// MaxIdleConns is the maximum number of idle connections to keep open.
+	MaxIdleConns int
+
+	// MaxConnsPerHost is the maximum number of connections to a single host.
+	MaxConnsPerHost int
+
+	// IdleTimeout is how long an idle connection is kept open. It is parsed
+	// with time.ParseDuration.
+	IdleTimeout string
+
+	// Timeout is the longest that a request may take before it is abandoned.
+	// It is parsed with time.ParseDuration.
+	Timeout string
+
+	// RequestTimeout is the request timeout setting of a project. It is parsed
+	// with time.ParseDuration.
+	RequestTimeout string
+
+	// BDumpState is a switch flag that, when set, indicates that the state of
+	// the client should be printed after a request is sent.
+	BDumpState bool
+
+	// BShowSecrets is a switch flag that, when set, indicates that values that
+	// look like secrets should not be masked in output.
+	BShowSecrets bool
+
+	// BInline is a switch flag that, when set, indicates that the full details
+	// of the request called by each step of a flow should be shown.
+	BInline bool
+
+	// BResolve is a switch flag that, when set, indicates that the request
+	// called by each step of a flow should be shown with all vars filled in.
+	BResolve bool
+
+	// BDryRun is a switch flag that, when set, indicates that requests should
+	// be built and output but not actually sent. For vars --rename, it
+	// indicates that the changes should be output but not made.
+	BDryRun bool
+
+	// BSecret is a switch flag that, when set, indicates that the variables
+	// being set are secrets whose values are masked in output. When
+	// explicitly set to false, it indicates that they are no longer secrets.
+	BSecret bool
+
+	// BReveal is a switch flag that, when set, indicates that the values of
+	// secret variables are to be shown instead of masked.
+	BReveal bool
 }
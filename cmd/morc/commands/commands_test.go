@@ -343,6 +343,16 @@ func testVarStore(curEnv string, vars map[string]map[string]string) morc.VarStor
 	return vs
 }
 
+// testVarStoreWithBaseURLs is the same as testVarStore, but also sets the
+// base URL of each environment in baseURLs.
+func testVarStoreWithBaseURLs(curEnv string, vars map[string]map[string]string, baseURLs map[string]string) morc.VarStore {
+	vs := testVarStore(curEnv, vars)
+	for env, u := range baseURLs {
+		vs.SetBaseURLIn(u, env)
+	}
+	return vs
+}
+
 func testProject_vars(curEnv string, vars map[string]map[string]string, moreVars ...map[string]map[string]string) morc.Project {
 	if len(moreVars) > 0 {
 		combined := make(map[string]map[string]string)
@@ -370,6 +380,14 @@ func testProject_vars(curEnv string, vars map[string]map[string]string, moreVars
 	}
 }
 
+// testProject_withSecrets returns p with each of names marked as a secret var.
+func testProject_withSecrets(p morc.Project, names ...string) morc.Project {
+	for _, name := range names {
+		p.Vars.SetSecret(name, true)
+	}
+	return p
+}
+
 func testReq(n int) string {
 	return fmt.Sprintf(testRequestBaseName+"%d", n)
 }
@@ -408,6 +426,41 @@ func testProject_singleFlowWithSequence(reqNums ...int) morc.Project {
 	}
 }
 
+func testProject_singleFlowWithNStepsAndRequires(n int, stepIdx int, requires ...string) morc.Project {
+	p := testProject_singleFlowWithNSteps(n)
+	p.Flows[testFlowName].Steps[stepIdx].Requires = requires
+	return p
+}
+
+func testProject_singleFlowWithNStepsAndGroups(groups ...int) morc.Project {
+	p := testProject_singleFlowWithNSteps(len(groups))
+	for i := range groups {
+		p.Flows[testFlowName].Steps[i].Group = groups[i]
+	}
+	return p
+}
+
+func testProject_singleFlowWithNStepsAndAsserts(n, idx, status int, bodyContains ...string) morc.Project {
+	p := testProject_singleFlowWithNSteps(n)
+	p.Flows[testFlowName].Steps[idx].AssertStatus = status
+	p.Flows[testFlowName].Steps[idx].AssertBodyContains = bodyContains
+	return p
+}
+
+func testProject_singleFlowWithNStepsAndJSONAsserts(n, idx int, exprs ...string) morc.Project {
+	p := testProject_singleFlowWithNSteps(n)
+	p.Flows[testFlowName].Steps[idx].AssertJSON = exprs
+	return p
+}
+
+func testProject_singleFlowWithNStepsAndAuthFlow(n int, reqName string, authFlow string) morc.Project {
+	p := testProject_singleFlowWithNSteps(n)
+	req := p.Templates[reqName]
+	req.AuthFlow = authFlow
+	p.Templates[reqName] = req
+	return p
+}
+
 func testProject_singleReqWillAllPropertiesSet() morc.Project {
 	return morc.Project{
 		Templates: map[string]morc.RequestTemplate{
@@ -514,3 +567,48 @@ func testFlows_singleFlowWithNSteps(n int) map[string]morc.Flow {
 func testFlows_singleFlowWithSequence(reqNums ...int) map[string]morc.Flow {
 	return testFlows_singleFlowWithNameAndSequence(testFlowName, reqNums...)
 }
+
+func Test_projPathFromFlagsOrFile(t *testing.T) {
+	testCases := []struct {
+		name   string
+		args   []string
+		envVar string
+		expect string
+	}{
+		{
+			name:   "default",
+			expect: morc.DefaultProjectPath,
+		},
+		{
+			name:   "env var set",
+			envVar: "env/project.json",
+			expect: "env/project.json",
+		},
+		{
+			name:   "flag set",
+			args:   []string{"-F", "flag/project.json"},
+			expect: "flag/project.json",
+		},
+		{
+			name:   "flag set overrides env var",
+			args:   []string{"-F", "flag/project.json"},
+			envVar: "env/project.json",
+			expect: "flag/project.json",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetProjFlags()
+			flags.ProjectFile = morc.DefaultProjectPath
+			t.Setenv(envVarProjectFile, tc.envVar)
+
+			if err := projCmd.ParseFlags(tc.args); !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expect, projPathFromFlagsOrFile(projCmd))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 	"sort"
 	"strings"
@@ -17,7 +18,7 @@ var cookiesCmd = &cobra.Command{
 	Annotations: map[string]string{
 		annotationKeyHelpUsages: "" +
 			"cookies [--url URL]\n" +
-			"cookies [--on | --off | --clear | --info]",
+			"cookies [--on | --off | --clear | --clear-expired | --info]",
 	},
 	GroupID: "project",
 	Short:   "View and perform operations on stored cookies",
@@ -26,7 +27,8 @@ var cookiesCmd = &cobra.Command{
 		"cookie recording is enabled for future requests made by calling morc send or morc exec. If --off is given, " +
 		"cookie recording is instead disabled, although existing cookies are kept until they expire. If --info is " +
 		"given, basic info about the cookie store as a whole is output. If --clear is given, existing cookies are " +
-		"immediately deleted.\n\n" +
+		"immediately deleted. If --clear-expired is given, only cookies that have expired, either because they are " +
+		"older than the project's cookie lifetime or because of their own Expires or Max-Age, are deleted.\n\n" +
 		"Cookie recording only applies to requests created from request templates in a project; one-off requests " +
 		"such as those sent by 'morc oneoff' or any of the method shorthand versions will not have their cookies " +
 		"associated with the project.",
@@ -49,6 +51,8 @@ var cookiesCmd = &cobra.Command{
 			return invokeCookiesInfo(io, args.projFile)
 		case cookiesActionClear:
 			return invokeCookiesClear(io, args.projFile)
+		case cookiesActionClearExpired:
+			return invokeCookiesClearExpired(io, args.projFile)
 		case cookiesActionEnable:
 			return invokeCookiesOn(io, args.projFile)
 		case cookiesActionDisable:
@@ -63,13 +67,14 @@ func init() {
 	cookiesCmd.PersistentFlags().StringVarP(&flags.ProjectFile, "project-file", "F", morc.DefaultProjectPath, "Use `FILE` for project data instead of "+morc.DefaultProjectPath+".")
 	cookiesCmd.PersistentFlags().BoolVarP(&flags.BInfo, "info", "", false, "Print summarizing information about stored cookies")
 	cookiesCmd.PersistentFlags().BoolVarP(&flags.BClear, "clear", "", false, "Delete all cookies")
+	cookiesCmd.PersistentFlags().BoolVarP(&flags.BClearExpired, "clear-expired", "", false, "Delete only expired cookies")
 	cookiesCmd.PersistentFlags().BoolVarP(&flags.BEnable, "on", "", false, "Enable cookie recording for future requests")
 	cookiesCmd.PersistentFlags().BoolVarP(&flags.BDisable, "off", "", false, "Disable cookie recording for future requests")
 	cookiesCmd.PersistentFlags().StringVarP(&flags.URL, "url", "u", "", "Get cookies that would only be set on the given URL")
 	cookiesCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
 
 	// mark the delete and default flags as mutually exclusive
-	cookiesCmd.MarkFlagsMutuallyExclusive("on", "off", "clear", "info", "url")
+	cookiesCmd.MarkFlagsMutuallyExclusive("on", "off", "clear", "clear-expired", "info", "url")
 
 	rootCmd.AddCommand(cookiesCmd)
 }
@@ -130,6 +135,25 @@ func invokeCookiesClear(io cmdio.IO, projFile string) error {
 	return nil
 }
 
+func invokeCookiesClearExpired(io cmdio.IO, projFile string) error {
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	before := p.Session.TotalCookieSets()
+	p.EvictOldCookies()
+	removed := before - p.Session.TotalCookieSets()
+
+	if err := writeSession(p); err != nil {
+		return err
+	}
+
+	io.PrintLoudf("Removed %s\n", io.CountOf(removed, "expired cookie"))
+
+	return nil
+}
+
 func invokeCookiesInfo(io cmdio.IO, projFile string) error {
 	p, err := readProject(projFile, true)
 	if err != nil {
@@ -197,8 +221,10 @@ func invokeCookiesList(io cmdio.IO, projFile string, url *url.URL) error {
 			return nil
 		}
 
+		// the jar only gives back name and value, so find the recorded
+		// cookie for the rest of the attributes.
 		for _, c := range cookies {
-			io.Printf("%s\n", c.String())
+			io.Printf("%s\n", recordedCookie(p.Session.Cookies, c).String())
 		}
 	} else {
 		// list them all
@@ -235,6 +261,20 @@ func invokeCookiesList(io cmdio.IO, projFile string, url *url.URL) error {
 	return nil
 }
 
+// recordedCookie returns the most recently recorded cookie in calls with the
+// same name and value as c, with all attributes it was set with. If there is
+// none, c itself is returned.
+func recordedCookie(calls []morc.SetCookiesCall, c *http.Cookie) *http.Cookie {
+	for i := len(calls) - 1; i >= 0; i-- {
+		for _, rec := range calls[i].Cookies {
+			if rec.Name == c.Name && rec.Value == c.Value {
+				return rec
+			}
+		}
+	}
+	return c
+}
+
 type cookiesArgs struct {
 	projFile string
 	action   cookiesAction
@@ -269,7 +309,7 @@ func parseCookiesArgs(cmd *cobra.Command, posArgs []string, args *cookiesArgs) e
 			}
 			args.url = u
 		}
-	case cookiesActionInfo, cookiesActionClear, cookiesActionEnable, cookiesActionDisable:
+	case cookiesActionInfo, cookiesActionClear, cookiesActionClearExpired, cookiesActionEnable, cookiesActionDisable:
 		// no additional args to parse
 	default:
 		panic(fmt.Sprintf("unhandled cookies action %q", args.action))
@@ -280,7 +320,7 @@ func parseCookiesArgs(cmd *cobra.Command, posArgs []string, args *cookiesArgs) e
 
 func parseCookiesActionFromFlags(cmd *cobra.Command, _ []string) (cookiesAction, error) {
 	// mutual exclusions enforced by cobra (and therefore we do not check them here):
-	// * --on, --off, --clear, --info, --url.
+	// * --on, --off, --clear, --clear-expired, --info, --url.
 
 	f := cmd.Flags()
 
@@ -290,6 +330,8 @@ func parseCookiesActionFromFlags(cmd *cobra.Command, _ []string) (cookiesAction,
 		return cookiesActionDisable, nil
 	} else if f.Changed("clear") {
 		return cookiesActionClear, nil
+	} else if f.Changed("clear-expired") {
+		return cookiesActionClearExpired, nil
 	} else if f.Changed("info") {
 		return cookiesActionInfo, nil
 	}
@@ -303,6 +345,7 @@ const (
 	cookiesActionList cookiesAction = iota
 	cookiesActionInfo
 	cookiesActionClear
+	cookiesActionClearExpired
 	cookiesActionEnable
 	cookiesActionDisable
 )
@@ -0,0 +1,254 @@
+package commands
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dekarrin/morc"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Cookies_List(t *testing.T) {
+	setTime := time.Now().UTC().Truncate(time.Second)
+	expires := setTime.Add(time.Hour)
+
+	testProject_cookies := func(calls ...morc.SetCookiesCall) morc.Project {
+		return morc.Project{
+			Templates: map[string]morc.RequestTemplate{},
+			Flows:     map[string]morc.Flow{},
+			Vars:      morc.NewVarStore(),
+			Session:   morc.Session{Cookies: calls},
+			Config: morc.Settings{
+				SeshFile:       "::PROJ_DIR::/session.json",
+				RecordSession:  true,
+				CookieLifetime: 24 * time.Hour,
+			},
+		}
+	}
+
+	exampleCall := morc.SetCookiesCall{
+		Time: setTime,
+		URL:  mustParseURL("http://example.com/"),
+		Cookies: []*http.Cookie{
+			{Name: "session", Value: "abc", Path: "/", Domain: "example.com", Expires: expires},
+			{Name: "theme", Value: "dark"},
+		},
+	}
+	otherCall := morc.SetCookiesCall{
+		Time: setTime,
+		URL:  mustParseURL("http://other.com/"),
+		Cookies: []*http.Cookie{
+			{Name: "id", Value: "413"},
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "no cookies",
+			args:               []string{"cookies"},
+			p:                  testProject_cookies(),
+			expectStdoutOutput: "(no cookies)\n",
+		},
+		{
+			name: "all cookies grouped by URL",
+			args: []string{"cookies"},
+			p:    testProject_cookies(otherCall, exampleCall),
+			expectStdoutOutput: "" +
+				"http://example.com/:\n" +
+				setTime.Format(time.RFC3339) + " session=abc; Path=/; Domain=example.com; Expires=" + expires.Format(http.TimeFormat) + "\n" +
+				setTime.Format(time.RFC3339) + " theme=dark\n" +
+				"\n" +
+				"http://other.com/:\n" +
+				setTime.Format(time.RFC3339) + " id=413\n",
+		},
+		{
+			name: "cookies for URL include recorded attributes",
+			args: []string{"cookies", "--url", "http://example.com/"},
+			p:    testProject_cookies(otherCall, exampleCall),
+			expectStdoutOutput: "" +
+				"session=abc; Path=/; Domain=example.com; Expires=" + expires.Format(http.TimeFormat) + "\n" +
+				"theme=dark\n",
+		},
+		{
+			name: "URL without scheme",
+			args: []string{"cookies", "--url", "other.com"},
+			p:    testProject_cookies(otherCall, exampleCall),
+			expectStdoutOutput: "" +
+				"id=413\n",
+		},
+		{
+			name:               "no cookies for URL",
+			args:               []string{"cookies", "--url", "http://nothing.com/"},
+			p:                  testProject_cookies(otherCall, exampleCall),
+			expectStdoutOutput: "(no cookies)\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetCookiesFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(cookiesCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_noProjectMutations(assert)
+			assert_noSessionFileMutations(assert)
+		})
+	}
+}
+
+func Test_Cookies_Clear(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	liveCall := morc.SetCookiesCall{
+		Time:    now,
+		URL:     mustParseURL("http://example.com/"),
+		Cookies: []*http.Cookie{{Name: "session", Value: "abc"}},
+	}
+	mixedCall := morc.SetCookiesCall{
+		Time: now,
+		URL:  mustParseURL("http://other.com/"),
+		Cookies: []*http.Cookie{
+			{Name: "stale", Value: "1", Expires: now.Add(-time.Hour)},
+			{Name: "fresh", Value: "2", Expires: now.Add(time.Hour)},
+		},
+	}
+	oldCall := morc.SetCookiesCall{
+		Time:    now.Add(-48 * time.Hour),
+		URL:     mustParseURL("http://example.com/"),
+		Cookies: []*http.Cookie{{Name: "ancient", Value: "413"}},
+	}
+
+	testProject_cookies := func(calls ...morc.SetCookiesCall) morc.Project {
+		return morc.Project{
+			Templates: map[string]morc.RequestTemplate{},
+			Flows:     map[string]morc.Flow{},
+			Vars:      morc.NewVarStore(),
+			Session:   morc.Session{Cookies: calls},
+			Config: morc.Settings{
+				SeshFile:       "::PROJ_DIR::/session.json",
+				RecordSession:  true,
+				CookieLifetime: 24 * time.Hour,
+			},
+		}
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectSession      morc.Session
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "clear all",
+			args:               []string{"cookies", "--clear"},
+			p:                  testProject_cookies(oldCall, liveCall, mixedCall),
+			expectSession:      morc.Session{},
+			expectStdoutOutput: "Cookies cleared",
+		},
+		{
+			name: "clear expired",
+			args: []string{"cookies", "--clear-expired"},
+			p:    testProject_cookies(oldCall, liveCall, mixedCall),
+			expectSession: morc.Session{Cookies: []morc.SetCookiesCall{
+				liveCall,
+				{Time: now, URL: mixedCall.URL, Cookies: mixedCall.Cookies[1:]},
+			}},
+			expectStdoutOutput: "Removed 2 expired cookies\n",
+		},
+		{
+			name:               "clear expired with none expired",
+			args:               []string{"cookies", "--clear-expired"},
+			p:                  testProject_cookies(liveCall),
+			expectSession:      morc.Session{Cookies: []morc.SetCookiesCall{liveCall}},
+			expectStdoutOutput: "Removed 0 expired cookies\n",
+		},
+		{
+			name:      "clear and clear expired",
+			args:      []string{"cookies", "--clear", "--clear-expired"},
+			p:         testProject_cookies(liveCall),
+			expectErr: "[clear clear-expired] were all set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetCookiesFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(cookiesCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_noProjectFileMutations(assert)
+			assert_noHistoryFileMutations(assert)
+			assert_sessionPersistedToBuffer(assert, tc.expectSession)
+		})
+	}
+}
+
+func resetCookiesFlags() {
+	flags.ProjectFile = ""
+	flags.BInfo = false
+	flags.BClear = false
+	flags.BClearExpired = false
+	flags.BEnable = false
+	flags.BDisable = false
+	flags.URL = ""
+	flags.BQuiet = false
+
+	cookiesCmd.Flags().VisitAll(func(fl *pflag.Flag) {
+		fl.Changed = false
+	})
+}
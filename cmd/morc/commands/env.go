@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 
@@ -17,7 +18,11 @@ var envCmd = &cobra.Command{
 		annotationKeyHelpUsages: "" +
 			"env [--all]\n" +
 			"env [ENV | --default]\n" +
-			"env [--delete ENV | --delete-all]",
+			"env [--delete ENV | --delete-all]\n" +
+			"env --export ENV FILE [--exclude-secrets]\n" +
+			"env --import FILE\n" +
+			"env [ENV | --default] --base-url URL\n" +
+			"env [ENV | --default] --get-base-url",
 	},
 	GroupID: "project",
 	Short:   "Show or manipulate request variable environments",
@@ -29,7 +34,23 @@ var envCmd = &cobra.Command{
 		"that environment. Doing so in the default environment would have the effect of clearning every single " +
 		"variable across all environments, so to avoid accidental erasure this operation cannot be done by specifying " +
 		"--default or --all. Instead, to clear all environments (and therefore all variables across all " +
-		"environments), use --delete-all.",
+		"environments), use --delete-all.\n\n" +
+		"An environment can be handed to another project by bundling it into a file with --export, giving the name of " +
+		"the environment and the FILE to write the bundle to. The bundle contains every variable defined in the " +
+		"environment; values that it gets from the default environment are not included. If --exclude-secrets is " +
+		"given, secret variables and variables whose names look like they hold secrets, such as TOKEN or PASSWORD, " +
+		"are left out of the bundle. A bundle is loaded into a project with --import, which sets every variable in " +
+		"it in the environment that it was exported from, creating that environment if needed.\n\n" +
+		"Each environment can have a base URL, set with --base-url. When a request template whose URL is only a " +
+		"path starting with '/', such as '/users/${ID}', is sent, the base URL of the current environment is put in " +
+		"front of it, so the whole project can be pointed at a different server by switching environments. If the " +
+		"current environment has no base URL, the one of the default environment is used. --base-url applies to " +
+		"the current environment, or to ENV or the default environment if one is given, without switching to it. " +
+		"Giving an empty URL removes the base URL. --get-base-url prints the base URL that is used in the " +
+		"environment.\n\n" +
+		"Bundles are a MORC-specific format. They are JSON objects with a \"format\" key of \"" + morc.EnvBundleFormat +
+		"\", a \"version\" key giving the version of the bundle format, an \"env\" key with the name of the " +
+		"environment, and a \"vars\" key with an object mapping each variable name to its value.",
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, posArgs []string) error {
 		var args envArgs
@@ -51,6 +72,14 @@ var envCmd = &cobra.Command{
 			return invokeEnvSwitch(io, args.projFile, args.env)
 		case envActionShow:
 			return invokeEnvShowCurrent(io, args.projFile)
+		case envActionExport:
+			return invokeEnvExport(io, args.projFile, args.env, args.file, args.excludeSecrets)
+		case envActionImport:
+			return invokeEnvImport(io, args.projFile, args.file)
+		case envActionSetBaseURL:
+			return invokeEnvSetBaseURL(io, args.projFile, args.env, args.baseURL)
+		case envActionGetBaseURL:
+			return invokeEnvGetBaseURL(io, args.projFile, args.env)
 		default:
 			return fmt.Errorf("unhandled env action %d", args.action)
 		}
@@ -63,10 +92,16 @@ func init() {
 	envCmd.PersistentFlags().BoolVarP(&flags.BDeleteAll, "delete-all", "", false, "Delete all environments and variables")
 	envCmd.PersistentFlags().BoolVarP(&flags.BAll, "all", "a", false, "List all environments instead of only the current one")
 	envCmd.PersistentFlags().BoolVarP(&flags.BDefault, "default", "", false, "Change to the default environment")
+	envCmd.PersistentFlags().StringVarP(&flags.Export, "export", "", "", "Bundle the variables in environment `ENV` into a file")
+	envCmd.PersistentFlags().StringVarP(&flags.Import, "import", "", "", "Load the environment bundled in `FILE`")
+	envCmd.PersistentFlags().BoolVarP(&flags.BExcludeSecrets, "exclude-secrets", "", false, "Leave secret and secret-looking variables out of an exported bundle")
+	envCmd.PersistentFlags().StringVarP(&flags.BaseURL, "base-url", "", "", "Set the base URL that request template URLs starting with '/' are sent to in the environment to `URL`")
+	envCmd.PersistentFlags().BoolVarP(&flags.BGetBaseURL, "get-base-url", "", false, "Print the base URL used in the environment")
 	envCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
 
 	// mark the delete and default flags as mutually exclusive
-	envCmd.MarkFlagsMutuallyExclusive("all", "default", "delete", "delete-all")
+	envCmd.MarkFlagsMutuallyExclusive("all", "default", "delete", "delete-all", "export", "import")
+	envCmd.MarkFlagsMutuallyExclusive("base-url", "get-base-url", "all", "delete", "delete-all", "export", "import")
 
 	rootCmd.AddCommand(envCmd)
 }
@@ -125,6 +160,7 @@ func invokeEnvDelete(io cmdio.IO, projFile string, env envSelection) error {
 			}
 			p.Vars.DeleteEnv(envName)
 		}
+		p.Vars.SetBaseURLIn("", "")
 	} else if env.useName != "" {
 		// delete in the specified environment
 		if sliceops.Index(p.Vars.EnvNames(), strings.ToUpper(env.useName)) < 0 {
@@ -193,10 +229,136 @@ func invokeEnvShowCurrent(io cmdio.IO, projFile string) error {
 	return nil
 }
 
+func invokeEnvExport(io cmdio.IO, projFile string, env envSelection, filename string, excludeSecrets bool) error {
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	if sliceops.Index(p.Vars.EnvNames(), strings.ToUpper(env.useName)) < 0 {
+		return fmt.Errorf("environment %q does not contain any variables", env.useName)
+	}
+
+	bundle := morc.NewEnvBundle(p.Vars, env.useName)
+
+	var excluded []string
+	if excludeSecrets {
+		for name := range bundle.Vars {
+			if isSecretVar(p.Vars, name) {
+				excluded = append(excluded, name)
+				delete(bundle.Vars, name)
+			}
+		}
+		sort.Strings(excluded)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("create %q: %w", filename, err)
+	}
+	defer f.Close()
+
+	if _, err := bundle.WriteTo(f); err != nil {
+		return fmt.Errorf("write %q: %w", filename, err)
+	}
+
+	io.PrintLoudf("Exported %s from environment %q to %s\n", io.CountOf(len(bundle.Vars), "var"), bundle.Env, filename)
+	if len(excluded) > 0 {
+		io.PrintLoudf("Excluded %s: %s\n", io.CountOf(len(excluded), "secret var"), strings.Join(excluded, ", "))
+	}
+
+	return nil
+}
+
+func invokeEnvImport(io cmdio.IO, projFile string, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("open %q: %w", filename, err)
+	}
+	defer f.Close()
+
+	bundle, err := morc.ReadEnvBundle(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range bundle.Vars {
+		name, err := morc.ParseVarName(strings.ToUpper(name))
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		p.Vars.SetIn(name, value, bundle.Env)
+	}
+
+	if err := writeProject(p, false); err != nil {
+		return err
+	}
+
+	envDesc := envDescription(bundle.Env)
+	io.PrintLoudf("Imported %s into %s\n", io.CountOf(len(bundle.Vars), "var"), envDesc)
+
+	return nil
+}
+
+func invokeEnvSetBaseURL(io cmdio.IO, projFile string, env envSelection, baseURL string) error {
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	envName := env.useName
+	if env.useCurrent {
+		envName = p.Vars.Environment
+	}
+	p.Vars.SetBaseURLIn(baseURL, envName)
+
+	if err := writeProject(p, false); err != nil {
+		return err
+	}
+
+	envDesc := envDescription(envName)
+	if baseURL == "" {
+		io.PrintLoudf("Removed base URL of %s\n", envDesc)
+	} else {
+		io.PrintLoudf("Set base URL of %s to %s\n", envDesc, baseURL)
+	}
+
+	return nil
+}
+
+func invokeEnvGetBaseURL(io cmdio.IO, projFile string, env envSelection) error {
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	// the base URL used in an environment falls back to the default's
+	if !env.useCurrent {
+		p.Vars.Environment = env.useName
+	}
+	baseURL := p.Vars.BaseURL()
+
+	if baseURL == "" {
+		io.PrintLoudf("(none)\n")
+	} else {
+		io.Printf("%s\n", baseURL)
+	}
+
+	return nil
+}
+
 type envArgs struct {
-	projFile string
-	action   envAction
-	env      envSelection
+	projFile       string
+	action         envAction
+	env            envSelection
+	file           string
+	excludeSecrets bool
+	baseURL        string
 }
 
 func parseEnvArgs(cmd *cobra.Command, posArgs []string, args *envArgs) error {
@@ -237,6 +399,24 @@ func parseEnvArgs(cmd *cobra.Command, posArgs []string, args *envArgs) error {
 		}
 	case envActionShow:
 		// nothing else to grab
+	case envActionExport:
+		args.env.useName = flags.Export
+		args.file = posArgs[0]
+		args.excludeSecrets = flags.BExcludeSecrets
+	case envActionImport:
+		args.file = flags.Import
+	case envActionSetBaseURL, envActionGetBaseURL:
+		if f.Changed("default") {
+			args.env.useDefault = true
+		} else if len(posArgs) > 0 {
+			args.env.useName = posArgs[0]
+			if args.env.useName == reservedDefaultEnvName {
+				return fmt.Errorf("cannot specify reserved name %q; use --default to select the default env", reservedDefaultEnvName)
+			}
+		} else {
+			args.env.useCurrent = true
+		}
+		args.baseURL = flags.BaseURL
 	default:
 		panic(fmt.Sprintf("unhandled vars action %q", args.action))
 	}
@@ -247,6 +427,45 @@ func parseEnvArgs(cmd *cobra.Command, posArgs []string, args *envArgs) error {
 func parseEnvActionFromFlags(cmd *cobra.Command, posArgs []string) (envAction, error) {
 	f := cmd.Flags()
 
+	if flags.BExcludeSecrets && !f.Changed("export") {
+		return envActionExport, fmt.Errorf("--exclude-secrets can only be used with --export")
+	}
+
+	if f.Changed("base-url") || f.Changed("get-base-url") {
+		action := envActionSetBaseURL
+		if f.Changed("get-base-url") {
+			action = envActionGetBaseURL
+		}
+		if len(posArgs) > 1 {
+			return action, fmt.Errorf("unknown positional argument %q", posArgs[1])
+		}
+		if len(posArgs) > 0 && f.Changed("default") {
+			return action, fmt.Errorf("unknown positional argument %q", posArgs[0])
+		}
+		return action, nil
+	}
+
+	if f.Changed("export") {
+		if len(posArgs) < 1 {
+			return envActionExport, fmt.Errorf("missing FILE to export to")
+		}
+		if len(posArgs) > 1 {
+			return envActionExport, fmt.Errorf("unknown positional argument %q", posArgs[1])
+		}
+		if flags.Export == "" || flags.Export == reservedDefaultEnvName {
+			return envActionExport, fmt.Errorf("cannot export the default env; use vars --export --default instead")
+		}
+		return envActionExport, nil
+	} else if f.Changed("import") {
+		if len(posArgs) > 0 {
+			return envActionImport, fmt.Errorf("unknown positional argument %q", posArgs[0])
+		}
+		if flags.Import == "" {
+			return envActionImport, fmt.Errorf("--import requires a file to import from")
+		}
+		return envActionImport, nil
+	}
+
 	if f.Changed("delete") {
 		if len(posArgs) > 1 {
 			return envActionDelete, fmt.Errorf("unknown positional argument %q", posArgs[1])
@@ -294,4 +513,8 @@ const (
 	envActionDelete
 	envActionSwitch
 	envActionShow
+	envActionExport
+	envActionImport
+	envActionSetBaseURL
+	envActionGetBaseURL
 )
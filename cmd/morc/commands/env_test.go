@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -117,7 +119,7 @@ func Test_Env_Delete(t *testing.T) {
 					"env1": {"var": "1"},
 				}),
 			},
-			expectErr: "if any flags in the group [all default delete delete-all] are set none of the others can be",
+			expectErr: "if any flags in the group [all default delete delete-all export import] are set none of the others can be",
 		},
 		{
 			name: "using reserved constant to delete default errors",
@@ -504,11 +506,404 @@ func Test_Env_ShowCurrent(t *testing.T) {
 	}
 }
 
+func Test_Env_SetBaseURL(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectP            morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name: "set in current environment",
+			args: []string{"env", "--base-url", "https://staging.example.com"},
+			p: morc.Project{
+				Vars: testVarStore("STAGING", map[string]map[string]string{
+					"": {"var": "1"},
+				}),
+			},
+			expectP: morc.Project{
+				Vars: testVarStoreWithBaseURLs("STAGING", map[string]map[string]string{
+					"": {"var": "1"},
+				}, map[string]string{"STAGING": "https://staging.example.com"}),
+			},
+			expectStdoutOutput: "Set base URL of environment \"STAGING\" to https://staging.example.com\n",
+		},
+		{
+			name: "set in named environment",
+			args: []string{"env", "prod", "--base-url", "https://example.com/api"},
+			p: morc.Project{
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"var": "1"},
+				}),
+			},
+			expectP: morc.Project{
+				Vars: testVarStoreWithBaseURLs("", map[string]map[string]string{
+					"": {"var": "1"},
+				}, map[string]string{"PROD": "https://example.com/api"}),
+			},
+			expectStdoutOutput: "Set base URL of environment \"PROD\" to https://example.com/api\n",
+		},
+		{
+			name: "set in default environment",
+			args: []string{"env", "--default", "--base-url", "http://localhost:8080"},
+			p: morc.Project{
+				Vars: testVarStore("STAGING", map[string]map[string]string{
+					"": {"var": "1"},
+				}),
+			},
+			expectP: morc.Project{
+				Vars: testVarStoreWithBaseURLs("STAGING", map[string]map[string]string{
+					"": {"var": "1"},
+				}, map[string]string{"": "http://localhost:8080"}),
+			},
+			expectStdoutOutput: "Set base URL of the default environment to http://localhost:8080\n",
+		},
+		{
+			name: "remove",
+			args: []string{"env", "--base-url", ""},
+			p: morc.Project{
+				Vars: testVarStoreWithBaseURLs("", map[string]map[string]string{
+					"": {"var": "1"},
+				}, map[string]string{"": "http://localhost:8080"}),
+			},
+			expectP: morc.Project{
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"var": "1"},
+				}),
+			},
+			expectStdoutOutput: "Removed base URL of the default environment\n",
+		},
+		{
+			name: "default and named environment",
+			args: []string{"env", "prod", "--default", "--base-url", "http://localhost:8080"},
+			p: morc.Project{
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"var": "1"},
+				}),
+			},
+			expectErr: "unknown positional argument \"prod\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetEnvFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(envCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			}
+
+			if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got none", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_projectFilesInBuffersMatch(assert, tc.expectP)
+		})
+	}
+}
+
+func Test_Env_GetBaseURL(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name: "current environment",
+			args: []string{"env", "--get-base-url"},
+			p: morc.Project{
+				Vars: testVarStoreWithBaseURLs("STAGING", map[string]map[string]string{
+					"": {"var": "1"},
+				}, map[string]string{"": "http://localhost:8080", "STAGING": "https://staging.example.com"}),
+			},
+			expectStdoutOutput: "https://staging.example.com\n",
+		},
+		{
+			name: "falls back to default environment",
+			args: []string{"env", "prod", "--get-base-url"},
+			p: morc.Project{
+				Vars: testVarStoreWithBaseURLs("STAGING", map[string]map[string]string{
+					"": {"var": "1"},
+				}, map[string]string{"": "http://localhost:8080", "STAGING": "https://staging.example.com"}),
+			},
+			expectStdoutOutput: "http://localhost:8080\n",
+		},
+		{
+			name: "none set",
+			args: []string{"env", "--default", "--get-base-url"},
+			p: morc.Project{
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"var": "1"},
+				}),
+			},
+			expectStdoutOutput: "(none)\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetEnvFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(envCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			}
+
+			if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got none", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_noProjectMutations(assert)
+		})
+	}
+}
+
+func Test_Env_Export(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file. ::FILE:: is replaced with the path to the bundle file
+		p                  morc.Project
+		expectFileContent  string
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "export env",
+			args:               []string{"env", "--export", "prod", "::FILE::"},
+			p:                  testProject_vars("", test_3EnvVarsMap),
+			expectFileContent:  "{\n  \"format\": \"morc-env-bundle\",\n  \"version\": 1,\n  \"env\": \"PROD\",\n  \"vars\": {\n    \"HOST\": \"example.com\",\n    \"SCHEME\": \"https\"\n  }\n}\n",
+			expectStdoutOutput: "Exported 2 vars from environment \"PROD\" to ::FILE::\n",
+		},
+		{
+			name:               "exclude secrets",
+			args:               []string{"env", "--export", "PROD", "::FILE::", "--exclude-secrets"},
+			p:                  testProject_vars("", test_3EnvVarsMap, map[string]map[string]string{"": {"API_TOKEN": ""}, "PROD": {"API_TOKEN": "8675309"}}),
+			expectFileContent:  "{\n  \"format\": \"morc-env-bundle\",\n  \"version\": 1,\n  \"env\": \"PROD\",\n  \"vars\": {\n    \"HOST\": \"example.com\",\n    \"SCHEME\": \"https\"\n  }\n}\n",
+			expectStdoutOutput: "Exported 2 vars from environment \"PROD\" to ::FILE::\nExcluded 1 secret var: API_TOKEN\n",
+		},
+		{
+			name: "exclude vars marked as secret",
+			args: []string{"env", "--export", "PROD", "::FILE::", "--exclude-secrets"},
+			p: func() morc.Project {
+				p := testProject_vars("", test_3EnvVarsMap)
+				p.Vars.SetSecret("HOST", true)
+				return p
+			}(),
+			expectFileContent:  "{\n  \"format\": \"morc-env-bundle\",\n  \"version\": 1,\n  \"env\": \"PROD\",\n  \"vars\": {\n    \"SCHEME\": \"https\"\n  }\n}\n",
+			expectStdoutOutput: "Exported 1 var from environment \"PROD\" to ::FILE::\nExcluded 1 secret var: HOST\n",
+		},
+		{
+			name:      "env does not exist",
+			args:      []string{"env", "--export", "STAGING", "::FILE::"},
+			p:         testProject_vars("", test_3EnvVarsMap),
+			expectErr: "environment \"STAGING\" does not contain any variables",
+		},
+		{
+			name:      "default env",
+			args:      []string{"env", "--export", reservedDefaultEnvName, "::FILE::"},
+			p:         testProject_vars("", test_3EnvVarsMap),
+			expectErr: "cannot export the default env",
+		},
+		{
+			name:      "missing file",
+			args:      []string{"env", "--export", "PROD"},
+			p:         testProject_vars("", test_3EnvVarsMap),
+			expectErr: "missing FILE to export to",
+		},
+		{
+			name:      "exclude secrets without export",
+			args:      []string{"env", "--exclude-secrets"},
+			p:         testProject_vars("", test_3EnvVarsMap),
+			expectErr: "--exclude-secrets can only be used with --export",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetEnvFlags()
+
+			bundlePath := filepath.Join(t.TempDir(), "bundle.json")
+			args := make([]string, len(tc.args))
+			for i := range tc.args {
+				args[i] = strings.ReplaceAll(tc.args[i], "::FILE::", bundlePath)
+			}
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(envCmd, projFilePath, args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(strings.ReplaceAll(tc.expectStdoutOutput, "::FILE::", bundlePath), output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			content, err := os.ReadFile(bundlePath)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectFileContent, string(content))
+
+			assert_noProjectMutations(assert)
+		})
+	}
+}
+
+func Test_Env_Import(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file. ::FILE:: is replaced with the path to the bundle file
+		fileContent        string
+		p                  morc.Project
+		expectP            morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "import into new env",
+			args:               []string{"env", "--import", "::FILE::"},
+			fileContent:        `{"format": "morc-env-bundle", "version": 1, "env": "STAGING", "vars": {"HOST": "staging.example.com"}}`,
+			p:                  testProject_vars("", test_3EnvVarsMap),
+			expectP:            testProject_vars("", test_3EnvVarsMap, map[string]map[string]string{"STAGING": {"HOST": "staging.example.com"}}),
+			expectStdoutOutput: "Imported 1 var into environment \"STAGING\"\n",
+		},
+		{
+			name:               "import into existing env",
+			args:               []string{"env", "--import", "::FILE::"},
+			fileContent:        `{"format": "morc-env-bundle", "version": 1, "env": "PROD", "vars": {"HOST": "api.example.com", "token": "8675309"}}`,
+			p:                  testProject_vars("", test_3EnvVarsMap),
+			expectP:            testProject_vars("", test_3EnvVarsMap, map[string]map[string]string{"": {"TOKEN": ""}, "PROD": {"HOST": "api.example.com", "TOKEN": "8675309"}}),
+			expectStdoutOutput: "Imported 2 vars into environment \"PROD\"\n",
+		},
+		{
+			name:        "not a bundle",
+			args:        []string{"env", "--import", "::FILE::"},
+			fileContent: `{"HOST": "example.com"}`,
+			p:           testProject_vars("", test_3EnvVarsMap),
+			expectErr:   "not a MORC env bundle",
+		},
+		{
+			name:        "unsupported version",
+			args:        []string{"env", "--import", "::FILE::"},
+			fileContent: `{"format": "morc-env-bundle", "version": 2, "env": "PROD", "vars": {}}`,
+			p:           testProject_vars("", test_3EnvVarsMap),
+			expectErr:   "unsupported env bundle version 2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetEnvFlags()
+
+			bundlePath := filepath.Join(t.TempDir(), "bundle.json")
+			if err := os.WriteFile(bundlePath, []byte(tc.fileContent), 0644); err != nil {
+				t.Fatalf("write bundle file: %v", err)
+			}
+			args := make([]string, len(tc.args))
+			for i := range tc.args {
+				args[i] = strings.ReplaceAll(tc.args[i], "::FILE::", bundlePath)
+			}
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(envCmd, projFilePath, args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_projectFilesInBuffersMatch(assert, tc.expectP)
+		})
+	}
+}
+
 func resetEnvFlags() {
 	flags.Delete = ""
 	flags.BDeleteAll = false
 	flags.BAll = false
 	flags.BDefault = false
+	flags.Export = ""
+	flags.Import = ""
+	flags.BExcludeSecrets = false
+	flags.BaseURL = ""
+	flags.BGetBaseURL = false
 	flags.BQuiet = false
 
 	envCmd.Flags().VisitAll(func(fl *pflag.Flag) {
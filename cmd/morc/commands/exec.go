@@ -1,11 +1,16 @@
 package commands
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dekarrin/morc"
 	"github.com/dekarrin/morc/cmd/morc/cmdio"
+	"github.com/dekarrin/rosed"
 	"github.com/spf13/cobra"
 )
 
@@ -13,10 +18,29 @@ var execCmd = &cobra.Command{
 	Use: "exec FLOW",
 	Annotations: map[string]string{
 		annotationKeyHelpUsages: "" +
-			"exec FLOW [-k] [-p PREFIX] [-V VAR=VALUE]... [output-flags]",
+			"exec FLOW [-k46] [--http1 | --http2] [--local-addr ADDR] [--resolve HOST:PORT:ADDR]... [--cert FILE [--key FILE]] [--cacert FILE] [--timeout DURATION] [--max-idle-conns N] [--max-conns-per-host N] [--idle-timeout DURATION] [-p PREFIX] [-V VAR=VALUE]... [--profile-timing] [output-flags]\n" +
+			"exec FLOW --dry-run [-p PREFIX] [-V VAR=VALUE]... [-f FORMAT]",
 	},
-	Short:   "Execute a flow of requests",
-	Long:    "Execute a sequence of requests defined in a flow stored in the project. Initial variable values can be set with -V and will override any in the store before the first request in the flow is executed.",
+	Short: "Execute a flow of requests",
+	Long: "Execute a sequence of requests defined in a flow stored in the project. Initial variable values can be set with -V and will override any in the store before the first request in the flow is executed.\n\n" +
+		"Each step is sent in order and its response is output as with 'morc send'. Any variables captured from a response " +
+		"are used by the steps after it, and every step is recorded in history if history is enabled. Before anything is " +
+		"sent, the flow is checked; if any step calls a request template that does not exist or that does not have both a " +
+		"method and a URL set, an error naming the step is returned and none of the flow is executed. If a step fails once " +
+		"the flow has started, the flow stops and the steps after it are not sent.\n\n" +
+		"Consecutive steps in the flow that share a parallel group (set with flows --group) are sent concurrently, and the " +
+		"flow does not continue until all of them have completed. Output from each step in a parallel group is shown in step " +
+		"order once the entire group is complete.\n\n" +
+		"Connections to remote hosts are reused between the requests in the flow. How many are kept open and for how long " +
+		"can be tuned with --max-idle-conns, --max-conns-per-host, and --idle-timeout, which can help when parallel groups " +
+		"send many requests to the same host at once.\n\n" +
+		"If --profile-timing is given, a summary of how long the flow took is printed once it completes. It lists the time " +
+		"each step took, the minimum and average times of any request template that is called by more than one step, the " +
+		"slowest step, and the total wall time of the flow. Because the steps in a parallel group are sent at the same time, " +
+		"the total wall time may be less than the sum of the step times.\n\n" +
+		"If --dry-run is given, each request in the flow is built and printed with all variables substituted, but nothing is sent. " +
+		"Variables that would be captured by an earlier step are left unsubstituted in the output. A dry run does not modify history, " +
+		"session, or variables.",
 	Args:    cobra.ExactArgs(1),
 	GroupID: "sending",
 	RunE: func(cmd *cobra.Command, posArgs []string) error {
@@ -30,7 +54,10 @@ var execCmd = &cobra.Command{
 		io := cmdio.From(cmd)
 		io.Quiet = flags.BQuiet
 
-		return invokeExec(io, args.projFile, args.flow, args.oneTimeVars, args.skipVerify, args.prefixOverride, args.outputCtrl)
+		if args.dryRun {
+			return invokeExecDryRun(io, args.projFile, args.flow, args.oneTimeVars, args.prefixOverride, args.outputCtrl)
+		}
+		return invokeExec(io, args.projFile, args.flow, args.oneTimeVars, args.skipVerify, args.profileTiming, args.prefixOverride, args.outputCtrl, args.transport)
 	},
 }
 
@@ -40,14 +67,25 @@ func init() {
 	execCmd.PersistentFlags().BoolVarP(&flags.BInsecure, "insecure", "k", false, "Disable all verification of server certificates when sending requests over TLS (HTTPS)")
 	execCmd.PersistentFlags().StringVarP(&flags.VarPrefix, "var-prefix", "p", "", "Temporarily override the prefix used to identify variables in the request templates in the executed flow. Only variables in the request templates that start with `PREFIX` will be interpreted as variables.")
 	execCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
+	execCmd.PersistentFlags().BoolVarP(&flags.BDryRun, "dry-run", "", false, "Print each request in the flow as it would be sent, but do not send any of them.")
+	execCmd.PersistentFlags().BoolVarP(&flags.BProfileTiming, "profile-timing", "", false, "Print a summary of the time taken by each step and by the flow as a whole once the flow completes.")
 
 	addRequestOutputFlags(execCmd)
+	addTransportFlags(execCmd)
+
+	execCmd.MarkFlagsMutuallyExclusive("dry-run", "insecure")
+	execCmd.MarkFlagsMutuallyExclusive("dry-run", "headers")
+	execCmd.MarkFlagsMutuallyExclusive("dry-run", "captures")
+	execCmd.MarkFlagsMutuallyExclusive("dry-run", "no-body")
+	execCmd.MarkFlagsMutuallyExclusive("dry-run", "profile-timing")
 
 	rootCmd.AddCommand(execCmd)
 }
 
 // invokeExec receives the name of the flow to execute and the options to use.
-func invokeExec(io cmdio.IO, projFile, flowName string, initialVarOverrides map[string]string, skipVerify bool, prefixOverride optionalC[string], oc morc.OutputControl) error {
+// If profileTiming is set, a summary of the time taken by the flow is printed
+// after it completes.
+func invokeExec(io cmdio.IO, projFile, flowName string, initialVarOverrides map[string]string, skipVerify, profileTiming bool, prefixOverride optionalC[string], oc morc.OutputControl, to transportOptions) error {
 	// load the project file
 	p, err := readProject(projFile, true)
 	if err != nil {
@@ -57,10 +95,327 @@ func invokeExec(io cmdio.IO, projFile, flowName string, initialVarOverrides map[
 	// case doesn't matter for flow names
 	flowName = strings.ToLower(flowName)
 
+	varOverrides := make(map[string]string)
+	// copy in the one-time vars
+	for k, v := range initialVarOverrides {
+		varOverrides[strings.ToUpper(k)] = v
+	}
+
+	varPrefix := prefixOverride.Or(p.VarPrefix())
+
+	oc.Writer = io.Out
+	flowStart := time.Now()
+	timings, err := runFlow(io, &p, flowName, varOverrides, skipVerify, true, varPrefix, oc, to)
+	if err != nil {
+		return err
+	}
+
+	if profileTiming {
+		io.Printf("\n%s", flowTimingSummary(flowName, timings, time.Since(flowStart)))
+	}
+
+	return nil
+}
+
+// runFlow sends every step of the flow with the given name in order and
+// records the results in p. Output for each step is written according to oc,
+// except for the output of parallel steps, which is written to io. When a step
+// captures a var, it is removed from varOverrides so that later steps use the
+// captured value. Captured vars are only persisted to the project file if
+// saveCaptures is set. The time taken by each step is returned in step order.
+// If any step fails, the flow stops and the steps after it are not sent.
+func runFlow(io cmdio.IO, p *morc.Project, flowName string, varOverrides map[string]string, skipVerify, saveCaptures bool, varPrefix string, oc morc.OutputControl, to transportOptions) ([]stepTiming, error) {
+	flow, templates, err := getExecableFlow(*p, flowName)
+	if err != nil {
+		return nil, err
+	}
+
+	// make shore every step that requires vars will have them by the time it
+	// is reached, so that a mis-ordered flow fails before anything is sent.
+	if err := checkFlowRequires(flow, templates, p.Vars.MergedSet(varOverrides), varPrefix); err != nil {
+		return nil, fmt.Errorf("flow %s: %w", flowName, err)
+	}
+
+	var timings []stepTiming
+	for _, batch := range flow.Batches() {
+		var results []morc.SendResult
+
+		if len(batch) == 1 {
+			// persistence should be covered in sendTemplate
+			i := batch[0]
+			asserts, err := stepAssertions(flow.Steps[i])
+			if err != nil {
+				return nil, fmt.Errorf("step #%d: %w", i, err)
+			}
+			opts := sendOptions{
+				skipVerify:     skipVerify,
+				noSaveCaptures: !saveCaptures,
+				asserts:        asserts,
+				oc:             oc,
+				transport:      to,
+			}
+			result, err := sendTemplate(p, templates[i], p.Vars.MergedSet(varOverrides), varPrefix, opts)
+			if err != nil {
+				return nil, fmt.Errorf("step #%d: %w", i, err)
+			}
+			results = append(results, result)
+		} else {
+			results, err = sendParallelSteps(io, p, batch, flow.Steps, templates, varOverrides, skipVerify, saveCaptures, varPrefix, oc, to)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for n, result := range results {
+			timings = append(timings, stepTiming{
+				step:     batch[n],
+				template: templates[batch[n]].Name,
+				elapsed:  result.RecvTime.Sub(result.SendTime),
+			})
+		}
+
+		// okay, need to update the varOverrides because if any were just
+		// captured, THAT is the new canonical value of the var
+		for _, result := range results {
+			for k := range result.Captures {
+				delete(varOverrides, strings.ToUpper(k))
+			}
+		}
+	}
+
+	return timings, nil
+}
+
+// stepTiming is how long a single step of a flow took to send.
+type stepTiming struct {
+	step     int
+	template string
+	elapsed  time.Duration
+}
+
+// flowTimingSummary gives the timing summary output by exec --profile-timing for
+// the flow with the given name. timings must be in step order.
+func flowTimingSummary(flowName string, timings []stepTiming, wall time.Duration) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Timing for flow %s:\n", flowName))
+
+	const minWidth = 8
+
+	stepTable := [][]string{{"STEP", "REQUEST", "TIME"}}
+	var slowest stepTiming
+	var templateOrder []string
+	byTemplate := map[string][]time.Duration{}
+	for i, st := range timings {
+		stepTable = append(stepTable, []string{fmt.Sprintf("#%d", st.step), st.template, roundTiming(st.elapsed).String()})
+
+		if i == 0 || st.elapsed > slowest.elapsed {
+			slowest = st
+		}
+		if _, ok := byTemplate[st.template]; !ok {
+			templateOrder = append(templateOrder, st.template)
+		}
+		byTemplate[st.template] = append(byTemplate[st.template], st.elapsed)
+	}
+	sb.WriteString(rosed.Editor{}.InsertTableOpts(rosed.End, stepTable, minWidth, rosed.Options{TableHeaders: true}).String())
+
+	repeatTable := [][]string{{"REQUEST", "SENDS", "MIN", "AVG"}}
+	for _, name := range templateOrder {
+		times := byTemplate[name]
+		if len(times) < 2 {
+			continue
+		}
+
+		fastest, total := times[0], time.Duration(0)
+		for _, t := range times {
+			if t < fastest {
+				fastest = t
+			}
+			total += t
+		}
+		avg := total / time.Duration(len(times))
+		repeatTable = append(repeatTable, []string{name, fmt.Sprintf("%d", len(times)), roundTiming(fastest).String(), roundTiming(avg).String()})
+	}
+	if len(repeatTable) > 1 {
+		sb.WriteString("\n")
+		sb.WriteString(rosed.Editor{}.InsertTableOpts(rosed.End, repeatTable, minWidth, rosed.Options{TableHeaders: true}).String())
+	}
+
+	sb.WriteString("\n")
+	if len(timings) > 0 {
+		sb.WriteString(fmt.Sprintf("Slowest step: #%d %s (%s)\n", slowest.step, slowest.template, roundTiming(slowest.elapsed)))
+	}
+	sb.WriteString(fmt.Sprintf("Total wall time: %s\n", roundTiming(wall)))
+
+	return sb.String()
+}
+
+// roundTiming rounds d to a precision that is useful for showing how long a
+// request took.
+func roundTiming(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
+
+// sendParallelSteps concurrently sends the templates called by each of the
+// steps whose indexes are in batch. Output from each step is buffered and
+// written to io in step order once all have completed. Results are then
+// recorded in p one step at a time in step order so that the shared project
+// state is never modified concurrently; if multiple steps capture the same
+// variable, the value from the latest step wins. Captured vars are only
+// persisted to the project file if saveCaptures is set. If any of the steps
+// fail, every step that still got a response is recorded before the errors of
+// the failed steps are returned together.
+func sendParallelSteps(io cmdio.IO, p *morc.Project, batch []int, steps []morc.FlowStep, templates []morc.RequestTemplate, varOverrides map[string]string, skipVerify, saveCaptures bool, varSymbol string, oc morc.OutputControl, to transportOptions) ([]morc.SendResult, error) {
+	results := make([]morc.SendResult, len(batch))
+	errs := make([]error, len(batch))
+	outputs := make([]*bytes.Buffer, len(batch))
+	masks := make([][]string, len(batch))
+
+	var wg sync.WaitGroup
+	batchStart := time.Now()
+	for n, stepIdx := range batch {
+		tmpl := templates[stepIdx]
+
+		outputs[n] = &bytes.Buffer{}
+		stepOC := oc
+		stepOC.Writer = outputs[n]
+
+		sendOpts, err := templateSendOptions(p, tmpl, p.Vars.MergedSet(varOverrides), skipVerify, stepOC, to)
+		if err != nil {
+			return nil, fmt.Errorf("step #%d: %w", stepIdx, err)
+		}
+		asserts, err := stepAssertions(steps[stepIdx])
+		if err != nil {
+			return nil, fmt.Errorf("step #%d: %w", stepIdx, err)
+		}
+		asserts.applyTo(&sendOpts)
+		masks[n] = sendOpts.Output.Mask
+
+		wg.Add(1)
+		go func(n int, tmpl morc.RequestTemplate, sendOpts morc.SendOptions) {
+			defer wg.Done()
+			results[n], errs[n] = morc.Send(tmpl.Method, tmpl.URL, varSymbol, sendOpts)
+		}(n, tmpl, sendOpts)
+	}
+	wg.Wait()
+
+	mergeParallelCookies(results, batchStart)
+
+	var stepErrs []error
+	for n, stepIdx := range batch {
+		io.Printf("%s", outputs[n].String())
+		if errs[n] != nil {
+			stepErrs = append(stepErrs, fmt.Errorf("step #%d: %w", stepIdx, errs[n]))
+		}
+	}
+
+	for n, stepIdx := range batch {
+		// a step that failed before it got a response has nothing to record
+		if results[n].Response == nil {
+			continue
+		}
+		if err := recordSendResult(p, templates[stepIdx], results[n], masks[n], saveCaptures, to.record); err != nil {
+			return nil, fmt.Errorf("step #%d: %w", stepIdx, err)
+		}
+	}
+
+	if len(stepErrs) == 1 {
+		return nil, stepErrs[0]
+	} else if len(stepErrs) > 1 {
+		return nil, errors.Join(stepErrs...)
+	}
+
+	return results, nil
+}
+
+// mergeParallelCookies sets the cookies of each of results to those that the
+// session holds once that result and every one before it is recorded. Every
+// step in a parallel group starts from the same session, so the Set-Cookie
+// calls made by each, which are those made at or after since, are added to the
+// session in step order rather than each step's cookies replacing the rest.
+func mergeParallelCookies(results []morc.SendResult, since time.Time) {
+	var merged []morc.SetCookiesCall
+	haveSession := false
+	for n := range results {
+		if results[n].Response == nil {
+			continue
+		}
+
+		for _, call := range results[n].Cookies {
+			if call.Time.Before(since) {
+				// this was already in the session, which only needs to be
+				// taken from one of the results
+				if !haveSession {
+					merged = append(merged, call)
+				}
+				continue
+			}
+			merged = append(merged, call)
+		}
+		haveSession = true
+
+		results[n].Cookies = append([]morc.SetCookiesCall(nil), merged...)
+	}
+}
+
+// invokeExecDryRun receives the name of the flow to print the requests of and
+// the options to use. No request is actually sent, and nothing in the project is
+// modified.
+func invokeExecDryRun(io cmdio.IO, projFile, flowName string, initialVarOverrides map[string]string, prefixOverride optionalC[string], oc morc.OutputControl) error {
+	// load the project file
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	// case doesn't matter for flow names
+	flowName = strings.ToLower(flowName)
+
+	flow, templates, err := getExecableFlow(p, flowName)
+	if err != nil {
+		return err
+	}
+
+	varOverrides := make(map[string]string)
+	// copy in the one-time vars
+	for k, v := range initialVarOverrides {
+		varOverrides[strings.ToUpper(k)] = v
+	}
+
+	varPrefix := prefixOverride.Or(p.VarPrefix())
+
+	if err := checkFlowRequires(flow, templates, p.Vars.MergedSet(varOverrides), varPrefix); err != nil {
+		return fmt.Errorf("flow %s: %w", flowName, err)
+	}
+
+	oc.Writer = io.Out
+	var captured []string
+	for i, tmpl := range templates {
+		if err := dryRunTemplate(&p, tmpl, p.Vars.MergedSet(varOverrides), captured, varPrefix, oc); err != nil {
+			return fmt.Errorf("step #%d: %w", i, err)
+		}
+
+		// we won't know the actual value of anything captured, so from here on
+		// out, leave those vars as they are in the template.
+		for k := range tmpl.Captures {
+			captured = append(captured, strings.ToUpper(k))
+		}
+	}
+
+	return nil
+}
+
+// getExecableFlow gets the flow with the given name from p along with the
+// templates called by each of its steps, in order. An error is returned if the
+// flow does not exist or if any of its steps call a template that does not
+// exist or cannot be sent.
+func getExecableFlow(p morc.Project, flowName string) (morc.Flow, []morc.RequestTemplate, error) {
 	// check if the project even has a flow with that name
 	flow, ok := p.Flows[flowName]
 	if !ok {
-		return fmt.Errorf("no flow named %s", flowName)
+		return flow, nil, fmt.Errorf("no flow named %s", flowName)
 	}
 
 	// now get all the templates and ensure they are valid
@@ -68,35 +423,44 @@ func invokeExec(io cmdio.IO, projFile, flowName string, initialVarOverrides map[
 	for i, step := range flow.Steps {
 		tmpl, ok := p.Templates[strings.ToLower(step.Template)]
 		if !ok {
-			return fmt.Errorf("flow %s calls non-existent request template %q in step #%d", flowName, step.Template, i-1)
+			return flow, nil, fmt.Errorf("flow %s calls non-existent request template %q in step #%d", flowName, step.Template, i)
 		}
 		if !tmpl.Sendable() {
-			return fmt.Errorf("flow %s calls incomplete request template %s in step #%d", flowName, step.Template, i-1)
+			return flow, nil, fmt.Errorf("flow %s calls incomplete request template %s in step #%d", flowName, step.Template, i)
 		}
 
 		templates = append(templates, tmpl)
 	}
 
-	varOverrides := make(map[string]string)
-	// copy in the one-time vars
-	for k, v := range initialVarOverrides {
-		varOverrides[strings.ToUpper(k)] = v
-	}
+	return flow, templates, nil
+}
 
-	varPrefix := prefixOverride.Or(p.VarPrefix())
+// checkFlowRequires checks that the variables required by each step in flow
+// will be available when that step is executed. A variable is considered
+// available if it is in initialVars or if it is captured by a step prior to the
+// one that requires it and not in the same parallel group. templates must be
+// the templates called by each step of flow, in order.
+func checkFlowRequires(flow morc.Flow, templates []morc.RequestTemplate, initialVars map[string]string, varPrefix string) error {
+	available := make(map[string]bool)
+	for k := range initialVars {
+		available[strings.ToUpper(k)] = true
+	}
 
-	oc.Writer = io.Out
-	for i, tmpl := range templates {
-		// persistence should be covered in sendTemplate
-		result, err := sendTemplate(&p, tmpl, p.Vars.MergedSet(varOverrides), skipVerify, varPrefix, oc)
-		if err != nil {
-			return fmt.Errorf("step #%d: %w", i, err)
+	// steps in the same parallel group cannot rely on each other's captures,
+	// so only mark captures available once the entire batch is checked.
+	for _, batch := range flow.Batches() {
+		for _, i := range batch {
+			for _, req := range flow.Steps[i].Requires {
+				if !available[strings.ToUpper(req)] {
+					return fmt.Errorf("step #%d requires %s{%s}, but it is not set by any prior step or by the var store", i, varPrefix, strings.ToUpper(req))
+				}
+			}
 		}
 
-		// okay, need to update the varOverrides because if any were just
-		// captured, THAT is the new canonical value of the var
-		for k := range result.Captures {
-			delete(varOverrides, strings.ToUpper(k))
+		for _, i := range batch {
+			for capName := range templates[i].Captures {
+				available[strings.ToUpper(capName)] = true
+			}
 		}
 	}
 
@@ -111,6 +475,9 @@ type execArgs struct {
 	outputCtrl     morc.OutputControl
 	skipVerify     bool
 	prefixOverride optionalC[string]
+	dryRun         bool
+	profileTiming  bool
+	transport      transportOptions
 }
 
 func parseExecArgs(cmd *cobra.Command, posArgs []string, args *execArgs) error {
@@ -120,6 +487,8 @@ func parseExecArgs(cmd *cobra.Command, posArgs []string, args *execArgs) error {
 	}
 
 	args.skipVerify = flags.BInsecure
+	args.dryRun = flags.BDryRun
+	args.profileTiming = flags.BProfileTiming
 
 	var err error
 	args.outputCtrl, err = gatherRequestOutputFlags(cmd)
@@ -127,6 +496,11 @@ func parseExecArgs(cmd *cobra.Command, posArgs []string, args *execArgs) error {
 		return err
 	}
 
+	args.transport, err = gatherTransportFlags(cmd)
+	if err != nil {
+		return err
+	}
+
 	// check vars
 	if len(flags.Vars) > 0 {
 		oneTimeVars := make(map[string]string)
@@ -0,0 +1,501 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dekarrin/morc"
+	"github.com/dekarrin/morc/cmd/morc/cmdio"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Exec(t *testing.T) {
+	respFnTokenOK := func(w http.ResponseWriter, r *http.Request) {
+		// suppress date header
+		w.Header()["Date"] = nil
+
+		if r.URL.Path == "/login" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"token":"8675309"}`))
+			return
+		}
+
+		if r.URL.Path == "/whoami" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"user":"vriska"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+
+	loginReq := morc.RequestTemplate{
+		Name:   "login",
+		Method: "POST",
+		URL:    "http://example.com/login",
+		Captures: map[string]morc.VarScraper{
+			"TOKEN": {Name: "TOKEN", Steps: []morc.TraversalStep{{Key: "token"}}},
+		},
+	}
+	whoamiReq := morc.RequestTemplate{
+		Name:   "whoami",
+		Method: "GET",
+		URL:    "http://example.com/whoami",
+		Captures: map[string]morc.VarScraper{
+			"USER": {Name: "USER", Steps: []morc.TraversalStep{{Key: "user"}}},
+		},
+	}
+	getReq := morc.RequestTemplate{
+		Name:   "get",
+		Method: "GET",
+		URL:    "http://example.com/things",
+	}
+
+	getIDReq := morc.RequestTemplate{
+		Name:    "getid",
+		Method:  "GET",
+		URL:     "http://example.com/things/${ID}",
+		Headers: http.Header{"Authorization": []string{"Bearer ${TOKEN}"}},
+	}
+
+	testProject_authFlow := func(steps ...morc.FlowStep) morc.Project {
+		return morc.Project{
+			Templates: map[string]morc.RequestTemplate{
+				"login":  loginReq,
+				"get":    getReq,
+				"getid":  getIDReq,
+				"whoami": whoamiReq,
+			},
+			Flows: map[string]morc.Flow{
+				"test": {Name: "test", Steps: steps},
+			},
+			Vars: morc.NewVarStore(),
+		}
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectProjectSaved bool
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name: "required var captured by prior step",
+			args: []string{"exec", "test"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "login"},
+				morc.FlowStep{Template: "get", Requires: []string{"TOKEN"}},
+			),
+			expectProjectSaved: true,
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"token\":\"8675309\"}\nHTTP/1.1 200 OK\n(no response body)\n",
+		},
+		{
+			name: "required var given as one-time var",
+			args: []string{"exec", "test", "-V", "TOKEN:1234"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "get", Requires: []string{"TOKEN"}},
+				morc.FlowStep{Template: "login"},
+			),
+			expectProjectSaved: true,
+			expectStdoutOutput: "HTTP/1.1 200 OK\n(no response body)\nHTTP/1.1 200 OK\n{\"token\":\"8675309\"}\n",
+		},
+		{
+			name: "step assertions pass",
+			args: []string{"exec", "test"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "login", AssertStatus: 200, AssertBodyContains: []string{"token"}},
+				morc.FlowStep{Template: "get", AssertStatus: 200},
+				morc.FlowStep{Template: "whoami", AssertJSON: []string{`.user == "vriska"`}},
+			),
+			expectProjectSaved: true,
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"token\":\"8675309\"}\nHTTP/1.1 200 OK\n(no response body)\nHTTP/1.1 200 OK\n{\"user\":\"vriska\"}\n",
+		},
+		{
+			name: "failed step assertions stop the flow",
+			args: []string{"exec", "test"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "whoami", AssertStatus: 201, AssertBodyContains: []string{"vriska", "terezi"}},
+				morc.FlowStep{Template: "get"},
+			),
+			expectErr: "step #0: 2 assertions failed:\n * status: expected 201, got 200 OK\n * body: expected to contain \"terezi\", but it does not",
+		},
+		{
+			name: "required var captured only by later step",
+			args: []string{"exec", "test"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "get", Requires: []string{"TOKEN"}},
+				morc.FlowStep{Template: "login"},
+			),
+			expectErr: "step #0 requires ${TOKEN}",
+		},
+		{
+			name: "step calls incomplete request template",
+			args: []string{"exec", "test"},
+			p: func() morc.Project {
+				p := testProject_authFlow(
+					morc.FlowStep{Template: "login"},
+					morc.FlowStep{Template: "draft"},
+				)
+				p.Templates["draft"] = morc.RequestTemplate{Name: "draft", URL: "http://example.com/draft"}
+				return p
+			}(),
+			expectErr: "flow test calls incomplete request template draft in step #1",
+		},
+		{
+			name: "step calls non-existent request template",
+			args: []string{"exec", "test"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "login"},
+				morc.FlowStep{Template: "get"},
+				morc.FlowStep{Template: "missing"},
+			),
+			expectErr: "flow test calls non-existent request template \"missing\" in step #2",
+		},
+		{
+			name: "max idle conns below 1",
+			args: []string{"exec", "test", "--max-idle-conns", "0"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "login"},
+			),
+			expectErr: "--max-idle-conns must be at least 1",
+		},
+		{
+			name: "invalid idle timeout",
+			args: []string{"exec", "test", "--idle-timeout", "soon"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "login"},
+			),
+			expectErr: "--idle-timeout: time: invalid duration",
+		},
+		{
+			name: "parallel group captures available to later steps",
+			args: []string{"exec", "test"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "login", Group: 1},
+				morc.FlowStep{Template: "whoami", Group: 1},
+				morc.FlowStep{Template: "get", Requires: []string{"TOKEN", "USER"}},
+			),
+			expectProjectSaved: true,
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"token\":\"8675309\"}\nHTTP/1.1 200 OK\n{\"user\":\"vriska\"}\nHTTP/1.1 200 OK\n(no response body)\n",
+		},
+		{
+			name: "parallel group step requires capture from same group",
+			args: []string{"exec", "test"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "login", Group: 1},
+				morc.FlowStep{Template: "get", Group: 1, Requires: []string{"TOKEN"}},
+			),
+			expectErr: "step #1 requires ${TOKEN}",
+		},
+		{
+			name: "dry run substitutes known vars and leaves captured ones",
+			args: []string{"exec", "test", "--dry-run", "-V", "ID:413"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "login"},
+				morc.FlowStep{Template: "getid"},
+			),
+			expectStdoutOutput: "------------------- REQUEST -------------------\n" +
+				"Request URI: http://example.com/login\n\n" +
+				"POST /login HTTP/1.1\r\nHost: example.com\r\nUser-Agent: Go-http-client/1.1\r\nContent-Length: 0\r\nAccept-Encoding: gzip\r\n\r\n\n" +
+				"(no request body)\n" +
+				"----------------- END REQUEST -----------------\n" +
+				"------------------- REQUEST -------------------\n" +
+				"Request URI: http://example.com/things/413\n\n" +
+				"GET /things/413 HTTP/1.1\r\nHost: example.com\r\nUser-Agent: Go-http-client/1.1\r\nAuthorization: Bearer ${TOKEN}\r\nAccept-Encoding: gzip\r\n\r\n\n" +
+				"(no request body)\n" +
+				"----------------- END REQUEST -----------------\n",
+		},
+		{
+			name: "profile timing with dry run",
+			args: []string{"exec", "test", "--dry-run", "--profile-timing"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "login"},
+			),
+			expectErr: "[dry-run profile-timing] were all set",
+		},
+		{
+			name: "dry run still checks required vars",
+			args: []string{"exec", "test", "--dry-run"},
+			p: testProject_authFlow(
+				morc.FlowStep{Template: "get", Requires: []string{"TOKEN"}},
+				morc.FlowStep{Template: "login"},
+			),
+			expectErr: "step #0 requires ${TOKEN}",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// setup test server
+			srv := httptest.NewServer(http.HandlerFunc(respFnTokenOK))
+			defer srv.Close()
+			srvClient := srv.Client()
+
+			// inject a custom transport so we always append the server root URL
+			srvClient.Transport = urlBaseRoundTripper{
+				base: srv.URL,
+				old:  srvClient.Transport,
+			}
+
+			cmdio.HTTPClient = srvClient
+
+			resetExecFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(execCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			if !tc.expectProjectSaved {
+				assert_noProjectFileMutations(assert)
+			}
+		})
+	}
+}
+
+func Test_Exec_ProfileTiming(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	srvClient := srv.Client()
+	srvClient.Transport = urlBaseRoundTripper{
+		base: srv.URL,
+		old:  srvClient.Transport,
+	}
+	cmdio.HTTPClient = srvClient
+
+	resetExecFlags()
+
+	p := morc.Project{
+		Templates: map[string]morc.RequestTemplate{
+			"get": {Name: "get", Method: "GET", URL: "http://example.com/things"},
+		},
+		Flows: map[string]morc.Flow{
+			"test": {Name: "test", Steps: []morc.FlowStep{{Template: "get"}, {Template: "get"}}},
+		},
+		Vars: morc.NewVarStore(),
+	}
+
+	projFilePath := createTestProjectIO(t, p)
+	output, _, err := runTestCommand(execCmd, projFilePath, []string{"exec", "test", "--profile-timing", "--no-body"})
+	if !assert.NoError(err) {
+		return
+	}
+
+	// actual times will vary, so only check that each part of the summary is
+	// present
+	assert.Contains(output, "Timing for flow test:\n")
+	assert.Regexp(`(?m)^#0 +get +\S+ *$`, output)
+	assert.Regexp(`(?m)^#1 +get +\S+ *$`, output)
+	assert.Regexp(`(?m)^get +2 +\S+ +\S+ *$`, output)
+	assert.Contains(output, "Slowest step: #")
+	assert.Contains(output, "Total wall time: ")
+}
+
+func Test_Exec_ParallelGroupRecording(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		http.SetCookie(w, &http.Cookie{Name: strings.TrimPrefix(r.URL.Path, "/"), Value: "1"})
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	srvClient := srv.Client()
+	srvClient.Transport = urlBaseRoundTripper{
+		base: srv.URL,
+		old:  srvClient.Transport,
+	}
+	cmdio.HTTPClient = srvClient
+
+	resetExecFlags()
+
+	p := morc.Project{
+		Templates: map[string]morc.RequestTemplate{
+			"first":  {Name: "first", Method: "GET", URL: "http://example.com/first"},
+			"second": {Name: "second", Method: "GET", URL: "http://example.com/second"},
+		},
+		Flows: map[string]morc.Flow{
+			"test": {Name: "test", Steps: []morc.FlowStep{
+				{Template: "first", Group: 1, AssertStatus: 201},
+				{Template: "second", Group: 1},
+			}},
+		},
+		Vars: morc.NewVarStore(),
+		Config: morc.Settings{
+			HistFile:      "::PROJ_DIR::/history.json",
+			SeshFile:      "::PROJ_DIR::/session.json",
+			RecordHistory: true,
+			RecordSession: true,
+		},
+	}
+
+	projFilePath := createTestProjectIO(t, p)
+	_, _, err := runTestCommand(execCmd, projFilePath, []string{"exec", "test", "--no-body"})
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "step #0: assertion failed")
+	}
+
+	// history and session are rewritten in full on every write, so only the
+	// last one written to each buffer is checked.
+	hist, err := morc.LoadHistory(strings.NewReader(lastJSONValue(t, histWriter.(*bytes.Buffer))))
+	if assert.NoError(err) && assert.Len(hist, 2, "both steps should be in history") {
+		assert.Equal("first", hist[0].Template)
+		assert.Equal("second", hist[1].Template)
+	}
+
+	sesh, err := morc.LoadSession(strings.NewReader(lastJSONValue(t, seshWriter.(*bytes.Buffer))))
+	if assert.NoError(err) {
+		var names []string
+		for _, call := range sesh.Cookies {
+			for _, c := range call.Cookies {
+				names = append(names, c.Name)
+			}
+		}
+		assert.ElementsMatch([]string{"first", "second"}, names, "cookies from both steps should be in session")
+	}
+}
+
+// lastJSONValue returns the last of the JSON values written one after another
+// to buf.
+func lastJSONValue(t *testing.T, buf *bytes.Buffer) string {
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	var last json.RawMessage
+	for dec.More() {
+		if err := dec.Decode(&last); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return string(last)
+}
+
+func Test_flowTimingSummary(t *testing.T) {
+	testCases := []struct {
+		name    string
+		timings []stepTiming
+		wall    time.Duration
+		expect  string
+	}{
+		{
+			name:   "no steps",
+			wall:   2 * time.Microsecond,
+			expect: "Timing for flow test:\nSTEP  REQUEST  TIME\n-------------------\n\nTotal wall time: 2µs\n",
+		},
+		{
+			name: "no repeated steps",
+			timings: []stepTiming{
+				{step: 0, template: "login", elapsed: 120 * time.Millisecond},
+				{step: 1, template: "get", elapsed: 45 * time.Millisecond},
+			},
+			wall: 166 * time.Millisecond,
+			expect: "Timing for flow test:\n" +
+				"STEP  REQUEST  TIME \n" +
+				"--------------------\n" +
+				"#0    login    120ms\n" +
+				"#1    get      45ms \n" +
+				"\n" +
+				"Slowest step: #0 login (120ms)\n" +
+				"Total wall time: 166ms\n",
+		},
+		{
+			name: "repeated steps",
+			timings: []stepTiming{
+				{step: 0, template: "login", elapsed: 20 * time.Millisecond},
+				{step: 1, template: "get", elapsed: 45 * time.Millisecond},
+				{step: 2, template: "get", elapsed: 75 * time.Millisecond},
+				{step: 3, template: "get", elapsed: 40 * time.Millisecond},
+			},
+			wall: 181 * time.Millisecond,
+			expect: "Timing for flow test:\n" +
+				"STEP  REQUEST  TIME\n" +
+				"-------------------\n" +
+				"#0    login    20ms\n" +
+				"#1    get      45ms\n" +
+				"#2    get      75ms\n" +
+				"#3    get      40ms\n" +
+				"\n" +
+				"REQUEST  SENDS  MIN   AVG \n" +
+				"--------------------------\n" +
+				"get      3      40ms  53ms\n" +
+				"\n" +
+				"Slowest step: #2 get (75ms)\n" +
+				"Total wall time: 181ms\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := flowTimingSummary("test", tc.timings, tc.wall)
+			assert.Equal(t, tc.expect, actual)
+		})
+	}
+}
+
+func resetExecFlags() {
+	flags.ProjectFile = ""
+	flags.Vars = nil
+	flags.BInsecure = false
+	flags.Timeout = ""
+	flags.BHeaders = false
+	flags.BCaptures = false
+	flags.BNoBody = false
+	flags.BRequest = false
+	flags.BVerbose = false
+	flags.Format = "pretty"
+	flags.VarPrefix = "$"
+	flags.BQuiet = false
+	flags.BDryRun = false
+	flags.BProfileTiming = false
+	flags.BIPv4 = false
+	flags.BIPv6 = false
+	flags.BHTTP1 = false
+	flags.BHTTP2 = false
+	flags.BEnvFallback = false
+	flags.LocalAddr = ""
+	flags.Resolve = nil
+	flags.CertFile = ""
+	flags.KeyFile = ""
+	flags.CACertFile = ""
+	flags.MaxIdleConns = 0
+	flags.MaxConnsPerHost = 0
+	flags.IdleTimeout = ""
+
+	execCmd.Flags().VisitAll(func(fl *pflag.Flag) {
+		fl.Changed = false
+	})
+}
@@ -16,28 +16,64 @@ var flowsCmd = &cobra.Command{
 	Use: "flows [FLOW]",
 	Annotations: map[string]string{
 		annotationKeyHelpUsages: "" +
-			"flows\n" +
+			"flows [--output FMT]\n" +
 			"flows --delete FLOW\n" +
 			"flows --new FLOW REQ1 REQ2 [REQN]...\n" +
-			"flows FLOW\n" +
+			"flows --copy SRC DEST\n" +
+			"flows FLOW [--inline | --resolve] [--output FMT]\n" +
 			"flows FLOW --get ATTR\n" +
-			"flows FLOW [-nuram]...",
+			"flows FLOW [-nuramRg]... [--add-before REF:REQ]... [--add-after REF:REQ]...\n" +
+			"flows FLOW --reorder IDX,IDX,... [-nuRg]...",
 	},
 	GroupID: "project",
 	Short:   "Get or modify request flows",
-	Long: "Performs operations on the flows defined in the project. With no other arguments, a listing of all flows is shown.\n\n" +
+	Long: "Performs operations on the flows defined in the project. With no other arguments, a listing of all flows is shown. The " +
+		"listing can be output as JSON for use by other tools by giving --output json.\n\n" +
 		"A new flow can be created by providing the name of the new flow with the --new flag and providing the names of least " +
 		"two requests to be included in the flow.\n\n" +
 		"A flow can be examined by providing FLOW, the name of it. This will display the list of all steps in the flow. To see a particular " +
 		"attribute of a flow, --get can be used to select it. --get takes either the string \"name\" to explicitly get the flow's name as " +
-		"it is recorded by MORC, or the index of a flow's step.\n\n" +
+		"it is recorded by MORC, or the index of a flow's step. Giving --inline along with FLOW shows the full details of the " +
+		"request each step calls, in the same format as 'morc reqs REQ', after the step itself. The steps are output as JSON " +
+		"instead if --output json is given, with the details of the request of each step included if --inline is " +
+		"also given. Giving --resolve instead shows the exact request each step would send, with all variables filled in " +
+		"from the current environment, in the same format as 'morc reqs REQ --resolve-vars'. Variables captured by an " +
+		"earlier step are left unsubstituted, and nothing is sent.\n\n" +
 		"To modify a flow, provide the name of the FLOW and give one or more modification flags. --name/-n is used to change the name, and " +
 		"can only be specified once. Steps are modified with other flags: --update/-u to change the request a step calls, --remove/-r to " +
 		"remove a step, --add/-a to add a step, and --move/-m to move a step to a new position. All step-modification flags " +
 		"can be specified more than once to apply multiple updates in the same call to MORC. For handling multiple types of step " +
 		"modifications given in the same invocation, MORC will apply the modifications in the following order: step template updates are " +
 		"applied in the order they were given in CLI flags, then all deletes are applied from highest to lowest index, followed by all adds " +
-		"from lowest to to highest index, and finally all moves in the order they were given in CLI flags.\n\n" +
+		"from lowest to to highest index, then all adds from --add-before and --add-after, then all moves in the order they were given in CLI flags, and finally all changes to required variables from " +
+		"--require/-R, to parallel groups from --group/-g, and to assertions from --assert-status, --assert-body-contains, and --assert in the " +
+		"order they were given in CLI flags.\n\n" +
+		"Since step indexes shift as a flow is edited, a step can also be added relative to an existing one with " +
+		"--add-before REF:REQ or --add-after REF:REQ, which add a step calling REQ just before or just after the first " +
+		"step that calls the request REF. The position of REF is found when the step is added, so it accounts for any " +
+		"steps added or removed before it. All --add-before flags are applied in the order they were given, followed " +
+		"by all --add-after flags in the order they were given.\n\n" +
+		"To rearrange every step at once, give --reorder with the current index of each step in the order they are to " +
+		"be put in, separated by commas. For example, --reorder 2,0,1 on a flow with three steps makes the last step " +
+		"the first. Every index in the flow must be given exactly once. --reorder is applied after step template updates " +
+		"and before any other modifications, and it cannot be given with --remove/-r, --add/-a, --add-before, " +
+		"--add-after, or --move/-m.\n\n" +
+		"A step can declare variables that must be set before it is executed with --require/-R. When the flow is executed, MORC will " +
+		"check that each required variable is either already set or is captured by an earlier step, and will refuse to run the flow " +
+		"if not.\n\n" +
+		"Steps can be put into a parallel group with --group/-g. Consecutive steps that are in the same parallel group are " +
+		"executed concurrently. A step in a parallel group cannot require a variable that is captured by another step in the " +
+		"same group. Setting a step's group to 0 or omitting the group removes it from any parallel group.\n\n" +
+		"So that a flow can double as a test, assertions can be made on the response to a step. --assert-status sets " +
+		"the status code that the response must have, --assert-body-contains adds text that its body must contain, and " +
+		"--assert adds an expression that a value in its JSON body must meet, in the same format as 'morc send --assert'. " +
+		"When the flow is executed, every assertion on a step is checked once its response is output, and if any fail, " +
+		"each failure is reported and the flow stops with a non-zero exit status. Omitting the CODE, TEXT, or EXPR " +
+		"clears the step's assertions of that kind.\n\n" +
+		"To make a variant of an existing flow, give --copy with the name of the flow to copy, SRC, and the name of " +
+		"the new flow, DEST, as a positional argument. All steps of SRC are copied to DEST, including their required " +
+		"variables, parallel groups, and assertions, and the two can then be modified separately. DEST must not " +
+		"already exist.\n\n" +
 		"A flow is deleted by providing the --delete/-D flag with the FLOW to be deleted as its argument.",
 	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, posArgs []string) error {
@@ -53,9 +89,9 @@ var flowsCmd = &cobra.Command{
 
 		switch args.action {
 		case flowsActionList:
-			return invokeFlowsList(io, args.projFile)
+			return invokeFlowsList(io, args.projFile, args.listFormat)
 		case flowsActionShow:
-			return invokeFlowsShow(io, args.projFile, args.flow)
+			return invokeFlowsShow(io, args.projFile, args.flow, args.inline, args.resolve, args.listFormat)
 		case flowsActionDelete:
 			return invokeFlowsDelete(io, args.projFile, args.flow)
 		case flowsActionEdit:
@@ -64,6 +100,8 @@ var flowsCmd = &cobra.Command{
 			return invokeFlowsGet(io, args.projFile, args.flow, args.getItem)
 		case flowsActionNew:
 			return invokeFlowsNew(io, args.projFile, args.flow, args.reqs)
+		case flowsActionCopy:
+			return invokeFlowsCopy(io, args.projFile, args.flow, args.copyDest)
 
 		default:
 			panic(fmt.Sprintf("unhandled flow action %q", args.action))
@@ -75,19 +113,46 @@ func init() {
 	flowsCmd.PersistentFlags().StringVarP(&flags.ProjectFile, "project-file", "F", morc.DefaultProjectPath, "Use `FILE` for project data instead of "+morc.DefaultProjectPath+".")
 	flowsCmd.PersistentFlags().StringVarP(&flags.Delete, "delete", "D", "", "Delete the flow with the name `FLOW`.")
 	flowsCmd.PersistentFlags().StringVarP(&flags.New, "new", "N", "", "Create a new flow with the name `FLOW`. When given, positional arguments are interpreted as ordered names of requests that make up the new flow's steps. At least two requests must be present.")
+	flowsCmd.PersistentFlags().StringVarP(&flags.Copy, "copy", "", "", "Copy the flow with the name `SRC` to a new flow named by the positional argument.")
 	flowsCmd.PersistentFlags().StringVarP(&flags.Get, "get", "G", "", "Get the value of an attribute of the flow. `ATTR` can either be 'name', to get the flow name, or the index of a specific step in the flow.")
 	flowsCmd.PersistentFlags().IntSliceVarP(&flags.StepRemovals, "remove", "r", nil, "Remove the step at index `IDX` from the flow. Can be given multiple times; if so, will be applied from highest to lowest index. Will be applied after all step updates from --update are applied.")
 	flowsCmd.PersistentFlags().StringArrayVarP(&flags.StepAdds, "add", "a", nil, "Add a new step calling request REQ at index IDX, or at the end of current steps if index is omitted. Argument must be a string in form `[IDX]:REQ`. Can be given multiple times; if so, will be applied from lowest to highest index after all updates and removals are applied.")
+	flowsCmd.PersistentFlags().StringArrayVarP(&flags.StepAddsBefore, "add-before", "", nil, "Add a new step calling request REQ just before the first step that calls request REF. Argument must be a string in form `REF:REQ`. Can be given multiple times; if so, will be applied in order given after all adds from --add are applied.")
+	flowsCmd.PersistentFlags().StringArrayVarP(&flags.StepAddsAfter, "add-after", "", nil, "Add a new step calling request REQ just after the first step that calls request REF. Argument must be a string in form `REF:REQ`. Can be given multiple times; if so, will be applied in order given after all adds from --add-before are applied.")
 	flowsCmd.PersistentFlags().StringArrayVarP(&flags.StepMoves, "move", "m", nil, "Move the step at index FROM to index TO. Argument must be a string in form `FROM:[TO]`. Can be given multiple times; if so, will be applied in order given after all replacements, removals, and adds are applied. If TO is not given, the step is moved to the end of the flow.")
+	flowsCmd.PersistentFlags().StringVarP(&flags.StepReorder, "reorder", "", "", "Rearrange all steps of the flow at once. Argument must be the current index of every step in the new order, separated by commas, such as `2,0,1`.")
 	flowsCmd.PersistentFlags().StringArrayVarP(&flags.StepReplaces, "update", "u", nil, "Update the template called in step IDX to REQ. Argument must be a string in form `IDX:REQ`. Can be given multiple times; if so, will be applied in order given before any other step modifications.")
+	flowsCmd.PersistentFlags().StringArrayVarP(&flags.StepRequires, "require", "R", nil, "Set the variables that must be set before step IDX is executed. Argument must be a string in form `IDX:[VAR1,VAR2,...]`; giving no variables clears the step's required variables. Can be given multiple times; if so, will be applied in order given after all other step modifications are applied.")
+	flowsCmd.PersistentFlags().StringArrayVarP(&flags.StepGroups, "group", "g", nil, "Put step IDX in parallel group GROUP. Argument must be a string in form `IDX:[GROUP]`; giving no group or a group of 0 removes the step from any parallel group. Can be given multiple times; if so, will be applied in order given after all other step modifications are applied.")
+	flowsCmd.PersistentFlags().StringArrayVarP(&flags.StepAssertStatus, "assert-status", "", nil, "Require the response to step IDX to have status code CODE. Argument must be a string in form `IDX:[CODE]`; giving no code clears the step's status assertion. Can be given multiple times; if so, will be applied in order given after all other step modifications are applied.")
+	flowsCmd.PersistentFlags().StringArrayVarP(&flags.StepAssertBodyContains, "assert-body-contains", "", nil, "Require the body of the response to step IDX to contain TEXT, in addition to any text it is already required to contain. Argument must be a string in form `IDX:[TEXT]`; giving no text clears all of the step's body assertions. Can be given multiple times; if so, will be applied in order given after all other step modifications are applied.")
+	flowsCmd.PersistentFlags().StringArrayVarP(&flags.StepAssertJSON, "assert", "", nil, "Require a value in the JSON body of the response to step IDX to meet EXPR, such as '.count == 5', in addition to any expressions it must already meet. Argument must be a string in form `IDX:[EXPR]`; giving no expression clears all of the step's JSON assertions. Can be given multiple times; if so, will be applied in order given after all other step modifications are applied.")
 	flowsCmd.PersistentFlags().StringVarP(&flags.Name, "name", "n", "", "Change the name of the flow to `NAME`.")
+	flowsCmd.PersistentFlags().BoolVarP(&flags.BInline, "inline", "", false, "When showing a flow, also show the full details of the request called by each step.")
+	flowsCmd.PersistentFlags().BoolVarP(&flags.BResolve, "resolve", "", false, "When showing a flow, also show the request each step would send with all vars filled in from the current environment.")
 	flowsCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
 
+	addListOutputFlag(flowsCmd)
+
 	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "remove")
 	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "add")
 	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "move")
 	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "update")
 	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "name")
+	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "require")
+	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "group")
+	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "assert-status")
+	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "assert-body-contains")
+	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "assert")
+	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "copy")
+	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "reorder")
+	flowsCmd.MarkFlagsMutuallyExclusive("reorder", "remove")
+	flowsCmd.MarkFlagsMutuallyExclusive("reorder", "add")
+	flowsCmd.MarkFlagsMutuallyExclusive("reorder", "add-before")
+	flowsCmd.MarkFlagsMutuallyExclusive("reorder", "add-after")
+	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "add-before")
+	flowsCmd.MarkFlagsMutuallyExclusive("delete", "new", "get", "add-after")
+	flowsCmd.MarkFlagsMutuallyExclusive("reorder", "move")
 
 	rootCmd.AddCommand(flowsCmd)
 }
@@ -238,6 +303,40 @@ func invokeFlowsEdit(io cmdio.IO, projFile, flowName string, attrs flowAttrValue
 		attrOrdering = append(attrOrdering, modKey)
 	}
 
+	for _, add := range attrs.stepRelativeAdds {
+		// find the step being added relative to as it is now, so that any
+		// steps added or removed before it are accounted for.
+		refIdx := -1
+		for i, step := range flow.Steps {
+			if strings.ToLower(step.Template) == add.ref {
+				refIdx = i
+				break
+			}
+		}
+		if refIdx < 0 {
+			return fmt.Errorf("cannot add step %s %s: no step calls request template %s", add.position(), add.ref, add.ref)
+		}
+
+		actualIdx := refIdx
+		if add.after {
+			actualIdx++
+		}
+
+		// make shore the new template exists
+		if _, exists := p.Templates[add.template]; !exists {
+			return fmt.Errorf("no request template %q in project", add.template)
+		}
+
+		if err := flow.InsertStep(actualIdx, morc.FlowStep{Template: add.template}); err != nil {
+			return fmt.Errorf("cannot add step at #%d: %w", actualIdx+1, err)
+		}
+
+		modKey := flowKey{stepIndex: actualIdx, uniqueInt: stepOpCount}
+		stepOpCount++
+		modifiedVals[modKey] = fmt.Sprintf("%s (added)", add.template)
+		attrOrdering = append(attrOrdering, modKey)
+	}
+
 	for _, move := range attrs.stepMoves {
 		actualFrom, err := sliceops.RealIndex(flow.Steps, move.from, false)
 		if err != nil {
@@ -264,6 +363,147 @@ func invokeFlowsEdit(io cmdio.IO, projFile, flowName string, attrs flowAttrValue
 		attrOrdering = append(attrOrdering, modKey)
 	}
 
+	if attrs.stepReorder != nil {
+		if err := flow.ReorderSteps(attrs.stepReorder); err != nil {
+			return fmt.Errorf("cannot reorder steps: %w", err)
+		}
+
+		// output by the index each step was at before the reorder
+		newIndexes := make([]int, len(attrs.stepReorder))
+		for newIdx, oldIdx := range attrs.stepReorder {
+			newIndexes[oldIdx] = newIdx
+		}
+
+		for oldIdx, newIdx := range newIndexes {
+			modKey := flowKey{stepIndex: oldIdx, uniqueInt: stepOpCount}
+			stepOpCount++
+
+			if oldIdx != newIdx {
+				modifiedVals[modKey] = fmt.Sprintf("index %d", newIdx)
+			} else {
+				noChangeVals[modKey] = fmt.Sprintf("index %d", oldIdx)
+			}
+			attrOrdering = append(attrOrdering, modKey)
+		}
+	}
+
+	for _, reqs := range attrs.stepRequires {
+		actualIdx, err := sliceops.RealIndex(flow.Steps, reqs.index, false)
+		if err != nil {
+			return fmt.Errorf("cannot set required vars of step #%d: %w", actualIdx, err)
+		}
+
+		modKey := flowKey{stepIndex: actualIdx, uniqueInt: stepOpCount}
+		stepOpCount++
+
+		oldDesc := describeStepRequires(flow.Steps[actualIdx].Requires, p.VarPrefix())
+		newDesc := describeStepRequires(reqs.vars, p.VarPrefix())
+
+		if oldDesc != newDesc {
+			flow.Steps[actualIdx].Requires = reqs.vars
+			modifiedVals[modKey] = newDesc
+		} else {
+			noChangeVals[modKey] = oldDesc
+		}
+		attrOrdering = append(attrOrdering, modKey)
+	}
+
+	for _, grp := range attrs.stepGroups {
+		actualIdx, err := sliceops.RealIndex(flow.Steps, grp.index, false)
+		if err != nil {
+			return fmt.Errorf("cannot set parallel group of step #%d: %w", actualIdx, err)
+		}
+
+		modKey := flowKey{stepIndex: actualIdx, uniqueInt: stepOpCount}
+		stepOpCount++
+
+		oldDesc := describeStepGroup(flow.Steps[actualIdx].Group)
+		newDesc := describeStepGroup(grp.group)
+
+		if oldDesc != newDesc {
+			flow.Steps[actualIdx].Group = grp.group
+			modifiedVals[modKey] = newDesc
+		} else {
+			noChangeVals[modKey] = oldDesc
+		}
+		attrOrdering = append(attrOrdering, modKey)
+	}
+
+	for _, as := range attrs.stepAssertStatus {
+		actualIdx, err := sliceops.RealIndex(flow.Steps, as.index, false)
+		if err != nil {
+			return fmt.Errorf("cannot set status assertion of step #%d: %w", actualIdx, err)
+		}
+
+		modKey := flowKey{stepIndex: actualIdx, uniqueInt: stepOpCount}
+		stepOpCount++
+
+		oldDesc := describeStepAssertStatus(flow.Steps[actualIdx].AssertStatus)
+		newDesc := describeStepAssertStatus(as.status)
+
+		if oldDesc != newDesc {
+			flow.Steps[actualIdx].AssertStatus = as.status
+			modifiedVals[modKey] = newDesc
+		} else {
+			noChangeVals[modKey] = oldDesc
+		}
+		attrOrdering = append(attrOrdering, modKey)
+	}
+
+	for _, ab := range attrs.stepAssertBody {
+		actualIdx, err := sliceops.RealIndex(flow.Steps, ab.index, false)
+		if err != nil {
+			return fmt.Errorf("cannot set body assertions of step #%d: %w", actualIdx, err)
+		}
+
+		modKey := flowKey{stepIndex: actualIdx, uniqueInt: stepOpCount}
+		stepOpCount++
+
+		var newTexts []string
+		if ab.text != "" {
+			newTexts = append(newTexts, flow.Steps[actualIdx].AssertBodyContains...)
+			newTexts = append(newTexts, ab.text)
+		}
+
+		oldDesc := describeStepAssertBody(flow.Steps[actualIdx].AssertBodyContains)
+		newDesc := describeStepAssertBody(newTexts)
+
+		if oldDesc != newDesc {
+			flow.Steps[actualIdx].AssertBodyContains = newTexts
+			modifiedVals[modKey] = newDesc
+		} else {
+			noChangeVals[modKey] = oldDesc
+		}
+		attrOrdering = append(attrOrdering, modKey)
+	}
+
+	for _, aj := range attrs.stepAssertJSON {
+		actualIdx, err := sliceops.RealIndex(flow.Steps, aj.index, false)
+		if err != nil {
+			return fmt.Errorf("cannot set JSON assertions of step #%d: %w", actualIdx, err)
+		}
+
+		modKey := flowKey{stepIndex: actualIdx, uniqueInt: stepOpCount}
+		stepOpCount++
+
+		var newExprs []string
+		if aj.expr != "" {
+			newExprs = append(newExprs, flow.Steps[actualIdx].AssertJSON...)
+			newExprs = append(newExprs, aj.expr)
+		}
+
+		oldDesc := describeStepAssertJSON(flow.Steps[actualIdx].AssertJSON)
+		newDesc := describeStepAssertJSON(newExprs)
+
+		if oldDesc != newDesc {
+			flow.Steps[actualIdx].AssertJSON = newExprs
+			modifiedVals[modKey] = newDesc
+		} else {
+			noChangeVals[modKey] = oldDesc
+		}
+		attrOrdering = append(attrOrdering, modKey)
+	}
+
 	// flow name might have been modified so take the currently set .Name and lowercase it.
 	p.Flows[strings.ToLower(flow.Name)] = flow
 	err = writeProject(p, false)
@@ -361,13 +601,47 @@ func invokeFlowsNew(io cmdio.IO, projFile, flowName string, templates []string)
 	return nil
 }
 
-func invokeFlowsShow(io cmdio.IO, projFile, flowName string) error {
+func invokeFlowsCopy(io cmdio.IO, projFile, srcName, destName string) error {
 	// load the project file
 	p, err := readProject(projFile, false)
 	if err != nil {
 		return err
 	}
 
+	// case doesn't matter for flow names
+	srcLower := strings.ToLower(srcName)
+	destLower := strings.ToLower(destName)
+
+	src, ok := p.Flows[srcLower]
+	if !ok {
+		return morc.NewFlowNotFoundError(srcName)
+	}
+	if _, exists := p.Flows[destLower]; exists {
+		return morc.NewFlowExistsError(destName)
+	}
+
+	dest := src.Copy()
+	dest.Name = destLower
+	p.Flows[destLower] = dest
+
+	// save the project file
+	err = writeProject(p, false)
+	if err != nil {
+		return err
+	}
+
+	io.PrintLoudf("Copied flow %s to %s\n", srcLower, destLower)
+
+	return nil
+}
+
+func invokeFlowsShow(io cmdio.IO, projFile, flowName string, inline, resolve bool, format listFormat) error {
+	// load the project file; vars are only needed if resolving requests
+	p, err := readProject(projFile, resolve)
+	if err != nil {
+		return err
+	}
+
 	// case doesn't matter for flow names
 	flowLower := strings.ToLower(flowName)
 	flow, ok := p.Flows[flowLower]
@@ -375,13 +649,23 @@ func invokeFlowsShow(io cmdio.IO, projFile, flowName string) error {
 		return morc.NewFlowNotFoundError(flowName)
 	}
 
+	if format == listFormatJSON {
+		return printJSON(io, flowsShowing(p, flowLower, inline))
+	}
+
 	if len(flow.Steps) == 0 {
 		io.PrintLoudln("(no steps in flow)")
 	}
 
+	oc := morc.OutputControl{Writer: io.Out}
+	var captured []string
 	for i, step := range flow.Steps {
 		req, exists := p.Templates[step.Template]
 
+		if (inline || resolve) && i > 0 {
+			io.Printf("\n")
+		}
+
 		if exists {
 			notSendableBang := ""
 			meth := req.Method
@@ -395,7 +679,52 @@ func invokeFlowsShow(io cmdio.IO, projFile, flowName string) error {
 				reqURL = "http://???"
 			}
 
-			io.Printf("%d:%s %s (%s %s)\n", i, notSendableBang, step.Template, meth, reqURL)
+			requiresStr := ""
+			if len(step.Requires) > 0 {
+				var names []string
+				for _, v := range step.Requires {
+					names = append(names, fmt.Sprintf("%s{%s}", p.VarPrefix(), v))
+				}
+				requiresStr = " requires " + strings.Join(names, ", ")
+			}
+
+			groupStr := ""
+			if step.Group != 0 {
+				groupStr = fmt.Sprintf(" [group %d]", step.Group)
+			}
+
+			assertStr := ""
+			var asserts []string
+			if step.AssertStatus != 0 {
+				asserts = append(asserts, fmt.Sprintf("status %d", step.AssertStatus))
+			}
+			for _, text := range step.AssertBodyContains {
+				asserts = append(asserts, fmt.Sprintf("body contains %q", text))
+			}
+			for _, expr := range step.AssertJSON {
+				asserts = append(asserts, fmt.Sprintf("'%s'", expr))
+			}
+			if len(asserts) > 0 {
+				assertStr = " asserts " + strings.Join(asserts, ", ")
+			}
+
+			io.Printf("%d:%s %s (%s %s)%s%s%s\n", i, notSendableBang, step.Template, meth, reqURL, requiresStr, groupStr, assertStr)
+
+			if inline {
+				printReqDetails(io, p, req)
+			}
+
+			if resolve && req.Sendable() {
+				if err := dryRunTemplate(&p, req, p.Vars.MergedSet(nil), captured, p.VarPrefix(), oc); err != nil {
+					return fmt.Errorf("step #%d: %w", i, err)
+				}
+
+				// captured values aren't known until the flow is executed, so
+				// leave them as they are in the template for later steps.
+				for k := range req.Captures {
+					captured = append(captured, strings.ToUpper(k))
+				}
+			}
 		} else {
 			io.Printf("%d:! %s (!non-existent req)\n", i, step.Template)
 		}
@@ -404,12 +733,16 @@ func invokeFlowsShow(io cmdio.IO, projFile, flowName string) error {
 	return nil
 }
 
-func invokeFlowsList(io cmdio.IO, projFile string) error {
+func invokeFlowsList(io cmdio.IO, projFile string, format listFormat) error {
 	p, err := readProject(projFile, false)
 	if err != nil {
 		return err
 	}
 
+	if format == listFormatJSON {
+		return printJSON(io, flowsListing(p))
+	}
+
 	if len(p.Flows) == 0 {
 		io.PrintLoudln("(none)")
 	} else {
@@ -440,6 +773,102 @@ func invokeFlowsList(io cmdio.IO, projFile string) error {
 	return nil
 }
 
+// flowsListEntry is an entry in the machine-readable listing of flows.
+type flowsListEntry struct {
+	Name     string `json:"name"`
+	Steps    int    `json:"steps"`
+	Execable bool   `json:"execable"`
+}
+
+// flowsListing returns entries for every flow in p, sorted by name.
+func flowsListing(p morc.Project) []flowsListEntry {
+	var sortedNames []string
+	for name := range p.Flows {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	entries := make([]flowsListEntry, len(sortedNames))
+	for i, name := range sortedNames {
+		entries[i] = flowsListEntry{
+			Name:     p.Flows[name].Name,
+			Steps:    len(p.Flows[name].Steps),
+			Execable: p.IsExecableFlow(name),
+		}
+	}
+
+	return entries
+}
+
+// flowsDetail is the machine-readable details of a flow.
+type flowsDetail struct {
+	Name     string            `json:"name"`
+	Execable bool              `json:"execable"`
+	Steps    []flowsDetailStep `json:"steps"`
+}
+
+// flowsDetailStep is a step in the machine-readable details of a flow. Method
+// and URL are empty if the request template it calls does not exist.
+type flowsDetailStep struct {
+	Template string   `json:"template"`
+	Exists   bool     `json:"exists"`
+	Method   string   `json:"method"`
+	URL      string   `json:"url"`
+	Requires []string `json:"requires"`
+	Group    int      `json:"group"`
+
+	AssertStatus       int      `json:"assert_status"`
+	AssertBodyContains []string `json:"assert_body_contains"`
+	AssertJSON         []string `json:"assert_json"`
+
+	// Request is the full details of the request template. It is only set
+	// when showing inline.
+	Request *reqsDetail `json:"request,omitempty"`
+}
+
+// flowsShowing returns the details of the flow in p with the given name, which
+// must exist. If inline is set, the details of the request template of each
+// step that exists are included.
+func flowsShowing(p morc.Project, name string, inline bool) flowsDetail {
+	flow := p.Flows[name]
+
+	detail := flowsDetail{
+		Name:     flow.Name,
+		Execable: p.IsExecableFlow(name),
+		Steps:    make([]flowsDetailStep, len(flow.Steps)),
+	}
+
+	for i, step := range flow.Steps {
+		ds := flowsDetailStep{
+			Template: step.Template,
+			Requires: []string{},
+			Group:    step.Group,
+
+			AssertStatus:       step.AssertStatus,
+			AssertBodyContains: []string{},
+			AssertJSON:         []string{},
+		}
+		ds.Requires = append(ds.Requires, step.Requires...)
+		ds.AssertBodyContains = append(ds.AssertBodyContains, step.AssertBodyContains...)
+		ds.AssertJSON = append(ds.AssertJSON, step.AssertJSON...)
+
+		if req, exists := p.Templates[step.Template]; exists {
+			ds.Exists = true
+			ds.Method = req.Method
+			ds.URL = req.URL
+
+			if inline {
+				reqDetail := reqsShowing(p, step.Template)
+				ds.Request = &reqDetail
+			}
+		}
+
+		detail.Steps[i] = ds
+	}
+
+	return detail
+}
+
 type flowsArgs struct {
 	projFile string
 	action   flowAction
@@ -447,14 +876,29 @@ type flowsArgs struct {
 	flow     string
 	reqs     []string
 	sets     flowAttrValues
+	inline   bool
+	resolve  bool
+
+	// copyDest is the name of the flow that the one named by flow is copied
+	// to.
+	copyDest string
+
+	listFormat listFormat
 }
 
 type flowAttrValues struct {
 	name             optional[string]
 	stepReplacements []flowStepUpsert
 	stepAdds         []flowStepUpsert
+	stepRelativeAdds []flowStepRelativeAdd
 	stepRemovals     []int
 	stepMoves        []flowStepMove
+	stepReorder      []int
+	stepRequires     []flowStepRequires
+	stepGroups       []flowStepGroup
+	stepAssertStatus []flowStepAssertStatus
+	stepAssertBody   []flowStepAssertBody
+	stepAssertJSON   []flowStepAssertJSON
 }
 
 type flowStepUpsert struct {
@@ -462,6 +906,48 @@ type flowStepUpsert struct {
 	template string
 }
 
+// flowStepRelativeAdd is a step to add just before or after the first step that
+// calls the request template ref.
+type flowStepRelativeAdd struct {
+	ref      string
+	template string
+	after    bool
+}
+
+// position returns "before" or "after" depending on where the step is added
+// relative to ref.
+func (add flowStepRelativeAdd) position() string {
+	if add.after {
+		return "after"
+	}
+	return "before"
+}
+
+type flowStepRequires struct {
+	index int
+	vars  []string
+}
+
+type flowStepGroup struct {
+	index int
+	group int
+}
+
+type flowStepAssertStatus struct {
+	index  int
+	status int
+}
+
+type flowStepAssertBody struct {
+	index int
+	text  string
+}
+
+type flowStepAssertJSON struct {
+	index int
+	expr  string
+}
+
 type flowStepMove struct {
 	from int
 	to   int
@@ -480,6 +966,26 @@ func parseFlowsArgs(cmd *cobra.Command, posArgs []string, args *flowsArgs) error
 		return err
 	}
 
+	args.listFormat, err = gatherListOutputFlag(cmd, args.action == flowsActionList || args.action == flowsActionShow, "flows")
+	if err != nil {
+		return err
+	}
+
+	if flags.BInline && args.action != flowsActionShow {
+		return fmt.Errorf("--inline can only be used when showing a flow")
+	}
+	if flags.BResolve {
+		if args.action != flowsActionShow {
+			return fmt.Errorf("--resolve can only be used when showing a flow")
+		}
+		if flags.BInline {
+			return fmt.Errorf("--resolve and --inline cannot be given together")
+		}
+		if args.listFormat == listFormatJSON {
+			return fmt.Errorf("--resolve cannot be used with --output json")
+		}
+	}
+
 	// do action-specific arg and flag parsing
 	switch args.action {
 	case flowsActionList:
@@ -487,6 +993,8 @@ func parseFlowsArgs(cmd *cobra.Command, posArgs []string, args *flowsArgs) error
 	case flowsActionShow:
 		// set arg 1 as the flow name
 		args.flow = posArgs[0]
+		args.inline = flags.BInline
+		args.resolve = flags.BResolve
 	case flowsActionDelete:
 		// special case of flow name set from a CLI flag rather than pos arg.
 		args.flow = flags.Delete
@@ -504,6 +1012,10 @@ func parseFlowsArgs(cmd *cobra.Command, posArgs []string, args *flowsArgs) error
 		args.flow = flags.New
 		args.sets.name = optional[string]{set: true, v: flags.New}
 		args.reqs = posArgs
+	case flowsActionCopy:
+		// special case of flow name set from a CLI flag rather than pos arg.
+		args.flow = flags.Copy
+		args.copyDest = posArgs[0]
 	case flowsActionEdit:
 		// set arg 1 as the flow name
 		args.flow = posArgs[0]
@@ -540,6 +1052,20 @@ func parseFlowsActionFromFlags(cmd *cobra.Command, posArgs []string) (flowAction
 			return flowsActionNew, fmt.Errorf("--new requires at least two requests in positional args")
 		}
 		return flowsActionNew, nil
+	} else if f.Changed("copy") {
+		if flowsSetFlagIsPresent(cmd) {
+			return flowsActionCopy, fmt.Errorf("--copy cannot be given with flags that modify a flow")
+		}
+		if flags.Copy == "" {
+			return flowsActionCopy, fmt.Errorf("--copy cannot be set to empty string")
+		}
+		if len(posArgs) < 1 {
+			return flowsActionCopy, fmt.Errorf("missing name of DEST to copy to")
+		}
+		if len(posArgs) > 1 {
+			return flowsActionCopy, fmt.Errorf("unknown positional argument %q", posArgs[1])
+		}
+		return flowsActionCopy, nil
 	} else if f.Changed("get") {
 		if len(posArgs) < 1 {
 			return flowsActionGet, fmt.Errorf("missing name of FLOW to get from")
@@ -603,6 +1129,30 @@ func parseFlowsSetFlags(cmd *cobra.Command, attrs *flowAttrValues) error {
 		}
 	}
 
+	if f.Lookup("add-before").Changed {
+		// add-before is in form REF:REQ, no exceptions.
+		for flagIdx, add := range flags.StepAddsBefore {
+			rel, err := parseFlowRelativeAddArg(add, false)
+			if err != nil {
+				return fmt.Errorf("--add-before #%d: %w", flagIdx+1, err)
+			}
+
+			attrs.stepRelativeAdds = append(attrs.stepRelativeAdds, rel)
+		}
+	}
+
+	if f.Lookup("add-after").Changed {
+		// add-after is in form REF:REQ, no exceptions.
+		for flagIdx, add := range flags.StepAddsAfter {
+			rel, err := parseFlowRelativeAddArg(add, true)
+			if err != nil {
+				return fmt.Errorf("--add-after #%d: %w", flagIdx+1, err)
+			}
+
+			attrs.stepRelativeAdds = append(attrs.stepRelativeAdds, rel)
+		}
+	}
+
 	if f.Lookup("move").Changed {
 		// move is in form FROM:TO, optionally may be FROM: (or just FROM).
 		for flagIdx, move := range flags.StepMoves {
@@ -615,9 +1165,263 @@ func parseFlowsSetFlags(cmd *cobra.Command, attrs *flowAttrValues) error {
 		}
 	}
 
+	if f.Lookup("reorder").Changed {
+		// reorder is in form IDX,IDX,...; whether it covers every step is not
+		// known until the flow is loaded.
+		order, err := parseFlowReorderArg(flags.StepReorder)
+		if err != nil {
+			return fmt.Errorf("--reorder: %w", err)
+		}
+		attrs.stepReorder = order
+	}
+
+	if f.Lookup("require").Changed {
+		// require is in form IDX:VARS, VARS may be empty to clear.
+		for flagIdx, req := range flags.StepRequires {
+			r, err := parseFlowRequireArg(req)
+			if err != nil {
+				return fmt.Errorf("--require #%d: %w", flagIdx+1, err)
+			}
+
+			attrs.stepRequires = append(attrs.stepRequires, r)
+		}
+	}
+
+	if f.Lookup("group").Changed {
+		// group is in form IDX:GROUP, GROUP may be empty to clear.
+		for flagIdx, grp := range flags.StepGroups {
+			g, err := parseFlowGroupArg(grp)
+			if err != nil {
+				return fmt.Errorf("--group #%d: %w", flagIdx+1, err)
+			}
+
+			attrs.stepGroups = append(attrs.stepGroups, g)
+		}
+	}
+
+	if f.Lookup("assert-status").Changed {
+		// assert-status is in form IDX:CODE, CODE may be empty to clear.
+		for flagIdx, as := range flags.StepAssertStatus {
+			a, err := parseFlowAssertStatusArg(as)
+			if err != nil {
+				return fmt.Errorf("--assert-status #%d: %w", flagIdx+1, err)
+			}
+
+			attrs.stepAssertStatus = append(attrs.stepAssertStatus, a)
+		}
+	}
+
+	if f.Lookup("assert-body-contains").Changed {
+		// assert-body-contains is in form IDX:TEXT, TEXT may be empty to clear.
+		for flagIdx, ab := range flags.StepAssertBodyContains {
+			a, err := parseFlowAssertBodyArg(ab)
+			if err != nil {
+				return fmt.Errorf("--assert-body-contains #%d: %w", flagIdx+1, err)
+			}
+
+			attrs.stepAssertBody = append(attrs.stepAssertBody, a)
+		}
+	}
+
+	if f.Lookup("assert").Changed {
+		// assert is in form IDX:EXPR, EXPR may be empty to clear.
+		for flagIdx, aj := range flags.StepAssertJSON {
+			a, err := parseFlowAssertJSONArg(aj)
+			if err != nil {
+				return fmt.Errorf("--assert #%d: %w", flagIdx+1, err)
+			}
+
+			attrs.stepAssertJSON = append(attrs.stepAssertJSON, a)
+		}
+	}
+
 	return nil
 }
 
+func parseFlowGroupArg(s string) (flowStepGroup, error) {
+	var grp flowStepGroup
+
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return grp, fmt.Errorf("not in IDX:GROUP or IDX: format: %q", s)
+	}
+
+	var err error
+	grp.index, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return grp, fmt.Errorf("IDX %q is not an integer", parts[0])
+	}
+
+	if parts[1] != "" {
+		grp.group, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return grp, fmt.Errorf("GROUP %q is not an integer", parts[1])
+		}
+		if grp.group < 0 {
+			return grp, fmt.Errorf("GROUP cannot be negative")
+		}
+	}
+
+	return grp, nil
+}
+
+// describeStepGroup gives a human-readable description of the parallel group
+// of a step for use in edit output.
+func describeStepGroup(group int) string {
+	if group == 0 {
+		return "no parallel group"
+	}
+	return fmt.Sprintf("parallel group %d", group)
+}
+
+func parseFlowAssertStatusArg(s string) (flowStepAssertStatus, error) {
+	var as flowStepAssertStatus
+
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return as, fmt.Errorf("not in IDX:CODE or IDX: format: %q", s)
+	}
+
+	var err error
+	as.index, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return as, fmt.Errorf("IDX %q is not an integer", parts[0])
+	}
+
+	if parts[1] != "" {
+		as.status, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return as, fmt.Errorf("CODE %q is not an integer", parts[1])
+		}
+		if err := checkStatusCode(as.status); err != nil {
+			return as, err
+		}
+	}
+
+	return as, nil
+}
+
+// describeStepAssertStatus gives a human-readable description of the status
+// assertion of a step for use in edit output.
+func describeStepAssertStatus(status int) string {
+	if status == 0 {
+		return "assert any status"
+	}
+	return fmt.Sprintf("assert status %d", status)
+}
+
+func parseFlowAssertBodyArg(s string) (flowStepAssertBody, error) {
+	var ab flowStepAssertBody
+
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return ab, fmt.Errorf("not in IDX:TEXT or IDX: format: %q", s)
+	}
+
+	var err error
+	ab.index, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return ab, fmt.Errorf("IDX %q is not an integer", parts[0])
+	}
+	ab.text = parts[1]
+
+	return ab, nil
+}
+
+// describeStepAssertBody gives a human-readable description of the body
+// assertions of a step for use in edit output.
+func describeStepAssertBody(texts []string) string {
+	if len(texts) == 0 {
+		return "assert any body"
+	}
+
+	quoted := make([]string, len(texts))
+	for i := range texts {
+		quoted[i] = fmt.Sprintf("%q", texts[i])
+	}
+	return "assert body contains " + strings.Join(quoted, ", ")
+}
+
+func parseFlowAssertJSONArg(s string) (flowStepAssertJSON, error) {
+	var aj flowStepAssertJSON
+
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return aj, fmt.Errorf("not in IDX:EXPR or IDX: format: %q", s)
+	}
+
+	var err error
+	aj.index, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return aj, fmt.Errorf("IDX %q is not an integer", parts[0])
+	}
+
+	if parts[1] != "" {
+		a, err := morc.ParseJSONAssertion(parts[1])
+		if err != nil {
+			return aj, err
+		}
+		aj.expr = a.String()
+	}
+
+	return aj, nil
+}
+
+// describeStepAssertJSON gives a human-readable description of the JSON
+// assertions of a step for use in edit output.
+func describeStepAssertJSON(exprs []string) string {
+	if len(exprs) == 0 {
+		return "assert nothing in JSON body"
+	}
+
+	quoted := make([]string, len(exprs))
+	for i := range exprs {
+		quoted[i] = fmt.Sprintf("'%s'", exprs[i])
+	}
+	return "assert " + strings.Join(quoted, ", ")
+}
+
+func parseFlowRequireArg(s string) (flowStepRequires, error) {
+	var reqs flowStepRequires
+
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return reqs, fmt.Errorf("not in IDX:VARS or IDX: format: %q", s)
+	}
+
+	var err error
+	reqs.index, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return reqs, fmt.Errorf("IDX %q is not an integer", parts[0])
+	}
+
+	if parts[1] != "" {
+		for _, name := range strings.Split(parts[1], ",") {
+			name = strings.TrimSpace(name)
+			if _, err := morc.ParseVarName(name); err != nil {
+				return reqs, fmt.Errorf("invalid var name %q: %w", name, err)
+			}
+			reqs.vars = append(reqs.vars, strings.ToUpper(name))
+		}
+	}
+
+	return reqs, nil
+}
+
+// describeStepRequires gives a human-readable description of the required vars
+// of a step for use in edit output.
+func describeStepRequires(vars []string, varPrefix string) string {
+	if len(vars) == 0 {
+		return "require nothing"
+	}
+
+	names := make([]string, len(vars))
+	for i := range vars {
+		names[i] = fmt.Sprintf("%s{%s}", varPrefix, vars[i])
+	}
+	return "require " + strings.Join(names, ", ")
+}
+
 func parseFlowMoveArg(s string) (flowStepMove, error) {
 	var move flowStepMove
 
@@ -654,6 +1458,37 @@ func parseFlowMoveArg(s string) (flowStepMove, error) {
 	return move, nil
 }
 
+func parseFlowReorderArg(s string) ([]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("must give the index of every step")
+	}
+
+	parts := strings.Split(s, ",")
+	order := make([]int, len(parts))
+	for i, p := range parts {
+		var err error
+		order[i], err = strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("not a valid step index: %q", p)
+		}
+	}
+
+	return order, nil
+}
+
+func parseFlowRelativeAddArg(s string, after bool) (flowStepRelativeAdd, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return flowStepRelativeAdd{}, fmt.Errorf("not in REF:REQ format: %q", s)
+	}
+
+	return flowStepRelativeAdd{
+		ref:      strings.ToLower(parts[0]),
+		template: strings.ToLower(parts[1]),
+		after:    after,
+	}, nil
+}
+
 func parseFlowUpsertArg(s string, optionalIndex bool) (flowStepUpsert, error) {
 	var ups flowStepUpsert
 	parts := strings.SplitN(s, ":", 2)
@@ -678,7 +1513,7 @@ func parseFlowUpsertArg(s string, optionalIndex bool) (flowStepUpsert, error) {
 
 func flowsSetFlagIsPresent(cmd *cobra.Command) bool {
 	f := cmd.Flags()
-	return f.Changed("add") || f.Changed("remove") || f.Changed("move") || f.Changed("update") || f.Changed("name")
+	return f.Changed("add") || f.Changed("add-before") || f.Changed("add-after") || f.Changed("remove") || f.Changed("move") || f.Changed("reorder") || f.Changed("update") || f.Changed("name") || f.Changed("require") || f.Changed("group") || f.Changed("assert-status") || f.Changed("assert-body-contains") || f.Changed("assert")
 }
 
 type flowAction int
@@ -690,6 +1525,7 @@ const (
 	flowsActionDelete
 	flowsActionGet
 	flowsActionEdit
+	flowsActionCopy
 )
 
 // probs overengineered given there is ONE flow attribute constant other than
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"net/http"
 	"strings"
 	"testing"
 
@@ -96,6 +97,86 @@ func Test_Flows_Delete(t *testing.T) {
 	}
 }
 
+func Test_Flows_Copy(t *testing.T) {
+	withCopy := func() morc.Project {
+		p := testProject_singleFlowWithNStepsAndRequires(3, 1, "USER_ID")
+		p.Flows["test2"] = morc.Flow{Name: "test2", Steps: p.Flows[testFlowName].Copy().Steps}
+		return p
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectP            morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "normal copy",
+			args:               []string{"flows", "--copy", "TEST", "Test2"},
+			p:                  testProject_singleFlowWithNStepsAndRequires(3, 1, "USER_ID"),
+			expectP:            withCopy(),
+			expectStdoutOutput: "Copied flow test to test2\n",
+		},
+		{
+			name:      "source does not exist",
+			args:      []string{"flows", "--copy", "test3", "test2"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "no flow named test3 exists",
+		},
+		{
+			name:      "destination already exists",
+			args:      []string{"flows", "--copy", "test", "test2"},
+			p:         withCopy(),
+			expectErr: "flow named test2 already exists",
+		},
+		{
+			name:      "missing destination",
+			args:      []string{"flows", "--copy", "test"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "missing name of DEST to copy to",
+		},
+		{
+			name:      "with modification flags",
+			args:      []string{"flows", "--copy", "test", "test2", "-r", "0"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "--copy cannot be given with flags that modify a flow",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetFlowsFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, _, err := runTestCommand(flowsCmd, projFilePath, tc.args)
+
+			// assert and check stdout
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			}
+			if tc.expectErr != "" {
+				t.Fatalf("expected returned error to contain %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+
+			assert_projectFilesInBuffersMatch(assert, tc.expectP)
+		})
+	}
+}
+
 func Test_Flows_Edit(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -190,6 +271,191 @@ func Test_Flows_Edit(t *testing.T) {
 			expectP:            testProject_singleFlowWithNSteps(3),
 			expectStderrOutput: "No change to step[2]; already set to index 2\n",
 		},
+		{
+			name:               "add before named step",
+			args:               []string{"flows", "test", "--add-before", "REQ2:req3"},
+			p:                  testProject_singleFlowWithNSteps(3),
+			expectP:            testProject_3Requests_singleFlowWithSequence(1, 3, 2, 3),
+			expectStdoutOutput: "Set step[1] to req3 (added)\n",
+		},
+		{
+			name:               "add after named step",
+			args:               []string{"flows", "test", "--add-after", "req3:req1"},
+			p:                  testProject_singleFlowWithNSteps(3),
+			expectP:            testProject_3Requests_singleFlowWithSequence(1, 2, 3, 1),
+			expectStdoutOutput: "Set step[3] to req1 (added)\n",
+		},
+		{
+			name:               "add after named step uses first step that calls it",
+			args:               []string{"flows", "test", "--add-after", "req1:req2"},
+			p:                  testProject_3Requests_singleFlowWithSequence(1, 3, 1),
+			expectP:            testProject_3Requests_singleFlowWithSequence(1, 2, 3, 1),
+			expectStdoutOutput: "Set step[1] to req2 (added)\n",
+		},
+		{
+			name:               "add before and after resolves each after prior adds",
+			args:               []string{"flows", "test", "--add-after", "req1:req2", "--add-before", "req1:req3"},
+			p:                  testProject_singleFlowWithNSteps(3),
+			expectP:            testProject_3Requests_singleFlowWithSequence(3, 1, 2, 2, 3),
+			expectStdoutOutput: "Set step[0] to req3 (added) and step[2] to req2 (added)\n",
+		},
+		{
+			name:      "add before step that is not in flow",
+			args:      []string{"flows", "test", "--add-before", "login:req1"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "cannot add step before login: no step calls request template login",
+		},
+		{
+			name:      "add after with missing request",
+			args:      []string{"flows", "test", "--add-after", "req1:"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "--add-after #1: not in REF:REQ format: \"req1:\"",
+		},
+		{
+			name:      "add after with nonexistent request",
+			args:      []string{"flows", "test", "--add-after", "req1:logout"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "no request template \"logout\" in project",
+		},
+		{
+			name:               "reorder all steps",
+			args:               []string{"flows", "test", "--reorder", "2,0,1"},
+			p:                  testProject_singleFlowWithNSteps(3),
+			expectP:            testProject_singleFlowWithSequence(3, 1, 2),
+			expectStdoutOutput: "Set step[0] to index 1, step[1] to index 2, and step[2] to index 0\n",
+		},
+		{
+			name:               "reorder some steps",
+			args:               []string{"flows", "test", "--reorder", "1, 0, 2"},
+			p:                  testProject_singleFlowWithNSteps(3),
+			expectP:            testProject_singleFlowWithSequence(2, 1, 3),
+			expectStdoutOutput: "Set step[0] to index 1 and step[1] to index 0\n",
+			expectStderrOutput: "No change to step[2]; already set to index 2\n",
+		},
+		{
+			name:      "reorder missing a step",
+			args:      []string{"flows", "test", "--reorder", "2,0"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "cannot reorder steps: order has 2 indexes but flow has 3 steps",
+		},
+		{
+			name:      "reorder with repeated step",
+			args:      []string{"flows", "test", "--reorder", "2,0,2"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "cannot reorder steps: step 2 is given more than once",
+		},
+		{
+			name:      "reorder with out of range step",
+			args:      []string{"flows", "test", "--reorder", "3,0,1"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "cannot reorder steps: 3 is not a step index; must be between 0 and 2",
+		},
+		{
+			name:      "reorder with bad index",
+			args:      []string{"flows", "test", "--reorder", "2,x,1"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "--reorder: not a valid step index: \"x\"",
+		},
+		{
+			name:      "reorder with move",
+			args:      []string{"flows", "test", "--reorder", "2,0,1", "-m", "0:1"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "if any flags in the group [reorder move] are set none of the others can be",
+		},
+		{
+			name:               "set required vars",
+			args:               []string{"flows", "test", "-R", "1:token,user_id"},
+			p:                  testProject_singleFlowWithNSteps(3),
+			expectP:            testProject_singleFlowWithNStepsAndRequires(3, 1, "TOKEN", "USER_ID"),
+			expectStdoutOutput: "Set step[1] to require ${TOKEN}, ${USER_ID}\n",
+		},
+		{
+			name:               "clear required vars",
+			args:               []string{"flows", "test", "-R", "1:"},
+			p:                  testProject_singleFlowWithNStepsAndRequires(3, 1, "TOKEN"),
+			expectP:            testProject_singleFlowWithNSteps(3),
+			expectStdoutOutput: "Set step[1] to require nothing\n",
+		},
+		{
+			name:               "set parallel groups",
+			args:               []string{"flows", "test", "-g", "0:1", "-g", "1:1"},
+			p:                  testProject_singleFlowWithNSteps(3),
+			expectP:            testProject_singleFlowWithNStepsAndGroups(1, 1, 0),
+			expectStdoutOutput: "Set step[0] to parallel group 1 and step[1] to parallel group 1\n",
+		},
+		{
+			name:               "clear parallel group",
+			args:               []string{"flows", "test", "-g", "1:"},
+			p:                  testProject_singleFlowWithNStepsAndGroups(1, 1, 0),
+			expectP:            testProject_singleFlowWithNStepsAndGroups(1, 0, 0),
+			expectStdoutOutput: "Set step[1] to no parallel group\n",
+		},
+		{
+			name:      "negative parallel group",
+			args:      []string{"flows", "test", "-g", "1:-2"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "--group #1: GROUP cannot be negative",
+		},
+		{
+			name:               "set status assertion",
+			args:               []string{"flows", "test", "--assert-status", "1:200"},
+			p:                  testProject_singleFlowWithNSteps(3),
+			expectP:            testProject_singleFlowWithNStepsAndAsserts(3, 1, 200),
+			expectStdoutOutput: "Set step[1] to assert status 200\n",
+		},
+		{
+			name:               "clear status assertion",
+			args:               []string{"flows", "test", "--assert-status", "1:"},
+			p:                  testProject_singleFlowWithNStepsAndAsserts(3, 1, 200),
+			expectP:            testProject_singleFlowWithNSteps(3),
+			expectStdoutOutput: "Set step[1] to assert any status\n",
+		},
+		{
+			name:      "invalid status assertion",
+			args:      []string{"flows", "test", "--assert-status", "1:42"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "--assert-status #1: 42 is not a valid HTTP status code",
+		},
+		{
+			name:               "add body assertions",
+			args:               []string{"flows", "test", "--assert-body-contains", "1:ok", "--assert-body-contains", "1:id: 8"},
+			p:                  testProject_singleFlowWithNSteps(3),
+			expectP:            testProject_singleFlowWithNStepsAndAsserts(3, 1, 0, "ok", "id: 8"),
+			expectStdoutOutput: "Set step[1] to assert body contains \"ok\" and step[1] to assert body contains \"ok\", \"id: 8\"\n",
+		},
+		{
+			name:               "clear body assertions",
+			args:               []string{"flows", "test", "--assert-body-contains", "1:"},
+			p:                  testProject_singleFlowWithNStepsAndAsserts(3, 1, 0, "ok"),
+			expectP:            testProject_singleFlowWithNSteps(3),
+			expectStdoutOutput: "Set step[1] to assert any body\n",
+		},
+		{
+			name:               "add JSON assertions",
+			args:               []string{"flows", "test", "--assert", "1:.count==5", "--assert", "1:.data.id != null"},
+			p:                  testProject_singleFlowWithNSteps(3),
+			expectP:            testProject_singleFlowWithNStepsAndJSONAsserts(3, 1, ".count == 5", ".data.id != null"),
+			expectStdoutOutput: "Set step[1] to assert '.count == 5' and step[1] to assert '.count == 5', '.data.id != null'\n",
+		},
+		{
+			name:               "clear JSON assertions",
+			args:               []string{"flows", "test", "--assert", "1:"},
+			p:                  testProject_singleFlowWithNStepsAndJSONAsserts(3, 1, ".count == 5"),
+			expectP:            testProject_singleFlowWithNSteps(3),
+			expectStdoutOutput: "Set step[1] to assert nothing in JSON body\n",
+		},
+		{
+			name:      "invalid JSON assertion",
+			args:      []string{"flows", "test", "--assert", "1:.count == five"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "--assert #1: value five is not a JSON literal",
+		},
+		{
+			name:      "required var with invalid name",
+			args:      []string{"flows", "test", "-R", "1:tok en"},
+			p:         testProject_singleFlowWithNSteps(3),
+			expectErr: "--require #1: invalid var name",
+		},
 		{
 			name:               "no-op move, quiet mode",
 			args:               []string{"flows", "test", "-m", "2:", "-q"},
@@ -457,6 +723,247 @@ func Test_Flows_Show(t *testing.T) {
 			p:                  testProject_singleFlowWithNSteps(2),
 			expectStdoutOutput: "0: req1 (GET https://example.com)\n1: req2 (POST https://example.com)\n",
 		},
+		{
+			name:               "flow is present - steps in parallel group",
+			args:               []string{"flows", "test"},
+			p:                  testProject_singleFlowWithNStepsAndGroups(2, 2),
+			expectStdoutOutput: "0: req1 (GET https://example.com) [group 2]\n1: req2 (POST https://example.com) [group 2]\n",
+		},
+		{
+			name:               "flow is present - step has assertions",
+			args:               []string{"flows", "test"},
+			p:                  testProject_singleFlowWithNStepsAndAsserts(2, 0, 200, "ok"),
+			expectStdoutOutput: "0: req1 (GET https://example.com) asserts status 200, body contains \"ok\"\n1: req2 (POST https://example.com)\n",
+		},
+		{
+			name:               "flow is present - step has required vars",
+			args:               []string{"flows", "test"},
+			p:                  testProject_singleFlowWithNStepsAndRequires(2, 1, "TOKEN", "USER"),
+			expectStdoutOutput: "0: req1 (GET https://example.com)\n1: req2 (POST https://example.com) requires ${TOKEN}, ${USER}\n",
+		},
+		{
+			name: "json output",
+			args: []string{"flows", "test", "--output", "json"},
+			p: morc.Project{
+				Flows: map[string]morc.Flow{
+					testFlowName: {
+						Name: testFlowName,
+						Steps: []morc.FlowStep{
+							{Template: testReq(1), Group: 1},
+							{Template: testReq(2), Requires: []string{"TOKEN"}},
+						},
+					},
+				},
+				Templates: testRequestsN(1),
+			},
+			expectStdoutOutput: `{
+  "name": "test",
+  "execable": false,
+  "steps": [
+    {
+      "template": "req1",
+      "exists": true,
+      "method": "GET",
+      "url": "https://example.com",
+      "requires": [],
+      "group": 1,
+      "assert_status": 0,
+      "assert_body_contains": [],
+      "assert_json": []
+    },
+    {
+      "template": "req2",
+      "exists": false,
+      "method": "",
+      "url": "",
+      "requires": [
+        "TOKEN"
+      ],
+      "group": 0,
+      "assert_status": 0,
+      "assert_body_contains": [],
+      "assert_json": []
+    }
+  ]
+}
+`,
+		},
+		{
+			name: "json output - inline",
+			args: []string{"flows", "test", "--output", "json", "--inline"},
+			p:    testProject_singleFlowWithNSteps(1),
+			expectStdoutOutput: `{
+  "name": "test",
+  "execable": true,
+  "steps": [
+    {
+      "template": "req1",
+      "exists": true,
+      "method": "GET",
+      "url": "https://example.com",
+      "requires": [],
+      "group": 0,
+      "assert_status": 0,
+      "assert_body_contains": [],
+      "assert_json": [],
+      "request": {
+        "name": "req1",
+        "method": "GET",
+        "url": "https://example.com",
+        "headers": {},
+        "body": "",
+        "body_file": "",
+        "captures": [],
+        "auth_flow": "",
+        "sendable": true
+      }
+    }
+  ]
+}
+`,
+		},
+		{
+			name: "inline - shows request details for each step",
+			args: []string{"flows", "test", "--inline"},
+			p: morc.Project{
+				Flows: testFlows_singleFlowWithNSteps(3),
+				Templates: map[string]morc.RequestTemplate{
+					testReq(1): {
+						Name:     testReq(1),
+						Method:   "POST",
+						URL:      "https://example.com/login",
+						Headers:  http.Header{"Content-Type": {"application/json"}},
+						Body:     []byte(`{"user": "vriska"}`),
+						Captures: map[string]morc.VarScraper{"TOKEN": {Name: "TOKEN", Steps: []morc.TraversalStep{{Key: "token"}}}},
+					},
+					testReq(2): {
+						Name: testReq(2),
+						URL:  "https://example.com/users",
+					},
+				},
+			},
+			expectStdoutOutput: "0: req1 (POST https://example.com/login)\n" +
+				"POST https://example.com/login\n" +
+				"\n" +
+				"HEADERS:\n" +
+				"Content-Type: application/json\n" +
+				"\n" +
+				"BODY:\n" +
+				"{\"user\": \"vriska\"}\n" +
+				"\n" +
+				"VAR CAPTURES:\n" +
+				"$TOKEN from .token\n" +
+				"\n" +
+				"AUTH FLOW: (none)\n" +
+				"\n" +
+				"1:! req2 (??? https://example.com/users)\n" +
+				"(no-method) https://example.com/users\n" +
+				"\n" +
+				"HEADERS: (none)\n" +
+				"\n" +
+				"BODY: (none)\n" +
+				"\n" +
+				"VAR CAPTURES: (none)\n" +
+				"\n" +
+				"AUTH FLOW: (none)\n" +
+				"\n" +
+				"2:! req3 (!non-existent req)\n",
+		},
+		{
+			name: "resolve - shows resolved request for each step",
+			args: []string{"flows", "test", "--resolve"},
+			p: morc.Project{
+				Flows: testFlows_singleFlowWithNSteps(3),
+				Templates: map[string]morc.RequestTemplate{
+					testReq(1): {
+						Name:     testReq(1),
+						Method:   "POST",
+						URL:      "https://${HOST}/login",
+						Headers:  http.Header{"Content-Type": {"application/json"}},
+						Body:     []byte(`{"user": "${USER}"}`),
+						Captures: map[string]morc.VarScraper{"TOKEN": {Name: "TOKEN", Steps: []morc.TraversalStep{{Key: "token"}}}},
+					},
+					testReq(2): {
+						Name:    testReq(2),
+						Method:  "GET",
+						URL:     "https://${HOST}/users",
+						Headers: http.Header{"Authorization": {"Bearer ${TOKEN}"}},
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"HOST": "example.com", "USER": "vriska", "TOKEN": "old"},
+				}),
+			},
+			expectStdoutOutput: "0: req1 (POST https://${HOST}/login)\n" +
+				"------------------- REQUEST -------------------\n" +
+				"Request URI: https://example.com/login\n" +
+				"\n" +
+				"POST /login HTTP/1.1\r\n" +
+				"Host: example.com\r\n" +
+				"User-Agent: Go-http-client/1.1\r\n" +
+				"Content-Length: 18\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Accept-Encoding: gzip\r\n" +
+				"\r\n" +
+				`{"user": "vriska"}` + "\n" +
+				"----------------- END REQUEST -----------------\n" +
+				"\n" +
+				"1: req2 (GET https://${HOST}/users)\n" +
+				"------------------- REQUEST -------------------\n" +
+				"Request URI: https://example.com/users\n" +
+				"\n" +
+				"GET /users HTTP/1.1\r\n" +
+				"Host: example.com\r\n" +
+				"User-Agent: Go-http-client/1.1\r\n" +
+				"Authorization: Bearer ${TOKEN}\r\n" +
+				"Accept-Encoding: gzip\r\n" +
+				"\r\n" +
+				"\n" +
+				"(no request body)\n" +
+				"----------------- END REQUEST -----------------\n" +
+				"\n" +
+				"2:! req3 (!non-existent req)\n",
+		},
+		{
+			name: "resolve - undefined var",
+			args: []string{"flows", "test", "--resolve"},
+			p: morc.Project{
+				Flows: testFlows_singleFlowWithNSteps(1),
+				Templates: map[string]morc.RequestTemplate{
+					testReq(1): {
+						Name:   testReq(1),
+						Method: "GET",
+						URL:    "https://example.com/${ID}",
+					},
+				},
+			},
+			expectErr:          "step #0",
+			expectStdoutOutput: "0: req1 (GET https://example.com/${ID})\n",
+		},
+		{
+			name:      "resolve - not showing a flow",
+			args:      []string{"flows", "--resolve"},
+			p:         testProject_singleFlowWithNSteps(2),
+			expectErr: "--resolve can only be used when showing a flow",
+		},
+		{
+			name:      "resolve - with inline",
+			args:      []string{"flows", "test", "--resolve", "--inline"},
+			p:         testProject_singleFlowWithNSteps(2),
+			expectErr: "--resolve and --inline cannot be given together",
+		},
+		{
+			name:      "resolve - with json output",
+			args:      []string{"flows", "test", "--resolve", "--output", "json"},
+			p:         testProject_singleFlowWithNSteps(2),
+			expectErr: "--resolve cannot be used with --output json",
+		},
+		{
+			name:      "inline - not showing a flow",
+			args:      []string{"flows", "--inline"},
+			p:         testProject_singleFlowWithNSteps(2),
+			expectErr: "--inline can only be used when showing a flow",
+		},
 		{
 			name:               "flow is present - all steps are valid, quiet mode still prints",
 			args:               []string{"flows", "test", "-q"},
@@ -561,6 +1068,48 @@ func Test_Flows_List(t *testing.T) {
 			},
 			expectStdoutOutput: "test:! 1 request\n",
 		},
+		{
+			name:               "json output - no flows",
+			args:               []string{"flows", "--output", "json"},
+			p:                  morc.Project{},
+			expectStdoutOutput: "[]\n",
+		},
+		{
+			name: "json output - multiple flows",
+			args: []string{"flows", "--output", "json"},
+			p: morc.Project{
+				Flows: map[string]morc.Flow{
+					"test": testFlows_singleFlowWithNSteps(2)["test"],
+					"aaa":  {Name: "aaa", Steps: []morc.FlowStep{{Template: "req3"}}},
+				},
+				Templates: testRequestsN(2),
+			},
+			expectStdoutOutput: `[
+  {
+    "name": "aaa",
+    "steps": 1,
+    "execable": false
+  },
+  {
+    "name": "test",
+    "steps": 2,
+    "execable": true
+  }
+]
+`,
+		},
+		{
+			name:      "json output - invalid format",
+			args:      []string{"flows", "--output", "yaml"},
+			p:         testProject_singleFlowWithNSteps(1),
+			expectErr: "invalid list output format \"yaml\"",
+		},
+		{
+			name:      "json output - not listing or showing",
+			args:      []string{"flows", "--delete", "test", "--output", "json"},
+			p:         testProject_singleFlowWithNSteps(1),
+			expectErr: "--output can only be used when listing or showing flows",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -600,11 +1149,23 @@ func resetFlowsFlags() {
 	flags.New = ""
 	flags.Delete = ""
 	flags.Get = ""
+	flags.Copy = ""
 	flags.Name = ""
 	flags.StepRemovals = nil
 	flags.StepAdds = nil
+	flags.StepAddsBefore = nil
+	flags.StepAddsAfter = nil
 	flags.StepMoves = nil
+	flags.StepReorder = ""
 	flags.StepReplaces = nil
+	flags.StepRequires = nil
+	flags.StepGroups = nil
+	flags.StepAssertStatus = nil
+	flags.StepAssertBodyContains = nil
+	flags.StepAssertJSON = nil
+	flags.ListOutput = "text"
+	flags.BInline = false
+	flags.BResolve = false
 	flags.BQuiet = false
 
 	flowsCmd.Flags().VisitAll(func(fl *pflag.Flag) {
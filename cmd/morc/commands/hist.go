@@ -1,12 +1,19 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dekarrin/morc"
 	"github.com/dekarrin/morc/cmd/morc/cmdio"
+	"github.com/dekarrin/morc/internal/sliceops"
 	"github.com/spf13/cobra"
 )
 
@@ -14,18 +21,48 @@ var histCmd = &cobra.Command{
 	Use: "hist [ENTRY]",
 	Annotations: map[string]string{
 		annotationKeyHelpUsages: "" +
-			"hist\n" +
+			"hist [--tail N] [--follow] [--filter-status CODE] [--filter-template REQ] [--since TIME]\n" +
 			"hist ENTRY [output-flags]\n" +
-			"hist [--on | --off | --clear | --info]",
+			"hist --replay ENTRY [--host HOST [--env ENV]] [output-flags]\n" +
+			"hist [--on | --off | --clear | --info]\n" +
+			"hist [--clear-before TIME | --clear-keep-last N]",
 	},
 	GroupID: "project",
 	Short:   "View and perform operations on request template sending history",
 	Long: "With no other arguments, prints out a listing of all summarized entries in the history. If an ENTRY is " +
-		"given by index number from the listing, the exact response as received from the original send of the " +
-		"template is printed. If --on is given, request history is enabled for future requests made by calling morc " +
+		"given by index number from the listing, the full details of that entry are printed: the request as it was " +
+		"sent, including its headers and body, followed by the vars captured from the response and the exact " +
+		"response as received, including its headers and body, along with the times the request was sent and the " +
+		"response received and the total round-trip time. If any of --request, --headers, --captures, or --no-body " +
+		"are given, only the parts selected by the output flags are shown, the same as for morc send. With --format " +
+		"json, the entry is printed as the same JSON object that morc send prints, with only the total time given " +
+		"in the timings. If --on is given, request history is enabled for future requests made by calling morc " +
 		"send or morc exec. If --off is given, history is instead disabled, although existing entries are kept. If " +
 		"--info is given, basic info about the history as a whole is output. If --clear is given, all existing " +
 		"history entries are immediately deleted.\n\n" +
+		"To trim the history instead of deleting all of it, give --clear-before TIME to delete every entry sent " +
+		"before TIME, which is in the same format as for --since, or --clear-keep-last N to delete all but the N " +
+		"most recently sent entries.\n\n" +
+		"When listing, --tail N limits the listing to only the last N entries. If --follow is given, after the " +
+		"listing is printed, morc will continue to watch the history file and print new entries as they are added, " +
+		"until interrupted. If the history file is cleared or replaced while being followed, listing starts over from " +
+		"the first entry in the new file.\n\n" +
+		"The listing can be narrowed with --filter-status CODE to show only entries whose response had that status " +
+		"code, --filter-template REQ to show only entries sent from the given request template, and --since TIME to " +
+		"show only entries sent at or after TIME, which is given either as a date in YYYY-MM-DD format (taken as " +
+		"midnight local time) or as a full RFC 3339 timestamp. If more than one filter is given, only entries that " +
+		"match all of them are shown. Entries keep their original index numbers in a filtered listing, and --tail " +
+		"applies to the filtered entries.\n\n" +
+		"To send the request of an entry again, give its index number to --replay. The request is sent exactly as it " +
+		"was recorded, with no var substitution, and the response is output the same as for morc send. Any cookies " +
+		"in the session are sent along with it and updated as normal, and if history is enabled, the replay is " +
+		"recorded as a new entry for the same request template. Captures of the request template are not run, so " +
+		"no vars are changed by a replay. Give --host to send the request to HOST instead of the host it was " +
+		"originally sent to; HOST may include a port and a scheme, such as 'https://example.com:8443', and may " +
+		"contain var references, which are filled in from the current environment or from the environment ENV if " +
+		"--env is given. This allows a request made against one environment to be replayed against another with, " +
+		"for example, --host '${HOST}' --env PROD. An entry whose request had the values of secret vars redacted " +
+		"from it when it was recorded cannot be replayed.\n\n" +
 		"History only applies to requests created from request templates in a project; one-off requests such as those " +
 		"sent by 'morc oneoff' or any of the method shorthand versions are not saved in history.",
 	Args: cobra.MaximumNArgs(1),
@@ -42,13 +79,15 @@ var histCmd = &cobra.Command{
 
 		switch args.action {
 		case histActionList:
-			return invokeHistList(io, args.projFile)
+			return invokeHistList(cmd.Context(), io, args.projFile, args.tail, args.follow, args.filter)
+		case histActionReplay:
+			return invokeHistReplay(io, args.projFile, args.entry, args.host, args.env, args.outputCtrl)
 		case histActionDetail:
 			return invokeHistDetail(io, args.projFile, args.entry, args.outputCtrl, args.noDates)
 		case histActionInfo:
 			return invokeHistInfo(io, args.projFile)
 		case histActionClear:
-			return invokeHistClear(io, args.projFile)
+			return invokeHistClear(io, args.projFile, args.clearBefore, args.keepLast)
 		case histActionEnable:
 			return invokeHistOn(io, args.projFile)
 		case histActionDisable:
@@ -63,13 +102,28 @@ func init() {
 	histCmd.PersistentFlags().StringVarP(&flags.ProjectFile, "project-file", "F", morc.DefaultProjectPath, "Use `FILE` for project data instead of "+morc.DefaultProjectPath+".")
 	histCmd.PersistentFlags().BoolVarP(&flags.BInfo, "info", "", false, "Print summarizing information about the history")
 	histCmd.PersistentFlags().BoolVarP(&flags.BClear, "clear", "", false, "Delete all history entries")
+	histCmd.PersistentFlags().StringVarP(&flags.ClearBefore, "clear-before", "", "", "Delete all history entries sent before `TIME`, given as YYYY-MM-DD or an RFC 3339 timestamp.")
+	histCmd.PersistentFlags().IntVarP(&flags.ClearKeepLast, "clear-keep-last", "", -1, "Delete all history entries except for the `N` most recently sent.")
 	histCmd.PersistentFlags().BoolVarP(&flags.BEnable, "on", "", false, "Enable history for future requests")
 	histCmd.PersistentFlags().BoolVarP(&flags.BDisable, "off", "", false, "Disable history for future requests")
 	histCmd.PersistentFlags().BoolVarP(&flags.BNoDates, "no-dates", "", false, "(Output flag) Do not prefix the request with the date of request and response with date of response. Only used with 'hist ENTRY'")
 	histCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
+	histCmd.PersistentFlags().IntVarP(&flags.Tail, "tail", "", -1, "List only the last `N` entries of the history.")
+	histCmd.PersistentFlags().BoolVarP(&flags.BFollow, "follow", "", false, "After listing, keep watching the history file and print new entries as they are added.")
+	histCmd.PersistentFlags().IntVarP(&flags.FilterStatus, "filter-status", "", 0, "List only entries whose response had status code `CODE`.")
+	histCmd.PersistentFlags().StringVarP(&flags.FilterTemplate, "filter-template", "", "", "List only entries sent from request template `REQ`.")
+	histCmd.PersistentFlags().IntVarP(&flags.Replay, "replay", "", -1, "Send the request of history entry `ENTRY` again, exactly as it was recorded.")
+	histCmd.PersistentFlags().StringVarP(&flags.Host, "host", "", "", "Send the replayed request to `HOST` instead of its original host. HOST may include a scheme and port, and vars in it are filled in.")
+	histCmd.PersistentFlags().StringVarP(&flags.Env, "env", "", "", "Fill in vars in the --host of a replay from environment `ENV` instead of the current one.")
+	histCmd.PersistentFlags().StringVarP(&flags.Since, "since", "", "", "List only entries sent at or after `TIME`, given as YYYY-MM-DD or an RFC 3339 timestamp.")
 
 	// mark the delete and default flags as mutually exclusive
-	histCmd.MarkFlagsMutuallyExclusive("on", "off", "clear", "info")
+	histCmd.MarkFlagsMutuallyExclusive("on", "off", "clear", "info", "replay", "clear-before", "clear-keep-last")
+	histCmd.MarkFlagsMutuallyExclusive("tail", "on", "off", "clear", "info", "replay", "clear-before", "clear-keep-last")
+	histCmd.MarkFlagsMutuallyExclusive("follow", "on", "off", "clear", "info", "replay", "clear-before", "clear-keep-last")
+	histCmd.MarkFlagsMutuallyExclusive("filter-status", "on", "off", "clear", "info", "replay", "clear-before", "clear-keep-last")
+	histCmd.MarkFlagsMutuallyExclusive("filter-template", "on", "off", "clear", "info", "replay", "clear-before", "clear-keep-last")
+	histCmd.MarkFlagsMutuallyExclusive("since", "on", "off", "clear", "info", "replay", "clear-before", "clear-keep-last")
 
 	addRequestOutputFlags(histCmd)
 
@@ -90,8 +144,18 @@ func invokeHistDetail(io cmdio.IO, projFile string, entry int, reqOC morc.Output
 	}
 
 	hist := p.History[entry]
+	reqOC.Mask = morc.SecretForms(p.Vars.SecretValues())
 
-	io.Printf("Request template: %s\n", hist.Template)
+	if reqOC.Format == morc.FormatJSON {
+		resp := *hist.Response
+		resp.Request = hist.Request
+		timings := &morc.Timings{Total: hist.RespTime.Sub(hist.ReqTime)}
+
+		reqOC.Writer = io.Out
+		return morc.OutputResponse(&resp, hist.Captures, timings, reqOC)
+	}
+
+	io.Printf("Request template: %s\n", histTemplateName(hist))
 
 	if !noDates {
 		io.Printf("Request sent:          %s\n", hist.ReqTime.Format(time.RFC3339))
@@ -104,13 +168,136 @@ func invokeHistDetail(io cmdio.IO, projFile string, entry int, reqOC morc.Output
 		return err
 	}
 
-	if err := morc.OutputResponse(hist.Response, hist.Captures, reqOC); err != nil {
+	if err := morc.OutputResponse(hist.Response, hist.Captures, nil, reqOC); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+func invokeHistReplay(io cmdio.IO, projFile string, entry int, host string, env optional[string], oc morc.OutputControl) error {
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	if entry < 0 {
+		return fmt.Errorf("entry number must be positive")
+	}
+	if entry >= len(p.History) {
+		return fmt.Errorf("can't get entry %d; %d is the highest entry available", entry, len(p.History)-1)
+	}
+
+	// a redacted request would be sent with the mask in place of the secrets
+	// it had, which is never what was wanted
+	if p.History[entry].Redacted {
+		return fmt.Errorf("entry %d: secret values in the request were redacted when it was recorded, so it cannot be sent again as it was", entry)
+	}
+
+	tmpl, err := replayTemplate(p.History[entry])
+	if err != nil {
+		return fmt.Errorf("entry %d: %w", entry, err)
+	}
+
+	if host != "" {
+		vars := p.Vars
+		if env.set {
+			envUpper := strings.ToUpper(env.v)
+			if envUpper == reservedDefaultEnvName {
+				envUpper = ""
+			} else if sliceops.Index(vars.EnvNames(), envUpper) < 0 {
+				return fmt.Errorf("environment %q does not contain any variables", env.v)
+			}
+			vars.Environment = envUpper
+		}
+
+		client := morc.NewRESTClient(0, nil)
+		client.VarPrefix = p.VarPrefix()
+		client.Vars = vars.MergedSet(nil)
+		host, err = client.Substitute(host)
+		if err != nil {
+			return fmt.Errorf("--host: %w", err)
+		}
+
+		tmpl.URL, err = replaceURLHost(tmpl.URL, host)
+		if err != nil {
+			return fmt.Errorf("--host: %w", err)
+		}
+	}
+
+	oc.Writer = io.Out
+
+	sendOpts, err := templateSendOptions(&p, tmpl, nil, false, oc, transportOptions{})
+	if err != nil {
+		return err
+	}
+	// the recorded request already has every header that was sent, including
+	// any defaults, and all of its vars are already filled in.
+	sendOpts.DefaultHeaders = nil
+	sendOpts.NoSubstitute = true
+
+	result, err := morc.Send(tmpl.Method, tmpl.URL, p.VarPrefix(), sendOpts)
+	if err != nil {
+		return err
+	}
+
+	return recordSendResult(&p, tmpl, result, sendOpts.Output.Mask, false, recordOverrides{})
+}
+
+// replayTemplate returns a request template that, when sent with no var
+// substitution, sends the same request that was recorded in h. It has the name
+// of the template that h was sent from and no captures.
+func replayTemplate(h morc.HistoryEntry) (morc.RequestTemplate, error) {
+	if h.Request == nil || h.Request.URL == nil {
+		return morc.RequestTemplate{}, fmt.Errorf("no request was recorded")
+	}
+
+	tmpl := morc.RequestTemplate{
+		Name:    h.Template,
+		Method:  h.Request.Method,
+		URL:     h.Request.URL.String(),
+		Headers: h.Request.Header.Clone(),
+	}
+
+	if h.Request.Body != nil && h.Request.Body != http.NoBody {
+		body, err := io.ReadAll(h.Request.Body)
+		if err != nil {
+			return morc.RequestTemplate{}, fmt.Errorf("read recorded request body: %w", err)
+		}
+		tmpl.Body = body
+	}
+
+	return tmpl, nil
+}
+
+// replaceURLHost returns rawURL with its host replaced by host. If host
+// includes a scheme, the scheme of rawURL is replaced as well.
+func replaceURLHost(rawURL, host string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.Contains(host, "://") {
+		hostURL, err := url.Parse(host)
+		if err != nil {
+			return "", err
+		}
+		if hostURL.Host == "" {
+			return "", fmt.Errorf("%q does not contain a host", host)
+		}
+		u.Scheme = hostURL.Scheme
+		u.Host = hostURL.Host
+	} else {
+		if strings.ContainsAny(host, "/?#") {
+			return "", fmt.Errorf("%q is not a host; give only a host and optional scheme and port", host)
+		}
+		u.Host = host
+	}
+
+	return u.String(), nil
+}
+
 func invokeHistOn(io cmdio.IO, projFile string) error {
 	p, err := readProject(projFile, true)
 	if err != nil {
@@ -150,23 +337,77 @@ func invokeHistOff(io cmdio.IO, projFile string) error {
 	return nil
 }
 
-func invokeHistClear(io cmdio.IO, projFile string) error {
+// invokeHistClear deletes history entries. If before is set, only entries sent
+// before it are deleted; otherwise, if keepLast is non-negative, all but the
+// keepLast most recently sent entries are deleted. If neither is given, all
+// entries are deleted.
+func invokeHistClear(io cmdio.IO, projFile string, before time.Time, keepLast int) error {
 	p, err := readProject(projFile, true)
 	if err != nil {
 		return err
 	}
 
-	p.History = nil
+	if before.IsZero() && keepLast < 0 {
+		p.History = nil
 
-	if err := writeHistory(p); err != nil {
-		return err
+		if err := writeHistory(p); err != nil {
+			return err
+		}
+
+		io.PrintLoudf("History cleared")
+		return nil
 	}
 
-	io.PrintLoudf("History cleared")
+	var kept []morc.HistoryEntry
+	if !before.IsZero() {
+		for _, idx := range (morc.HistoryFilter{Since: before}).Filter(p.History) {
+			kept = append(kept, p.History[idx])
+		}
+	} else {
+		kept = histKeepLast(p.History, keepLast)
+	}
 
+	removed := len(p.History) - len(kept)
+	if removed > 0 {
+		p.History = kept
+		if err := writeHistory(p); err != nil {
+			return err
+		}
+	}
+
+	io.PrintLoudf("Deleted %s; %d remaining\n", io.CountOf(removed, "history entr", "ies", "y"), len(kept))
 	return nil
 }
 
+// histKeepLast returns the n entries of history that were sent most recently,
+// in the same order they are in history.
+func histKeepLast(history []morc.HistoryEntry, n int) []morc.HistoryEntry {
+	if n >= len(history) {
+		return history
+	}
+
+	byTime := make([]int, len(history))
+	for i := range byTime {
+		byTime[i] = i
+	}
+	sort.SliceStable(byTime, func(i, j int) bool {
+		return history[byTime[i]].ReqTime.Before(history[byTime[j]].ReqTime)
+	})
+
+	keep := make(map[int]bool, n)
+	for _, idx := range byTime[len(byTime)-n:] {
+		keep[idx] = true
+	}
+
+	kept := make([]morc.HistoryEntry, 0, n)
+	for i := range history {
+		if keep[i] {
+			kept = append(kept, history[i])
+		}
+	}
+	return kept
+}
+
 func invokeHistInfo(io cmdio.IO, projFile string) error {
 	p, err := readProject(projFile, true)
 	if err != nil {
@@ -194,34 +435,135 @@ func invokeHistInfo(io cmdio.IO, projFile string) error {
 	return nil
 }
 
-func invokeHistList(io cmdio.IO, projFile string) error {
+func invokeHistList(ctx context.Context, io cmdio.IO, projFile string, tail int, follow bool, filter morc.HistoryFilter) error {
 	p, err := readProject(projFile, true)
 	if err != nil {
 		return err
 	}
 
-	if len(p.History) == 0 {
+	if follow && p.Config.HistFile == "" {
+		return fmt.Errorf("project is not configured to use a history file; nothing to follow")
+	}
+
+	if len(p.History) == 0 && !follow {
 		io.PrintLoudln("(no history)")
 		return nil
 	}
 
-	// layout:
-	// 0: 5/25/1993 12:34:56 PM - get-google - GET /api/v1/thing - 200 OK - 1.2s
+	matched := filter.Filter(p.History)
+	if len(matched) == 0 && !follow {
+		io.PrintLoudln("(no matching history)")
+		return nil
+	}
 
-	for i, h := range p.History {
-		io.Printf(
-			"%d: %s - %s - %s %s - %s - %s\n",
-			i,
-			h.ReqTime.Format(time.RFC3339),
-			h.Template,
-			h.Request.Method,
-			h.Request.URL,
-			h.Response.Status,
-			h.RespTime.Sub(h.ReqTime),
-		)
+	if tail >= 0 && tail < len(matched) {
+		matched = matched[len(matched)-tail:]
 	}
 
-	return nil
+	mask := morc.SecretForms(p.Vars.SecretValues())
+	for _, i := range matched {
+		printHistListEntry(io, i, p.History[i], mask)
+	}
+
+	if !follow {
+		return nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	histPath := p.Config.HistoryFSPath()
+	load := func() ([]morc.HistoryEntry, error) {
+		return morc.LoadHistoryFromDisk(histPath)
+	}
+
+	return followHistory(ctx, io, load, p.History, filter, mask, histFollowInterval)
+}
+
+// histFollowInterval is how often the history file is checked for new entries
+// when following it.
+var histFollowInterval = 500 * time.Millisecond
+
+// followHistory polls for history entries every interval using load and prints
+// any that come after the ones in seen and are matched by filter, with the
+// values in mask masked. It returns once ctx is done. If the loaded history no
+// longer begins with the entries already seen, the history is assumed to have
+// been cleared or replaced and printing starts over from the first entry.
+//
+// Errors from load are not fatal; the history file may be in the middle of
+// being written or replaced, so the entries are simply checked again on the
+// next poll.
+func followHistory(ctx context.Context, io cmdio.IO, load func() ([]morc.HistoryEntry, error), seen []morc.HistoryEntry, filter morc.HistoryFilter, mask []string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		entries, err := load()
+		if err != nil {
+			continue
+		}
+
+		if !histContinues(seen, entries) {
+			io.PrintLoudErrln("(history was cleared or replaced; starting over)")
+			seen = nil
+		}
+
+		for i := len(seen); i < len(entries); i++ {
+			if filter.Matches(entries[i]) {
+				printHistListEntry(io, i, entries[i], mask)
+			}
+		}
+		seen = entries
+	}
+}
+
+// histContinues returns whether entries could be the result of only appending
+// to seen. Only the last entry of seen is compared, which is enough to catch
+// truncation as well as replacement with an unrelated history file.
+func histContinues(seen, entries []morc.HistoryEntry) bool {
+	if len(entries) < len(seen) {
+		return false
+	}
+	if len(seen) == 0 {
+		return true
+	}
+
+	last := len(seen) - 1
+	return seen[last].ReqTime.Equal(entries[last].ReqTime) && seen[last].Template == entries[last].Template
+}
+
+// histTemplateName returns the name of the template that h was sent from, or
+// "(none)" if it was sent without one.
+func histTemplateName(h morc.HistoryEntry) string {
+	if h.Template == "" {
+		return "(none)"
+	}
+	return h.Template
+}
+
+// printHistListEntry prints the one-line summary of h at index idx, with any of
+// the values in mask masked in its URL.
+func printHistListEntry(io cmdio.IO, idx int, h morc.HistoryEntry, mask []string) {
+	// layout:
+	// 0: 5/25/1993 12:34:56 PM - get-google - GET /api/v1/thing - 200 OK - 1.2s
+
+	io.Printf(
+		"%d: %s - %s - %s %s - %s - %s\n",
+		idx,
+		h.ReqTime.Format(time.RFC3339),
+		histTemplateName(h),
+		h.Request.Method,
+		morc.MaskValues(h.Request.URL.String(), mask),
+		h.Response.Status,
+		h.RespTime.Sub(h.ReqTime),
+	)
 }
 
 type histArgs struct {
@@ -231,6 +573,14 @@ type histArgs struct {
 	entry      int
 	outputCtrl morc.OutputControl
 	noDates    bool
+	tail       int
+	follow     bool
+	filter     morc.HistoryFilter
+	host       string
+	env        optional[string]
+
+	clearBefore time.Time
+	keepLast    int
 }
 
 func parseHistArgs(cmd *cobra.Command, posArgs []string, args *histArgs) error {
@@ -249,7 +599,20 @@ func parseHistArgs(cmd *cobra.Command, posArgs []string, args *histArgs) error {
 
 	switch args.action {
 	case histActionList:
-		// no additional args to parse
+		if cmd.Flags().Changed("tail") {
+			if flags.Tail < 0 {
+				return fmt.Errorf("--tail must be a non-negative number of entries")
+			}
+			args.tail = flags.Tail
+		} else {
+			args.tail = -1
+		}
+		args.follow = flags.BFollow
+
+		args.filter, err = parseHistFilterFlags(cmd)
+		if err != nil {
+			return err
+		}
 	case histActionDetail:
 		args.entry, err = strconv.Atoi(posArgs[0])
 		if err != nil {
@@ -261,8 +624,49 @@ func parseHistArgs(cmd *cobra.Command, posArgs []string, args *histArgs) error {
 			return err
 		}
 
+		// with no flags selecting what to show, show the full entry
+		f := cmd.Flags()
+		if !f.Changed("request") && !f.Changed("headers") && !f.Changed("captures") && !f.Changed("verbose") && !f.Changed("no-body") {
+			args.outputCtrl.Request = true
+			args.outputCtrl.Headers = true
+			args.outputCtrl.Captures = true
+		}
+
 		args.noDates = flags.BNoDates
-	case histActionInfo, histActionClear, histActionEnable, histActionDisable:
+	case histActionReplay:
+		args.entry = flags.Replay
+
+		args.outputCtrl, err = gatherRequestOutputFlags(cmd)
+		if err != nil {
+			return err
+		}
+
+		if cmd.Flags().Changed("host") {
+			if flags.Host == "" {
+				return fmt.Errorf("--host cannot be set to empty string")
+			}
+			args.host = flags.Host
+		}
+		if cmd.Flags().Changed("env") {
+			if flags.Env == "" {
+				return fmt.Errorf("cannot specify env \"\"; use %q for the default env", reservedDefaultEnvName)
+			}
+			args.env = optional[string]{set: true, v: flags.Env}
+		}
+	case histActionClear:
+		args.keepLast = -1
+		if cmd.Flags().Changed("clear-before") {
+			args.clearBefore, err = parseHistTime(flags.ClearBefore)
+			if err != nil {
+				return fmt.Errorf("--clear-before: %w", err)
+			}
+		} else if cmd.Flags().Changed("clear-keep-last") {
+			if flags.ClearKeepLast < 0 {
+				return fmt.Errorf("--clear-keep-last must be a non-negative number of entries")
+			}
+			args.keepLast = flags.ClearKeepLast
+		}
+	case histActionInfo, histActionEnable, histActionDisable:
 		// no additional args to parse
 	default:
 		panic(fmt.Sprintf("unhandled hist action %q", args.action))
@@ -293,9 +697,9 @@ func parseHistActionFromFlags(cmd *cobra.Command, posArgs []string) (histAction,
 			return histActionEnable, fmt.Errorf("cannot use output flags with --off")
 		}
 		return histActionDisable, nil
-	} else if f.Changed("clear") {
+	} else if f.Changed("clear") || f.Changed("clear-before") || f.Changed("clear-keep-last") {
 		if len(posArgs) > 0 {
-			return histActionClear, fmt.Errorf("--clear cannot be used with positional argument %q", posArgs[0])
+			return histActionClear, fmt.Errorf("--clear, --clear-before, and --clear-keep-last cannot be used with positional argument %q", posArgs[0])
 		}
 		if requestOutputFlagIsPresent(cmd) {
 			return histActionEnable, fmt.Errorf("cannot use output flags with --clear")
@@ -311,6 +715,34 @@ func parseHistActionFromFlags(cmd *cobra.Command, posArgs []string) (histAction,
 		return histActionInfo, nil
 	}
 
+	if f.Changed("replay") {
+		if len(posArgs) > 0 {
+			return histActionReplay, fmt.Errorf("unknown positional argument %q", posArgs[0])
+		}
+		if f.Changed("no-dates") {
+			return histActionReplay, fmt.Errorf("--no-dates cannot be used with --replay")
+		}
+		if f.Changed("env") && !f.Changed("host") {
+			return histActionReplay, fmt.Errorf("--env can only be used with --host")
+		}
+		return histActionReplay, nil
+	}
+
+	if f.Changed("host") || f.Changed("env") {
+		return histActionList, fmt.Errorf("--host and --env can only be used with --replay")
+	}
+
+	if f.Changed("tail") || f.Changed("follow") {
+		if len(posArgs) > 0 {
+			return histActionList, fmt.Errorf("--tail and --follow can only be used when listing history")
+		}
+	}
+	if f.Changed("filter-status") || f.Changed("filter-template") || f.Changed("since") {
+		if len(posArgs) > 0 {
+			return histActionList, fmt.Errorf("--filter-status, --filter-template, and --since can only be used when listing history")
+		}
+	}
+
 	if len(posArgs) == 0 {
 		if requestOutputFlagIsPresent(cmd) {
 			return histActionList, fmt.Errorf("output flags are only valid when selecting a history entry to show")
@@ -323,10 +755,54 @@ func parseHistActionFromFlags(cmd *cobra.Command, posArgs []string) (histAction,
 	}
 }
 
+// parseHistFilterFlags builds a HistoryFilter from the history listing filter
+// flags.
+func parseHistFilterFlags(cmd *cobra.Command) (morc.HistoryFilter, error) {
+	var filter morc.HistoryFilter
+	f := cmd.Flags()
+
+	if f.Changed("filter-status") {
+		if flags.FilterStatus < 100 || flags.FilterStatus > 999 {
+			return filter, fmt.Errorf("--filter-status must be a three-digit HTTP status code")
+		}
+		filter.StatusCode = flags.FilterStatus
+	}
+
+	if f.Changed("filter-template") {
+		if flags.FilterTemplate == "" {
+			return filter, fmt.Errorf("--filter-template cannot be set to empty string")
+		}
+		filter.Template = flags.FilterTemplate
+	}
+
+	if f.Changed("since") {
+		since, err := parseHistTime(flags.Since)
+		if err != nil {
+			return filter, fmt.Errorf("--since: %w", err)
+		}
+		filter.Since = since
+	}
+
+	return filter, nil
+}
+
+// parseHistTime parses s as either an RFC 3339 timestamp or a date in
+// YYYY-MM-DD format. A date is taken to be midnight at the start of that day in
+// local time.
+func parseHistTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date in YYYY-MM-DD format or an RFC 3339 timestamp", s)
+}
+
 func requestOutputFlagIsPresent(cmd *cobra.Command) bool {
 	f := cmd.Flags()
 
-	return f.Changed("request") || f.Changed("captures") || f.Changed("headers") || f.Changed("no-body") || f.Changed("flags") || f.Changed("no-dates")
+	return f.Changed("request") || f.Changed("captures") || f.Changed("headers") || f.Changed("verbose") || f.Changed("no-body") || f.Changed("flags") || f.Changed("no-dates")
 }
 
 type histAction int
@@ -338,4 +814,5 @@ const (
 	histActionClear
 	histActionEnable
 	histActionDisable
+	histActionReplay
 )
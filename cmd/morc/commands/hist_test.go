@@ -0,0 +1,831 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dekarrin/morc"
+	"github.com/dekarrin/morc/cmd/morc/cmdio"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_HistList(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "no history",
+			args:               []string{"hist"},
+			p:                  testProject_withHistory(0),
+			expectStdoutOutput: "(no history)\n",
+		},
+		{
+			name: "all entries",
+			args: []string{"hist"},
+			p:    testProject_withHistory(3),
+			expectStdoutOutput: "" +
+				"0: 2024-01-01T00:00:00Z - req0 - GET /0 - 200 OK - 1s\n" +
+				"1: 2024-01-01T00:01:00Z - req1 - GET /1 - 200 OK - 1s\n" +
+				"2: 2024-01-01T00:02:00Z - req2 - GET /2 - 200 OK - 1s\n",
+		},
+		{
+			name: "tail fewer than all",
+			args: []string{"hist", "--tail", "2"},
+			p:    testProject_withHistory(3),
+			expectStdoutOutput: "" +
+				"1: 2024-01-01T00:01:00Z - req1 - GET /1 - 200 OK - 1s\n" +
+				"2: 2024-01-01T00:02:00Z - req2 - GET /2 - 200 OK - 1s\n",
+		},
+		{
+			name: "tail more than all",
+			args: []string{"hist", "--tail", "5"},
+			p:    testProject_withHistory(2),
+			expectStdoutOutput: "" +
+				"0: 2024-01-01T00:00:00Z - req0 - GET /0 - 200 OK - 1s\n" +
+				"1: 2024-01-01T00:01:00Z - req1 - GET /1 - 200 OK - 1s\n",
+		},
+		{
+			name:      "negative tail",
+			args:      []string{"hist", "--tail", "-2"},
+			p:         testProject_withHistory(2),
+			expectErr: "--tail must be a non-negative number of entries",
+		},
+		{
+			name: "filter by status",
+			args: []string{"hist", "--filter-status", "500"},
+			p:    testProject_withHistoryStatuses(200, 500, 404, 500),
+			expectStdoutOutput: "" +
+				"1: 2024-01-01T00:01:00Z - req1 - GET /1 - 500 Internal Server Error - 1s\n" +
+				"3: 2024-01-01T00:03:00Z - req3 - GET /3 - 500 Internal Server Error - 1s\n",
+		},
+		{
+			name:               "filter by template is case-insensitive",
+			args:               []string{"hist", "--filter-template", "REQ2"},
+			p:                  testProject_withHistory(3),
+			expectStdoutOutput: "2: 2024-01-01T00:02:00Z - req2 - GET /2 - 200 OK - 1s\n",
+		},
+		{
+			name: "filter by since timestamp",
+			args: []string{"hist", "--since", "2024-01-01T00:01:00Z"},
+			p:    testProject_withHistory(3),
+			expectStdoutOutput: "" +
+				"1: 2024-01-01T00:01:00Z - req1 - GET /1 - 200 OK - 1s\n" +
+				"2: 2024-01-01T00:02:00Z - req2 - GET /2 - 200 OK - 1s\n",
+		},
+		{
+			name:               "filter by since date excludes earlier entries",
+			args:               []string{"hist", "--since", "2024-01-03"},
+			p:                  testProject_withHistory(3),
+			expectStdoutOutput: "(no matching history)\n",
+		},
+		{
+			name:               "filters are combined",
+			args:               []string{"hist", "--filter-status", "500", "--filter-template", "req1", "--since", "2024-01-01T00:00:30Z"},
+			p:                  testProject_withHistoryStatuses(500, 500, 200),
+			expectStdoutOutput: "1: 2024-01-01T00:01:00Z - req1 - GET /1 - 500 Internal Server Error - 1s\n",
+		},
+		{
+			name:               "tail applies after filtering",
+			args:               []string{"hist", "--filter-status", "500", "--tail", "1"},
+			p:                  testProject_withHistoryStatuses(500, 500, 200),
+			expectStdoutOutput: "1: 2024-01-01T00:01:00Z - req1 - GET /1 - 500 Internal Server Error - 1s\n",
+		},
+		{
+			name:      "invalid since",
+			args:      []string{"hist", "--since", "yesterday"},
+			p:         testProject_withHistory(1),
+			expectErr: `--since: "yesterday" is not a date in YYYY-MM-DD format or an RFC 3339 timestamp`,
+		},
+		{
+			name:      "invalid status",
+			args:      []string{"hist", "--filter-status", "42"},
+			p:         testProject_withHistory(1),
+			expectErr: "--filter-status must be a three-digit HTTP status code",
+		},
+		{
+			name:      "filter with entry",
+			args:      []string{"hist", "1", "--filter-status", "200"},
+			p:         testProject_withHistory(2),
+			expectErr: "--filter-status, --filter-template, and --since can only be used when listing history",
+		},
+		{
+			name:      "filter with info",
+			args:      []string{"hist", "--info", "--since", "2024-01-01"},
+			p:         testProject_withHistory(2),
+			expectErr: "were all set",
+		},
+		{
+			name:      "tail with entry",
+			args:      []string{"hist", "1", "--tail", "2"},
+			p:         testProject_withHistory(2),
+			expectErr: "--tail and --follow can only be used when listing history",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetHistFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(histCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_noProjectMutations(assert)
+		})
+	}
+}
+
+func Test_HistDetail(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name: "full entry is shown by default",
+			args: []string{"hist", "0"},
+			p:    testProject_withDetailedHistory(),
+			expectStdoutOutput: "" +
+				"Request template: create-user\n" +
+				"Request sent:          2024-01-01T00:00:00Z\n" +
+				"Response received:     2024-01-01T00:00:02Z\n" +
+				"Total round-trip time: 2s\n" +
+				"------------------- REQUEST -------------------\n" +
+				"Request URI: http://example.com/users\n" +
+				"\n" +
+				"POST /users HTTP/1.1\r\n" +
+				"Host: example.com\r\n" +
+				"User-Agent: Go-http-client/1.1\r\n" +
+				"Content-Length: 16\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Accept-Encoding: gzip\r\n" +
+				"\r\n" +
+				"{\"name\": \"jack\"}\n" +
+				"----------------- END REQUEST -----------------\n" +
+				"----------------- VAR CAPTURES ----------------\n" +
+				"USER_ID: 413\n" +
+				"-----------------------------------------------\n" +
+				"HTTP/1.1 201 Created\n" +
+				"------------------- HEADERS -------------------\n" +
+				"Content-Type: application/json\n" +
+				"-----------------------------------------------\n" +
+				"{\"id\": 413}\n",
+		},
+		{
+			name: "output flags select what is shown",
+			args: []string{"hist", "0", "--headers"},
+			p:    testProject_withDetailedHistory(),
+			expectStdoutOutput: "" +
+				"Request template: create-user\n" +
+				"Request sent:          2024-01-01T00:00:00Z\n" +
+				"Response received:     2024-01-01T00:00:02Z\n" +
+				"Total round-trip time: 2s\n" +
+				"HTTP/1.1 201 Created\n" +
+				"------------------- HEADERS -------------------\n" +
+				"Content-Type: application/json\n" +
+				"-----------------------------------------------\n" +
+				"{\"id\": 413}\n",
+		},
+		{
+			name: "no dates",
+			args: []string{"hist", "0", "--no-dates", "--no-body"},
+			p:    testProject_withDetailedHistory(),
+			expectStdoutOutput: "" +
+				"Request template: create-user\n" +
+				"HTTP/1.1 201 Created\n",
+		},
+		{
+			name: "json format",
+			args: []string{"hist", "0", "-f", "json"},
+			p:    testProject_withDetailedHistory(),
+			expectStdoutOutput: `{
+  "request": {
+    "method": "POST",
+    "url": "http://example.com/users",
+    "headers": {
+      "Content-Type": [
+        "application/json"
+      ]
+    }
+  },
+  "proto": "HTTP/1.1",
+  "status": "201 Created",
+  "status_code": 201,
+  "headers": {
+    "Content-Type": [
+      "application/json"
+    ]
+  },
+  "body": "{\"id\": 413}",
+  "captures": {
+    "USER_ID": "413"
+  },
+  "timings": {
+    "dns_ms": 0,
+    "connect_ms": 0,
+    "tls_ms": 0,
+    "ttfb_ms": 0,
+    "total_ms": 2000
+  }
+}
+`,
+		},
+		{
+			name:      "entry out of range",
+			args:      []string{"hist", "1"},
+			p:         testProject_withDetailedHistory(),
+			expectErr: "can't get entry 1; 0 is the highest entry available",
+		},
+		{
+			name:      "entry is not a number",
+			args:      []string{"hist", "first"},
+			p:         testProject_withDetailedHistory(),
+			expectErr: `"first" is not a valid history entry index`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetHistFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, _, err := runTestCommand(histCmd, projFilePath, tc.args)
+
+			// assert and check stdout
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+
+			assert_noProjectMutations(assert)
+		})
+	}
+}
+
+func Test_HistReplay(t *testing.T) {
+	respFnEcho := func(w http.ResponseWriter, r *http.Request) {
+		// suppress date header
+		w.Header()["Date"] = nil
+
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.RequestURI() + " " + r.Header.Get("X-Test") + " " + string(body)))
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		entryURL           string   // URL of the request in entry 1; $TESTSERVER_URL$ is replaced
+		vars               map[string]map[string]string
+		entryRedacted      bool
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "request is sent as recorded",
+			args:               []string{"hist", "--replay", "1"},
+			entryURL:           "$TESTSERVER_URL$/users?id=${ID}",
+			vars:               map[string]map[string]string{"": {"ID": "8"}},
+			expectStdoutOutput: "HTTP/1.1 200 OK\nPOST /users?id=${ID} ${ID} {\"id\": \"${ID}\"}\n",
+		},
+		{
+			name:               "replay to a different host",
+			args:               []string{"hist", "--replay", "1", "--host", "$TESTSERVER_HOST$"},
+			entryURL:           "http://replay.invalid/users",
+			expectStdoutOutput: "HTTP/1.1 200 OK\nPOST /users ${ID} {\"id\": \"${ID}\"}\n",
+		},
+		{
+			name:     "replay to host of another env",
+			args:     []string{"hist", "--replay", "1", "--host", "http://${HOST}", "--env", "prod"},
+			entryURL: "https://replay.invalid/users",
+			vars: map[string]map[string]string{
+				"":     {"HOST": "replay.invalid"},
+				"PROD": {"HOST": "$TESTSERVER_HOST$"},
+			},
+			expectStdoutOutput: "HTTP/1.1 200 OK\nPOST /users ${ID} {\"id\": \"${ID}\"}\n",
+		},
+		{
+			name:      "unknown env",
+			args:      []string{"hist", "--replay", "1", "--host", "${HOST}", "--env", "staging"},
+			entryURL:  "http://replay.invalid/users",
+			expectErr: `environment "staging" does not contain any variables`,
+		},
+		{
+			name:      "host with path",
+			args:      []string{"hist", "--replay", "1", "--host", "example.com/api"},
+			entryURL:  "http://replay.invalid/users",
+			expectErr: `--host: "example.com/api" is not a host`,
+		},
+		{
+			name:          "redacted entry",
+			args:          []string{"hist", "--replay", "1"},
+			entryURL:      "$TESTSERVER_URL$/users",
+			entryRedacted: true,
+			expectErr:     "entry 1: secret values in the request were redacted when it was recorded",
+		},
+		{
+			name:      "entry out of range",
+			args:      []string{"hist", "--replay", "2"},
+			entryURL:  "$TESTSERVER_URL$/users",
+			expectErr: "can't get entry 2; 1 is the highest entry available",
+		},
+		{
+			name:      "env without host",
+			args:      []string{"hist", "--replay", "1", "--env", "prod"},
+			entryURL:  "$TESTSERVER_URL$/users",
+			expectErr: "--env can only be used with --host",
+		},
+		{
+			name:      "host without replay",
+			args:      []string{"hist", "--host", "example.com"},
+			entryURL:  "$TESTSERVER_URL$/users",
+			expectErr: "--host and --env can only be used with --replay",
+		},
+		{
+			name:      "replay with ENTRY",
+			args:      []string{"hist", "1", "--replay", "1"},
+			entryURL:  "$TESTSERVER_URL$/users",
+			expectErr: `unknown positional argument "1"`,
+		},
+		{
+			name:      "replay with tail",
+			args:      []string{"hist", "--replay", "1", "--tail", "1"},
+			entryURL:  "$TESTSERVER_URL$/users",
+			expectErr: "were all set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetHistFlags()
+
+			srv := httptest.NewServer(http.HandlerFunc(respFnEcho))
+			defer srv.Close()
+			cmdio.HTTPClient = srv.Client()
+			srvHost := mustParseURL(srv.URL).Host
+			fillSrv := func(s string) string {
+				s = strings.ReplaceAll(s, "$TESTSERVER_URL$", srv.URL)
+				return strings.ReplaceAll(s, "$TESTSERVER_HOST$", srvHost)
+			}
+
+			p := testProject_withHistory(2)
+			p.History[1].Request.Method = "POST"
+			p.History[1].Request.URL = mustParseURL(fillSrv(tc.entryURL))
+			p.History[1].Request.Header = http.Header{"X-Test": {"${ID}"}}
+			p.History[1].Request.Body = io.NopCloser(strings.NewReader(`{"id": "${ID}"}`))
+			p.History[1].Redacted = tc.entryRedacted
+			for env, vars := range tc.vars {
+				for k, v := range vars {
+					p.Vars.SetIn(k, fillSrv(v), env)
+				}
+			}
+
+			args := make([]string, len(tc.args))
+			for i := range tc.args {
+				args[i] = fillSrv(tc.args[i])
+			}
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, p)
+			// set up the root command and run
+			output, _, err := runTestCommand(histCmd, projFilePath, args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+
+			// the replay is recorded as a new entry for the same template, and
+			// no captures are run so the project itself is untouched
+			assert_noProjectFileMutations(assert)
+			hist, err := morc.LoadHistory(histWriter.(*bytes.Buffer))
+			if !assert.NoError(err) {
+				return
+			}
+			if assert.Len(hist, 3) {
+				assert.Equal("req1", hist[2].Template)
+				assert.Equal("POST", hist[2].Request.Method)
+			}
+		})
+	}
+}
+
+func Test_HistClear(t *testing.T) {
+	// entries out of order by time, to check that keep-last goes by ReqTime
+	outOfOrder := testProject_withHistory(4)
+	outOfOrder.History[1].ReqTime, outOfOrder.History[3].ReqTime = outOfOrder.History[3].ReqTime, outOfOrder.History[1].ReqTime
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string   // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStdoutOutput string   // set with expected output to stdout
+		expectTemplates    []string // templates of the entries expected to remain; nil if history is not expected to be written
+	}{
+		{
+			name:               "clear all",
+			args:               []string{"hist", "--clear"},
+			p:                  testProject_withHistory(3),
+			expectStdoutOutput: "History cleared",
+			expectTemplates:    []string{},
+		},
+		{
+			name:               "clear before time",
+			args:               []string{"hist", "--clear-before", "2024-01-01T00:01:00Z"},
+			p:                  testProject_withHistory(3),
+			expectStdoutOutput: "Deleted 1 history entry; 2 remaining\n",
+			expectTemplates:    []string{"req1", "req2"},
+		},
+		{
+			name:               "clear before date that removes nothing",
+			args:               []string{"hist", "--clear-before", "2023-12-01"},
+			p:                  testProject_withHistory(3),
+			expectStdoutOutput: "Deleted 0 history entries; 3 remaining\n",
+		},
+		{
+			name:               "keep last",
+			args:               []string{"hist", "--clear-keep-last", "2"},
+			p:                  testProject_withHistory(3),
+			expectStdoutOutput: "Deleted 1 history entry; 2 remaining\n",
+			expectTemplates:    []string{"req1", "req2"},
+		},
+		{
+			name:               "keep last goes by request time",
+			args:               []string{"hist", "--clear-keep-last", "2"},
+			p:                  outOfOrder,
+			expectStdoutOutput: "Deleted 2 history entries; 2 remaining\n",
+			expectTemplates:    []string{"req1", "req2"},
+		},
+		{
+			name:               "keep last zero",
+			args:               []string{"hist", "--clear-keep-last", "0"},
+			p:                  testProject_withHistory(2),
+			expectStdoutOutput: "Deleted 2 history entries; 0 remaining\n",
+			expectTemplates:    []string{},
+		},
+		{
+			name:               "keep last more than all",
+			args:               []string{"hist", "--clear-keep-last", "5"},
+			p:                  testProject_withHistory(2),
+			expectStdoutOutput: "Deleted 0 history entries; 2 remaining\n",
+		},
+		{
+			name:      "negative keep last",
+			args:      []string{"hist", "--clear-keep-last", "-1"},
+			p:         testProject_withHistory(2),
+			expectErr: "--clear-keep-last must be a non-negative number of entries",
+		},
+		{
+			name:      "invalid clear before",
+			args:      []string{"hist", "--clear-before", "last week"},
+			p:         testProject_withHistory(2),
+			expectErr: `--clear-before: "last week" is not a date in YYYY-MM-DD format or an RFC 3339 timestamp`,
+		},
+		{
+			name:      "clear before with entry",
+			args:      []string{"hist", "1", "--clear-before", "2024-01-01"},
+			p:         testProject_withHistory(2),
+			expectErr: `--clear, --clear-before, and --clear-keep-last cannot be used with positional argument "1"`,
+		},
+		{
+			name:      "clear before and keep last",
+			args:      []string{"hist", "--clear-before", "2024-01-01", "--clear-keep-last", "1"},
+			p:         testProject_withHistory(2),
+			expectErr: "were all set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetHistFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, _, err := runTestCommand(histCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert_noProjectFileMutations(assert)
+
+			if tc.expectTemplates == nil {
+				assert_noHistoryFileMutations(assert)
+				return
+			}
+
+			hist, err := morc.LoadHistory(histWriter.(*bytes.Buffer))
+			if !assert.NoError(err) {
+				return
+			}
+			actualTemplates := []string{}
+			for _, h := range hist {
+				actualTemplates = append(actualTemplates, h.Template)
+			}
+			assert.Equal(tc.expectTemplates, actualTemplates)
+		})
+	}
+}
+
+func Test_followHistory(t *testing.T) {
+	testCases := []struct {
+		name               string
+		seen               int
+		failFirstLoad      bool
+		filter             morc.HistoryFilter
+		loads              [][]morc.HistoryEntry
+		expectStdoutOutput string
+		expectStderrOutput string
+	}{
+		{
+			name: "no new entries",
+			seen: 2,
+			loads: [][]morc.HistoryEntry{
+				testHistoryEntries(2),
+			},
+		},
+		{
+			name: "appended entries are printed once",
+			seen: 1,
+			loads: [][]morc.HistoryEntry{
+				testHistoryEntries(2),
+				testHistoryEntries(2),
+				testHistoryEntries(3),
+			},
+			expectStdoutOutput: "" +
+				"1: 2024-01-01T00:01:00Z - req1 - GET /1 - 200 OK - 1s\n" +
+				"2: 2024-01-01T00:02:00Z - req2 - GET /2 - 200 OK - 1s\n",
+		},
+		{
+			name: "truncated history starts over",
+			seen: 3,
+			loads: [][]morc.HistoryEntry{
+				nil,
+				testHistoryEntries(1),
+			},
+			expectStdoutOutput: "0: 2024-01-01T00:00:00Z - req0 - GET /0 - 200 OK - 1s\n",
+			expectStderrOutput: "(history was cleared or replaced; starting over)\n",
+		},
+		{
+			name: "replaced history starts over",
+			seen: 1,
+			loads: [][]morc.HistoryEntry{
+				testHistoryEntries(3)[1:],
+			},
+			expectStdoutOutput: "" +
+				"0: 2024-01-01T00:01:00Z - req1 - GET /1 - 200 OK - 1s\n" +
+				"1: 2024-01-01T00:02:00Z - req2 - GET /2 - 200 OK - 1s\n",
+			expectStderrOutput: "(history was cleared or replaced; starting over)\n",
+		},
+		{
+			name:   "appended entries not matching filter are skipped",
+			seen:   1,
+			filter: morc.HistoryFilter{Template: "req2"},
+			loads: [][]morc.HistoryEntry{
+				testHistoryEntries(3),
+			},
+			expectStdoutOutput: "2: 2024-01-01T00:02:00Z - req2 - GET /2 - 200 OK - 1s\n",
+		},
+		{
+			name:          "load errors are retried",
+			seen:          0,
+			failFirstLoad: true,
+			loads: [][]morc.HistoryEntry{
+				testHistoryEntries(1),
+			},
+			expectStdoutOutput: "0: 2024-01-01T00:00:00Z - req0 - GET /0 - 200 OK - 1s\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			io := cmdio.IO{Out: stdout, Err: stderr}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			calls := 0
+			failed := false
+			load := func() ([]morc.HistoryEntry, error) {
+				if tc.failFirstLoad && !failed {
+					failed = true
+					return nil, fmt.Errorf("file is being written")
+				}
+				if calls >= len(tc.loads) {
+					cancel()
+					return nil, fmt.Errorf("no more loads")
+				}
+				entries := tc.loads[calls]
+				calls++
+				return entries, nil
+			}
+
+			err := followHistory(ctx, io, load, testHistoryEntries(tc.seen), tc.filter, nil, time.Millisecond)
+
+			assert.NoError(err)
+			assert.Equal(tc.expectStdoutOutput, stdout.String(), "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, stderr.String(), "stderr output mismatch")
+		})
+	}
+}
+
+func testProject_withHistory(n int) morc.Project {
+	return morc.Project{
+		Templates: map[string]morc.RequestTemplate{},
+		Flows:     map[string]morc.Flow{},
+		Vars:      morc.NewVarStore(),
+		History:   testHistoryEntries(n),
+		Config: morc.Settings{
+			HistFile:      "::PROJ_DIR::/history.json",
+			RecordHistory: true,
+		},
+	}
+}
+
+// testProject_withDetailedHistory returns a project whose history has a
+// single entry with request and response headers, bodies, and captures.
+func testProject_withDetailedHistory() morc.Project {
+	p := testProject_withHistory(0)
+
+	reqTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	reqBody := `{"name": "jack"}`
+	respBody := `{"id": 413}`
+
+	p.History = []morc.HistoryEntry{{
+		Template: "create-user",
+		ReqTime:  reqTime,
+		RespTime: reqTime.Add(2 * time.Second),
+		Request: &http.Request{
+			Method:        "POST",
+			URL:           mustParseURL("http://example.com/users"),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"application/json"}},
+			Body:          io.NopCloser(strings.NewReader(reqBody)),
+			ContentLength: int64(len(reqBody)),
+		},
+		Response: &http.Response{
+			Status:        "201 Created",
+			StatusCode:    http.StatusCreated,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"application/json"}},
+			Body:          io.NopCloser(strings.NewReader(respBody)),
+			ContentLength: int64(len(respBody)),
+		},
+		Captures: map[string]string{"USER_ID": "413"},
+	}}
+
+	return p
+}
+
+// testProject_withHistoryStatuses returns a project whose history has one
+// entry per given status code, in order.
+func testProject_withHistoryStatuses(codes ...int) morc.Project {
+	p := testProject_withHistory(len(codes))
+	for i, code := range codes {
+		p.History[i].Response.StatusCode = code
+		p.History[i].Response.Status = fmt.Sprintf("%d %s", code, http.StatusText(code))
+	}
+	return p
+}
+
+func testHistoryEntries(n int) []morc.HistoryEntry {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	entries := make([]morc.HistoryEntry, n)
+	for i := range entries {
+		reqTime := base.Add(time.Duration(i) * time.Minute)
+		entries[i] = morc.HistoryEntry{
+			Template: fmt.Sprintf("req%d", i),
+			ReqTime:  reqTime,
+			RespTime: reqTime.Add(time.Second),
+			Request: &http.Request{
+				Method:     "GET",
+				URL:        mustParseURL(fmt.Sprintf("/%d", i)),
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Body:       http.NoBody,
+			},
+			Response: &http.Response{
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Body:       http.NoBody,
+			},
+		}
+	}
+	return entries
+}
+
+func resetHistFlags() {
+	flags.ProjectFile = ""
+	flags.BInfo = false
+	flags.BClear = false
+	flags.BEnable = false
+	flags.BDisable = false
+	flags.BNoDates = false
+	flags.BQuiet = false
+	flags.Tail = -1
+	flags.BFollow = false
+	flags.FilterStatus = 0
+	flags.FilterTemplate = ""
+	flags.Since = ""
+	flags.Replay = -1
+	flags.ClearBefore = ""
+	flags.ClearKeepLast = -1
+	flags.Host = ""
+	flags.Env = ""
+	flags.BHeaders = false
+	flags.BCaptures = false
+	flags.BNoBody = false
+	flags.BRequest = false
+	flags.BVerbose = false
+	flags.Format = "pretty"
+
+	histCmd.Flags().VisitAll(func(fl *pflag.Flag) {
+		fl.Changed = false
+	})
+}
@@ -23,7 +23,7 @@ var initCmd = &cobra.Command{
 		"instead.",
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		projName := "Unnamed Project"
+		projName := defaultProjectName
 		if len(args) > 0 {
 			projName = args[0]
 		}
@@ -41,6 +41,10 @@ var initCmd = &cobra.Command{
 	},
 }
 
+// defaultProjectName is the name given to new projects when one is not
+// otherwise specified.
+const defaultProjectName = "Unnamed Project"
+
 func init() {
 	initCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
 
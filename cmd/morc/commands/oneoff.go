@@ -16,7 +16,7 @@ var oneoffCmd = &cobra.Command{
 	Use: "oneoff METHOD URL",
 	Annotations: map[string]string{
 		annotationKeyHelpUsages: "" +
-			"oneoff METHOD URL [-HdCVkbcp] [output-flags]",
+			"oneoff METHOD URL [-HdCVkbcp46] [--data-urlencode FIELD]... [--http1 | --http2] [--local-addr ADDR] [--resolve HOST:PORT:ADDR]... [--cert FILE [--key FILE]] [--cacert FILE] [--timeout DURATION] [output-flags]",
 	},
 	GroupID: "sending",
 	Short:   "Make an arbitrary one-off HTTP request",
@@ -53,6 +53,7 @@ func addOneoffRequestFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().StringVarP(&flags.ReadStateFile, "read-state", "c", "", "Read and use the cookies and vars saved in statefile `FILE`.")
 	cmd.PersistentFlags().StringArrayVarP(&flags.Headers, "header", "H", []string{}, "Add a header to the request. Argument is in form `KEY:VALUE` (spaces after the colon are allowed). May be set multiple times.")
 	cmd.PersistentFlags().StringVarP(&flags.BodyData, "data", "d", "", "Add the given `DATA` as a body to the request; prefix with '@' to instead interperet DATA as a filename that body data is to be read from.")
+	cmd.PersistentFlags().StringArrayVarP(&flags.DataURLEncode, "data-urlencode", "", []string{}, "URL-encode `FIELD` and add it to the body of the request, joined to any other body data with '&', the same as curl. FIELD may be 'content', '=content', 'name=content', '@filename', or 'name@filename'; only the content is encoded, after variables in it are substituted. Sets the Content-Type header to "+morc.FormContentType+" if it is not otherwise set. May be set multiple times.")
 	cmd.PersistentFlags().StringVarP(&flags.VarPrefix, "var-prefix", "p", "$", "Set the leading variable symbol used to indicate the start of a variable in the request to `PREFIX`.")
 	cmd.PersistentFlags().StringArrayVarP(&flags.CaptureVars, "capture-var", "C", []string{}, "Get a variable's value from the response. Argument is in format `VAR:SPEC`. The SPEC part has format ':START,END' for byte offset (note the leading colon, resulting in 'VAR::START,END'), or '.path[0].to.value' (jq-ish syntax) for JSON body data. Alternatively, it may be 'raw' to indicate that the entire response body should be captured.")
 	cmd.PersistentFlags().StringArrayVarP(&flags.Vars, "var", "V", []string{}, "Temporarily set a variable's value for the current request only. Format is `VAR=VALUE`.")
@@ -60,6 +61,7 @@ func addOneoffRequestFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
 
 	addRequestOutputFlags(cmd)
+	addTransportFlags(cmd)
 }
 
 func addQuickMethodCommand(method string) {
@@ -70,7 +72,7 @@ func addQuickMethodCommand(method string) {
 		Use: lowerMeth + " URL",
 		Annotations: map[string]string{
 			annotationKeyHelpUsages: "" +
-				lowerMeth + " URL [-HdCVkbcp] [output-flags]",
+				lowerMeth + " URL [-HdCVkbcp46] [--data-urlencode FIELD]... [--http1 | --http2] [--local-addr ADDR] [--resolve HOST:PORT:ADDR]... [--cert FILE [--key FILE]] [--cacert FILE] [--timeout DURATION] [output-flags]",
 		},
 		GroupID: "quickreqs",
 		Short:   "Make a one-off " + upperMeth + " request",
@@ -104,11 +106,14 @@ func makeOneoffRequest(io cmdio.IO, args oneoffArgs) error {
 		SaveStateFile:      args.stateFileOut,
 		Headers:            args.headers,
 		Body:               args.bodyData,
+		Form:               args.form,
 		Captures:           args.captures,
 		Output:             args.outputCtrl,
 		Vars:               args.vars,
 		InsecureSkipVerify: args.skipVerify,
 	}
+	// there is no project, so only settings given as flags apply
+	args.transport.applyTo(&sendOpts, morc.Settings{})
 
 	// inject the http client, in case we are to use a specific one
 	sendOpts.Client = cmdio.HTTPClient
@@ -127,9 +132,11 @@ type oneoffArgs struct {
 	captures     []morc.VarScraper
 	headers      http.Header
 	bodyData     []byte
+	form         []morc.FormField
 	outputCtrl   morc.OutputControl
 	skipVerify   bool
 	prefix       string
+	transport    transportOptions
 }
 
 func parseOneoffArgs(cmd *cobra.Command, posArgs []string, args *oneoffArgs) error {
@@ -142,8 +149,15 @@ func parseOneoffArgs(cmd *cobra.Command, posArgs []string, args *oneoffArgs) err
 		args.url = posArgs[1]
 	}
 
-	args.stateFileIn = flags.ReadStateFile
-	args.stateFileOut = flags.WriteStateFile
+	var err error
+	args.stateFileIn, err = projDirPath(cmd, flags.ReadStateFile)
+	if err != nil {
+		return fmt.Errorf("--read-state: %w", err)
+	}
+	args.stateFileOut, err = projDirPath(cmd, flags.WriteStateFile)
+	if err != nil {
+		return fmt.Errorf("--write-state: %w", err)
+	}
 	args.skipVerify = flags.BInsecure
 	args.prefix = flags.VarPrefix
 
@@ -151,12 +165,16 @@ func parseOneoffArgs(cmd *cobra.Command, posArgs []string, args *oneoffArgs) err
 		return fmt.Errorf("variable prefix cannot be set to empty string")
 	}
 
-	var err error
 	args.outputCtrl, err = gatherRequestOutputFlags(cmd)
 	if err != nil {
 		return err
 	}
 
+	args.transport, err = gatherTransportFlags(cmd)
+	if err != nil {
+		return err
+	}
+
 	// check get vars
 	if len(flags.CaptureVars) > 0 {
 		scrapers := []morc.VarScraper{}
@@ -207,20 +225,30 @@ func parseOneoffArgs(cmd *cobra.Command, posArgs []string, args *oneoffArgs) err
 
 	// check body data; load it immediately if it refers to a file
 	if strings.HasPrefix(flags.BodyData, "@") {
+		bodyFile, err := projDirPath(cmd, flags.BodyData[1:])
+		if err != nil {
+			return fmt.Errorf("body file: %w", err)
+		}
+
 		// read entire file now
-		fRaw, err := os.Open(flags.BodyData[1:])
+		fRaw, err := os.Open(bodyFile)
 		if err != nil {
-			return fmt.Errorf("open %q: %w", flags.BodyData[1:], err)
+			return fmt.Errorf("open %q: %w", bodyFile, err)
 		}
 		defer fRaw.Close()
 		bodyData, err := io.ReadAll(fRaw)
 		if err != nil {
-			return fmt.Errorf("read %q: %w", flags.BodyData[1:], err)
+			return fmt.Errorf("read %q: %w", bodyFile, err)
 		}
 		args.bodyData = bodyData
 	} else {
 		args.bodyData = []byte(flags.BodyData)
 	}
 
+	args.form, err = parseDataURLEncodeFlags(cmd)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -2,6 +2,11 @@ package commands
 
 import (
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,9 +21,11 @@ var projCmd = &cobra.Command{
 	Annotations: map[string]string{
 		annotationKeyHelpUsages: "" +
 			"proj\n" +
-			"proj --new [-nHSCcRp]\n" +
+			"proj --new [-nHSCcRp] [--set-name-from-dir] [--request-timeout DURATION] [--compact-files ON|OFF] [--env-fallback ON|OFF] [--max-history N] [--client-cert FILE] [--client-key FILE] [--ca-cert FILE] [--add-default-header 'KEY: VALUE']...\n" +
 			"proj --get ATTR\n" +
-			"proj [-nHSCcRp]",
+			"proj --check\n" +
+			"proj --dump-effective-config\n" +
+			"proj [-nHSCcRp] [--set-name-from-dir] [--request-timeout DURATION] [--compact-files ON|OFF] [--env-fallback ON|OFF] [--max-history N] [--client-cert FILE] [--client-key FILE] [--ca-cert FILE] [--add-default-header 'KEY: VALUE']... [--remove-default-header KEY]...",
 	},
 	GroupID: "project",
 	Short:   "Show or manipulate project attributes and config",
@@ -39,6 +46,10 @@ var projCmd = &cobra.Command{
 			return invokeProjShow(io, opts.projFile)
 		case projActionGet:
 			return invokeProjGet(io, opts.projFile, opts.getItem)
+		case projActionCheck:
+			return invokeProjCheck(io, opts.projFile)
+		case projActionDumpConfig:
+			return invokeProjDumpConfig(io, opts.projFile)
 		case projActionNew:
 			return invokeProjNew(io, opts.projFile, opts.sets)
 		case projActionEdit:
@@ -54,22 +65,46 @@ func init() {
 	projCmd.PersistentFlags().BoolVarP(&flags.BNew, "new", "N", false, "Create a new project instead of reading/editing one. Combine with other arguments to specify values for the new project.")
 	projCmd.PersistentFlags().StringVarP(&flags.Get, "get", "G", "", "Get the value of a specific attribute of the project. `ATTR` is the name of an attribute to retrieve and must be one of the following: "+strings.Join(projAttrKeyNames(), ", "))
 	projCmd.PersistentFlags().StringVarP(&flags.Name, "name", "n", "", "Set the name of the project to `NAME`")
+	projCmd.PersistentFlags().BoolVarP(&flags.BNameFromDir, "set-name-from-dir", "", false, "Set the name of the project to the name of the current directory")
 	projCmd.PersistentFlags().StringVarP(&flags.HistoryFile, "history-file", "H", "", "Set the history file to `FILE`. If the special string '"+morc.ProjDirVar+"' is in the path, it is replaced with the directory containing the project file whenever morc is executed, allowing the history file path to still function even if the containing directory is moved.")
 	projCmd.PersistentFlags().StringVarP(&flags.SessionFile, "cookies-file", "C", "", "Set the session (cookies) storage file to `FILE`. If the special string '"+morc.ProjDirVar+"' is in the path, it is replaced with the directory containing the project file whenever morc is executed, allowing the session file path to still function even if the containing directory is moved.")
 	projCmd.PersistentFlags().StringVarP(&flags.CookieLifetime, "cookie-lifetime", "L", "", "Set the lifetime of recorded cookies to `DUR`. DUR must be a duration string such as 8m2s or similar. If set to 0 or less, it will be interpreted as '24h'. Altering this on an existing project will immediately apply an eviction check to all current cookies; this may result in some being purged.")
+	projCmd.PersistentFlags().StringVarP(&flags.RequestTimeout, "request-timeout", "", "", "Set the timeout of requests sent from the project to `DURATION`. DURATION must be a duration string such as 30s or similar. If set to 0 or less, it will be interpreted as '30s'. It can be overridden for a single send with --timeout.")
 	projCmd.PersistentFlags().StringVarP(&flags.RecordCookies, "cookies", "c", "", "Set whether cookie recording is enabled. `ON|OFF` must be one of 'ON' or 'OFF'. Setting this is equivalent to calling 'morc cookies --on' or 'morc cookies --off'")
 	projCmd.PersistentFlags().StringVarP(&flags.RecordHistory, "history", "R", "", "Set whether history recording is enabled. `ON|OFF` must be one of 'ON' or 'OFF'. Setting this is equivalent to calling 'morc history --on' or 'morc history --off'")
+	projCmd.PersistentFlags().StringVarP(&flags.CompactFiles, "compact-files", "", "", "Set whether the project, history, and session files are written as compact single-line JSON instead of indented JSON. `ON|OFF` must be one of 'ON' or 'OFF'. Changing this rewrites all of the files in the new format.")
+	projCmd.PersistentFlags().StringVarP(&flags.EnvFallback, "env-fallback", "", "", "Set whether vars that are not defined in the project are taken from OS environment variables with the same name when sending requests. `ON|OFF` must be one of 'ON' or 'OFF'.")
+	projCmd.PersistentFlags().StringVarP(&flags.MaxHistory, "max-history", "", "", "Set the most entries that history may hold to `N`. Once it is full, the oldest entry is dropped each time a new one is added. If set to 0, history is unlimited.")
+	projCmd.PersistentFlags().StringVarP(&flags.ClientCert, "client-cert", "", "", "Set the client certificate presented to servers that ask for one, for mutual TLS, to the PEM-encoded certificate in `FILE`. If no client key file is set, the private key is read from FILE as well. If the special string '"+morc.ProjDirVar+"' is in the path, it is replaced with the directory containing the project file.")
+	projCmd.PersistentFlags().StringVarP(&flags.ClientKey, "client-key", "", "", "Set the private key of the client certificate to the PEM-encoded key in `FILE`. If the special string '"+morc.ProjDirVar+"' is in the path, it is replaced with the directory containing the project file.")
+	projCmd.PersistentFlags().StringVarP(&flags.CACert, "ca-cert", "", "", "Verify servers using only the PEM-encoded CA certificates in `FILE` instead of those of the system. If the special string '"+morc.ProjDirVar+"' is in the path, it is replaced with the directory containing the project file.")
 	projCmd.PersistentFlags().StringVarP(&flags.VarPrefix, "var-prefix", "p", "", "Set the variable prefix string to `PREFIX`. It will be \"$\" by default.")
+	projCmd.PersistentFlags().StringArrayVarP(&flags.DefaultHeaders, "add-default-header", "", []string{}, "Add a default header that is sent with every request in the project unless the request template has its own header with the same key. Format is `KEY:VALUE`. Any existing default header with the same key is replaced. May be given multiple times; giving the same key more than once sets multiple values for it.")
+	projCmd.PersistentFlags().StringArrayVarP(&flags.RemoveDefaultHeaders, "remove-default-header", "", []string{}, "Remove the default header with key `KEY` from the project. May be given multiple times.")
+	projCmd.PersistentFlags().BoolVarP(&flags.BCheck, "check", "", false, "Check the project for problems that would cause a send or an exec to fail, and print each one found.")
+	projCmd.PersistentFlags().BoolVarP(&flags.BDumpEffectiveConfig, "dump-effective-config", "", false, "Print every setting of the project as it is used when sending requests, with defaults applied and file paths resolved. Flags that override a setting for a single send or exec are not included. Values of secret-looking default headers and of secret vars are masked.")
 	projCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
 
-	projCmd.MarkFlagsMutuallyExclusive("new", "get")
+	projCmd.MarkFlagsMutuallyExclusive("new", "get", "check", "dump-effective-config")
 	projCmd.MarkFlagsMutuallyExclusive("cookies", "get")
 	projCmd.MarkFlagsMutuallyExclusive("cookie-lifetime", "get")
+	projCmd.MarkFlagsMutuallyExclusive("request-timeout", "get")
 	projCmd.MarkFlagsMutuallyExclusive("history", "get")
 	projCmd.MarkFlagsMutuallyExclusive("history-file", "get")
 	projCmd.MarkFlagsMutuallyExclusive("cookies-file", "get")
 	projCmd.MarkFlagsMutuallyExclusive("name", "get")
+	projCmd.MarkFlagsMutuallyExclusive("set-name-from-dir", "get")
+	projCmd.MarkFlagsMutuallyExclusive("set-name-from-dir", "name")
 	projCmd.MarkFlagsMutuallyExclusive("var-prefix", "get")
+	projCmd.MarkFlagsMutuallyExclusive("compact-files", "get")
+	projCmd.MarkFlagsMutuallyExclusive("env-fallback", "get")
+	projCmd.MarkFlagsMutuallyExclusive("max-history", "get")
+	projCmd.MarkFlagsMutuallyExclusive("client-cert", "get")
+	projCmd.MarkFlagsMutuallyExclusive("client-key", "get")
+	projCmd.MarkFlagsMutuallyExclusive("ca-cert", "get")
+	projCmd.MarkFlagsMutuallyExclusive("add-default-header", "get")
+	projCmd.MarkFlagsMutuallyExclusive("remove-default-header", "get")
+	projCmd.MarkFlagsMutuallyExclusive("remove-default-header", "new")
 
 	customFormattedCommandDescriptions[projCmd.Name()] = longHelp{fn: projCmdHelp, resultIsWrapped: true}
 
@@ -93,6 +128,30 @@ var (
 		s += "The project can be modified by passing any flag allowed with --new "
 		s += "and provided a value.\n"
 		s += "\n"
+		s += "To find problems in the project before they cause a send or an exec to fail, "
+		s += "pass --check. Each problem found is printed on its own line, and morc exits with "
+		s += "a non-zero status if there are any. The check finds request templates that have "
+		s += "no method or URL, request templates with invalid captures or an auth flow that "
+		s += "does not exist, flows that have no steps, call request templates that do not exist "
+		s += "or cannot be sent, or have steps that require vars that will not be set, and vars "
+		s += "used in request templates or default headers that are not defined in any "
+		s += "environment and are not captured by any request template.\n"
+		s += "\n"
+		s += "Settings that are not set in the project file are given a default when morc "
+		s += "runs. To see every setting as it is used, pass --dump-effective-config. Each "
+		s += "setting is printed with the name it has in the attributes section, along with "
+		s += "the project file in use and the active var environment. The values of default "
+		s += "headers that look like they hold a secret, such as Authorization, are masked, "
+		s += "as are the values of any secret vars in the other headers. Only the project "
+		s += "file and the defaults are shown; flags given to a single send or exec, which "
+		s += "are --timeout, --cert, --key, --cacert, --env-fallback, and --var-prefix, are "
+		s += "applied on top of them for that command only.\n"
+		s += "\n"
+		s += "Instead of giving a name with --name, --set-name-from-dir can be used to "
+		s += "name the project after the current directory. If the current directory "
+		s += "has no usable name, such as when it is the root directory, the name '"
+		s += defaultProjectName + "' is used instead.\n"
+		s += "\n"
 		s += "Attributes for --get:\n"
 
 		// above is starting string, load it into a roseditor and then insert
@@ -104,7 +163,15 @@ var (
 			{projKeySeshFile.Name(), "The path to the session file. Does not affect whether sessions (cookies) are actually recorded; use " + projKeyCookies.Name() + " for that. If the special string '" + morc.ProjDirVar + "' is in the path, it is replaced with the directory containing the project file whenever morc is executed, allowing the session file path to still function even if the containing directory is moved."},
 			{projKeyHistory.Name(), "Whether cookie recording is enabled. When setting, the value must must be the string 'ON' or 'OFF' (case-insensitive). Setting this is equivalent to calling 'morc cookies --on' or 'morc cookies --off'"},
 			{projKeyCookieLifetime.Name(), "The lifetime of recorded Set-Cookie calls. When setting, the value must be a duration such as '24h' or '1h30m'. If set to 0 or less, it will be interpreted as 24h. Altering this will immediately apply an eviction check to all current cookies; this may result in some being purged."},
+			{projKeyRequestTimeout.Name(), "The longest that a request sent from the project may take before it is abandoned. When setting, the value must be a duration such as '30s' or '1m'. If set to 0 or less, it will be interpreted as 30s. It can be overridden for a single send with --timeout."},
 			{projKeyVarPrefix.Name(), "The prefix used by variables in request templates. Variables that have the form PREFIX{VAR_NAME} in request templates will be interpreted with their actual value prior to sending the request."},
+			{projKeyCompactFiles.Name(), "Whether the project, history, and session files are written as compact single-line JSON. The value will either be the string 'ON' or 'OFF' (case-insensitive). When OFF, the files are written as indented JSON. Defaults to OFF."},
+			{projKeyEnvFallback.Name(), "Whether a var that is not defined in the project is taken from the OS environment variable with the same name when a request is sent, such as for secrets that should not be saved in the project file. The value will either be the string 'ON' or 'OFF' (case-insensitive). When OFF, only vars in the project and those given with -V are used, although it can be turned on for a single send with --env-fallback. Defaults to OFF."},
+			{projKeyMaxHistory.Name(), "The most entries that history may hold. Once it is full, the oldest entry is dropped each time a request is sent and recorded. When setting, the value must be a non-negative integer. A new cap takes effect the next time an entry is added; to trim history right away, use 'morc hist --clear-keep-last'. If set to 0, history is unlimited. Defaults to 0."},
+			{projKeyClientCert.Name(), "The path to a PEM-encoded client certificate that is presented to servers that ask for one, for mutual TLS. If " + projKeyClientKey.Name() + " is not set, the private key is read from this file as well. If the special string '" + morc.ProjDirVar + "' is in the path, it is replaced with the directory containing the project file. It can be overridden for a single send with --cert."},
+			{projKeyClientKey.Name(), "The path to the PEM-encoded private key of the client certificate in " + projKeyClientCert.Name() + ". If the special string '" + morc.ProjDirVar + "' is in the path, it is replaced with the directory containing the project file."},
+			{projKeyCACert.Name(), "The path to a bundle of PEM-encoded CA certificates that servers are verified with instead of those of the system. If the special string '" + morc.ProjDirVar + "' is in the path, it is replaced with the directory containing the project file. It can be overridden for a single send with --cacert."},
+			{projKeyDefaultHeaders.Name(), "Headers that are sent with every request template in the project. A request template's own header takes precedence over a default header with the same key. Default headers are added with --add-default-header and removed with --remove-default-header."},
 		}
 
 		// format all with roseditor.
@@ -125,7 +192,7 @@ func invokeProjEdit(io cmdio.IO, projFile string, attrs projAttrValues) error {
 	// if either the history file or session file are altered, or if cookie
 	// lifetime is altered, we need to load the current files to mutate or
 	// copy the data
-	modifyAllFiles := attrs.changesFilePaths() || attrs.cookieLifetime.set
+	modifyAllFiles := attrs.changesFilePaths() || attrs.cookieLifetime.set || attrs.compactFiles.set
 
 	// load the project file
 	p, err := readProject(projFile, modifyAllFiles)
@@ -208,6 +275,15 @@ func invokeProjEdit(io cmdio.IO, projFile string, attrs projAttrValues) error {
 		}
 	}
 
+	if attrs.requestTimeout.set {
+		if attrs.requestTimeout.v == p.Config.RequestTimeout {
+			noChangeVals[projKeyRequestTimeout] = p.Config.RequestTimeout
+		} else {
+			p.Config.RequestTimeout = attrs.requestTimeout.v
+			modifiedVals[projKeyRequestTimeout] = p.Config.RequestTimeout
+		}
+	}
+
 	if attrs.recordHistory.set {
 		// enabling is not allowed if the history file is unset
 		if p.Config.HistFile == "" && attrs.recordHistory.Is(true) {
@@ -245,6 +321,86 @@ func invokeProjEdit(io cmdio.IO, projFile string, attrs projAttrValues) error {
 		}
 	}
 
+	if attrs.compactFiles.set {
+		if attrs.compactFiles.v == p.Config.CompactFiles {
+			noChangeVals[projKeyCompactFiles] = io.OnOrOff(p.Config.CompactFiles)
+		} else {
+			p.Config.CompactFiles = attrs.compactFiles.v
+			modifiedVals[projKeyCompactFiles] = io.OnOrOff(p.Config.CompactFiles)
+		}
+	}
+
+	if attrs.envFallback.set {
+		if attrs.envFallback.v == p.Config.EnvFallback {
+			noChangeVals[projKeyEnvFallback] = io.OnOrOff(p.Config.EnvFallback)
+		} else {
+			p.Config.EnvFallback = attrs.envFallback.v
+			modifiedVals[projKeyEnvFallback] = io.OnOrOff(p.Config.EnvFallback)
+		}
+	}
+
+	if attrs.maxHistory.set {
+		if attrs.maxHistory.v == p.Config.MaxHistoryEntries {
+			noChangeVals[projKeyMaxHistory] = strconv.Itoa(p.Config.MaxHistoryEntries)
+		} else {
+			p.Config.MaxHistoryEntries = attrs.maxHistory.v
+			modifiedVals[projKeyMaxHistory] = strconv.Itoa(p.Config.MaxHistoryEntries)
+		}
+	}
+
+	if attrs.clientCert.set {
+		if attrs.clientCert.v == p.Config.ClientCertFile {
+			noChangeVals[projKeyClientCert] = p.Config.ClientCertFile
+		} else {
+			p.Config.ClientCertFile = attrs.clientCert.v
+			modifiedVals[projKeyClientCert] = p.Config.ClientCertFile
+		}
+	}
+
+	if attrs.clientKey.set {
+		if attrs.clientKey.v == p.Config.ClientKeyFile {
+			noChangeVals[projKeyClientKey] = p.Config.ClientKeyFile
+		} else {
+			p.Config.ClientKeyFile = attrs.clientKey.v
+			modifiedVals[projKeyClientKey] = p.Config.ClientKeyFile
+		}
+	}
+
+	if attrs.caCert.set {
+		if attrs.caCert.v == p.Config.CACertFile {
+			noChangeVals[projKeyCACert] = p.Config.CACertFile
+		} else {
+			p.Config.CACertFile = attrs.caCert.v
+			modifiedVals[projKeyCACert] = p.Config.CACertFile
+		}
+	}
+
+	if attrs.defaultHeaders.set || attrs.removeDefaultHeaders.set {
+		oldSummary := defaultHeadersSummary(p.Config.DefaultHeaders)
+
+		updated := p.Config.DefaultHeaders.Clone()
+		if updated == nil {
+			updated = make(http.Header)
+		}
+		for _, key := range attrs.removeDefaultHeaders.v {
+			updated.Del(key)
+		}
+		for key, vals := range attrs.defaultHeaders.v {
+			updated[key] = vals
+		}
+		if len(updated) == 0 {
+			updated = nil
+		}
+
+		newSummary := defaultHeadersSummary(updated)
+		if newSummary == oldSummary {
+			noChangeVals[projKeyDefaultHeaders] = oldSummary
+		} else {
+			p.Config.DefaultHeaders = updated
+			modifiedVals[projKeyDefaultHeaders] = newSummary
+		}
+	}
+
 	err = writeProject(p, modifyAllFiles)
 	if err != nil {
 		return err
@@ -280,9 +436,18 @@ func invokeProjNew(io cmdio.IO, projFile string, attrs projAttrValues) error {
 			HistFile:       attrs.histFile.v,
 			SeshFile:       attrs.seshFile.v,
 			CookieLifetime: attrs.cookieLifetime.Or(24 * time.Hour),
+			RequestTimeout: attrs.requestTimeout.Or(morc.DefaultRequestTimeout),
 			RecordSession:  attrs.recordCookies.v,
 			RecordHistory:  attrs.recordHistory.v,
 			VarPrefix:      attrs.varPrefix.Or("$"),
+			DefaultHeaders: attrs.defaultHeaders.v,
+			CompactFiles:   attrs.compactFiles.v,
+			EnvFallback:    attrs.envFallback.v,
+
+			MaxHistoryEntries: attrs.maxHistory.v,
+			ClientCertFile:    attrs.clientCert.v,
+			ClientKeyFile:     attrs.clientKey.v,
+			CACertFile:        attrs.caCert.v,
 		},
 	}
 
@@ -309,6 +474,9 @@ func invokeProjNew(io cmdio.IO, projFile string, attrs projAttrValues) error {
 		}
 	}
 
+	if attrs.nameFromDir {
+		io.PrintLoudf("Named project %q after current directory\n", p.Name)
+	}
 	io.PrintLoudf("Project created successfully in %s\n", projFile)
 
 	return nil
@@ -329,12 +497,36 @@ func invokeProjGet(io cmdio.IO, projFile string, item projKey) error {
 		io.Printf("%s\n", proj.Config.SeshFile)
 	case projKeyCookieLifetime:
 		io.Printf("%s\n", proj.Config.CookieLifetime)
+	case projKeyRequestTimeout:
+		io.Printf("%s\n", proj.RequestTimeout())
 	case projKeyCookies:
 		io.Printf("%s\n", io.OnOrOff(proj.Config.RecordSession))
 	case projKeyHistory:
 		io.Printf("%s\n", io.OnOrOff(proj.Config.RecordHistory))
 	case projKeyVarPrefix:
 		io.Printf("%s\n", proj.Config.VarPrefix)
+	case projKeyCompactFiles:
+		io.Printf("%s\n", io.OnOrOff(proj.Config.CompactFiles))
+	case projKeyEnvFallback:
+		io.Printf("%s\n", io.OnOrOff(proj.Config.EnvFallback))
+	case projKeyMaxHistory:
+		io.Printf("%d\n", proj.Config.MaxHistoryEntries)
+	case projKeyClientCert:
+		io.Printf("%s\n", proj.Config.ClientCertFile)
+	case projKeyClientKey:
+		io.Printf("%s\n", proj.Config.ClientKeyFile)
+	case projKeyCACert:
+		io.Printf("%s\n", proj.Config.CACertFile)
+	case projKeyDefaultHeaders:
+		if len(proj.Config.DefaultHeaders) == 0 {
+			io.PrintLoudf("(none)\n")
+		} else {
+			for _, name := range sortedHeaderKeys(proj.Config.DefaultHeaders) {
+				for _, val := range proj.Config.DefaultHeaders[name] {
+					io.Printf("%s: %s\n", name, val)
+				}
+			}
+		}
 	default:
 		panic(fmt.Sprintf("unhandled proj key %q", item))
 	}
@@ -342,6 +534,192 @@ func invokeProjGet(io cmdio.IO, projFile string, item projKey) error {
 	return nil
 }
 
+func invokeProjDumpConfig(io cmdio.IO, projFile string) error {
+	p, err := readProject(projFile, false)
+	if err != nil {
+		return err
+	}
+
+	cfg := p.EffectiveConfig()
+
+	// projFile is the path actually in use, which may have come from the -F
+	// flag, an environment variable, or a project pointer file.
+	io.Printf("PROJECT-FILE: %s\n", projFile)
+	io.Printf("%s: %s\n", projKeyHistFile, cfg.HistFile)
+	io.Printf("%s: %s\n", projKeyHistory, io.OnOrOff(cfg.RecordHistory))
+	io.Printf("%s: %s\n", projKeySeshFile, cfg.SeshFile)
+	io.Printf("%s: %s\n", projKeyCookies, io.OnOrOff(cfg.RecordSession))
+	io.Printf("%s: %s\n", projKeyCookieLifetime, cfg.CookieLifetime)
+	io.Printf("%s: %s\n", projKeyRequestTimeout, cfg.RequestTimeout)
+	io.Printf("%s: %s\n", projKeyVarPrefix, cfg.VarPrefix)
+	io.Printf("%s: %s\n", projKeyCompactFiles, io.OnOrOff(cfg.CompactFiles))
+	io.Printf("%s: %s\n", projKeyEnvFallback, io.OnOrOff(cfg.EnvFallback))
+	io.Printf("%s: %d\n", projKeyMaxHistory, cfg.MaxHistoryEntries)
+	io.Printf("%s: %s\n", projKeyClientCert, cfg.ClientCertFile)
+	io.Printf("%s: %s\n", projKeyClientKey, cfg.ClientKeyFile)
+	io.Printf("%s: %s\n", projKeyCACert, cfg.CACertFile)
+	io.Printf("%s: %s\n", projKeyDefaultHeaders, defaultHeadersSummary(maskSecretHeaders(cfg.DefaultHeaders, p.Vars)))
+
+	if p.Vars.Environment == "" {
+		io.Printf("ENV: (default)\n")
+	} else {
+		io.Printf("ENV: %s\n", p.Vars.Environment)
+	}
+
+	return nil
+}
+
+// maskSecretHeaders returns a copy of headers with the values of every header
+// whose key names a secret var in vars or looks like it holds a secret replaced
+// with maskedValue. The values of secret vars are masked in all other headers.
+func maskSecretHeaders(headers http.Header, vars morc.VarStore) http.Header {
+	if len(headers) == 0 {
+		return headers
+	}
+
+	masked := make(http.Header, len(headers))
+	for name, vals := range headers {
+		if !isSecretVar(vars, strings.ReplaceAll(name, "-", "_")) {
+			for _, v := range vals {
+				masked[name] = append(masked[name], morc.MaskValues(v, vars.SecretValues()))
+			}
+			continue
+		}
+		for range vals {
+			masked[name] = append(masked[name], maskedValue)
+		}
+	}
+	return masked
+}
+
+func invokeProjCheck(io cmdio.IO, projFile string) error {
+	p, err := readProject(projFile, false)
+	if err != nil {
+		return err
+	}
+
+	problems := projectProblems(p)
+	for _, prob := range problems {
+		io.Println(prob)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %s in project", io.CountOf(len(problems), "problem"))
+	}
+
+	io.PrintLoudf("No problems found in project\n")
+	return nil
+}
+
+// projectProblems returns a description of each problem in p that would cause a
+// send or an exec to fail. Problems with request templates are given first in
+// order of template name, followed by those with flows in order of flow name,
+// and finally those with default headers.
+func projectProblems(p morc.Project) []string {
+	var problems []string
+	prefix := p.VarPrefix()
+
+	// vars that are captured by some template may not be set until a send
+	captured := map[string]bool{}
+	for _, tmpl := range p.Templates {
+		for capName := range tmpl.Captures {
+			captured[strings.ToUpper(capName)] = true
+		}
+	}
+	undefined := func(name string) bool {
+		if captured[name] || p.Vars.IsDefinedIn(name, "") {
+			return false
+		}
+		if p.Config.EnvFallback {
+			if _, ok := os.LookupEnv(name); ok {
+				return false
+			}
+		}
+		return len(p.Vars.NonDefaultEnvsWith(name)) == 0
+	}
+
+	tmplNames := make([]string, 0, len(p.Templates))
+	for name := range p.Templates {
+		tmplNames = append(tmplNames, name)
+	}
+	sort.Strings(tmplNames)
+
+	for _, name := range tmplNames {
+		tmpl := p.Templates[name]
+
+		if tmpl.Method == "" {
+			problems = append(problems, fmt.Sprintf("request template %s has no method set", name))
+		}
+		if tmpl.URL == "" {
+			problems = append(problems, fmt.Sprintf("request template %s has no URL set", name))
+		}
+
+		for _, capName := range sortedCapNames(tmpl) {
+			if err := checkCapture(capName, tmpl.Captures[capName]); err != nil {
+				problems = append(problems, fmt.Sprintf("request template %s: capture %s{%s}: %v", name, prefix, capName, err))
+			}
+		}
+
+		if tmpl.AuthFlow != "" {
+			if _, ok := p.Flows[strings.ToLower(tmpl.AuthFlow)]; !ok {
+				problems = append(problems, fmt.Sprintf("request template %s: auth flow %s does not exist", name, tmpl.AuthFlow))
+			}
+		}
+
+		for _, varName := range tmpl.ReferencedVars(prefix) {
+			if undefined(varName) {
+				problems = append(problems, fmt.Sprintf("request template %s: %s{%s} is not defined in any environment", name, prefix, varName))
+			}
+		}
+	}
+
+	flowNames := make([]string, 0, len(p.Flows))
+	for name := range p.Flows {
+		flowNames = append(flowNames, name)
+	}
+	sort.Strings(flowNames)
+
+	for _, name := range flowNames {
+		flow := p.Flows[name]
+
+		if len(flow.Steps) == 0 {
+			problems = append(problems, fmt.Sprintf("flow %s has no steps", name))
+			continue
+		}
+
+		if !p.IsExecableFlow(name) {
+			for i, step := range flow.Steps {
+				tmpl, ok := p.Templates[strings.ToLower(step.Template)]
+				if !ok {
+					problems = append(problems, fmt.Sprintf("flow %s: step #%d calls non-existent request template %s", name, i+1, step.Template))
+				} else if !tmpl.Sendable() {
+					problems = append(problems, fmt.Sprintf("flow %s: step #%d calls incomplete request template %s", name, i+1, step.Template))
+				}
+			}
+			continue
+		}
+
+		_, templates, err := getExecableFlow(p, name)
+		if err == nil {
+			err = checkFlowRequires(flow, templates, p.Vars.MergedSet(nil), prefix)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("flow %s: %v", name, err))
+		}
+	}
+
+	for _, key := range sortedHeaderKeys(p.Config.DefaultHeaders) {
+		hdrTmpl := morc.RequestTemplate{Headers: http.Header{key: p.Config.DefaultHeaders[key]}}
+		for _, varName := range hdrTmpl.ReferencedVars(prefix) {
+			if undefined(varName) {
+				problems = append(problems, fmt.Sprintf("default header %s: %s{%s} is not defined in any environment", key, prefix, varName))
+			}
+		}
+	}
+
+	return problems
+}
+
 func invokeProjShow(io cmdio.IO, projFile string) error {
 	proj, err := readProject(projFile, true)
 	if err != nil {
@@ -356,11 +734,29 @@ func invokeProjShow(io cmdio.IO, projFile string) error {
 	io.Println()
 	io.Printf("Variable prefix: %s\n", proj.VarPrefix())
 	io.Printf("Cookie record lifetime: %s\n", proj.Config.CookieLifetime)
+	io.Printf("Request timeout: %s\n", proj.RequestTimeout())
+	io.Printf("Default headers: %s\n", defaultHeadersSummary(proj.Config.DefaultHeaders))
 	io.Printf("Project file on record: %s\n", proj.Config.ProjFile)
 	io.Printf("Session file on record: %s\n", proj.Config.SeshFile)
 	io.Printf("History file on record: %s\n", proj.Config.HistFile)
 	io.Printf("Cookie recording is %s\n", io.OnOrOff(proj.Config.RecordSession))
 	io.Printf("History tracking is %s\n", io.OnOrOff(proj.Config.RecordHistory))
+	if proj.Config.MaxHistoryEntries > 0 {
+		io.Printf("History is capped at %s\n", io.CountOf(proj.Config.MaxHistoryEntries, "entr", "ies", "y"))
+	} else {
+		io.Printf("History size is unlimited\n")
+	}
+	io.Printf("Compact file output is %s\n", io.OnOrOff(proj.Config.CompactFiles))
+	io.Printf("Environment var fallback is %s\n", io.OnOrOff(proj.Config.EnvFallback))
+	if proj.Config.ClientCertFile != "" {
+		io.Printf("Client certificate file: %s\n", proj.Config.ClientCertFile)
+	}
+	if proj.Config.ClientKeyFile != "" {
+		io.Printf("Client key file: %s\n", proj.Config.ClientKeyFile)
+	}
+	if proj.Config.CACertFile != "" {
+		io.Printf("CA certificate file: %s\n", proj.Config.CACertFile)
+	}
 	io.Println()
 	if proj.Vars.Environment == "" {
 		io.Printf("Using default var environment\n")
@@ -385,7 +781,26 @@ type projAttrValues struct {
 	seshFile       optionalC[string]
 	histFile       optionalC[string]
 	cookieLifetime optionalC[time.Duration]
+	requestTimeout optionalC[time.Duration]
 	varPrefix      optionalC[string]
+	compactFiles   optionalC[bool]
+	envFallback    optionalC[bool]
+	maxHistory     optionalC[int]
+	clientCert     optionalC[string]
+	clientKey      optionalC[string]
+	caCert         optionalC[string]
+
+	// defaultHeaders is default headers to add, replacing any existing ones
+	// with the same key.
+	defaultHeaders optional[http.Header]
+
+	// removeDefaultHeaders is keys of default headers to remove. Removals are
+	// applied before additions.
+	removeDefaultHeaders optional[[]string]
+
+	// nameFromDir is whether name was set from the current directory instead
+	// of given explicitly.
+	nameFromDir bool
 }
 
 func (sfv projAttrValues) changesFilePaths() bool {
@@ -407,7 +822,7 @@ func parseProjArgs(cmd *cobra.Command, _ []string, args *projArgs) error {
 
 	// do action-specific arg and flag parsing
 	switch args.action {
-	case projActionInfo:
+	case projActionInfo, projActionCheck, projActionDumpConfig:
 		// no-op; no further checks to do
 	case projActionGet:
 		// parse the get from the string
@@ -440,6 +855,16 @@ func parseProjActionFromFlags() (projAction, error) {
 
 	if flags.Get != "" {
 		return projActionGet, nil
+	} else if flags.BCheck {
+		if projSetFlagIsPresent() {
+			return projActionCheck, fmt.Errorf("--check cannot be given with flags that set project attributes")
+		}
+		return projActionCheck, nil
+	} else if flags.BDumpEffectiveConfig {
+		if projSetFlagIsPresent() {
+			return projActionDumpConfig, fmt.Errorf("--dump-effective-config cannot be given with flags that set project attributes")
+		}
+		return projActionDumpConfig, nil
 	} else if flags.BNew {
 		return projActionNew, nil
 	} else if projSetFlagIsPresent() {
@@ -453,6 +878,15 @@ func parseProjSetFlags(cmd *cobra.Command, attrs *projAttrValues) error {
 		attrs.name = optionalC[string]{set: true, v: flags.Name}
 	}
 
+	if flags.BNameFromDir {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("get current directory: %w", err)
+		}
+		attrs.name = optionalC[string]{set: true, v: projectNameFromDir(wd)}
+		attrs.nameFromDir = true
+	}
+
 	if cmd.Flags().Lookup("history-file").Changed {
 		attrs.histFile = optionalC[string]{set: true, v: flags.HistoryFile}
 	}
@@ -469,6 +903,14 @@ func parseProjSetFlags(cmd *cobra.Command, attrs *projAttrValues) error {
 		attrs.cookieLifetime = optionalC[time.Duration]{set: true, v: cl}
 	}
 
+	if cmd.Flags().Lookup("request-timeout").Changed {
+		rt, err := time.ParseDuration(flags.RequestTimeout)
+		if err != nil {
+			return fmt.Errorf("request-timeout: %w", err)
+		}
+		attrs.requestTimeout = optionalC[time.Duration]{set: true, v: rt}
+	}
+
 	if cmd.Flags().Lookup("cookies").Changed {
 		isOn, err := parseOnOff(flags.RecordCookies)
 		if err != nil {
@@ -493,17 +935,132 @@ func parseProjSetFlags(cmd *cobra.Command, attrs *projAttrValues) error {
 		attrs.varPrefix = optionalC[string]{set: true, v: flags.VarPrefix}
 	}
 
+	if cmd.Flags().Lookup("compact-files").Changed {
+		isOn, err := parseOnOff(flags.CompactFiles)
+		if err != nil {
+			return fmt.Errorf("compact-files: %w", err)
+		}
+		attrs.compactFiles = optionalC[bool]{set: true, v: isOn}
+	}
+
+	if cmd.Flags().Lookup("env-fallback").Changed {
+		isOn, err := parseOnOff(flags.EnvFallback)
+		if err != nil {
+			return fmt.Errorf("env-fallback: %w", err)
+		}
+		attrs.envFallback = optionalC[bool]{set: true, v: isOn}
+	}
+
+	if cmd.Flags().Lookup("max-history").Changed {
+		max, err := strconv.Atoi(flags.MaxHistory)
+		if err != nil {
+			return fmt.Errorf("max-history: %q is not a valid integer", flags.MaxHistory)
+		}
+		if max < 0 {
+			return fmt.Errorf("max-history: must be a non-negative number of entries")
+		}
+		attrs.maxHistory = optionalC[int]{set: true, v: max}
+	}
+
+	if cmd.Flags().Lookup("client-cert").Changed {
+		attrs.clientCert = optionalC[string]{set: true, v: flags.ClientCert}
+	}
+
+	if cmd.Flags().Lookup("client-key").Changed {
+		attrs.clientKey = optionalC[string]{set: true, v: flags.ClientKey}
+	}
+
+	if cmd.Flags().Lookup("ca-cert").Changed {
+		attrs.caCert = optionalC[string]{set: true, v: flags.CACert}
+	}
+
+	if cmd.Flags().Lookup("add-default-header").Changed {
+		headers := make(http.Header)
+		for idx, h := range flags.DefaultHeaders {
+			// split the header into key and value
+			parts := strings.SplitN(h, ":", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("default header add #%d (%q) is not in format key: value", idx+1, h)
+			}
+			canonKey := http.CanonicalHeaderKey(strings.TrimSpace(parts[0]))
+			if canonKey == "" {
+				return fmt.Errorf("default header add #%d (%q) does not have a valid header key", idx+1, h)
+			}
+			headers.Add(canonKey, strings.TrimSpace(parts[1]))
+		}
+		attrs.defaultHeaders = optional[http.Header]{set: true, v: headers}
+	}
+
+	if cmd.Flags().Lookup("remove-default-header").Changed {
+		delHeaders := make([]string, len(flags.RemoveDefaultHeaders))
+		for idx, h := range flags.RemoveDefaultHeaders {
+			trimmed := strings.TrimSpace(h)
+			if trimmed == "" || strings.Contains(trimmed, " ") || strings.Contains(trimmed, ":") {
+				return fmt.Errorf("default header delete #%d (%q) is not a valid header key", idx+1, h)
+			}
+			delHeaders[idx] = trimmed
+		}
+		attrs.removeDefaultHeaders = optional[[]string]{set: true, v: delHeaders}
+	}
+
 	return nil
 }
 
+// defaultHeadersSummary returns a single-line description of the given default
+// headers, with keys in alphabetical order.
+func defaultHeadersSummary(headers http.Header) string {
+	if len(headers) == 0 {
+		return "(none)"
+	}
+
+	var items []string
+	for _, name := range sortedHeaderKeys(headers) {
+		for _, val := range headers[name] {
+			items = append(items, fmt.Sprintf("%s: %s", name, val))
+		}
+	}
+	return strings.Join(items, "; ")
+}
+
+// sortedHeaderKeys returns the keys of headers in alphabetical order.
+func sortedHeaderKeys(headers http.Header) []string {
+	keys := make([]string, 0, len(headers))
+	for name := range headers {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// projectNameFromDir returns the project name to use for a project in the given
+// directory. If the directory has no usable base name, as is the case for the
+// root directory, defaultProjectName is returned.
+func projectNameFromDir(dir string) string {
+	name := filepath.Base(filepath.Clean(dir))
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		return defaultProjectName
+	}
+	return name
+}
+
 func projSetFlagIsPresent() bool {
 	return flags.Name != "" ||
+		flags.BNameFromDir ||
 		flags.HistoryFile != "" ||
 		flags.SessionFile != "" ||
 		flags.CookieLifetime != "" ||
+		flags.RequestTimeout != "" ||
 		flags.RecordCookies != "" ||
 		flags.RecordHistory != "" ||
-		flags.VarPrefix != ""
+		flags.VarPrefix != "" ||
+		flags.CompactFiles != "" ||
+		flags.EnvFallback != "" ||
+		flags.MaxHistory != "" ||
+		flags.ClientCert != "" ||
+		flags.ClientKey != "" ||
+		flags.CACert != "" ||
+		len(flags.DefaultHeaders) > 0 ||
+		len(flags.RemoveDefaultHeaders) > 0
 }
 
 type projAction int
@@ -513,6 +1070,8 @@ const (
 	projActionGet
 	projActionNew
 	projActionEdit
+	projActionCheck
+	projActionDumpConfig
 )
 
 type projKey string
@@ -522,9 +1081,17 @@ const (
 	projKeyHistFile       projKey = "HISTORY-FILE"
 	projKeySeshFile       projKey = "SESSION-FILE"
 	projKeyCookieLifetime projKey = "COOKIE-LIFETIME"
+	projKeyRequestTimeout projKey = "REQUEST-TIMEOUT"
 	projKeyCookies        projKey = "COOKIES"
 	projKeyHistory        projKey = "HISTORY"
 	projKeyVarPrefix      projKey = "VAR-PREFIX"
+	projKeyCompactFiles   projKey = "COMPACT-FILES"
+	projKeyEnvFallback    projKey = "ENV-FALLBACK"
+	projKeyMaxHistory     projKey = "MAX-HISTORY"
+	projKeyClientCert     projKey = "CLIENT-CERT"
+	projKeyClientKey      projKey = "CLIENT-KEY"
+	projKeyCACert         projKey = "CA-CERT"
+	projKeyDefaultHeaders projKey = "DEFAULT-HEADERS"
 )
 
 // Human prints the human-readable description of the key.
@@ -538,12 +1105,28 @@ func (pk projKey) Human() string {
 		return "session file"
 	case projKeyCookieLifetime:
 		return "cookie lifetime"
+	case projKeyRequestTimeout:
+		return "request timeout"
 	case projKeyCookies:
 		return "cookie recording"
 	case projKeyHistory:
 		return "history recording"
 	case projKeyVarPrefix:
 		return "variable prefix"
+	case projKeyCompactFiles:
+		return "compact file output"
+	case projKeyEnvFallback:
+		return "environment var fallback"
+	case projKeyMaxHistory:
+		return "history size cap"
+	case projKeyClientCert:
+		return "client certificate file"
+	case projKeyClientKey:
+		return "client key file"
+	case projKeyCACert:
+		return "CA certificate file"
+	case projKeyDefaultHeaders:
+		return "default headers"
 	default:
 		return fmt.Sprintf("unknown project key %q", pk)
 	}
@@ -563,7 +1146,15 @@ var (
 		projKeySeshFile,
 		projKeyCookies,
 		projKeyCookieLifetime,
+		projKeyRequestTimeout,
 		projKeyVarPrefix,
+		projKeyCompactFiles,
+		projKeyEnvFallback,
+		projKeyMaxHistory,
+		projKeyClientCert,
+		projKeyClientKey,
+		projKeyCACert,
+		projKeyDefaultHeaders,
 	}
 )
 
@@ -585,12 +1176,28 @@ func parseProjAttrKey(s string) (projKey, error) {
 		return projKeySeshFile, nil
 	case projKeyCookieLifetime.Name():
 		return projKeyCookieLifetime, nil
+	case projKeyRequestTimeout.Name():
+		return projKeyRequestTimeout, nil
 	case projKeyCookies.Name():
 		return projKeyCookies, nil
 	case projKeyHistory.Name():
 		return projKeyHistory, nil
 	case projKeyVarPrefix.Name():
 		return projKeyVarPrefix, nil
+	case projKeyCompactFiles.Name():
+		return projKeyCompactFiles, nil
+	case projKeyEnvFallback.Name():
+		return projKeyEnvFallback, nil
+	case projKeyMaxHistory.Name():
+		return projKeyMaxHistory, nil
+	case projKeyClientCert.Name():
+		return projKeyClientCert, nil
+	case projKeyClientKey.Name():
+		return projKeyClientKey, nil
+	case projKeyCACert.Name():
+		return projKeyCACert, nil
+	case projKeyDefaultHeaders.Name():
+		return projKeyDefaultHeaders, nil
 	default:
 		return "", fmt.Errorf("invalid attribute %q; must be one of %s", s, strings.Join(projAttrKeyNames(), ", "))
 	}
@@ -1,8 +1,11 @@
 package commands
 
 import (
+	"net/http"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dekarrin/morc"
 	"github.com/spf13/pflag"
@@ -31,6 +34,12 @@ func Test_Proj_Show(t *testing.T) {
 Variable prefix: $
 Cookie record lifetime: 0s`,
 		},
+		{
+			name:               "show project with name",
+			args:               []string{"proj"},
+			p:                  morc.Project{Name: "My API"},
+			expectStdoutOutput: "Project: My API\n0 requests, 0 flows\n",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -69,6 +78,154 @@ Cookie record lifetime: 0s`,
 	}
 }
 
+func Test_Proj_DumpEffectiveConfig(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name: "defaults are applied",
+			args: []string{"proj", "--dump-effective-config"},
+			p:    morc.Project{Name: "TEST"},
+			expectStdoutOutput: `HISTORY-FILE: 
+HISTORY: OFF
+SESSION-FILE: 
+COOKIES: OFF
+COOKIE-LIFETIME: 24h0m0s
+REQUEST-TIMEOUT: 30s
+VAR-PREFIX: $
+COMPACT-FILES: OFF
+ENV-FALLBACK: OFF
+MAX-HISTORY: 0
+CLIENT-CERT: 
+CLIENT-KEY: 
+CA-CERT: 
+DEFAULT-HEADERS: (none)
+ENV: (default)
+`,
+		},
+		{
+			name: "set values are used",
+			args: []string{"proj", "--dump-effective-config"},
+			p: morc.Project{
+				Name: "TEST",
+				Config: morc.Settings{
+					CookieLifetime: time.Hour,
+					RequestTimeout: 5 * time.Second,
+					VarPrefix:      "#",
+					CompactFiles:   true,
+					EnvFallback:    true,
+
+					MaxHistoryEntries: 50,
+					ClientCertFile:    "certs/client.pem",
+					CACertFile:        "certs/ca.pem",
+				},
+				Vars: testVarStore("STAGING", map[string]map[string]string{
+					"":        {},
+					"STAGING": {},
+				}),
+			},
+			expectStdoutOutput: `COOKIE-LIFETIME: 1h0m0s
+REQUEST-TIMEOUT: 5s
+VAR-PREFIX: #
+COMPACT-FILES: ON
+ENV-FALLBACK: ON
+MAX-HISTORY: 50
+CLIENT-CERT: certs/client.pem
+CLIENT-KEY: 
+CA-CERT: certs/ca.pem
+DEFAULT-HEADERS: (none)
+ENV: STAGING
+`,
+		},
+		{
+			name: "secret default headers are masked",
+			args: []string{"proj", "--dump-effective-config"},
+			p: morc.Project{
+				Name: "TEST",
+				Config: morc.Settings{
+					DefaultHeaders: http.Header{
+						"Accept":        {"application/json"},
+						"Authorization": {"Bearer 413"},
+						"X-Api-Key":     {"612"},
+					},
+				},
+			},
+			expectStdoutOutput: "DEFAULT-HEADERS: Accept: application/json; Authorization: ***; X-Api-Key: ***\n",
+		},
+		{
+			name: "default headers with secret vars are masked",
+			args: []string{"proj", "--dump-effective-config"},
+			p: func() morc.Project {
+				p := morc.Project{
+					Name: "TEST",
+					Config: morc.Settings{
+						DefaultHeaders: http.Header{
+							"Accept":    {"application/json"},
+							"X-Session": {"1025"},
+							"X-Trace":   {"trace-8128"},
+						},
+					},
+					Vars: testVarStore("", map[string]map[string]string{
+						"": {"X_SESSION": "", "TRACE_ID": "8128"},
+					}),
+				}
+				p.Vars.SetSecret("X_SESSION", true)
+				p.Vars.SetSecret("TRACE_ID", true)
+				return p
+			}(),
+			expectStdoutOutput: "DEFAULT-HEADERS: Accept: application/json; X-Session: ***; X-Trace: trace-***\n",
+		},
+		{
+			name:      "set flags are not allowed",
+			args:      []string{"proj", "--dump-effective-config", "--name", "TEST"},
+			p:         morc.Project{},
+			expectErr: "--dump-effective-config cannot be given with flags that set project attributes",
+		},
+		{
+			name:      "not allowed with --get",
+			args:      []string{"proj", "--dump-effective-config", "--get", "name"},
+			p:         morc.Project{},
+			expectErr: "were all set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetProjFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, _, err := runTestCommand(projCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			}
+			if tc.expectErr != "" {
+				t.Fatalf("expected returned error to contain %q, got no error", tc.expectErr)
+			}
+
+			assert.Contains(output, "PROJECT-FILE: "+projFilePath+"\n")
+			assert.Contains(output, tc.expectStdoutOutput, "stdout output mismatch")
+
+			assert_noProjectMutations(assert)
+		})
+	}
+}
+
 func Test_Proj_Get(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -94,6 +251,78 @@ func Test_Proj_Get(t *testing.T) {
 			},
 			expectStdoutOutput: "0s\n",
 		},
+		{
+			name: "get request timeout - default",
+			args: []string{"proj", "-G", "request-timeout"},
+			p: morc.Project{
+				Name: "TEST",
+			},
+			expectStdoutOutput: "30s\n",
+		},
+		{
+			name: "get request timeout - set",
+			args: []string{"proj", "-G", "request-timeout"},
+			p: morc.Project{
+				Name:   "TEST",
+				Config: morc.Settings{RequestTimeout: 5 * time.Second},
+			},
+			expectStdoutOutput: "5s\n",
+		},
+		{
+			name: "get compact files",
+			args: []string{"proj", "-G", "compact-files"},
+			p: morc.Project{
+				Name: "TEST",
+			},
+			expectStdoutOutput: "OFF\n",
+		},
+		{
+			name: "get env fallback",
+			args: []string{"proj", "-G", "env-fallback"},
+			p: morc.Project{
+				Name:   "TEST",
+				Config: morc.Settings{EnvFallback: true},
+			},
+			expectStdoutOutput: "ON\n",
+		},
+		{
+			name: "get max history",
+			args: []string{"proj", "-G", "max-history"},
+			p: morc.Project{
+				Name:   "TEST",
+				Config: morc.Settings{MaxHistoryEntries: 50},
+			},
+			expectStdoutOutput: "50\n",
+		},
+		{
+			name: "get client cert",
+			args: []string{"proj", "-G", "client-cert"},
+			p: morc.Project{
+				Name:   "TEST",
+				Config: morc.Settings{ClientCertFile: "certs/client.pem"},
+			},
+			expectStdoutOutput: "certs/client.pem\n",
+		},
+		{
+			name: "get default headers - none",
+			args: []string{"proj", "-G", "default-headers"},
+			p: morc.Project{
+				Name: "TEST",
+			},
+			expectStdoutOutput: "(none)\n",
+		},
+		{
+			name: "get default headers",
+			args: []string{"proj", "-G", "default-headers"},
+			p: morc.Project{
+				Name: "TEST",
+				Config: morc.Settings{DefaultHeaders: http.Header{
+					"User-Agent": {"morc-test"},
+					"Accept":     {"text/plain", "text/html"},
+				}},
+			},
+			expectStdoutOutput: "Accept: text/plain\nAccept: text/html\nUser-Agent: morc-test\n",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -128,16 +357,352 @@ func Test_Proj_Get(t *testing.T) {
 	}
 }
 
+func Test_Proj_Check(t *testing.T) {
+	t.Setenv("MORC_TEST_TOKEN", "env-token")
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "no problems",
+			args:               []string{"proj", "--check"},
+			p:                  testProject_singleFlowWithNSteps(2),
+			expectStdoutOutput: "No problems found in project\n",
+		},
+		{
+			name: "no problems, quiet mode",
+			args: []string{"proj", "--check", "-q"},
+			p:    testProject_singleFlowWithNSteps(2),
+		},
+		{
+			name: "vars defined in another env or captured are not problems",
+			args: []string{"proj", "--check", "-q"},
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"login": {
+						Name: "login", Method: "POST", URL: "${HOST}/login",
+						Captures: map[string]morc.VarScraper{"TOKEN": {Name: "TOKEN", OffsetStart: 1, OffsetEnd: 3}},
+					},
+					"get-user": {
+						Name: "get-user", Method: "GET", URL: "${HOST}/users/${@uuid}?raw=$${ESCAPED}",
+						Headers: http.Header{"Authorization": {"Bearer ${TOKEN}"}},
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{"PROD": {"HOST": "https://example.com"}}),
+			},
+		},
+		{
+			name: "problems are reported",
+			args: []string{"proj", "--check"},
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"req1": {Name: "req1", URL: "${HOST}/users", Body: []byte(`{"id": "${USER_ID}"}`)},
+					"req2": {
+						Name: "req2", Method: "GET", URL: "https://example.com", AuthFlow: "login",
+						Captures: map[string]morc.VarScraper{"TOKEN": {Name: "TOKEN", OffsetStart: 3, OffsetEnd: 1}},
+					},
+				},
+				Flows: map[string]morc.Flow{
+					"empty":  {Name: "empty"},
+					"broken": {Name: "broken", Steps: []morc.FlowStep{{Template: "req2"}, {Template: "req3"}, {Template: "req1"}}},
+					"needs":  {Name: "needs", Steps: []morc.FlowStep{{Template: "req2", Requires: []string{"NAME"}}}},
+				},
+				Vars: testVarStore("", map[string]map[string]string{"": {"HOST": "https://example.com"}}),
+				Config: morc.Settings{
+					DefaultHeaders: http.Header{"X-Api-Key": {"${API_KEY}"}},
+				},
+			},
+			expectStdoutOutput: "request template req1 has no method set\n" +
+				"request template req1: ${USER_ID} is not defined in any environment\n" +
+				"request template req2: capture ${TOKEN}: end offset 1 is less than or equal to start offset 3\n" +
+				"request template req2: auth flow login does not exist\n" +
+				"flow broken: step #2 calls non-existent request template req3\n" +
+				"flow broken: step #3 calls incomplete request template req1\n" +
+				"flow empty has no steps\n" +
+				"flow needs: step #0 requires ${NAME}, but it is not set by any prior step or by the var store\n" +
+				"default header X-Api-Key: ${API_KEY} is not defined in any environment\n",
+			expectErr: "found 9 problems in project",
+		},
+		{
+			name: "vars from environment are defined with env fallback",
+			args: []string{"proj", "--check"},
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"req1": {Name: "req1", Method: "GET", URL: "https://example.com", Headers: http.Header{"Authorization": {"Bearer ${MORC_TEST_TOKEN}"}}},
+				},
+				Config: morc.Settings{EnvFallback: true},
+			},
+			expectStdoutOutput: "No problems found in project\n",
+		},
+		{
+			name:      "check with set flag",
+			args:      []string{"proj", "--check", "-n", "TEST"},
+			p:         testProject_singleFlowWithNSteps(2),
+			expectErr: "--check cannot be given with flags that set project attributes",
+		},
+		{
+			name:      "check with get",
+			args:      []string{"proj", "--check", "-G", "name"},
+			p:         testProject_singleFlowWithNSteps(2),
+			expectErr: "[check get] were all set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetProjFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(projCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_noProjectMutations(assert)
+		})
+	}
+}
+
+func Test_Proj_Edit(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectP            morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "set name",
+			args:               []string{"proj", "-n", "vriska"},
+			p:                  morc.Project{Name: "TEST"},
+			expectP:            morc.Project{Name: "vriska"},
+			expectStdoutOutput: "Set project name to vriska\n",
+		},
+		{
+			name:               "set name to current name",
+			args:               []string{"proj", "--name", "My API"},
+			p:                  morc.Project{Name: "My API"},
+			expectP:            morc.Project{Name: "My API"},
+			expectStderrOutput: "No change to project name; already set to My API\n",
+		},
+		{
+			name:               "set name from dir",
+			args:               []string{"proj", "--set-name-from-dir"},
+			p:                  morc.Project{Name: "TEST"},
+			expectP:            morc.Project{Name: "commands"},
+			expectStdoutOutput: "Set project name to commands\n",
+		},
+		{
+			name:      "set name from dir with name",
+			args:      []string{"proj", "--set-name-from-dir", "-n", "vriska"},
+			p:         morc.Project{Name: "TEST"},
+			expectErr: "[name set-name-from-dir] were all set",
+		},
+		{
+			name:               "set request timeout",
+			args:               []string{"proj", "--request-timeout", "45s"},
+			p:                  morc.Project{Name: "TEST"},
+			expectP:            morc.Project{Name: "TEST", Config: morc.Settings{RequestTimeout: 45 * time.Second}},
+			expectStdoutOutput: "Set request timeout to 45s\n",
+		},
+		{
+			name:      "set request timeout - invalid",
+			args:      []string{"proj", "--request-timeout", "soon"},
+			p:         morc.Project{Name: "TEST"},
+			expectErr: "request-timeout: time: invalid duration",
+		},
+		{
+			name:               "set compact files",
+			args:               []string{"proj", "--compact-files", "on"},
+			p:                  morc.Project{Name: "TEST"},
+			expectP:            morc.Project{Name: "TEST", Config: morc.Settings{CompactFiles: true}},
+			expectStdoutOutput: "Set compact file output to ON\n",
+		},
+		{
+			name:               "set env fallback",
+			args:               []string{"proj", "--env-fallback", "on"},
+			p:                  morc.Project{Name: "TEST"},
+			expectP:            morc.Project{Name: "TEST", Config: morc.Settings{EnvFallback: true}},
+			expectStdoutOutput: "Set environment var fallback to ON\n",
+		},
+		{
+			name:      "set env fallback - invalid",
+			args:      []string{"proj", "--env-fallback", "maybe"},
+			p:         morc.Project{Name: "TEST"},
+			expectErr: "env-fallback: ",
+		},
+		{
+			name:               "set max history",
+			args:               []string{"proj", "--max-history", "50"},
+			p:                  morc.Project{Name: "TEST"},
+			expectP:            morc.Project{Name: "TEST", Config: morc.Settings{MaxHistoryEntries: 50}},
+			expectStdoutOutput: "Set history size cap to 50\n",
+		},
+		{
+			name:      "set max history - negative",
+			args:      []string{"proj", "--max-history", "-1"},
+			p:         morc.Project{Name: "TEST"},
+			expectErr: "max-history: must be a non-negative number of entries",
+		},
+		{
+			name:      "set max history - invalid",
+			args:      []string{"proj", "--max-history", "lots"},
+			p:         morc.Project{Name: "TEST"},
+			expectErr: `max-history: "lots" is not a valid integer`,
+		},
+		{
+			name:               "set client cert and key",
+			args:               []string{"proj", "--client-cert", "certs/client.pem", "--client-key", "certs/client.key"},
+			p:                  morc.Project{Name: "TEST"},
+			expectP:            morc.Project{Name: "TEST", Config: morc.Settings{ClientCertFile: "certs/client.pem", ClientKeyFile: "certs/client.key"}},
+			expectStdoutOutput: "Set client certificate file to certs/client.pem and client key file to certs/client.key\n",
+		},
+		{
+			name:               "set CA cert",
+			args:               []string{"proj", "--ca-cert", "certs/ca.pem"},
+			p:                  morc.Project{Name: "TEST"},
+			expectP:            morc.Project{Name: "TEST", Config: morc.Settings{CACertFile: "certs/ca.pem"}},
+			expectStdoutOutput: "Set CA certificate file to certs/ca.pem\n",
+		},
+		{
+			name:               "add default header",
+			args:               []string{"proj", "--add-default-header", "user-agent: morc-test"},
+			p:                  morc.Project{Name: "TEST"},
+			expectP:            morc.Project{Name: "TEST", Config: morc.Settings{DefaultHeaders: http.Header{"User-Agent": {"morc-test"}}}},
+			expectStdoutOutput: "Set default headers to User-Agent: morc-test\n",
+		},
+		{
+			name: "add default header replaces existing",
+			args: []string{"proj", "--add-default-header", "Accept: application/json"},
+			p: morc.Project{Name: "TEST", Config: morc.Settings{DefaultHeaders: http.Header{
+				"User-Agent": {"morc-test"},
+				"Accept":     {"text/plain"},
+			}}},
+			expectP: morc.Project{Name: "TEST", Config: morc.Settings{DefaultHeaders: http.Header{
+				"User-Agent": {"morc-test"},
+				"Accept":     {"application/json"},
+			}}},
+			expectStdoutOutput: "Set default headers to Accept: application/json; User-Agent: morc-test\n",
+		},
+		{
+			name:               "remove default header",
+			args:               []string{"proj", "--remove-default-header", "user-agent"},
+			p:                  morc.Project{Name: "TEST", Config: morc.Settings{DefaultHeaders: http.Header{"User-Agent": {"morc-test"}}}},
+			expectP:            morc.Project{Name: "TEST"},
+			expectStdoutOutput: "Set default headers to (none)\n",
+		},
+		{
+			name:               "remove default header that does not exist",
+			args:               []string{"proj", "--remove-default-header", "Accept"},
+			p:                  morc.Project{Name: "TEST", Config: morc.Settings{DefaultHeaders: http.Header{"User-Agent": {"morc-test"}}}},
+			expectP:            morc.Project{Name: "TEST", Config: morc.Settings{DefaultHeaders: http.Header{"User-Agent": {"morc-test"}}}},
+			expectStderrOutput: "No change to default headers; already set to User-Agent: morc-test\n",
+		},
+		{
+			name:      "add default header - invalid",
+			args:      []string{"proj", "--add-default-header", "User-Agent"},
+			p:         morc.Project{Name: "TEST"},
+			expectErr: `default header add #1 ("User-Agent") is not in format key: value`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetProjFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(projCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_projectPersistedToBuffer(assert, tc.expectP)
+		})
+	}
+}
+
+func Test_projectNameFromDir(t *testing.T) {
+	testCases := []struct {
+		name   string
+		dir    string
+		expect string
+	}{
+		{name: "normal dir", dir: filepath.Join("home", "vriska", "my-api"), expect: "my-api"},
+		{name: "trailing separator", dir: filepath.Join("home", "vriska", "my-api") + string(filepath.Separator), expect: "my-api"},
+		{name: "root dir", dir: string(filepath.Separator), expect: defaultProjectName},
+		{name: "empty", dir: "", expect: defaultProjectName},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, projectNameFromDir(tc.dir))
+		})
+	}
+}
+
 func resetProjFlags() {
 	flags.BNew = false
 	flags.Get = ""
 	flags.Name = ""
+	flags.BNameFromDir = false
 	flags.CookieLifetime = ""
+	flags.RequestTimeout = ""
+	flags.CompactFiles = ""
+	flags.EnvFallback = ""
+	flags.MaxHistory = ""
+	flags.ClientCert = ""
+	flags.ClientKey = ""
+	flags.CACert = ""
+	flags.DefaultHeaders = []string{}
+	flags.RemoveDefaultHeaders = []string{}
 	flags.SessionFile = ""
 	flags.HistoryFile = ""
 	flags.RecordCookies = ""
 	flags.RecordHistory = ""
 	flags.VarPrefix = ""
+	flags.BCheck = false
+	flags.BDumpEffectiveConfig = false
+	flags.BQuiet = false
 
 	projCmd.Flags().VisitAll(func(fl *pflag.Flag) {
 		fl.Changed = false
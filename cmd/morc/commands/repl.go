@@ -0,0 +1,452 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dekarrin/morc"
+	"github.com/dekarrin/morc/cmd/morc/cmdio"
+	"github.com/dekarrin/morc/internal/sliceops"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const replPrompt = "morc> "
+
+var replCmd = &cobra.Command{
+	Use: "repl",
+	Annotations: map[string]string{
+		annotationKeyHelpUsages: "" +
+			"repl [-q]",
+	},
+	GroupID: "project",
+	Short:   "Run commands against a project in an interactive prompt",
+	Long: "Open an interactive prompt for running commands against a project without starting MORC again for each " +
+		"one. Each line entered is run as though it were given to morc, without the leading 'morc'; for example, " +
+		"'send get-user' or 'vars USER_ID 413'. Arguments are split on whitespace, and can be grouped with single " +
+		"quotes, which keep their contents exactly as typed, or double quotes, in which a backslash escapes a double " +
+		"quote or another backslash.\n\n" +
+		"The project is loaded once when the prompt opens and is kept in memory, so variables captured by one command " +
+		"are available to the next. Any changes are still saved to the project files just as they would be outside " +
+		"of the prompt. Cookies set by responses are kept for the rest of the prompt even if cookie recording is not " +
+		"enabled for the project, and values captured with --no-save-captures are likewise kept in memory but not " +
+		"saved. Commands that do not give -F use the project that the prompt was opened with.\n\n" +
+		"In addition to the normal commands, the prompt accepts 'help' to list what can be run, 'help COMMAND' to " +
+		"show help for a command, 'env use ENV' to switch to environment ENV (or to the default environment if ENV " +
+		"is omitted), and 'exit' or 'quit' to close the prompt. The prompt is also closed at the end of input. If -q " +
+		"is given when opening the prompt, every command run in it is run as though it were also given -q.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, posArgs []string) error {
+		var args replArgs
+		if err := parseReplArgs(cmd, posArgs, &args); err != nil {
+			return err
+		}
+
+		// done checking args, don't show usage on error
+		cmd.SilenceUsage = true
+		io := cmdio.From(cmd)
+		io.Quiet = flags.BQuiet
+
+		return invokeRepl(io, args.projFile)
+	},
+}
+
+func init() {
+	replCmd.PersistentFlags().StringVarP(&flags.ProjectFile, "project-file", "F", morc.DefaultProjectPath, "Use `FILE` for project data instead of "+morc.DefaultProjectPath+".")
+	replCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output, including from every command run in the prompt.")
+
+	rootCmd.AddCommand(replCmd)
+}
+
+// activeRepl is the REPL session that is currently running, if any. While it is
+// set, commands use the project it holds in memory instead of loading it from
+// disk.
+var activeRepl *replSession
+
+// replSession is the state kept in memory between commands run in the REPL.
+type replSession struct {
+	projFile string
+
+	// project is the project as it was last persisted by a command, or as it
+	// was when the REPL was opened if none have been. It holds nothing that
+	// is only kept in memory.
+	project morc.Project
+
+	// unsavedVars is the values captured without being saved, keyed by the
+	// upper-case name of the env they were captured into and then by the name
+	// of the var. They are kept for the rest of the REPL but never persisted.
+	unsavedVars map[string]map[string]string
+
+	// unsavedCookies is the calls to set cookies made by responses that were
+	// not recorded to the session. They are kept for the rest of the REPL but
+	// never persisted.
+	unsavedCookies []morc.SetCookiesCall
+}
+
+// loaded returns a copy of the in-memory project with all unsaved values and
+// cookies applied to it if filename refers to it. It is safe to call on a nil
+// *replSession.
+func (rs *replSession) loaded(filename string) (morc.Project, bool) {
+	if rs == nil || filename != rs.projFile {
+		return morc.Project{}, false
+	}
+
+	// commands modify the project they are given in place, including ones
+	// that never persist it, so each one gets its own copy
+	p := rs.project.Copy()
+	for env, vars := range rs.unsavedVars {
+		for name, val := range vars {
+			p.Vars.SetIn(name, val, env)
+		}
+	}
+	if len(rs.unsavedCookies) > 0 {
+		p.Session.Cookies = append(p.Session.Cookies, rs.unsavedCookies...)
+		sort.SliceStable(p.Session.Cookies, func(i, j int) bool {
+			return p.Session.Cookies[i].Time.Before(p.Session.Cookies[j].Time)
+		})
+	}
+	return p, true
+}
+
+// remember updates the in-memory project with p if p is the project that rs
+// was opened with, and returns p as it is to be persisted, without any of the
+// unsaved values and cookies applied by loaded. An unsaved value that was since
+// changed in p is no longer unsaved, and the new value is kept. It is safe to
+// call on a nil *replSession, in which case p is returned as-is.
+func (rs *replSession) remember(p morc.Project) morc.Project {
+	if rs == nil || p.Config.ProjFile != rs.projFile {
+		return p
+	}
+
+	p = p.Copy()
+	for env, vars := range rs.unsavedVars {
+		for name, val := range vars {
+			if !p.Vars.IsDefinedIn(name, env) || p.Vars.GetFrom(name, env) != val {
+				delete(vars, name)
+				continue
+			}
+			revertVar(&p.Vars, rs.project.Vars, name, env)
+		}
+		if len(vars) == 0 {
+			delete(rs.unsavedVars, env)
+		}
+	}
+	p.Session.Cookies = withoutSetCookiesCalls(p.Session.Cookies, rs.unsavedCookies)
+
+	rs.project = p
+	return p
+}
+
+// keepUnsavedVar records that the var name was set to value in env without
+// being saved. It is safe to call on a nil *replSession.
+func (rs *replSession) keepUnsavedVar(env, name, value string) {
+	if rs == nil {
+		return
+	}
+
+	env = strings.ToUpper(env)
+	if rs.unsavedVars == nil {
+		rs.unsavedVars = map[string]map[string]string{}
+	}
+	if rs.unsavedVars[env] == nil {
+		rs.unsavedVars[env] = map[string]string{}
+	}
+	rs.unsavedVars[env][strings.ToUpper(name)] = value
+}
+
+// forgetUnsavedVar records that the value of the var name in env is to be
+// saved, such as when it is set explicitly or captured and saved. It is safe to
+// call on a nil *replSession.
+func (rs *replSession) forgetUnsavedVar(env, name string) {
+	if rs == nil {
+		return
+	}
+
+	env = strings.ToUpper(env)
+	delete(rs.unsavedVars[env], strings.ToUpper(name))
+	if len(rs.unsavedVars[env]) == 0 {
+		delete(rs.unsavedVars, env)
+	}
+}
+
+// keepUnsavedCookies records that every call in after that is not in before
+// was made without being recorded to the session. It is safe to call on a nil
+// *replSession.
+func (rs *replSession) keepUnsavedCookies(before, after []morc.SetCookiesCall) {
+	if rs == nil {
+		return
+	}
+	rs.unsavedCookies = append(rs.unsavedCookies, withoutSetCookiesCalls(after, before)...)
+}
+
+// revertVar sets the var name in env of vs back to its value in orig. If it was
+// not defined there in orig, it is removed from it, along with the env itself
+// if that leaves it empty and it is not in orig.
+func revertVar(vs *morc.VarStore, orig morc.VarStore, name, env string) {
+	if orig.IsDefinedIn(name, env) {
+		vs.SetIn(name, orig.GetFrom(name, env), env)
+		return
+	}
+
+	// a var always exists in the default env, so if it did not exist there it
+	// did not exist at all
+	if env == "" || !orig.IsDefinedIn(name, "") {
+		vs.Remove(name)
+	} else {
+		vs.UnsetIn(name, env)
+	}
+
+	if env != "" && len(vs.DefinedIn(env)) == 0 && sliceops.Index(orig.EnvNames(), strings.ToUpper(env)) < 0 {
+		vs.DeleteEnv(env)
+	}
+}
+
+// withoutSetCookiesCalls returns the calls in calls that are not in remove.
+// Calls are matched by when they were made, the URL they were made for, and
+// the names of the cookies they set.
+func withoutSetCookiesCalls(calls, remove []morc.SetCookiesCall) []morc.SetCookiesCall {
+	if len(remove) == 0 {
+		return calls
+	}
+
+	var kept []morc.SetCookiesCall
+	for _, call := range calls {
+		found := false
+		for _, r := range remove {
+			if sameSetCookiesCall(call, r) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			kept = append(kept, call)
+		}
+	}
+	return kept
+}
+
+func sameSetCookiesCall(a, b morc.SetCookiesCall) bool {
+	if !a.Time.Equal(b.Time) || len(a.Cookies) != len(b.Cookies) {
+		return false
+	}
+	if (a.URL == nil) != (b.URL == nil) || (a.URL != nil && a.URL.String() != b.URL.String()) {
+		return false
+	}
+	for i := range a.Cookies {
+		if a.Cookies[i].Name != b.Cookies[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+func invokeRepl(io cmdio.IO, projFile string) error {
+	if activeRepl != nil {
+		return fmt.Errorf("already running in a REPL")
+	}
+
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+	p.Config.ProjFile = projFile
+
+	activeRepl = &replSession{projFile: projFile, project: p}
+	defer func() {
+		activeRepl = nil
+	}()
+
+	io.PrintLoudf("Loaded project %s; enter 'help' for commands or 'exit' to quit\n", projFile)
+
+	lines := bufio.NewScanner(io.In)
+	for {
+		io.Printf(replPrompt)
+		if !lines.Scan() {
+			break
+		}
+
+		cmdArgs, err := splitReplLine(lines.Text())
+		if err != nil {
+			io.PrintErrf("Error: %s\n", err)
+			continue
+		}
+		if len(cmdArgs) == 0 {
+			continue
+		}
+
+		switch strings.ToLower(cmdArgs[0]) {
+		case "exit", "quit":
+			return nil
+		case "help":
+			if len(cmdArgs) == 1 {
+				printReplHelp(io)
+				continue
+			}
+		case "env":
+			if len(cmdArgs) >= 2 && strings.ToLower(cmdArgs[1]) == "use" {
+				if len(cmdArgs) > 3 {
+					io.PrintErrf("Error: unknown positional argument %q\n", cmdArgs[3])
+					continue
+				}
+				if len(cmdArgs) == 2 || cmdArgs[2] == reservedDefaultEnvName {
+					cmdArgs = []string{"env", "--default"}
+				} else {
+					cmdArgs = []string{"env", cmdArgs[2]}
+				}
+			}
+		}
+
+		if err := runReplCommand(cmdArgs, io.Quiet); err != nil {
+			io.PrintErrf("Error: %s\n", err)
+		}
+	}
+
+	if err := lines.Err(); err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+
+	// end of input closes the prompt; put the shell's prompt on its own line
+	io.Println()
+	return nil
+}
+
+// runReplCommand runs a command given to the REPL as though it were given to
+// morc. If quiet is set, the command is run as though it were given -q.
+func runReplCommand(cmdArgs []string, quiet bool) error {
+	// start each command from its defaults so that flags given to one command do
+	// not carry over to the next
+	if cmd, _, err := rootCmd.Find(cmdArgs); err == nil {
+		resetCommandFlags(cmd)
+	}
+	flags.BQuiet = quiet
+
+	rootCmd.SetArgs(cmdArgs)
+	return rootCmd.Execute()
+}
+
+// resetCommandFlags returns every flag that cmd declares to its default value.
+func resetCommandFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			_ = sv.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+
+	// flags declared as persistent are not in Flags() until the command has
+	// parsed its arguments at least once
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	cmd.SilenceUsage = false
+}
+
+func printReplHelp(io cmdio.IO) {
+	var names []string
+	shorts := map[string]string{}
+	width := 0
+	for _, c := range rootCmd.Commands() {
+		if !c.IsAvailableCommand() || c.Name() == "repl" {
+			continue
+		}
+		names = append(names, c.Name())
+		shorts[c.Name()] = c.Short
+		if len(c.Name()) > width {
+			width = len(c.Name())
+		}
+	}
+	sort.Strings(names)
+
+	io.Println("Enter any morc command without the leading 'morc'. Commands:")
+	for _, name := range names {
+		io.Printf("  %-*s  %s\n", width, name, shorts[name])
+	}
+	io.Println()
+	io.Println("REPL commands:")
+	io.Println("  help [COMMAND]  Show this help, or help for COMMAND")
+	io.Println("  env use [ENV]   Switch to environment ENV, or to the default environment")
+	io.Println("  exit, quit      Close the prompt")
+}
+
+// splitReplLine splits a line entered in the REPL into arguments. Whitespace
+// separates arguments unless it is quoted. Single quotes keep their contents as
+// they are, double quotes allow a backslash to escape a double quote or a
+// backslash, and outside of quotes a backslash escapes any character.
+func splitReplLine(line string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, ch := range line {
+		if escaped {
+			if quote == '"' && ch != '"' && ch != '\\' {
+				cur.WriteRune('\\')
+			}
+			cur.WriteRune(ch)
+			escaped = false
+			continue
+		}
+
+		switch {
+		case quote == '\'':
+			if ch == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(ch)
+			}
+		case quote == '"':
+			if ch == '"' {
+				quote = 0
+			} else if ch == '\\' {
+				escaped = true
+			} else {
+				cur.WriteRune(ch)
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+			inArg = true
+		case ch == '\\':
+			escaped = true
+			inArg = true
+		case ch == ' ' || ch == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(ch)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("nothing to escape at end of line")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+
+	return args, nil
+}
+
+type replArgs struct {
+	projFile string
+}
+
+func parseReplArgs(cmd *cobra.Command, _ []string, args *replArgs) error {
+	args.projFile = projPathFromFlagsOrFile(cmd)
+	if args.projFile == "" {
+		return fmt.Errorf("project file cannot be set to empty string")
+	}
+
+	return nil
+}
@@ -0,0 +1,296 @@
+package commands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dekarrin/morc"
+	"github.com/dekarrin/morc/cmd/morc/cmdio"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Repl(t *testing.T) {
+	respFnLogin := func(w http.ResponseWriter, r *http.Request) {
+		// suppress date header
+		w.Header()["Date"] = nil
+
+		if r.URL.Path == "/login" {
+			w.Header().Set("Set-Cookie", "session=413")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(r.Header.Get("Cookie")))
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		input              string
+		p                  morc.Project
+		expectP            morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectProjectSaved bool
+		expectStdoutOutput string // set with expected output to stdout
+		expectStderrOutput string // set with expected output to stderr
+	}{
+		{
+			name:  "exit closes the prompt",
+			args:  []string{"repl"},
+			input: "exit\n",
+			p:     testProject_vars("", map[string]map[string]string{"": {"NAME": "VRISKA"}}),
+			expectStdoutOutput: "Loaded project (in-memory); enter 'help' for commands or 'exit' to quit\n" +
+				"morc> ",
+		},
+		{
+			name:               "end of input closes the prompt",
+			args:               []string{"repl", "-q"},
+			input:              "",
+			p:                  testProject_vars("", map[string]map[string]string{"": {"NAME": "VRISKA"}}),
+			expectStdoutOutput: "morc> \n",
+		},
+		{
+			name:  "vars set in one command are seen by the next",
+			args:  []string{"repl", "-q"},
+			input: "vars NAME 'Vriska Serket'\nvars NAME\nquit\n",
+			p:     testProject_vars("", map[string]map[string]string{"": {"NAME": "VRISKA"}}),
+			expectP: testProject_vars("", map[string]map[string]string{
+				"": {"NAME": "Vriska Serket"},
+			}),
+			expectProjectSaved: true,
+			expectStdoutOutput: "morc> morc> Vriska Serket\n" +
+				"morc> ",
+		},
+		{
+			name:  "flags do not carry over to the next command",
+			args:  []string{"repl", "-q"},
+			input: "vars --delete NAME\nvars\nexit\n",
+			p: testProject_vars("", map[string]map[string]string{
+				"": {"NAME": "VRISKA", "SCHEME": "https"},
+			}),
+			expectP: testProject_vars("", map[string]map[string]string{
+				"": {"SCHEME": "https"},
+			}),
+			expectProjectSaved: true,
+			expectStdoutOutput: "morc> morc> ${SCHEME} = \"https\"\n" +
+				"morc> ",
+		},
+		{
+			name:               "env use switches environments",
+			args:               []string{"repl", "-q"},
+			input:              "env use PROD\nenv\nexit\n",
+			p:                  testProject_vars("", test_3EnvVarsMap),
+			expectP:            testProject_vars("PROD", test_3EnvVarsMap),
+			expectProjectSaved: true,
+			expectStdoutOutput: "morc> morc> PROD\n" +
+				"morc> ",
+		},
+		{
+			name:               "env use with no env switches to the default",
+			args:               []string{"repl", "-q"},
+			input:              "env use\nexit\n",
+			p:                  testProject_vars("PROD", test_3EnvVarsMap),
+			expectP:            testProject_vars("", test_3EnvVarsMap),
+			expectProjectSaved: true,
+			expectStdoutOutput: "morc> morc> ",
+		},
+		{
+			name:               "loud output is shown without -q",
+			args:               []string{"repl"},
+			input:              "env use PROD\n",
+			p:                  testProject_vars("", test_3EnvVarsMap),
+			expectP:            testProject_vars("PROD", test_3EnvVarsMap),
+			expectProjectSaved: true,
+			expectStdoutOutput: "Loaded project (in-memory); enter 'help' for commands or 'exit' to quit\n" +
+				"morc> Switched to environment \"PROD\"\n" +
+				"morc> \n",
+		},
+		{
+			name:  "errors do not close the prompt",
+			args:  []string{"repl", "-q"},
+			input: "reqs nope\nvars 'NAME\nrepl\nvars NAME\nexit\n",
+			p:     testProject_vars("", map[string]map[string]string{"": {"NAME": "VRISKA"}}),
+			expectStdoutOutput: "morc> morc> morc> morc> VRISKA\n" +
+				"morc> ",
+			expectStderrOutput: "Error: no request named nope exists in project\n" +
+				"Error: unterminated ' quote\n" +
+				"Error: already running in a REPL\n",
+		},
+		{
+			name:  "cookies are kept between sends",
+			args:  []string{"repl", "-q"},
+			input: "send login\nsend echo\nexit\n",
+			p: testProject_withRequests(
+				morc.RequestTemplate{Name: "login", Method: "POST", URL: "$TESTSERVER_URL$/login"},
+				morc.RequestTemplate{Name: "echo", Method: "GET", URL: "$TESTSERVER_URL$/echo"},
+			),
+			// send in quiet mode outputs only the response body
+			expectStdoutOutput: "morc> morc> session=413morc> ",
+		},
+		{
+			name:  "values captured without saving are not persisted by later commands",
+			args:  []string{"repl", "-q"},
+			input: "send login\nsend echo --no-save-captures\nvars SESSION\nvars NAME Terezi\nexit\n",
+			p: func() morc.Project {
+				p := testProject_withRequests(
+					morc.RequestTemplate{Name: "login", Method: "POST", URL: "$TESTSERVER_URL$/login"},
+					morc.RequestTemplate{Name: "echo", Method: "GET", URL: "$TESTSERVER_URL$/echo", Captures: map[string]morc.VarScraper{
+						"SESSION": {Name: "SESSION", OffsetStart: 8, OffsetEnd: 11},
+					}},
+				)
+				p.Vars = testVarStore("", map[string]map[string]string{"": {"NAME": "VRISKA"}})
+				return p
+			}(),
+			expectP: func() morc.Project {
+				p := testProject_withRequests(
+					morc.RequestTemplate{Name: "login", Method: "POST", URL: "$TESTSERVER_URL$/login"},
+					morc.RequestTemplate{Name: "echo", Method: "GET", URL: "$TESTSERVER_URL$/echo", Captures: map[string]morc.VarScraper{
+						"SESSION": {Name: "SESSION", OffsetStart: 8, OffsetEnd: 11},
+					}},
+				)
+				p.Vars = testVarStore("", map[string]map[string]string{"": {"NAME": "Terezi"}})
+				return p
+			}(),
+			expectProjectSaved: true,
+			expectStdoutOutput: "morc> morc> session=413morc> 413\n" +
+				"morc> morc> ",
+		},
+		{
+			name:  "dry run rename does not change the in-memory project",
+			args:  []string{"repl", "-q"},
+			input: "vars --rename NAME WHO --dry-run\nvars NAME\nexit\n",
+			p:     testProject_vars("", map[string]map[string]string{"": {"NAME": "VRISKA"}}),
+			expectStdoutOutput: "morc> Would rename ${NAME} to ${WHO}\n" +
+				"morc> VRISKA\n" +
+				"morc> ",
+		},
+		{
+			name:      "project does not exist",
+			args:      []string{"repl"},
+			expectErr: "project",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// setup test server
+			srv := httptest.NewServer(http.HandlerFunc(respFnLogin))
+			defer srv.Close()
+			srvClient := srv.Client()
+
+			// inject a custom transport so we always append the server root URL
+			srvClient.Transport = urlBaseRoundTripper{
+				base: srv.URL,
+				old:  srvClient.Transport,
+			}
+			cmdio.HTTPClient = srvClient
+
+			// cookies are only sent back to the host that set them, so
+			// templates that rely on them give the URL of the test server
+			for _, p := range []morc.Project{tc.p, tc.expectP} {
+				for name, tmpl := range p.Templates {
+					tmpl.URL = strings.ReplaceAll(tmpl.URL, "$TESTSERVER_URL$", srv.URL)
+					p.Templates[name] = tmpl
+				}
+			}
+
+			resetReplFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := "(in-memory)"
+			if tc.expectErr == "" {
+				projFilePath = createTestProjectIO(t, tc.p)
+			} else {
+				projReader = nil
+				projWriter = nil
+			}
+
+			rootCmd.SetIn(strings.NewReader(tc.input))
+			defer rootCmd.SetIn(nil)
+
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(replCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			if tc.expectProjectSaved {
+				assert_projectPersistedToBuffer(assert, tc.expectP)
+			} else {
+				assert_noProjectFileMutations(assert)
+			}
+		})
+	}
+}
+
+func Test_splitReplLine(t *testing.T) {
+	testCases := []struct {
+		name      string
+		line      string
+		expect    []string
+		expectErr string
+	}{
+		{name: "empty", line: "", expect: nil},
+		{name: "only whitespace", line: " \t ", expect: nil},
+		{name: "single word", line: "vars", expect: []string{"vars"}},
+		{name: "extra whitespace", line: "  vars \t NAME  ", expect: []string{"vars", "NAME"}},
+		{name: "single quotes", line: `vars NAME 'Vriska  Serket'`, expect: []string{"vars", "NAME", "Vriska  Serket"}},
+		{name: "single quotes keep backslashes", line: `reqs r -d '{"a": "\n"}'`, expect: []string{"reqs", "r", "-d", `{"a": "\n"}`}},
+		{name: "double quotes", line: `vars NAME "Vriska Serket"`, expect: []string{"vars", "NAME", "Vriska Serket"}},
+		{name: "double quotes with escapes", line: `reqs r -d "{\"a\": \"\\\n\"}"`, expect: []string{"reqs", "r", "-d", `{"a": "\\n"}`}},
+		{name: "escaped space outside quotes", line: `vars NAME Vriska\ Serket`, expect: []string{"vars", "NAME", "Vriska Serket"}},
+		{name: "quotes joined to word", line: `vars NAME=' x'`, expect: []string{"vars", "NAME= x"}},
+		{name: "empty quotes", line: `vars NAME ''`, expect: []string{"vars", "NAME", ""}},
+		{name: "unterminated single quote", line: `vars 'NAME`, expectErr: "unterminated ' quote"},
+		{name: "unterminated double quote", line: `vars "NAME`, expectErr: "unterminated \" quote"},
+		{name: "trailing backslash", line: `vars NAME\`, expectErr: "nothing to escape at end of line"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := splitReplLine(tc.line)
+			if tc.expectErr != "" {
+				assert.EqualError(err, tc.expectErr)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func resetReplFlags() {
+	flags.ProjectFile = ""
+	flags.BQuiet = false
+
+	replCmd.Flags().VisitAll(func(fl *pflag.Flag) {
+		fl.Changed = false
+	})
+}
@@ -1,15 +1,19 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"reflect"
 	"sort"
 	"strings"
 
 	"github.com/dekarrin/morc"
 	"github.com/dekarrin/morc/cmd/morc/cmdio"
+	"github.com/dekarrin/morc/internal/sliceops"
 	"github.com/spf13/cobra"
 )
 
@@ -17,37 +21,108 @@ var reqsCmd = &cobra.Command{
 	Use: "reqs [REQ]",
 	Annotations: map[string]string{
 		annotationKeyHelpUsages: "" +
-			"reqs\n" +
+			"reqs [--tag TAG] [--output FMT]\n" +
 			"reqs --delete REQ [-f]\n" +
-			"reqs --new REQ [-d DATA | -d @FILE] [-XuH]...\n" +
-			"reqs REQ\n" +
+			"reqs --copy SRC DEST\n" +
+			"reqs --new REQ [-d DATA | -d @FILE | --body-file FILE] [--data-urlencode FIELD]... [--auth-flow FLOW] [--desc DESC] [--add-tag TAG]... [-XuH]...\n" +
+			"reqs --new REQ --from-history ENTRY [-d DATA | -d @FILE] [--auth-flow FLOW] [--desc DESC] [--add-tag TAG]... [-XuH]...\n" +
+			"reqs REQ [--output FMT]\n" +
 			"reqs REQ --get ATTR\n" +
-			"reqs REQ [-ndXuHrR]...",
+			"reqs REQ --get headers [--sorted alpha|received]\n" +
+			"reqs REQ --export-http FILE [--editor-vars]\n" +
+			"reqs REQ --resolve-vars\n" +
+			"reqs --import-http FILE\n" +
+			"reqs REQ [-ndXuHrR]... [--body-file FILE] [--data-urlencode FIELD]... [--auth-flow FLOW] [--desc DESC] [--add-tag TAG]... [--remove-tag TAG]...\n" +
+			"reqs REQ --wrap-body KEY [-XuHr]...\n" +
+			"reqs REQ --env ENV [-dHrR]...",
 	},
 	GroupID: "project",
 	Short:   "Show or modify request templates",
 	Long: "Manipulate project request templates. By itself, prints out a listing of the names and methods of the " +
-		"request templates in the project.\n\n" +
+		"request templates in the project. The listing can be output as JSON for use by other tools by giving " +
+		"--output json.\n\n" +
 		"A new request template can be created by providing the name of it to the --new flag and using flags to " +
 		"specify attributes to set on the new request. The method of the request is set with the --method/-X flag. " +
 		"The payload in the request body is set with the -d/--data flag, either directly by providing the body as the " +
-		"argument or indirectly by loading from a filename given after a leading '@'. Headers are set with the " +
+		"argument or indirectly by loading from a filename given after a leading '@'. A URL-encoded form body can be " +
+		"built up with --data-urlencode, which works the same as it does in curl: each FIELD is given as 'content', " +
+		"'=content', 'name=content', '@filename', or 'name@filename', its content is URL-encoded, and all of them are " +
+		"joined with '&' to each other and to any body given with -d. If the request does not have a Content-Type " +
+		"header, it is set to " + morc.FormContentType + ". The fields are stored unencoded and replace any that the " +
+		"request already has; they are encoded each time the request is sent, after any variables in them are filled " +
+		"in. Setting a body with -d or --body-file without --data-urlencode removes the fields. " +
+		"To instead have the body read from a file each time the request is sent, give the path to it with " +
+		"--body-file; any edits to the file are then used by the next send without needing to update the request " +
+		"template, and vars in it are filled in the same as for any other body. A relative path is resolved against " +
+		"the directory morc is run from when the request is sent. Setting a body file removes any body set with -d, " +
+		"and setting a body with -d removes the body file. " +
+		"Headers are set with the " +
 		"-H/--header flag. Multiple headers may be specified by providing multiple -H flags. The URL of the request " +
 		"is set with the the -u/--url flag.\n\n" +
+		"To save a request that was sent before as a new request template, give --from-history with the index of its " +
+		"entry in the history, as shown by morc hist, along with --new. The method, URL, headers, and body of the new " +
+		"request template are taken from the request that was recorded in the entry, with any vars in them already " +
+		"filled in as they were when it was sent. Any other flags given set attributes of the new template the same " +
+		"as they do without --from-history, except that headers given with -H are added to the recorded ones. If the " +
+		"values of secret vars were redacted from the recorded request, they are left as " + morc.SecretMask + " in " +
+		"the new template and a warning is printed.\n\n" +
 		"A particular request can be viewed by providing the name of the request, REQ, as a positional argument to " +
-		"the flows command. This will show all details of a request template. To see only a specific attribute of a " +
+		"the flows command. This will show all details of a request template; give --output json to print them " +
+		"as JSON instead. To see only a specific attribute of a " +
 		"request, provide --get along with the name of the attribute of the request to show. The attribute, ATTR, " +
 		"must be one of the following: " + strings.Join(reqAttrKeyNames(), ", ") + ". If 'HEADERS' is selected, all " +
-		"headers on the request are printed. To see the value(s) of only a particular header, use --get-header with " +
+		"headers on the request are printed in alphabetical order by key; give --sorted received to instead print " +
+		"them in the order their keys were first added with -H, which can matter when header order is significant, " +
+		"such as for request signing. To see the value(s) of only a particular header, use --get-header with " +
 		"the name of the header to see instead.\n\n" +
+		"To check the values that vars in a request will be filled in with without sending it, give REQ along with " +
+		"--resolve-vars. The method, URL, headers, and body of the request are printed with every var substituted " +
+		"using the current environment, the same as they would be at send time. Vars captured by the auth flow of " +
+		"the request are left as they are, since their values are not known until the flow is run.\n\n" +
 		"Modifications to existing request templates are performed by giving REQ as a positional argument followed by " +
 		"one or more flag that sets a property of the request. For example, to change the method of a request, " +
 		"provide the -X flag followed by the new method. All flags that are supported during request creation are " +
 		"also supported when modifying a request (-X, -d, -u, -H), in addition to a few others. The name of the " +
 		"request is updated with -n/--name. Since -H only *adds* new header values, --remove-header/-r can be used to " +
 		"remove an existing header from the request. If it is a multi-valued header, only the last value added is " +
-		"removed. Finally, calling --remove-body/-R will remove the body payload entirely, which may differ from " +
-		"simply setting it to the empty string.\n\n" +
+		"removed. Finally, calling --remove-body/-R will remove the body payload entirely, including any body file, " +
+		"which may differ from simply setting it to the empty string.\n\n" +
+		"To wrap the existing body of a request in a JSON object, give --wrap-body with the key to put it under. For " +
+		"example, --wrap-body data turns a body of '{\"id\": 1}' into '{\"data\": {\"id\": 1}}'. If the existing body " +
+		"is not valid JSON, it is put under the key as a JSON string instead; note that this includes bodies with " +
+		"variables that are not inside of quotes. The wrapped body is indented if the existing body has more than " +
+		"one line.\n\n" +
+		"A flow can be set to run before a request is sent by giving its name with --auth-flow. This is useful for " +
+		"requests that need a token that expires; any vars the flow captures are used when filling in the request. " +
+		"Give --auth-flow an empty string to clear it. See the send command for details.\n\n" +
+		"A description of what a request does, or anything to watch out for when using it, can be set with --desc. " +
+		"It is shown when the request is viewed and next to its name in the listing of requests, and is never sent. " +
+		"Give --desc an empty string to clear it.\n\n" +
+		"Requests can be grouped by giving them tags. A tag is added with --add-tag and removed with --remove-tag, " +
+		"and both can be given multiple times. Tags are not case-sensitive and cannot contain whitespace or commas. " +
+		"To list only the requests that have a particular tag, give --tag with the tag when listing requests.\n\n" +
+		"A request can be exported to a .http file for use in editors such as the VS Code REST Client or the " +
+		"JetBrains HTTP Client by giving REQ along with --export-http and the FILE to write. The request line, each " +
+		"header value on its own line, and the body are written as-is, so var references keep the project's syntax; " +
+		"give --editor-vars to convert them to the {{NAME}} syntax used by the editors instead. Var references that " +
+		"use transforms or dynamic vars have no equivalent in the editors and are always left as-is.\n\n" +
+		"Going the other way, --import-http reads a .http FILE and creates a request template for each request in " +
+		"it, where requests are separated by lines beginning with '###'. Each template is named from a '# @name NAME' " +
+		"comment in its request, or is named request-N after its position in the file if there is none. Editor vars " +
+		"in the {{NAME}} syntax are converted to var references. Requests that cannot be parsed or whose name is " +
+		"already used by a request template are skipped with a warning.\n\n" +
+		"Vars already allow values in a request to differ between environments, but sometimes the request itself " +
+		"needs to be different, such as by having an extra header only in production. Giving --env with the name " +
+		"of an environment along with -H, -r, -d, or -R changes the override of the request in ENV instead of " +
+		"the request itself. When the request is sent while ENV is the current environment, each header in its " +
+		"override replaces all values of that header in the request, and a body in its override replaces the body " +
+		"of the request, including any body file. -r removes every value of the header from the override, and -R " +
+		"removes the override's body so that the request's own body is used. Overrides are shown after the rest of " +
+		"the request.\n\n" +
+		"To make a variant of an existing request template, give --copy with the name of the template to copy, SRC, " +
+		"and the name of the new template, DEST, as a positional argument. Everything in SRC, including its headers, " +
+		"body, and captures, is copied to DEST, and the two can then be modified separately. DEST must not already " +
+		"exist.\n\n" +
 		"Requests are deleted by passing the --delete flag with a request name as its argument. This will " +
 		"irreversibly remove the request from the project entirely.",
 	Args: cobra.MaximumNArgs(1),
@@ -64,17 +139,28 @@ var reqsCmd = &cobra.Command{
 
 		switch args.action {
 		case reqsActionList:
-			return invokeReqsList(io, args.projFile)
+			return invokeReqsList(io, args.projFile, args.listFormat, args.tag)
 		case reqsActionShow:
-			return invokeReqsShow(io, args.projFile, args.req)
+			return invokeReqsShow(io, args.projFile, args.req, args.listFormat)
 		case reqsActionDelete:
 			return invokeReqsDelete(io, args.projFile, args.req, args.force)
+		case reqsActionCopy:
+			return invokeReqsCopy(io, args.projFile, args.req, args.copyDest)
 		case reqsActionGet:
-			return invokeReqsGet(io, args.projFile, args.req, args.getItem)
+			return invokeReqsGet(io, args.projFile, args.req, args.getItem, args.headerOrder)
 		case reqsActionNew:
-			return invokeReqsNew(io, args.projFile, args.req, args.sets)
+			return invokeReqsNew(io, args.projFile, args.req, args.sets, args.fromHistory)
 		case reqsActionEdit:
+			if args.env != "" {
+				return invokeReqsEditEnv(io, args.projFile, args.req, args.env, args.sets)
+			}
 			return invokeReqsEdit(io, args.projFile, args.req, args.sets)
+		case reqsActionExportHTTP:
+			return invokeReqsExportHTTP(io, args.projFile, args.req, args.exportFile, args.editorVars)
+		case reqsActionImportHTTP:
+			return invokeReqsImportHTTP(io, args.projFile, args.importFile)
+		case reqsActionResolveVars:
+			return invokeReqsResolveVars(io, args.projFile, args.req)
 		default:
 			panic(fmt.Sprintf("unhandled reqs action %q", args.action))
 		}
@@ -89,22 +175,52 @@ func init() {
 	reqsCmd.PersistentFlags().StringVarP(&flags.GetHeader, "get-header", "", "", "Get the value(s) of the given header `KEY` that is currently set on the request.")
 	reqsCmd.PersistentFlags().StringVarP(&flags.Name, "name", "n", "", "Change the name of a request template to `NAME`.")
 	reqsCmd.PersistentFlags().StringArrayVarP(&flags.RemoveHeaders, "remove-header", "r", []string{}, "Remove header with key `KEY` from the request. If multiple headers with the same key exist, only the most recently added one will be deleted.")
-	reqsCmd.PersistentFlags().StringVarP(&flags.BodyData, "data", "d", "", "Add the given `DATA` as a body to the request; prefix with '@' to instead interperet DATA as a filename that body data is to be read from.")
+	reqsCmd.PersistentFlags().StringVarP(&flags.BodyData, "data", "d", "", "Add the given `DATA` as a body to the request; prefix with '@' to instead interperet DATA as a filename that body data is to be read from. If the special string '"+morc.ProjDirVar+"' is in the filename, it is replaced with the directory containing the project file.")
+	reqsCmd.PersistentFlags().StringVarP(&flags.BodyFile, "body-file", "", "", "Read the body of the request from `FILE` each time it is sent instead of storing it in the request template. If the special string '"+morc.ProjDirVar+"' is in the path, it is replaced with the directory containing the project file. Give an empty string to clear it.")
+	reqsCmd.PersistentFlags().StringArrayVarP(&flags.DataURLEncode, "data-urlencode", "", []string{}, "URL-encode `FIELD` and add it to the body of the request, joined to any other body data with '&', the same as curl. FIELD may be 'content', '=content', 'name=content', '@filename', or 'name@filename'; only the content is encoded, after variables in it are substituted at send time. Sets the Content-Type header to "+morc.FormContentType+" if it is not otherwise set. May be set multiple times.")
 	reqsCmd.PersistentFlags().StringArrayVarP(&flags.Headers, "header", "H", []string{}, "Add a header to the request. Format is `KEY:VALUE`. Multiple headers may be set by providing multiple -H flags. If multiple headers with the same key are set, they will be set in the order they were given.")
 	reqsCmd.PersistentFlags().StringVarP(&flags.Method, "method", "X", "GET", "Set the request method to `METHOD`.")
 	reqsCmd.PersistentFlags().StringVarP(&flags.URL, "url", "u", "http://example.com", "Specify the `URL` for the request.")
+	reqsCmd.PersistentFlags().StringVarP(&flags.WrapBody, "wrap-body", "", "", "Wrap the existing body of the request in a JSON object under the key `KEY`. If the body is not valid JSON, it is wrapped as a string.")
+	reqsCmd.PersistentFlags().StringVarP(&flags.AuthFlow, "auth-flow", "", "", "Run the flow `FLOW` before sending the request, so that vars it captures can be used in the request. Give an empty string to clear it.")
+	reqsCmd.PersistentFlags().StringVarP(&flags.Description, "desc", "", "", "Set the description of the request to `DESC`. Give an empty string to clear it.")
+	reqsCmd.PersistentFlags().StringArrayVarP(&flags.AddTags, "add-tag", "", []string{}, "Add the tag `TAG` to the request. May be set multiple times.")
+	reqsCmd.PersistentFlags().StringArrayVarP(&flags.RemoveTags, "remove-tag", "", []string{}, "Remove the tag `TAG` from the request. May be set multiple times.")
+	reqsCmd.PersistentFlags().StringVarP(&flags.Tag, "tag", "", "", "Only list the request templates that have the tag `TAG`.")
 	reqsCmd.PersistentFlags().BoolVarP(&flags.BRemoveBody, "remove-body", "R", false, "Delete all existing body data from the request")
 	reqsCmd.PersistentFlags().BoolVarP(&flags.BForce, "force", "f", false, "Force deletion of the request template even if it is used in flows. Only valid with --delete/-D.")
+	reqsCmd.PersistentFlags().StringVarP(&flags.HeaderOrder, "sorted", "", "alpha", "Output headers in `ORDER` when used with --get headers. ORDER must be one of 'alpha' for alphabetical order by key or 'received' for the order the keys were first added to the request with -H.")
+	reqsCmd.PersistentFlags().StringVarP(&flags.ExportHTTP, "export-http", "", "", "Write the request template to `FILE` in the .http format used by editors.")
+	reqsCmd.PersistentFlags().StringVarP(&flags.ImportHTTP, "import-http", "", "", "Create a request template for each request in the .http file `FILE`.")
+	reqsCmd.PersistentFlags().StringVarP(&flags.Copy, "copy", "", "", "Copy the request template named `SRC` to a new request template named by the positional argument.")
+	reqsCmd.PersistentFlags().IntVarP(&flags.FromHistory, "from-history", "", -1, "Create the new request template from the request recorded in history entry `ENTRY`. Only valid with --new/-N.")
+	reqsCmd.PersistentFlags().BoolVarP(&flags.BEditorVars, "editor-vars", "", false, "Convert var references to the {{NAME}} syntax of editors when exporting with --export-http.")
+	reqsCmd.PersistentFlags().StringVarP(&flags.Env, "env", "e", "", "Apply header and body modifications to the override of the request used in environment `ENV` instead of to the request itself.")
+	reqsCmd.PersistentFlags().BoolVarP(&flags.BResolveVars, "resolve-vars", "", false, "Print the request with all vars filled in from the current environment instead of showing the template.")
 	reqsCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
 
+	addListOutputFlag(reqsCmd)
+
 	reqsCmd.MarkFlagsMutuallyExclusive("new", "delete", "get", "get-header", "name")
 	reqsCmd.MarkFlagsMutuallyExclusive("new", "delete", "get", "get-header", "remove-header")
 	reqsCmd.MarkFlagsMutuallyExclusive("new", "delete", "get", "get-header", "remove-body")
 	reqsCmd.MarkFlagsMutuallyExclusive("delete", "get", "get-header", "data")
+	reqsCmd.MarkFlagsMutuallyExclusive("delete", "get", "get-header", "data-urlencode")
+	reqsCmd.MarkFlagsMutuallyExclusive("delete", "get", "get-header", "body-file")
 	reqsCmd.MarkFlagsMutuallyExclusive("delete", "get", "get-header", "method")
 	reqsCmd.MarkFlagsMutuallyExclusive("delete", "get", "get-header", "header")
 	reqsCmd.MarkFlagsMutuallyExclusive("delete", "get", "get-header", "url")
-	reqsCmd.MarkFlagsMutuallyExclusive("data", "remove-body")
+	reqsCmd.MarkFlagsMutuallyExclusive("delete", "get", "get-header", "auth-flow")
+	reqsCmd.MarkFlagsMutuallyExclusive("delete", "get", "get-header", "desc")
+	reqsCmd.MarkFlagsMutuallyExclusive("delete", "get", "get-header", "add-tag")
+	reqsCmd.MarkFlagsMutuallyExclusive("new", "delete", "get", "get-header", "remove-tag")
+	reqsCmd.MarkFlagsMutuallyExclusive("new", "delete", "get", "get-header", "wrap-body")
+	reqsCmd.MarkFlagsMutuallyExclusive("new", "delete", "get", "get-header", "export-http", "import-http", "resolve-vars", "copy")
+	reqsCmd.MarkFlagsMutuallyExclusive("data", "remove-body", "wrap-body")
+	reqsCmd.MarkFlagsMutuallyExclusive("body-file", "data", "remove-body", "wrap-body")
+	reqsCmd.MarkFlagsMutuallyExclusive("body-file", "data-urlencode")
+	reqsCmd.MarkFlagsMutuallyExclusive("data-urlencode", "wrap-body")
+	reqsCmd.MarkFlagsMutuallyExclusive("data-urlencode", "remove-body")
 	reqsCmd.MarkFlagsMutuallyExclusive("new", "get", "get-header", "force")
 
 	rootCmd.AddCommand(reqsCmd)
@@ -128,6 +244,8 @@ func invokeReqsEdit(io cmdio.IO, projFile, reqName string, attrs reqAttrValues)
 		return morc.NewReqNotFoundError(reqLower)
 	}
 
+	attrs.addFormContentType(req.Headers)
+
 	modifiedVals := map[reqKey]interface{}{}
 	noChangeVals := map[reqKey]interface{}{}
 
@@ -173,8 +291,16 @@ func invokeReqsEdit(io cmdio.IO, projFile, reqName string, attrs reqAttrValues)
 	// any name changes will have gone to req.Name at this point; be shore to
 	// use that for any name displaying from this point forward
 
-	// body modifications
-	if attrs.body.set {
+	// body modifications; form fields replace the body the same as -d does,
+	// and setting either replaces any existing form fields
+	if attrs.body.set || len(attrs.form) > 0 {
+		hadBodyFile := req.BodyFile != ""
+		hadForm := len(req.Form) > 0
+		if hadBodyFile {
+			req.BodyFile = ""
+			modifiedVals[reqKeyBodyFile] = "(none)"
+		}
+
 		if !(attrs.body.v == nil && req.Body == nil) {
 			req.Body = attrs.body.v
 
@@ -183,9 +309,56 @@ func invokeReqsEdit(io cmdio.IO, projFile, reqName string, attrs reqAttrValues)
 			} else {
 				modifiedVals[reqKeyData] = "data with length " + fmt.Sprint(len(req.Body))
 			}
-		} else {
+		} else if !hadBodyFile && !hadForm && attrs.body.set {
 			noChangeVals[reqKeyData] = "(none)"
 		}
+
+		if !reflect.DeepEqual(req.Form, attrs.form) {
+			req.Form = attrs.form
+
+			if len(req.Form) == 0 {
+				modifiedVals[reqKeyForm] = "(none)"
+			} else {
+				modifiedVals[reqKeyForm] = io.CountOf(len(req.Form), "field")
+			}
+		}
+	}
+
+	if attrs.bodyFile.set {
+		if req.BodyFile != attrs.bodyFile.v {
+			req.BodyFile = attrs.bodyFile.v
+			modifiedVals[reqKeyBodyFile] = orNone(attrs.bodyFile.v)
+		} else {
+			noChangeVals[reqKeyBodyFile] = orNone(attrs.bodyFile.v)
+		}
+
+		if attrs.bodyFile.v != "" && req.Body != nil {
+			req.Body = nil
+			modifiedVals[reqKeyData] = "(none)"
+		}
+		if attrs.bodyFile.v != "" && req.Form != nil {
+			req.Form = nil
+			modifiedVals[reqKeyForm] = "(none)"
+		}
+	}
+
+	if attrs.wrapBodyKey.set {
+		if req.BodyFile != "" {
+			return fmt.Errorf("request template %s reads its body from a file; it cannot be wrapped", req.Name)
+		}
+		if len(req.Form) > 0 {
+			return fmt.Errorf("request template %s has form fields in its body; it cannot be wrapped", req.Name)
+		}
+		if req.Body == nil {
+			return fmt.Errorf("request template %s has no body to wrap", req.Name)
+		}
+
+		wrapped, err := wrapBody(req.Body, attrs.wrapBodyKey.v)
+		if err != nil {
+			return err
+		}
+		req.Body = wrapped
+		modifiedVals[reqKeyData] = fmt.Sprintf("data with length %d wrapped in %q", len(req.Body), attrs.wrapBodyKey.v)
 	}
 
 	// header removals
@@ -204,6 +377,7 @@ func invokeReqsEdit(io cmdio.IO, projFile, reqName string, attrs reqAttrValues)
 				} else {
 					// delete the most recently added header with this key.
 					req.Headers.Del(key)
+					canonKey := http.CanonicalHeaderKey(key)
 
 					oldVal := vals[len(vals)-1]
 					// if there's more than one value, put the other ones back to honor
@@ -215,6 +389,9 @@ func invokeReqsEdit(io cmdio.IO, projFile, reqName string, attrs reqAttrValues)
 						}
 					} else {
 						modifiedVals[modKey] = "no longer exist"
+						req.HeaderOrder = sliceops.Filter(req.HeaderOrder, func(k string) bool {
+							return k != canonKey
+						})
 					}
 				}
 			}
@@ -228,6 +405,13 @@ func invokeReqsEdit(io cmdio.IO, projFile, reqName string, attrs reqAttrValues)
 			req.Headers = make(http.Header)
 		}
 
+		// record the order of any keys not already on the request
+		for _, key := range attrs.headerOrder {
+			if len(req.Headers.Values(key)) == 0 {
+				req.HeaderOrder = append(req.HeaderOrder, key)
+			}
+		}
+
 		// to make reproducible, sort the header keys first
 		sortedKeys := make([]string, 0, len(attrs.headers.v))
 		for key := range attrs.headers.v {
@@ -266,6 +450,40 @@ func invokeReqsEdit(io cmdio.IO, projFile, reqName string, attrs reqAttrValues)
 		}
 	}
 
+	if attrs.authFlow.set {
+		if err := checkAuthFlowExists(p, attrs.authFlow.v); err != nil {
+			return err
+		}
+
+		if req.AuthFlow != attrs.authFlow.v {
+			req.AuthFlow = attrs.authFlow.v
+			modifiedVals[reqKeyAuthFlow] = orNone(attrs.authFlow.v)
+		} else {
+			noChangeVals[reqKeyAuthFlow] = orNone(attrs.authFlow.v)
+		}
+	}
+
+	if attrs.description.set {
+		if req.Description != attrs.description.v {
+			req.Description = attrs.description.v
+			modifiedVals[reqKeyDescription] = orNone(attrs.description.v)
+		} else {
+			noChangeVals[reqKeyDescription] = orNone(attrs.description.v)
+		}
+	}
+
+	if attrs.addTags.set || attrs.removeTags.set {
+		oldTags := strings.Join(req.Tags, ", ")
+		req.Tags = updateTags(req.Tags, attrs.addTags.v, attrs.removeTags.v)
+		newTags := strings.Join(req.Tags, ", ")
+
+		if newTags != oldTags {
+			modifiedVals[reqKeyTags] = orNone(newTags)
+		} else {
+			noChangeVals[reqKeyTags] = orNone(newTags)
+		}
+	}
+
 	p.Templates[strings.ToLower(req.Name)] = req
 
 	// save the project file
@@ -280,7 +498,113 @@ func invokeReqsEdit(io cmdio.IO, projFile, reqName string, attrs reqAttrValues)
 	return nil
 }
 
-func invokeReqsNew(io cmdio.IO, projFile, reqName string, attrs reqAttrValues) error {
+// invokeReqsEditEnv applies the header and body modifications in attrs to the
+// override of the request template that is used when env is the current
+// environment, rather than to the template itself. A header removal removes
+// every value of that key from the override. If nothing is left in the
+// override afterwards, it is removed.
+func invokeReqsEditEnv(io cmdio.IO, projFile, reqName, env string, attrs reqAttrValues) error {
+	p, err := readProject(projFile, false)
+	if err != nil {
+		return err
+	}
+
+	// case doesn't matter for request template names or env names
+	reqLower := strings.ToLower(reqName)
+	req, ok := p.Templates[reqLower]
+	if !ok {
+		return morc.NewReqNotFoundError(reqLower)
+	}
+	envUpper := strings.ToUpper(env)
+
+	override := req.EnvOverrides[envUpper].Copy()
+
+	modifiedVals := map[reqKey]interface{}{}
+	noChangeVals := map[reqKey]interface{}{}
+	var attrOrdering []reqKey
+
+	if attrs.body.set {
+		dataKey := reqKey{name: reqKeyData.name, env: envUpper}
+		attrOrdering = append(attrOrdering, dataKey)
+
+		if attrs.body.v == nil && override.Body == nil {
+			noChangeVals[dataKey] = "(none)"
+		} else {
+			override.Body = attrs.body.v
+			if override.Body == nil {
+				modifiedVals[dataKey] = "(none)"
+			} else {
+				modifiedVals[dataKey] = "data with length " + fmt.Sprint(len(override.Body))
+			}
+		}
+	}
+
+	if attrs.removeHeaders.set {
+		for _, key := range attrs.removeHeaders.v {
+			modKey := reqKey{header: key, uniqueInt: len(attrOrdering), env: envUpper}
+			attrOrdering = append(attrOrdering, modKey)
+
+			if len(override.Headers.Values(key)) < 1 {
+				noChangeVals[modKey] = "not exist"
+			} else {
+				override.Headers.Del(key)
+				modifiedVals[modKey] = "no longer exist"
+			}
+		}
+	}
+
+	if attrs.headers.set {
+		if override.Headers == nil {
+			override.Headers = make(http.Header)
+		}
+
+		// to make reproducible, sort the header keys first
+		sortedKeys := make([]string, 0, len(attrs.headers.v))
+		for key := range attrs.headers.v {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+
+		for _, key := range sortedKeys {
+			for _, v := range attrs.headers.v[key] {
+				modKey := reqKey{header: key, uniqueInt: len(attrOrdering), env: envUpper}
+				attrOrdering = append(attrOrdering, modKey)
+
+				modifiedVals[modKey] = fmt.Sprintf("have new value %s", v)
+				override.Headers.Add(key, v)
+			}
+		}
+	}
+
+	if len(override.Headers) == 0 {
+		override.Headers = nil
+	}
+
+	if override.Empty() {
+		delete(req.EnvOverrides, envUpper)
+		if len(req.EnvOverrides) == 0 {
+			req.EnvOverrides = nil
+		}
+	} else {
+		if req.EnvOverrides == nil {
+			req.EnvOverrides = make(map[string]morc.TemplateOverride)
+		}
+		req.EnvOverrides[envUpper] = override
+	}
+
+	p.Templates[reqLower] = req
+
+	err = writeProject(p, false)
+	if err != nil {
+		return err
+	}
+
+	cmdio.OutputLoudEditAttrsResult(io, modifiedVals, noChangeVals, attrOrdering)
+
+	return nil
+}
+
+func invokeReqsNew(io cmdio.IO, projFile, reqName string, attrs reqAttrValues, fromHistory int) error {
 	// load the project file
 	p, err := readProject(projFile, true)
 	if err != nil {
@@ -294,18 +618,70 @@ func invokeReqsNew(io cmdio.IO, projFile, reqName string, attrs reqAttrValues) e
 		return morc.NewReqExistsError(reqLower)
 	}
 
+	base := morc.RequestTemplate{
+		Method: "GET",
+		URL:    "http://example.com",
+	}
+	if fromHistory >= 0 {
+		if fromHistory >= len(p.History) {
+			return fmt.Errorf("can't get history entry %d; %d is the highest entry available", fromHistory, len(p.History)-1)
+		}
+		base, err = replayTemplate(p.History[fromHistory])
+		if err != nil {
+			return fmt.Errorf("history entry %d: %w", fromHistory, err)
+		}
+		if p.History[fromHistory].Redacted {
+			io.PrintErrf("WARN: secret values in history entry %d were redacted; replace each %s in the new request with the var it was\n", fromHistory, morc.SecretMask)
+		}
+	}
+
+	attrs.addFormContentType(base.Headers)
+
+	if err := checkAuthFlowExists(p, attrs.authFlow.v); err != nil {
+		return err
+	}
+
+	// headers given as flags are added to any from the history entry
+	headers := base.Headers
+	if headers == nil {
+		headers = attrs.headers.v
+	} else {
+		for k, vals := range attrs.headers.v {
+			for _, v := range vals {
+				headers.Add(k, v)
+			}
+		}
+	}
+
+	// form fields replace any body from the history entry, the same as -d
+	body := attrs.body.Or(base.Body)
+	if len(attrs.form) > 0 {
+		body = attrs.body.v
+	}
+
 	// create the new request template
 	req := morc.RequestTemplate{
 		Name:    reqName,
-		Method:  attrs.method.Or("GET"),
-		URL:     attrs.url.Or("http://example.com"),
-		Headers: attrs.headers.v,
-		Body:    attrs.body.v,
+		Method:  attrs.method.Or(base.Method),
+		URL:     attrs.url.Or(base.URL),
+		Headers: headers,
+		Body:    body,
+		Form:    attrs.form,
+
+		BodyFile: attrs.bodyFile.v,
+		AuthFlow: attrs.authFlow.v,
+
+		HeaderOrder: attrs.headerOrder,
+		Description: attrs.description.v,
+		Tags:        updateTags(nil, attrs.addTags.v, nil),
 	}
 
 	if p.Templates == nil {
 		p.Templates = make(map[string]morc.RequestTemplate)
 	}
+	if req.BodyFile != "" {
+		req.Body = nil
+	}
 	p.Templates[reqLower] = req
 
 	// save the project file
@@ -319,6 +695,86 @@ func invokeReqsNew(io cmdio.IO, projFile, reqName string, attrs reqAttrValues) e
 	return nil
 }
 
+func invokeReqsCopy(io cmdio.IO, projFile, srcName, destName string) error {
+	// load the project file
+	p, err := readProject(projFile, false)
+	if err != nil {
+		return err
+	}
+
+	// case doesn't matter for request template names
+	srcLower := strings.ToLower(srcName)
+	destLower := strings.ToLower(destName)
+
+	src, ok := p.Templates[srcLower]
+	if !ok {
+		return morc.NewReqNotFoundError(srcLower)
+	}
+	if _, exists := p.Templates[destLower]; exists {
+		return morc.NewReqExistsError(destLower)
+	}
+
+	dest := src.Copy()
+	dest.Name = destLower
+	p.Templates[destLower] = dest
+
+	// save the project file
+	err = writeProject(p, false)
+	if err != nil {
+		return err
+	}
+
+	io.PrintLoudf("Copied request %s to %s\n", srcLower, destLower)
+
+	return nil
+}
+
+// updateTags returns tags with remove taken out and add put in, sorted and with
+// no duplicates. All of the tags must already be in lower case. If there are
+// no tags left, nil is returned.
+func updateTags(tags, add, remove []string) []string {
+	var updated []string
+	for _, t := range append(append([]string{}, tags...), add...) {
+		if sliceops.Index(remove, t) < 0 && sliceops.Index(updated, t) < 0 {
+			updated = append(updated, t)
+		}
+	}
+	sort.Strings(updated)
+	return updated
+}
+
+// parseTag returns the tag s in lower case, or an error if it is not a valid
+// tag.
+func parseTag(s string) (string, error) {
+	if s == "" {
+		return "", fmt.Errorf("tag cannot be empty")
+	}
+	if strings.ContainsAny(s, ", \t\r\n") {
+		return "", fmt.Errorf("tag %q cannot contain whitespace or commas", s)
+	}
+	return strings.ToLower(s), nil
+}
+
+// checkAuthFlowExists returns an error if flowName is not the name of a flow in
+// p. An empty flowName is always allowed, as it means there is no auth flow.
+func checkAuthFlowExists(p morc.Project, flowName string) error {
+	if flowName == "" {
+		return nil
+	}
+	if _, ok := p.Flows[flowName]; !ok {
+		return morc.NewFlowNotFoundError(flowName)
+	}
+	return nil
+}
+
+// orNone returns s, or "(none)" if it is empty.
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
 func invokeReqsDelete(io cmdio.IO, projFile, reqName string, force bool) error {
 	// load the project file
 	p, err := readProject(projFile, true)
@@ -360,7 +816,109 @@ func invokeReqsDelete(io cmdio.IO, projFile, reqName string, force bool) error {
 	return nil
 }
 
-func invokeReqsShow(io cmdio.IO, projFile, reqName string) error {
+// invokeReqsResolveVars prints the request that would be sent by the request
+// template reqName, with all vars filled in, without sending it. Vars captured
+// by the auth flow of the template are left as they are.
+func invokeReqsResolveVars(io cmdio.IO, projFile, reqName string) error {
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	// case doesn't matter for request template names
+	reqName = strings.ToLower(reqName)
+
+	tmpl, ok := p.Templates[reqName]
+	if !ok {
+		return morc.NewReqNotFoundError(reqName)
+	}
+	if !tmpl.Sendable() {
+		return fmt.Errorf("request template %s is incomplete", reqName)
+	}
+
+	var unexpanded []string
+	if tmpl.AuthFlow != "" {
+		_, flowTemplates, err := getExecableFlow(p, strings.ToLower(tmpl.AuthFlow))
+		if err != nil {
+			return fmt.Errorf("auth flow: %w", err)
+		}
+		for _, ft := range flowTemplates {
+			for k := range ft.Captures {
+				unexpanded = append(unexpanded, strings.ToUpper(k))
+			}
+		}
+	}
+
+	oc := morc.OutputControl{Writer: io.Out}
+	return dryRunTemplate(&p, tmpl, p.Vars.MergedSet(nil), unexpanded, p.VarPrefix(), oc)
+}
+
+func invokeReqsExportHTTP(io cmdio.IO, projFile, reqName, filename string, editorVars bool) error {
+	p, err := readProject(projFile, false)
+	if err != nil {
+		return err
+	}
+
+	// case doesn't matter for request template names
+	reqName = strings.ToLower(reqName)
+	req, ok := p.Templates[reqName]
+	if !ok {
+		return morc.NewReqNotFoundError(reqName)
+	}
+
+	if err := os.WriteFile(filename, req.HTTPFile(p.VarPrefix(), editorVars), 0644); err != nil {
+		return fmt.Errorf("write %q: %w", filename, err)
+	}
+
+	io.PrintLoudf("Exported request %s to %s\n", reqName, filename)
+
+	return nil
+}
+
+func invokeReqsImportHTTP(io cmdio.IO, projFile, filename string) error {
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("read %q: %w", filename, err)
+	}
+
+	templates, errs := morc.ParseHTTPFile(data, p.VarPrefix())
+	for _, err := range errs {
+		io.PrintErrf("WARN: skipping %v\n", err)
+	}
+
+	var created int
+	for _, req := range templates {
+		// case doesn't matter for request template names
+		reqLower := strings.ToLower(req.Name)
+		if _, exists := p.Templates[reqLower]; exists {
+			io.PrintErrf("WARN: skipping request: %v\n", morc.NewReqExistsError(reqLower))
+			continue
+		}
+
+		if p.Templates == nil {
+			p.Templates = make(map[string]morc.RequestTemplate)
+		}
+		p.Templates[reqLower] = req
+		created++
+	}
+
+	if created > 0 {
+		if err := writeProject(p, false); err != nil {
+			return err
+		}
+	}
+
+	io.PrintLoudf("Created %s from %s\n", io.CountOf(created, "request template"), filename)
+
+	return nil
+}
+
+func invokeReqsShow(io cmdio.IO, projFile, reqName string, format listFormat) error {
 	// load the project file
 	p, err := readProject(projFile, true)
 	if err != nil {
@@ -374,7 +932,123 @@ func invokeReqsShow(io cmdio.IO, projFile, reqName string) error {
 		return morc.NewReqNotFoundError(reqLower)
 	}
 
-	// print out the request details
+	if format == listFormatJSON {
+		return printJSON(io, reqsShowing(p, reqLower))
+	}
+
+	printReqDetails(io, p, req)
+	return nil
+}
+
+// reqsDetail is the machine-readable details of a request template.
+type reqsDetail struct {
+	Name     string              `json:"name"`
+	Method   string              `json:"method"`
+	URL      string              `json:"url"`
+	Headers  map[string][]string `json:"headers"`
+	Body     string              `json:"body"`
+	BodyFile string              `json:"body_file"`
+	Form     []reqsDetailField   `json:"form,omitempty"`
+	Captures []reqsDetailCapture `json:"captures"`
+	AuthFlow string              `json:"auth_flow"`
+	Sendable bool                `json:"sendable"`
+
+	Description  string                           `json:"description,omitempty"`
+	Tags         []string                         `json:"tags,omitempty"`
+	EnvOverrides map[string]reqsDetailEnvOverride `json:"env_overrides,omitempty"`
+}
+
+// reqsDetailEnvOverride is the override of a request template in an
+// environment in the machine-readable details of a request template. Body is
+// nil if the override does not replace the body.
+type reqsDetailEnvOverride struct {
+	Headers map[string][]string `json:"headers"`
+	Body    *string             `json:"body"`
+}
+
+// reqsDetailField is a form field in the machine-readable details of a
+// request template. Value is not encoded.
+type reqsDetailField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// reqsDetailCapture is a var capture in the machine-readable details of a
+// request template.
+type reqsDetailCapture struct {
+	Var  string `json:"var"`
+	Spec string `json:"spec"`
+}
+
+// reqsShowing returns the details of the request template in p with the given
+// name, which must exist. Captures are sorted by var name.
+func reqsShowing(p morc.Project, name string) reqsDetail {
+	tmpl := p.Templates[name]
+
+	detail := reqsDetail{
+		Name:     name,
+		Method:   tmpl.Method,
+		URL:      tmpl.URL,
+		Headers:  map[string][]string{},
+		Body:     string(tmpl.Body),
+		BodyFile: tmpl.BodyFile,
+		Captures: []reqsDetailCapture{},
+		AuthFlow: tmpl.AuthFlow,
+		Sendable: tmpl.Sendable(),
+
+		Description: tmpl.Description,
+		Tags:        tmpl.Tags,
+	}
+
+	for _, f := range tmpl.Form {
+		detail.Form = append(detail.Form, reqsDetailField{Name: f.Name, Value: f.Value})
+	}
+
+	for k, vals := range tmpl.Headers {
+		detail.Headers[k] = vals
+	}
+
+	for env, override := range tmpl.EnvOverrides {
+		if detail.EnvOverrides == nil {
+			detail.EnvOverrides = map[string]reqsDetailEnvOverride{}
+		}
+
+		od := reqsDetailEnvOverride{Headers: map[string][]string{}}
+		for k, vals := range override.Headers {
+			od.Headers[k] = vals
+		}
+		if override.Body != nil {
+			body := string(override.Body)
+			od.Body = &body
+		}
+		detail.EnvOverrides[env] = od
+	}
+
+	var capNames []string
+	for capName := range tmpl.Captures {
+		capNames = append(capNames, capName)
+	}
+	sort.Strings(capNames)
+
+	for _, capName := range capNames {
+		cap := tmpl.Captures[capName]
+		detail.Captures = append(detail.Captures, reqsDetailCapture{
+			Var:  strings.ToUpper(cap.Name),
+			Spec: cap.Spec(),
+		})
+	}
+
+	return detail
+}
+
+// printReqDetails prints the description, method, URL, headers, body,
+// captures, auth flow, and tags of req. The description and tags are only
+// printed if req has them.
+func printReqDetails(io cmdio.IO, p morc.Project, req morc.RequestTemplate) {
+	if req.Description != "" {
+		io.Printf("%s\n\n", req.Description)
+	}
+
 	meth := req.Method
 	if meth == "" {
 		meth = "(no-method)"
@@ -408,7 +1082,9 @@ func invokeReqsShow(io cmdio.IO, projFile, reqName string) error {
 	}
 	io.Printf("\n")
 
-	if len(req.Body) > 0 {
+	if req.BodyFile != "" {
+		io.Printf("BODY: (from file %s)\n", req.BodyFile)
+	} else if len(req.Body) > 0 {
 		io.Printf("BODY:\n")
 		io.Printf("%s\n", string(req.Body))
 	} else {
@@ -418,6 +1094,14 @@ func invokeReqsShow(io cmdio.IO, projFile, reqName string) error {
 	}
 	io.Printf("\n")
 
+	if len(req.Form) > 0 {
+		io.Printf("FORM:\n")
+		for _, f := range req.Form {
+			io.Printf("%s\n", formFieldString(f))
+		}
+		io.Printf("\n")
+	}
+
 	if len(req.Captures) > 0 {
 		io.Printf("VAR CAPTURES:\n")
 
@@ -447,27 +1131,58 @@ func invokeReqsShow(io cmdio.IO, projFile, reqName string) error {
 		io.Printf("AUTH FLOW: %s\n", req.AuthFlow)
 	}
 
-	return nil
+	if len(req.Tags) > 0 {
+		io.Printf("\nTAGS: %s\n", strings.Join(req.Tags, ", "))
+	}
+
+	if len(req.EnvOverrides) > 0 {
+		var envs []string
+		for env := range req.EnvOverrides {
+			envs = append(envs, env)
+		}
+		sort.Strings(envs)
+
+		for _, env := range envs {
+			override := req.EnvOverrides[env]
+			io.Printf("\nOVERRIDES IN ENV %s:\n", env)
+
+			var sortedNames []string
+			for name := range override.Headers {
+				sortedNames = append(sortedNames, name)
+			}
+			sort.Strings(sortedNames)
+
+			for _, name := range sortedNames {
+				for _, val := range override.Headers[name] {
+					io.Printf("%s: %s\n", name, val)
+				}
+			}
+			if override.Body != nil {
+				io.Printf("BODY:\n%s\n", string(override.Body))
+			}
+		}
+	}
 }
 
-func invokeReqsList(io cmdio.IO, projFile string) error {
+func invokeReqsList(io cmdio.IO, projFile string, format listFormat, tag string) error {
 	p, err := readProject(projFile, true)
 	if err != nil {
 		return err
 	}
 
-	if len(p.Templates) == 0 {
+	if format == listFormatJSON {
+		return printJSON(io, reqsListing(p, tag))
+	}
+
+	sortedNames := sortedTemplateNames(p, tag)
+	if len(sortedNames) == 0 {
 		io.PrintLoudln("(none)")
 	} else {
-		// alphabetize the templates
-		var sortedNames []string
-		for name := range p.Templates {
-			sortedNames = append(sortedNames, name)
-		}
-		sort.Strings(sortedNames)
 
-		// get the longest method name
+		// get the longest method name, and the longest name of a request with
+		// a description so that descriptions line up
 		maxLen := 0
+		maxNameLen := 0
 		for _, name := range sortedNames {
 			meth := p.Templates[name].Method
 			if meth == "" {
@@ -476,6 +1191,9 @@ func invokeReqsList(io cmdio.IO, projFile string) error {
 			if len(meth) > maxLen {
 				maxLen = len(meth)
 			}
+			if p.Templates[name].Description != "" && len(name) > maxNameLen {
+				maxNameLen = len(name)
+			}
 		}
 
 		for _, name := range sortedNames {
@@ -483,14 +1201,73 @@ func invokeReqsList(io cmdio.IO, projFile string) error {
 			if meth == "" {
 				meth = "???"
 			}
-			io.Printf("%-*s %s\n", maxLen, meth, name)
+			desc := p.Templates[name].Description
+			if desc == "" {
+				io.Printf("%-*s %s\n", maxLen, meth, name)
+			} else {
+				io.Printf("%-*s %-*s  %s\n", maxLen, meth, maxNameLen, name, firstLine(desc))
+			}
 		}
 	}
 
 	return nil
 }
 
-func invokeReqsGet(io cmdio.IO, projFile, reqName string, item reqKey) error {
+// firstLine returns s up to but not including its first line break, so that
+// multi-line text can be shown in a single-line listing.
+func firstLine(s string) string {
+	if idx := strings.IndexAny(s, "\r\n"); idx >= 0 {
+		return s[:idx]
+	}
+	return s
+}
+
+// sortedTemplateNames returns the names of the request templates in p in
+// alphabetical order. If tag is not empty, only the names of templates that
+// have it are included.
+func sortedTemplateNames(p morc.Project, tag string) []string {
+	var sortedNames []string
+	for name, tmpl := range p.Templates {
+		if tag == "" || tmpl.HasTag(tag) {
+			sortedNames = append(sortedNames, name)
+		}
+	}
+	sort.Strings(sortedNames)
+	return sortedNames
+}
+
+// reqsListEntry is an entry in the machine-readable listing of request
+// templates.
+type reqsListEntry struct {
+	Name     string `json:"name"`
+	Method   string `json:"method"`
+	Sendable bool   `json:"sendable"`
+
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// reqsListing returns entries for every request template in p, sorted by name.
+// If tag is not empty, only templates that have it are included.
+func reqsListing(p morc.Project, tag string) []reqsListEntry {
+	sortedNames := sortedTemplateNames(p, tag)
+
+	entries := make([]reqsListEntry, len(sortedNames))
+	for i, name := range sortedNames {
+		tmpl := p.Templates[name]
+		entries[i] = reqsListEntry{
+			Name:        tmpl.Name,
+			Method:      tmpl.Method,
+			Sendable:    tmpl.Sendable(),
+			Description: tmpl.Description,
+			Tags:        tmpl.Tags,
+		}
+	}
+
+	return entries
+}
+
+func invokeReqsGet(io cmdio.IO, projFile, reqName string, item reqKey, headerOrder headerOrder) error {
 	// load the project file
 	p, err := readProject(projFile, true)
 	if err != nil {
@@ -531,25 +1308,57 @@ func invokeReqsGet(io cmdio.IO, projFile, reqName string, item reqKey) error {
 		if len(req.Headers) == 0 {
 			io.PrintLoudf("(none)\n")
 		} else {
-			// alphabetize headers
-			var sortedNames []string
-			for name := range req.Headers {
-				sortedNames = append(sortedNames, name)
+			var names []string
+			if headerOrder == headerOrderReceived {
+				names = req.AuthoredHeaderKeys()
+			} else {
+				// alphabetize headers
+				for name := range req.Headers {
+					names = append(names, name)
+				}
+				sort.Strings(names)
 			}
-			sort.Strings(sortedNames)
 
-			for _, name := range sortedNames {
+			for _, name := range names {
 				for _, val := range req.Headers[name] {
 					io.Printf("%s: %s\n", name, val)
 				}
 			}
 		}
+	case reqKeyBodyFile:
+		if req.BodyFile == "" {
+			io.PrintLoudf("(none)\n")
+		} else {
+			io.Printf("%s\n", req.BodyFile)
+		}
+	case reqKeyForm:
+		if len(req.Form) == 0 {
+			io.PrintLoudf("(none)\n")
+		} else {
+			for _, f := range req.Form {
+				io.Printf("%s\n", formFieldString(f))
+			}
+		}
 	case reqKeyAuthFlow:
 		if req.AuthFlow == "" {
 			io.PrintLoudf("(none)\n")
 		} else {
 			io.Printf("%s\n", req.AuthFlow)
 		}
+	case reqKeyDescription:
+		if req.Description == "" {
+			io.PrintLoudf("(none)\n")
+		} else {
+			io.Printf("%s\n", req.Description)
+		}
+	case reqKeyTags:
+		if len(req.Tags) == 0 {
+			io.PrintLoudf("(none)\n")
+		} else {
+			for _, t := range req.Tags {
+				io.Printf("%s\n", t)
+			}
+		}
 	case reqKeyCaptures:
 		if len(req.Captures) == 0 {
 			io.PrintLoudf("(none)\n")
@@ -592,6 +1401,31 @@ type reqsArgs struct {
 	force    bool
 	req      string
 
+	headerOrder headerOrder
+
+	listFormat listFormat
+
+	// tag is the tag that templates must have to be listed. It is empty if
+	// all templates are to be listed.
+	tag string
+
+	// copyDest is the name of the request template that the one named by req
+	// is copied to.
+	copyDest string
+
+	// env is the environment whose override of the request is modified
+	// instead of the request itself. It is empty if the request itself is to
+	// be modified.
+	env string
+
+	exportFile string
+	editorVars bool
+	importFile string
+
+	// fromHistory is the index of the history entry to create a new request
+	// template from, or -1 to create it from only the flags.
+	fromHistory int
+
 	sets reqAttrValues
 }
 
@@ -602,6 +1436,59 @@ type reqAttrValues struct {
 	body          optional[[]byte]
 	headers       optional[http.Header]
 	removeHeaders optional[[]string]
+
+	// wrapBodyKey is the key of the JSON object to wrap the existing body in.
+	wrapBodyKey optional[string]
+
+	// bodyFile is the path to the file to read the body from at send time. An
+	// empty value clears it.
+	bodyFile optional[string]
+
+	// authFlow is the name of the flow to run before sending the request. An
+	// empty value clears it.
+	authFlow optional[string]
+
+	// description is the description of the request. An empty value clears
+	// it.
+	description optional[string]
+
+	// addTags is the tags to add to the request, in lower case.
+	addTags optional[[]string]
+
+	// removeTags is the tags to remove from the request, in lower case.
+	removeTags optional[[]string]
+
+	// headerOrder is the keys of headers in the order they first appear in
+	// the flags. It is only set if headers is.
+	headerOrder []string
+
+	// form is the fields given with --data-urlencode. They replace the form
+	// fields of the request and are encoded when it is sent.
+	form []morc.FormField
+}
+
+// formFieldString returns f in the form it is given to --data-urlencode, with
+// its value unencoded.
+func formFieldString(f morc.FormField) string {
+	return f.Name + "=" + f.Value
+}
+
+// addFormContentType adds a Content-Type header for morc.FormContentType to
+// the headers being set if there are form fields in attrs and there is no
+// Content-Type in either the headers being set or the existing headers of the
+// request.
+func (attrs *reqAttrValues) addFormContentType(existing http.Header) {
+	if len(attrs.form) == 0 {
+		return
+	}
+
+	if attrs.headers.v.Get("Content-Type") == "" && existing.Get("Content-Type") == "" {
+		if attrs.headers.v == nil {
+			attrs.headers = optional[http.Header]{set: true, v: make(http.Header)}
+		}
+		attrs.headers.v.Set("Content-Type", morc.FormContentType)
+		attrs.headerOrder = append(attrs.headerOrder, "Content-Type")
+	}
 }
 
 func parseReqsArgs(cmd *cobra.Command, posArgs []string, args *reqsArgs) error {
@@ -617,10 +1504,37 @@ func parseReqsArgs(cmd *cobra.Command, posArgs []string, args *reqsArgs) error {
 		return err
 	}
 
+	args.listFormat, err = gatherListOutputFlag(cmd, args.action == reqsActionList || args.action == reqsActionShow, "requests")
+	if err != nil {
+		return err
+	}
+
+	if cmd.Flags().Changed("tag") && args.action != reqsActionList {
+		return fmt.Errorf("--tag can only be used when listing requests")
+	}
+
+	if cmd.Flags().Changed("env") {
+		if args.action != reqsActionEdit {
+			return fmt.Errorf("--env can only be used when modifying a request")
+		}
+		if flags.Env == "" {
+			return fmt.Errorf("--env cannot be set to empty string")
+		}
+		if !reqsEnvOverrideFlagsOnly(cmd) {
+			return fmt.Errorf("--env can only be given with --header/-H, --remove-header/-r, --data/-d, and --remove-body/-R")
+		}
+		args.env = flags.Env
+	}
+
 	// do action-specific arg and flag parsing
 	switch args.action {
 	case reqsActionList:
-		// nothing else to do
+		if cmd.Flags().Changed("tag") {
+			args.tag, err = parseTag(flags.Tag)
+			if err != nil {
+				return fmt.Errorf("--tag: %w", err)
+			}
+		}
 	case reqsActionShow:
 		// use arg 1 as the req name
 		args.req = posArgs[0]
@@ -643,6 +1557,16 @@ func parseReqsArgs(cmd *cobra.Command, posArgs []string, args *reqsArgs) error {
 		} else {
 			args.getItem = reqKey{header: flags.GetHeader}
 		}
+
+		if cmd.Flags().Changed("sorted") {
+			if args.getItem != reqKeyHeaders {
+				return fmt.Errorf("--sorted can only be used with --get %s", strings.ToLower(reqKeyHeaders.name))
+			}
+			args.headerOrder, err = parseHeaderOrder(flags.HeaderOrder)
+			if err != nil {
+				return err
+			}
+		}
 	case reqsActionNew:
 		// above action parsing already checked that invalid set opts will not
 		// be present so we can just call parseReqsSetFlags and then use
@@ -654,6 +1578,14 @@ func parseReqsArgs(cmd *cobra.Command, posArgs []string, args *reqsArgs) error {
 		// set req name from the flag
 		args.req = flags.New
 		args.sets.name = optional[string]{set: true, v: flags.New}
+
+		args.fromHistory = -1
+		if cmd.Flags().Changed("from-history") {
+			if flags.FromHistory < 0 {
+				return fmt.Errorf("--from-history must be a non-negative history entry index")
+			}
+			args.fromHistory = flags.FromHistory
+		}
 	case reqsActionEdit:
 		// use arg 1 as the req name
 		args.req = posArgs[0]
@@ -661,6 +1593,20 @@ func parseReqsArgs(cmd *cobra.Command, posArgs []string, args *reqsArgs) error {
 		if err := parseReqsSetFlags(cmd, &args.sets); err != nil {
 			return err
 		}
+	case reqsActionCopy:
+		// special case of req name set from a CLI flag rather than pos arg.
+		args.req = flags.Copy
+		args.copyDest = posArgs[0]
+	case reqsActionExportHTTP:
+		// use arg 1 as the req name
+		args.req = posArgs[0]
+		args.exportFile = flags.ExportHTTP
+		args.editorVars = flags.BEditorVars
+	case reqsActionImportHTTP:
+		args.importFile = flags.ImportHTTP
+	case reqsActionResolveVars:
+		// use arg 1 as the req name
+		args.req = posArgs[0]
 	default:
 		panic(fmt.Sprintf("unhandled reqs action %q", args.action))
 	}
@@ -683,6 +1629,18 @@ func parseReqsActionFromFlags(cmd *cobra.Command, posArgs []string) (reqsAction,
 		return reqsActionEdit, fmt.Errorf("--force/-f can only be used with --delete/-D")
 	}
 
+	if cmd.Flags().Changed("sorted") && flags.Get == "" {
+		return reqsAction(0), fmt.Errorf("--sorted can only be used with --get %s", strings.ToLower(reqKeyHeaders.name))
+	}
+
+	if cmd.Flags().Changed("from-history") && flags.New == "" {
+		return reqsAction(0), fmt.Errorf("--from-history can only be used with --new/-N")
+	}
+
+	if flags.BEditorVars && !cmd.Flags().Changed("export-http") {
+		return reqsAction(0), fmt.Errorf("--editor-vars can only be used with --export-http")
+	}
+
 	if flags.Delete != "" {
 		if len(posArgs) > 0 {
 			return reqsAction(0), fmt.Errorf("unknown positional argument %q", posArgs[0])
@@ -701,6 +1659,56 @@ func parseReqsActionFromFlags(cmd *cobra.Command, posArgs []string) (reqsAction,
 			return reqsActionGet, fmt.Errorf("unknown positional argument %q", posArgs[1])
 		}
 		return reqsActionGet, nil
+	} else if cmd.Flags().Changed("export-http") {
+		if reqsSetFlagIsPresent(cmd) {
+			return reqsActionExportHTTP, fmt.Errorf("--export-http cannot be given with flags that modify a request")
+		}
+		if flags.ExportHTTP == "" {
+			return reqsActionExportHTTP, fmt.Errorf("--export-http cannot be set to empty string")
+		}
+		if len(posArgs) < 1 {
+			return reqsActionExportHTTP, fmt.Errorf("missing name of REQ to export")
+		}
+		if len(posArgs) > 1 {
+			return reqsActionExportHTTP, fmt.Errorf("unknown positional argument %q", posArgs[1])
+		}
+		return reqsActionExportHTTP, nil
+	} else if cmd.Flags().Changed("copy") {
+		if reqsSetFlagIsPresent(cmd) {
+			return reqsActionCopy, fmt.Errorf("--copy cannot be given with flags that modify a request")
+		}
+		if flags.Copy == "" {
+			return reqsActionCopy, fmt.Errorf("--copy cannot be set to empty string")
+		}
+		if len(posArgs) < 1 {
+			return reqsActionCopy, fmt.Errorf("missing name of DEST to copy to")
+		}
+		if len(posArgs) > 1 {
+			return reqsActionCopy, fmt.Errorf("unknown positional argument %q", posArgs[1])
+		}
+		return reqsActionCopy, nil
+	} else if cmd.Flags().Changed("import-http") {
+		if reqsSetFlagIsPresent(cmd) {
+			return reqsActionImportHTTP, fmt.Errorf("--import-http cannot be given with flags that modify a request")
+		}
+		if flags.ImportHTTP == "" {
+			return reqsActionImportHTTP, fmt.Errorf("--import-http cannot be set to empty string")
+		}
+		if len(posArgs) > 0 {
+			return reqsActionImportHTTP, fmt.Errorf("unknown positional argument %q", posArgs[0])
+		}
+		return reqsActionImportHTTP, nil
+	} else if flags.BResolveVars {
+		if reqsSetFlagIsPresent(cmd) {
+			return reqsActionResolveVars, fmt.Errorf("--resolve-vars cannot be given with flags that modify a request")
+		}
+		if len(posArgs) < 1 {
+			return reqsActionResolveVars, fmt.Errorf("missing name of REQ to resolve vars in")
+		}
+		if len(posArgs) > 1 {
+			return reqsActionResolveVars, fmt.Errorf("unknown positional argument %q", posArgs[1])
+		}
+		return reqsActionResolveVars, nil
 	} else if reqsSetFlagIsPresent(cmd) {
 		if len(posArgs) < 1 {
 			return reqsActionEdit, fmt.Errorf("missing name of REQ to update")
@@ -738,15 +1746,20 @@ func parseReqsSetFlags(cmd *cobra.Command, attrs *reqAttrValues) error {
 
 	if f.Changed("data") {
 		if strings.HasPrefix(flags.BodyData, "@") {
+			bodyFile, err := projDirPath(cmd, flags.BodyData[1:])
+			if err != nil {
+				return fmt.Errorf("body file: %w", err)
+			}
+
 			// read entire file now
-			fRaw, err := os.Open(flags.BodyData[1:])
+			fRaw, err := os.Open(bodyFile)
 			if err != nil {
-				return fmt.Errorf("open %q: %w", flags.BodyData[1:], err)
+				return fmt.Errorf("open %q: %w", bodyFile, err)
 			}
 			defer fRaw.Close()
 			bodyData, err := io.ReadAll(fRaw)
 			if err != nil {
-				return fmt.Errorf("read %q: %w", flags.BodyData[1:], err)
+				return fmt.Errorf("read %q: %w", bodyFile, err)
 			}
 			attrs.body = optional[[]byte]{set: true, v: bodyData}
 		} else {
@@ -754,12 +1767,63 @@ func parseReqsSetFlags(cmd *cobra.Command, attrs *reqAttrValues) error {
 		}
 	}
 
+	if f.Changed("data-urlencode") {
+		form, err := parseDataURLEncodeFlags(cmd)
+		if err != nil {
+			return err
+		}
+		attrs.form = form
+	}
+
+	if f.Changed("body-file") {
+		attrs.bodyFile = optional[string]{set: true, v: flags.BodyFile}
+	}
+
 	if f.Changed("remove-body") {
 		attrs.body = optional[[]byte]{set: true, v: nil}
 	}
 
+	if f.Changed("wrap-body") {
+		if flags.WrapBody == "" {
+			return fmt.Errorf("--wrap-body: key cannot be empty")
+		}
+		attrs.wrapBodyKey = optional[string]{set: true, v: flags.WrapBody}
+	}
+
+	if f.Changed("auth-flow") {
+		// case doesn't matter for flow names
+		attrs.authFlow = optional[string]{set: true, v: strings.ToLower(flags.AuthFlow)}
+	}
+
+	if f.Changed("desc") {
+		attrs.description = optional[string]{set: true, v: flags.Description}
+	}
+
+	if f.Changed("add-tag") {
+		tags, err := parseTagFlags(flags.AddTags)
+		if err != nil {
+			return fmt.Errorf("--add-tag: %w", err)
+		}
+		attrs.addTags = optional[[]string]{set: true, v: tags}
+	}
+
+	if f.Changed("remove-tag") {
+		tags, err := parseTagFlags(flags.RemoveTags)
+		if err != nil {
+			return fmt.Errorf("--remove-tag: %w", err)
+		}
+		attrs.removeTags = optional[[]string]{set: true, v: tags}
+
+		for _, t := range tags {
+			if sliceops.Index(attrs.addTags.v, t) >= 0 {
+				return fmt.Errorf("tag %q cannot be both added and removed", t)
+			}
+		}
+	}
+
 	if f.Changed("header") {
 		headers := make(http.Header)
+		var order []string
 		for idx, h := range flags.Headers {
 
 			// split the header into key and value
@@ -772,9 +1836,13 @@ func parseReqsSetFlags(cmd *cobra.Command, attrs *reqAttrValues) error {
 				return fmt.Errorf("header add #%d (%q) does not have a valid header key", idx+1, h)
 			}
 			value := strings.TrimSpace(parts[1])
+			if len(headers.Values(canonKey)) == 0 {
+				order = append(order, canonKey)
+			}
 			headers.Add(canonKey, value)
 		}
 		attrs.headers = optional[http.Header]{set: true, v: headers}
+		attrs.headerOrder = order
 	}
 
 	if f.Changed("remove-header") {
@@ -793,6 +1861,36 @@ func parseReqsSetFlags(cmd *cobra.Command, attrs *reqAttrValues) error {
 	return nil
 }
 
+// parseTagFlags parses each of the given tags with parseTag.
+func parseTagFlags(tags []string) ([]string, error) {
+	parsed := make([]string, len(tags))
+	for idx, t := range tags {
+		var err error
+		parsed[idx], err = parseTag(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// reqsEnvOverrideFlagsOnly returns whether the only modification flags given
+// are those that can be applied to the override of a request in an
+// environment.
+func reqsEnvOverrideFlagsOnly(cmd *cobra.Command) bool {
+	f := cmd.Flags()
+	return !(f.Changed("method") ||
+		f.Changed("url") ||
+		f.Changed("name") ||
+		f.Changed("data-urlencode") ||
+		f.Changed("wrap-body") ||
+		f.Changed("body-file") ||
+		f.Changed("auth-flow") ||
+		f.Changed("desc") ||
+		f.Changed("add-tag") ||
+		f.Changed("remove-tag"))
+}
+
 func reqsSetFlagIsPresent(cmd *cobra.Command) bool {
 	f := cmd.Flags()
 	return f.Changed("method") ||
@@ -801,8 +1899,58 @@ func reqsSetFlagIsPresent(cmd *cobra.Command) bool {
 		f.Changed("name") ||
 		f.Changed("header") ||
 		f.Changed("data") ||
+		f.Changed("data-urlencode") ||
 		f.Changed("remove-header") ||
-		f.Changed("remove-body")
+		f.Changed("remove-body") ||
+		f.Changed("wrap-body") ||
+		f.Changed("body-file") ||
+		f.Changed("auth-flow") ||
+		f.Changed("desc") ||
+		f.Changed("add-tag") ||
+		f.Changed("remove-tag")
+}
+
+// wrapBody returns a JSON object with body under key. If body is valid JSON, it
+// is put in as-is; otherwise, it is put in as a JSON string. The result is
+// indented if body has more than one line.
+func wrapBody(body []byte, key string) ([]byte, error) {
+	var value interface{} = string(body)
+	if json.Valid(body) {
+		value = json.RawMessage(body)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if bytes.Contains(bytes.TrimSpace(body), []byte("\n")) {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(map[string]interface{}{key: value}); err != nil {
+		return nil, fmt.Errorf("wrap body: %w", err)
+	}
+
+	// Encode always ends with a newline, which the original body may not have
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// headerOrder is the order that the headers of a request template are output
+// in.
+type headerOrder int
+
+const (
+	headerOrderAlpha headerOrder = iota
+	headerOrderReceived
+)
+
+func parseHeaderOrder(s string) (headerOrder, error) {
+	switch strings.ToLower(s) {
+	case "alpha":
+		return headerOrderAlpha, nil
+	case "received":
+		return headerOrderReceived, nil
+	default:
+		return headerOrderAlpha, fmt.Errorf("--sorted must be one of 'alpha' or 'received', not %q", s)
+	}
 }
 
 type reqsAction int
@@ -814,28 +1962,44 @@ const (
 	reqsActionDelete
 	reqsActionGet
 	reqsActionEdit
+	reqsActionExportHTTP
+	reqsActionImportHTTP
+	reqsActionResolveVars
+	reqsActionCopy
 )
 
 type reqKey struct {
 	name      string
 	header    string
 	uniqueInt int // only used for sorting in edit output
+
+	// env is the environment whose override the key is in, or empty if it is
+	// in the request template itself.
+	env string
 }
 
 var (
-	reqKeyName     reqKey = reqKey{name: "NAME"}
-	reqKeyMethod   reqKey = reqKey{name: "METHOD"}
-	reqKeyURL      reqKey = reqKey{name: "URL"}
-	reqKeyData     reqKey = reqKey{name: "DATA"}
-	reqKeyHeaders  reqKey = reqKey{name: "HEADERS"}
-	reqKeyAuthFlow reqKey = reqKey{name: "AUTH"}
-	reqKeyCaptures reqKey = reqKey{name: "CAPTURES"}
+	reqKeyName        reqKey = reqKey{name: "NAME"}
+	reqKeyMethod      reqKey = reqKey{name: "METHOD"}
+	reqKeyURL         reqKey = reqKey{name: "URL"}
+	reqKeyData        reqKey = reqKey{name: "DATA"}
+	reqKeyBodyFile    reqKey = reqKey{name: "BODY-FILE"}
+	reqKeyForm        reqKey = reqKey{name: "FORM"}
+	reqKeyHeaders     reqKey = reqKey{name: "HEADERS"}
+	reqKeyAuthFlow    reqKey = reqKey{name: "AUTH"}
+	reqKeyCaptures    reqKey = reqKey{name: "CAPTURES"}
+	reqKeyDescription reqKey = reqKey{name: "DESC"}
+	reqKeyTags        reqKey = reqKey{name: "TAGS"}
 
 	// OR a specific header key denoted via leading ":".
 )
 
 // Human prints the human-readable description of the key.
 func (rk reqKey) Human() string {
+	if rk.env != "" {
+		return fmt.Sprintf("%s in env %s", reqKey{name: rk.name, header: rk.header}.Human(), rk.env)
+	}
+
 	if rk.header != "" {
 		return fmt.Sprintf("header %s", rk.header)
 	}
@@ -849,12 +2013,20 @@ func (rk reqKey) Human() string {
 		return "request URL"
 	case reqKeyData.name:
 		return "request body"
+	case reqKeyBodyFile.name:
+		return "request body file"
+	case reqKeyForm.name:
+		return "request form fields"
 	case reqKeyHeaders.name:
 		return "request headers"
 	case reqKeyAuthFlow.name:
 		return "request auth flow"
 	case reqKeyCaptures.name:
 		return "request var captures"
+	case reqKeyDescription.name:
+		return "request description"
+	case reqKeyTags.name:
+		return "request tags"
 	default:
 		return fmt.Sprintf("unknown req key %q", rk.name)
 	}
@@ -876,9 +2048,13 @@ var (
 		reqKeyMethod,
 		reqKeyURL,
 		reqKeyData,
+		reqKeyBodyFile,
+		reqKeyForm,
 		reqKeyHeaders,
 		reqKeyAuthFlow,
 		reqKeyCaptures,
+		reqKeyDescription,
+		reqKeyTags,
 	}
 )
 
@@ -900,12 +2076,20 @@ func parseReqAttrKey(s string) (reqKey, error) {
 		return reqKeyURL, nil
 	case reqKeyData.Name():
 		return reqKeyData, nil
+	case reqKeyBodyFile.Name():
+		return reqKeyBodyFile, nil
+	case reqKeyForm.Name():
+		return reqKeyForm, nil
 	case reqKeyHeaders.Name():
 		return reqKeyHeaders, nil
 	case reqKeyAuthFlow.Name():
 		return reqKeyAuthFlow, nil
 	case reqKeyCaptures.Name():
 		return reqKeyCaptures, nil
+	case reqKeyDescription.Name():
+		return reqKeyDescription, nil
+	case reqKeyTags.Name():
+		return reqKeyTags, nil
 	default:
 		return reqKey{}, fmt.Errorf("must be one of: %s", strings.Join(reqAttrKeyNames(), ", "))
 	}
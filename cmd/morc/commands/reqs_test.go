@@ -135,6 +135,211 @@ func Test_Reqs_Delete(t *testing.T) {
 	}
 }
 
+func Test_Reqs_Copy(t *testing.T) {
+	src := morc.RequestTemplate{
+		Name:    "req1",
+		Method:  "POST",
+		URL:     "http://example.com/users",
+		Headers: http.Header{"Content-Type": {"application/json"}},
+		Body:    []byte(`{"name": "Vriska"}`),
+		Captures: map[string]morc.VarScraper{
+			"ID": {Name: "ID", Steps: []morc.TraversalStep{{Key: "id"}}},
+		},
+	}
+	dest := src
+	dest.Name = "req2"
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectP            morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "copy request",
+			args:               []string{"reqs", "--copy", "REQ1", "Req2"},
+			p:                  testProject_withRequests(src),
+			expectP:            testProject_withRequests(src, dest),
+			expectStdoutOutput: "Copied request req1 to req2\n",
+		},
+		{
+			name:      "source does not exist",
+			args:      []string{"reqs", "--copy", "req3", "req2"},
+			p:         testProject_withRequests(src),
+			expectErr: "no request named req3 exists",
+		},
+		{
+			name:      "destination already exists",
+			args:      []string{"reqs", "--copy", "req1", "req2"},
+			p:         testProject_withRequests(src, dest),
+			expectErr: "request named req2 already exists in project",
+		},
+		{
+			name:      "missing destination",
+			args:      []string{"reqs", "--copy", "req1"},
+			p:         testProject_withRequests(src),
+			expectErr: "missing name of DEST to copy to",
+		},
+		{
+			name:      "with modification flags",
+			args:      []string{"reqs", "--copy", "req1", "req2", "-X", "PUT"},
+			p:         testProject_withRequests(src),
+			expectErr: "--copy cannot be given with flags that modify a request",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetReqsFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, _, err := runTestCommand(reqsCmd, projFilePath, tc.args)
+
+			// assert and check stdout
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			}
+			if tc.expectErr != "" {
+				t.Fatalf("expected returned error to contain %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+
+			assert_projectFilesInBuffersMatch(assert, tc.expectP)
+		})
+	}
+}
+
+func Test_Reqs_EnvOverride(t *testing.T) {
+	base := morc.RequestTemplate{
+		Name:    "req1",
+		Method:  "POST",
+		URL:     "http://example.com/users",
+		Headers: http.Header{"Content-Type": {"application/json"}},
+		Body:    []byte(`{"name": "Vriska"}`),
+	}
+	withOverrides := func(overrides map[string]morc.TemplateOverride) morc.RequestTemplate {
+		r := base.Copy()
+		r.EnvOverrides = overrides
+		return r
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectP            morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStdoutOutput string // set with expected output to stdout
+		expectStderrOutput string // set with expected output to stderr
+	}{
+		{
+			name: "add header in env",
+			args: []string{"reqs", "req1", "--env", "prod", "-H", "X-Debug: 0"},
+			p:    testProject_withRequests(base),
+			expectP: testProject_withRequests(withOverrides(map[string]morc.TemplateOverride{
+				"PROD": {Headers: http.Header{"X-Debug": {"0"}}},
+			})),
+			expectStdoutOutput: "Set header X-Debug in env PROD to have new value 0\n",
+		},
+		{
+			name: "set body in env",
+			args: []string{"reqs", "req1", "-e", "PROD", "-d", `{"name": "Terezi"}`},
+			p: testProject_withRequests(withOverrides(map[string]morc.TemplateOverride{
+				"PROD": {Headers: http.Header{"X-Debug": {"0"}}},
+			})),
+			expectP: testProject_withRequests(withOverrides(map[string]morc.TemplateOverride{
+				"PROD": {Headers: http.Header{"X-Debug": {"0"}}, Body: []byte(`{"name": "Terezi"}`)},
+			})),
+			expectStdoutOutput: "Set request body in env PROD to data with length 18\n",
+		},
+		{
+			name: "removing everything removes the override",
+			args: []string{"reqs", "req1", "--env", "PROD", "-r", "X-Debug", "-R"},
+			p: testProject_withRequests(withOverrides(map[string]morc.TemplateOverride{
+				"PROD": {Headers: http.Header{"X-Debug": {"0"}}, Body: []byte(`{}`)},
+			})),
+			expectP:            testProject_withRequests(base),
+			expectStdoutOutput: "Set request body in env PROD to (none) and header X-Debug in env PROD to no longer exist\n",
+		},
+		{
+			name:               "remove header not in override",
+			args:               []string{"reqs", "req1", "--env", "PROD", "-r", "Content-Type"},
+			p:                  testProject_withRequests(base),
+			expectP:            testProject_withRequests(base),
+			expectStderrOutput: "No change to header Content-Type in env PROD; already set to not exist\n",
+		},
+		{
+			name:      "request does not exist",
+			args:      []string{"reqs", "req2", "--env", "PROD", "-H", "X-Debug: 0"},
+			p:         testProject_withRequests(base),
+			expectErr: "no request named req2 exists",
+		},
+		{
+			name:      "with flags that cannot be overridden",
+			args:      []string{"reqs", "req1", "--env", "PROD", "-X", "PUT"},
+			p:         testProject_withRequests(base),
+			expectErr: "--env can only be given with --header/-H, --remove-header/-r, --data/-d, and --remove-body/-R",
+		},
+		{
+			name:      "not modifying a request",
+			args:      []string{"reqs", "req1", "--env", "PROD"},
+			p:         testProject_withRequests(base),
+			expectErr: "--env can only be used when modifying a request",
+		},
+		{
+			name:      "empty env",
+			args:      []string{"reqs", "req1", "--env", "", "-H", "X-Debug: 0"},
+			p:         testProject_withRequests(base),
+			expectErr: "--env cannot be set to empty string",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetReqsFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(reqsCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			}
+			if tc.expectErr != "" {
+				t.Fatalf("expected returned error to contain %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_projectFilesInBuffersMatch(assert, tc.expectP)
+		})
+	}
+}
+
 func Test_Reqs_Edit(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -173,6 +378,137 @@ func Test_Reqs_Edit(t *testing.T) {
 			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte(`{"name":"JACK NOIR"}`)}),
 			expectStdoutOutput: "Set request body to data with length 20\n",
 		},
+		{
+			name: "set form body",
+			args: []string{"reqs", "req1", "--data-urlencode", "q=50% off"},
+			p:    testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte(`{"name":"JACK NOIR"}`)}),
+			expectP: testProject_withRequests(morc.RequestTemplate{
+				Name: "req1",
+				Form: []morc.FormField{{Name: "q", Value: "50% off"}},
+				Headers: http.Header(map[string][]string{
+					"Content-Type": {"application/x-www-form-urlencoded"},
+				}),
+				HeaderOrder: []string{"Content-Type"},
+			}),
+			expectStdoutOutput: "Set request body to (none), request form fields to 1 field, and header Content-Type to have new value application/x-www-form-urlencoded\n",
+		},
+		{
+			name: "set form body, content type already on request",
+			args: []string{"reqs", "req1", "--data-urlencode", "q=50% off"},
+			p: testProject_withRequests(morc.RequestTemplate{
+				Name:        "req1",
+				Headers:     http.Header(map[string][]string{"Content-Type": {"text/plain"}}),
+				HeaderOrder: []string{"Content-Type"},
+			}),
+			expectP: testProject_withRequests(morc.RequestTemplate{
+				Name:        "req1",
+				Form:        []morc.FormField{{Name: "q", Value: "50% off"}},
+				Headers:     http.Header(map[string][]string{"Content-Type": {"text/plain"}}),
+				HeaderOrder: []string{"Content-Type"},
+			}),
+			expectStdoutOutput: "Set request form fields to 1 field\n",
+		},
+		{
+			name:               "set body removes form fields",
+			args:               []string{"reqs", "req1", "-d", "a=1"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Form: []morc.FormField{{Name: "q", Value: "50% off"}}}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte("a=1")}),
+			expectStdoutOutput: "Set request body to data with length 3 and request form fields to (none)\n",
+		},
+		{
+			name:               "wrap JSON body",
+			args:               []string{"reqs", "req1", "--wrap-body", "data"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte(`{"name":"<JACK NOIR>"}`)}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte(`{"data":{"name":"<JACK NOIR>"}}`)}),
+			expectStdoutOutput: "Set request body to data with length 31 wrapped in \"data\"\n",
+		},
+		{
+			name:               "wrap multi-line JSON body",
+			args:               []string{"reqs", "req1", "--wrap-body", "data"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte("{\n  \"id\": 413\n}")}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte("{\n  \"data\": {\n    \"id\": 413\n  }\n}")}),
+			expectStdoutOutput: "Set request body to data with length 33 wrapped in \"data\"\n",
+		},
+		{
+			name:               "wrap non-JSON body",
+			args:               []string{"reqs", "req1", "--wrap-body", "payload"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte(`{"id": ${ID}}`)}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte(`{"payload":"{\"id\": ${ID}}"}`)}),
+			expectStdoutOutput: "Set request body to data with length 29 wrapped in \"payload\"\n",
+		},
+		{
+			name:      "wrap missing body",
+			args:      []string{"reqs", "req1", "--wrap-body", "data"},
+			p:         testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectErr: "request template req1 has no body to wrap",
+		},
+		{
+			name:      "wrap body with data",
+			args:      []string{"reqs", "req1", "--wrap-body", "data", "-d", "{}"},
+			p:         testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte(`{}`)}),
+			expectErr: "[data wrap-body] were all set",
+		},
+		{
+			name:               "set auth flow",
+			args:               []string{"reqs", "req1", "--auth-flow", "TEST"},
+			p:                  testProject_singleFlowWithNSteps(2),
+			expectP:            testProject_singleFlowWithNStepsAndAuthFlow(2, "req1", "test"),
+			expectStdoutOutput: "Set request auth flow to test\n",
+		},
+		{
+			name:               "clear auth flow",
+			args:               []string{"reqs", "req1", "--auth-flow", ""},
+			p:                  testProject_singleFlowWithNStepsAndAuthFlow(2, "req1", "test"),
+			expectP:            testProject_singleFlowWithNSteps(2),
+			expectStdoutOutput: "Set request auth flow to (none)\n",
+		},
+		{
+			name:               "set description",
+			args:               []string{"reqs", "req1", "--desc", "Needs an admin token"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Description: "Needs an admin token"}),
+			expectStdoutOutput: "Set request description to Needs an admin token\n",
+		},
+		{
+			name:               "clear description",
+			args:               []string{"reqs", "req1", "--desc", ""},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Description: "Needs an admin token"}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectStdoutOutput: "Set request description to (none)\n",
+		},
+		{
+			name:               "add and remove tags",
+			args:               []string{"reqs", "req1", "--add-tag", "smoke", "--remove-tag", "old"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Tags: []string{"auth", "old"}}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Tags: []string{"auth", "smoke"}}),
+			expectStdoutOutput: "Set request tags to auth, smoke\n",
+		},
+		{
+			name:               "remove last tag",
+			args:               []string{"reqs", "req1", "--remove-tag", "OLD"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Tags: []string{"old"}}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectStdoutOutput: "Set request tags to (none)\n",
+		},
+		{
+			name:               "add tag that is already present",
+			args:               []string{"reqs", "req1", "--add-tag", "auth"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Tags: []string{"auth"}}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Tags: []string{"auth"}}),
+			expectStderrOutput: "No change to request tags; already set to auth\n",
+		},
+		{
+			name:      "add and remove same tag",
+			args:      []string{"reqs", "req1", "--add-tag", "auth", "--remove-tag", "auth"},
+			p:         testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectErr: "tag \"auth\" cannot be both added and removed",
+		},
+		{
+			name:      "set auth flow to missing flow",
+			args:      []string{"reqs", "req1", "--auth-flow", "login"},
+			p:         testProject_singleFlowWithNSteps(2),
+			expectErr: "no flow named login exists in project",
+		},
 		{
 			name:               "remove body",
 			args:               []string{"reqs", "req1", "--remove-body"},
@@ -187,6 +523,66 @@ func Test_Reqs_Edit(t *testing.T) {
 			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
 			expectStdoutOutput: "",
 		},
+		{
+			name:               "set body file",
+			args:               []string{"reqs", "req1", "--body-file", "body.json"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", BodyFile: "body.json"}),
+			expectStdoutOutput: "Set request body file to body.json\n",
+		},
+		{
+			name:               "set body file replaces body",
+			args:               []string{"reqs", "req1", "--body-file", "body.json"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte(`{"name":"JACK NOIR"}`)}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", BodyFile: "body.json"}),
+			expectStdoutOutput: "Set request body to (none) and request body file to body.json\n",
+		},
+		{
+			name:               "set body replaces body file",
+			args:               []string{"reqs", "req1", "-d", `{"name":"JACK NOIR"}`},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", BodyFile: "body.json"}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte(`{"name":"JACK NOIR"}`)}),
+			expectStdoutOutput: "Set request body to data with length 20 and request body file to (none)\n",
+		},
+		{
+			name:               "clear body file",
+			args:               []string{"reqs", "req1", "--body-file", ""},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", BodyFile: "body.json"}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectStdoutOutput: "Set request body file to (none)\n",
+		},
+		{
+			name:               "remove body removes body file",
+			args:               []string{"reqs", "req1", "--remove-body"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", BodyFile: "body.json"}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectStdoutOutput: "Set request body file to (none)\n",
+		},
+		{
+			name:      "wrap body from file",
+			args:      []string{"reqs", "req1", "--wrap-body", "data"},
+			p:         testProject_withRequests(morc.RequestTemplate{Name: "req1", BodyFile: "body.json"}),
+			expectErr: "request template req1 reads its body from a file; it cannot be wrapped",
+		},
+		{
+			name:      "wrap body with form fields",
+			args:      []string{"reqs", "req1", "--wrap-body", "data"},
+			p:         testProject_withRequests(morc.RequestTemplate{Name: "req1", Body: []byte(`{}`), Form: []morc.FormField{{Name: "q", Value: "1"}}}),
+			expectErr: "request template req1 has form fields in its body; it cannot be wrapped",
+		},
+		{
+			name:               "remove body removes form fields",
+			args:               []string{"reqs", "req1", "--remove-body"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Form: []morc.FormField{{Name: "q", Value: "1"}}}),
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectStdoutOutput: "Set request form fields to (none)\n",
+		},
+		{
+			name:      "body file with body",
+			args:      []string{"reqs", "req1", "--body-file", "body.json", "-d", "test"},
+			p:         testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectErr: "if any flags in the group [body-file data remove-body wrap-body] are set none of the others can be",
+		},
 		{
 			name: "add header (none present)",
 			args: []string{"reqs", "req1", "-H", "User-Agent: morc/0.0.0"},
@@ -196,6 +592,7 @@ func Test_Reqs_Edit(t *testing.T) {
 				Headers: http.Header(map[string][]string{
 					"User-Agent": {"morc/0.0.0"},
 				}),
+				HeaderOrder: []string{"User-Agent"},
 			}),
 			expectStdoutOutput: "Set header User-Agent to have new value morc/0.0.0\n",
 		},
@@ -214,6 +611,7 @@ func Test_Reqs_Edit(t *testing.T) {
 					"Content-Type": {"application/json"},
 					"User-Agent":   {"morc/0.0.0"},
 				}),
+				HeaderOrder: []string{"User-Agent"},
 			}),
 			expectStdoutOutput: "Set header User-Agent to have new value morc/0.0.0\n",
 		},
@@ -249,6 +647,26 @@ func Test_Reqs_Edit(t *testing.T) {
 			}),
 			expectStdoutOutput: "Set header User-Agent to no longer exist\n",
 		},
+		{
+			name: "remove header (one present) also removes it from order",
+			args: []string{"reqs", "req1", "-r", "User-Agent"},
+			p: testProject_withRequests(morc.RequestTemplate{
+				Name: "req1",
+				Headers: http.Header(map[string][]string{
+					"Content-Type": {"application/json"},
+					"User-Agent":   {"morc/0.0.0"},
+				}),
+				HeaderOrder: []string{"User-Agent", "Content-Type"},
+			}),
+			expectP: testProject_withRequests(morc.RequestTemplate{
+				Name: "req1",
+				Headers: http.Header(map[string][]string{
+					"Content-Type": {"application/json"},
+				}),
+				HeaderOrder: []string{"Content-Type"},
+			}),
+			expectStdoutOutput: "Set header User-Agent to no longer exist\n",
+		},
 		{
 			name: "remove header (multi present)",
 			args: []string{"reqs", "req1", "-r", "User-Agent"},
@@ -380,6 +798,33 @@ func Test_Reqs_New(t *testing.T) {
 			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Method: "GET", URL: "http://example.com", Body: []byte(`{"name":"JACK NOIR"}`)}),
 			expectStdoutOutput: "Created new request req1\n",
 		},
+		{
+			name:               "description initially set",
+			args:               []string{"reqs", "--new", "req1", "--desc", "Gets the first user"},
+			p:                  morc.Project{},
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Method: "GET", URL: "http://example.com", Description: "Gets the first user"}),
+			expectStdoutOutput: "Created new request req1\n",
+		},
+		{
+			name:               "tags initially set",
+			args:               []string{"reqs", "--new", "req1", "--add-tag", "Smoke", "--add-tag", "auth", "--add-tag", "smoke"},
+			p:                  morc.Project{},
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Method: "GET", URL: "http://example.com", Tags: []string{"auth", "smoke"}}),
+			expectStdoutOutput: "Created new request req1\n",
+		},
+		{
+			name:      "tag with whitespace",
+			args:      []string{"reqs", "--new", "req1", "--add-tag", "smoke test"},
+			p:         morc.Project{},
+			expectErr: "--add-tag: tag \"smoke test\" cannot contain whitespace or commas",
+		},
+		{
+			name:               "body file initially set",
+			args:               []string{"reqs", "--new", "req1", "--body-file", "body.json"},
+			p:                  morc.Project{},
+			expectP:            testProject_withRequests(morc.RequestTemplate{Name: "req1", Method: "GET", URL: "http://example.com", BodyFile: "body.json"}),
+			expectStdoutOutput: "Created new request req1\n",
+		},
 		{
 			name: "headers initially set",
 			args: []string{"reqs", "--new", "req1", "-H", "Content-Type: application/json", "-H", "User-Agent: morc/0.0.0", "-H", "User-Agent: test/0.0.0"},
@@ -392,9 +837,69 @@ func Test_Reqs_New(t *testing.T) {
 					"Content-Type": {"application/json"},
 					"User-Agent":   {"morc/0.0.0", "test/0.0.0"},
 				}),
+				HeaderOrder: []string{"Content-Type", "User-Agent"},
+			}),
+			expectStdoutOutput: "Created new request req1\n",
+		},
+		{
+			name: "form body initially set",
+			args: []string{"reqs", "--new", "req1", "-X", "POST", "--data-urlencode", "name=JACK NOIR", "--data-urlencode", "title=Archagent & Queen's Ring", "--data-urlencode", "=a+b"},
+			p:    morc.Project{},
+			expectP: testProject_withRequests(morc.RequestTemplate{
+				Name:   "req1",
+				Method: "POST",
+				URL:    "http://example.com",
+				Form: []morc.FormField{
+					{Name: "name", Value: "JACK NOIR"},
+					{Name: "title", Value: "Archagent & Queen's Ring"},
+					{Value: "a+b"},
+				},
+				Headers: http.Header(map[string][]string{
+					"Content-Type": {"application/x-www-form-urlencoded"},
+				}),
+				HeaderOrder: []string{"Content-Type"},
+			}),
+			expectStdoutOutput: "Created new request req1\n",
+		},
+		{
+			name: "form body kept with data and vars left unencoded",
+			args: []string{"reqs", "--new", "req1", "-d", "a=1", "--data-urlencode", "user=${USER} (admin)"},
+			p:    morc.Project{},
+			expectP: testProject_withRequests(morc.RequestTemplate{
+				Name:   "req1",
+				Method: "GET",
+				URL:    "http://example.com",
+				Body:   []byte("a=1"),
+				Form:   []morc.FormField{{Name: "user", Value: "${USER} (admin)"}},
+				Headers: http.Header(map[string][]string{
+					"Content-Type": {"application/x-www-form-urlencoded"},
+				}),
+				HeaderOrder: []string{"Content-Type"},
+			}),
+			expectStdoutOutput: "Created new request req1\n",
+		},
+		{
+			name: "form body does not replace given content type",
+			args: []string{"reqs", "--new", "req1", "-H", "Content-Type: text/plain", "--data-urlencode", "hello world"},
+			p:    morc.Project{},
+			expectP: testProject_withRequests(morc.RequestTemplate{
+				Name:   "req1",
+				Method: "GET",
+				URL:    "http://example.com",
+				Form:   []morc.FormField{{Value: "hello world"}},
+				Headers: http.Header(map[string][]string{
+					"Content-Type": {"text/plain"},
+				}),
+				HeaderOrder: []string{"Content-Type"},
 			}),
 			expectStdoutOutput: "Created new request req1\n",
 		},
+		{
+			name:      "form field file does not exist",
+			args:      []string{"reqs", "--new", "req1", "--data-urlencode", "name@/nonexistent/morc/file.txt"},
+			p:         morc.Project{},
+			expectErr: "data-urlencode #1 (\"name@/nonexistent/morc/file.txt\"): read",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -461,10 +966,185 @@ func Test_Reqs_New(t *testing.T) {
 
 		assert_projectFilesInBuffersMatch(assert, expectP)
 	})
-}
 
-func Test_Reqs_Get(t *testing.T) {
-	testCases := []struct {
+	t.Run("form field file relative to project dir", func(t *testing.T) {
+		assert := assert.New(t)
+		resetReqsFlags()
+
+		p := morc.Project{}
+		expectP := testProject_withRequests(morc.RequestTemplate{
+			Name:   "req1",
+			Method: "GET",
+			URL:    "http://example.com",
+			Form:   []morc.FormField{{Name: "note", Value: "Jack Noir\n"}},
+			Headers: http.Header(map[string][]string{
+				"Content-Type": {"application/x-www-form-urlencoded"},
+			}),
+			HeaderOrder: []string{"Content-Type"},
+		})
+		expectStdoutOutput := "Created new request req1\n"
+
+		createTestProjectIO(t, p)
+		projDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(projDir, "note.txt"), []byte("Jack Noir\n"), 0644)
+		if err != nil {
+			t.Fatalf("failed to write form field file: %v", err)
+		}
+
+		args := []string{"reqs", "--new", "req1", "--data-urlencode", "note@" + morc.ProjDirVar + "/note.txt"}
+
+		// set up the root command and run
+		output, outputErr, err := runTestCommand(reqsCmd, filepath.Join(projDir, "project.json"), args)
+
+		// assert and check stdout and stderr
+		if !assert.NoError(err) {
+			return
+		}
+
+		// assertions
+
+		assert.Equal(expectStdoutOutput, output)
+		assert.Equal("", outputErr)
+
+		assert_projectFilesInBuffersMatch(assert, expectP)
+	})
+
+	t.Run("body file relative to project dir", func(t *testing.T) {
+		assert := assert.New(t)
+		resetReqsFlags()
+
+		p := morc.Project{}
+		expectP := testProject_withRequests(morc.RequestTemplate{Name: "req1", Method: "GET", URL: "http://example.com", Body: []byte(`{"name":"JACK NOIR"}`)})
+		expectStdoutOutput := "Created new request req1\n"
+
+		createTestProjectIO(t, p)
+		projDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(projDir, "body.json"), []byte(`{"name":"JACK NOIR"}`), 0644)
+		if err != nil {
+			t.Fatalf("failed to write body file: %v", err)
+		}
+
+		args := []string{"reqs", "--new", "req1", "-d", "@" + morc.ProjDirVar + "/body.json"}
+
+		// set up the root command and run
+		output, outputErr, err := runTestCommand(reqsCmd, filepath.Join(projDir, "project.json"), args)
+
+		// assert and check stdout and stderr
+		if !assert.NoError(err) {
+			return
+		}
+
+		// assertions
+
+		assert.Equal(expectStdoutOutput, output)
+		assert.Equal("", outputErr)
+
+		assert_projectFilesInBuffersMatch(assert, expectP)
+	})
+}
+
+func Test_Reqs_NewFromHistory(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		expectReq          morc.RequestTemplate
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name: "request is taken from entry",
+			args: []string{"reqs", "--new", "req1", "--from-history", "0"},
+			expectReq: morc.RequestTemplate{
+				Name:    "req1",
+				Method:  "POST",
+				URL:     "http://example.com/users",
+				Headers: http.Header{"Content-Type": {"application/json"}},
+				Body:    []byte(`{"name": "jack"}`),
+			},
+			expectStdoutOutput: "Created new request req1\n",
+		},
+		{
+			name: "flags override entry",
+			args: []string{"reqs", "--new", "req1", "--from-history", "0", "-X", "put", "-u", "http://example.com/users/${ID}", "-d", `{"name": "jane"}`},
+			expectReq: morc.RequestTemplate{
+				Name:    "req1",
+				Method:  "PUT",
+				URL:     "http://example.com/users/${ID}",
+				Headers: http.Header{"Content-Type": {"application/json"}},
+				Body:    []byte(`{"name": "jane"}`),
+			},
+			expectStdoutOutput: "Created new request req1\n",
+		},
+		{
+			name: "headers are added to those of entry",
+			args: []string{"reqs", "--new", "req1", "--from-history", "0", "-H", "Content-Type: text/plain", "-H", "X-Test: 1"},
+			expectReq: morc.RequestTemplate{
+				Name:   "req1",
+				Method: "POST",
+				URL:    "http://example.com/users",
+				Headers: http.Header{
+					"Content-Type": {"application/json", "text/plain"},
+					"X-Test":       {"1"},
+				},
+				Body:        []byte(`{"name": "jack"}`),
+				HeaderOrder: []string{"Content-Type", "X-Test"},
+			},
+			expectStdoutOutput: "Created new request req1\n",
+		},
+		{
+			name:      "entry out of range",
+			args:      []string{"reqs", "--new", "req1", "--from-history", "1"},
+			expectErr: "can't get history entry 1; 0 is the highest entry available",
+		},
+		{
+			name:      "negative entry",
+			args:      []string{"reqs", "--new", "req1", "--from-history", "-1"},
+			expectErr: "--from-history must be a non-negative history entry index",
+		},
+		{
+			name:      "without --new",
+			args:      []string{"reqs", "req1", "--from-history", "0"},
+			expectErr: "--from-history can only be used with --new/-N",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetReqsFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, testProject_withDetailedHistory())
+			// set up the root command and run
+			output, _, err := runTestCommand(reqsCmd, projFilePath, tc.args)
+
+			// assert and check stdout
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output)
+
+			expectP := testProject_withDetailedHistory()
+			expectP.History = nil
+			expectP.Templates["req1"] = tc.expectReq
+			assert_projectPersistedToBuffer(assert, expectP)
+			assert_noHistoryFileMutations(assert)
+		})
+	}
+}
+
+func Test_Reqs_Get(t *testing.T) {
+	testCases := []struct {
 		name               string
 		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
 		p                  morc.Project
@@ -508,6 +1188,42 @@ func Test_Reqs_Get(t *testing.T) {
 			p:                  testProject_singleReqWillAllPropertiesSet(),
 			expectStdoutOutput: "Content-Type: application/json\nUser-Agent: morc/0.0.0\nUser-Agent: test/0.0.0\n",
 		},
+		{
+			name:               "get headers sorted alpha",
+			args:               []string{"reqs", "req1", "--get", "headers", "--sorted", "alpha"},
+			p:                  testProject_withRequests(reqWithAuthoredHeaders()),
+			expectStdoutOutput: "Content-Type: application/json\nUser-Agent: morc/0.0.0\nX-Signature: abc\n",
+		},
+		{
+			name:               "get headers sorted by received",
+			args:               []string{"reqs", "req1", "--get", "headers", "--sorted", "received"},
+			p:                  testProject_withRequests(reqWithAuthoredHeaders()),
+			expectStdoutOutput: "X-Signature: abc\nContent-Type: application/json\nUser-Agent: morc/0.0.0\n",
+		},
+		{
+			name:               "get headers sorted by received, no recorded order",
+			args:               []string{"reqs", "req1", "--get", "headers", "--sorted", "received"},
+			p:                  testProject_singleReqWillAllPropertiesSet(),
+			expectStdoutOutput: "Content-Type: application/json\nUser-Agent: morc/0.0.0\nUser-Agent: test/0.0.0\n",
+		},
+		{
+			name:      "sorted with unknown order",
+			args:      []string{"reqs", "req1", "--get", "headers", "--sorted", "random"},
+			p:         testProject_singleReqWillAllPropertiesSet(),
+			expectErr: "--sorted must be one of 'alpha' or 'received', not \"random\"",
+		},
+		{
+			name:      "sorted with other attribute",
+			args:      []string{"reqs", "req1", "--get", "url", "--sorted", "received"},
+			p:         testProject_singleReqWillAllPropertiesSet(),
+			expectErr: "--sorted can only be used with --get headers",
+		},
+		{
+			name:      "sorted without get",
+			args:      []string{"reqs", "req1", "--sorted", "received"},
+			p:         testProject_singleReqWillAllPropertiesSet(),
+			expectErr: "--sorted can only be used with --get headers",
+		},
 		{
 			name:               "get specific header, single-valued",
 			args:               []string{"reqs", "req1", "--get-header", "Content-Type"},
@@ -526,12 +1242,42 @@ func Test_Reqs_Get(t *testing.T) {
 			p:                  testProject_singleReqWillAllPropertiesSet(),
 			expectStdoutOutput: "auth1\n",
 		},
+		{
+			name:               "get description",
+			args:               []string{"reqs", "req1", "--get", "desc"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Description: "Gets the first user"}),
+			expectStdoutOutput: "Gets the first user\n",
+		},
+		{
+			name:               "get description, none set",
+			args:               []string{"reqs", "req1", "--get", "desc"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectStdoutOutput: "(none)\n",
+		},
+		{
+			name:               "get tags",
+			args:               []string{"reqs", "req1", "--get", "tags"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", Tags: []string{"auth", "smoke"}}),
+			expectStdoutOutput: "auth\nsmoke\n",
+		},
 		{
 			name:               "get data",
 			args:               []string{"reqs", "req1", "--get", "data"},
 			p:                  testProject_singleReqWillAllPropertiesSet(),
 			expectStdoutOutput: "{\n    \"username\": \"grimAuxiliatrix\"\n}\n",
 		},
+		{
+			name:               "get body file",
+			args:               []string{"reqs", "req1", "--get", "body-file"},
+			p:                  testProject_withRequests(morc.RequestTemplate{Name: "req1", BodyFile: "body.json"}),
+			expectStdoutOutput: "body.json\n",
+		},
+		{
+			name:               "get body file, not set",
+			args:               []string{"reqs", "req1", "--get", "body-file"},
+			p:                  testProject_singleReqWillAllPropertiesSet(),
+			expectStdoutOutput: "(none)\n",
+		},
 		{
 			name:               "get captures",
 			args:               []string{"reqs", "req1", "--get", "captures"},
@@ -594,6 +1340,57 @@ func Test_Reqs_Show(t *testing.T) {
 			p:         morc.Project{},
 			expectErr: "no request named \"\" exists in project",
 		},
+		{
+			name: "json output",
+			args: []string{"reqs", "req1", "--output", "json"},
+			p:    testProject_singleReqWillAllPropertiesSet(),
+			expectStdoutOutput: `{
+  "name": "req1",
+  "method": "GET",
+  "url": "http://example.com",
+  "headers": {
+    "Content-Type": [
+      "application/json"
+    ],
+    "User-Agent": [
+      "morc/0.0.0",
+      "test/0.0.0"
+    ]
+  },
+  "body": "{\n    \"username\": \"grimAuxiliatrix\"\n}",
+  "body_file": "",
+  "captures": [
+    {
+      "var": "VAR1",
+      "spec": "offset 1,3"
+    },
+    {
+      "var": "VAR2",
+      "spec": ".key1"
+    }
+  ],
+  "auth_flow": "auth1",
+  "sendable": true
+}
+`,
+		},
+		{
+			name: "json output - nothing set",
+			args: []string{"reqs", "req1", "--output", "json"},
+			p:    testProject_withRequests(morc.RequestTemplate{Name: "req1"}),
+			expectStdoutOutput: `{
+  "name": "req1",
+  "method": "",
+  "url": "",
+  "headers": {},
+  "body": "",
+  "body_file": "",
+  "captures": [],
+  "auth_flow": "",
+  "sendable": false
+}
+`,
+		},
 		{
 			name: "req is present",
 			args: []string{"reqs", "req1"},
@@ -643,6 +1440,112 @@ func Test_Reqs_Show(t *testing.T) {
 				"\n" +
 				"AUTH FLOW: (none)\n",
 		},
+		{
+			name: "req is present, with env overrides",
+			args: []string{"reqs", "req1"},
+			p: testProject_withRequests(morc.RequestTemplate{
+				Name:   "req1",
+				Method: "GET",
+				URL:    "https://example.com",
+				EnvOverrides: map[string]morc.TemplateOverride{
+					"STAGING": {Body: []byte(`{"debug": true}`)},
+					"PROD":    {Headers: http.Header{"X-Debug": {"0"}, "X-Api-Key": {"${KEY}"}}},
+				},
+			}),
+			expectStdoutOutput: "" +
+				"GET https://example.com\n" +
+				"\n" +
+				"HEADERS: (none)\n" +
+				"\n" +
+				"BODY: (none)\n" +
+				"\n" +
+				"VAR CAPTURES: (none)\n" +
+				"\n" +
+				"AUTH FLOW: (none)\n" +
+				"\n" +
+				"OVERRIDES IN ENV PROD:\n" +
+				"X-Api-Key: ${KEY}\n" +
+				"X-Debug: 0\n" +
+				"\n" +
+				"OVERRIDES IN ENV STAGING:\n" +
+				"BODY:\n" +
+				"{\"debug\": true}\n",
+		},
+		{
+			name: "json output - env overrides",
+			args: []string{"reqs", "req1", "--output", "json"},
+			p: testProject_withRequests(morc.RequestTemplate{
+				Name:   "req1",
+				Method: "GET",
+				URL:    "https://example.com",
+				EnvOverrides: map[string]morc.TemplateOverride{
+					"PROD": {Headers: http.Header{"X-Debug": {"0"}}},
+				},
+			}),
+			expectStdoutOutput: `{
+  "name": "req1",
+  "method": "GET",
+  "url": "https://example.com",
+  "headers": {},
+  "body": "",
+  "body_file": "",
+  "captures": [],
+  "auth_flow": "",
+  "sendable": true,
+  "env_overrides": {
+    "PROD": {
+      "headers": {
+        "X-Debug": [
+          "0"
+        ]
+      },
+      "body": null
+    }
+  }
+}
+`,
+		},
+		{
+			name: "req is present, with description",
+			args: []string{"reqs", "req1"},
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"req1": {Name: "req1", Method: "GET", URL: "http://example.com", Description: "Gets the first user.\nNeeds a token."},
+				},
+			},
+			expectStdoutOutput: "" +
+				"Gets the first user.\n" +
+				"Needs a token.\n" +
+				"\n" +
+				"GET http://example.com\n" +
+				"\n" +
+				"HEADERS: (none)\n" +
+				"\n" +
+				"BODY: (none)\n" +
+				"\n" +
+				"VAR CAPTURES: (none)\n" +
+				"\n" +
+				"AUTH FLOW: (none)\n",
+		},
+		{
+			name: "req is present, with body file",
+			args: []string{"reqs", "req1"},
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"req1": {Name: "req1", BodyFile: "body.json"},
+				},
+			},
+			expectStdoutOutput: "" +
+				"(no-method) (no-url)\n" +
+				"\n" +
+				"HEADERS: (none)\n" +
+				"\n" +
+				"BODY: (from file body.json)\n" +
+				"\n" +
+				"VAR CAPTURES: (none)\n" +
+				"\n" +
+				"AUTH FLOW: (none)\n",
+		},
 		{
 			name: "req is present, with body",
 			args: []string{"reqs", "req1"},
@@ -665,6 +1568,30 @@ func Test_Reqs_Show(t *testing.T) {
 				"\n" +
 				"AUTH FLOW: (none)\n",
 		},
+		{
+			name: "req is present, with form fields",
+			args: []string{"reqs", "req1"},
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"req1": {Name: "req1", Body: []byte("a=1"), Form: []morc.FormField{{Name: "user", Value: "${USER} & co"}, {Value: "50% off"}}},
+				},
+			},
+			expectStdoutOutput: "" +
+				"(no-method) (no-url)\n" +
+				"\n" +
+				"HEADERS: (none)\n" +
+				"\n" +
+				"BODY:\n" +
+				"a=1\n" +
+				"\n" +
+				"FORM:\n" +
+				"user=${USER} & co\n" +
+				"=50% off\n" +
+				"\n" +
+				"VAR CAPTURES: (none)\n" +
+				"\n" +
+				"AUTH FLOW: (none)\n",
+		},
 		{
 			name: "req is present, with headers",
 			args: []string{"reqs", "req1"},
@@ -739,6 +1666,347 @@ func Test_Reqs_Show(t *testing.T) {
 	}
 }
 
+func Test_Reqs_ExportHTTP(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F or the export file; they are automatically set
+		p                  morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStdoutOutput string // set with expected output to stdout
+		expectFile         string
+	}{
+		{
+			name:      "req not present",
+			args:      []string{"reqs", "test"},
+			p:         morc.Project{},
+			expectErr: "no request named test exists in project",
+		},
+		{
+			name: "export as-is",
+			args: []string{"reqs", "REQ1"},
+			p: testProject_withRequests(morc.RequestTemplate{
+				Name:    "req1",
+				Method:  "POST",
+				URL:     "https://example.com/${ID}",
+				Headers: http.Header{"Content-Type": {"application/json"}},
+				Body:    []byte(`{"name": "${NAME}"}`),
+			}),
+			expectStdoutOutput: "Exported request req1 to $EXPORT_FILE$\n",
+			expectFile: "# @name req1\n" +
+				"POST https://example.com/${ID}\n" +
+				"Content-Type: application/json\n" +
+				"\n" +
+				`{"name": "${NAME}"}` + "\n",
+		},
+		{
+			name: "export with editor vars",
+			args: []string{"reqs", "req1", "--editor-vars"},
+			p: testProject_withRequests(morc.RequestTemplate{
+				Name:   "req1",
+				Method: "GET",
+				URL:    "https://example.com/${ID}",
+			}),
+			expectStdoutOutput: "Exported request req1 to $EXPORT_FILE$\n",
+			expectFile:         "# @name req1\nGET https://example.com/{{ID}}\n",
+		},
+		{
+			name:      "export with modification flags",
+			args:      []string{"reqs", "req1", "-X", "POST"},
+			p:         testProject_nRequests(1),
+			expectErr: "--export-http cannot be given with flags that modify a request",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetReqsFlags()
+
+			exportFile := filepath.Join(t.TempDir(), "req.http")
+			args := append(tc.args, "--export-http", exportFile)
+
+			// create project and dump config to a temp dir
+			profFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, _, err := runTestCommand(reqsCmd, profFilePath, args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			}
+			if tc.expectErr != "" {
+				t.Fatalf("expected returned error to contain %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(strings.ReplaceAll(tc.expectStdoutOutput, "$EXPORT_FILE$", exportFile), output)
+
+			data, err := os.ReadFile(exportFile)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectFile, string(data))
+
+			assert_noProjectMutations(assert)
+		})
+	}
+}
+
+func Test_Reqs_EditorVarsWithoutExport(t *testing.T) {
+	resetReqsFlags()
+
+	profFilePath := createTestProjectIO(t, testProject_nRequests(1))
+	_, _, err := runTestCommand(reqsCmd, profFilePath, []string{"reqs", "req1", "--editor-vars"})
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "--editor-vars can only be used with --export-http")
+	}
+}
+
+func Test_Reqs_ResolveVars(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F, it is automatically set
+		p                  morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:      "req not present",
+			args:      []string{"reqs", "test", "--resolve-vars"},
+			p:         morc.Project{},
+			expectErr: "no request named test exists in project",
+		},
+		{
+			name:      "missing req",
+			args:      []string{"reqs", "--resolve-vars"},
+			p:         testProject_nRequests(1),
+			expectErr: "missing name of REQ to resolve vars in",
+		},
+		{
+			name:      "with modification flags",
+			args:      []string{"reqs", "req1", "--resolve-vars", "-X", "POST"},
+			p:         testProject_nRequests(1),
+			expectErr: "--resolve-vars cannot be given with flags that modify a request",
+		},
+		{
+			name: "vars from current env with default fallback",
+			args: []string{"reqs", "REQ1", "--resolve-vars"},
+			p: func() morc.Project {
+				p := testProject_withRequests(morc.RequestTemplate{
+					Name:    "req1",
+					Method:  "POST",
+					URL:     "https://${HOST}/users/${ID}",
+					Headers: http.Header{"Content-Type": {"application/json"}},
+					Body:    []byte(`{"name": "${NAME}"}`),
+				})
+				p.Vars = testVarStore("PROD", map[string]map[string]string{
+					"":     {"HOST": "localhost", "ID": "8", "NAME": "vriska"},
+					"PROD": {"HOST": "example.com"},
+				})
+				return p
+			}(),
+			expectStdoutOutput: "------------------- REQUEST -------------------\n" +
+				"Request URI: https://example.com/users/8\n" +
+				"\n" +
+				"POST /users/8 HTTP/1.1\r\n" +
+				"Host: example.com\r\n" +
+				"User-Agent: Go-http-client/1.1\r\n" +
+				"Content-Length: 18\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Accept-Encoding: gzip\r\n" +
+				"\r\n" +
+				`{"name": "vriska"}` + "\n" +
+				"----------------- END REQUEST -----------------\n",
+		},
+		{
+			name: "env override of current env is applied",
+			args: []string{"reqs", "req1", "--resolve-vars"},
+			p: func() morc.Project {
+				p := testProject_withRequests(morc.RequestTemplate{
+					Name:    "req1",
+					Method:  "POST",
+					URL:     "https://example.com/users",
+					Headers: http.Header{"Content-Type": {"application/json"}},
+					Body:    []byte(`{"name": "vriska"}`),
+					EnvOverrides: map[string]morc.TemplateOverride{
+						"PROD":    {Headers: http.Header{"X-Api-Key": {"${KEY}"}}},
+						"STAGING": {Body: []byte(`{}`)},
+					},
+				})
+				p.Vars = testVarStore("PROD", map[string]map[string]string{
+					"":     {"KEY": ""},
+					"PROD": {"KEY": "8675309"},
+				})
+				return p
+			}(),
+			expectStdoutOutput: "------------------- REQUEST -------------------\n" +
+				"Request URI: https://example.com/users\n" +
+				"\n" +
+				"POST /users HTTP/1.1\r\n" +
+				"Host: example.com\r\n" +
+				"User-Agent: Go-http-client/1.1\r\n" +
+				"Content-Length: 18\r\n" +
+				"Content-Type: application/json\r\n" +
+				"X-Api-Key: 8675309\r\n" +
+				"Accept-Encoding: gzip\r\n" +
+				"\r\n" +
+				`{"name": "vriska"}` + "\n" +
+				"----------------- END REQUEST -----------------\n",
+		},
+		{
+			name: "undefined var",
+			args: []string{"reqs", "req1", "--resolve-vars"},
+			p: testProject_withRequests(morc.RequestTemplate{
+				Name:   "req1",
+				Method: "GET",
+				URL:    "https://example.com/${ID}",
+			}),
+			expectErr: "ID",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetReqsFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+
+			// set up the root command and run
+			output, _, err := runTestCommand(reqsCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if tc.expectErr != "" {
+				if assert.Error(err) {
+					assert.Contains(err.Error(), tc.expectErr)
+				}
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output)
+		})
+	}
+}
+
+func Test_Reqs_ImportHTTP(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F or the import file; they are automatically set
+		p                  morc.Project
+		file               string
+		expectP            morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStdoutOutput string // set with expected output to stdout
+		expectStderrOutput string // set with expected output to stderr
+	}{
+		{
+			name: "import into empty project",
+			args: []string{"reqs"},
+			p:    morc.Project{},
+			file: "# @name login\n" +
+				"POST https://example.com/login\n" +
+				"Content-Type: application/json\n" +
+				"\n" +
+				"{\"user\": \"{{USER}}\"}\n" +
+				"###\n" +
+				"GET https://example.com/me\n",
+			expectP: testProject_withRequests(
+				morc.RequestTemplate{
+					Name:        "login",
+					Method:      "POST",
+					URL:         "https://example.com/login",
+					Headers:     http.Header{"Content-Type": {"application/json"}},
+					HeaderOrder: []string{"Content-Type"},
+					Body:        []byte(`{"user": "${USER}"}`),
+				},
+				morc.RequestTemplate{Name: "request-2", Method: "GET", URL: "https://example.com/me"},
+			),
+			expectStdoutOutput: "Created 2 request templates from $IMPORT_FILE$\n",
+		},
+		{
+			name: "malformed and existing requests are skipped",
+			args: []string{"reqs"},
+			p:    testProject_nRequests(1),
+			file: "# @name req1\n" +
+				"GET https://example.com/1\n" +
+				"###\n" +
+				"GET https://example.com/2 extra\n" +
+				"###\n" +
+				"# @name req2\n" +
+				"GET https://example.com/3\n",
+			expectP: testProject_withRequests(
+				testProject_nRequests(1).Templates["req1"],
+				morc.RequestTemplate{Name: "req2", Method: "GET", URL: "https://example.com/3"},
+			),
+			expectStdoutOutput: "Created 1 request template from $IMPORT_FILE$\n",
+			expectStderrOutput: "" +
+				"WARN: skipping request at line 4: request line is not in the form METHOD URL: \"GET https://example.com/2 extra\"\n" +
+				"WARN: skipping request: request named req1 already exists in project\n",
+		},
+		{
+			name:      "import with REQ",
+			args:      []string{"reqs", "req1"},
+			p:         testProject_nRequests(1),
+			expectErr: `unknown positional argument "req1"`,
+		},
+		{
+			name:      "import with modification flags",
+			args:      []string{"reqs", "-X", "POST"},
+			p:         testProject_nRequests(1),
+			expectErr: "--import-http cannot be given with flags that modify a request",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetReqsFlags()
+
+			importFile := filepath.Join(t.TempDir(), "reqs.http")
+			if err := os.WriteFile(importFile, []byte(tc.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+			args := append(tc.args, "--import-http", importFile)
+
+			// create project and dump config to a temp dir
+			profFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(reqsCmd, profFilePath, args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			}
+			if tc.expectErr != "" {
+				t.Fatalf("expected returned error to contain %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(strings.ReplaceAll(tc.expectStdoutOutput, "$IMPORT_FILE$", importFile), output)
+			assert.Equal(tc.expectStderrOutput, outputErr)
+
+			assert_projectPersistedToBuffer(assert, tc.expectP)
+		})
+	}
+}
+
 func Test_Reqs_List(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -781,6 +2049,105 @@ func Test_Reqs_List(t *testing.T) {
 			}},
 			expectStdoutOutput: "GET req1\n??? req2\n",
 		},
+		{
+			name: "filter by tag",
+			args: []string{"reqs", "--tag", "AUTH"},
+			p: morc.Project{Templates: map[string]morc.RequestTemplate{
+				"login":  {Name: "login", Method: "POST", Tags: []string{"auth"}},
+				"logout": {Name: "logout", Method: "DELETE", Tags: []string{"auth", "smoke"}},
+				"users":  {Name: "users", Method: "GET", Tags: []string{"smoke"}},
+			}},
+			expectStdoutOutput: "POST   login\nDELETE logout\n",
+		},
+		{
+			name: "filter by tag, none have it",
+			args: []string{"reqs", "--tag", "admin"},
+			p: morc.Project{Templates: map[string]morc.RequestTemplate{
+				"users": {Name: "users", Method: "GET", Tags: []string{"smoke"}},
+			}},
+			expectStdoutOutput: "(none)\n",
+		},
+		{
+			name: "filter by tag, json output",
+			args: []string{"reqs", "--tag", "smoke", "--output", "json"},
+			p: morc.Project{Templates: map[string]morc.RequestTemplate{
+				"login": {Name: "login", Method: "POST", URL: "http://example.com", Tags: []string{"auth"}},
+				"users": {Name: "users", Method: "GET", URL: "http://example.com", Tags: []string{"smoke"}},
+			}},
+			expectStdoutOutput: `[
+  {
+    "name": "users",
+    "method": "GET",
+    "sendable": true,
+    "tags": [
+      "smoke"
+    ]
+  }
+]
+`,
+		},
+		{
+			name:      "tag given with request name",
+			args:      []string{"reqs", "req1", "--tag", "smoke"},
+			p:         testProject_nRequests(1),
+			expectErr: "--tag can only be used when listing requests",
+		},
+		{
+			name: "descriptions are shown after names",
+			args: []string{"reqs"},
+			p: morc.Project{Templates: map[string]morc.RequestTemplate{
+				"get-user":    {Name: "get-user", Method: "GET", Description: "Gets a user"},
+				"delete-user": {Name: "delete-user", Method: "DELETE", Description: "Deletes a user.\nCannot be undone."},
+				"x":           {Name: "x", Method: "GET"},
+			}},
+			expectStdoutOutput: "" +
+				"DELETE delete-user  Deletes a user.\n" +
+				"GET    get-user     Gets a user\n" +
+				"GET    x\n",
+		},
+		{
+			name: "json output with description",
+			args: []string{"reqs", "--output", "json"},
+			p: morc.Project{Templates: map[string]morc.RequestTemplate{
+				"req1": {Name: "req1", Method: "GET", URL: "http://example.com", Description: "Gets a user"},
+			}},
+			expectStdoutOutput: `[
+  {
+    "name": "req1",
+    "method": "GET",
+    "sendable": true,
+    "description": "Gets a user"
+  }
+]
+`,
+		},
+		{
+			name: "json output",
+			args: []string{"reqs", "--output", "json"},
+			p: morc.Project{Templates: map[string]morc.RequestTemplate{
+				"req2": {Name: "req2"},
+				"req1": {Name: "req1", Method: "GET", URL: "http://example.com"},
+			}},
+			expectStdoutOutput: `[
+  {
+    "name": "req1",
+    "method": "GET",
+    "sendable": true
+  },
+  {
+    "name": "req2",
+    "method": "",
+    "sendable": false
+  }
+]
+`,
+		},
+		{
+			name:               "json output - no reqs, quiet mode still prints",
+			args:               []string{"reqs", "--output", "json", "-q"},
+			p:                  morc.Project{},
+			expectStdoutOutput: "[]\n",
+		},
 		{
 			name: "one flow present - req not sendable",
 			args: []string{"flows"},
@@ -800,6 +2167,8 @@ func Test_Reqs_List(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			assert := assert.New(t)
 			resetReqsFlags()
+			// some cases run the flows command
+			resetFlowsFlags()
 
 			// create project and dump config to a temp dir
 			projFilePath := createTestProjectIO(t, tc.p)
@@ -828,6 +2197,20 @@ func Test_Reqs_List(t *testing.T) {
 	}
 }
 
+func reqWithAuthoredHeaders() morc.RequestTemplate {
+	return morc.RequestTemplate{
+		Name:   "req1",
+		Method: "POST",
+		URL:    "http://example.com",
+		Headers: http.Header{
+			"Content-Type": {"application/json"},
+			"User-Agent":   {"morc/0.0.0"},
+			"X-Signature":  {"abc"},
+		},
+		HeaderOrder: []string{"X-Signature", "Content-Type"},
+	}
+}
+
 func resetReqsFlags() {
 	flags.New = ""
 	flags.Delete = ""
@@ -835,12 +2218,28 @@ func resetReqsFlags() {
 	flags.GetHeader = ""
 	flags.RemoveHeaders = nil
 	flags.BRemoveBody = false
+	flags.WrapBody = ""
+	flags.AuthFlow = ""
+	flags.Description = ""
+	flags.AddTags = nil
+	flags.RemoveTags = nil
+	flags.Tag = ""
+	flags.Env = ""
+	flags.Copy = ""
 	flags.BodyData = ""
+	flags.DataURLEncode = nil
 	flags.Headers = nil
 	flags.Method = ""
 	flags.URL = ""
 	flags.Name = ""
 	flags.BForce = false
+	flags.ListOutput = "text"
+	flags.HeaderOrder = "alpha"
+	flags.ExportHTTP = ""
+	flags.ImportHTTP = ""
+	flags.FromHistory = -1
+	flags.BEditorVars = false
+	flags.BResolveVars = false
 	flags.BQuiet = false
 
 	reqsCmd.Flags().VisitAll(func(fl *pflag.Flag) {
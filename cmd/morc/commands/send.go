@@ -1,9 +1,17 @@
 package commands
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/dekarrin/morc"
 	"github.com/dekarrin/morc/cmd/morc/cmdio"
@@ -11,16 +19,116 @@ import (
 )
 
 var sendCmd = &cobra.Command{
-	Use: "send REQ",
+	Use: "send [REQ]",
 	Annotations: map[string]string{
 		annotationKeyHelpUsages: "" +
-			"send REQ [-k] [-V VAR=VALUE]... [output-flags]",
+			"send --url URL [-X METHOD] [-H 'KEY: VALUE']... [-d DATA] [--data-urlencode FIELD]... [--set-json FIELD]... [--auth-flow FLOW] [-k46] [--http1 | --http2] [--local-addr ADDR] [--resolve HOST:PORT:ADDR]... [--cert FILE [--key FILE]] [--cacert FILE] [--timeout DURATION] [-V VAR=VALUE]... [--prompt] [-C NAME:SPEC]... [--cookie COOKIE]... [--no-save-captures] [--no-history] [--no-save-session | --record] [--ignore-body-capture-errors-but-fail-exit] [--no-substitute-headers] [--dump-state [--show-secrets]] [--assert-header 'KEY: VALUE']... [--assert-header-present KEY]... [--header-match MODE] [--assert-status CODE] [--assert-body-contains TEXT]... [--assert EXPR]... [--retry-on-body-match TEXT [--retry-max-attempts N] [--retry-backoff DURATION]] [-o FILE] [output-flags]\n" +
+			"send REQ [-X METHOD] [--set-json FIELD]... [-k46] [--http1 | --http2] [--local-addr ADDR] [--resolve HOST:PORT:ADDR]... [--cert FILE [--key FILE]] [--cacert FILE] [--timeout DURATION] [-V VAR=VALUE]... [--prompt] [-C NAME:SPEC]... [--cookie COOKIE]... [--no-save-captures] [--ignore-body-capture-errors-but-fail-exit] [--no-substitute-headers] [--dump-state [--show-secrets]] [--assert-header 'KEY: VALUE']... [--assert-header-present KEY]... [--header-match MODE] [--assert-status CODE] [--assert-body-contains TEXT]... [--assert EXPR]... [--retry-on-body-match TEXT [--retry-max-attempts N] [--retry-backoff DURATION]] [-o FILE] [output-flags]\n" +
+			"send --tag TAG [-X METHOD] [-k46] [--http1 | --http2] [--local-addr ADDR] [--resolve HOST:PORT:ADDR]... [--cert FILE [--key FILE]] [--cacert FILE] [--timeout DURATION] [-V VAR=VALUE]... [--prompt] [--cookie COOKIE]... [--no-save-captures] [--assert-header 'KEY: VALUE']... [--assert-header-present KEY]... [--header-match MODE] [--assert-status CODE] [--assert-body-contains TEXT]... [--assert EXPR]... [output-flags]",
 	},
 	Short: "Send a request defined in a template (REQ)",
 	Long: "Send a request by building it from a request template (REQ) stored in the project. All variables are " +
 		"filled prior to sending and the request is sent to the remote server. The response is then printed. Any data " +
-		"captured from the response is automatically stored to their respective variables.",
-	Args:    cobra.ExactArgs(1),
+		"captured from the response is automatically stored to their respective variables.\n\n" +
+		"A request can also be sent without a template by omitting REQ and giving --url along with any of --method/-X, " +
+		"--header/-H, --data/-d, --data-urlencode, and --auth-flow, which work the same as they do for 'morc reqs " +
+		"--new'. The request is built in memory and sent exactly as though it were a template in the project, using " +
+		"the project's variables and cookies and recording history as normal, but it is not saved as a template. " +
+		"Captures for it can be given with --capture-override/-C.\n\n" +
+		"The method of REQ can be overridden for the current send only with --method/-X. This is useful with HEAD to " +
+		"check only the headers of a response without downloading its body. A response to a HEAD request never has " +
+		"a body, so the Content-Length it gives is printed in place of one, and any capture from the body fails.\n\n" +
+		"To send a group of requests at once, give --tag instead of REQ. Every request template with the tag is sent " +
+		"in alphabetical order by name, each exactly as it would be if it were given as REQ, along with any other " +
+		"flags given. A failed request does not stop the rest from being sent; once all of them have been, a summary " +
+		"of which passed and which failed is printed, and morc exits with a non-zero status if any failed. Combined " +
+		"with the assertion flags below, this can be used to run a simple test suite.\n\n" +
+		"If REQ has an auth flow set on it with 'morc reqs REQ --auth-flow FLOW', that flow is executed first and " +
+		"REQ is sent afterwards with any variables the flow captured, even if --no-save-captures is given. Responses " +
+		"to the steps of the auth flow are not printed. If any step of the auth flow fails, REQ is not sent and morc " +
+		"exits with a non-zero status; steps of the flow that were already sent are still recorded. Auth flows are " +
+		"only run by send, and not for requests sent as steps of a flow with exec.\n\n" +
+		"If a variable that the request uses is not defined, the send normally fails. With --prompt, morc instead " +
+		"asks for the value of each one on the terminal before the request is sent, the same as if it were given " +
+		"with --var/-V. The values are used for this send only and are not saved to the project.\n\n" +
+		"Additional captures can be given for the current send only with --capture-override/-C. A capture override " +
+		"with the same name as a capture in the template replaces it for this send; the template itself is not " +
+		"modified. Values captured by overrides are still stored to their variables just like any other capture, " +
+		"unless --no-save-captures is given, in which case no captured values are saved to the project.\n\n" +
+		"Whether the request is recorded in history and whether cookies from the response are saved to the " +
+		"session normally follow the project's settings, as set with 'morc hist --on' and 'morc cookies --on'. " +
+		"For a one-off send such as a probe, --no-history keeps the request out of history and " +
+		"--no-save-session keeps its cookies out of the session regardless of those settings. Conversely, --record " +
+		"records both even if the project does not. These also apply to any auth flow run before REQ.\n\n" +
+		"Normally, if any capture cannot be taken from the response, morc stops with an error before the response is " +
+		"printed or recorded. If --ignore-body-capture-errors-but-fail-exit is given, the response is instead printed " +
+		"and recorded in history as normal and the captures that did succeed are saved; every capture that failed is " +
+		"then reported and morc exits with a non-zero status.\n\n" +
+		"If a header must contain text that looks like a variable, such as '${X}' being passed through to another " +
+		"templating system, --no-substitute-headers sends the keys and values of all headers exactly as they are. " +
+		"Variables in the URL and body are still substituted.\n\n" +
+		"To help debug what is being persisted between requests, --dump-state prints the state of the client after " +
+		"the request is sent to stderr. This is the cookies and captured variables exactly as they would be saved to " +
+		"a oneshot state file. Values of secret variables and of variables whose names look like they hold secrets, " +
+		"such as TOKEN or PASSWORD, are masked wherever they appear unless --show-secrets is also given.\n\n" +
+		"The headers of the response can be checked with --assert-header, given as 'KEY: VALUE', and " +
+		"--assert-header-present, given as just the KEY of a header that must be in the response. Both may be given " +
+		"multiple times. Header keys are matched case-insensitively. By default, --assert-header requires the value of " +
+		"the header to be exactly VALUE; --header-match can be used to instead require that it contains VALUE with " +
+		"'contains' or matches VALUE as a regular expression with 'regex'. If a header has multiple values, only one " +
+		"of them needs to match. Assertions are checked after the response is output, and if any fail, each failure " +
+		"is reported along with the actual value of the header and morc exits with a non-zero status. The response is " +
+		"still recorded in history and any captures are still saved.\n\n" +
+		"For lightweight smoke tests, --assert-status requires the response to have status CODE, and " +
+		"--assert-body-contains requires its body to contain TEXT; it may be given multiple times. These are checked " +
+		"along with any header assertions, and every assertion that fails is reported together.\n\n" +
+		"To send only a few fields of a resource, such as for a PATCH or PUT, --set-json sets a field in a JSON " +
+		"object body. FIELD is given as KEY=VALUE, and may be given multiple times. A VALUE of true, false, or null, or " +
+		"one that is a number, is sent as that JSON type and any other VALUE is sent as a string; to give the exact " +
+		"JSON of the value instead, such as '\"13\"' to send a number as a string or an array or object, use " +
+		"KEY:=JSON. If the request already has a body, it must be a JSON object, and the fields are set in it, " +
+		"replacing any that are already there; otherwise, a new object is sent. Variables are substituted in keys " +
+		"and values before their types are worked out. The Content-Type header is set to " + morc.JSONContentType + " " +
+		"if it is not otherwise set. --set-json cannot be used with --data-urlencode.\n\n" +
+		"Values in a JSON body can be checked with --assert, which may be given multiple times. EXPR is in the form " +
+		"'PATH OP VALUE', such as '.count == 5' or '.data.id != null'. PATH is a path into the body in the same format " +
+		"as a JSON capture, or '.' for the entire body. OP is one of ==, !=, <, <=, >, or >=, and VALUE is a JSON " +
+		"literal such as 5, \"text\", true, or null. == and != compare any two JSON values exactly, and a PATH that " +
+		"does not exist is treated as null by them; the other operators compare only two numbers or two strings. If " +
+		"only PATH is given, the assertion checks that it exists. A failed --assert is reported with the value that " +
+		"was found and makes morc exit with a non-zero status, just like any other assertion.\n\n" +
+		"To see where the time of a request is spent, --timings prints how long the DNS lookup, connecting, the TLS " +
+		"handshake, and receiving the first byte of the response took, along with the total time. A phase that did " +
+		"not happen, such as the DNS lookup when the URL has an IP address, is shown as 0s.\n\n" +
+		"Redirects in the response are followed automatically, and normally only the final response is shown. To see " +
+		"each redirect that was followed, such as when debugging a redirect loop in a login, give --show-redirects. " +
+		"Every hop is listed before the response with the request that was made, the status it got, and the " +
+		"Location it redirected to.\n\n" +
+		"For endpoints that respond before a result is ready, --retry-on-body-match sends the request again for as " +
+		"long as the body of the response contains TEXT, up to --retry-max-attempts times in total (5 by default). " +
+		"Before each retry, morc waits for --retry-backoff (1s by default), doubling the wait each time. Only the last " +
+		"response is output, checked against any assertions, and captured from, and it is used even if it still " +
+		"contains TEXT once all attempts are used up. Retry conditions are OR'd together, so as further conditions are " +
+		"added, a response that meets any one of them is retried.\n\n" +
+		"The body of the response can be written to a file instead of being printed with --output/-o. If the " +
+		"request has no captures and is not retried with --retry-on-body-match, the body is streamed to the file as " +
+		"it is received without being held in memory, which allows large downloads. Otherwise, it is read in full " +
+		"first so that it can be checked, and then written to the file. A streamed body is not kept in history. " +
+		"The body is written to a temporary file next to FILE that only replaces FILE once the request succeeds, so " +
+		"a failed request leaves any existing FILE as it was.\n\n" +
+		"For use in scripts, --quiet/-q outputs only the body of the response exactly as it was received, like curl " +
+		"-s; the status line, section banners, and notes such as '(no response body)' are all left out, and nothing " +
+		"is output at all if the response has no body. It cannot be combined with other output flags.\n\n" +
+		"To see everything about the exchange at once, like with curl -v, give -v/--verbose. It is the same as " +
+		"giving --request, --headers, and --captures together.\n\n" +
+		"For a quick summary of the response, --stats prints a line such as '200 OK · 1.2 KiB · 342ms' after it. " +
+		"In formats 'line' and 'sr', the summary is instead given as key=value pairs on a line starting with STATS.\n\n" +
+		"For use by other tools, --format json prints a single JSON object once the response is received. It has the " +
+		"method, URL, and headers of the request that was sent, the status, headers, and body of the response, the " +
+		"values of all captures, the timings of each phase in milliseconds, and any redirects that were followed. A body that is not valid UTF-8 is " +
+		"given in base64, with body_encoding set to 'base64'. The body is null if --no-body is given or if it is " +
+		"written to a file with --output/-o. --stats cannot be used with format 'json'.",
+	Args:    cobra.MaximumNArgs(1),
 	GroupID: "sending",
 	RunE: func(cmd *cobra.Command, posArgs []string) error {
 		var args sendArgs
@@ -33,52 +141,458 @@ var sendCmd = &cobra.Command{
 		io := cmdio.From(cmd)
 		io.Quiet = flags.BQuiet
 
-		return invokeSend(io, args.projFile, args.req, args.oneTimeVars, args.skipVerify, args.prefixOverride, args.outputCtrl)
+		// every prompt reads from the same reader so that input read ahead
+		// for one request is not lost to the ones after it
+		if args.opts.prompt {
+			args.opts.promptLines = bufio.NewScanner(io.In)
+		}
+
+		if args.tag != "" {
+			return invokeSendTagged(io, args.projFile, args.tag, func(p *morc.Project, reqName string) error {
+				return sendRequest(io, p, reqName, args.adhoc, args.opts)
+			})
+		}
+
+		return invokeSend(io, args.projFile, args.req, args.adhoc, args.opts)
 	},
 }
 
 func init() {
 	sendCmd.PersistentFlags().StringVarP(&flags.ProjectFile, "project-file", "F", morc.DefaultProjectPath, "Use `FILE` for project data instead of "+morc.DefaultProjectPath+".")
 	sendCmd.PersistentFlags().StringArrayVarP(&flags.Vars, "var", "V", []string{}, "Temporarily set a variable's value for the current request only. Overrides any value currently in the store. The argument to this flag must be in `VAR=VALUE` format.")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BPrompt, "prompt", "", false, "Ask on the terminal for the value of each variable the request uses that is not defined, and use it for the current request only.")
 	sendCmd.PersistentFlags().BoolVarP(&flags.BInsecure, "insecure", "k", false, "Disable all verification of server certificates when sending requests over TLS (HTTPS)")
 	sendCmd.PersistentFlags().StringVarP(&flags.VarPrefix, "var-prefix", "p", "", "Temporarily override the prefix used to identify variables in the request template for the current request only. Only variables in the request template that start with `PREFIX` will be interpreted as variables.")
-	sendCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all output except for the response body, which is output exactly as it was received.")
+	sendCmd.PersistentFlags().StringArrayVarP(&flags.CaptureOverrides, "capture-override", "C", []string{}, "Capture a variable from the response for the current request only, replacing any capture in the template with the same name. The argument to this flag must be in `NAME:SPEC` format. Can be given multiple times.")
+	sendCmd.PersistentFlags().StringArrayVarP(&flags.Cookies, "cookie", "", []string{}, "Send the request with a cookie as though it had been set by a prior response. `COOKIE` must be in 'NAME=VALUE' format and may be followed by Set-Cookie attributes such as ';domain=example.com'. Without a domain, the cookie is treated as set by the request's URL. Can be given multiple times.")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BNoSaveCaptures, "no-save-captures", "", false, "Do not save values captured from the response to the project's variables.")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BNoHistory, "no-history", "", false, "Do not record the request in history, even if the project records history.")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BNoSaveSession, "no-save-session", "", false, "Do not save cookies from the response to the session, even if the project records the session.")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BRecord, "record", "", false, "Record the request in history and save cookies from the response to the session, even if the project does not record them.")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BDeferCaptureErrors, "ignore-body-capture-errors-but-fail-exit", "", false, "Print and record the response even if captures from it fail, then report the failed captures and exit with a non-zero status.")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BNoSubstituteHeaders, "no-substitute-headers", "", false, "Send header keys and values exactly as they are, without substituting any variables in them.")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BDumpState, "dump-state", "", false, "After sending, print the cookies and variables held by the client to stderr as they would be saved to a state file.")
+	sendCmd.PersistentFlags().StringArrayVarP(&flags.AssertHeaders, "assert-header", "", []string{}, "Fail if the response does not have a header with the given value. The argument must be in `'KEY: VALUE'` format. How the value is compared is set with --header-match. Can be given multiple times.")
+	sendCmd.PersistentFlags().StringArrayVarP(&flags.AssertHeadersPresent, "assert-header-present", "", []string{}, "Fail if the response does not have a header with key `KEY`. Can be given multiple times.")
+	sendCmd.PersistentFlags().IntVarP(&flags.AssertStatus, "assert-status", "", 0, "Fail if the status code of the response is not `CODE`.")
+	sendCmd.PersistentFlags().StringArrayVarP(&flags.AssertBodyContains, "assert-body-contains", "", []string{}, "Fail if the body of the response does not contain `TEXT`. Can be given multiple times.")
+	sendCmd.PersistentFlags().StringArrayVarP(&flags.Asserts, "assert", "", []string{}, "Fail if a value in the JSON body of the response does not meet `EXPR`, such as '.count == 5'. Can be given multiple times.")
+	sendCmd.PersistentFlags().StringVarP(&flags.HeaderMatch, "header-match", "", "exact", "Compare header values in --assert-header using `MODE`, which must be one of 'exact', 'contains', or 'regex'.")
+	sendCmd.PersistentFlags().StringVarP(&flags.RetryBodyMatch, "retry-on-body-match", "", "", "Send the request again while the body of the response contains `TEXT`, waiting longer before each retry.")
+	sendCmd.PersistentFlags().IntVarP(&flags.RetryMaxAttempts, "retry-max-attempts", "", morc.DefaultRetryMaxAttempts, "Send the request at most `N` times in total when retrying it with --retry-on-body-match.")
+	sendCmd.PersistentFlags().StringVarP(&flags.RetryBackoff, "retry-backoff", "", "1s", "Wait `DURATION` before the first retry of --retry-on-body-match. The wait is doubled for each retry after it.")
+	sendCmd.PersistentFlags().StringVarP(&flags.OutputFile, "output", "o", "", "(Output flag) Write the body of the response to `FILE` instead of printing it. Unless the body is needed for a capture, assertion, or retry, it is written as it is received, and the request timeout then only limits the wait for the response to start, not for the whole body to arrive.")
+	sendCmd.PersistentFlags().StringVarP(&flags.URL, "url", "u", "", "Send a request to `URL` without using a template. Required if REQ is not given.")
+	sendCmd.PersistentFlags().StringVarP(&flags.Method, "method", "X", "GET", "Set the method of a request sent without a template to `METHOD`. If REQ is given, its method is instead overridden for the current request only, such as with HEAD to get only the headers of its response.")
+	sendCmd.PersistentFlags().StringArrayVarP(&flags.Headers, "header", "H", []string{}, "Add a header to a request sent without a template. Format is `KEY:VALUE`. May be given multiple times.")
+	sendCmd.PersistentFlags().StringVarP(&flags.BodyData, "data", "d", "", "Add the given `DATA` as a body to a request sent without a template; prefix with '@' to instead interperet DATA as a filename that body data is to be read from.")
+	sendCmd.PersistentFlags().StringArrayVarP(&flags.DataURLEncode, "data-urlencode", "", []string{}, "URL-encode `FIELD` and add it to the body of a request sent without a template, the same as with 'morc reqs'. May be given multiple times.")
+	sendCmd.PersistentFlags().StringArrayVarP(&flags.SetJSON, "set-json", "", []string{}, "Set a field in the JSON object body of the request, creating the body if there is not one. `FIELD` must be in 'KEY=VALUE' format, where a VALUE that is a number, true, false, or null is sent as that type, or in 'KEY:=JSON' format to give the exact JSON of the value. May be given multiple times.")
+	sendCmd.PersistentFlags().StringVarP(&flags.Tag, "tag", "", "", "Send every request template that has the tag `TAG`, in alphabetical order by name, instead of a single REQ.")
+	sendCmd.PersistentFlags().StringVarP(&flags.AuthFlow, "auth-flow", "", "", "Run the flow `FLOW` before sending a request sent without a template.")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BTimings, "timings", "", false, "(Output flag) Output the time taken by the DNS lookup, connect, TLS handshake, and first byte of the response, and in total")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BShowRedirects, "show-redirects", "", false, "(Output flag) Output each redirect that was followed to get the response, with its status and Location, before the response")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BStats, "stats", "", false, "(Output flag) Output a one-line summary of the response status, body size, and time taken after the response. Allowed in format 'sr'.")
+	sendCmd.PersistentFlags().BoolVarP(&flags.BShowSecrets, "show-secrets", "", false, "Do not mask the values of secret and secret-looking variables in the output of --dump-state.")
 
 	addRequestOutputFlags(sendCmd)
+	addTransportFlags(sendCmd)
+
+	sendCmd.MarkFlagsMutuallyExclusive("record", "no-history")
+	sendCmd.MarkFlagsMutuallyExclusive("record", "no-save-session")
+	sendCmd.MarkFlagsMutuallyExclusive("set-json", "data-urlencode")
 
 	rootCmd.AddCommand(sendCmd)
 }
 
 // invokeRequest receives named vars and checked/defaulted requestOptions.
-func invokeSend(io cmdio.IO, projFile, reqName string, varOverrides map[string]string, skipVerify bool, prefixOverride optionalC[string], oc morc.OutputControl) error {
+func invokeSend(io cmdio.IO, projFile, reqName string, adhoc optional[reqAttrValues], opts sendOptions) error {
 	// load the project file
 	p, err := readProject(projFile, true)
 	if err != nil {
 		return err
 	}
 
-	// case doesn't matter for request template names
-	reqName = strings.ToLower(reqName)
+	return sendRequest(io, &p, reqName, adhoc, opts)
+}
+
+// sendRequest sends the request template in p named reqName, or the request
+// given by adhoc if it is set, and records the results in p.
+func sendRequest(io cmdio.IO, p *morc.Project, reqName string, adhoc optional[reqAttrValues], opts sendOptions) error {
+	varPrefix := opts.prefixOverride.Or(p.VarPrefix())
+	varOverrides := opts.varOverrides
 
-	// check if the project already has a request with the same name
-	tmpl, ok := p.Templates[reqName]
-	if !ok {
-		return fmt.Errorf("no request template %s", reqName)
+	var tmpl morc.RequestTemplate
+	var err error
+	if adhoc.set {
+		tmpl, err = adhocTemplate(*p, adhoc.v)
+		if err != nil {
+			return err
+		}
+	} else {
+		// case doesn't matter for request template names
+		reqName = strings.ToLower(reqName)
+
+		// check if the project already has a request with the same name
+		var ok bool
+		tmpl, ok = p.Templates[reqName]
+		if !ok {
+			return fmt.Errorf("no request template %s", reqName)
+		}
+
+		// tmpl is a copy, so this does not affect the template in the project
+		tmpl.Method = opts.methodOverride.Or(tmpl.Method)
 	}
 
-	oc.Writer = io.Out
+	// apply capture overrides to a copy of the captures so the template in the
+	// project is not affected.
+	if len(opts.capOverrides) > 0 {
+		caps := make(map[string]morc.VarScraper, len(tmpl.Captures)+len(opts.capOverrides))
+		for k, v := range tmpl.Captures {
+			caps[k] = v
+		}
+		for _, c := range opts.capOverrides {
+			caps[strings.ToUpper(c.Name)] = c
+		}
+		tmpl.Captures = caps
+	}
 
-	_, err = sendTemplate(&p, tmpl, p.Vars.MergedSet(varOverrides), skipVerify, prefixOverride.Or(p.VarPrefix()), oc)
-	return err
+	if tmpl.AuthFlow != "" {
+		// exec gives captured vars precedence over one-time vars, so do the
+		// same here.
+		authOverrides := make(map[string]string, len(varOverrides))
+		for k, v := range varOverrides {
+			authOverrides[strings.ToUpper(k)] = v
+		}
+
+		if err := runAuthFlow(io, p, tmpl, authOverrides, opts.skipVerify, !opts.noSaveCaptures, varPrefix, opts.oc, opts.transport); err != nil {
+			return err
+		}
+		varOverrides = authOverrides
+	}
+
+	if opts.prompt {
+		varOverrides, err = promptForMissingVars(io, opts.promptLines, *p, tmpl, varOverrides, varPrefix, opts.transport.envFallback)
+		if err != nil {
+			return err
+		}
+	}
+
+	opts.oc.Writer = io.Out
+
+	// only the response to REQ itself goes to the output file, so it is not
+	// opened until after any auth flow is done.
+	var bodyOut *pendingFile
+	if opts.outputFile != "" {
+		bodyOut, err = createPendingFile(opts.outputFile)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer bodyOut.discard()
+		opts.oc.BodyWriter = bodyOut
+	}
+
+	result, err := sendTemplate(p, tmpl, p.Vars.MergedSet(varOverrides), varPrefix, opts)
+	if result.State != nil {
+		printStateDump(io, *result.State, p.Vars, !opts.showSecrets)
+	}
+	if err != nil {
+		return err
+	}
+
+	if bodyOut != nil {
+		if err := bodyOut.commit(); err != nil {
+			return fmt.Errorf("write output file: %w", err)
+		}
+	}
+	return nil
+}
+
+// pendingFile is a file that is written under a temporary name in the same
+// directory as the file it is for, and that only replaces that file once it is
+// committed. This keeps a failed write from clobbering an existing file.
+type pendingFile struct {
+	*os.File
+	name      string
+	committed bool
+}
+
+// createPendingFile creates a pendingFile that will replace the file at name
+// when committed.
+func createPendingFile(name string) (*pendingFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &pendingFile{File: f, name: name}, nil
+}
+
+// commit closes the file and moves it into place. The file is given the same
+// permissions as the file it replaces, or 0644 if there isn't one.
+func (pf *pendingFile) commit() error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(pf.name); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := pf.Chmod(mode); err != nil {
+		return err
+	}
+	if err := pf.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(pf.Name(), pf.name); err != nil {
+		return err
+	}
+	pf.committed = true
+	return nil
+}
+
+// discard closes and removes the file if it has not been committed. It is safe
+// to call after commit.
+func (pf *pendingFile) discard() {
+	if pf.committed {
+		return
+	}
+	pf.Close()
+	os.Remove(pf.Name())
+}
+
+// invokeSendTagged sends every request template in the project that has tag,
+// in alphabetical order by name, by calling send with the project and the name
+// of each. Because the same project is given to each call, vars captured and
+// cookies set by one request are available to the ones after it. All of them
+// are sent even if some fail, and a summary of the result of each is printed
+// once they are done. An error is returned if any of them failed.
+func invokeSendTagged(io cmdio.IO, projFile, tag string, send func(p *morc.Project, reqName string) error) error {
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	names := sortedTemplateNames(p, tag)
+	if len(names) == 0 {
+		return fmt.Errorf("no request templates have tag %s", tag)
+	}
+
+	failures := map[string]error{}
+	for i, name := range names {
+		if i > 0 {
+			io.PrintLoudf("\n")
+		}
+		io.PrintLoudf("==> %s\n", name)
+
+		if err := send(&p, name); err != nil {
+			failures[name] = err
+		}
+	}
+
+	io.PrintLoudf("\nSUMMARY:\n")
+	for _, name := range names {
+		if err, failed := failures[name]; failed {
+			io.PrintLoudf("FAIL %s: %s\n", name, err)
+		} else {
+			io.PrintLoudf("PASS %s\n", name)
+		}
+	}
+	io.PrintLoudf("%d passed, %d failed\n", len(names)-len(failures), len(failures))
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d requests with tag %s failed", len(failures), len(names), tag)
+	}
+	return nil
+}
+
+// promptForMissingVars asks on io for the value of each var that tmpl needs
+// but that is defined in neither p nor varOverrides, reading each value as a
+// line from lines, and returns varOverrides with the entered values added.
+// Vars needed by the default headers of p and by the values of other vars are
+// asked for as well. If envFallback is set or p falls back to the OS
+// environment, vars defined there are not asked for. varOverrides itself is
+// not modified.
+func promptForMissingVars(io cmdio.IO, lines *bufio.Scanner, p morc.Project, tmpl morc.RequestTemplate, varOverrides map[string]string, varPrefix string, envFallback bool) (map[string]string, error) {
+	defined := p.Vars.MergedSet(varOverrides)
+	envFallback = envFallback || p.Config.EnvFallback
+
+	var missing []string
+	checked := map[string]bool{}
+	// only the override for the current env is applied when sending, so vars
+	// used by the overrides of other envs are not needed
+	pending := tmpl.ForEnv(p.Vars.Environment).ReferencedVars(varPrefix)
+	pending = append(pending, morc.RequestTemplate{Headers: p.Config.DefaultHeaders}.ReferencedVars(varPrefix)...)
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		if checked[name] {
+			continue
+		}
+		checked[name] = true
+
+		if val, ok := defined[name]; ok {
+			// the value may itself refer to vars that are not defined
+			pending = append(pending, morc.RequestTemplate{URL: val}.ReferencedVars(varPrefix)...)
+			continue
+		}
+		if envFallback {
+			if _, ok := os.LookupEnv(name); ok {
+				continue
+			}
+		}
+		missing = append(missing, name)
+	}
+
+	if len(missing) == 0 {
+		return varOverrides, nil
+	}
+
+	withEntered := make(map[string]string, len(varOverrides)+len(missing))
+	for k, v := range varOverrides {
+		withEntered[strings.ToUpper(k)] = v
+	}
+
+	for _, name := range missing {
+		io.PrintErrf("Value for %s{%s}: ", varPrefix, name)
+		if !lines.Scan() {
+			if err := lines.Err(); err != nil {
+				return nil, fmt.Errorf("read value of %s{%s}: %w", varPrefix, name, err)
+			}
+			return nil, fmt.Errorf("no value given for %s{%s}", varPrefix, name)
+		}
+		withEntered[name] = lines.Text()
+	}
+
+	return withEntered, nil
+}
+
+// adhocTemplate builds a request template that is not in p from attrs.
+func adhocTemplate(p morc.Project, attrs reqAttrValues) (morc.RequestTemplate, error) {
+	if err := checkAuthFlowExists(p, attrs.authFlow.v); err != nil {
+		return morc.RequestTemplate{}, err
+	}
+
+	return morc.RequestTemplate{
+		Method:      attrs.method.Or("GET"),
+		URL:         attrs.url.v,
+		Headers:     attrs.headers.v,
+		Body:        attrs.body.v,
+		Form:        attrs.form,
+		AuthFlow:    attrs.authFlow.v,
+		HeaderOrder: attrs.headerOrder,
+	}, nil
+}
+
+// authFlowOutput is where responses to the steps of an auth flow are written.
+// Only the response to the request that was asked for is shown.
+var authFlowOutput = io.Discard
+
+// runAuthFlow runs the auth flow of tmpl so that any vars it captures are
+// available to tmpl. If the auth flow fails, an error is returned that says tmpl
+// was not sent. Auth flows of the templates called by the auth flow are not run.
+func runAuthFlow(io cmdio.IO, p *morc.Project, tmpl morc.RequestTemplate, varOverrides map[string]string, skipVerify, saveCaptures bool, varPrefix string, oc morc.OutputControl, to transportOptions) error {
+	authIO := io
+	authIO.Out = authFlowOutput
+	oc.Writer = authFlowOutput
+
+	flowName := strings.ToLower(tmpl.AuthFlow)
+	if _, err := runFlow(authIO, p, flowName, varOverrides, skipVerify, saveCaptures, varPrefix, oc, to); err != nil {
+		reqName := tmpl.Name
+		if reqName == "" {
+			reqName = "the request"
+		}
+		return fmt.Errorf("auth flow %s failed, so %s was not sent: %w", flowName, reqName, err)
+	}
+
+	return nil
+}
+
+// printStateDump prints state to stderr. If maskSecrets is set, the values of
+// variables that are secrets in vars or whose names look like they hold
+// secrets are replaced with asterisks, as is every occurrence of those values
+// elsewhere in the dump.
+func printStateDump(io cmdio.IO, state morc.State, vars morc.VarStore, maskSecrets bool) {
+	var mask []string
+	if maskSecrets {
+		mask = vars.SecretValues()
+		for name, val := range state.Vars {
+			if val != "" && isSecretVar(vars, name) {
+				mask = append(mask, val)
+			}
+		}
+	}
+
+	io.PrintErrf("Client state:\n")
+	io.PrintErrf("Cookies:\n")
+	if len(state.Cookies) == 0 {
+		io.PrintErrf("(none)\n")
+	} else {
+		for _, call := range state.Cookies {
+			io.PrintErrf(" * %s (set %s):\n", call.URL, call.Time.Format(time.RFC3339))
+			for _, c := range call.Cookies {
+				io.PrintErrf("   * %s\n", morc.MaskValues(c.String(), mask))
+			}
+		}
+	}
+
+	io.PrintErrf("Variables:\n")
+	if len(state.Vars) == 0 {
+		io.PrintErrf("(none)\n")
+	} else {
+		names := make([]string, 0, len(state.Vars))
+		for k := range state.Vars {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			val := state.Vars[name]
+			if maskSecrets && isSecretVar(vars, name) {
+				val = maskedValue
+			} else {
+				val = morc.MaskValues(val, mask)
+			}
+			io.PrintErrf(" * %s: %s\n", name, val)
+		}
+	}
 }
 
 type sendArgs struct {
-	projFile       string
-	req            string
-	oneTimeVars    map[string]string
-	outputCtrl     morc.OutputControl
-	skipVerify     bool
-	prefixOverride optionalC[string]
+	projFile string
+	req      string
+	adhoc    optional[reqAttrValues]
+	opts     sendOptions
+
+	// tag is the tag of the request templates to send. It is empty if a
+	// single request is being sent.
+	tag string
+}
+
+// sendOptions holds the options given for a single send of a request template
+// that are not part of the template itself. The zero value sends the template
+// as it is, saving captures and recording history and the session as set in
+// the project.
+type sendOptions struct {
+	methodOverride   optionalC[string]
+	varOverrides     map[string]string
+	prefixOverride   optionalC[string]
+	capOverrides     []morc.VarScraper
+	cookies          []*http.Cookie
+	jsonFields       []morc.JSONField
+	skipVerify       bool
+	noSaveCaptures   bool
+	deferCaptureErrs bool
+	noSubstHeaders   bool
+	dumpState        bool
+	showSecrets      bool
+	prompt           bool
+	asserts          responseAssertions
+	retry            morc.RetryOptions
+	outputFile       string
+	oc               morc.OutputControl
+	transport        transportOptions
+
+	// promptLines is where values are read from when prompt is set. It is
+	// shared by every send made by a single command.
+	promptLines *bufio.Scanner
 }
 
 func parseSendArgs(cmd *cobra.Command, posArgs []string, args *sendArgs) error {
@@ -90,7 +604,29 @@ func parseSendArgs(cmd *cobra.Command, posArgs []string, args *sendArgs) error {
 	}
 
 	var err error
-	args.outputCtrl, err = gatherRequestOutputFlags(cmd)
+	args.opts.oc, err = gatherRequestOutputFlags(cmd)
+	if err != nil {
+		return err
+	}
+	args.opts.oc.Timings = flags.BTimings
+	args.opts.oc.Redirects = flags.BShowRedirects
+	args.opts.oc.Stats = flags.BStats
+	if args.opts.oc.Format == morc.FormatJSON && flags.BStats {
+		return fmt.Errorf("--stats cannot be used with format 'json'")
+	}
+
+	// quiet mode outputs nothing but the response body
+	if flags.BQuiet {
+		if args.opts.oc.Format == morc.FormatJSON {
+			return fmt.Errorf("--quiet/-q cannot be used with format 'json'")
+		}
+		if flags.BRequest || flags.BHeaders || flags.BCaptures || flags.BVerbose || flags.BTimings || flags.BShowRedirects || flags.BStats {
+			return fmt.Errorf("--quiet/-q only outputs the response body and cannot be used with other output flags")
+		}
+		args.opts.oc.BodyOnly = true
+	}
+
+	args.opts.transport, err = gatherTransportFlags(cmd)
 	if err != nil {
 		return err
 	}
@@ -109,41 +645,260 @@ func parseSendArgs(cmd *cobra.Command, posArgs []string, args *sendArgs) error {
 			}
 			oneTimeVars[varName] = parts[1]
 		}
-		args.oneTimeVars = oneTimeVars
+		args.opts.varOverrides = oneTimeVars
+	}
+
+	for idx, c := range flags.CaptureOverrides {
+		scraper, err := morc.ParseVarScraper(c)
+		if err != nil {
+			return fmt.Errorf("capture override #%d (%q): %w", idx+1, c, err)
+		}
+		scraper.Name = strings.ToUpper(scraper.Name)
+		args.opts.capOverrides = append(args.opts.capOverrides, scraper)
+	}
+
+	for idx, c := range flags.Cookies {
+		cookie, err := parseCookieArg(c)
+		if err != nil {
+			return fmt.Errorf("cookie #%d (%q): %w", idx+1, c, err)
+		}
+		args.opts.cookies = append(args.opts.cookies, cookie)
+	}
+
+	args.opts.noSaveCaptures = flags.BNoSaveCaptures
+
+	if flags.BRecord {
+		args.opts.transport.record.history = optionalC[bool]{set: true, v: true}
+		args.opts.transport.record.session = optionalC[bool]{set: true, v: true}
+	}
+	if flags.BNoHistory {
+		args.opts.transport.record.history = optionalC[bool]{set: true, v: false}
+	}
+	if flags.BNoSaveSession {
+		args.opts.transport.record.session = optionalC[bool]{set: true, v: false}
+	}
+	args.opts.deferCaptureErrs = flags.BDeferCaptureErrors
+	args.opts.noSubstHeaders = flags.BNoSubstituteHeaders
+
+	for idx, f := range flags.SetJSON {
+		field, err := parseSetJSONArg(f)
+		if err != nil {
+			return fmt.Errorf("set-json #%d (%q): %w", idx+1, f, err)
+		}
+		args.opts.jsonFields = append(args.opts.jsonFields, field)
+	}
+
+	if flags.BShowSecrets && !flags.BDumpState {
+		return fmt.Errorf("--show-secrets can only be used with --dump-state")
+	}
+	args.opts.dumpState = flags.BDumpState
+	args.opts.showSecrets = flags.BShowSecrets
+
+	if cmd.Flags().Changed("header-match") && len(flags.AssertHeaders) == 0 {
+		return fmt.Errorf("--header-match can only be used with --assert-header")
+	}
+	match, err := morc.ParseHeaderMatch(flags.HeaderMatch)
+	if err != nil {
+		return fmt.Errorf("--header-match: %w", err)
+	}
+	for idx, h := range flags.AssertHeaders {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("assert-header #%d (%q) is not in format key: value", idx+1, h)
+		}
+		key := strings.TrimSpace(parts[0])
+		if key == "" || strings.Contains(key, " ") {
+			return fmt.Errorf("assert-header #%d (%q) does not have a valid header key", idx+1, h)
+		}
+		value := strings.TrimSpace(parts[1])
+		if match == morc.HeaderMatchRegex {
+			if _, err := regexp.Compile(value); err != nil {
+				return fmt.Errorf("assert-header #%d (%q): %w", idx+1, h, err)
+			}
+		}
+		args.opts.asserts.headers = append(args.opts.asserts.headers, morc.HeaderAssertion{Key: key, Value: value, Match: match})
+	}
+	for idx, h := range flags.AssertHeadersPresent {
+		key := strings.TrimSpace(h)
+		if key == "" || strings.Contains(key, " ") || strings.Contains(key, ":") {
+			return fmt.Errorf("assert-header-present #%d (%q) is not a valid header key", idx+1, h)
+		}
+		args.opts.asserts.headers = append(args.opts.asserts.headers, morc.HeaderAssertion{Key: key, Present: true})
+	}
+
+	if cmd.Flags().Changed("assert-status") {
+		if err := checkStatusCode(flags.AssertStatus); err != nil {
+			return fmt.Errorf("--assert-status: %w", err)
+		}
+		args.opts.asserts.status = flags.AssertStatus
+	}
+	args.opts.asserts.bodyContains = flags.AssertBodyContains
+	for idx, expr := range flags.Asserts {
+		a, err := morc.ParseJSONAssertion(expr)
+		if err != nil {
+			return fmt.Errorf("assert #%d (%q): %w", idx+1, expr, err)
+		}
+		args.opts.asserts.json = append(args.opts.asserts.json, a)
+	}
+
+	args.opts.prompt = flags.BPrompt
+
+	if cmd.Flags().Changed("output") {
+		if flags.OutputFile == "" {
+			return fmt.Errorf("--output cannot be set to empty string")
+		}
+		if flags.BNoBody {
+			return fmt.Errorf("--no-body cannot be used with --output")
+		}
+		args.opts.outputFile = flags.OutputFile
+	}
+
+	if flags.RetryBodyMatch == "" {
+		if cmd.Flags().Changed("retry-max-attempts") {
+			return fmt.Errorf("--retry-max-attempts can only be used with --retry-on-body-match")
+		}
+		if cmd.Flags().Changed("retry-backoff") {
+			return fmt.Errorf("--retry-backoff can only be used with --retry-on-body-match")
+		}
+	} else {
+		if flags.RetryMaxAttempts < 1 {
+			return fmt.Errorf("--retry-max-attempts must be at least 1")
+		}
+		backoff, err := time.ParseDuration(flags.RetryBackoff)
+		if err != nil {
+			return fmt.Errorf("--retry-backoff: %w", err)
+		}
+		if backoff <= 0 {
+			return fmt.Errorf("--retry-backoff must be a positive duration")
+		}
+		args.opts.retry = morc.RetryOptions{
+			BodyMatch:   flags.RetryBodyMatch,
+			MaxAttempts: flags.RetryMaxAttempts,
+			Backoff:     backoff,
+		}
 	}
 
 	if flags.BInsecure {
-		args.skipVerify = true
+		args.opts.skipVerify = true
 	}
 
 	if cmd.Flags().Lookup("var-prefix").Changed {
-		args.prefixOverride = optionalC[string]{v: flags.VarPrefix, set: true}
+		args.opts.prefixOverride = optionalC[string]{v: flags.VarPrefix, set: true}
 	}
 
-	args.req = posArgs[0]
+	if cmd.Flags().Changed("tag") {
+		f := cmd.Flags()
+		if len(posArgs) > 0 {
+			return fmt.Errorf("--tag cannot be used when REQ is given")
+		}
+		if f.Changed("url") || f.Changed("header") || f.Changed("data") || f.Changed("data-urlencode") || f.Changed("auth-flow") {
+			return fmt.Errorf("--url, --header, --data, --data-urlencode, and --auth-flow cannot be used with --tag")
+		}
+		if f.Changed("output") {
+			return fmt.Errorf("--output cannot be used with --tag")
+		}
+		if f.Changed("method") {
+			args.opts.methodOverride = optionalC[string]{set: true, v: strings.ToUpper(flags.Method)}
+		}
+		args.tag, err = parseTag(flags.Tag)
+		if err != nil {
+			return fmt.Errorf("--tag: %w", err)
+		}
+	} else if len(posArgs) > 0 {
+		f := cmd.Flags()
+		if f.Changed("url") || f.Changed("header") || f.Changed("data") || f.Changed("data-urlencode") || f.Changed("auth-flow") {
+			return fmt.Errorf("--url, --header, --data, --data-urlencode, and --auth-flow can only be used when REQ is not given")
+		}
+		if f.Changed("method") {
+			args.opts.methodOverride = optionalC[string]{set: true, v: strings.ToUpper(flags.Method)}
+		}
+		args.req = posArgs[0]
+	} else {
+		if !cmd.Flags().Changed("url") {
+			return fmt.Errorf("REQ, --url, or --tag is required")
+		}
+		if flags.URL == "" {
+			return fmt.Errorf("--url cannot be set to empty string")
+		}
+
+		var attrs reqAttrValues
+		if err := parseReqsSetFlags(cmd, &attrs); err != nil {
+			return err
+		}
+		args.adhoc = optional[reqAttrValues]{set: true, v: attrs}
+	}
 
 	return nil
 }
 
-func sendTemplate(p *morc.Project, tmpl morc.RequestTemplate, vars map[string]string, skipVerify bool, varSymbol string, oc morc.OutputControl) (morc.SendResult, error) {
-	// TODO: flows will call this and persist on EVERY request which is probably not needed.
+// parseSetJSONArg parses the argument to --set-json. It must be in KEY=VALUE
+// format, or in KEY:=JSON format to give the raw JSON of the value.
+func parseSetJSONArg(arg string) (morc.JSONField, error) {
+	sep := strings.Index(arg, "=")
+	if sep < 0 {
+		return morc.JSONField{}, fmt.Errorf("not in KEY=VALUE or KEY:=JSON format")
+	}
 
+	field := morc.JSONField{Key: arg[:sep], Value: arg[sep+1:]}
+	if strings.HasSuffix(field.Key, ":") {
+		field.Key = strings.TrimSuffix(field.Key, ":")
+		field.Raw = true
+	}
+	if field.Key == "" {
+		return morc.JSONField{}, fmt.Errorf("KEY cannot be empty")
+	}
+	return field, nil
+}
+
+// parseCookieArg parses a cookie given on the command line. It is in the same
+// format as the value of a Set-Cookie header.
+func parseCookieArg(s string) (*http.Cookie, error) {
+	resp := http.Response{Header: http.Header{"Set-Cookie": []string{s}}}
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || !strings.Contains(strings.SplitN(s, ";", 2)[0], "=") {
+		return nil, fmt.Errorf("not in NAME=VALUE[;ATTR=VALUE]... format")
+	}
+	return cookies[0], nil
+}
+
+// templateSendOptions checks that tmpl can be sent and builds the SendOptions
+// for sending it as part of p.
+func templateSendOptions(p *morc.Project, tmpl morc.RequestTemplate, vars map[string]string, skipVerify bool, oc morc.OutputControl, to transportOptions) (morc.SendOptions, error) {
 	if tmpl.Method == "" {
-		return morc.SendResult{}, fmt.Errorf("request template %s has no method set", tmpl.Name)
+		return morc.SendOptions{}, fmt.Errorf("request template %s has no method set", tmpl.Name)
 	}
 
 	if tmpl.URL == "" {
-		return morc.SendResult{}, fmt.Errorf("request template %s has no URL set", tmpl.Name)
+		return morc.SendOptions{}, fmt.Errorf("request template %s has no URL set", tmpl.Name)
 	}
 
+	// apply any structural changes made to the request in the current env
+	tmpl = tmpl.ForEnv(p.Vars.Environment)
+
+	cfg := p.EffectiveConfig()
 	sendOpts := morc.SendOptions{
 		Vars:               vars,
 		Body:               tmpl.Body,
+		BodyFile:           morc.ExpandProjDir(tmpl.BodyFile, cfg.ProjFile),
+		Form:               tmpl.Form,
 		Headers:            tmpl.Headers,
+		DefaultHeaders:     cfg.DefaultHeaders,
 		Output:             oc,
-		CookieLifetime:     p.Config.CookieLifetime,
+		CookieLifetime:     cfg.CookieLifetime,
 		InsecureSkipVerify: skipVerify,
+		BaseURL:            p.Vars.BaseURL(),
 	}
+	to.applyTo(&sendOpts, cfg)
+
+	// secret vars are masked in output, including any given just for this send
+	// and any transformed forms of them
+	secrets := p.Vars.SecretValues()
+	for _, name := range p.Vars.Secrets() {
+		if v := vars[name]; v != "" {
+			secrets = append(secrets, v)
+		}
+	}
+	sendOpts.Output.Mask = morc.SecretForms(secrets)
 
 	capVarNames := []string{}
 	for k := range tmpl.Captures {
@@ -161,24 +916,143 @@ func sendTemplate(p *morc.Project, tmpl morc.RequestTemplate, vars map[string]st
 	// inject the http client, in case we are to use a specific one
 	sendOpts.Client = cmdio.HTTPClient
 
-	result, err := morc.Send(tmpl.Method, tmpl.URL, varSymbol, sendOpts)
+	return sendOpts, nil
+}
+
+// dryRunTemplate outputs the request that would be sent by tmpl without
+// sending it. References to any of the vars in unexpanded are left as they are.
+// Nothing in p is modified or persisted.
+func dryRunTemplate(p *morc.Project, tmpl morc.RequestTemplate, vars map[string]string, unexpanded []string, varSymbol string, oc morc.OutputControl) error {
+	sendOpts, err := templateSendOptions(p, tmpl, vars, false, oc, transportOptions{})
+	if err != nil {
+		return err
+	}
+	sendOpts.DryRun = true
+	sendOpts.Unexpanded = unexpanded
+
+	_, err = morc.Send(tmpl.Method, tmpl.URL, varSymbol, sendOpts)
+	return err
+}
+
+// sendTemplate sends tmpl with vars and records the results in p. Any cookies in
+// opts are added to those in p's session before sending. Unless
+// opts.noSaveCaptures is set, any values captured from the response are
+// persisted to the project file; they are always updated in the in-memory p
+// regardless. The options in opts that only apply to a send made by the send
+// command, such as its method and capture overrides, are not used; they must
+// already be applied to tmpl and vars. If any of the assertions in opts fail,
+// the results are still recorded and the *morc.AssertionError is returned.
+// Likewise, if opts.deferCaptureErrs is set and any captures fail, the results
+// are still recorded and the *morc.CaptureError is returned.
+func sendTemplate(p *morc.Project, tmpl morc.RequestTemplate, vars map[string]string, varSymbol string, opts sendOptions) (morc.SendResult, error) {
+	// TODO: flows will call this and persist on EVERY request which is probably not needed.
+
+	sendOpts, err := templateSendOptions(p, tmpl, vars, opts.skipVerify, opts.oc, opts.transport)
 	if err != nil {
+		return morc.SendResult{}, err
+	}
+	sendOpts.ExtraCookies = opts.cookies
+	sendOpts.DumpState = opts.dumpState
+	opts.asserts.applyTo(&sendOpts)
+	sendOpts.DeferCaptureErrors = opts.deferCaptureErrs
+	sendOpts.NoSubstituteHeaders = opts.noSubstHeaders
+	sendOpts.JSONFields = opts.jsonFields
+	sendOpts.Retry = opts.retry
+
+	result, err := morc.Send(tmpl.Method, tmpl.URL, varSymbol, sendOpts)
+	var assertErr *morc.AssertionError
+	var capErr *morc.CaptureError
+	if err != nil && !errors.As(err, &assertErr) && !errors.As(err, &capErr) {
 		return result, err
 	}
 
+	if recErr := recordSendResult(p, tmpl, result, sendOpts.Output.Mask, !opts.noSaveCaptures, opts.transport.record); recErr != nil {
+		return result, recErr
+	}
+	return result, err
+}
+
+// responseAssertions holds the checks that are made against a response once it
+// has been output.
+type responseAssertions struct {
+	status       int
+	headers      []morc.HeaderAssertion
+	bodyContains []string
+	json         []morc.JSONAssertion
+}
+
+// stepAssertions returns the assertions that are made against the response to
+// step.
+func stepAssertions(step morc.FlowStep) (responseAssertions, error) {
+	ra := responseAssertions{
+		status:       step.AssertStatus,
+		bodyContains: step.AssertBodyContains,
+	}
+	for _, expr := range step.AssertJSON {
+		a, err := morc.ParseJSONAssertion(expr)
+		if err != nil {
+			return ra, fmt.Errorf("assertion %q: %w", expr, err)
+		}
+		ra.json = append(ra.json, a)
+	}
+	return ra, nil
+}
+
+// applyTo sets the options in opts that correspond to those in ra.
+func (ra responseAssertions) applyTo(opts *morc.SendOptions) {
+	opts.AssertStatus = ra.status
+	opts.AssertHeaders = ra.headers
+	opts.AssertBodyContains = ra.bodyContains
+	opts.AssertJSON = ra.json
+}
+
+// checkStatusCode returns an error if code is not a valid HTTP status code.
+func checkStatusCode(code int) error {
+	if code < 100 || code > 599 {
+		return fmt.Errorf("%d is not a valid HTTP status code", code)
+	}
+	return nil
+}
+
+// recordOverrides replaces whether history and the session are recorded for a
+// single invocation, regardless of the settings of the project. An override
+// that is not set leaves the project's setting in effect.
+type recordOverrides struct {
+	history optionalC[bool]
+	session optionalC[bool]
+}
+
+// recordSendResult updates p with the captures, history, and cookies from the
+// result of sending tmpl and persists them as configured in p, unless replaced
+// by rec. Each of redact is masked in the history entry; it should be the same
+// values that were masked in the output of the send, which include the forms
+// that secrets take when var transforms are applied to them. If saveCaptures is
+// not set, captured values are updated in the in-memory p only.
+func recordSendResult(p *morc.Project, tmpl morc.RequestTemplate, result morc.SendResult, redact []string, saveCaptures bool, rec recordOverrides) error {
 	// if any variable changes occurred, persist to disk
 	if len(result.Captures) > 0 {
 		for k, v := range result.Captures {
-			p.Vars.Set(k, v)
+			cap := tmpl.Captures[k]
+			cap.Name = k
+			p.Vars.SetCaptured(cap, v)
+
+			if saveCaptures {
+				activeRepl.forgetUnsavedVar(capturedEnv(p.Vars, cap), k)
+			} else {
+				activeRepl.keepUnsavedVar(capturedEnv(p.Vars, cap), k, v)
+			}
 		}
-		err := writeProject(*p, false)
-		if err != nil {
-			return result, fmt.Errorf("save project to disk: %w", err)
+
+		if saveCaptures {
+			err := writeProject(*p, false)
+			if err != nil {
+				return fmt.Errorf("save project to disk: %w", err)
+			}
 		}
 	}
 
 	// persist history
-	if p.Config.RecordHistory {
+	if rec.history.Or(p.Config.RecordHistory) {
 		entry := morc.HistoryEntry{
 			Template: tmpl.Name,
 			ReqTime:  result.SendTime,
@@ -186,24 +1060,47 @@ func sendTemplate(p *morc.Project, tmpl morc.RequestTemplate, vars map[string]st
 			Request:  result.Request,
 			Response: result.Response,
 			Captures: result.Captures,
+			Redact:   redact,
 		}
 
-		p.History = append(p.History, entry)
+		p.AddHistory(entry)
 		err := writeHistory(*p)
 		if err != nil {
-			return result, fmt.Errorf("save history to disk: %w", err)
+			return fmt.Errorf("save history to disk: %w", err)
 		}
 	}
 
 	// persist cookies
-	if p.Config.RecordSession && len(result.Cookies) > 0 {
+	if rec.session.Or(p.Config.RecordSession) && len(result.Cookies) > 0 {
 		p.Session.Cookies = result.Cookies
 
 		err := writeSession(*p)
 		if err != nil {
-			return result, fmt.Errorf("save session to disk: %w", err)
+			return fmt.Errorf("save session to disk: %w", err)
 		}
+	} else if activeRepl != nil && len(result.Cookies) > 0 {
+		// a REPL keeps cookies for the rest of its session even when they are
+		// not recorded
+		activeRepl.keepUnsavedCookies(p.Session.Cookies, result.Cookies)
+		p.Session.Cookies = result.Cookies
 	}
 
-	return result, nil
+	// captures that were not saved and unrecorded cookies are still kept in
+	// memory by a REPL
+	activeRepl.remember(*p)
+
+	return nil
+}
+
+// capturedEnv returns the name of the env in vs that a value captured by
+// scraper is stored in.
+func capturedEnv(vs morc.VarStore, scraper morc.VarScraper) string {
+	switch scraper.Env {
+	case "":
+		return vs.Environment
+	case morc.DefaultEnvName:
+		return ""
+	default:
+		return scraper.Env
+	}
 }
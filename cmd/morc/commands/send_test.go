@@ -1,12 +1,18 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dekarrin/morc"
 	"github.com/dekarrin/morc/cmd/morc/cmdio"
@@ -33,12 +39,16 @@ func (rt urlBaseRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 		fullURL += "?" + req.URL.RawQuery
 	}
 
-	var err error
-	req.URL, err = url.Parse(fullURL)
+	u, err := url.Parse(fullURL)
 	if err != nil {
 		return nil, err
 	}
 
+	// update the URL in place rather than replacing it; the client forks the
+	// request it is given when it has a timeout, and the tests check the URL
+	// of the original.
+	*req.URL = *u
+
 	return rt.old.RoundTrip(req)
 }
 
@@ -68,6 +78,70 @@ func Test_Send(t *testing.T) {
 		_, _ = w.Write([]byte(`{"name":{"first":"VRISKA","last":"SERKET"}}`))
 	}
 
+	respFnEchoCookies := func(w http.ResponseWriter, r *http.Request) {
+		// suppress date header
+		w.Header()["Date"] = nil
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(r.Header.Get("Cookie")))
+	}
+
+	respFnAuth := func(w http.ResponseWriter, r *http.Request) {
+		// suppress date header
+		w.Header()["Date"] = nil
+
+		if r.URL.Path == "/auth" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"token":"abc123"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}
+
+	pendingCalls := 0
+	respFnPendingTwice := func(w http.ResponseWriter, r *http.Request) {
+		// suppress date header
+		w.Header()["Date"] = nil
+
+		w.WriteHeader(http.StatusOK)
+		pendingCalls++
+		if pendingCalls%3 != 0 {
+			_, _ = w.Write([]byte(`{"status":"pending"}`))
+		} else {
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"status":"done","calls":%d}`, pendingCalls)))
+		}
+	}
+
+	respFnEchoRequest := func(w http.ResponseWriter, r *http.Request) {
+		// suppress date header
+		w.Header()["Date"] = nil
+
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(fmt.Sprintf("%s %s %s %s", r.Method, r.URL.Path, r.Header.Get("X-Name"), body)))
+	}
+
+	authFlowTemplates := map[string]morc.RequestTemplate{
+		"auth": {
+			Name:   "auth",
+			Method: "POST",
+			URL:    "/auth",
+			Captures: map[string]morc.VarScraper{
+				"TOKEN": {Name: "TOKEN", Steps: []morc.TraversalStep{{Key: "token"}}},
+			},
+		},
+		"testreq": {
+			Name:     "testreq",
+			Method:   "GET",
+			URL:      "/",
+			Headers:  http.Header{"Authorization": []string{"Bearer ${TOKEN}"}},
+			AuthFlow: "login",
+		},
+	}
+
 	testCases := []struct {
 		name               string
 		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
@@ -135,6 +209,164 @@ func Test_Send(t *testing.T) {
 			expectHistorySaved: true,
 			expectSessionSaved: false,
 		},
+		{
+			name:   "send drops oldest history entries past the cap",
+			args:   []string{"send", "testreq"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+				History: testHistoryEntries(2),
+				Config: morc.Settings{
+					HistFile:          "::PROJ_DIR::/history.json",
+					RecordHistory:     true,
+					MaxHistoryEntries: 1,
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+				History: []morc.HistoryEntry{
+					{
+						Template: "testreq",
+						Request: &http.Request{
+							Method:     "GET",
+							URL:        mustParseURL("/"),
+							Proto:      "HTTP/1.1",
+							ProtoMajor: 1,
+							ProtoMinor: 1,
+							Body:       http.NoBody,
+						},
+						Response: &http.Response{
+							Status:     fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK)),
+							StatusCode: http.StatusOK,
+							Proto:      "HTTP/1.1",
+							ProtoMajor: 1,
+							ProtoMinor: 1,
+							Header: http.Header{
+								"Content-Length": []string{"0"},
+							},
+							Body: http.NoBody,
+						},
+					},
+				},
+				Config: morc.Settings{
+					HistFile:          "::PROJ_DIR::/history.json",
+					RecordHistory:     true,
+					MaxHistoryEntries: 1,
+				},
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: true,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "send --no-history does not save history",
+			args:   []string{"send", "testreq", "--no-history"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+				Config: morc.Settings{
+					HistFile:      "::PROJ_DIR::/history.json",
+					RecordHistory: true,
+				},
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "send --record saves history when it is off",
+			args:   []string{"send", "testreq", "--record"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+				Config: morc.Settings{
+					HistFile: "::PROJ_DIR::/history.json",
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+				History: []morc.HistoryEntry{
+					{
+						Template: "testreq",
+						Request: &http.Request{
+							Method:     "GET",
+							URL:        mustParseURL("/"),
+							Proto:      "HTTP/1.1",
+							ProtoMajor: 1,
+							ProtoMinor: 1,
+							Body:       http.NoBody,
+						},
+						Response: &http.Response{
+							Status:     fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK)),
+							StatusCode: http.StatusOK,
+							Proto:      "HTTP/1.1",
+							ProtoMajor: 1,
+							ProtoMinor: 1,
+							Header: http.Header{
+								"Content-Length": []string{"0"},
+							},
+							Body: http.NoBody,
+						},
+					},
+				},
+				Config: morc.Settings{
+					HistFile: "::PROJ_DIR::/history.json",
+				},
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: true,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "send --no-save-session does not save session data",
+			args:   []string{"send", "testreq", "--no-save-session"},
+			respFn: respFnNoBodyOKCookie,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+				Config: morc.Settings{
+					SeshFile:      "::PROJ_DIR::/session.json",
+					RecordSession: true,
+				},
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "send --record cannot be given with --no-history",
+			args:   []string{"send", "testreq", "--record", "--no-history"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "none of the others can be",
+		},
 		{
 			name:   "send saves session data",
 			args:   []string{"send", "testreq"},
@@ -252,7 +484,7 @@ func Test_Send(t *testing.T) {
 			expectSessionSaved: false,
 		},
 		{
-			name:   "send saves body captures - entire request",
+			name:   "send saves body captures - into target env",
 			args:   []string{"send", "testreq"},
 			respFn: respFnJSONBodyOK,
 			p: morc.Project{
@@ -262,10 +494,15 @@ func Test_Send(t *testing.T) {
 						Method: "GET",
 						URL:    "/",
 						Captures: map[string]morc.VarScraper{
-							"TEST": {Name: "TEST"},
+							"FIRST": {Name: "FIRST", Env: morc.DefaultEnvName, Steps: []morc.TraversalStep{{Key: "name"}, {Key: "first"}}},
+							"LAST":  {Name: "LAST", Env: "STAGING", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "last"}}},
 						},
 					},
 				},
+				Vars: testVarStore("PROD", map[string]map[string]string{
+					"":     {"FIRST": "", "LAST": ""},
+					"PROD": {"FIRST": "ARADIA"},
+				}),
 			},
 			expectP: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
@@ -274,12 +511,15 @@ func Test_Send(t *testing.T) {
 						Method: "GET",
 						URL:    "/",
 						Captures: map[string]morc.VarScraper{
-							"TEST": {Name: "TEST"},
+							"FIRST": {Name: "FIRST", Env: morc.DefaultEnvName, Steps: []morc.TraversalStep{{Key: "name"}, {Key: "first"}}},
+							"LAST":  {Name: "LAST", Env: "STAGING", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "last"}}},
 						},
 					},
 				},
-				Vars: testVarStore("", map[string]map[string]string{
-					"": {"TEST": `{"name":{"first":"VRISKA","last":"SERKET"}}`},
+				Vars: testVarStore("PROD", map[string]map[string]string{
+					"":        {"FIRST": "VRISKA", "LAST": ""},
+					"PROD":    {"FIRST": "ARADIA"},
+					"STAGING": {"LAST": "SERKET"},
 				}),
 			},
 			expectStdoutOutput: `HTTP/1.1 200 OK
@@ -288,9 +528,10 @@ func Test_Send(t *testing.T) {
 			expectProjectSaved: true,
 			expectHistorySaved: false,
 			expectSessionSaved: false,
-		}, {
-			name:   "send saves body captures - entire request -2",
-			args:   []string{"send", "testreq"},
+		},
+		{
+			name:   "capture override replaces template capture",
+			args:   []string{"send", "testreq", "--capture-override", "test:.name.first"},
 			respFn: respFnJSONBodyOK,
 			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
@@ -299,7 +540,7 @@ func Test_Send(t *testing.T) {
 						Method: "GET",
 						URL:    "/",
 						Captures: map[string]morc.VarScraper{
-							"TEST": {Name: "TEST", OffsetEnd: -2},
+							"TEST": {Name: "TEST", OffsetStart: 18, OffsetEnd: 24},
 						},
 					},
 				},
@@ -311,12 +552,12 @@ func Test_Send(t *testing.T) {
 						Method: "GET",
 						URL:    "/",
 						Captures: map[string]morc.VarScraper{
-							"TEST": {Name: "TEST", OffsetEnd: -2},
+							"TEST": {Name: "TEST", OffsetStart: 18, OffsetEnd: 24},
 						},
 					},
 				},
 				Vars: testVarStore("", map[string]map[string]string{
-					"": {"TEST": `{"name":{"first":"VRISKA","last":"SERKET"`},
+					"": {"TEST": "VRISKA"},
 				}),
 			},
 			expectStdoutOutput: `HTTP/1.1 200 OK
@@ -327,125 +568,110 @@ func Test_Send(t *testing.T) {
 			expectSessionSaved: false,
 		},
 		{
-			name:   "request has a body",
-			args:   []string{"send", "testreq"},
-			respFn: respFnNoBodyOK,
+			name:   "capture override adds to template captures",
+			args:   []string{"send", "testreq", "-C", "LAST:.name.last", "--captures"},
+			respFn: respFnJSONBodyOK,
 			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
-					"testreq": {Name: "testreq", Method: "GET", URL: "/", Body: []byte("testbody")},
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"TEST": {Name: "TEST", OffsetStart: 18, OffsetEnd: 24},
+						},
+					},
 				},
 			},
 			expectP: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
-					"testreq": {Name: "testreq", Method: "GET", URL: "/", Body: []byte("testbody")},
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"TEST": {Name: "TEST", OffsetStart: 18, OffsetEnd: 24},
+						},
+					},
 				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"TEST": "VRISKA", "LAST": "SERKET"},
+				}),
 			},
-			expectStdoutOutput: `HTTP/1.1 200 OK
-(no response body)
+			expectStdoutOutput: `----------------- VAR CAPTURES ----------------
+LAST: SERKET
+TEST: VRISKA
+-----------------------------------------------
+HTTP/1.1 200 OK
+{"name":{"first":"VRISKA","last":"SERKET"}}
 `,
-			expectProjectSaved: false,
+			expectProjectSaved: true,
 			expectHistorySaved: false,
 			expectSessionSaved: false,
 		},
 		{
-			name:   "request has headers",
-			args:   []string{"send", "testreq"},
-			respFn: respFnNoBodyOK,
+			name:   "capture override with bad spec",
+			args:   []string{"send", "testreq", "-C", "LAST"},
+			respFn: respFnJSONBodyOK,
 			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
-					"testreq": {Name: "testreq", Method: "GET", URL: "/", Headers: http.Header{"X-Test": []string{"test"}}},
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
 				},
 			},
-			expectP: morc.Project{
+			expectErr: "capture override #1 (\"LAST\"): not in NAME:SPEC format",
+		},
+		{
+			name:   "no-save-captures does not persist captured values",
+			args:   []string{"send", "testreq", "--no-save-captures"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
-					"testreq": {Name: "testreq", Method: "GET", URL: "/", Headers: http.Header{"X-Test": []string{"test"}}},
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"TEST": {Name: "TEST", OffsetStart: 18, OffsetEnd: 24},
+						},
+					},
 				},
 			},
 			expectStdoutOutput: `HTTP/1.1 200 OK
-(no response body)
+{"name":{"first":"VRISKA","last":"SERKET"}}
 `,
 			expectProjectSaved: false,
 			expectHistorySaved: false,
 			expectSessionSaved: false,
 		},
 		{
-			name:   "print request",
-			args:   []string{"send", "testreq", "--request"},
-			respFn: respFnNoBodyOK,
+			name:   "auth flow is run before the request",
+			args:   []string{"send", "testreq", "--no-save-captures"},
+			respFn: respFnAuth,
 			p: morc.Project{
-				Templates: map[string]morc.RequestTemplate{
-					"testreq": {Name: "testreq", Method: "GET", URL: "/", Body: []byte("testvalue"), Headers: http.Header{"X-Test": []string{"test"}}},
+				Templates: authFlowTemplates,
+				Flows: map[string]morc.Flow{
+					"login": {Name: "login", Steps: []morc.FlowStep{{Template: "auth"}}},
 				},
 			},
-			expectP: morc.Project{
-				Templates: map[string]morc.RequestTemplate{
-					"testreq": {Name: "testreq", Method: "GET", URL: "/", Body: []byte("testvalue"), Headers: http.Header{"X-Test": []string{"test"}}},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+Bearer abc123
+`,
+		},
+		{
+			name:   "auth flow that fails stops the request",
+			args:   []string{"send", "testreq"},
+			respFn: respFnAuth,
+			p: morc.Project{
+				Templates: authFlowTemplates,
+				Flows: map[string]morc.Flow{
+					"login": {Name: "login", Steps: []morc.FlowStep{{Template: "missing"}}},
 				},
 			},
-			expectStdoutOutput: `------------------- REQUEST -------------------
-Request URI: $TESTSERVER_URL$/
-
-GET / HTTP/1.1` + "\r" + `
-Host: $TESTSERVER_HOST$` + "\r" + `
-User-Agent: Go-http-client/1.1` + "\r" + `
-Content-Length: 9` + "\r" + `
-X-Test: test` + "\r" + `
-Accept-Encoding: gzip` + "\r" + `
-` + "\r" + `
-testvalue
------------------ END REQUEST -----------------
-HTTP/1.1 200 OK
-(no response body)
-`,
-			expectProjectSaved: false,
-			expectHistorySaved: false,
-			expectSessionSaved: false,
-		},
-		{
-			name:   "print response headers",
-			args:   []string{"send", "testreq", "--headers"},
-			respFn: respFnNoBodyOK,
-			p: morc.Project{
-				Templates: map[string]morc.RequestTemplate{
-					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
-				},
-			},
-			expectP: morc.Project{
-				Templates: map[string]morc.RequestTemplate{
-					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
-				},
-			},
-			expectStdoutOutput: `HTTP/1.1 200 OK
-------------------- HEADERS -------------------
-Content-Length: 0
------------------------------------------------
-(no response body)
-`,
-
-			expectProjectSaved: false,
-			expectHistorySaved: false,
-			expectSessionSaved: false,
-		},
-		{
-			name:   "suppress response",
-			args:   []string{"send", "testreq", "--no-body"},
-			respFn: respFnJSONBodyOK,
-			p: morc.Project{
-				Templates: map[string]morc.RequestTemplate{
-					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
-				},
-			},
-			expectP: morc.Project{
-				Templates: map[string]morc.RequestTemplate{
-					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
-				},
-			},
-			expectStdoutOutput: `HTTP/1.1 200 OK
-`,
+			expectErr: "auth flow login failed, so testreq was not sent",
 		},
 		{
-			name:   "print body captures",
-			args:   []string{"send", "testreq", "--captures"},
+			name:   "dump state masks secret-looking vars",
+			args:   []string{"send", "testreq", "--dump-state"},
 			respFn: respFnJSONBodyOK,
 			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
@@ -454,7 +680,8 @@ Content-Length: 0
 						Method: "GET",
 						URL:    "/",
 						Captures: map[string]morc.VarScraper{
-							"TEST": {Name: "TEST", OffsetStart: 18, OffsetEnd: 24},
+							"AUTH_TOKEN": {Name: "AUTH_TOKEN", OffsetStart: 18, OffsetEnd: 24},
+							"LAST":       {Name: "LAST", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "last"}}},
 						},
 					},
 				},
@@ -466,312 +693,2334 @@ Content-Length: 0
 						Method: "GET",
 						URL:    "/",
 						Captures: map[string]morc.VarScraper{
-							"TEST": {Name: "TEST", OffsetStart: 18, OffsetEnd: 24},
+							"AUTH_TOKEN": {Name: "AUTH_TOKEN", OffsetStart: 18, OffsetEnd: 24},
+							"LAST":       {Name: "LAST", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "last"}}},
 						},
 					},
 				},
 				Vars: testVarStore("", map[string]map[string]string{
-					"": {"TEST": "VRISKA"},
+					"": {"AUTH_TOKEN": "VRISKA", "LAST": "SERKET"},
 				}),
 			},
-			expectStdoutOutput: `----------------- VAR CAPTURES ----------------
-TEST: VRISKA
------------------------------------------------
-HTTP/1.1 200 OK
-{"name":{"first":"VRISKA","last":"SERKET"}}
-`,
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"name\":{\"first\":\"VRISKA\",\"last\":\"SERKET\"}}\n",
+			expectStderrOutput: "Client state:\nCookies:\n(none)\nVariables:\n * AUTH_TOKEN: ***\n * LAST: SERKET\n",
 			expectProjectSaved: true,
-			expectHistorySaved: false,
-			expectSessionSaved: false,
 		},
 		{
-			name:   "send template with var in url",
-			args:   []string{"send", "testreq", "--request"},
-			respFn: respFnNoBodyOK,
+			name:   "dump state masks vars marked as secret",
+			args:   []string{"send", "testreq", "--dump-state"},
+			respFn: respFnJSONBodyOK,
 			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
 					"testreq": {
 						Name:   "testreq",
 						Method: "GET",
-						URL:    "${PATH}",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"FIRST": {Name: "FIRST", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "first"}}},
+							"LAST":  {Name: "LAST", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "last"}}},
+						},
 					},
 				},
-				Vars: testVarStore("", map[string]map[string]string{
-					"": {"PATH": "/path"},
-				}),
+				Vars: func() morc.VarStore {
+					v := testVarStore("", map[string]map[string]string{"": {"FIRST": "ARADIA"}})
+					v.SetSecret("FIRST", true)
+					return v
+				}(),
 			},
 			expectP: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
 					"testreq": {
 						Name:   "testreq",
 						Method: "GET",
-						URL:    "${PATH}",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"FIRST": {Name: "FIRST", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "first"}}},
+							"LAST":  {Name: "LAST", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "last"}}},
+						},
 					},
 				},
-				Vars: testVarStore("", map[string]map[string]string{
-					"": {"PATH": "/path"},
-				}),
+				Vars: func() morc.VarStore {
+					v := testVarStore("", map[string]map[string]string{"": {"FIRST": "VRISKA", "LAST": "SERKET"}})
+					v.SetSecret("FIRST", true)
+					return v
+				}(),
 			},
-			expectStdoutOutput: `------------------- REQUEST -------------------
-Request URI: $TESTSERVER_URL$/path
-
-GET /path HTTP/1.1` + "\r" + `
-Host: $TESTSERVER_HOST$` + "\r" + `
-User-Agent: Go-http-client/1.1` + "\r" + `
-Accept-Encoding: gzip` + "\r" + `
-` + "\r" + `
-
-(no request body)
------------------ END REQUEST -----------------
-HTTP/1.1 200 OK
-(no response body)
-`,
-			expectProjectSaved: false,
-			expectHistorySaved: false,
-			expectSessionSaved: false,
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"name\":{\"first\":\"VRISKA\",\"last\":\"SERKET\"}}\n",
+			expectStderrOutput: "Client state:\nCookies:\n(none)\nVariables:\n * FIRST: ***\n * LAST: SERKET\n",
+			expectProjectSaved: true,
 		},
 		{
-			name:   "send template with var in headers",
-			args:   []string{"send", "testreq", "--request"},
-			respFn: respFnNoBodyOK,
+			name:   "dump state with secrets shown",
+			args:   []string{"send", "testreq", "--dump-state", "--show-secrets"},
+			respFn: respFnJSONBodyOK,
 			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
 					"testreq": {
-						Name:    "testreq",
-						Method:  "GET",
-						URL:     "/",
-						Headers: http.Header{"${API_KEY_HEADER}": []string{"${API_KEY}"}},
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"AUTH_TOKEN": {Name: "AUTH_TOKEN", OffsetStart: 18, OffsetEnd: 24},
+							"LAST":       {Name: "LAST", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "last"}}},
+						},
 					},
 				},
-				Vars: testVarStore("", map[string]map[string]string{
-					"": {"API_KEY": "fake", "API_KEY_HEADER": "X-Api-Key"},
-				}),
 			},
 			expectP: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
 					"testreq": {
-						Name:    "testreq",
-						Method:  "GET",
-						URL:     "/",
-						Headers: http.Header{"${API_KEY_HEADER}": []string{"${API_KEY}"}},
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"AUTH_TOKEN": {Name: "AUTH_TOKEN", OffsetStart: 18, OffsetEnd: 24},
+							"LAST":       {Name: "LAST", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "last"}}},
+						},
 					},
 				},
 				Vars: testVarStore("", map[string]map[string]string{
-					"": {"API_KEY": "fake", "API_KEY_HEADER": "X-Api-Key"},
+					"": {"AUTH_TOKEN": "VRISKA", "LAST": "SERKET"},
 				}),
 			},
-			expectStdoutOutput: `------------------- REQUEST -------------------
-Request URI: $TESTSERVER_URL$/
-
-GET / HTTP/1.1` + "\r" + `
-Host: $TESTSERVER_HOST$` + "\r" + `
-User-Agent: Go-http-client/1.1` + "\r" + `
-X-Api-Key: fake` + "\r" + `
-Accept-Encoding: gzip` + "\r" + `
-` + "\r" + `
-
-(no request body)
------------------ END REQUEST -----------------
-HTTP/1.1 200 OK
-(no response body)
-`,
-			expectProjectSaved: false,
-			expectHistorySaved: false,
-			expectSessionSaved: false,
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"name\":{\"first\":\"VRISKA\",\"last\":\"SERKET\"}}\n",
+			expectStderrOutput: "Client state:\nCookies:\n(none)\nVariables:\n * AUTH_TOKEN: VRISKA\n * LAST: SERKET\n",
+			expectProjectSaved: true,
 		},
 		{
-			name:   "send template with var in body",
-			args:   []string{"send", "testreq", "--request"},
-			respFn: respFnNoBodyOK,
+			name:   "show secrets without dump state",
+			args:   []string{"send", "testreq", "--show-secrets"},
+			respFn: respFnJSONBodyOK,
 			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
 					"testreq": {
 						Name:   "testreq",
 						Method: "GET",
 						URL:    "/",
-						Body:   []byte("special.key=${API_KEY}"),
+						Captures: map[string]morc.VarScraper{
+							"AUTH_TOKEN": {Name: "AUTH_TOKEN", OffsetStart: 18, OffsetEnd: 24},
+							"LAST":       {Name: "LAST", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "last"}}},
+						},
 					},
 				},
-				Vars: testVarStore("", map[string]map[string]string{
-					"": {"API_KEY": "fake"},
-				}),
 			},
-			expectP: morc.Project{
+			expectErr: "--show-secrets can only be used with --dump-state",
+		},
+		{
+			name:   "header assertion passes",
+			args:   []string{"send", "testreq", "--assert-header", "Content-Type: application/json"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
-					"testreq": {
-						Name:   "testreq",
-						Method: "GET",
-						URL:    "/",
-						Body:   []byte("special.key=${API_KEY}"),
-					},
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
 				},
-				Vars: testVarStore("", map[string]map[string]string{
-					"": {"API_KEY": "fake"},
-				}),
 			},
-			expectStdoutOutput: `------------------- REQUEST -------------------
-Request URI: $TESTSERVER_URL$/
-
-GET / HTTP/1.1` + "\r" + `
-Host: $TESTSERVER_HOST$` + "\r" + `
-User-Agent: Go-http-client/1.1` + "\r" + `
-Content-Length: 16` + "\r" + `
-Accept-Encoding: gzip` + "\r" + `
-` + "\r" + `
-special.key=fake
------------------ END REQUEST -----------------
-HTTP/1.1 200 OK
-(no response body)
-`,
-			expectProjectSaved: false,
-			expectHistorySaved: false,
-			expectSessionSaved: false,
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"name\":{\"first\":\"VRISKA\",\"last\":\"SERKET\"}}\n",
 		},
-
 		{
-			name:   "send template with CLI-overriden var in body",
-			args:   []string{"send", "testreq", "--request", "-V", "API_KEY=test-value"},
-			respFn: respFnNoBodyOK,
+			name:   "header assertion key is case-insensitive",
+			args:   []string{"send", "testreq", "--assert-header", "content-type: application/json"},
+			respFn: respFnJSONBodyOK,
 			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
-					"testreq": {
-						Name:   "testreq",
-						Method: "GET",
-						URL:    "/",
-						Body:   []byte("special.key=${API_KEY}"),
-					},
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
 				},
-				Vars: testVarStore("", map[string]map[string]string{
-					"": {"API_KEY": "fake"},
-				}),
 			},
-			expectP: morc.Project{
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"name\":{\"first\":\"VRISKA\",\"last\":\"SERKET\"}}\n",
+		},
+		{
+			name:   "header assertion with contains",
+			args:   []string{"send", "testreq", "--assert-header", "Content-Type: json", "--header-match", "contains"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
-					"testreq": {
-						Name:   "testreq",
-						Method: "GET",
-						URL:    "/",
-						Body:   []byte("special.key=${API_KEY}"),
-					},
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
 				},
-				Vars: testVarStore("", map[string]map[string]string{
-					"": {"API_KEY": "fake"},
-				}),
 			},
-			expectStdoutOutput: `------------------- REQUEST -------------------
-Request URI: $TESTSERVER_URL$/
-
-GET / HTTP/1.1` + "\r" + `
-Host: $TESTSERVER_HOST$` + "\r" + `
-User-Agent: Go-http-client/1.1` + "\r" + `
-Content-Length: 22` + "\r" + `
-Accept-Encoding: gzip` + "\r" + `
-` + "\r" + `
-special.key=test-value
------------------ END REQUEST -----------------
-HTTP/1.1 200 OK
-(no response body)
-`,
-			expectProjectSaved: false,
-			expectHistorySaved: false,
-			expectSessionSaved: false,
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"name\":{\"first\":\"VRISKA\",\"last\":\"SERKET\"}}\n",
 		},
 		{
-			name:   "send template with var in url, non-default prefix",
-			args:   []string{"send", "testreq", "--request"},
-			respFn: respFnNoBodyOK,
+			name:   "header assertion with regex",
+			args:   []string{"send", "testreq", "--assert-header", "Content-Type: ^application/(json|xml)$", "--header-match", "regex"},
+			respFn: respFnJSONBodyOK,
 			p: morc.Project{
 				Templates: map[string]morc.RequestTemplate{
-					"testreq": {
-						Name:   "testreq",
-						Method: "GET",
-						URL:    "^{PATH}",
-					},
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
 				},
-				Vars: testVarStore("", map[string]map[string]string{
-					"": {"PATH": "/path"},
-				}),
+			},
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"name\":{\"first\":\"VRISKA\",\"last\":\"SERKET\"}}\n",
+		},
+		{
+			name:   "header present assertion passes",
+			args:   []string{"send", "testreq", "--assert-header-present", "content-type"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"name\":{\"first\":\"VRISKA\",\"last\":\"SERKET\"}}\n",
+		},
+		{
+			name:   "header assertion fails",
+			args:   []string{"send", "testreq", "--assert-header", "Content-Type: text/html"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "assertion failed: header Content-Type: expected \"text/html\", got \"application/json\"",
+		},
+		{
+			name:   "header assertion fails with contains",
+			args:   []string{"send", "testreq", "--assert-header", "Content-Type: xml", "--header-match", "contains"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "assertion failed: header Content-Type: expected value containing \"xml\", got \"application/json\"",
+		},
+		{
+			name:   "multiple header assertions fail",
+			args:   []string{"send", "testreq", "--assert-header", "Content-Type: text/html", "--assert-header-present", "X-Request-Id"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "2 assertions failed:\n * header Content-Type: expected \"text/html\", got \"application/json\"\n * header X-Request-Id: expected to be present, but it is not",
+		},
+		{
+			name:      "resolve not in HOST:PORT:ADDR format",
+			args:      []string{"send", "testreq", "--resolve", "example.com:443"},
+			p:         testProject_singleReqWillAllPropertiesSet(),
+			expectErr: "resolve #1 (\"example.com:443\"): not in HOST:PORT:ADDR format",
+		},
+		{
+			name:      "key without cert",
+			args:      []string{"send", "testreq", "--key", "client.key"},
+			p:         testProject_singleReqWillAllPropertiesSet(),
+			expectErr: "--key can only be used with --cert",
+		},
+		{
+			name:      "http1 and http2 together",
+			args:      []string{"send", "testreq", "--http1", "--http2"},
+			p:         testProject_singleReqWillAllPropertiesSet(),
+			expectErr: "if any flags in the group [http1 http2] are set none of the others can be; [http1 http2] were all set",
+		},
+		{
+			name:   "set-json merges fields into template body",
+			args:   []string{"send", "testreq", "--set-json", "name=${NAME}", "--set-json", "age=13", "--set-json", "id:=\"8\""},
+			respFn: respFnEchoRequest,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "PATCH", URL: "/trolls", Body: []byte(`{"name": "Vriska", "lusus": "spider"}`)},
+				},
+				Vars: testVarStore("", map[string]map[string]string{"": {"NAME": "VRISKA"}}),
+			},
+			expectStdoutOutput: "HTTP/1.1 200 OK\nPATCH /trolls  {\"age\":13,\"id\":\"8\",\"lusus\":\"spider\",\"name\":\"VRISKA\"}\n",
+		},
+		{
+			name:               "set-json without template builds new body",
+			args:               []string{"send", "--url", "/trolls", "-X", "PUT", "--set-json", "name=VRISKA", "--set-json", "troll=true"},
+			respFn:             respFnEchoRequest,
+			p:                  morc.Project{Templates: map[string]morc.RequestTemplate{}},
+			expectStdoutOutput: "HTTP/1.1 200 OK\nPUT /trolls  {\"name\":\"VRISKA\",\"troll\":true}\n",
+		},
+		{
+			name: "set-json not in KEY=VALUE format",
+			args: []string{"send", "testreq", "--set-json", "name"},
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "PATCH", URL: "/"},
+				},
+			},
+			expectErr: "set-json #1 (\"name\"): not in KEY=VALUE or KEY:=JSON format",
+		},
+		{
+			name:   "set-json with body that is not an object",
+			args:   []string{"send", "testreq", "--set-json", "name=VRISKA"},
+			respFn: respFnEchoRequest,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "PATCH", URL: "/", Body: []byte(`"VRISKA"`)},
+				},
+			},
+			expectErr: "set JSON fields: body is not a JSON object",
+		},
+		{
+			name:   "status and body assertions pass",
+			args:   []string{"send", "testreq", "--assert-status", "200", "--assert-body-contains", "VRISKA"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"name\":{\"first\":\"VRISKA\",\"last\":\"SERKET\"}}\n",
+		},
+		{
+			name:   "status and body assertions fail",
+			args:   []string{"send", "testreq", "--assert-status", "404", "--assert-body-contains", "VRISKA", "--assert-body-contains", "ok"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "2 assertions failed:\n * status: expected 404, got 200 OK\n * body: expected to contain \"ok\", but it does not",
+		},
+		{
+			name:   "JSON assertions pass",
+			args:   []string{"send", "testreq", "--assert", ".name.first == \"VRISKA\"", "--assert", ".name.last"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectStdoutOutput: "HTTP/1.1 200 OK\n{\"name\":{\"first\":\"VRISKA\",\"last\":\"SERKET\"}}\n",
+		},
+		{
+			name:   "JSON assertion fails",
+			args:   []string{"send", "testreq", "--assert", ".name.first != \"VRISKA\""},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "assertion failed: json .name.first: expected != \"VRISKA\", got \"VRISKA\"",
+		},
+		{
+			name:   "invalid JSON assertion",
+			args:   []string{"send", "testreq", "--assert", ".name.first ~ \"V\""},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "assert #1 (\".name.first ~ \\\"V\\\"\"): path: unescaped whitespace character",
+		},
+		{
+			name:   "invalid status assertion",
+			args:   []string{"send", "testreq", "--assert-status", "1000"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "--assert-status: 1000 is not a valid HTTP status code",
+		},
+		{
+			name:   "header match without assert-header",
+			args:   []string{"send", "testreq", "--header-match", "contains"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "--header-match can only be used with --assert-header",
+		},
+		{
+			name:   "invalid header match",
+			args:   []string{"send", "testreq", "--assert-header", "Content-Type: json", "--header-match", "fuzzy"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "--header-match: not one of 'exact', 'contains', or 'regex': \"fuzzy\"",
+		},
+		{
+			name:   "invalid header assertion regex",
+			args:   []string{"send", "testreq", "--assert-header", "Content-Type: (json", "--header-match", "regex"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "assert-header #1 (\"Content-Type: (json\"): error parsing regexp",
+		},
+		{
+			name:   "header assertion without value",
+			args:   []string{"send", "testreq", "--assert-header", "Content-Type"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "assert-header #1 (\"Content-Type\") is not in format key: value",
+		},
+		{
+			name:   "manual cookies are sent",
+			args:   []string{"send", "testreq", "--cookie", "session=abc", "--cookie", "theme=dark"},
+			respFn: respFnEchoCookies,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "http://example.com/"},
+				},
+			},
+			expectStdoutOutput: "HTTP/1.1 200 OK\nsession=abc; theme=dark\n",
+		},
+		{
+			name:   "manual cookie with domain is sent to subdomain",
+			args:   []string{"send", "testreq", "--cookie", "theme=dark; Domain=example.com"},
+			respFn: respFnEchoCookies,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "http://api.example.com/"},
+				},
+			},
+			expectStdoutOutput: "HTTP/1.1 200 OK\ntheme=dark\n",
+		},
+		{
+			name:   "manual cookie with bad format",
+			args:   []string{"send", "testreq", "--cookie", "session"},
+			respFn: respFnEchoCookies,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "http://example.com/"},
+				},
+			},
+			expectErr: "cookie #1 (\"session\"): not in NAME=VALUE[;ATTR=VALUE]... format",
+		},
+		{
+			name:   "send saves body captures - entire request",
+			args:   []string{"send", "testreq"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"TEST": {Name: "TEST"},
+						},
+					},
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"TEST": {Name: "TEST"},
+						},
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"TEST": `{"name":{"first":"VRISKA","last":"SERKET"}}`},
+				}),
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+{"name":{"first":"VRISKA","last":"SERKET"}}
+`,
+			expectProjectSaved: true,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		}, {
+			name:   "send saves body captures - entire request -2",
+			args:   []string{"send", "testreq"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"TEST": {Name: "TEST", OffsetEnd: -2},
+						},
+					},
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"TEST": {Name: "TEST", OffsetEnd: -2},
+						},
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"TEST": `{"name":{"first":"VRISKA","last":"SERKET"`},
+				}),
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+{"name":{"first":"VRISKA","last":"SERKET"}}
+`,
+			expectProjectSaved: true,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "request has a body",
+			args:   []string{"send", "testreq"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/", Body: []byte("testbody")},
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/", Body: []byte("testbody")},
+				},
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "request has headers",
+			args:   []string{"send", "testreq"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/", Headers: http.Header{"X-Test": []string{"test"}}},
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/", Headers: http.Header{"X-Test": []string{"test"}}},
+				},
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "print request",
+			args:   []string{"send", "testreq", "--request"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/", Body: []byte("testvalue"), Headers: http.Header{"X-Test": []string{"test"}}},
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/", Body: []byte("testvalue"), Headers: http.Header{"X-Test": []string{"test"}}},
+				},
+			},
+			expectStdoutOutput: `------------------- REQUEST -------------------
+Request URI: $TESTSERVER_URL$/
+
+GET / HTTP/1.1` + "\r" + `
+Host: $TESTSERVER_HOST$` + "\r" + `
+User-Agent: Go-http-client/1.1` + "\r" + `
+Content-Length: 9` + "\r" + `
+X-Test: test` + "\r" + `
+Accept-Encoding: gzip` + "\r" + `
+` + "\r" + `
+testvalue
+----------------- END REQUEST -----------------
+HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "print request resolves auth header from default env",
+			args:   []string{"send", "testreq", "--request"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/", Headers: http.Header{"Authorization": []string{"Bearer ${TOKEN}"}}},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"":        {"TOKEN": "default-token"},
+					"STAGING": {"TOKEN": "staging-token"},
+				}),
+			},
+			expectStdoutOutput: `------------------- REQUEST -------------------
+Request URI: $TESTSERVER_URL$/
+
+GET / HTTP/1.1` + "\r" + `
+Host: $TESTSERVER_HOST$` + "\r" + `
+User-Agent: Go-http-client/1.1` + "\r" + `
+Authorization: Bearer default-token` + "\r" + `
+Accept-Encoding: gzip` + "\r" + `
+` + "\r" + `
+
+(no request body)
+----------------- END REQUEST -----------------
+HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "print request resolves auth header from STAGING env",
+			args:   []string{"send", "testreq", "--request"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/", Headers: http.Header{"Authorization": []string{"Bearer ${TOKEN}"}}},
+				},
+				Vars: testVarStore("STAGING", map[string]map[string]string{
+					"":        {"TOKEN": "default-token"},
+					"STAGING": {"TOKEN": "staging-token"},
+				}),
+			},
+			expectStdoutOutput: `------------------- REQUEST -------------------
+Request URI: $TESTSERVER_URL$/
+
+GET / HTTP/1.1` + "\r" + `
+Host: $TESTSERVER_HOST$` + "\r" + `
+User-Agent: Go-http-client/1.1` + "\r" + `
+Authorization: Bearer staging-token` + "\r" + `
+Accept-Encoding: gzip` + "\r" + `
+` + "\r" + `
+
+(no request body)
+----------------- END REQUEST -----------------
+HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "print response headers",
+			args:   []string{"send", "testreq", "--headers"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+------------------- HEADERS -------------------
+Content-Length: 0
+-----------------------------------------------
+(no response body)
+`,
+
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "suppress response",
+			args:   []string{"send", "testreq", "--no-body"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+`,
+		},
+		{
+			name:   "print body captures",
+			args:   []string{"send", "testreq", "--captures"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"TEST": {Name: "TEST", OffsetStart: 18, OffsetEnd: 24},
+						},
+					},
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"TEST": {Name: "TEST", OffsetStart: 18, OffsetEnd: 24},
+						},
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"TEST": "VRISKA"},
+				}),
+			},
+			expectStdoutOutput: `----------------- VAR CAPTURES ----------------
+TEST: VRISKA
+-----------------------------------------------
+HTTP/1.1 200 OK
+{"name":{"first":"VRISKA","last":"SERKET"}}
+`,
+			expectProjectSaved: true,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "verbose prints request, headers, and captures",
+			args:   []string{"send", "testreq", "-v"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"TEST": {Name: "TEST", OffsetStart: 18, OffsetEnd: 24},
+						},
+					},
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Captures: map[string]morc.VarScraper{
+							"TEST": {Name: "TEST", OffsetStart: 18, OffsetEnd: 24},
+						},
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"TEST": "VRISKA"},
+				}),
+			},
+			expectStdoutOutput: `------------------- REQUEST -------------------
+Request URI: $TESTSERVER_URL$/
+
+GET / HTTP/1.1` + "\r" + `
+Host: $TESTSERVER_HOST$` + "\r" + `
+User-Agent: Go-http-client/1.1` + "\r" + `
+Accept-Encoding: gzip` + "\r" + `
+` + "\r" + `
+
+(no request body)
+----------------- END REQUEST -----------------
+----------------- VAR CAPTURES ----------------
+TEST: VRISKA
+-----------------------------------------------
+HTTP/1.1 200 OK
+------------------- HEADERS -------------------
+Content-Length: 43
+Content-Type: application/json
+-----------------------------------------------
+{"name":{"first":"VRISKA","last":"SERKET"}}
+`,
+			expectProjectSaved: true,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "quiet outputs only the body",
+			args:   []string{"send", "testreq", "-q"},
+			respFn: respFnJSONBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectStdoutOutput: `{"name":{"first":"VRISKA","last":"SERKET"}}`,
+		},
+		{
+			name:   "quiet with no body outputs nothing",
+			args:   []string{"send", "testreq", "-q"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectStdoutOutput: "",
+		},
+		{
+			name:   "quiet not allowed with other output flags",
+			args:   []string{"send", "testreq", "-q", "--headers"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "--quiet/-q only outputs the response body and cannot be used with other output flags",
+		},
+		{
+			name:   "show-redirects not allowed in sr format",
+			args:   []string{"send", "testreq", "--show-redirects", "-f", "sr"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "format 'sr' only allows status line and response body",
+		},
+		{
+			name:   "quiet not allowed in json format",
+			args:   []string{"send", "testreq", "-q", "-f", "json"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "--quiet/-q cannot be used with format 'json'",
+		},
+		{
+			name:   "verbose not allowed in sr format",
+			args:   []string{"send", "testreq", "-v", "-f", "sr"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "format 'sr' only allows status line and response body",
+		},
+		{
+			name:   "send template with var in url",
+			args:   []string{"send", "testreq", "--request"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "${PATH}",
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"PATH": "/path"},
+				}),
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "${PATH}",
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"PATH": "/path"},
+				}),
+			},
+			expectStdoutOutput: `------------------- REQUEST -------------------
+Request URI: $TESTSERVER_URL$/path
+
+GET /path HTTP/1.1` + "\r" + `
+Host: $TESTSERVER_HOST$` + "\r" + `
+User-Agent: Go-http-client/1.1` + "\r" + `
+Accept-Encoding: gzip` + "\r" + `
+` + "\r" + `
+
+(no request body)
+----------------- END REQUEST -----------------
+HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "send template with var in headers",
+			args:   []string{"send", "testreq", "--request"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:    "testreq",
+						Method:  "GET",
+						URL:     "/",
+						Headers: http.Header{"${API_KEY_HEADER}": []string{"${API_KEY}"}},
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"API_KEY": "fake", "API_KEY_HEADER": "X-Api-Key"},
+				}),
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:    "testreq",
+						Method:  "GET",
+						URL:     "/",
+						Headers: http.Header{"${API_KEY_HEADER}": []string{"${API_KEY}"}},
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"API_KEY": "fake", "API_KEY_HEADER": "X-Api-Key"},
+				}),
+			},
+			expectStdoutOutput: `------------------- REQUEST -------------------
+Request URI: $TESTSERVER_URL$/
+
+GET / HTTP/1.1` + "\r" + `
+Host: $TESTSERVER_HOST$` + "\r" + `
+User-Agent: Go-http-client/1.1` + "\r" + `
+X-Api-Key: fake` + "\r" + `
+Accept-Encoding: gzip` + "\r" + `
+` + "\r" + `
+
+(no request body)
+----------------- END REQUEST -----------------
+HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "send template with var in body",
+			args:   []string{"send", "testreq", "--request"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Body:   []byte("special.key=${API_KEY}"),
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"API_KEY": "fake"},
+				}),
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Body:   []byte("special.key=${API_KEY}"),
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"API_KEY": "fake"},
+				}),
+			},
+			expectStdoutOutput: `------------------- REQUEST -------------------
+Request URI: $TESTSERVER_URL$/
+
+GET / HTTP/1.1` + "\r" + `
+Host: $TESTSERVER_HOST$` + "\r" + `
+User-Agent: Go-http-client/1.1` + "\r" + `
+Content-Length: 16` + "\r" + `
+Accept-Encoding: gzip` + "\r" + `
+` + "\r" + `
+special.key=fake
+----------------- END REQUEST -----------------
+HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+
+		{
+			name:   "send template with CLI-overriden var in body",
+			args:   []string{"send", "testreq", "--request", "-V", "API_KEY=test-value"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Body:   []byte("special.key=${API_KEY}"),
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"API_KEY": "fake"},
+				}),
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Body:   []byte("special.key=${API_KEY}"),
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"API_KEY": "fake"},
+				}),
+			},
+			expectStdoutOutput: `------------------- REQUEST -------------------
+Request URI: $TESTSERVER_URL$/
+
+GET / HTTP/1.1` + "\r" + `
+Host: $TESTSERVER_HOST$` + "\r" + `
+User-Agent: Go-http-client/1.1` + "\r" + `
+Content-Length: 22` + "\r" + `
+Accept-Encoding: gzip` + "\r" + `
+` + "\r" + `
+special.key=test-value
+----------------- END REQUEST -----------------
+HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "send template with transformed secret var in body",
+			args:   []string{"send", "testreq", "--request"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Body:   []byte("key=${API_KEY|base64}&q=${API_KEY|urlencode}"),
+					},
+				},
+				Vars: func() morc.VarStore {
+					v := testVarStore("", map[string]map[string]string{"": {"API_KEY": "fake&1"}})
+					v.SetSecret("API_KEY", true)
+					return v
+				}(),
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/",
+						Body:   []byte("key=${API_KEY|base64}&q=${API_KEY|urlencode}"),
+					},
+				},
+				Vars: func() morc.VarStore {
+					v := testVarStore("", map[string]map[string]string{"": {"API_KEY": "fake&1"}})
+					v.SetSecret("API_KEY", true)
+					return v
+				}(),
+			},
+			expectStdoutOutput: `------------------- REQUEST -------------------
+Request URI: $TESTSERVER_URL$/
+
+GET / HTTP/1.1` + "\r" + `
+Host: $TESTSERVER_HOST$` + "\r" + `
+User-Agent: Go-http-client/1.1` + "\r" + `
+Content-Length: 23` + "\r" + `
+Accept-Encoding: gzip` + "\r" + `
+` + "\r" + `
+key=***&q=***
+----------------- END REQUEST -----------------
+HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "send template with var in url, non-default prefix",
+			args:   []string{"send", "testreq", "--request"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "^{PATH}",
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"PATH": "/path"},
+				}),
+				Config: morc.Settings{
+					VarPrefix: "^",
+				},
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "^{PATH}",
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"PATH": "/path"},
+				}),
 				Config: morc.Settings{
 					VarPrefix: "^",
 				},
 			},
-			expectP: morc.Project{
-				Templates: map[string]morc.RequestTemplate{
-					"testreq": {
-						Name:   "testreq",
-						Method: "GET",
-						URL:    "^{PATH}",
-					},
-				},
-				Vars: testVarStore("", map[string]map[string]string{
-					"": {"PATH": "/path"},
-				}),
-				Config: morc.Settings{
-					VarPrefix: "^",
-				},
+			expectStdoutOutput: `------------------- REQUEST -------------------
+Request URI: $TESTSERVER_URL$/path
+
+GET /path HTTP/1.1` + "\r" + `
+Host: $TESTSERVER_HOST$` + "\r" + `
+User-Agent: Go-http-client/1.1` + "\r" + `
+Accept-Encoding: gzip` + "\r" + `
+` + "\r" + `
+
+(no request body)
+----------------- END REQUEST -----------------
+HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		}, {
+			name:   "send template with var in url, prefix override",
+			args:   []string{"send", "testreq", "--request", "-p", "^"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "^{PATH}",
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"PATH": "/path"},
+				}),
+			},
+			expectP: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "^{PATH}",
+					},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"PATH": "/path"},
+				}),
+			},
+			expectStdoutOutput: `------------------- REQUEST -------------------
+Request URI: $TESTSERVER_URL$/path
+
+GET /path HTTP/1.1` + "\r" + `
+Host: $TESTSERVER_HOST$` + "\r" + `
+User-Agent: Go-http-client/1.1` + "\r" + `
+Accept-Encoding: gzip` + "\r" + `
+` + "\r" + `
+
+(no request body)
+----------------- END REQUEST -----------------
+HTTP/1.1 200 OK
+(no response body)
+`,
+			expectProjectSaved: false,
+			expectHistorySaved: false,
+			expectSessionSaved: false,
+		},
+		{
+			name:   "ad-hoc request - defaults to GET",
+			args:   []string{"send", "--url", "/users"},
+			respFn: respFnEchoRequest,
+			p:      morc.Project{},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+GET /users  
+`,
+		},
+		{
+			name:   "ad-hoc request - method, header, and body with vars",
+			args:   []string{"send", "--url", "/users/${ID}", "-X", "post", "-H", "X-Name: ${NAME}", "-d", `{"id": "${ID}"}`},
+			respFn: respFnEchoRequest,
+			p:      testProject_vars("", map[string]map[string]string{"": {"ID": "413", "NAME": "VRISKA"}}),
+			expectStdoutOutput: `HTTP/1.1 200 OK
+POST /users/413 VRISKA {"id": "413"}
+`,
+		},
+		{
+			name:   "no substitute headers",
+			args:   []string{"send", "--url", "/users/${ID}", "-H", "X-Name: ${NAME}", "--no-substitute-headers"},
+			respFn: respFnEchoRequest,
+			p:      testProject_vars("", map[string]map[string]string{"": {"ID": "413", "NAME": "VRISKA"}}),
+			expectStdoutOutput: `HTTP/1.1 200 OK
+GET /users/413 ${NAME} 
+`,
+		},
+		{
+			name:   "ad-hoc request - captures are saved but template is not",
+			args:   []string{"send", "--url", "/", "-C", "NAME:.name.first"},
+			respFn: respFnJSONBodyOK,
+			p:      morc.Project{},
+			expectP: morc.Project{
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"NAME": "VRISKA"},
+				}),
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+{"name":{"first":"VRISKA","last":"SERKET"}}
+`,
+			expectProjectSaved: true,
+		},
+		{
+			name:      "ad-hoc request - auth flow does not exist",
+			args:      []string{"send", "--url", "/", "--auth-flow", "login"},
+			respFn:    respFnNoBodyOK,
+			p:         morc.Project{},
+			expectErr: "no flow named login exists in project",
+		},
+		{
+			name:   "retry on body match",
+			args:   []string{"send", "testreq", "--retry-on-body-match", `status":"pending`, "--retry-backoff", "1ms"},
+			respFn: respFnPendingTwice,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+{"status":"done","calls":3}
+`,
+		},
+		{
+			name:   "--retry-max-attempts without --retry-on-body-match",
+			args:   []string{"send", "testreq", "--retry-max-attempts", "3"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "--retry-max-attempts can only be used with --retry-on-body-match",
+		},
+		{
+			name:   "--retry-backoff must be positive",
+			args:   []string{"send", "testreq", "--retry-on-body-match", "pending", "--retry-backoff", "0s"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "--retry-backoff must be a positive duration",
+		},
+		{
+			name:   "--output with --no-body",
+			args:   []string{"send", "testreq", "-o", "body.txt", "--no-body"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "--no-body cannot be used with --output",
+		},
+		{
+			name:   "timings not allowed in sr format",
+			args:   []string{"send", "testreq", "--timings", "-f", "sr"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "format 'sr' only allows status line and response body",
+		},
+		{
+			name:   "headers not allowed in json format",
+			args:   []string{"send", "testreq", "--headers", "-f", "json"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "format 'json' always includes the request, headers, captures, redirects, and timings",
+		},
+		{
+			name:   "stats not allowed in json format",
+			args:   []string{"send", "testreq", "--stats", "-f", "json"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "--stats cannot be used with format 'json'",
+		},
+		{
+			name:      "neither REQ nor --url",
+			args:      []string{"send"},
+			respFn:    respFnNoBodyOK,
+			p:         morc.Project{},
+			expectErr: "REQ, --url, or --tag is required",
+		},
+		{
+			name:   "REQ with method override",
+			args:   []string{"send", "testreq", "-X", "post"},
+			respFn: respFnEchoRequest,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/users"},
+				},
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+POST /users  
+`,
+		},
+		{
+			name:   "HEAD request shows Content-Length instead of body",
+			args:   []string{"send", "testreq", "-X", "HEAD", "--headers"},
+			respFn: respFnEchoRequest,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/users"},
+				},
+			},
+			expectStdoutOutput: `HTTP/1.1 200 OK
+------------------- HEADERS -------------------
+Content-Length: 13
+Content-Type: text/plain; charset=utf-8
+-----------------------------------------------
+(HEAD response; Content-Length: 13)
+`,
+		},
+		{
+			name:   "HEAD request fails body captures",
+			args:   []string{"send", "testreq", "-X", "HEAD"},
+			respFn: respFnEchoRequest,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {
+						Name:   "testreq",
+						Method: "GET",
+						URL:    "/users",
+						Captures: map[string]morc.VarScraper{
+							"METHOD": {Name: "METHOD", OffsetStart: 0, OffsetEnd: 4},
+						},
+					},
+				},
+			},
+			expectErr: "scrape METHOD: response to HEAD request has no body",
+		},
+		{
+			name:   "REQ with request flags",
+			args:   []string{"send", "testreq", "-d", "VRISKA"},
+			respFn: respFnNoBodyOK,
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"testreq": {Name: "testreq", Method: "GET", URL: "/"},
+				},
+			},
+			expectErr: "can only be used when REQ is not given",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// setup test server
+			srv := httptest.NewServer(http.HandlerFunc(tc.respFn))
+			defer srv.Close()
+			srvClient := srv.Client()
+
+			// inject a custom transport so we always append the server root URL
+			srvClient.Transport = urlBaseRoundTripper{
+				base: srv.URL,
+				old:  srvClient.Transport,
+			}
+
+			// make shore that expected historic entries have proper prefix
+			if tc.expectP.History != nil {
+				for i := range tc.expectP.History {
+					tc.expectP.History[i].Request.URL = mustParseURL(srv.URL + tc.expectP.History[i].Request.URL.Path)
+				}
+			}
+
+			// make shore that expected session set-cookie-calls have proper prefix
+			if tc.expectP.Session.Cookies != nil {
+				for i := range tc.expectP.Session.Cookies {
+					tc.expectP.Session.Cookies[i].URL = mustParseURL(srv.URL + tc.expectP.Session.Cookies[i].URL.Path)
+				}
+			}
+
+			// make shore stdout output replaces server things
+			tc.expectStdoutOutput = strings.ReplaceAll(tc.expectStdoutOutput, "$TESTSERVER_URL$", srv.URL)
+			srvHost := mustParseURL(srv.URL).Host
+			tc.expectStdoutOutput = strings.ReplaceAll(tc.expectStdoutOutput, "$TESTSERVER_HOST$", srvHost)
+
+			cmdio.HTTPClient = srvClient
+
+			resetSendFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(sendCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			if tc.expectProjectSaved {
+				assert_projectPersistedToBuffer(assert, tc.expectP)
+			} else {
+				assert_noProjectFileMutations(assert)
+			}
+
+			if tc.expectHistorySaved {
+				assert_historyPersistedToBuffer(assert, tc.expectP.History)
+			} else {
+				assert_noHistoryFileMutations(assert)
+			}
+
+			if tc.expectSessionSaved {
+				assert_sessionPersistedToBuffer(assert, tc.expectP.Session)
+			} else {
+				assert_noSessionFileMutations(assert)
+			}
+		})
+	}
+}
+
+func Test_Send_Output(t *testing.T) {
+	respFnJSONBodyOK := func(w http.ResponseWriter, r *http.Request) {
+		// suppress date header
+		w.Header()["Date"] = nil
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"name":"VRISKA"}`))
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F or -o; they are automatically set
+		tmpl               morc.RequestTemplate
+		expectStdoutOutput string
+		expectVars         map[string]string
+	}{
+		{
+			name:               "body is streamed to file",
+			args:               []string{"send", "testreq"},
+			tmpl:               morc.RequestTemplate{Name: "testreq", Method: "GET", URL: "/"},
+			expectStdoutOutput: "HTTP/1.1 200 OK\n",
+		},
+		{
+			name: "body is still captured from",
+			args: []string{"send", "testreq"},
+			tmpl: morc.RequestTemplate{
+				Name:   "testreq",
+				Method: "GET",
+				URL:    "/",
+				Captures: map[string]morc.VarScraper{
+					"NAME": {Name: "NAME", Steps: []morc.TraversalStep{{Key: "name"}}},
+				},
+			},
+			expectStdoutOutput: "HTTP/1.1 200 OK\n",
+			expectVars:         map[string]string{"NAME": "VRISKA"},
+		},
+		{
+			name:               "line format",
+			args:               []string{"send", "testreq", "-f", "line"},
+			tmpl:               morc.RequestTemplate{Name: "testreq", Method: "GET", URL: "/"},
+			expectStdoutOutput: "HTTP/1.1 200 OK\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// setup test server
+			srv := httptest.NewServer(http.HandlerFunc(respFnJSONBodyOK))
+			defer srv.Close()
+			srvClient := srv.Client()
+
+			// inject a custom transport so we always append the server root URL
+			srvClient.Transport = urlBaseRoundTripper{
+				base: srv.URL,
+				old:  srvClient.Transport,
+			}
+			cmdio.HTTPClient = srvClient
+
+			resetSendFlags()
+
+			outFile := filepath.Join(t.TempDir(), "body.json")
+			args := append(tc.args, "-o", outFile)
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, testProject_withRequests(tc.tmpl))
+			// set up the root command and run
+			output, _, err := runTestCommand(sendCmd, projFilePath, args)
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+
+			body, err := os.ReadFile(outFile)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(`{"name":"VRISKA"}`, string(body))
+
+			if tc.expectVars != nil {
+				assert_projectPersistedToBuffer(assert, morc.Project{
+					Templates: map[string]morc.RequestTemplate{"testreq": tc.tmpl},
+					Vars:      testVarStore("", map[string]map[string]string{"": tc.expectVars}),
+				})
+			}
+		})
+	}
+}
+
+func Test_Send_Output_FailedSendKeepsFile(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"oops"}`))
+	}))
+	defer srv.Close()
+	srvClient := srv.Client()
+	srvClient.Transport = urlBaseRoundTripper{
+		base: srv.URL,
+		old:  srvClient.Transport,
+	}
+	cmdio.HTTPClient = srvClient
+
+	resetSendFlags()
+
+	outDir := t.TempDir()
+	outFile := filepath.Join(outDir, "body.json")
+	if err := os.WriteFile(outFile, []byte(`{"name":"VRISKA"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	projFilePath := createTestProjectIO(t, testProject_withRequests(morc.RequestTemplate{Name: "testreq", Method: "GET", URL: "/"}))
+	_, _, err := runTestCommand(sendCmd, projFilePath, []string{"send", "testreq", "--assert-status", "200", "-o", outFile})
+	assert.Error(err)
+
+	body, err := os.ReadFile(outFile)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(`{"name":"VRISKA"}`, string(body))
+
+	// the temporary file is cleaned up
+	entries, err := os.ReadDir(outDir)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Len(entries, 1)
+}
+
+func Test_Send_JSONFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	// setup test server
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"8"}`))
+	}))
+	defer srv.Close()
+	srvClient := srv.Client()
+
+	// inject a custom transport so we always append the server root URL
+	srvClient.Transport = urlBaseRoundTripper{
+		base: srv.URL,
+		old:  srvClient.Transport,
+	}
+	cmdio.HTTPClient = srvClient
+
+	resetSendFlags()
+
+	tmpl := morc.RequestTemplate{
+		Name:    "testreq",
+		Method:  "POST",
+		URL:     "/users",
+		Headers: http.Header{"X-Name": {"VRISKA"}},
+		Body:    []byte(`{"name":"VRISKA"}`),
+		Captures: map[string]morc.VarScraper{
+			"ID": {Name: "ID", Steps: []morc.TraversalStep{{Key: "id"}}},
+		},
+	}
+
+	// create project and dump config to a temp dir
+	projFilePath := createTestProjectIO(t, testProject_withRequests(tmpl))
+	// set up the root command and run
+	output, _, err := runTestCommand(sendCmd, projFilePath, []string{"send", "testreq", "-f", "json"})
+	if !assert.NoError(err) {
+		return
+	}
+
+	var result struct {
+		Request struct {
+			Method  string              `json:"method"`
+			URL     string              `json:"url"`
+			Headers map[string][]string `json:"headers"`
+		} `json:"request"`
+		StatusCode int                 `json:"status_code"`
+		Headers    map[string][]string `json:"headers"`
+		Body       string              `json:"body"`
+		Captures   map[string]string   `json:"captures"`
+		Timings    map[string]float64  `json:"timings"`
+	}
+	if !assert.NoError(json.Unmarshal([]byte(output), &result), "output is not a single JSON object") {
+		return
+	}
+
+	assert.Equal("POST", result.Request.Method)
+	assert.Equal(srv.URL+"/users", result.Request.URL)
+	assert.Equal([]string{"VRISKA"}, result.Request.Headers["X-Name"])
+	assert.Equal(http.StatusCreated, result.StatusCode)
+	assert.Equal([]string{"application/json"}, result.Headers["Content-Type"])
+	assert.Equal(`{"id":"8"}`, result.Body)
+	assert.Equal(map[string]string{"ID": "8"}, result.Captures)
+	assert.Contains(result.Timings, "total_ms")
+}
+
+func Test_Send_BodyFile(t *testing.T) {
+	assert := assert.New(t)
+
+	// setup test server that echoes back the request body
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+	srvClient := srv.Client()
+
+	// inject a custom transport so we always append the server root URL
+	srvClient.Transport = urlBaseRoundTripper{
+		base: srv.URL,
+		old:  srvClient.Transport,
+	}
+	cmdio.HTTPClient = srvClient
+
+	bodyFilePath := filepath.Join(t.TempDir(), "body.json")
+	tmpl := morc.RequestTemplate{
+		Name:     "testreq",
+		Method:   "POST",
+		URL:      "/users",
+		BodyFile: bodyFilePath,
+	}
+
+	// the file is read fresh on every send
+	for _, name := range []string{"VRISKA", "NEPETA"} {
+		resetSendFlags()
+		projFilePath := createTestProjectIO(t, testProject_withRequests(tmpl))
+
+		if err := os.WriteFile(bodyFilePath, []byte(`{"name":"`+name+`","id":"${ID}"}`), 0644); err != nil {
+			t.Fatalf("failed to write body file: %v", err)
+		}
+
+		output, _, err := runTestCommand(sendCmd, projFilePath, []string{"send", "testreq", "-q", "-V", "ID=8"})
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(`{"name":"`+name+`","id":"8"}`, output)
+	}
+
+	// a missing file is an error at send time
+	resetSendFlags()
+	projFilePath := createTestProjectIO(t, testProject_withRequests(tmpl))
+	if err := os.Remove(bodyFilePath); err != nil {
+		t.Fatalf("failed to remove body file: %v", err)
+	}
+	_, _, err := runTestCommand(sendCmd, projFilePath, []string{"send", "testreq"})
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "read body file")
+	}
+}
+
+func Test_Send_Form(t *testing.T) {
+	// setup test server that echoes back the content type and request body
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(r.Header.Get("Content-Type") + "\n"))
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+	srvClient := srv.Client()
+
+	// inject a custom transport so we always append the server root URL
+	srvClient.Transport = urlBaseRoundTripper{
+		base: srv.URL,
+		old:  srvClient.Transport,
+	}
+	cmdio.HTTPClient = srvClient
+
+	// the value of V is encoded only once it is filled in, and the escaped
+	// reference is kept as it is
+	const expectOutput = "application/x-www-form-urlencoded\na=1&name=a%26b%3Dc%20d&lit=%24%24%7BV%7D"
+
+	t.Run("request template", func(t *testing.T) {
+		assert := assert.New(t)
+
+		tmpl := morc.RequestTemplate{
+			Name:   "testreq",
+			Method: "POST",
+			URL:    "/form",
+			Body:   []byte("a=1"),
+			Form:   []morc.FormField{{Name: "name", Value: "${V}"}, {Name: "lit", Value: "$${V}"}},
+		}
+
+		resetSendFlags()
+		projFilePath := createTestProjectIO(t, testProject_withRequests(tmpl))
+		output, _, err := runTestCommand(sendCmd, projFilePath, []string{"send", "testreq", "-q", "-V", "V=a&b=c d"})
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(expectOutput, output)
+	})
+
+	t.Run("ad-hoc request", func(t *testing.T) {
+		assert := assert.New(t)
+
+		resetSendFlags()
+		projFilePath := createTestProjectIO(t, morc.Project{})
+		output, _, err := runTestCommand(sendCmd, projFilePath, []string{"send", "--url", "/form", "-X", "POST", "-d", "a=1", "--data-urlencode", "name=${V}", "--data-urlencode", "lit=$${V}", "-q", "-V", "V=a&b=c d"})
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(expectOutput, output)
+	})
+}
+
+func Test_Send_EnvFallback(t *testing.T) {
+	t.Setenv("MORC_TEST_TOKEN", "env-token")
+
+	// setup test server that echoes back the Authorization header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+	srvClient := srv.Client()
+
+	// inject a custom transport so we always append the server root URL
+	srvClient.Transport = urlBaseRoundTripper{
+		base: srv.URL,
+		old:  srvClient.Transport,
+	}
+	cmdio.HTTPClient = srvClient
+
+	tmpl := morc.RequestTemplate{
+		Name:    "testreq",
+		Method:  "GET",
+		URL:     "/",
+		Headers: http.Header{"Authorization": {"Bearer ${MORC_TEST_TOKEN}"}},
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string
+		expectStdoutOutput string
+	}{
+		{
+			name:      "off by default",
+			args:      []string{"send", "testreq", "-q"},
+			p:         testProject_withRequests(tmpl),
+			expectErr: "variable MORC_TEST_TOKEN not found",
+		},
+		{
+			name:               "flag given",
+			args:               []string{"send", "testreq", "-q", "--env-fallback"},
+			p:                  testProject_withRequests(tmpl),
+			expectStdoutOutput: "Bearer env-token",
+		},
+		{
+			name: "project setting on",
+			args: []string{"send", "testreq", "-q"},
+			p: func() morc.Project {
+				p := testProject_withRequests(tmpl)
+				p.Config.EnvFallback = true
+				return p
+			}(),
+			expectStdoutOutput: "Bearer env-token",
+		},
+		{
+			name:               "var given with -V takes precedence",
+			args:               []string{"send", "testreq", "-q", "--env-fallback", "-V", "MORC_TEST_TOKEN=flag-token"},
+			p:                  testProject_withRequests(tmpl),
+			expectStdoutOutput: "Bearer flag-token",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetSendFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+
+			// set up the root command and run
+			output, _, err := runTestCommand(sendCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if tc.expectErr != "" {
+				if assert.Error(err) {
+					assert.Contains(err.Error(), tc.expectErr)
+				}
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output)
+		})
+	}
+}
+
+func Test_Send_RedactsOneTimeSecretsInHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	srvClient := srv.Client()
+	srvClient.Transport = urlBaseRoundTripper{
+		base: srv.URL,
+		old:  srvClient.Transport,
+	}
+	cmdio.HTTPClient = srvClient
+
+	resetSendFlags()
+
+	p := testProject_withRequests(morc.RequestTemplate{
+		Name:    "testreq",
+		Method:  "GET",
+		URL:     "/",
+		Headers: http.Header{"Authorization": {"Bearer ${TOKEN}"}},
+	})
+	p.Vars.Set("TOKEN", "stored-token")
+	p.Vars.SetSecret("TOKEN", true)
+	p.Config.HistFile = "::PROJ_DIR::/history.json"
+	p.Config.RecordHistory = true
+
+	projFilePath := createTestProjectIO(t, p)
+	_, _, err := runTestCommand(sendCmd, projFilePath, []string{"send", "testreq", "-q", "-V", "TOKEN=one-time-token"})
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.NotContains(histWriter.(*bytes.Buffer).String(), "one-time-token")
+
+	entries, err := morc.LoadHistory(strings.NewReader(lastJSONValue(t, histWriter.(*bytes.Buffer))))
+	if !assert.NoError(err) || !assert.Len(entries, 1) {
+		return
+	}
+	assert.Equal("Bearer ***", entries[0].Request.Header.Get("Authorization"))
+	assert.True(entries[0].Redacted)
+}
+
+func Test_Send_RedactsTransformedSecretsInHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	srvClient := srv.Client()
+	srvClient.Transport = urlBaseRoundTripper{
+		base: srv.URL,
+		old:  srvClient.Transport,
+	}
+	cmdio.HTTPClient = srvClient
+
+	resetSendFlags()
+
+	p := testProject_withRequests(morc.RequestTemplate{
+		Name:    "testreq",
+		Method:  "GET",
+		URL:     "/?q=${PASS|urlencode}",
+		Headers: http.Header{"Authorization": {"Basic ${PASS|base64}"}},
+	})
+	p.Vars.Set("PASS", "hunter2&x")
+	p.Vars.SetSecret("PASS", true)
+	p.Config.HistFile = "::PROJ_DIR::/history.json"
+	p.Config.RecordHistory = true
+
+	projFilePath := createTestProjectIO(t, p)
+	_, _, err := runTestCommand(sendCmd, projFilePath, []string{"send", "testreq", "-q"})
+	if !assert.NoError(err) {
+		return
+	}
+
+	histJSON := histWriter.(*bytes.Buffer).String()
+	assert.NotContains(histJSON, "hunter2%26x")
+	assert.NotContains(histJSON, "aHVudGVyMiZ4")
+
+	entries, err := morc.LoadHistory(strings.NewReader(lastJSONValue(t, histWriter.(*bytes.Buffer))))
+	if !assert.NoError(err) || !assert.Len(entries, 1) {
+		return
+	}
+	assert.Equal("Basic ***", entries[0].Request.Header.Get("Authorization"))
+	assert.Equal("q=***", entries[0].Request.URL.RawQuery)
+	assert.True(entries[0].Redacted)
+}
+
+func Test_templateSendOptions_Settings(t *testing.T) {
+	tmpl := morc.RequestTemplate{Name: "testreq", Method: "GET", URL: "/"}
+
+	testCases := []struct {
+		name          string
+		config        morc.Settings
+		to            transportOptions
+		expectTimeout time.Duration
+		expectCert    string
+		expectKey     string
+		expectCACert  string
+		expectEnvFall bool
+	}{
+		{
+			name:          "defaults",
+			expectTimeout: morc.DefaultRequestTimeout,
+		},
+		{
+			name: "project settings",
+			config: morc.Settings{
+				RequestTimeout: 5 * time.Second,
+				ClientCertFile: "/certs/client.pem",
+				ClientKeyFile:  "/certs/client.key",
+				CACertFile:     "/certs/ca.pem",
+				EnvFallback:    true,
+			},
+			expectTimeout: 5 * time.Second,
+			expectCert:    "/certs/client.pem",
+			expectKey:     "/certs/client.key",
+			expectCACert:  "/certs/ca.pem",
+			expectEnvFall: true,
+		},
+		{
+			name: "flags override project settings",
+			config: morc.Settings{
+				RequestTimeout: 5 * time.Second,
+				ClientCertFile: "/certs/client.pem",
+				ClientKeyFile:  "/certs/client.key",
+				CACertFile:     "/certs/ca.pem",
 			},
-			expectStdoutOutput: `------------------- REQUEST -------------------
-Request URI: $TESTSERVER_URL$/path
+			to: transportOptions{
+				timeout:     time.Minute,
+				certFile:    "/other/cert.pem",
+				caCertFile:  "/other/ca.pem",
+				envFallback: true,
+			},
+			expectTimeout: time.Minute,
+			expectCert:    "/other/cert.pem",
+			expectCACert:  "/other/ca.pem",
+			expectEnvFall: true,
+		},
+	}
 
-GET /path HTTP/1.1` + "\r" + `
-Host: $TESTSERVER_HOST$` + "\r" + `
-User-Agent: Go-http-client/1.1` + "\r" + `
-Accept-Encoding: gzip` + "\r" + `
-` + "\r" + `
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
 
-(no request body)
------------------ END REQUEST -----------------
-HTTP/1.1 200 OK
-(no response body)
-`,
-			expectProjectSaved: false,
-			expectHistorySaved: false,
-			expectSessionSaved: false,
-		}, {
-			name:   "send template with var in url, prefix override",
-			args:   []string{"send", "testreq", "--request", "-p", "^"},
-			respFn: respFnNoBodyOK,
-			p: morc.Project{
-				Templates: map[string]morc.RequestTemplate{
-					"testreq": {
-						Name:   "testreq",
-						Method: "GET",
-						URL:    "^{PATH}",
-					},
-				},
-				Vars: testVarStore("", map[string]map[string]string{
-					"": {"PATH": "/path"},
-				}),
-			},
+			p := testProject_withRequests(tmpl)
+			p.Config = tc.config
+
+			sendOpts, err := templateSendOptions(&p, tmpl, nil, false, morc.OutputControl{}, tc.to)
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectTimeout, sendOpts.Timeout)
+			assert.Equal(tc.expectCert, sendOpts.ClientCertFile)
+			assert.Equal(tc.expectKey, sendOpts.ClientKeyFile)
+			assert.Equal(tc.expectCACert, sendOpts.CACertFile)
+			assert.Equal(tc.expectEnvFall, sendOpts.EnvFallback)
+		})
+	}
+}
+
+func Test_Send_Prompt(t *testing.T) {
+	t.Setenv("MORC_TEST_TOKEN", "env-token")
+
+	// setup test server that echoes back the path and Authorization header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(r.URL.Path + " " + r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+	srvClient := srv.Client()
+
+	// inject a custom transport so we always append the server root URL
+	srvClient.Transport = urlBaseRoundTripper{
+		base: srv.URL,
+		old:  srvClient.Transport,
+	}
+	cmdio.HTTPClient = srvClient
+
+	tmpl := morc.RequestTemplate{
+		Name:    "testreq",
+		Method:  "GET",
+		URL:     "/users/${USER_ID}",
+		Headers: http.Header{"Authorization": {"Bearer ${MORC_TEST_TOKEN}"}},
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		input              string
+		expectErr          string
+		expectStdoutOutput string
+		expectStderrOutput string
+	}{
+		{
+			name:      "off by default",
+			args:      []string{"send", "testreq", "-q"},
+			p:         testProject_withRequests(tmpl),
+			input:     "413\ntoken\n",
+			expectErr: "variable USER_ID not found",
+		},
+		{
+			name:               "asks for each missing var",
+			args:               []string{"send", "testreq", "-q", "--prompt"},
+			p:                  testProject_withRequests(tmpl),
+			input:              "413\ntoken\n",
+			expectStdoutOutput: "/users/413 Bearer token",
+			expectStderrOutput: "Value for ${USER_ID}: Value for ${MORC_TEST_TOKEN}: ",
+		},
+		{
+			name: "defined vars are not asked for",
+			args: []string{"send", "testreq", "-q", "--prompt", "-V", "MORC_TEST_TOKEN=flag-token"},
+			p: func() morc.Project {
+				p := testProject_withRequests(tmpl)
+				p.Vars = testVarStore("", map[string]map[string]string{"": {"USER_ID": "${OTHER}"}})
+				return p
+			}(),
+			input:              "612\n",
+			expectStdoutOutput: "/users/612 Bearer flag-token",
+			expectStderrOutput: "Value for ${OTHER}: ",
+		},
+		{
+			name: "vars only used by the override of another env are not asked for",
+			args: []string{"send", "testreq", "-q", "--prompt", "-V", "MORC_TEST_TOKEN=t"},
+			p: func() morc.Project {
+				withOverride := tmpl.Copy()
+				withOverride.EnvOverrides = map[string]morc.TemplateOverride{
+					"PROD": {Headers: http.Header{"X-Api-Key": {"${PROD_KEY}"}}},
+				}
+				return testProject_withRequests(withOverride)
+			}(),
+			input:              "413\n",
+			expectStdoutOutput: "/users/413 Bearer t",
+			expectStderrOutput: "Value for ${USER_ID}: ",
+		},
+		{
+			name:               "vars in OS env are not asked for with --env-fallback",
+			args:               []string{"send", "testreq", "-q", "--prompt", "--env-fallback"},
+			p:                  testProject_withRequests(tmpl),
+			input:              "413\n",
+			expectStdoutOutput: "/users/413 Bearer env-token",
+			expectStderrOutput: "Value for ${USER_ID}: ",
+		},
+		{
+			name:               "nothing missing",
+			args:               []string{"send", "testreq", "-q", "--prompt", "-V", "USER_ID=1", "-V", "MORC_TEST_TOKEN=t"},
+			p:                  testProject_withRequests(tmpl),
+			expectStdoutOutput: "/users/1 Bearer t",
+		},
+		{
+			name: "each request with --tag is asked for in turn",
+			args: []string{"send", "--tag", "smoke", "-q", "--prompt", "-V", "MORC_TEST_TOKEN=t"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{Name: "a-user", Method: "GET", URL: "/users/${USER_ID}", Tags: []string{"smoke"}},
+				morc.RequestTemplate{Name: "b-post", Method: "GET", URL: "/posts/${POST_ID}", Tags: []string{"smoke"}},
+			),
+			input:              "413\n612\n",
+			expectStdoutOutput: "/users/413 /posts/612 ",
+			expectStderrOutput: "Value for ${USER_ID}: Value for ${POST_ID}: ",
+		},
+		{
+			name:      "input ends before all values given",
+			args:      []string{"send", "testreq", "-q", "--prompt"},
+			p:         testProject_withRequests(tmpl),
+			input:     "413\n",
+			expectErr: "no value given for ${MORC_TEST_TOKEN}",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetSendFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+
+			rootCmd.SetIn(strings.NewReader(tc.input))
+			defer rootCmd.SetIn(nil)
+
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(sendCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if tc.expectErr != "" {
+				if assert.Error(err) {
+					assert.Contains(err.Error(), tc.expectErr)
+				}
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output)
+			assert.Equal(tc.expectStderrOutput, outputErr)
+
+			// entered values are not saved
+			assert_noProjectFileMutations(assert)
+		})
+	}
+}
+
+func Test_Send_Tag(t *testing.T) {
+	// setup test server that gives 404 for any path but /ok
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("missing"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	srvClient := srv.Client()
+
+	// inject a custom transport so we always append the server root URL
+	srvClient.Transport = urlBaseRoundTripper{
+		base: srv.URL,
+		old:  srvClient.Transport,
+	}
+	cmdio.HTTPClient = srvClient
+
+	proj := testProject_withRequests(
+		morc.RequestTemplate{Name: "b-users", Method: "GET", URL: "/ok", Tags: []string{"smoke"}},
+		morc.RequestTemplate{Name: "a-health", Method: "GET", URL: "/ok", Tags: []string{"smoke"}},
+		morc.RequestTemplate{Name: "c-missing", Method: "GET", URL: "/missing", Tags: []string{"smoke", "broken"}},
+		morc.RequestTemplate{Name: "d-untagged", Method: "GET", URL: "/missing"},
+	)
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string
+		expectStdoutOutput string
+	}{
+		{
+			name: "all pass",
+			args: []string{"send", "--tag", "smoke", "--no-body"},
+			p: testProject_withRequests(
+				morc.RequestTemplate{Name: "b-users", Method: "GET", URL: "/ok", Tags: []string{"smoke"}},
+				morc.RequestTemplate{Name: "a-health", Method: "GET", URL: "/ok", Tags: []string{"smoke"}},
+			),
+			expectStdoutOutput: "" +
+				"==> a-health\n" +
+				"HTTP/1.1 200 OK\n" +
+				"\n" +
+				"==> b-users\n" +
+				"HTTP/1.1 200 OK\n" +
+				"\n" +
+				"SUMMARY:\n" +
+				"PASS a-health\n" +
+				"PASS b-users\n" +
+				"2 passed, 0 failed\n",
+		},
+		{
+			name:      "failures do not stop the rest",
+			args:      []string{"send", "--tag", "SMOKE", "--no-body", "--assert-status", "200"},
+			p:         proj,
+			expectErr: "1 of 3 requests with tag smoke failed",
+			expectStdoutOutput: "" +
+				"==> a-health\n" +
+				"HTTP/1.1 200 OK\n" +
+				"\n" +
+				"==> b-users\n" +
+				"HTTP/1.1 200 OK\n" +
+				"\n" +
+				"==> c-missing\n" +
+				"HTTP/1.1 404 Not Found\n" +
+				"\n" +
+				"SUMMARY:\n" +
+				"PASS a-health\n" +
+				"PASS b-users\n" +
+				"FAIL c-missing: assertion failed: status: expected 200, got 404 Not Found\n" +
+				"2 passed, 1 failed\n",
+		},
+		{
+			name:      "no requests have tag",
+			args:      []string{"send", "--tag", "admin"},
+			p:         proj,
+			expectErr: "no request templates have tag admin",
+		},
+		{
+			name:      "tag with REQ",
+			args:      []string{"send", "a-health", "--tag", "smoke"},
+			p:         proj,
+			expectErr: "--tag cannot be used when REQ is given",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetSendFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+
+			// set up the root command and run
+			output, _, err := runTestCommand(sendCmd, projFilePath, tc.args)
+
+			// assert and check stdout
+			if tc.expectErr != "" {
+				if assert.Error(err) {
+					assert.Contains(err.Error(), tc.expectErr)
+				}
+			} else if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output)
+		})
+	}
+}
+
+func Test_Send_DeferCaptureErrors(t *testing.T) {
+	respFnJSONBodyOK := func(w http.ResponseWriter, r *http.Request) {
+		// suppress date header
+		w.Header()["Date"] = nil
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"name":{"first":"VRISKA","last":"SERKET"}}`))
+	}
+
+	tmpl := morc.RequestTemplate{
+		Name:   "testreq",
+		Method: "GET",
+		URL:    "/",
+		Captures: map[string]morc.VarScraper{
+			"FIRST":  {Name: "FIRST", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "first"}}},
+			"MIDDLE": {Name: "MIDDLE", Steps: []morc.TraversalStep{{Key: "name"}, {Key: "middle"}}},
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectP            morc.Project
+		expectErr          string
+		expectProjectSaved bool
+		expectStdoutOutput string
+	}{
+		{
+			name:      "failed capture stops send without flag",
+			args:      []string{"send", "testreq"},
+			p:         testProject_withRequests(tmpl),
+			expectErr: "scrape MIDDLE",
+		},
+		{
+			name: "failed capture is reported after response with flag",
+			args: []string{"send", "testreq", "--ignore-body-capture-errors-but-fail-exit"},
+			p:    testProject_withRequests(tmpl),
 			expectP: morc.Project{
-				Templates: map[string]morc.RequestTemplate{
-					"testreq": {
-						Name:   "testreq",
-						Method: "GET",
-						URL:    "^{PATH}",
-					},
-				},
+				Templates: map[string]morc.RequestTemplate{"testreq": tmpl},
 				Vars: testVarStore("", map[string]map[string]string{
-					"": {"PATH": "/path"},
+					"": {"FIRST": "VRISKA"},
 				}),
 			},
-			expectStdoutOutput: `------------------- REQUEST -------------------
-Request URI: $TESTSERVER_URL$/path
-
-GET /path HTTP/1.1` + "\r" + `
-Host: $TESTSERVER_HOST$` + "\r" + `
-User-Agent: Go-http-client/1.1` + "\r" + `
-Accept-Encoding: gzip` + "\r" + `
-` + "\r" + `
-
-(no request body)
------------------ END REQUEST -----------------
-HTTP/1.1 200 OK
-(no response body)
+			expectErr:          "capture failed: scrape MIDDLE",
+			expectProjectSaved: true,
+			expectStdoutOutput: `HTTP/1.1 200 OK
+{"name":{"first":"VRISKA","last":"SERKET"}}
 `,
-			expectProjectSaved: false,
-			expectHistorySaved: false,
-			expectSessionSaved: false,
 		},
 	}
 
@@ -780,7 +3029,7 @@ HTTP/1.1 200 OK
 			assert := assert.New(t)
 
 			// setup test server
-			srv := httptest.NewServer(http.HandlerFunc(tc.respFn))
+			srv := httptest.NewServer(http.HandlerFunc(respFnJSONBodyOK))
 			defer srv.Close()
 			srvClient := srv.Client()
 
@@ -789,26 +3038,6 @@ HTTP/1.1 200 OK
 				base: srv.URL,
 				old:  srvClient.Transport,
 			}
-
-			// make shore that expected historic entries have proper prefix
-			if tc.expectP.History != nil {
-				for i := range tc.expectP.History {
-					tc.expectP.History[i].Request.URL = mustParseURL(srv.URL + tc.expectP.History[i].Request.URL.Path)
-				}
-			}
-
-			// make shore that expected session set-cookie-calls have proper prefix
-			if tc.expectP.Session.Cookies != nil {
-				for i := range tc.expectP.Session.Cookies {
-					tc.expectP.Session.Cookies[i].URL = mustParseURL(srv.URL + tc.expectP.Session.Cookies[i].URL.Path)
-				}
-			}
-
-			// make shore stdout output replaces server things
-			tc.expectStdoutOutput = strings.ReplaceAll(tc.expectStdoutOutput, "$TESTSERVER_URL$", srv.URL)
-			srvHost := mustParseURL(srv.URL).Host
-			tc.expectStdoutOutput = strings.ReplaceAll(tc.expectStdoutOutput, "$TESTSERVER_HOST$", srvHost)
-
 			cmdio.HTTPClient = srvClient
 
 			resetSendFlags()
@@ -816,59 +3045,82 @@ HTTP/1.1 200 OK
 			// create project and dump config to a temp dir
 			projFilePath := createTestProjectIO(t, tc.p)
 			// set up the root command and run
-			output, outputErr, err := runTestCommand(sendCmd, projFilePath, tc.args)
+			output, _, err := runTestCommand(sendCmd, projFilePath, tc.args)
 
-			// assert and check stdout and stderr
-			if err != nil {
-				if tc.expectErr == "" {
-					t.Fatalf("unexpected returned error: %v", err)
-					return
-				}
-				if !strings.Contains(err.Error(), tc.expectErr) {
-					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
-				}
-				return
-			} else if tc.expectErr != "" {
-				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			// the error is returned even when the rest of the send went through
+			if assert.Error(err) {
+				assert.Contains(err.Error(), tc.expectErr)
 			}
 
-			// assertions
-
 			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
-			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
 
 			if tc.expectProjectSaved {
 				assert_projectPersistedToBuffer(assert, tc.expectP)
 			} else {
 				assert_noProjectFileMutations(assert)
 			}
-
-			if tc.expectHistorySaved {
-				assert_historyPersistedToBuffer(assert, tc.expectP.History)
-			} else {
-				assert_noHistoryFileMutations(assert)
-			}
-
-			if tc.expectSessionSaved {
-				assert_sessionPersistedToBuffer(assert, tc.expectP.Session)
-			} else {
-				assert_noSessionFileMutations(assert)
-			}
 		})
 	}
 }
 
 func resetSendFlags() {
 	flags.ProjectFile = ""
+	flags.URL = ""
+	flags.Method = "GET"
+	flags.Headers = nil
+	flags.BodyData = ""
+	flags.DataURLEncode = nil
+	flags.SetJSON = nil
+	flags.AuthFlow = ""
 	flags.Vars = nil
 	flags.BInsecure = false
+	flags.Timeout = ""
 	flags.BHeaders = false
 	flags.BCaptures = false
 	flags.BNoBody = false
+	flags.BTimings = false
+	flags.BStats = false
+	flags.OutputFile = ""
+	flags.RetryBodyMatch = ""
+	flags.RetryMaxAttempts = morc.DefaultRetryMaxAttempts
+	flags.RetryBackoff = "1s"
 	flags.BRequest = false
+	flags.BVerbose = false
+	flags.BShowRedirects = false
 	flags.Format = "pretty" // TODO: make this default not be magic but rather have the cmd flag init and the reset use it
 	flags.VarPrefix = "$"
 	flags.BQuiet = false
+	flags.CaptureOverrides = nil
+	flags.BNoSaveCaptures = false
+	flags.AssertStatus = 0
+	flags.AssertBodyContains = []string{}
+	flags.Asserts = []string{}
+	flags.BNoHistory = false
+	flags.BNoSaveSession = false
+	flags.BRecord = false
+	flags.BDeferCaptureErrors = false
+	flags.BNoSubstituteHeaders = false
+	flags.Cookies = nil
+	flags.BIPv4 = false
+	flags.BIPv6 = false
+	flags.BHTTP1 = false
+	flags.BHTTP2 = false
+	flags.BEnvFallback = false
+	flags.BPrompt = false
+	flags.Tag = ""
+	flags.LocalAddr = ""
+	flags.Resolve = nil
+	flags.CertFile = ""
+	flags.KeyFile = ""
+	flags.CACertFile = ""
+	flags.BDumpState = false
+	flags.BShowSecrets = false
+	flags.AssertHeaders = nil
+	flags.AssertHeadersPresent = nil
+	flags.HeaderMatch = "exact"
+	flags.MaxIdleConns = 0
+	flags.MaxConnsPerHost = 0
+	flags.IdleTimeout = ""
 
 	sendCmd.Flags().VisitAll(func(fl *pflag.Flag) {
 		fl.Changed = false
@@ -2,7 +2,11 @@ package commands
 
 import (
 	"bufio"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/dekarrin/morc"
 	"github.com/dekarrin/morc/cmd/morc/cmdio"
@@ -27,27 +31,401 @@ var stateCmd = &cobra.Command{
 	},
 }
 
+var stateCookiesCmd = &cobra.Command{
+	Use: "cookies",
+	Annotations: map[string]string{
+		annotationKeyHelpUsages: "" +
+			"state cookies -s FILE\n" +
+			"state cookies clear -s FILE",
+	},
+	Short: "Show cookies in a oneshot state file",
+	Long: "Load the oneshot state file given with --state and print out all cookies recorded in it, grouped by the " +
+		"URL they were set from. This is the same cookie data that is loaded by --read-state and saved by " +
+		"--write-state when making one-off requests.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, posArgs []string) error {
+		filename, err := parseStateFileFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		// done checking args, don't show usage on error
+		cmd.SilenceUsage = true
+		io := cmdio.From(cmd)
+		io.Quiet = flags.BQuiet
+
+		return invokeStateCookiesList(io, filename)
+	},
+}
+
+var stateCookiesClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete all cookies in a oneshot state file",
+	Long: "Load the oneshot state file given with --state, remove all cookies from it, and write it back. Variables " +
+		"in the state file are not affected.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, posArgs []string) error {
+		filename, err := parseStateFileFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		// done checking args, don't show usage on error
+		cmd.SilenceUsage = true
+		io := cmdio.From(cmd)
+		io.Quiet = flags.BQuiet
+
+		return invokeStateCookiesClear(io, filename)
+	},
+}
+
+var stateVarsCmd = &cobra.Command{
+	Use: "vars",
+	Annotations: map[string]string{
+		annotationKeyHelpUsages: "" +
+			"state vars -s FILE\n" +
+			"state vars get NAME -s FILE\n" +
+			"state vars set NAME VALUE -s FILE\n" +
+			"state vars delete NAME -s FILE",
+	},
+	Short: "Show variables in a oneshot state file",
+	Long: "Load the oneshot state file given with --state and print out all variables in it. Unlike project vars, " +
+		"state file vars are not split into environments; there is only a single set of them. This is the same var " +
+		"data that is loaded by --read-state and saved by --write-state when making one-off requests. As with project " +
+		"vars, the names of state file vars keep the case they were given in, and a reference to one in a request " +
+		"must use the same case. A NAME given to get, set, or delete matches an existing variable regardless of " +
+		"case; a new variable set with set is given the NAME exactly as it is typed.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, posArgs []string) error {
+		filename, err := parseStateFileFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		// done checking args, don't show usage on error
+		cmd.SilenceUsage = true
+		io := cmdio.From(cmd)
+		io.Quiet = flags.BQuiet
+
+		return invokeStateVarsList(io, filename)
+	},
+}
+
+var stateVarsGetCmd = &cobra.Command{
+	Use:   "get NAME",
+	Short: "Show the value of a variable in a oneshot state file",
+	Long:  "Load the oneshot state file given with --state and print out the value of variable NAME in it.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, posArgs []string) error {
+		filename, err := parseStateFileFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		// done checking args, don't show usage on error
+		cmd.SilenceUsage = true
+		io := cmdio.From(cmd)
+		io.Quiet = flags.BQuiet
+
+		return invokeStateVarsGet(io, filename, posArgs[0])
+	},
+}
+
+var stateVarsSetCmd = &cobra.Command{
+	Use:   "set NAME VALUE",
+	Short: "Set a variable in a oneshot state file",
+	Long: "Load the oneshot state file given with --state, set variable NAME to VALUE in it, and write it back. The " +
+		"variable is created if it does not already exist.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, posArgs []string) error {
+		filename, err := parseStateFileFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		// done checking args, don't show usage on error
+		cmd.SilenceUsage = true
+		io := cmdio.From(cmd)
+		io.Quiet = flags.BQuiet
+
+		return invokeStateVarsSet(io, filename, posArgs[0], posArgs[1])
+	},
+}
+
+var stateVarsDeleteCmd = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "Delete a variable from a oneshot state file",
+	Long:  "Load the oneshot state file given with --state, remove variable NAME from it, and write it back.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, posArgs []string) error {
+		filename, err := parseStateFileFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		// done checking args, don't show usage on error
+		cmd.SilenceUsage = true
+		io := cmdio.From(cmd)
+		io.Quiet = flags.BQuiet
+
+		return invokeStateVarsDelete(io, filename, posArgs[0])
+	},
+}
+
 func init() {
 	stateCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
 
+	stateCookiesCmd.PersistentFlags().StringVarP(&flags.StateFile, "state", "s", "", "Use the oneshot state data in statefile `FILE`.")
+	stateCookiesCmd.AddCommand(stateCookiesClearCmd)
+	stateCmd.AddCommand(stateCookiesCmd)
+
+	stateVarsCmd.PersistentFlags().StringVarP(&flags.StateFile, "state", "s", "", "Use the oneshot state data in statefile `FILE`.")
+	stateVarsCmd.AddCommand(stateVarsGetCmd)
+	stateVarsCmd.AddCommand(stateVarsSetCmd)
+	stateVarsCmd.AddCommand(stateVarsDeleteCmd)
+	stateCmd.AddCommand(stateVarsCmd)
+
 	rootCmd.AddCommand(stateCmd)
 }
 
-func invokeStateShow(io cmdio.IO, filename string) error {
-	// open a buffered reader on the file
-	fRaw, err := os.Open(filename)
+func parseStateFileFlag(cmd *cobra.Command) (string, error) {
+	if flags.StateFile == "" {
+		return "", fmt.Errorf("state file must be given with --state")
+	}
+	filename, err := projDirPath(cmd, flags.StateFile)
+	if err != nil {
+		return "", fmt.Errorf("--state: %w", err)
+	}
+	return filename, nil
+}
+
+func invokeStateCookiesList(io cmdio.IO, filename string) error {
+	state, err := readStateFile(filename)
 	if err != nil {
 		return err
 	}
 
+	if len(state.Cookies) == 0 {
+		io.PrintLoudln("(no cookies)")
+		return nil
+	}
+
+	cookiesByURL := map[string][]morc.SetCookiesCall{}
+	urls := []string{}
+	for _, c := range state.Cookies {
+		u := c.URL.String()
+
+		if _, ok := cookiesByURL[u]; !ok {
+			urls = append(urls, u)
+		}
+		cookiesByURL[u] = append(cookiesByURL[u], c)
+	}
+	sort.Strings(urls)
+
+	for i, u := range urls {
+		io.Printf("%s:\n", u)
+		for _, call := range cookiesByURL[u] {
+			for _, c := range call.Cookies {
+				io.Printf("%s %s\n", call.Time.Format(time.RFC3339), c.String())
+			}
+		}
+
+		if i < len(urls)-1 {
+			io.Println()
+		}
+	}
+
+	return nil
+}
+
+func invokeStateCookiesClear(io cmdio.IO, filename string) error {
+	state, err := readStateFile(filename)
+	if err != nil {
+		return err
+	}
+
+	state.Cookies = nil
+
+	if err := writeStateFile(filename, state); err != nil {
+		return err
+	}
+
+	io.PrintLoudf("Cookies cleared\n")
+
+	return nil
+}
+
+func invokeStateVarsList(io cmdio.IO, filename string) error {
+	state, err := readStateFile(filename)
+	if err != nil {
+		return err
+	}
+
+	if len(state.Vars) == 0 {
+		io.PrintLoudln("(none)")
+		return nil
+	}
+
+	names := make([]string, 0, len(state.Vars))
+	for k := range state.Vars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		io.Printf("%s = %q\n", name, state.Vars[name])
+	}
+
+	return nil
+}
+
+func invokeStateVarsGet(io cmdio.IO, filename, name string) error {
+	name, err := morc.ParseVarName(name)
+	if err != nil {
+		return err
+	}
+
+	state, err := readStateFile(filename)
+	if err != nil {
+		return err
+	}
+
+	key, ok := stateVarKey(state.Vars, name)
+	if !ok {
+		return fmt.Errorf("%s is not defined", name)
+	}
+
+	io.Println(state.Vars[key])
+
+	return nil
+}
+
+func invokeStateVarsSet(io cmdio.IO, filename, name, value string) error {
+	// dont even bother to load if the var name is invalid
+	name, err := morc.ParseVarName(name)
+	if err != nil {
+		return err
+	}
+
+	state, err := readStateFile(filename)
+	if err != nil {
+		return err
+	}
+
+	if state.Vars == nil {
+		state.Vars = map[string]string{}
+	}
+	if key, ok := stateVarKey(state.Vars, name); ok {
+		name = key
+	}
+	state.Vars[name] = value
+
+	if err := writeStateFile(filename, state); err != nil {
+		return err
+	}
+
+	io.PrintLoudf("Set %s to %q\n", name, value)
+
+	return nil
+}
+
+func invokeStateVarsDelete(io cmdio.IO, filename, name string) error {
+	name, err := morc.ParseVarName(name)
+	if err != nil {
+		return err
+	}
+
+	state, err := readStateFile(filename)
+	if err != nil {
+		return err
+	}
+
+	key, ok := stateVarKey(state.Vars, name)
+	if !ok {
+		return fmt.Errorf("%s does not exist", name)
+	}
+	name = key
+	delete(state.Vars, name)
+
+	if err := writeStateFile(filename, state); err != nil {
+		return err
+	}
+
+	io.PrintLoudf("Deleted %s\n", name)
+
+	return nil
+}
+
+// stateVarKey returns the key in vars of the var with the given name. A key
+// that matches name exactly is used if there is one; otherwise the first key,
+// in sorted order, that matches it regardless of case is used.
+func stateVarKey(vars map[string]string, name string) (string, bool) {
+	if _, ok := vars[name]; ok {
+		return name, true
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if strings.EqualFold(k, name) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// readStateFile loads the oneshot state data in the given file.
+func readStateFile(filename string) (morc.State, error) {
+	fRaw, err := os.Open(filename)
+	if err != nil {
+		return morc.State{}, err
+	}
+	defer fRaw.Close()
+
 	file := bufio.NewReader(fRaw)
 
 	var state morc.State
 	rzr, err := rezi.NewReader(file, nil)
 	if err != nil {
-		return err
+		return morc.State{}, fmt.Errorf("read state file %s: %w", filename, err)
 	}
 	if err := rzr.Dec(&state); err != nil {
+		return morc.State{}, fmt.Errorf("read state file %s: %w", filename, err)
+	}
+
+	return state, nil
+}
+
+// writeStateFile writes the given oneshot state data to the given file,
+// replacing any existing contents.
+func writeStateFile(filename string, state morc.State) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rzw, err := rezi.NewWriter(file, nil)
+	if err != nil {
+		return fmt.Errorf("write state file %s: %w", filename, err)
+	}
+	if err := rzw.Enc(state); err != nil {
+		return fmt.Errorf("write state file %s: %w", filename, err)
+	}
+	if err := rzw.Close(); err != nil {
+		return fmt.Errorf("write state file %s: %w", filename, err)
+	}
+
+	return nil
+}
+
+func invokeStateShow(io cmdio.IO, filename string) error {
+	state, err := readStateFile(filename)
+	if err != nil {
 		return err
 	}
 
@@ -0,0 +1,305 @@
+package commands
+
+import (
+	"bytes"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dekarrin/morc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StateCookies(t *testing.T) {
+	setTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testState := func() morc.State {
+		return morc.State{
+			Cookies: []morc.SetCookiesCall{
+				{
+					Time:    setTime,
+					URL:     mustParseURL("http://example.com"),
+					Cookies: []*http.Cookie{{Name: "cookie1", Value: "value1"}},
+				},
+				{
+					Time:    setTime,
+					URL:     mustParseURL("http://alpha.example.com"),
+					Cookies: []*http.Cookie{{Name: "cookie2", Value: "value2"}},
+				},
+			},
+			Vars: map[string]string{"USER": "vriska"},
+		}
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -s; it is automatically set to a state file
+		state              morc.State
+		expectState        morc.State // only checked if expectStateWritten is true
+		expectStateWritten bool
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "list no cookies",
+			args:               []string{"state", "cookies"},
+			state:              morc.State{Vars: map[string]string{}},
+			expectStdoutOutput: "(no cookies)\n",
+		},
+		{
+			name:  "list cookies",
+			args:  []string{"state", "cookies"},
+			state: testState(),
+			expectStdoutOutput: "" +
+				"http://alpha.example.com:\n" +
+				"2024-01-01T00:00:00Z cookie2=value2\n" +
+				"\n" +
+				"http://example.com:\n" +
+				"2024-01-01T00:00:00Z cookie1=value1\n",
+		},
+		{
+			name:               "clear cookies",
+			args:               []string{"state", "cookies", "clear"},
+			state:              testState(),
+			expectStateWritten: true,
+			expectState:        morc.State{Vars: map[string]string{"USER": "vriska"}},
+			expectStdoutOutput: "Cookies cleared\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetStateFlags()
+
+			stateFile := filepath.Join(t.TempDir(), "state.rezi")
+			if err := writeStateFile(stateFile, tc.state); err != nil {
+				t.Fatal(err)
+				return
+			}
+
+			output, outputErr, err := runTestStateCommand(stateCookiesCmd, stateFile, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			updated, err := readStateFile(stateFile)
+			if !assert.NoError(err) {
+				return
+			}
+
+			expect := tc.state
+			if tc.expectStateWritten {
+				expect = tc.expectState
+			}
+			assert.Equal(expect.Vars, updated.Vars, "state vars mismatch")
+			assert.Equal(len(expect.Cookies), len(updated.Cookies), "state cookies count mismatch")
+		})
+	}
+}
+
+func Test_StateVars(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -s; it is automatically set to a state file
+		vars               map[string]string
+		expectVars         map[string]string
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "list no vars",
+			args:               []string{"state", "vars"},
+			vars:               map[string]string{},
+			expectVars:         map[string]string{},
+			expectStdoutOutput: "(none)\n",
+		},
+		{
+			name:               "list vars",
+			args:               []string{"state", "vars"},
+			vars:               map[string]string{"USER": "vriska", "ID": "413"},
+			expectVars:         map[string]string{"USER": "vriska", "ID": "413"},
+			expectStdoutOutput: "ID = \"413\"\nUSER = \"vriska\"\n",
+		},
+		{
+			name:               "get var",
+			args:               []string{"state", "vars", "get", "USER"},
+			vars:               map[string]string{"USER": "vriska"},
+			expectVars:         map[string]string{"USER": "vriska"},
+			expectStdoutOutput: "vriska\n",
+		},
+		{
+			name:               "get var is case-insensitive",
+			args:               []string{"state", "vars", "get", "user"},
+			vars:               map[string]string{"USER": "vriska"},
+			expectVars:         map[string]string{"USER": "vriska"},
+			expectStdoutOutput: "vriska\n",
+		},
+		{
+			name:               "get lower-case var",
+			args:               []string{"state", "vars", "get", "token"},
+			vars:               map[string]string{"token": "xyz"},
+			expectVars:         map[string]string{"token": "xyz"},
+			expectStdoutOutput: "xyz\n",
+		},
+		{
+			name:      "get undefined var",
+			args:      []string{"state", "vars", "get", "ID"},
+			vars:      map[string]string{"USER": "vriska"},
+			expectErr: "ID is not defined",
+		},
+		{
+			name:               "set new var",
+			args:               []string{"state", "vars", "set", "ID", "413"},
+			vars:               map[string]string{"USER": "vriska"},
+			expectVars:         map[string]string{"USER": "vriska", "ID": "413"},
+			expectStdoutOutput: "Set ID to \"413\"\n",
+		},
+		{
+			name:               "set new var keeps case of name",
+			args:               []string{"state", "vars", "set", "id", "413"},
+			vars:               map[string]string{"USER": "vriska"},
+			expectVars:         map[string]string{"USER": "vriska", "id": "413"},
+			expectStdoutOutput: "Set id to \"413\"\n",
+		},
+		{
+			name:               "set existing var is case-insensitive",
+			args:               []string{"state", "vars", "set", "TOKEN", "abc"},
+			vars:               map[string]string{"token": "xyz"},
+			expectVars:         map[string]string{"token": "abc"},
+			expectStdoutOutput: "Set token to \"abc\"\n",
+		},
+		{
+			name:               "set prefers var with exact case",
+			args:               []string{"state", "vars", "set", "token", "abc"},
+			vars:               map[string]string{"TOKEN": "xyz", "token": "xyz"},
+			expectVars:         map[string]string{"TOKEN": "xyz", "token": "abc"},
+			expectStdoutOutput: "Set token to \"abc\"\n",
+		},
+		{
+			name:      "set invalid var name",
+			args:      []string{"state", "vars", "set", "USER.ID", "413"},
+			vars:      map[string]string{"USER": "vriska"},
+			expectErr: "contains invalid characters",
+		},
+		{
+			name:      "set empty var name",
+			args:      []string{"state", "vars", "set", "", "413"},
+			vars:      map[string]string{"USER": "vriska"},
+			expectErr: "name is empty",
+		},
+		{
+			name:               "set existing var",
+			args:               []string{"state", "vars", "set", "USER", "terezi"},
+			vars:               map[string]string{"USER": "vriska"},
+			expectVars:         map[string]string{"USER": "terezi"},
+			expectStdoutOutput: "Set USER to \"terezi\"\n",
+		},
+		{
+			name:               "delete var",
+			args:               []string{"state", "vars", "delete", "USER"},
+			vars:               map[string]string{"USER": "vriska", "ID": "413"},
+			expectVars:         map[string]string{"ID": "413"},
+			expectStdoutOutput: "Deleted USER\n",
+		},
+		{
+			name:               "delete var is case-insensitive",
+			args:               []string{"state", "vars", "delete", "user"},
+			vars:               map[string]string{"USER": "vriska", "ID": "413"},
+			expectVars:         map[string]string{"ID": "413"},
+			expectStdoutOutput: "Deleted USER\n",
+		},
+		{
+			name:       "delete undefined var",
+			args:       []string{"state", "vars", "delete", "ID"},
+			vars:       map[string]string{"USER": "vriska"},
+			expectVars: map[string]string{"USER": "vriska"},
+			expectErr:  "ID does not exist",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetStateFlags()
+
+			stateFile := filepath.Join(t.TempDir(), "state.rezi")
+			if err := writeStateFile(stateFile, morc.State{Vars: tc.vars}); err != nil {
+				t.Fatal(err)
+				return
+			}
+
+			output, outputErr, err := runTestStateCommand(stateVarsCmd, stateFile, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			updated, err := readStateFile(stateFile)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectVars, updated.Vars, "state vars mismatch")
+		})
+	}
+}
+
+// runTestStateCommand is like runTestCommand but passes a state file with -s
+// instead of a project file with -F.
+func runTestStateCommand(cmd *cobra.Command, stateFilePath string, args []string) (stdout string, stderr string, err error) {
+	stdoutCapture := &bytes.Buffer{}
+	stderrCapture := &bytes.Buffer{}
+
+	args = append(args, "-s", stateFilePath)
+
+	cmd.Root().SetOut(stdoutCapture)
+	cmd.Root().SetErr(stderrCapture)
+	cmd.Root().SetArgs(args)
+
+	err = cmd.Root().Execute()
+	return stdoutCapture.String(), stderrCapture.String(), err
+}
+
+func resetStateFlags() {
+	flags.StateFile = ""
+	flags.BQuiet = false
+
+	for _, cmd := range []*cobra.Command{stateCmd, stateCookiesCmd, stateCookiesClearCmd, stateVarsCmd, stateVarsGetCmd, stateVarsSetCmd, stateVarsDeleteCmd} {
+		cmd.Flags().VisitAll(func(fl *pflag.Flag) {
+			fl.Changed = false
+		})
+	}
+}
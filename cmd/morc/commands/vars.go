@@ -1,8 +1,12 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/dekarrin/morc"
@@ -16,10 +20,15 @@ var varsCmd = &cobra.Command{
 	Use: "vars [VAR [VALUE]]",
 	Annotations: map[string]string{
 		annotationKeyHelpUsages: "" +
-			"vars [--env ENV | --current | --default]\n" +
+			"vars [--reveal] [--env ENV | --current | --default]\n" +
 			"vars --delete VAR [--env ENV | --current | --default | --all]\n" +
-			"vars VAR [--env ENV | --current | --default | --all]\n" +
-			"vars VAR VALUE [--env ENV | --current | --default | --all]",
+			"vars VAR [--reveal] [--env ENV | --current | --default | --all]\n" +
+			"vars VAR VALUE [--secret[=false]] [--env ENV | --current | --default | --all]\n" +
+			"vars --import FILE [--secret[=false]] [--env ENV | --current | --default | --all]\n" +
+			"vars --export FILE [--env ENV | --current | --default]\n" +
+			"vars --usages VAR\n" +
+			"vars --unused\n" +
+			"vars --rename OLD NEW [--dry-run]",
 	},
 	GroupID: "project",
 	Short:   "Show or manipulate request variables",
@@ -39,7 +48,49 @@ var varsCmd = &cobra.Command{
 		"--all will give a listing of all values of VAR across all environments, including the default one.\n\n" +
 		"A variable is deleted by passing the flag --delete with the name of the VAR as an argument to it. Similarly to the " +
 		"other commands, --env, --current, --default, and --all can be used to specify deletion from an environment other " +
-		"than the current one.",
+		"than the current one.\n\n" +
+		"The value of a variable may itself refer to other variables, such as setting URL_PATH to '${BASE}/users'. " +
+		"Values are stored exactly as given and the references within them are only expanded when the variable is " +
+		"used in a request, at which point they are expanded recursively. A variable that refers back to itself, " +
+		"either directly or through other variables, is an error, as is nesting references more than 10 levels " +
+		"deep.\n\n" +
+		"Names beginning with '@' are reserved for dynamic vars, which are computed fresh every time a request is " +
+		"sent and are never stored. They cannot be set with vars. The available dynamic vars are '${@uuid}' for a random " +
+		"UUID, '${@now}' for the current time in RFC 3339 format, '${@unix}' for the current Unix timestamp in " +
+		"seconds, and '${@randint:MIN,MAX}' for a random integer between MIN and MAX, inclusive.\n\n" +
+		"A variable that holds a secret, such as a token or a password, can be marked as one by giving --secret when " +
+		"setting it. The value of a secret variable is used as normal when a request is sent, but it is shown as '" +
+		morc.SecretMask + "' when listing or getting vars, in requests output with --request, and in history. Give " +
+		"--reveal to show the values of secret variables when listing or getting vars. A variable stays a secret in " +
+		"every environment until it is deleted from all of them or it is set again with --secret=false.\n\n" +
+		"When a variable is used in a request, its value can be transformed by following its name with '|' and the " +
+		"name of a transform, such as '${TOKEN|base64}'. Multiple transforms may be chained, such as " +
+		"'${NAME|trim|urlencode}', and are applied in order. The available transforms are 'base64', 'base64url', " +
+		"'urlencode', 'upper', 'lower', and 'trim'.\n\n" +
+		"Many variables can be set at once by passing --import with the path to a FILE containing them. If FILE ends in " +
+		"'.json', it must contain a single JSON object whose keys are variable names and whose values are strings. " +
+		"Otherwise, it is read as a .env file with one KEY=VALUE pair per line; blank lines and lines starting with '#' " +
+		"are ignored, a leading 'export ' is allowed, and values may be surrounded by single or double quotes. Names " +
+		"are uppercased. Each variable is set exactly as though it were set with VAR VALUE, so --env, --current, " +
+		"--default, --all, and --secret may be used to select where the variables are set and whether they are " +
+		"secrets.\n\n" +
+		"The opposite is done with --export, which writes variables to FILE in the same format that --import reads, " +
+		"chosen by the extension of FILE in the same way. By default, all variables accessible from the current " +
+		"environment are written, including values filled from the default environment, exactly as they would be " +
+		"listed by vars. --env=ENV, --current, and --default can be used to instead write only the variables defined " +
+		"in a single environment. The values of secret variables are written as they are and are not masked.\n\n" +
+		"To find out which request templates use a variable, such as before deleting or renaming it, pass --usages " +
+		"with the name of the VAR. Every template whose URL, headers, or body refers to VAR with the project's var " +
+		"prefix is listed by name. To find variables that can be pruned, give --unused instead; every variable " +
+		"defined in any environment that is not referred to by the URL, headers, or body of a request template, by " +
+		"a default header, by the base URL of an environment, or by the value of another variable that is itself " +
+		"referred to is listed. Files that request bodies are read from with --body-file are not checked.\n\n" +
+		"A variable is renamed by passing --rename with its current name, OLD, and giving the NEW name as an " +
+		"argument. The variable is renamed in every environment it is defined in, and every reference to it in the " +
+		"URL, headers, body, and form fields of request templates, in the values of other variables, in default " +
+		"headers, and in the base URL of each environment is changed to refer to NEW, as is any capture into it and " +
+		"any flow step that requires it. NEW must not already be defined. Give --dry-run to list what would be " +
+		"changed without changing anything.",
 	Args: cobra.MaximumNArgs(2),
 	RunE: func(cmd *cobra.Command, posArgs []string) error {
 		var args varsArgs
@@ -54,13 +105,23 @@ var varsCmd = &cobra.Command{
 
 		switch args.action {
 		case varsActionList:
-			return invokeVarList(io, args.projFile, args.env)
+			return invokeVarList(io, args.projFile, args.env, args.reveal)
 		case varsActionGet:
-			return invokeVarGet(io, args.projFile, args.env, args.varName)
+			return invokeVarGet(io, args.projFile, args.env, args.varName, args.reveal)
 		case varsActionSet:
-			return invokeVarSet(io, args.projFile, args.env, args.varName, args.value)
+			return invokeVarSet(io, args.projFile, args.env, args.varName, args.value, args.secret)
 		case varsActionDelete:
 			return invokeVarDelete(io, args.projFile, args.env, args.varName)
+		case varsActionImport:
+			return invokeVarImport(io, args.projFile, args.env, args.file, args.secret)
+		case varsActionExport:
+			return invokeVarExport(io, args.projFile, args.env, args.file)
+		case varsActionUsages:
+			return invokeVarUsages(io, args.projFile, args.varName)
+		case varsActionUnused:
+			return invokeVarUnused(io, args.projFile)
+		case varsActionRename:
+			return invokeVarRename(io, args.projFile, args.varName, args.value, args.dryRun)
 		default:
 			panic(fmt.Sprintf("unhandled vars action %q", args.action))
 		}
@@ -74,15 +135,24 @@ func init() {
 	varsCmd.PersistentFlags().BoolVarP(&flags.BDefault, "default", "", false, "Apply to the default environment.")
 	varsCmd.PersistentFlags().BoolVarP(&flags.BCurrent, "current", "", false, "Apply only to current environment. This is the same as --env followed by the name of the current environment.")
 	varsCmd.PersistentFlags().BoolVarP(&flags.BAll, "all", "a", false, "Apply to all environments. The meaning varies based on the operation being performed. When deleting, this will delete the variable from all environments. When getting, this will list all values of the variable in each env that defines it. When setting, it sets the value of the variable in all environments to the given value.")
+	varsCmd.PersistentFlags().StringVarP(&flags.Import, "import", "", "", "Set all variables defined in `FILE`. FILE is read as JSON if it ends in '.json' and as a .env file of KEY=VALUE lines otherwise.")
+	varsCmd.PersistentFlags().StringVarP(&flags.Export, "export", "", "", "Write variables to `FILE`. FILE is written as JSON if it ends in '.json' and as a .env file of KEY=VALUE lines otherwise.")
+	varsCmd.PersistentFlags().StringVarP(&flags.Usages, "usages", "", "", "List the request templates that refer to the variable `VAR`.")
+	varsCmd.PersistentFlags().BoolVarP(&flags.BUnused, "unused", "", false, "List the variables that are not referred to anywhere in the project.")
+	varsCmd.PersistentFlags().StringVarP(&flags.Rename, "rename", "", "", "Rename the variable `OLD` to the name given as an argument, updating every reference to it.")
+	varsCmd.PersistentFlags().BoolVarP(&flags.BDryRun, "dry-run", "", false, "With --rename, list what would be changed but do not change anything.")
+	varsCmd.PersistentFlags().BoolVarP(&flags.BSecret, "secret", "", false, "Mark the variables being set as secrets, whose values are masked in output. Give --secret=false to unmark them.")
+	varsCmd.PersistentFlags().BoolVarP(&flags.BReveal, "reveal", "", false, "Show the values of secret variables instead of masking them.")
 	varsCmd.PersistentFlags().BoolVarP(&flags.BQuiet, "quiet", "q", false, "Suppress all unnecessary output.")
 
 	// mark the env and default flags as mutually exclusive
 	varsCmd.MarkFlagsMutuallyExclusive("env", "default", "all", "current")
+	varsCmd.MarkFlagsMutuallyExclusive("delete", "import", "export", "usages", "unused", "rename")
 
 	rootCmd.AddCommand(varsCmd)
 }
 
-func invokeVarSet(io cmdio.IO, projFile string, env envSelection, varName, value string) error {
+func invokeVarSet(io cmdio.IO, projFile string, env envSelection, varName, value string, secret optional[bool]) error {
 	// dont even bother to load if the var name is invalid
 	varName, err := morc.ParseVarName(strings.ToUpper(varName))
 	if err != nil {
@@ -94,31 +164,396 @@ func invokeVarSet(io cmdio.IO, projFile string, env envSelection, varName, value
 		return err
 	}
 
+	setVarInEnv(&p, env, varName, value)
+	if secret.set {
+		p.Vars.SetSecret(varName, secret.v)
+	}
+
+	// a value set explicitly is saved even if it is the same as one captured
+	// without saving in a REPL
+	for _, envName := range p.Vars.EnvNames() {
+		if p.Vars.IsDefinedIn(varName, envName) && p.Vars.GetFrom(varName, envName) == value {
+			activeRepl.forgetUnsavedVar(envName, varName)
+		}
+	}
+
+	shownValue := varDisplayValue(p, varName, value, false)
+	if env.useAll {
+		io.PrintLoudf("Set %s{%s} to %s in all envs\n", p.VarPrefix(), varName, shownValue)
+	} else if env.useDefault {
+		io.PrintLoudf("Set %s{%s} to %s in default env\n", p.VarPrefix(), varName, shownValue)
+	} else if env.useName != "" {
+		io.PrintLoudf("Set %s{%s} to %s in env %s\n", p.VarPrefix(), varName, shownValue, env.useName)
+	} else if env.useCurrent {
+		io.PrintLoudf("Set %s{%s} to %s in current env\n", p.VarPrefix(), varName, shownValue)
+	} else {
+		io.PrintLoudf("Set %s{%s} to %s\n", p.VarPrefix(), varName, shownValue)
+	}
+
+	return writeProject(p, false)
+}
+
+// varDisplayValue returns value quoted for output, or morc.SecretMask if
+// varName is a secret in p and reveal is not set.
+func varDisplayValue(p morc.Project, varName, value string, reveal bool) string {
+	if !reveal && p.Vars.IsSecret(varName) {
+		return morc.SecretMask
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+// setVarInEnv sets varName to value in the environment(s) of p selected by
+// env.
+func setVarInEnv(p *morc.Project, env envSelection, varName, value string) {
 	if env.useAll {
 		// get list of envs to set in
 		allEnvs := p.Vars.EnvNames()
 		for _, envName := range allEnvs {
 			p.Vars.SetIn(varName, value, envName)
 		}
-		io.PrintLoudf("Set %s{%s} to %q in all envs\n", p.VarPrefix(), varName, value)
 	} else if env.useDefault {
 		p.Vars.SetIn(varName, value, "")
-		io.PrintLoudf("Set %s{%s} to %q in default env\n", p.VarPrefix(), varName, value)
 	} else if env.useName != "" {
 		p.Vars.SetIn(varName, value, env.useName)
-		io.PrintLoudf("Set %s{%s} to %q in env %s\n", p.VarPrefix(), varName, value, env.useName)
 	} else if env.useCurrent {
 		p.Vars.SetIn(varName, value, p.Vars.Environment)
-		io.PrintLoudf("Set %s{%s} to %q in current env\n", p.VarPrefix(), varName, value)
 	} else {
 		p.Vars.Set(varName, value)
-		io.PrintLoudf("Set %s{%s} to %q\n", p.VarPrefix(), varName, value)
 	}
+}
 
-	return writeProject(p, false)
+func invokeVarImport(io cmdio.IO, projFile string, env envSelection, filename string, secret optional[bool]) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("read %q: %w", filename, err)
+	}
+
+	var vars map[string]string
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		vars, err = parseJSONVars(data)
+	} else {
+		vars, err = parseDotEnvVars(data)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	// set in sorted order so that envs created by setting are reproducible
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		setVarInEnv(&p, env, name, vars[name])
+		if secret.set {
+			p.Vars.SetSecret(name, secret.v)
+		}
+	}
+
+	if err := writeProject(p, false); err != nil {
+		return err
+	}
+
+	intoMsg := ""
+	if env.IsSpecified() {
+		intoMsg = fmt.Sprintf(" into %s", env)
+	}
+	io.PrintLoudf("Imported %s%s\n", io.CountOf(len(names), "var"), intoMsg)
+	return nil
+}
+
+func invokeVarExport(io cmdio.IO, projFile string, env envSelection, filename string) error {
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	vars := map[string]string{}
+	if env.IsSpecified() {
+		targetEnv := env.useName
+		if env.useCurrent {
+			targetEnv = p.Vars.Environment
+		}
+		for _, name := range p.Vars.DefinedIn(targetEnv) {
+			vars[name] = p.Vars.GetFrom(name, targetEnv)
+		}
+	} else {
+		for _, name := range p.Vars.All() {
+			vars[name] = p.Vars.Get(name)
+		}
+	}
+
+	var data []byte
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		data, err = json.MarshalIndent(vars, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode vars: %w", err)
+		}
+		data = append(data, '\n')
+	} else {
+		data = formatDotEnvVars(vars)
+	}
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("write %q: %w", filename, err)
+	}
+
+	fromMsg := ""
+	if env.IsSpecified() {
+		fromMsg = fmt.Sprintf(" from %s", env)
+	}
+	io.PrintLoudf("Exported %s%s to %s\n", io.CountOf(len(vars), "var"), fromMsg, filename)
+	return nil
+}
+
+// formatDotEnvVars gives the contents of a .env file that sets each of vars.
+// Values are always quoted so that they can be read back exactly by
+// parseDotEnvVars.
+func formatDotEnvVars(vars map[string]string) []byte {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("%s=%s\n", name, strconv.Quote(vars[name])))
+	}
+	return []byte(sb.String())
+}
+
+// parseJSONVars parses a JSON object of variable names to string values. Names
+// are uppercased and checked for validity.
+func parseJSONVars(data []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("not a JSON object of vars: %w", err)
+	}
+
+	vars := make(map[string]string, len(raw))
+	for k, v := range raw {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("value of %q is not a string", k)
+		}
+
+		name, err := morc.ParseVarName(strings.ToUpper(k))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", k, err)
+		}
+		vars[name] = str
+	}
+
+	return vars, nil
+}
+
+// parseDotEnvVars parses the contents of a .env file containing KEY=VALUE
+// lines. Blank lines and comment lines starting with '#' are skipped, a
+// leading "export " on a line is ignored, and a value surrounded by matching
+// quotes has them removed. Names are uppercased and checked for validity.
+func parseDotEnvVars(data []byte) (map[string]string, error) {
+	vars := map[string]string{}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for idx, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: not in KEY=VALUE format", idx+1)
+		}
+
+		name, err := morc.ParseVarName(strings.ToUpper(strings.TrimSpace(parts[0])))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", idx+1, err)
+		}
+
+		value := strings.TrimSpace(parts[1])
+		if len(value) >= 2 {
+			if value[0] == '"' && value[len(value)-1] == '"' {
+				unquoted, err := strconv.Unquote(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid quoted value: %w", idx+1, err)
+				}
+				value = unquoted
+			} else if value[0] == '\'' && value[len(value)-1] == '\'' {
+				value = value[1 : len(value)-1]
+			}
+		}
+
+		vars[name] = value
+	}
+
+	return vars, nil
+}
+
+func invokeVarUsages(io cmdio.IO, projFile string, varName string) error {
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	varName = strings.ToUpper(varName)
+
+	var names []string
+	for name, tmpl := range p.Templates {
+		for _, ref := range tmpl.ReferencedVars(p.VarPrefix()) {
+			if ref == varName {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		io.PrintLoudf("No requests reference %s{%s}\n", p.VarPrefix(), varName)
+		return nil
+	}
+
+	for _, name := range names {
+		io.Println(name)
+	}
+
+	return nil
+}
+
+func invokeVarUnused(io cmdio.IO, projFile string) error {
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	unused := p.UnusedVars()
+	if len(unused) == 0 {
+		io.PrintLoudf("All variables are referenced\n")
+		return nil
+	}
+
+	for _, name := range unused {
+		io.Println(name)
+	}
+
+	return nil
+}
+
+func invokeVarRename(io cmdio.IO, projFile string, oldName, newName string, dryRun bool) error {
+	// dont even bother to load if the new var name is invalid
+	newName, err := morc.ParseVarName(strings.ToUpper(newName))
+	if err != nil {
+		return err
+	}
+	oldName = strings.ToUpper(oldName)
+
+	p, err := readProject(projFile, true)
+	if err != nil {
+		return err
+	}
+
+	// a var defined in any env is always defined in the default env
+	if !p.Vars.IsDefinedIn(oldName, "") {
+		return fmt.Errorf("%s{%s} does not exist", p.VarPrefix(), oldName)
+	}
+	if oldName == newName {
+		return fmt.Errorf("%s{%s} already has that name", p.VarPrefix(), oldName)
+	}
+	if p.Vars.IsDefinedIn(newName, "") {
+		return fmt.Errorf("%s{%s} already exists", p.VarPrefix(), newName)
+	}
+
+	var reqNames []string
+	for name, tmpl := range p.Templates {
+		if updated, changed := tmpl.RenameVar(p.VarPrefix(), oldName, newName); changed {
+			p.Templates[name] = updated
+			reqNames = append(reqNames, name)
+		}
+	}
+	sort.Strings(reqNames)
+
+	var flowNames []string
+	for name, flow := range p.Flows {
+		if updated, changed := flow.RenameVar(oldName, newName); changed {
+			p.Flows[name] = updated
+			flowNames = append(flowNames, name)
+		}
+	}
+	sort.Strings(flowNames)
+
+	headersChanged, baseURLEnvs := p.RenameVarInConfig(oldName, newName)
+
+	p.Vars.Rename(oldName, newName)
+
+	var varNames []string
+	for _, envName := range p.Vars.EnvNames() {
+		for _, name := range p.Vars.DefinedIn(envName) {
+			val, changed := morc.RenameVarRefs(p.Vars.GetFrom(name, envName), p.VarPrefix(), oldName, newName)
+			if !changed {
+				continue
+			}
+			p.Vars.SetIn(name, val, envName)
+			if sliceops.Index(varNames, name) < 0 {
+				varNames = append(varNames, name)
+			}
+		}
+	}
+	sort.Strings(varNames)
+
+	if dryRun {
+		io.Printf("Would rename %s{%s} to %s{%s}\n", p.VarPrefix(), oldName, p.VarPrefix(), newName)
+		for _, name := range reqNames {
+			io.Printf("Would update request %s\n", name)
+		}
+		for _, name := range flowNames {
+			io.Printf("Would update flow %s\n", name)
+		}
+		for _, name := range varNames {
+			io.Printf("Would update %s{%s}\n", p.VarPrefix(), name)
+		}
+		if headersChanged {
+			io.Printf("Would update default headers\n")
+		}
+		for _, env := range baseURLEnvs {
+			io.Printf("Would update base URL of %s\n", envDescription(env))
+		}
+		return nil
+	}
+
+	if err := writeProject(p, false); err != nil {
+		return err
+	}
+
+	io.PrintLoudf("Renamed %s{%s} to %s{%s}; updated %s, %s, and %s\n", p.VarPrefix(), oldName, p.VarPrefix(), newName, io.CountOf(len(reqNames), "request"), io.CountOf(len(flowNames), "flow"), io.CountOf(len(varNames), "var"))
+	if headersChanged {
+		io.PrintLoudf("Updated default headers\n")
+	}
+	for _, env := range baseURLEnvs {
+		io.PrintLoudf("Updated base URL of %s\n", envDescription(env))
+	}
+	return nil
+}
+
+// envDescription returns a description of the environment with the given name
+// for use in output, such as `environment "PROD"`.
+func envDescription(env string) string {
+	if env == "" {
+		return "the default environment"
+	}
+	return fmt.Sprintf("environment %q", strings.ToUpper(env))
 }
 
-func invokeVarGet(io cmdio.IO, projFile string, env envSelection, varName string) error {
+func invokeVarGet(io cmdio.IO, projFile string, env envSelection, varName string, reveal bool) error {
 	p, err := readProject(projFile, true)
 	if err != nil {
 		return err
@@ -164,7 +599,9 @@ func invokeVarGet(io cmdio.IO, projFile string, env envSelection, varName string
 			}
 
 			displayVal := val
-			if !io.Quiet {
+			if !reveal && p.Vars.IsSecret(varName) {
+				displayVal = morc.SecretMask
+			} else if !io.Quiet {
 				displayVal = fmt.Sprintf("%q", val)
 			}
 
@@ -232,6 +669,9 @@ func invokeVarGet(io cmdio.IO, projFile string, env envSelection, varName string
 		val = p.Vars.Get(varName)
 	}
 
+	if !reveal && p.Vars.IsSecret(varName) {
+		val = morc.SecretMask
+	}
 	io.Println(val)
 
 	return nil
@@ -364,7 +804,7 @@ func invokeVarDelete(io cmdio.IO, projFile string, env envSelection, varName str
 	return nil
 }
 
-func invokeVarList(io cmdio.IO, projFile string, env envSelection) error {
+func invokeVarList(io cmdio.IO, projFile string, env envSelection, reveal bool) error {
 	p, err := readProject(projFile, true)
 	if err != nil {
 		return err
@@ -409,7 +849,7 @@ func invokeVarList(io cmdio.IO, projFile string, env envSelection) error {
 			} else {
 				v = p.Vars.Get(name)
 			}
-			io.Printf("%s{%s} = %q\n", p.VarPrefix(), name, v)
+			io.Printf("%s{%s} = %s\n", p.VarPrefix(), name, varDisplayValue(p, name, v, reveal))
 		}
 	}
 
@@ -422,6 +862,16 @@ type varsArgs struct {
 	env      envSelection
 	varName  string
 	value    string
+	file     string
+	dryRun   bool
+
+	// secret is whether the vars being set are to be marked as secrets. It is
+	// only set if --secret was given.
+	secret optional[bool]
+
+	// reveal is whether the values of secret vars are shown when listing or
+	// getting vars.
+	reveal bool
 }
 
 func parseVarsArgs(cmd *cobra.Command, posArgs []string, args *varsArgs) error {
@@ -448,6 +898,20 @@ func parseVarsArgs(cmd *cobra.Command, posArgs []string, args *varsArgs) error {
 		args.env.useCurrent = true
 	}
 
+	if cmd.Flags().Changed("secret") {
+		if args.action != varsActionSet && args.action != varsActionImport {
+			return fmt.Errorf("--secret can only be used when setting or importing vars")
+		}
+		args.secret = optional[bool]{set: true, v: flags.BSecret}
+	}
+
+	if flags.BReveal {
+		if args.action != varsActionList && args.action != varsActionGet {
+			return fmt.Errorf("--reveal can only be used when listing or getting vars")
+		}
+		args.reveal = true
+	}
+
 	// do action-specific arg and flag parsing
 	switch args.action {
 	case varsActionList:
@@ -462,6 +926,19 @@ func parseVarsArgs(cmd *cobra.Command, posArgs []string, args *varsArgs) error {
 	case varsActionDelete:
 		args.varName = flags.Delete
 		args.env.useAll = flags.BAll
+	case varsActionImport:
+		args.file = flags.Import
+		args.env.useAll = flags.BAll
+	case varsActionExport:
+		args.file = flags.Export
+	case varsActionUsages:
+		args.varName = flags.Usages
+	case varsActionUnused:
+		// nothing to do here
+	case varsActionRename:
+		args.varName = flags.Rename
+		args.value = posArgs[0]
+		args.dryRun = flags.BDryRun
 	default:
 		panic(fmt.Sprintf("unhandled vars action %q", args.action))
 	}
@@ -478,6 +955,84 @@ func parseVarsActionFromFlags(cmd *cobra.Command, posArgs []string) (varsAction,
 
 	f := cmd.Flags()
 
+	if f.Changed("import") {
+		if len(posArgs) > 0 {
+			return varsActionImport, fmt.Errorf("unknown positional argument %q", posArgs[0])
+		}
+		if flags.Import == "" {
+			return varsActionImport, fmt.Errorf("--import requires a file to import from")
+		}
+		if flags.Env == reservedDefaultEnvName {
+			return varsActionImport, fmt.Errorf("cannot specify reserved env name %q; use --default to import into default env", reservedDefaultEnvName)
+		}
+		if f.Changed("env") && flags.Env == "" {
+			return varsActionImport, fmt.Errorf("cannot specify env \"\"; use --default to import into default env")
+		}
+		return varsActionImport, nil
+	}
+
+	if f.Changed("export") {
+		if len(posArgs) > 0 {
+			return varsActionExport, fmt.Errorf("unknown positional argument %q", posArgs[0])
+		}
+		if flags.Export == "" {
+			return varsActionExport, fmt.Errorf("--export requires a file to export to")
+		}
+		if flags.BAll {
+			return varsActionExport, fmt.Errorf("--all cannot be used with --export; export each environment to its own file with --env")
+		}
+		if flags.Env == reservedDefaultEnvName {
+			return varsActionExport, fmt.Errorf("cannot specify reserved env name %q; use --default to export from default env", reservedDefaultEnvName)
+		}
+		if f.Changed("env") && flags.Env == "" {
+			return varsActionExport, fmt.Errorf("cannot specify env \"\"; use --default to export from default env")
+		}
+		return varsActionExport, nil
+	}
+
+	if f.Changed("rename") {
+		if len(posArgs) < 1 {
+			return varsActionRename, fmt.Errorf("--rename requires a new name for the var as an argument")
+		}
+		if len(posArgs) > 1 {
+			return varsActionRename, fmt.Errorf("unknown positional argument %q", posArgs[1])
+		}
+		if flags.Rename == "" {
+			return varsActionRename, fmt.Errorf("--rename requires a var to rename")
+		}
+		if f.Changed("env") || flags.BDefault || flags.BCurrent || flags.BAll {
+			return varsActionRename, fmt.Errorf("--rename applies to all environments; it cannot be used with --env, --default, --current, or --all")
+		}
+		return varsActionRename, nil
+	}
+
+	if flags.BDryRun {
+		return varsActionList, fmt.Errorf("--dry-run can only be used with --rename")
+	}
+
+	if f.Changed("usages") {
+		if len(posArgs) > 0 {
+			return varsActionUsages, fmt.Errorf("unknown positional argument %q", posArgs[0])
+		}
+		if flags.Usages == "" {
+			return varsActionUsages, fmt.Errorf("--usages requires a var to find")
+		}
+		if f.Changed("env") || flags.BDefault || flags.BCurrent || flags.BAll {
+			return varsActionUsages, fmt.Errorf("--usages applies to all requests; it cannot be used with --env, --default, --current, or --all")
+		}
+		return varsActionUsages, nil
+	}
+
+	if flags.BUnused {
+		if len(posArgs) > 0 {
+			return varsActionUnused, fmt.Errorf("unknown positional argument %q", posArgs[0])
+		}
+		if f.Changed("env") || flags.BDefault || flags.BCurrent || flags.BAll {
+			return varsActionUnused, fmt.Errorf("--unused applies to all environments; it cannot be used with --env, --default, --current, or --all")
+		}
+		return varsActionUnused, nil
+	}
+
 	if f.Changed("delete") {
 		if len(posArgs) > 1 {
 			return varsActionDelete, fmt.Errorf("unknown positional argument %q", posArgs[1])
@@ -535,4 +1090,9 @@ const (
 	varsActionGet
 	varsActionSet
 	varsActionDelete
+	varsActionImport
+	varsActionExport
+	varsActionUsages
+	varsActionUnused
+	varsActionRename
 )
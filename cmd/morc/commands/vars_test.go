@@ -1,6 +1,9 @@
 package commands
 
 import (
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -122,6 +125,28 @@ func Test_Vars_List(t *testing.T) {
 			},
 			expectStdoutOutput: "${VAR1} = \"1\"\n${VAR2} = \"something\"\n",
 		},
+		{
+			name: "secret vars are masked",
+			args: []string{"vars"},
+			p: testProject_withSecrets(testProject_vars("", map[string]map[string]string{
+				"": {
+					"TOKEN": "8b5f2c9e",
+					"USER":  "vriska",
+				},
+			}), "TOKEN"),
+			expectStdoutOutput: "${TOKEN} = ***\n${USER} = \"vriska\"\n",
+		},
+		{
+			name: "secret vars are revealed",
+			args: []string{"vars", "--reveal"},
+			p: testProject_withSecrets(testProject_vars("", map[string]map[string]string{
+				"": {
+					"TOKEN": "8b5f2c9e",
+					"USER":  "vriska",
+				},
+			}), "TOKEN"),
+			expectStdoutOutput: "${TOKEN} = \"8b5f2c9e\"\n${USER} = \"vriska\"\n",
+		},
 		{
 			name: "vars with wrong case printed as uppercase",
 			args: []string{"vars"},
@@ -681,6 +706,24 @@ func Test_Vars_Get(t *testing.T) {
 			p:                  testProject_vars("", test_3EnvVarsMap),
 			expectStdoutOutput: test_3EnvVarsMap[""]["HOST"] + "\n",
 		},
+		{
+			name:               "secret var is masked",
+			args:               []string{"vars", "HOST"},
+			p:                  testProject_withSecrets(testProject_vars("", test_3EnvVarsMap), "HOST"),
+			expectStdoutOutput: "***\n",
+		},
+		{
+			name:               "secret var is revealed",
+			args:               []string{"vars", "HOST", "--reveal"},
+			p:                  testProject_withSecrets(testProject_vars("", test_3EnvVarsMap), "HOST"),
+			expectStdoutOutput: test_3EnvVarsMap[""]["HOST"] + "\n",
+		},
+		{
+			name:      "reveal when setting",
+			args:      []string{"vars", "HOST", "localhost", "--reveal"},
+			p:         testProject_vars("", test_3EnvVarsMap),
+			expectErr: "--reveal can only be used when listing or getting vars",
+		},
 		{
 			name:               "unspecified env, current=default, var is not present",
 			args:               []string{"vars", "PASSWORD"},
@@ -914,6 +957,33 @@ func Test_Vars_Set(t *testing.T) {
 			expectP:            testProject_vars("", map[string]map[string]string{"": {"VAR1": "VRISKA"}}),
 			expectStdoutOutput: "Set ${VAR1} to \"VRISKA\"\n",
 		},
+		{
+			name:               "make a new secret var",
+			args:               []string{"vars", "token", "8b5f2c9e", "--secret"},
+			p:                  morc.Project{},
+			expectP:            testProject_withSecrets(testProject_vars("", map[string]map[string]string{"": {"TOKEN": "8b5f2c9e"}}), "TOKEN"),
+			expectStdoutOutput: "Set ${TOKEN} to ***\n",
+		},
+		{
+			name:               "set existing secret var keeps it secret",
+			args:               []string{"vars", "token", "413"},
+			p:                  testProject_withSecrets(testProject_vars("", map[string]map[string]string{"": {"TOKEN": "8b5f2c9e"}}), "TOKEN"),
+			expectP:            testProject_withSecrets(testProject_vars("", map[string]map[string]string{"": {"TOKEN": "413"}}), "TOKEN"),
+			expectStdoutOutput: "Set ${TOKEN} to ***\n",
+		},
+		{
+			name:               "unmark secret var",
+			args:               []string{"vars", "token", "413", "--secret=false"},
+			p:                  testProject_withSecrets(testProject_vars("", map[string]map[string]string{"": {"TOKEN": "8b5f2c9e"}}), "TOKEN"),
+			expectP:            testProject_vars("", map[string]map[string]string{"": {"TOKEN": "413"}}),
+			expectStdoutOutput: "Set ${TOKEN} to \"413\"\n",
+		},
+		{
+			name:      "secret when getting",
+			args:      []string{"vars", "token", "--secret"},
+			p:         morc.Project{},
+			expectErr: "--secret can only be used when setting or importing vars",
+		},
 		{
 			name:               "make a new var, quiet mode",
 			args:               []string{"vars", "var1", "VRISKA", "-q"},
@@ -1045,6 +1115,12 @@ func Test_Vars_Set(t *testing.T) {
 			p:         testProject_vars("DEBUG", test_3EnvVarsMap),
 			expectErr: "cannot specify env \"\"; use --default to set in default env",
 		},
+		{
+			name:      "dynamic var name ERRORS",
+			args:      []string{"vars", "@uuid", "VRISKA"},
+			p:         testProject_vars("", test_3EnvVarsMap),
+			expectErr: "name \"@UUID\" is reserved for dynamic vars",
+		},
 		{
 			name:               "--default, current=default, var not present",
 			args:               []string{"vars", "var1", "VRISKA", "--default"},
@@ -1144,6 +1220,660 @@ func Test_Vars_Set(t *testing.T) {
 	}
 }
 
+func Test_Vars_Import(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file. ::FILE:: is replaced with the path to the import file
+		fileName           string
+		fileContent        string
+		p                  morc.Project
+		expectP            morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               ".env file",
+			args:               []string{"vars", "--import", "::FILE::"},
+			fileName:           "vars.env",
+			fileContent:        "# comment\nvar1=VRISKA\n\nexport VAR2 = \"TEREZI PYROPE\"\nvar3='KANAYA'\n",
+			p:                  morc.Project{},
+			expectP:            testProject_vars("", map[string]map[string]string{"": {"VAR1": "VRISKA", "VAR2": "TEREZI PYROPE", "VAR3": "KANAYA"}}),
+			expectStdoutOutput: "Imported 3 vars\n",
+		},
+		{
+			name:               "JSON file",
+			args:               []string{"vars", "--import", "::FILE::"},
+			fileName:           "vars.json",
+			fileContent:        `{"var1": "VRISKA", "VAR2": "TEREZI"}`,
+			p:                  morc.Project{},
+			expectP:            testProject_vars("", map[string]map[string]string{"": {"VAR1": "VRISKA", "VAR2": "TEREZI"}}),
+			expectStdoutOutput: "Imported 2 vars\n",
+		},
+		{
+			name:               "into specific env",
+			args:               []string{"vars", "--import", "::FILE::", "--env", "PROD"},
+			fileName:           "vars.env",
+			fileContent:        "VAR1=VRISKA\n",
+			p:                  testProject_vars("", test_3EnvVarsMap),
+			expectP:            testProject_vars("", test_3EnvVarsMap, map[string]map[string]string{"": {"VAR1": ""}, "PROD": {"VAR1": "VRISKA"}}),
+			expectStdoutOutput: "Imported 1 var into environment PROD\n",
+		},
+		{
+			name:               "into all envs",
+			args:               []string{"vars", "--import", "::FILE::", "--all"},
+			fileName:           "vars.env",
+			fileContent:        "VAR1=VRISKA\n",
+			p:                  testProject_vars("", test_3EnvVarsMap),
+			expectP:            testProject_vars("", test_3EnvVarsMap, map[string]map[string]string{"": {"VAR1": "VRISKA"}, "PROD": {"VAR1": "VRISKA"}, "DEBUG": {"VAR1": "VRISKA"}}),
+			expectStdoutOutput: "Imported 1 var into all environments\n",
+		},
+		{
+			name:        ".env line not in KEY=VALUE format",
+			args:        []string{"vars", "--import", "::FILE::"},
+			fileName:    "vars.env",
+			fileContent: "VAR1=VRISKA\nVAR2\n",
+			p:           morc.Project{},
+			expectErr:   "line 2: not in KEY=VALUE format",
+		},
+		{
+			name:        "JSON value not a string",
+			args:        []string{"vars", "--import", "::FILE::"},
+			fileName:    "vars.json",
+			fileContent: `{"VAR1": 8}`,
+			p:           morc.Project{},
+			expectErr:   "value of \"VAR1\" is not a string",
+		},
+		{
+			name:        "with positional args",
+			args:        []string{"vars", "var1", "--import", "::FILE::"},
+			fileName:    "vars.env",
+			fileContent: "VAR1=VRISKA\n",
+			p:           morc.Project{},
+			expectErr:   "unknown positional argument \"var1\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetVarsFlags()
+
+			importPath := filepath.Join(t.TempDir(), tc.fileName)
+			if err := os.WriteFile(importPath, []byte(tc.fileContent), 0644); err != nil {
+				t.Fatalf("write import file: %v", err)
+			}
+			args := make([]string, len(tc.args))
+			for i := range tc.args {
+				args[i] = strings.ReplaceAll(tc.args[i], "::FILE::", importPath)
+			}
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(varsCmd, projFilePath, args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_projectFilesInBuffersMatch(assert, tc.expectP)
+		})
+	}
+}
+
+func Test_Vars_Export(t *testing.T) {
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file. ::FILE:: is replaced with the path to the export file
+		fileName           string
+		p                  morc.Project
+		expectFileContent  string
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "current view to .env",
+			args:               []string{"vars", "--export", "::FILE::"},
+			fileName:           "vars.env",
+			p:                  testProject_vars("PROD", test_3EnvVarsMap),
+			expectFileContent:  "EXTRA=\"data\"\nHOST=\"example.com\"\nSCHEME=\"https\"\n",
+			expectStdoutOutput: "Exported 3 vars to ::FILE::\n",
+		},
+		{
+			name:               "current env only to .env",
+			args:               []string{"vars", "--export", "::FILE::", "--current"},
+			fileName:           "vars.env",
+			p:                  testProject_vars("PROD", test_3EnvVarsMap),
+			expectFileContent:  "HOST=\"example.com\"\nSCHEME=\"https\"\n",
+			expectStdoutOutput: "Exported 2 vars from the current environment to ::FILE::\n",
+		},
+		{
+			name:               "default env to JSON",
+			args:               []string{"vars", "--export", "::FILE::", "--default"},
+			fileName:           "vars.json",
+			p:                  testProject_vars("PROD", test_3EnvVarsMap),
+			expectFileContent:  "{\n  \"EXTRA\": \"data\",\n  \"HOST\": \"internal-test.example.com\",\n  \"SCHEME\": \"http\"\n}\n",
+			expectStdoutOutput: "Exported 3 vars from the default environment to ::FILE::\n",
+		},
+		{
+			name:               "values with quotes are escaped",
+			args:               []string{"vars", "--export", "::FILE::"},
+			fileName:           "vars.env",
+			p:                  testProject_vars("", map[string]map[string]string{"": {"VAR1": "say \"hi\"\n"}}),
+			expectFileContent:  "VAR1=\"say \\\"hi\\\"\\n\"\n",
+			expectStdoutOutput: "Exported 1 var to ::FILE::\n",
+		},
+		{
+			name:      "with --all",
+			args:      []string{"vars", "--export", "::FILE::", "--all"},
+			fileName:  "vars.env",
+			p:         testProject_vars("", test_3EnvVarsMap),
+			expectErr: "--all cannot be used with --export",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetVarsFlags()
+
+			exportPath := filepath.Join(t.TempDir(), tc.fileName)
+			args := make([]string, len(tc.args))
+			for i := range tc.args {
+				args[i] = strings.ReplaceAll(tc.args[i], "::FILE::", exportPath)
+			}
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(varsCmd, projFilePath, args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(strings.ReplaceAll(tc.expectStdoutOutput, "::FILE::", exportPath), output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			content, err := os.ReadFile(exportPath)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectFileContent, string(content))
+
+			assert_noProjectMutations(assert)
+		})
+	}
+}
+
+func Test_Vars_Usages(t *testing.T) {
+	reqs := testProject_withRequests(
+		morc.RequestTemplate{Name: "get-user", Method: "GET", URL: "${SCHEME}://example.com/users/${USER_ID}"},
+		morc.RequestTemplate{Name: "login", Method: "POST", URL: "${SCHEME}://example.com/login", Headers: http.Header{"Authorization": []string{"Bearer ${token}"}}},
+		morc.RequestTemplate{Name: "update-user", Method: "PATCH", URL: "/users", Body: []byte(`{"id": "${USER_ID}"}`)},
+		morc.RequestTemplate{Name: "escaped", Method: "GET", URL: "/users/$${USER_ID}"},
+	)
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name:               "used in URL and body",
+			args:               []string{"vars", "--usages", "USER_ID"},
+			p:                  reqs,
+			expectStdoutOutput: "get-user\nupdate-user\n",
+		},
+		{
+			name:               "used in header, case-insensitive",
+			args:               []string{"vars", "--usages", "Token"},
+			p:                  reqs,
+			expectStdoutOutput: "login\n",
+		},
+		{
+			name:               "used in many",
+			args:               []string{"vars", "--usages", "SCHEME"},
+			p:                  reqs,
+			expectStdoutOutput: "get-user\nlogin\n",
+		},
+		{
+			name:               "not used",
+			args:               []string{"vars", "--usages", "PASSWORD"},
+			p:                  reqs,
+			expectStdoutOutput: "No requests reference ${PASSWORD}\n",
+		},
+		{
+			name: "not used - quiet",
+			args: []string{"vars", "--usages", "PASSWORD", "-q"},
+			p:    reqs,
+		},
+		{
+			name: "custom var prefix",
+			args: []string{"vars", "--usages", "USER_ID"},
+			p: morc.Project{
+				Templates: map[string]morc.RequestTemplate{
+					"get-user": {Name: "get-user", Method: "GET", URL: "/users/#{USER_ID}"},
+					"other":    {Name: "other", Method: "GET", URL: "/users/${USER_ID}"},
+				},
+				Config: morc.Settings{VarPrefix: "#"},
+			},
+			expectStdoutOutput: "get-user\n",
+		},
+		{
+			name:      "with positional args",
+			args:      []string{"vars", "USER_ID", "--usages", "USER_ID"},
+			p:         reqs,
+			expectErr: "unknown positional argument \"USER_ID\"",
+		},
+		{
+			name:      "with env",
+			args:      []string{"vars", "--usages", "USER_ID", "--env", "PROD"},
+			p:         reqs,
+			expectErr: "--usages applies to all requests",
+		},
+		{
+			name:      "with delete",
+			args:      []string{"vars", "--usages", "USER_ID", "--delete", "USER_ID"},
+			p:         reqs,
+			expectErr: "were all set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetVarsFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(varsCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_noProjectMutations(assert)
+		})
+	}
+}
+
+func Test_Vars_Unused(t *testing.T) {
+	withVars := func(p morc.Project, vars map[string]map[string]string) morc.Project {
+		p.Vars = testVarStore("", vars)
+		return p
+	}
+
+	reqs := testProject_withRequests(
+		morc.RequestTemplate{Name: "get-user", Method: "GET", URL: "${URL_PATH}/${user_id}"},
+		morc.RequestTemplate{Name: "login", Method: "POST", URL: "/login", Headers: http.Header{"Authorization": []string{"Bearer ${TOKEN}"}}},
+	)
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name: "unused in any env are listed",
+			args: []string{"vars", "--unused"},
+			p: withVars(reqs, map[string]map[string]string{
+				"":     {"URL_PATH": "${BASE}/users", "BASE": "http://localhost", "USER_ID": "8", "TOKEN": "", "PASSWORD": "", "OLD_ID": ""},
+				"PROD": {"TOKEN": "413", "PASSWORD": "grimAuxiliatrix"},
+			}),
+			expectStdoutOutput: "OLD_ID\nPASSWORD\n",
+		},
+		{
+			name: "all used",
+			args: []string{"vars", "--unused"},
+			p: withVars(reqs, map[string]map[string]string{
+				"": {"URL_PATH": "/users", "USER_ID": "8", "TOKEN": ""},
+			}),
+			expectStdoutOutput: "All variables are referenced\n",
+		},
+		{
+			name: "all used - quiet",
+			args: []string{"vars", "--unused", "-q"},
+			p: withVars(reqs, map[string]map[string]string{
+				"": {"URL_PATH": "/users", "USER_ID": "8", "TOKEN": ""},
+			}),
+		},
+		{
+			name: "custom var prefix",
+			args: []string{"vars", "--unused"},
+			p: func() morc.Project {
+				p := withVars(testProject_withRequests(
+					morc.RequestTemplate{Name: "get-user", Method: "GET", URL: "/users/#{USER_ID}/${OTHER}"},
+				), map[string]map[string]string{
+					"": {"USER_ID": "8", "OTHER": ""},
+				})
+				p.Config.VarPrefix = "#"
+				return p
+			}(),
+			expectStdoutOutput: "OTHER\n",
+		},
+		{
+			name:      "with positional args",
+			args:      []string{"vars", "USER_ID", "--unused"},
+			p:         reqs,
+			expectErr: "unknown positional argument \"USER_ID\"",
+		},
+		{
+			name:      "with env",
+			args:      []string{"vars", "--unused", "--env", "PROD"},
+			p:         reqs,
+			expectErr: "--unused applies to all environments",
+		},
+		{
+			name:      "with usages",
+			args:      []string{"vars", "--unused", "--usages", "USER_ID"},
+			p:         reqs,
+			expectErr: "were all set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetVarsFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(varsCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			assert_noProjectMutations(assert)
+		})
+	}
+}
+
+func Test_Vars_Rename(t *testing.T) {
+	reqs := map[string]morc.RequestTemplate{
+		"get-user": {Name: "get-user", Method: "GET", URL: "${SCHEME}://example.com/users/${ID}"},
+		"login":    {Name: "login", Method: "POST", URL: "${SCHEME}://example.com/login"},
+	}
+	renamedReqs := map[string]morc.RequestTemplate{
+		"get-user": {Name: "get-user", Method: "GET", URL: "${SCHEME}://example.com/users/${USER_ID}"},
+		"login":    {Name: "login", Method: "POST", URL: "${SCHEME}://example.com/login"},
+	}
+
+	testCases := []struct {
+		name               string
+		args               []string // DO NOT INCLUDE -F; it is automatically set to a project file
+		p                  morc.Project
+		expectP            morc.Project
+		expectErr          string // set if command.Execute expected to fail, with a string that would be in the error message
+		expectProjectSaved bool
+		expectStderrOutput string // set with expected output to stderr
+		expectStdoutOutput string // set with expected output to stdout
+	}{
+		{
+			name: "rename in all envs and requests",
+			args: []string{"vars", "--rename", "id", "user_id"},
+			p: morc.Project{
+				Templates: reqs,
+				Vars: testVarStore("", map[string]map[string]string{
+					"":     {"ID": "413", "SCHEME": "https"},
+					"PROD": {"ID": "612"},
+				}),
+			},
+			expectP: morc.Project{
+				Templates: renamedReqs,
+				Vars: testVarStore("", map[string]map[string]string{
+					"":     {"USER_ID": "413", "SCHEME": "https"},
+					"PROD": {"USER_ID": "612"},
+				}),
+			},
+			expectProjectSaved: true,
+			expectStdoutOutput: "Renamed ${ID} to ${USER_ID}; updated 1 request, 0 flows, and 0 vars\n",
+		},
+		{
+			name: "references in var values are renamed",
+			args: []string{"vars", "--rename", "ID", "USER_ID"},
+			p: testProject_vars("", map[string]map[string]string{
+				"":     {"ID": "413", "PATH": "/users/${ID}"},
+				"PROD": {"PATH": "/v2/users/${ID}"},
+			}),
+			expectP: testProject_vars("", map[string]map[string]string{
+				"":     {"USER_ID": "413", "PATH": "/users/${USER_ID}"},
+				"PROD": {"PATH": "/v2/users/${USER_ID}"},
+			}),
+			expectProjectSaved: true,
+			expectStdoutOutput: "Renamed ${ID} to ${USER_ID}; updated 0 requests, 0 flows, and 1 var\n",
+		},
+		{
+			name: "flow step requirements are renamed",
+			args: []string{"vars", "--rename", "ID", "USER_ID"},
+			p: morc.Project{
+				Templates: reqs,
+				Flows: map[string]morc.Flow{
+					"user":  {Name: "user", Steps: []morc.FlowStep{{Template: "login"}, {Template: "get-user", Requires: []string{"SCHEME", "id"}}}},
+					"login": {Name: "login", Steps: []morc.FlowStep{{Template: "login", Requires: []string{"SCHEME"}}}},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"ID": "413", "SCHEME": "https"},
+				}),
+			},
+			expectP: morc.Project{
+				Templates: renamedReqs,
+				Flows: map[string]morc.Flow{
+					"user":  {Name: "user", Steps: []morc.FlowStep{{Template: "login"}, {Template: "get-user", Requires: []string{"SCHEME", "USER_ID"}}}},
+					"login": {Name: "login", Steps: []morc.FlowStep{{Template: "login", Requires: []string{"SCHEME"}}}},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"USER_ID": "413", "SCHEME": "https"},
+				}),
+			},
+			expectProjectSaved: true,
+			expectStdoutOutput: "Renamed ${ID} to ${USER_ID}; updated 1 request, 1 flow, and 0 vars\n",
+		},
+		{
+			name: "default headers and base URLs are renamed",
+			args: []string{"vars", "--rename", "TOKEN", "AUTH"},
+			p: func() morc.Project {
+				p := testProject_vars("", map[string]map[string]string{
+					"":     {"TOKEN": "abc", "HOST": "localhost"},
+					"PROD": {"TOKEN": "xyz"},
+				})
+				p.Config.DefaultHeaders = http.Header{"Authorization": {"Bearer ${TOKEN}"}, "Accept": {"application/json"}}
+				p.Vars.SetBaseURLIn("https://${HOST}/${TOKEN}", "")
+				p.Vars.SetBaseURLIn("https://example.com/${TOKEN}", "PROD")
+				p.Vars.SetBaseURLIn("https://${HOST}", "STAGING")
+				return p
+			}(),
+			expectP: func() morc.Project {
+				p := testProject_vars("", map[string]map[string]string{
+					"":     {"AUTH": "abc", "HOST": "localhost"},
+					"PROD": {"AUTH": "xyz"},
+				})
+				p.Config.DefaultHeaders = http.Header{"Authorization": {"Bearer ${AUTH}"}, "Accept": {"application/json"}}
+				p.Vars.SetBaseURLIn("https://${HOST}/${AUTH}", "")
+				p.Vars.SetBaseURLIn("https://example.com/${AUTH}", "PROD")
+				p.Vars.SetBaseURLIn("https://${HOST}", "STAGING")
+				return p
+			}(),
+			expectProjectSaved: true,
+			expectStdoutOutput: "Renamed ${TOKEN} to ${AUTH}; updated 0 requests, 0 flows, and 0 vars\n" +
+				"Updated default headers\n" +
+				"Updated base URL of the default environment\n" +
+				"Updated base URL of environment \"PROD\"\n",
+		},
+		{
+			name: "dry run lists default headers and base URLs",
+			args: []string{"vars", "--rename", "TOKEN", "AUTH", "--dry-run"},
+			p: func() morc.Project {
+				p := testProject_vars("", map[string]map[string]string{"": {"TOKEN": "abc"}})
+				p.Config.DefaultHeaders = http.Header{"Authorization": {"Bearer ${TOKEN}"}}
+				p.Vars.SetBaseURLIn("https://example.com/${TOKEN}", "PROD")
+				return p
+			}(),
+			expectStdoutOutput: "Would rename ${TOKEN} to ${AUTH}\n" +
+				"Would update default headers\n" +
+				"Would update base URL of environment \"PROD\"\n",
+		},
+		{
+			name: "dry run",
+			args: []string{"vars", "--rename", "ID", "USER_ID", "--dry-run"},
+			p: morc.Project{
+				Templates: reqs,
+				Flows: map[string]morc.Flow{
+					"user": {Name: "user", Steps: []morc.FlowStep{{Template: "get-user", Requires: []string{"ID"}}}},
+				},
+				Vars: testVarStore("", map[string]map[string]string{
+					"": {"ID": "413", "PATH": "/users/${ID}"},
+				}),
+			},
+			expectStdoutOutput: "Would rename ${ID} to ${USER_ID}\n" +
+				"Would update request get-user\n" +
+				"Would update flow user\n" +
+				"Would update ${PATH}\n",
+		},
+		{
+			name:      "var does not exist",
+			args:      []string{"vars", "--rename", "NAME", "USER_NAME"},
+			p:         testProject_vars("", map[string]map[string]string{"": {"ID": "413"}}),
+			expectErr: "${NAME} does not exist",
+		},
+		{
+			name:      "new name already exists",
+			args:      []string{"vars", "--rename", "ID", "NAME"},
+			p:         testProject_vars("", map[string]map[string]string{"": {"ID": "413", "NAME": "VRISKA"}}),
+			expectErr: "${NAME} already exists",
+		},
+		{
+			name:      "new name is invalid",
+			args:      []string{"vars", "--rename", "ID", "@ID"},
+			p:         testProject_vars("", map[string]map[string]string{"": {"ID": "413"}}),
+			expectErr: "@ID",
+		},
+		{
+			name:      "no new name",
+			args:      []string{"vars", "--rename", "ID"},
+			p:         testProject_vars("", map[string]map[string]string{"": {"ID": "413"}}),
+			expectErr: "--rename requires a new name for the var as an argument",
+		},
+		{
+			name:      "with env",
+			args:      []string{"vars", "--rename", "ID", "USER_ID", "--default"},
+			p:         testProject_vars("", map[string]map[string]string{"": {"ID": "413"}}),
+			expectErr: "--rename applies to all environments",
+		},
+		{
+			name:      "dry run without rename",
+			args:      []string{"vars", "--dry-run"},
+			p:         testProject_vars("", map[string]map[string]string{"": {"ID": "413"}}),
+			expectErr: "--dry-run can only be used with --rename",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			resetVarsFlags()
+
+			// create project and dump config to a temp dir
+			projFilePath := createTestProjectIO(t, tc.p)
+			// set up the root command and run
+			output, outputErr, err := runTestCommand(varsCmd, projFilePath, tc.args)
+
+			// assert and check stdout and stderr
+			if err != nil {
+				if tc.expectErr == "" {
+					t.Fatalf("unexpected returned error: %v", err)
+					return
+				}
+				if !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("expected returned error to contain %q, got %q", tc.expectErr, err)
+				}
+				return
+			} else if tc.expectErr != "" {
+				t.Fatalf("expected error %q, got no error", tc.expectErr)
+			}
+
+			// assertions
+
+			assert.Equal(tc.expectStdoutOutput, output, "stdout output mismatch")
+			assert.Equal(tc.expectStderrOutput, outputErr, "stderr output mismatch")
+
+			if tc.expectProjectSaved {
+				assert_projectPersistedToBuffer(assert, tc.expectP)
+			} else {
+				assert_noProjectMutations(assert)
+			}
+		})
+	}
+}
+
 func resetVarsFlags() {
 	flags.ProjectFile = ""
 	flags.Delete = ""
@@ -1151,6 +1881,14 @@ func resetVarsFlags() {
 	flags.BDefault = false
 	flags.BCurrent = false
 	flags.BAll = false
+	flags.Import = ""
+	flags.Export = ""
+	flags.Usages = ""
+	flags.BUnused = false
+	flags.Rename = ""
+	flags.BDryRun = false
+	flags.BSecret = false
+	flags.BReveal = false
 	flags.BQuiet = false
 
 	varsCmd.Flags().VisitAll(func(fl *pflag.Flag) {
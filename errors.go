@@ -2,7 +2,10 @@ package morc
 
 // TODO: convert all error wording to these functions, adding as needed
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func NewFlowNotFoundError(name string) error {
 	if name == "" {
@@ -31,3 +34,30 @@ func NewReqExistsError(name string) error {
 	}
 	return fmt.Errorf("request named %s already exists in project", name)
 }
+
+// AssertionError is returned when a response fails one or more assertions made
+// against it. Failures holds a description of each failed assertion.
+type AssertionError struct {
+	Failures []string
+}
+
+func (e *AssertionError) Error() string {
+	if len(e.Failures) == 1 {
+		return "assertion failed: " + e.Failures[0]
+	}
+	return fmt.Sprintf("%d assertions failed:\n * %s", len(e.Failures), strings.Join(e.Failures, "\n * "))
+}
+
+// CaptureError is returned when captures could not be taken from a response but
+// the response was otherwise handled, such as when errors from captures are
+// deferred with SendOptions.DeferCaptureErrors.
+type CaptureError struct {
+	Failures []string
+}
+
+func (e *CaptureError) Error() string {
+	if len(e.Failures) == 1 {
+		return "capture failed: " + e.Failures[0]
+	}
+	return fmt.Sprintf("%d captures failed:\n * %s", len(e.Failures), strings.Join(e.Failures, "\n * "))
+}
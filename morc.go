@@ -3,15 +3,25 @@ package morc
 
 import (
 	"bytes"
+	"context"
+	crand "crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"net/http/httptrace"
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
@@ -19,6 +29,7 @@ import (
 	"sync"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/dekarrin/rezi/v2"
 )
@@ -27,6 +38,17 @@ const Version = "0.4.3+dev"
 
 const (
 	varNamePattern = `[-a-zA-Z0-9_]+`
+
+	// dynamicVarPattern matches the name of a dynamic var, including its
+	// leading '@' and any arguments.
+	dynamicVarPattern = `@[a-zA-Z]+(?::[^}|]*)?`
+
+	// transformNamePattern matches the name of a transform applied to a var.
+	transformNamePattern = `[a-zA-Z0-9]+`
+
+	// MaxVarDepth is the maximum number of nested variable references that
+	// will be followed when expanding a single variable's value.
+	MaxVarDepth = 10
 )
 
 type TraversalStep struct {
@@ -34,30 +56,145 @@ type TraversalStep struct {
 	Index int
 }
 
+// String returns the step as it would be written in a capture spec. Keys that
+// could not be parsed back as-is, such as those containing dots, brackets,
+// whitespace, or quotes, are quoted so that the result round-trips through
+// ParseVarScraperSpec.
 func (t TraversalStep) String() string {
 	if t.Key != "" {
-		return "." + t.Key
+		if !keyNeedsQuoting(t.Key) {
+			return "." + t.Key
+		}
+
+		var sb strings.Builder
+		sb.WriteString(".\"")
+		for _, ch := range t.Key {
+			if ch == '"' || ch == '\\' {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(ch)
+		}
+		sb.WriteRune('"')
+		return sb.String()
 	}
 	return fmt.Sprintf("[%d]", t.Index)
 }
 
+// keyNeedsQuoting returns whether key must be quoted to be given as a key in a
+// capture spec.
+func keyNeedsQuoting(key string) bool {
+	for _, ch := range key {
+		switch {
+		case ch == '.', ch == '[', ch == '"', ch == '\\', ch == '|', unicode.IsSpace(ch):
+			return true
+		}
+	}
+	return false
+}
+
 func (t TraversalStep) Traverse(data interface{}) (interface{}, error) {
 	switch data := data.(type) {
 	case map[string]interface{}:
-		return data[t.Key], nil
+		val, ok := data[t.Key]
+		if !ok {
+			return nil, fmt.Errorf("key %q does not exist", t.Key)
+		}
+		return val, nil
 	case []interface{}:
+		if t.Index < 0 || t.Index >= len(data) {
+			return nil, fmt.Errorf("index %d is out of range for array of length %d", t.Index, len(data))
+		}
 		return data[t.Index], nil
 	default:
 		return nil, fmt.Errorf("can't traverse %T", data)
 	}
 }
 
+// ParseVarScraperSpec parses a capture spec into a VarScraper with the given
+// name. The spec may consist of multiple specs separated by "||", in which
+// case the first is the primary spec and the rest are alternatives that are
+// tried in order if it fails.
 func ParseVarScraperSpec(name, spec string) (VarScraper, error) {
+	specs := splitSpecAlternatives(spec)
+	if len(specs) == 1 {
+		return parseSingleVarScraperSpec(name, spec)
+	}
+
+	scraper, err := parseSingleVarScraperSpec(name, specs[0])
+	if err != nil {
+		return VarScraper{}, err
+	}
+
+	for i, altSpec := range specs[1:] {
+		if altSpec == "" {
+			return VarScraper{}, fmt.Errorf("alternative #%d is empty", i+1)
+		}
+
+		alt, err := parseSingleVarScraperSpec("", altSpec)
+		if err != nil {
+			return VarScraper{}, fmt.Errorf("alternative #%d: %w", i+1, err)
+		}
+		scraper.Alternatives = append(scraper.Alternatives, alt)
+	}
+
+	return scraper, nil
+}
+
+// splitSpecAlternatives splits spec on every "||" that is not inside of a
+// quoted key or escaped. Each resulting spec has surrounding whitespace
+// trimmed.
+func splitSpecAlternatives(spec string) []string {
+	var specs []string
+	var cur strings.Builder
+	inQuote := false
+
+	specR := []rune(spec)
+	for i := 0; i < len(specR); i++ {
+		ch := specR[i]
+
+		if ch == '\\' && i+1 < len(specR) {
+			cur.WriteRune(ch)
+			cur.WriteRune(specR[i+1])
+			i++
+			continue
+		}
+
+		if ch == '"' {
+			inQuote = !inQuote
+		} else if !inQuote && ch == '|' && i+1 < len(specR) && specR[i+1] == '|' {
+			specs = append(specs, strings.TrimSpace(cur.String()))
+			cur.Reset()
+			i++
+			continue
+		}
+
+		cur.WriteRune(ch)
+	}
+	specs = append(specs, strings.TrimSpace(cur.String()))
+
+	if len(specs) == 1 {
+		// leave a lone spec exactly as given
+		return []string{spec}
+	}
+	return specs
+}
+
+func parseSingleVarScraperSpec(name, spec string) (VarScraper, error) {
 	// okay, are we looking at a byte offset or a JSON traversal?
 	if strings.HasPrefix(spec, ":") {
-		// it is a byte offset of the form ":START,END"
-		offsets := strings.SplitN(spec[1:], ",", 2)
+		// it is a byte offset of the form ":START,END", or a rune offset of
+		// the form ":rSTART,END"
+		offsetSpec := spec[1:]
+		runes := strings.HasPrefix(offsetSpec, "r")
+		if runes {
+			offsetSpec = offsetSpec[1:]
+		}
+
+		offsets := strings.SplitN(offsetSpec, ",", 2)
 		if len(offsets) != 2 {
+			if runes {
+				return VarScraper{}, fmt.Errorf("%q is not in :rSTART,END format", spec)
+			}
 			return VarScraper{}, fmt.Errorf("%q is not in :START,END format", spec)
 		}
 
@@ -69,10 +206,6 @@ func ParseVarScraperSpec(name, spec string) (VarScraper, error) {
 			if err != nil {
 				return VarScraper{}, fmt.Errorf("%q: start offset: %w", spec, err)
 			}
-
-			if start < 0 {
-				return VarScraper{}, fmt.Errorf("%q: start offset cannot be negative", spec)
-			}
 		}
 
 		if len(offsets[1]) > 0 {
@@ -82,15 +215,15 @@ func ParseVarScraperSpec(name, spec string) (VarScraper, error) {
 			}
 		}
 
-		// only matters if end is greater than 0; 0 means "to the end", -1 means 1 from the end, etc.
-		if end <= start && end > 0 {
-			return VarScraper{}, fmt.Errorf("end offset %d is less than or equal to start offset %d", end, start)
+		if err := checkOffsets(start, end); err != nil {
+			return VarScraper{}, err
 		}
 
 		return VarScraper{
 			Name:        name,
 			OffsetStart: start,
 			OffsetEnd:   end,
+			RuneOffsets: runes,
 		}, nil
 	}
 
@@ -250,6 +383,9 @@ func ParseVarName(name string) (string, error) {
 	if name == "" {
 		return "", fmt.Errorf("name is empty")
 	}
+	if strings.HasPrefix(name, "@") {
+		return "", fmt.Errorf("name %q is reserved for dynamic vars", name)
+	}
 	if !regexp.MustCompile(`^` + varNamePattern + `$`).MatchString(name) {
 		return "", fmt.Errorf("name %q contains invalid characters", name)
 	}
@@ -282,14 +418,168 @@ type VarScraper struct {
 	OffsetStart int
 	OffsetEnd   int
 	Steps       []TraversalStep // if non-nil, OffsetStart and OffsetEnd are ignored
+
+	// RuneOffsets is whether OffsetStart and OffsetEnd count UTF-8 encoded
+	// runes rather than bytes. It has no effect if Steps is non-nil.
+	RuneOffsets bool
+
+	// Alternatives are specs that are tried in order if scraping with this
+	// one fails. The first that succeeds is used. The Name of each is ignored,
+	// and alternatives do not themselves have alternatives.
+	Alternatives []VarScraper
+
+	// Env is the name of the environment that captured values are stored in.
+	// If empty, they are stored in whichever environment is current when the
+	// value is captured. DefaultEnvName refers to the default environment. Env
+	// is ignored for alternatives.
+	Env string
+
+	// Transform is applied to the scraped value before it is returned. It is
+	// applied once, to whichever of v or its alternatives produced the value,
+	// and is ignored for alternatives.
+	Transform CaptureTransform
+
+	// Default is the value that is captured if scraping fails, such as when
+	// a key in the path is not present in the response. If nil, a failed
+	// scrape is an error. Transform is not applied to Default, and Default is
+	// ignored for alternatives.
+	Default *string
+}
+
+// CaptureTransform is an operation applied to a value after it is scraped from
+// a response. The zero value leaves the value unchanged.
+type CaptureTransform string
+
+const (
+	TransformNone         CaptureTransform = ""
+	TransformTrim         CaptureTransform = "trim"
+	TransformBase64Decode CaptureTransform = "base64decode"
+	TransformURLDecode    CaptureTransform = "urldecode"
+	TransformLower        CaptureTransform = "lower"
+	TransformUpper        CaptureTransform = "upper"
+)
+
+// CaptureTransforms returns all of the non-empty CaptureTransforms, in the
+// order they should be listed in.
+func CaptureTransforms() []CaptureTransform {
+	return []CaptureTransform{
+		TransformTrim,
+		TransformBase64Decode,
+		TransformURLDecode,
+		TransformLower,
+		TransformUpper,
+	}
+}
+
+// ParseCaptureTransform parses the name of a CaptureTransform. Case is ignored,
+// and the empty string or "none" gives TransformNone.
+func ParseCaptureTransform(s string) (CaptureTransform, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "none" {
+		return TransformNone, nil
+	}
+
+	ct := CaptureTransform(s)
+	if err := ct.Validate(); err != nil {
+		return TransformNone, err
+	}
+	return ct, nil
+}
+
+// Validate returns an error if ct is not one of the supported transforms.
+func (ct CaptureTransform) Validate() error {
+	if ct == TransformNone {
+		return nil
+	}
+	for _, known := range CaptureTransforms() {
+		if ct == known {
+			return nil
+		}
+	}
+
+	names := make([]string, len(CaptureTransforms()))
+	for i, known := range CaptureTransforms() {
+		names[i] = string(known)
+	}
+	return fmt.Errorf("unknown transform %q; must be one of %s", string(ct), strings.Join(names, ", "))
+}
+
+// Apply returns the result of applying ct to val.
+func (ct CaptureTransform) Apply(val string) (string, error) {
+	switch ct {
+	case TransformNone:
+		return val, nil
+	case TransformTrim:
+		return strings.TrimSpace(val), nil
+	case TransformBase64Decode:
+		// tokens are often in the URL-safe alphabet or unpadded, so accept
+		// any of the standard variants.
+		encodings := []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding}
+		var firstErr error
+		for _, enc := range encodings {
+			decoded, err := enc.DecodeString(val)
+			if err == nil {
+				return string(decoded), nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return "", fmt.Errorf("base64decode: %w", firstErr)
+	case TransformURLDecode:
+		decoded, err := url.QueryUnescape(val)
+		if err != nil {
+			return "", fmt.Errorf("urldecode: %w", err)
+		}
+		return decoded, nil
+	case TransformLower:
+		return strings.ToLower(val), nil
+	case TransformUpper:
+		return strings.ToUpper(val), nil
+	default:
+		return "", ct.Validate()
+	}
 }
 
 func (v VarScraper) String() string {
 	s := fmt.Sprintf("%s from ", strings.ToUpper(v.Name))
 	s += v.Spec()
+	if v.Transform != TransformNone {
+		s += " with " + string(v.Transform)
+	}
+	if v.Default != nil {
+		s += fmt.Sprintf(" (default %q)", *v.Default)
+	}
+	switch v.Env {
+	case "":
+	case DefaultEnvName:
+		s += " into default env"
+	default:
+		s += " into env " + strings.ToUpper(v.Env)
+	}
 	return s
 }
 
+// Copy returns a deep copy of v that shares no memory with it.
+func (v VarScraper) Copy() VarScraper {
+	c := v
+	if v.Steps != nil {
+		c.Steps = make([]TraversalStep, len(v.Steps))
+		copy(c.Steps, v.Steps)
+	}
+	if v.Alternatives != nil {
+		c.Alternatives = make([]VarScraper, len(v.Alternatives))
+		for i, alt := range v.Alternatives {
+			c.Alternatives[i] = alt.Copy()
+		}
+	}
+	if v.Default != nil {
+		def := *v.Default
+		c.Default = &def
+	}
+	return c
+}
+
 func (v VarScraper) IsOffsetSpec() bool {
 	return len(v.Steps) == 0
 }
@@ -317,15 +607,87 @@ func (v VarScraper) EqualSpec(other VarScraper) bool {
 		if v.OffsetStart != other.OffsetStart || v.OffsetEnd != other.OffsetEnd {
 			return false
 		}
+		if v.RuneOffsets != other.RuneOffsets && (v.OffsetStart != 0 || v.OffsetEnd != 0) {
+			return false
+		}
 	} else {
 		// not comprable
 		return false
 	}
 
+	if len(v.Alternatives) != len(other.Alternatives) {
+		return false
+	}
+	for i := range v.Alternatives {
+		if !v.Alternatives[i].EqualSpec(other.Alternatives[i]) {
+			return false
+		}
+	}
+
 	return true
 }
 
+// Validate checks that v holds a capture spec that ParseVarScraperSpec could
+// have produced. This is mainly useful for catching captures that were made
+// invalid by hand-editing a project file.
+func (v VarScraper) Validate() error {
+	if _, err := ParseVarName(v.Name); err != nil {
+		return err
+	}
+
+	if err := v.validateSingle(); err != nil {
+		return err
+	}
+
+	if err := v.Transform.Validate(); err != nil {
+		return err
+	}
+
+	for i, alt := range v.Alternatives {
+		if len(alt.Alternatives) > 0 {
+			return fmt.Errorf("alternative #%d: alternatives cannot themselves have alternatives", i+1)
+		}
+		if err := alt.validateSingle(); err != nil {
+			return fmt.Errorf("alternative #%d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// validateSingle checks v without any of its alternatives.
+func (v VarScraper) validateSingle() error {
+	if v.IsJSONSpec() {
+		// every sequence of keys and indexes is a valid path
+		return nil
+	}
+
+	return checkOffsets(v.OffsetStart, v.OffsetEnd)
+}
+
+// checkOffsets checks that the byte offsets of an offset spec are in order.
+// Negative offsets count back from the end of the data and an end of 0 means
+// the end of the data, so end can only be compared to start when both count
+// from the same side; otherwise it is checked when the offsets are resolved
+// against the data in Scrape.
+func checkOffsets(start, end int) error {
+	sameSide := (start >= 0 && end > 0) || (start < 0 && end < 0)
+	if sameSide && end <= start {
+		return fmt.Errorf("end offset %d is less than or equal to start offset %d", end, start)
+	}
+	return nil
+}
+
 func (v VarScraper) Spec() string {
+	s := v.singleSpec()
+	for _, alt := range v.Alternatives {
+		s += " || " + alt.singleSpec()
+	}
+	return s
+}
+
+// singleSpec is the spec of v without any of its alternatives.
+func (v VarScraper) singleSpec() string {
 	s := ""
 	if len(v.Steps) > 0 {
 		for _, step := range v.Steps {
@@ -335,7 +697,14 @@ func (v VarScraper) Spec() string {
 		if v.OffsetStart == 0 && v.OffsetEnd == 0 {
 			s += "entire response"
 		} else {
-			s += fmt.Sprintf("offset %d,", v.OffsetStart)
+			if v.RuneOffsets {
+				s += "rune "
+			}
+			if v.OffsetStart < 0 {
+				s += fmt.Sprintf("offset <END%d>,", v.OffsetStart)
+			} else {
+				s += fmt.Sprintf("offset %d,", v.OffsetStart)
+			}
 
 			if v.OffsetEnd == 0 {
 				s += "<END>"
@@ -349,26 +718,77 @@ func (v VarScraper) Spec() string {
 	return s
 }
 
+// resolveOffsets resolves the offsets of v against data of the given length,
+// converting any offsets relative to the end to absolute ones, and checks that
+// they are in bounds. units is appended to the length in errors.
+func (v VarScraper) resolveOffsets(length int, units string) (start, end int, err error) {
+	start, end = v.OffsetStart, v.OffsetEnd
+	if start < 0 {
+		start = length + v.OffsetStart
+		if start < 0 {
+			return 0, 0, fmt.Errorf("start offset is %d but data length is only %d%s", v.OffsetStart, length, units)
+		}
+	}
+	if start > length {
+		return 0, 0, fmt.Errorf("start offset is %d but data length is only %d%s", v.OffsetStart, length, units)
+	}
+	if v.OffsetEnd > length {
+		return 0, 0, fmt.Errorf("end offset is %d but data length is only %d%s", v.OffsetEnd, length, units)
+	}
+
+	// if end is 0, it is the rest of the data
+	if v.OffsetEnd == 0 {
+		return start, length, nil
+	}
+	if v.OffsetEnd < 0 {
+		end = length + v.OffsetEnd
+	}
+	if end <= start {
+		return 0, 0, fmt.Errorf("effective end offset of %d (%d) is less than or equal to effective start offset of %d (%d)", v.OffsetEnd, end, v.OffsetStart, start)
+	}
+	return start, end, nil
+}
+
+// Scrape gets the value of the variable from data. If v has alternatives and
+// scraping with v fails, each alternative is tried in order and the value from
+// the first to succeed is returned. If all of them fail, the error from the
+// last one is returned. The Transform of v, if any, is applied to the value
+// before it is returned.
 func (v VarScraper) Scrape(data []byte) (string, error) {
+	val, err := v.scrapeSingle(data)
+	for _, alt := range v.Alternatives {
+		if err == nil {
+			break
+		}
+		val, err = alt.scrapeSingle(data)
+	}
+	if err != nil {
+		return val, err
+	}
+	return v.Transform.Apply(val)
+}
+
+// scrapeSingle gets the value of the variable from data using only v and not
+// any of its alternatives.
+func (v VarScraper) scrapeSingle(data []byte) (string, error) {
 	if len(v.Steps) < 1 {
-		// binary offset only, just do a bounds check
-		if v.OffsetEnd > 0 && v.OffsetEnd > len(data) {
-			return "", fmt.Errorf("end offset is %d but data length is only %d", v.OffsetEnd, len(data))
-		}
-
-		// if end is 0, return the rest of the data
-		if v.OffsetEnd == 0 {
-			return string(data[v.OffsetStart:]), nil
-		} else if v.OffsetEnd < 0 {
-			// bounds check
-			actualEnd := len(data) + v.OffsetEnd
-			if actualEnd < v.OffsetStart {
-				return "", fmt.Errorf("effective end offset of %d (%d) is less than start offset %d", v.OffsetEnd, actualEnd, v.OffsetStart)
+		if v.RuneOffsets {
+			if !utf8.Valid(data) {
+				return "", fmt.Errorf("data is not valid UTF-8")
 			}
-			return string(data[v.OffsetStart:actualEnd]), nil
-		} else {
-			return string(data[v.OffsetStart:v.OffsetEnd]), nil
+			runes := []rune(string(data))
+			start, end, err := v.resolveOffsets(len(runes), " runes")
+			if err != nil {
+				return "", err
+			}
+			return string(runes[start:end]), nil
 		}
+
+		start, end, err := v.resolveOffsets(len(data), "")
+		if err != nil {
+			return "", err
+		}
+		return string(data[start:end]), nil
 	}
 
 	// otherwise, perform the traversal. hopefully we got either a JSON map or a
@@ -432,8 +852,34 @@ type RESTClient struct {
 	VarOverrides map[string]string // Cleared after every call to SendRequest.
 	VarPrefix    string
 
+	// Unexpanded is the set of variables whose references are left in place
+	// as-is rather than substituted.
+	Unexpanded map[string]bool
+
 	Scrapers []VarScraper
 
+	// DeferCaptureErrors is whether SendRequest keeps going when a scraper
+	// fails. If set, every scraper is tried and the response and the values
+	// that were captured are returned along with a *CaptureError for the rest.
+	DeferCaptureErrors bool
+
+	// NoSubstituteHeaders is whether CreateRequest leaves variables in header
+	// keys and values as they are instead of substituting them.
+	NoSubstituteHeaders bool
+
+	// NoSubstitute is whether CreateRequest and EncodeForm leave variables
+	// everywhere as they are. It takes precedence over NoSubstituteHeaders.
+	NoSubstitute bool
+
+	// EnvFallback is whether Substitute looks up a variable in the OS
+	// environment when it is in neither VarOverrides nor Vars.
+	EnvFallback bool
+
+	// BaseURL is put in front of any URL given to CreateRequest that is only a
+	// path starting with "/". Variables in it are substituted the same as in
+	// the URL.
+	BaseURL string
+
 	// cookie jar that records all SetCookies calls; this is a pointer to the
 	// same jar that is passed to HTTP
 	jar  *TimedCookieJar
@@ -446,11 +892,11 @@ func (r *RESTClient) SetCookieJar(jar *TimedCookieJar) {
 }
 
 // NewRESTClient creates a new RESTClient. 0 for cookie lifetime will default it
-// to 24 hours. If an http.Client is provided, it will be used for making calls,
-// but its cookie jar will be replaced with MORC's timeoutable variant. Callers
+// to 24 hours. If an http.Client is provided, a copy of it will be used for
+// making calls, but with its cookie jar replaced with MORC's timeoutable variant. Callers
 // should use other methods to load cookies in. If httpClient is set to nil, it
-// will default to a new http.Client with a default transport and timeout of 30
-// seconds.
+// will default to a new http.Client with a default transport and a timeout of
+// DefaultRequestTimeout.
 func NewRESTClient(cookieLifetime time.Duration, httpClient *http.Client) *RESTClient {
 	cookies := NewTimedCookieJar(nil, cookieLifetime)
 
@@ -459,10 +905,14 @@ func NewRESTClient(cookieLifetime time.Duration, httpClient *http.Client) *RESTC
 			Transport:     http.DefaultTransport,
 			CheckRedirect: nil,
 			Jar:           cookies,
-			Timeout:       30 * time.Second,
+			Timeout:       DefaultRequestTimeout,
 		}
 	} else {
-		httpClient.Jar = cookies
+		// copy the client so that we do not modify the caller's; this keeps
+		// clients created concurrently from clobbering each other's cookie jar
+		clientCopy := *httpClient
+		clientCopy.Jar = cookies
+		httpClient = &clientCopy
 	}
 
 	return &RESTClient{
@@ -475,14 +925,24 @@ func NewRESTClient(cookieLifetime time.Duration, httpClient *http.Client) *RESTC
 }
 
 // CreateRequest creates a request to the given endpoint. Values set in Vars and
-// VarOverrides are used to fill any variables in the URL, data, and headers.
+// VarOverrides are used to fill any variables in the URL, data, and headers. If
+// the URL is only a path starting with "/" and r.BaseURL is set, the request
+// is made to that path on the base URL.
 func (r *RESTClient) CreateRequest(method string, url string, data []byte, hdrs http.Header) (*http.Request, error) {
 	// find every variable in url of  and replace it with the value from r.Vars (or return error if encountering invalid var)
-	url, err := r.Substitute(url)
+	url, err := r.substituteUnlessVerbatim(url)
 	if err != nil {
 		return nil, fmt.Errorf("substitute vars in URL: %w", err)
 	}
 
+	if strings.HasPrefix(url, "/") && r.BaseURL != "" {
+		base, err := r.substituteUnlessVerbatim(r.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("substitute vars in base URL: %w", err)
+		}
+		url = strings.TrimSuffix(base, "/") + url
+	}
+
 	// okay, now ensure that the URL has a scheme
 	lowerURL := strings.ToLower(url)
 	if !strings.HasPrefix(lowerURL, "http://") && !strings.HasPrefix(lowerURL, "https://") {
@@ -493,7 +953,7 @@ func (r *RESTClient) CreateRequest(method string, url string, data []byte, hdrs
 	// find every variable in data and replace it with the value from r.Vars (or return error if encountering invalid var)
 	if data != nil {
 		dataStr := string(data)
-		dataStr, err = r.Substitute(dataStr)
+		dataStr, err = r.substituteUnlessVerbatim(dataStr)
 		if err != nil {
 			return nil, fmt.Errorf("substitute vars in data: %w", err)
 		}
@@ -506,10 +966,18 @@ func (r *RESTClient) CreateRequest(method string, url string, data []byte, hdrs
 		return nil, err
 	}
 
-	// find every variable in headers and replace it with the value from r.Vars (or return error if encountering invalid var)
+	// find every variable in headers and replace it with the value from r.Vars (or return error if encountering invalid var),
+	// unless told to leave them alone
 	if len(hdrs) > 0 {
 		req.Header = make(http.Header)
 		for key, values := range hdrs {
+			if r.NoSubstituteHeaders || r.NoSubstitute {
+				for _, value := range values {
+					req.Header.Add(key, value)
+				}
+				continue
+			}
+
 			newKey, err := r.Substitute(key)
 			if err != nil {
 				return nil, fmt.Errorf("substitute header key %q: %w", key, err)
@@ -533,6 +1001,18 @@ func (r *RESTClient) CreateRequest(method string, url string, data []byte, hdrs
 // scanned for var captures and those that are captured are stored in Vars and
 // re
 func (r *RESTClient) SendRequest(req *http.Request) (*http.Response, map[string]string, error) {
+	resp, respBody, err := r.exchange(req)
+	if err != nil {
+		return resp, nil, err
+	}
+
+	return r.capture(resp, respBody)
+}
+
+// exchange sends req and reads the entire body of the response into memory.
+// The returned response's body is replaced with a reader over the returned
+// bytes.
+func (r *RESTClient) exchange(req *http.Request) (*http.Response, []byte, error) {
 	resp, err := r.http.Do(req)
 	if err != nil {
 		return resp, nil, err
@@ -546,12 +1026,97 @@ func (r *RESTClient) SendRequest(req *http.Request) (*http.Response, map[string]
 	resp.Body.Close()
 	resp.Body = io.NopCloser(bytes.NewBuffer(respBody))
 
+	return resp, respBody, nil
+}
+
+// stream sends req and copies the body of the response to w as it is received
+// instead of reading it into memory. The returned response has no body and its
+// ContentLength is set to the number of bytes that were copied. The scrapers of
+// r are not run.
+//
+// The timeout of the http client of r only bounds the wait for the headers of
+// the response; once they arrive, the body is copied for as long as it takes,
+// so that large downloads are not cut off.
+func (r *RESTClient) stream(req *http.Request, w io.Writer) (*http.Response, error) {
+	client := r.http
+	var headerTimer *time.Timer
+	if timeout := r.http.Timeout; timeout > 0 {
+		untimed := *r.http
+		untimed.Timeout = 0
+		client = &untimed
+
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		headerTimer = time.AfterFunc(timeout, cancel)
+		req = req.WithContext(ctx)
+	}
+
+	resp, err := client.Do(req)
+	if headerTimer != nil && !headerTimer.Stop() && err != nil {
+		return resp, fmt.Errorf("no response within %s: %w", r.http.Timeout, err)
+	}
+	if err != nil {
+		return resp, err
+	}
+	defer resp.Body.Close()
+
+	sw := &streamWriter{w: w}
+	n, err := io.Copy(sw, resp.Body)
+	if err != nil {
+		if sw.err != nil {
+			return resp, fmt.Errorf("write response body: %w", err)
+		}
+		return resp, fmt.Errorf("read response body: %w", err)
+	}
+	resp.Body = http.NoBody
+	resp.ContentLength = n
+
+	// clear var overrides
+	r.VarOverrides = map[string]string{}
+
+	return resp, nil
+}
+
+// streamWriter is an io.Writer that records the last error returned by the
+// writer it wraps, so that a failed copy can be told apart from a failed read.
+type streamWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	n, err := sw.w.Write(p)
+	if err != nil {
+		sw.err = err
+	}
+	return n, err
+}
+
+// capture runs all of the scrapers of r against respBody, the body of resp,
+// and returns resp along with the captured values.
+func (r *RESTClient) capture(resp *http.Response, respBody []byte) (*http.Response, map[string]string, error) {
 	// scrape vars from response
 	capturedVars := make(map[string]string)
+	var capFailures []string
 	for _, scraper := range r.Scrapers {
-		value, err := scraper.Scrape(respBody)
+		var value string
+		var err error
+		if isHeadResponse(resp) {
+			// there is no body to scrape, no matter what it may look like
+			err = fmt.Errorf("response to HEAD request has no body")
+		} else {
+			value, err = scraper.Scrape(respBody)
+		}
+		if err != nil && scraper.Default != nil {
+			value, err = *scraper.Default, nil
+		}
 		if err != nil {
-			return resp, nil, fmt.Errorf("scrape %s: %w", scraper.Name, err)
+			err = fmt.Errorf("scrape %s: %w", scraper.Name, err)
+			if !r.DeferCaptureErrors {
+				return resp, nil, err
+			}
+			capFailures = append(capFailures, err.Error())
+			continue
 		}
 		capturedVars[scraper.Name] = value
 		r.Vars[scraper.Name] = value
@@ -560,55 +1125,143 @@ func (r *RESTClient) SendRequest(req *http.Request) (*http.Response, map[string]
 	// clear var overrides
 	r.VarOverrides = map[string]string{}
 
+	if len(capFailures) > 0 {
+		return resp, capturedVars, &CaptureError{Failures: capFailures}
+	}
+
 	return resp, capturedVars, nil
 }
 
+// Substitute replaces every variable reference in s with its value from
+// VarOverrides or Vars, or from the OS environment variable of the same name if
+// it is in neither and EnvFallback is set. Values may themselves contain references to other
+// variables, which are expanded recursively up to MaxVarDepth levels deep. A
+// variable that refers back to itself, directly or through other variables,
+// results in an error. A reference preceded by a doubled prefix (such as
+// "$${NAME}") or to a variable in Unexpanded is left as-is. References to
+// dynamic vars, whose names begin with '@' (such as "${@uuid}"), are replaced
+// with a freshly-computed value on every call. A reference may be followed by
+// one or more transforms, each given as '|' and the name of the transform (such
+// as "${TOKEN|trim|base64}"), which are applied to the value in order.
 func (r *RESTClient) Substitute(s string) (string, error) {
 	// find every variable in s and replace it with the value from r.Vars (or return error if not)
-	expr := regexp.QuoteMeta(r.VarPrefix + "{")
-	expr += `(` + varNamePattern + `)`
-	expr += regexp.QuoteMeta("}")
-
-	rx, err := regexp.Compile(expr)
+	rx, err := regexp.Compile(varRefPattern(r.VarPrefix))
 	if err != nil {
 		return "", fmt.Errorf("compile regular expression: %w", err)
 	}
 
+	return r.substitute(rx, s, nil)
+}
+
+// substituteUnlessVerbatim returns the result of Substitute on s, or s as it is
+// if r.NoSubstitute is set.
+func (r *RESTClient) substituteUnlessVerbatim(s string) (string, error) {
+	if r.NoSubstitute {
+		return s, nil
+	}
+	return r.Substitute(s)
+}
+
+// varRefPattern returns a regular expression pattern that matches a reference
+// to a variable using the given prefix. Submatch 1 is the name of the variable
+// and submatch 2 is any transforms applied to it, each with a leading '|'.
+func varRefPattern(prefix string) string {
+	expr := regexp.QuoteMeta(prefix + "{")
+	expr += `(` + varNamePattern + `|` + dynamicVarPattern + `)`
+	expr += `((?:\|` + transformNamePattern + `)*)`
+	expr += regexp.QuoteMeta("}")
+	return expr
+}
+
+// substitute does the actual work of Substitute. refChain is the list of
+// variables currently being expanded, outermost first.
+func (r *RESTClient) substitute(rx *regexp.Regexp, s string, refChain []string) (string, error) {
 	updated := strings.Builder{}
 	var lastSearchEnd int
 
 	// find all matches
-	matchPairs := rx.FindAllStringIndex(s, -1)
-	for _, pair := range matchPairs {
+	matches := rx.FindAllStringSubmatchIndex(s, -1)
+	for _, m := range matches {
 		// check if it begins with a doubled prefix; if so, skip it
 		prefixLen := len(r.VarPrefix)
 
-		if pair[0]-prefixLen >= 0 {
-			prevSequence := s[pair[0]-prefixLen : pair[0]]
+		if m[0]-prefixLen >= 0 {
+			prevSequence := s[m[0]-prefixLen : m[0]]
 			if prevSequence == r.VarPrefix {
 				// ignore it
 				continue
 			}
 		}
 
-		// get the variable name
-		varName := s[pair[0]+prefixLen+1 : pair[1]-1]
+		// get the variable name and any transforms
+		varName := s[m[2]:m[3]]
+		var transforms []string
+		if m[5] > m[4] {
+			transforms = strings.Split(s[m[4]+1:m[5]], "|")
+		}
+
+		if r.Unexpanded[varName] {
+			// leave the reference exactly as it is
+			continue
+		}
 
-		// get the value from r.VarOverrides followed by r.Vars
 		var varValue string
-		var ok bool
-		if varValue, ok = r.VarOverrides[varName]; !ok {
-			varValue, ok = r.Vars[varName]
+		if strings.HasPrefix(varName, "@") {
+			// dynamic vars are computed fresh every time and are never stored,
+			// so there is nothing to look up or expand further
+			var err error
+			varValue, err = resolveDynamicVar(varName)
+			if err != nil {
+				return "", err
+			}
+		} else {
+			// get the value from r.VarOverrides followed by r.Vars, then the
+			// environment if enabled
+			var ok bool
+			if varValue, ok = r.VarOverrides[varName]; !ok {
+				varValue, ok = r.Vars[varName]
+				if !ok && r.EnvFallback {
+					varValue, ok = os.LookupEnv(varName)
+				}
+				if !ok {
+					if len(refChain) > 0 {
+						return "", fmt.Errorf("variable %s not found (referenced by %s)", varName, refChain[len(refChain)-1])
+					}
+					return "", fmt.Errorf("variable %s not found", varName)
+				}
+			}
+
+			// expand any references within the value itself
+			for _, name := range refChain {
+				if name == varName {
+					cycle := append(append([]string{}, refChain...), varName)
+					return "", fmt.Errorf("variable reference cycle: %s", strings.Join(cycle, " -> "))
+				}
+			}
+			if len(refChain) >= MaxVarDepth {
+				return "", fmt.Errorf("variable %s: references nested more than %d deep", refChain[0], MaxVarDepth)
+			}
+			var err error
+			varValue, err = r.substitute(rx, varValue, append(refChain, varName))
+			if err != nil {
+				return "", err
+			}
+		}
+
+		// transforms are applied in the order they are given
+		for _, t := range transforms {
+			fn, ok := varTransforms[strings.ToLower(t)]
 			if !ok {
-				return "", fmt.Errorf("variable %s not found", varName)
+				return "", fmt.Errorf("variable %s: unknown transform %q", varName, t)
 			}
+			varValue = fn(varValue)
 		}
 
 		// add replaced value and any prior content to updated
-		updated.WriteString(s[:pair[0]])
+		updated.WriteString(s[lastSearchEnd:m[0]])
 		updated.WriteString(varValue)
 
-		lastSearchEnd = pair[1]
+		lastSearchEnd = m[1]
 	}
 
 	if len(s) > lastSearchEnd {
@@ -618,41 +1271,690 @@ func (r *RESTClient) Substitute(s string) (string, error) {
 	return updated.String(), nil
 }
 
-// State holds all information in a saved state file.
-type State struct {
-	Cookies []SetCookiesCall
-	Vars    map[string]string
+// varTransforms is the functions that can be applied to the value of a
+// variable by following its name with '|' and the name of the transform, such
+// as "${TOKEN|base64}".
+var varTransforms = map[string]func(string) string{
+	"base64":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"base64url": func(s string) string { return base64.URLEncoding.EncodeToString([]byte(s)) },
+	"urlencode": formEscape,
+	"upper":     strings.ToUpper,
+	"lower":     strings.ToLower,
+	"trim":      strings.TrimSpace,
 }
 
-func (r *RESTClient) WriteState(w io.Writer) error {
-	rzw, err := rezi.NewWriter(w, nil)
-	if err != nil {
-		return fmt.Errorf("create REZI writer: %w", err)
+// resolveDynamicVar computes the current value of the dynamic var with the
+// given name. The name includes the leading '@' and any arguments after a ':'.
+//
+// Supported dynamic vars are:
+//
+//   - @uuid - a random version 4 UUID.
+//   - @now - the current time in RFC 3339 format, in UTC.
+//   - @unix - the current time as seconds since the Unix epoch.
+//   - @randint:MIN,MAX - a random integer between MIN and MAX, inclusive.
+func resolveDynamicVar(name string) (string, error) {
+	fn, argStr, hasArgs := strings.Cut(name[1:], ":")
+	fn = strings.ToLower(fn)
+
+	noArgs := func() error {
+		if hasArgs {
+			return fmt.Errorf("dynamic var @%s does not take arguments", fn)
+		}
+		return nil
 	}
 
-	r.jar.evictOld()
-	state := State{
-		Cookies: r.jar.calls,
-		Vars:    r.Vars,
+	switch fn {
+	case "uuid":
+		if err := noArgs(); err != nil {
+			return "", err
+		}
+		return newUUID()
+	case "now":
+		if err := noArgs(); err != nil {
+			return "", err
+		}
+		return time.Now().UTC().Format(time.RFC3339), nil
+	case "unix":
+		if err := noArgs(); err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(time.Now().Unix(), 10), nil
+	case "randint":
+		minStr, maxStr, ok := strings.Cut(argStr, ",")
+		if !hasArgs || !ok {
+			return "", fmt.Errorf("dynamic var @randint requires arguments in form @randint:MIN,MAX")
+		}
+		lo, err := strconv.Atoi(strings.TrimSpace(minStr))
+		if err != nil {
+			return "", fmt.Errorf("dynamic var @randint: MIN is not a valid integer: %q", minStr)
+		}
+		hi, err := strconv.Atoi(strings.TrimSpace(maxStr))
+		if err != nil {
+			return "", fmt.Errorf("dynamic var @randint: MAX is not a valid integer: %q", maxStr)
+		}
+		if lo > hi {
+			return "", fmt.Errorf("dynamic var @randint: MIN %d is greater than MAX %d", lo, hi)
+		}
+		return strconv.Itoa(randIntBetween(lo, hi)), nil
+	default:
+		return "", fmt.Errorf("unknown dynamic var @%s", fn)
 	}
+}
 
-	if err := rzw.Enc(state); err != nil {
-		return fmt.Errorf("encode cookie jar: %w", err)
+// randIntBetween returns a random integer between lo and hi, inclusive. lo must
+// not be greater than hi. The width of the range is computed as a uint64 so
+// that any range that fits in an int can be used.
+func randIntBetween(lo, hi int) int {
+	width := uint64(hi) - uint64(lo)
+	if width < math.MaxInt64 {
+		return lo + int(rand.Int63n(int64(width)+1))
 	}
 
-	return rzw.Close()
+	// too wide for Int63n; draw until a value falls within the range, which
+	// happens at least half of the time
+	for {
+		if v := rand.Uint64(); v <= width {
+			return lo + int(v)
+		}
+	}
 }
 
-func (r *RESTClient) ReadState(rd io.Reader) error {
-	rzr, err := rezi.NewReader(rd, nil)
-	if err != nil {
-		return fmt.Errorf("create REZI reader: %w", err)
+// newUUID returns a new random (version 4) UUID in its canonical string form.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate UUID: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// HeaderMatch is the way that a HeaderAssertion compares the value of a header
+// to its expected value.
+type HeaderMatch int
+
+const (
+	// HeaderMatchExact requires the value to be exactly the expected value.
+	HeaderMatchExact HeaderMatch = iota
+
+	// HeaderMatchContains requires the value to contain the expected value.
+	HeaderMatchContains
+
+	// HeaderMatchRegex requires the value to match the expected value as a
+	// regular expression.
+	HeaderMatchRegex
+)
+
+func (hm HeaderMatch) String() string {
+	switch hm {
+	case HeaderMatchExact:
+		return "exact"
+	case HeaderMatchContains:
+		return "contains"
+	case HeaderMatchRegex:
+		return "regex"
+	default:
+		return fmt.Sprintf("HeaderMatch(%d)", int(hm))
+	}
+}
+
+// ParseHeaderMatch parses the name of a HeaderMatch as returned by its String
+// method. Case does not matter.
+func ParseHeaderMatch(s string) (HeaderMatch, error) {
+	switch strings.ToLower(s) {
+	case "exact":
+		return HeaderMatchExact, nil
+	case "contains":
+		return HeaderMatchContains, nil
+	case "regex":
+		return HeaderMatchRegex, nil
+	default:
+		return HeaderMatchExact, fmt.Errorf("not one of 'exact', 'contains', or 'regex': %q", s)
+	}
+}
+
+// HeaderAssertion is a check made against a header of a response. The key is
+// matched case-insensitively.
+type HeaderAssertion struct {
+	Key string
+
+	// Present is whether the assertion only checks that the header is
+	// present. If set, Value and Match are ignored.
+	Present bool
+
+	// Value is the expected value, compared as given by Match. If the header
+	// has more than one value, the assertion passes if any of them match.
+	Value string
+	Match HeaderMatch
+}
+
+// Check returns an error describing how h fails the assertion, or nil if it
+// passes.
+func (a HeaderAssertion) Check(h http.Header) error {
+	key := http.CanonicalHeaderKey(a.Key)
+	values := h.Values(key)
+
+	if a.Present {
+		if len(values) == 0 {
+			return fmt.Errorf("header %s: expected to be present, but it is not", key)
+		}
+		return nil
+	}
+
+	var matches func(string) bool
+	var expected string
+	switch a.Match {
+	case HeaderMatchContains:
+		matches = func(v string) bool { return strings.Contains(v, a.Value) }
+		expected = fmt.Sprintf("value containing %q", a.Value)
+	case HeaderMatchRegex:
+		rx, err := regexp.Compile(a.Value)
+		if err != nil {
+			return fmt.Errorf("header %s: invalid regex %q: %w", key, a.Value, err)
+		}
+		matches = rx.MatchString
+		expected = fmt.Sprintf("value matching %q", a.Value)
+	default:
+		matches = func(v string) bool { return v == a.Value }
+		expected = fmt.Sprintf("%q", a.Value)
+	}
+
+	if len(values) == 0 {
+		return fmt.Errorf("header %s: expected %s, but it is not present", key, expected)
+	}
+	for _, v := range values {
+		if matches(v) {
+			return nil
+		}
+	}
+
+	quoted := make([]string, len(values))
+	for i := range values {
+		quoted[i] = fmt.Sprintf("%q", values[i])
+	}
+	return fmt.Errorf("header %s: expected %s, got %s", key, expected, strings.Join(quoted, ", "))
+}
+
+// AssertOp is the comparison that a JSONAssertion makes between the value at
+// its path and its expected value.
+type AssertOp int
+
+const (
+	// AssertExists requires only that the path exists. The expected value is
+	// not used.
+	AssertExists AssertOp = iota
+
+	// AssertEqual requires the value to be equal to the expected value.
+	AssertEqual
+
+	// AssertNotEqual requires the value to not be equal to the expected value.
+	AssertNotEqual
+
+	// AssertLess requires the value to be less than the expected value.
+	AssertLess
+
+	// AssertLessEqual requires the value to be less than or equal to the
+	// expected value.
+	AssertLessEqual
+
+	// AssertGreater requires the value to be greater than the expected value.
+	AssertGreater
+
+	// AssertGreaterEqual requires the value to be greater than or equal to the
+	// expected value.
+	AssertGreaterEqual
+)
+
+func (op AssertOp) String() string {
+	switch op {
+	case AssertExists:
+		return "exists"
+	case AssertEqual:
+		return "=="
+	case AssertNotEqual:
+		return "!="
+	case AssertLess:
+		return "<"
+	case AssertLessEqual:
+		return "<="
+	case AssertGreater:
+		return ">"
+	case AssertGreaterEqual:
+		return ">="
+	default:
+		return fmt.Sprintf("AssertOp(%d)", int(op))
+	}
+}
+
+// assertOpsBySymbol is every AssertOp that is written as a symbol in an
+// assertion expression, keyed by that symbol.
+var assertOpsBySymbol = map[string]AssertOp{
+	"==": AssertEqual,
+	"!=": AssertNotEqual,
+	"<":  AssertLess,
+	"<=": AssertLessEqual,
+	">":  AssertGreater,
+	">=": AssertGreaterEqual,
+}
+
+// JSONAssertion is a check made against a value in a JSON response body. The
+// value is found by following Path from the root of the body, in the same way
+// as a capture, and compared to Value with Op.
+//
+// Equality compares JSON values exactly; a path that does not exist is treated
+// as null, so that '!= null' can be used to check that a value is present and
+// set. The ordering operators can only compare two numbers or two strings.
+type JSONAssertion struct {
+	Path  []TraversalStep
+	Op    AssertOp
+	Value interface{}
+}
+
+// ParseJSONAssertion parses an assertion expression of the form 'PATH OP VALUE'
+// or just 'PATH'. PATH is a JSON path in the same format as a capture spec, or
+// '.' for the entire body. OP is one of '==', '!=', '<', '<=', '>', or '>='.
+// VALUE is a JSON literal such as 5, "text", true, or null. If only PATH is
+// given, the assertion checks that it exists.
+func ParseJSONAssertion(s string) (JSONAssertion, error) {
+	pathStr, opStr, valueStr := splitAssertionExpr(s)
+
+	var a JSONAssertion
+	pathStr = strings.TrimSpace(pathStr)
+	if pathStr == "" {
+		return a, fmt.Errorf("missing path")
+	}
+	if pathStr != "." {
+		if !strings.HasPrefix(pathStr, ".") && !strings.HasPrefix(pathStr, "[") {
+			return a, fmt.Errorf("path %q must start with '.' or '['", pathStr)
+		}
+		if strings.HasPrefix(pathStr, "[") {
+			pathStr = "." + pathStr
+		}
+		scraper, err := parseSingleVarScraperSpec("", pathStr)
+		if err != nil {
+			return a, fmt.Errorf("path: %w", err)
+		}
+		a.Path = scraper.Steps
+	}
+
+	if opStr == "" {
+		a.Op = AssertExists
+		return a, nil
+	}
+
+	op, ok := assertOpsBySymbol[opStr]
+	if !ok {
+		return a, fmt.Errorf("unknown operator %q", opStr)
+	}
+	a.Op = op
+
+	valueStr = strings.TrimSpace(valueStr)
+	if valueStr == "" {
+		return a, fmt.Errorf("missing value after %s", opStr)
+	}
+	if err := json.Unmarshal([]byte(valueStr), &a.Value); err != nil {
+		return a, fmt.Errorf("value %s is not a JSON literal such as 5, \"text\", true, or null", valueStr)
+	}
+
+	if op != AssertEqual && op != AssertNotEqual {
+		switch a.Value.(type) {
+		case float64, string:
+		default:
+			return a, fmt.Errorf("%s can only compare numbers or strings", opStr)
+		}
+	}
+
+	return a, nil
+}
+
+// splitAssertionExpr splits s into the path, operator, and value of an
+// assertion expression. The operator is the first run of '=', '!', '<', and '>'
+// that is not inside of a quoted key or escaped. If there is none, s is
+// returned as the path.
+func splitAssertionExpr(s string) (path, op, value string) {
+	inQuote := false
+	sR := []rune(s)
+	for i := 0; i < len(sR); i++ {
+		ch := sR[i]
+
+		if ch == '\\' {
+			i++
+			continue
+		}
+		if ch == '"' {
+			inQuote = !inQuote
+			continue
+		}
+		if inQuote || !strings.ContainsRune("=!<>", ch) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(sR) && strings.ContainsRune("=!<>", sR[end]) {
+			end++
+		}
+		return string(sR[:i]), string(sR[i:end]), string(sR[end:])
+	}
+	return s, "", ""
+}
+
+// String returns the expression that a was parsed from, in canonical form.
+func (a JSONAssertion) String() string {
+	path := a.pathString()
+	if a.Op == AssertExists {
+		return path
+	}
+
+	value, _ := json.Marshal(a.Value)
+	return fmt.Sprintf("%s %s %s", path, a.Op, value)
+}
+
+// pathString returns the path of a as it is written in an expression.
+func (a JSONAssertion) pathString() string {
+	if len(a.Path) == 0 {
+		return "."
+	}
+	var sb strings.Builder
+	for _, step := range a.Path {
+		sb.WriteString(step.String())
+	}
+	return sb.String()
+}
+
+// Check returns an error describing how body fails the assertion, or nil if it
+// passes.
+func (a JSONAssertion) Check(body []byte) error {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Errorf("json %s: body is not valid JSON", a.pathString())
+	}
+
+	var traverseErr error
+	for idx, step := range a.Path {
+		data, traverseErr = step.Traverse(data)
+		if traverseErr != nil {
+			var seq strings.Builder
+			for _, oldStep := range a.Path[:idx+1] {
+				seq.WriteString(oldStep.String())
+			}
+			traverseErr = fmt.Errorf("at %s: %w", seq.String(), traverseErr)
+			data = nil
+			break
+		}
+	}
+
+	if a.Op == AssertExists {
+		if traverseErr != nil {
+			return fmt.Errorf("json %s: expected to exist, but it does not (%s)", a.pathString(), traverseErr)
+		}
+		return nil
+	}
+
+	got := "missing"
+	if traverseErr == nil {
+		gotBytes, _ := json.Marshal(data)
+		got = string(gotBytes)
+	}
+	expected, _ := json.Marshal(a.Value)
+	fail := fmt.Errorf("json %s: expected %s %s, got %s", a.pathString(), a.Op, expected, got)
+
+	switch a.Op {
+	case AssertEqual:
+		if !reflect.DeepEqual(data, a.Value) {
+			return fail
+		}
+	case AssertNotEqual:
+		if reflect.DeepEqual(data, a.Value) {
+			return fail
+		}
+	default:
+		cmp, ok := compareJSONOrdered(data, a.Value)
+		if !ok {
+			return fmt.Errorf("json %s: cannot compare %s to %s", a.pathString(), got, expected)
+		}
+		var pass bool
+		switch a.Op {
+		case AssertLess:
+			pass = cmp < 0
+		case AssertLessEqual:
+			pass = cmp <= 0
+		case AssertGreater:
+			pass = cmp > 0
+		case AssertGreaterEqual:
+			pass = cmp >= 0
+		}
+		if !pass {
+			return fail
+		}
+	}
+
+	return nil
+}
+
+// compareJSONOrdered compares two JSON values that are both numbers or both
+// strings, returning -1, 0, or 1 as x is less than, equal to, or greater than
+// y. If they cannot be compared, ok is false.
+func compareJSONOrdered(x, y interface{}) (cmp int, ok bool) {
+	switch xv := x.(type) {
+	case float64:
+		yv, isNum := y.(float64)
+		if !isNum {
+			return 0, false
+		}
+		if xv < yv {
+			return -1, true
+		} else if xv > yv {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		yv, isStr := y.(string)
+		if !isStr {
+			return 0, false
+		}
+		return strings.Compare(xv, yv), true
+	default:
+		return 0, false
+	}
+}
+
+// FormContentType is the content type of a body made of URL-encoded form
+// fields.
+const FormContentType = "application/x-www-form-urlencoded"
+
+// FormField is a single field of a URL-encoded form body. If Name is empty,
+// only the Value is included in the body.
+type FormField struct {
+	Name  string
+	Value string
+}
+
+// Encode returns the field in the form it appears in a URL-encoded body. Only
+// the value is encoded; the name is used exactly as it is, same as curl does.
+func (f FormField) Encode() string {
+	if f.Name == "" {
+		return formEscape(f.Value)
+	}
+	return f.Name + "=" + formEscape(f.Value)
+}
+
+// EncodeForm substitutes variables in each of the given fields and then
+// URL-encodes them, joining them with '&'.
+func (r *RESTClient) EncodeForm(fields []FormField) (string, error) {
+	encoded := make([]string, len(fields))
+	for i, f := range fields {
+		name, err := r.substituteUnlessVerbatim(f.Name)
+		if err != nil {
+			return "", fmt.Errorf("field #%d: substitute vars in name: %w", i+1, err)
+		}
+		value, err := r.substituteUnlessVerbatim(f.Value)
+		if err != nil {
+			return "", fmt.Errorf("field #%d: substitute vars in value: %w", i+1, err)
+		}
+		encoded[i] = FormField{Name: name, Value: value}.Encode()
+	}
+
+	return strings.Join(encoded, "&"), nil
+}
+
+// joinFormBody joins encoded form data to the end of body with '&'. A new
+// slice is always returned.
+func joinFormBody(body []byte, encoded string) []byte {
+	joined := make([]byte, 0, len(body)+1+len(encoded))
+	joined = append(joined, body...)
+	if len(body) > 0 {
+		joined = append(joined, '&')
+	}
+	return append(joined, encoded...)
+}
+
+// JSONContentType is the content type of a JSON body.
+const JSONContentType = "application/json"
+
+// JSONField is a single field that is set in a JSON object body. Unless Raw is
+// set, the type of Value is inferred: "true" and "false" become booleans,
+// "null" becomes null, anything that is a valid JSON number becomes a number,
+// and everything else is a string. If Raw is set, Value must be a JSON value,
+// which is used as-is.
+type JSONField struct {
+	Key   string
+	Value string
+	Raw   bool
+}
+
+// value returns the JSON value of f.
+func (f JSONField) value() (interface{}, error) {
+	if f.Raw {
+		if !json.Valid([]byte(f.Value)) {
+			return nil, fmt.Errorf("%q is not valid JSON", f.Value)
+		}
+		return json.RawMessage(f.Value), nil
+	}
+
+	switch f.Value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	// a quoted number would also unmarshal to a json.Number, so only bare
+	// numbers are checked
+	var num json.Number
+	isBare := f.Value != "" && f.Value == strings.TrimSpace(f.Value) && f.Value[0] != '"'
+	if isBare && json.Unmarshal([]byte(f.Value), &num) == nil {
+		return num, nil
+	}
+	return f.Value, nil
+}
+
+// MergeJSONFields substitutes variables in body and in the key and value of
+// each of the given fields, and then sets each field in body, which must be a
+// JSON object or empty. Fields are set in order, so a later field with the same
+// key as an earlier one replaces it. The merged object is returned with its
+// keys sorted.
+func (r *RESTClient) MergeJSONFields(body []byte, fields []JSONField) ([]byte, error) {
+	obj := map[string]interface{}{}
+
+	bodyStr, err := r.substituteUnlessVerbatim(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("substitute vars in body: %w", err)
+	}
+	if strings.TrimSpace(bodyStr) != "" {
+		dec := json.NewDecoder(strings.NewReader(bodyStr))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil || obj == nil {
+			return nil, fmt.Errorf("body is not a JSON object")
+		}
+	}
+
+	for i, f := range fields {
+		key, err := r.substituteUnlessVerbatim(f.Key)
+		if err != nil {
+			return nil, fmt.Errorf("field #%d: substitute vars in key: %w", i+1, err)
+		}
+		f.Value, err = r.substituteUnlessVerbatim(f.Value)
+		if err != nil {
+			return nil, fmt.Errorf("field #%d: substitute vars in value: %w", i+1, err)
+		}
+		obj[key], err = f.value()
+		if err != nil {
+			return nil, fmt.Errorf("field #%d: %w", i+1, err)
+		}
+	}
+
+	return json.Marshal(obj)
+}
+
+// formEscape percent-encodes every byte of s that is not an unreserved
+// character as defined by RFC 3986. Unlike url.QueryEscape, spaces are encoded
+// as "%20", which matches the behavior of curl.
+func formEscape(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c == '-' || c == '.' || c == '_' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(hex[c>>4])
+			sb.WriteByte(hex[c&0x0f])
+		}
+	}
+	return sb.String()
+}
+
+// decodeState reads a State written by WriteState from rd.
+func decodeState(rd io.Reader) (State, error) {
+	rzr, err := rezi.NewReader(rd, nil)
+	if err != nil {
+		return State{}, fmt.Errorf("create REZI reader: %w", err)
 	}
 
-	// first, get state object
 	var state State
 	if err := rzr.Dec(&state); err != nil {
-		return fmt.Errorf("decode state: %w", err)
+		return State{}, fmt.Errorf("decode state: %w", err)
+	}
+
+	return state, rzr.Close()
+}
+
+// State holds all information in a saved state file.
+type State struct {
+	Cookies []SetCookiesCall
+	Vars    map[string]string
+}
+
+func (r *RESTClient) WriteState(w io.Writer) error {
+	rzw, err := rezi.NewWriter(w, nil)
+	if err != nil {
+		return fmt.Errorf("create REZI writer: %w", err)
+	}
+
+	r.jar.evictOld()
+	state := State{
+		Cookies: r.jar.calls,
+		Vars:    r.Vars,
+	}
+
+	if err := rzw.Enc(state); err != nil {
+		return fmt.Errorf("encode cookie jar: %w", err)
+	}
+
+	return rzw.Close()
+}
+
+func (r *RESTClient) ReadState(rd io.Reader) error {
+	// first, get state object
+	state, err := decodeState(rd)
+	if err != nil {
+		return err
 	}
 
 	// create the cookie jar
@@ -672,7 +1974,7 @@ func (r *RESTClient) ReadState(rd io.Reader) error {
 	r.http.Jar = jar
 	r.Vars = state.Vars
 
-	return rzr.Close()
+	return nil
 }
 
 type SetCookiesCall struct {
@@ -774,7 +2076,7 @@ type TimedCookieJar struct {
 // wrapped.
 func NewTimedCookieJar(wrapped http.CookieJar, lifetime time.Duration) *TimedCookieJar {
 	if lifetime <= 0 {
-		lifetime = 24 * time.Hour
+		lifetime = DefaultCookieLifetime
 	}
 	if wrapped == nil {
 		var err error
@@ -816,19 +2118,83 @@ func (j *TimedCookieJar) Cookies(u *url.URL) []*http.Cookie {
 	return j.wrapped.Cookies(u)
 }
 
+// evictOld removes all calls that are older than the lifetime of the jar, as
+// well as individual cookies that have expired according to their own Expires
+// or Max-Age. Calls that have no cookies left after this are removed entirely.
+//
+// A cookie with a negative Max-Age or an Expires in the past is an instruction
+// to delete any existing cookie with the same name, so in addition to being
+// removed itself, it also removes any matching cookie recorded in an earlier
+// call; otherwise, replaying the remaining calls would bring back the cookie
+// that it deleted.
 func (j *TimedCookieJar) evictOld() {
-	// remove any calls that are older than Lifetime
-	oldestTime := time.Now().Add(-j.lifetime)
-	startIdx := -1
-	for idx, call := range j.calls {
-		if !call.Time.Before(oldestTime) {
-			startIdx = idx
+	now := time.Now()
+	oldestTime := now.Add(-j.lifetime)
+
+	// go from newest to oldest so deletions are known about before the
+	// cookies they delete are encountered.
+	deleted := map[string]bool{}
+	kept := make([]SetCookiesCall, 0, len(j.calls))
+	for i := len(j.calls) - 1; i >= 0; i-- {
+		call := j.calls[i]
+		if call.Time.Before(oldestTime) {
+			// all calls prior to this one are older and will also be evicted
 			break
 		}
+
+		liveCookies := make([]*http.Cookie, 0, len(call.Cookies))
+		for _, c := range call.Cookies {
+			key := cookieRecordKey(call.URL, c)
+
+			if cookieExpired(c, call.Time, now) {
+				deleted[key] = true
+				continue
+			}
+			if deleted[key] {
+				continue
+			}
+			liveCookies = append(liveCookies, c)
+		}
+
+		if len(liveCookies) == 0 && len(call.Cookies) > 0 {
+			continue
+		}
+
+		call.Cookies = liveCookies
+		kept = append(kept, call)
+	}
+
+	// put them back in oldest-to-newest order
+	for i, k := 0, len(kept)-1; i < k; i, k = i+1, k-1 {
+		kept[i], kept[k] = kept[k], kept[i]
+	}
+	j.calls = kept
+}
+
+// cookieExpired returns whether cookie c, set at time setTime, has expired as
+// of now according to its Max-Age and Expires attributes. Max-Age takes
+// precedence over Expires if both are present.
+func cookieExpired(c *http.Cookie, setTime, now time.Time) bool {
+	if c.MaxAge < 0 {
+		return true
+	}
+	if c.MaxAge > 0 {
+		return !now.Before(setTime.Add(time.Duration(c.MaxAge) * time.Second))
 	}
-	if startIdx >= 0 {
-		j.calls = j.calls[startIdx:]
+	if !c.Expires.IsZero() {
+		return !now.Before(c.Expires)
+	}
+	return false
+}
+
+// cookieRecordKey returns a key that identifies the cookie that c would set
+// when received from u, for finding cookies replaced by later ones.
+func cookieRecordKey(u *url.URL, c *http.Cookie) string {
+	host := ""
+	if u != nil {
+		host = u.Hostname()
 	}
+	return strings.ToLower(host) + "\x00" + strings.ToLower(c.Domain) + "\x00" + c.Path + "\x00" + c.Name
 }
 
 func (j *TimedCookieJar) checkEviction() {
@@ -873,6 +2239,12 @@ type Format int
 const (
 	FormatPretty Format = iota
 	FormatLine
+
+	// FormatJSON outputs the request and response together as a single JSON
+	// object once the response is received. The other fields of
+	// OutputControl that select parts of the output are ignored, except for
+	// SuppressResponseBody.
+	FormatJSON
 )
 
 type OutputControl struct {
@@ -895,38 +2267,148 @@ type OutputControl struct {
 	// to stdout after the response is received.
 	SuppressResponseBody bool
 
+	// BodyOnly controls whether the response body should be the only thing
+	// output. If set, the body is output exactly as it was received, without
+	// the status line, section banners, or a trailing newline, and all other
+	// output is suppressed regardless of the other fields. It has no effect in
+	// FormatJSON.
+	BodyOnly bool
+
+	// Timings controls whether the timings of each phase of the request should
+	// be output to stdout after the response is received.
+	Timings bool
+
+	// Redirects controls whether the chain of redirects that was followed to
+	// get the response should be output to stdout before the response.
+	Redirects bool
+
+	// Stats controls whether a one-line summary of the response status, the
+	// size of its body, and the time taken to receive it should be output to
+	// stdout after the response is received.
+	Stats bool
+
 	// Format sets the format of the output. The default is "pretty", which is
 	// human-readable. "line" is a more compact format that is slightly more
 	// machine-readable. "sr" is a format that is shorthand for "line" but
-	// including only the status and response payload.
+	// including only the status and response payload. "json" outputs
+	// everything as a single JSON object.
 	Format Format
 
 	// Writer is the writer to which output should be written. If not set,
 	// output will be written to os.Stdout.
 	Writer io.Writer
+
+	// BodyWriter is the writer to which the response body is written in place
+	// of Writer. If set, Send streams the body to it as it is received instead
+	// of holding it in memory, unless the body is needed for captures or
+	// retries, in which case it is written once it has been read in full.
+	BodyWriter io.Writer
+
+	// Mask is values that are replaced with SecretMask wherever they appear in
+	// the output of the request, such as the values of secret variables.
+	Mask []string
 }
 
-// SendOptions is used to encapsulate non-critical options for sending a request
-// via the Send function.
-type SendOptions struct {
-	// Vars are request variables and their values that are set only for this
-	// request. A variable in Vars with the same name as one that is in any
-	// loaded state (should that be requested) will override the loaded state
-	// value, but will not be saved in resulting state.
-	Vars map[string]string
+// SecretMask is output in place of the value of a secret variable.
+const SecretMask = "***"
 
-	// Captures is a list of variable scrapers that will be used to extract
-	// values from the response body. The captured values *will* be kept in any
-	// saved state (should state save be requested).
-	Captures []VarScraper
+// MaskValues returns s with every occurrence of each of values replaced with
+// SecretMask. Longer values are replaced first so that a value that contains
+// another is masked in full. Empty values are ignored.
+func MaskValues(s string, values []string) string {
+	if len(values) == 0 {
+		return s
+	}
 
-	// LoadStateFile is the path to a state file that should be loaded before
-	// sending the request. If this is set, the state file will be loaded and
-	// used to populate the initial RESTClient state before applying any further
-	// options given in this struct.
-	LoadStateFile string
+	sorted := make([]string, len(values))
+	copy(sorted, values)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
 
-	// SaveStateFile is the path to a state file that should be saved after
+	for _, v := range sorted {
+		if v != "" {
+			s = strings.ReplaceAll(s, v, SecretMask)
+		}
+	}
+	return s
+}
+
+// SecretForms returns each of values along with every form that it takes when
+// one or two var transforms are applied to it, such as when a secret var is
+// referenced as "${PASS|base64}" or "${PASS|trim|urlencode}", so that the
+// value is masked by MaskValues however it is used. Empty values are ignored
+// and no form is returned more than once.
+func SecretForms(values []string) []string {
+	names := make([]string, 0, len(varTransforms))
+	for name := range varTransforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var forms []string
+	seen := map[string]bool{}
+	add := func(v string) {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			forms = append(forms, v)
+		}
+	}
+
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		add(v)
+		for _, first := range names {
+			once := varTransforms[first](v)
+			add(once)
+			for _, second := range names {
+				add(varTransforms[second](once))
+			}
+		}
+	}
+	return forms
+}
+
+// maskHeaders returns a copy of h with values masked by MaskValues. It is
+// never nil, so that it is output as an empty JSON object rather than null.
+func maskHeaders(h http.Header, values []string) map[string][]string {
+	m := jsonHeaders(h)
+	if len(values) == 0 {
+		return m
+	}
+	for k, vals := range m {
+		masked := make([]string, len(vals))
+		for i := range vals {
+			masked[i] = MaskValues(vals[i], values)
+		}
+		m[k] = masked
+	}
+	return m
+}
+
+// SendOptions is used to encapsulate non-critical options for sending a request
+// via the Send function.
+type SendOptions struct {
+	// Vars are request variables and their values that are set only for this
+	// request. A variable in Vars with the same name as one that is in any
+	// loaded state (should that be requested) will override the loaded state
+	// value, but will not be saved in resulting state.
+	Vars map[string]string
+
+	// Captures is a list of variable scrapers that will be used to extract
+	// values from the response body. The captured values *will* be kept in any
+	// saved state (should state save be requested).
+	Captures []VarScraper
+
+	// LoadStateFile is the path to a state file that should be loaded before
+	// sending the request. If this is set, the state file will be loaded and
+	// used to populate the initial RESTClient state before applying any further
+	// options given in this struct.
+	LoadStateFile string
+
+	// SaveStateFile is the path to a state file that should be saved after
 	// sending the request, for non-project based state saving. If set, state
 	// will be saved in this file immediately after the response is received.
 	SaveStateFile string
@@ -936,15 +2418,40 @@ type SendOptions struct {
 	// will be performed on the data prior to sending.
 	Body []byte
 
+	// BodyFile is the path to a file whose contents are used as the body of
+	// the request. It is read when the request is sent and takes precedence
+	// over Body. Variable substitution is performed on the contents the same
+	// as for Body.
+	BodyFile string
+
 	// Headers is a map of headers to be sent with the request. If not set, the
 	// request will be sent with default headers only. Variable substitution
 	// will be performed on the header names and values prior to sending.
 	Headers http.Header
 
+	// DefaultHeaders is headers that are sent with the request for every key
+	// that is not already in Headers. Variable substitution is performed on
+	// them the same as for Headers.
+	DefaultHeaders http.Header
+
 	// Cookies loads the given cookies from a set of SetCookiesCalls into
 	// the client before sending the request.
 	Cookies []SetCookiesCall
 
+	// ExtraCookies is cookies to add to the client as though they had been set
+	// by a prior Set-Cookie header. Cookies without a Domain are recorded as
+	// set by the URL of the request; cookies with one are recorded as set by
+	// that domain. They are added after any in Cookies and any loaded from
+	// state, and will be included in SendResult.Cookies.
+	ExtraCookies []*http.Cookie
+
+	// Timeout is the longest that the request may take, including reading the
+	// response body. If the body is streamed to Output.BodyWriter, it only
+	// bounds the wait for the response headers. If not set to a positive
+	// duration, the timeout of Client is used, or DefaultRequestTimeout if
+	// Client is not set.
+	Timeout time.Duration
+
 	// CookieLifetime is the lifetime of cookie records in the client. It is
 	// used to evict old cookie records regardless of actual lifetime in the
 	// Set-Cookie header that originally caused it to be set. If not set, it
@@ -972,6 +2479,142 @@ type SendOptions struct {
 	// should generally NOT be used in production code. THIS IS INSECURE AND
 	// SHOULD BE USED WITH CAUTION.
 	InsecureSkipVerify bool
+
+	// ClientCertFile is the path to a PEM-encoded client certificate to
+	// present to servers that request one, for mutual TLS. If ClientKeyFile is
+	// not set, the private key is read from this file as well.
+	ClientCertFile string
+
+	// ClientKeyFile is the path to the PEM-encoded private key of the
+	// certificate in ClientCertFile. It cannot be set without ClientCertFile.
+	ClientKeyFile string
+
+	// CACertFile is the path to a PEM bundle of CA certificates to verify
+	// servers with. If set, only these CAs are trusted, not those of the
+	// system.
+	CACertFile string
+
+	// IPVersion restricts connections to the remote host to a single version
+	// of IP. It must be 0, 4, or 6. If 4, only IPv4 addresses are used; if 6,
+	// only IPv6 addresses are used. If 0, either may be used.
+	IPVersion int
+
+	// HTTPVersion restricts the version of HTTP that is used to send the
+	// request. It must be 0, 1, or 2. If 1, HTTP/1.1 is always used, even if
+	// the remote host supports HTTP/2. If 2, HTTP/2 is required; the URL must
+	// be https, and if the remote host does not respond with HTTP/2, Send
+	// returns an error. If 0, HTTP/2 is used if the remote host supports it.
+	HTTPVersion int
+
+	// LocalAddr is the local source address that connections to the remote
+	// host are made from. It may be either an IP address or the name of a
+	// network interface, in which case the first address on the interface is
+	// used. If IPVersion is also set, the local address must be of that
+	// version, and an interface will have its first address of that version
+	// used; if IPVersion is not set, it is taken to be the version of the
+	// local address. If a proxy is in use (such as one from the HTTP_PROXY
+	// environment variable), these options apply to the connection to the
+	// proxy. If not set, the system selects the source address.
+	LocalAddr string
+
+	// Resolve gives addresses to connect to for particular hosts and ports in
+	// place of the ones they would be resolved to. The Host header and the
+	// server name used for TLS are still taken from the URL. If a proxy is in
+	// use, these apply to the connection to the proxy.
+	Resolve []ResolveOverride
+
+	// Pool contains options for tuning the pool of connections to remote
+	// hosts. Transports are shared between calls to Send that are given the
+	// same transport options, so these only matter when many requests are
+	// sent in the same process, such as when executing a flow.
+	Pool PoolOptions
+
+	// Retry contains options for sending the request again when the response
+	// indicates that the result is not yet ready. By default, requests are
+	// never retried.
+	Retry RetryOptions
+
+	// DumpState is a flag that, if set, will cause the state of the client
+	// after the request is sent to be included in the returned SendResult. It
+	// is exactly the data that would be saved to SaveStateFile.
+	DumpState bool
+
+	// DryRun is a flag that, if set, will cause the request to be built with
+	// all variables substituted and output as though Output.Request were set,
+	// but not actually sent. No state file will be saved, and the returned
+	// SendResult will have only its Request field set.
+	DryRun bool
+
+	// Unexpanded is a list of variables whose references are left as-is in the
+	// request instead of being substituted, such as those that would have been
+	// captured by a prior request in a dry run.
+	Unexpanded []string
+
+	// AssertHeaders is checks that are made against the headers of the
+	// response once it has been output. If any of them fail, Send returns an
+	// *AssertionError along with the otherwise complete SendResult.
+	AssertHeaders []HeaderAssertion
+
+	// AssertStatus is the status code that the response must have. It is
+	// checked along with AssertHeaders, and if it fails, Send returns an
+	// *AssertionError in the same way. If 0, the status is not checked.
+	AssertStatus int
+
+	// AssertBodyContains is text that the body of the response must contain.
+	// Each is checked along with AssertHeaders, and if any fail, Send returns
+	// an *AssertionError in the same way. If any are given, the body is never
+	// streamed to Output.BodyWriter, as it must be read in full to be checked.
+	AssertBodyContains []string
+
+	// AssertJSON is checks that are made against values in the JSON body of the
+	// response. Each is checked along with AssertHeaders, and if any fail, Send
+	// returns an *AssertionError in the same way. If any are given, the body is
+	// never streamed to Output.BodyWriter.
+	AssertJSON []JSONAssertion
+
+	// DeferCaptureErrors is a flag that, if set, will cause captures that fail
+	// to not stop the response from being handled. The response is output and
+	// the state file saved as normal, and Send returns a *CaptureError along
+	// with the SendResult, which has only the captures that succeeded. If any
+	// header assertions also fail, both errors are returned joined together.
+	DeferCaptureErrors bool
+
+	// NoSubstituteHeaders is a flag that, if set, will cause header keys and
+	// values to be sent exactly as they are given, without any variables in
+	// them being substituted. Variables in the URL and body are still
+	// substituted.
+	NoSubstituteHeaders bool
+
+	// NoSubstitute is a flag that, if set, will cause the URL, headers, body,
+	// and form fields to be sent exactly as they are given, without any
+	// variables in them being substituted. It is for sending requests whose
+	// variables have already been filled in, such as ones from history.
+	NoSubstitute bool
+
+	// EnvFallback is a flag that, if set, will cause any variable that is not
+	// in Vars or the project to be taken from the OS environment variable of
+	// the same name, if there is one.
+	EnvFallback bool
+
+	// BaseURL is put in front of the URL of the request if it is only a path
+	// starting with "/", such as the base URL of the current environment of a
+	// project.
+	BaseURL string
+
+	// Form is fields that are URL-encoded and added to the body, in the same
+	// way as curl's --data-urlencode. Variables are substituted in each field
+	// before it is encoded. The encoded fields are joined with '&' and then
+	// joined to the end of Body, if it is set, with another '&'. If any fields
+	// are given and Headers does not include a Content-Type, it is set to
+	// FormContentType.
+	Form []FormField
+
+	// JSONFields is fields that are set in the body, which must be a JSON
+	// object or empty, as by RESTClient.MergeJSONFields. They are merged after
+	// any BodyFile is read. Variables are substituted in Body and each field
+	// before they are merged. If any fields are given and Headers does not
+	// include a Content-Type, it is set to JSONContentType.
+	JSONFields []JSONField
 }
 
 type SendResult struct {
@@ -995,6 +2638,97 @@ type SendResult struct {
 	// Cookies is all cookies available in the client after the request was
 	// sent.
 	Cookies []SetCookiesCall
+
+	// State is the state of the client after the request was sent. It is only
+	// set if DumpState was set in the SendOptions.
+	State *State
+
+	// Timings is how long each phase of sending the request took.
+	Timings Timings
+
+	// Redirects is every redirect response that was followed to get Response,
+	// in the order they were received. Their bodies have already been closed.
+	Redirects []*http.Response
+}
+
+// Timings is the time taken by each phase of sending a request. A phase that
+// did not happen, such as the DNS lookup for a URL with an IP address or the
+// connect when an idle connection was reused, is zero.
+type Timings struct {
+	// DNS is the time taken to look up the address of the host.
+	DNS time.Duration
+
+	// Connect is the time taken to open a connection to the host.
+	Connect time.Duration
+
+	// TLSHandshake is the time taken by the TLS handshake.
+	TLSHandshake time.Duration
+
+	// TTFB is the time from starting to send the request until the first byte
+	// of the response was received.
+	TTFB time.Duration
+
+	// Total is the time from starting to send the request until the entire
+	// response was received.
+	Total time.Duration
+}
+
+// timingTrace records the times of the events of a request that are needed to
+// build its Timings.
+type timingTrace struct {
+	mtx sync.Mutex
+
+	start     time.Time
+	dnsStart  time.Time
+	dnsDone   time.Time
+	connStart time.Time
+	connDone  time.Time
+	tlsStart  time.Time
+	tlsDone   time.Time
+	firstByte time.Time
+}
+
+// clientTrace returns a ClientTrace that records event times in tt. If the
+// request is redirected, the times are those of the last request.
+func (tt *timingTrace) clientTrace() *httptrace.ClientTrace {
+	record := func(t *time.Time) {
+		tt.mtx.Lock()
+		defer tt.mtx.Unlock()
+		*t = time.Now()
+	}
+
+	return &httptrace.ClientTrace{
+		GetConn:              func(string) { record(&tt.start) },
+		DNSStart:             func(httptrace.DNSStartInfo) { record(&tt.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { record(&tt.dnsDone) },
+		ConnectStart:         func(string, string) { record(&tt.connStart) },
+		ConnectDone:          func(string, string, error) { record(&tt.connDone) },
+		TLSHandshakeStart:    func() { record(&tt.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { record(&tt.tlsDone) },
+		GotFirstResponseByte: func() { record(&tt.firstByte) },
+	}
+}
+
+// timings gives the Timings of the traced request, which was fully received
+// at end.
+func (tt *timingTrace) timings(start, end time.Time) Timings {
+	tt.mtx.Lock()
+	defer tt.mtx.Unlock()
+
+	since := func(from, to time.Time) time.Duration {
+		if from.IsZero() || to.IsZero() {
+			return 0
+		}
+		return to.Sub(from)
+	}
+
+	return Timings{
+		DNS:          since(tt.dnsStart, tt.dnsDone),
+		Connect:      since(tt.connStart, tt.connDone),
+		TLSHandshake: since(tt.tlsStart, tt.tlsDone),
+		TTFB:         since(start, tt.firstByte),
+		Total:        since(start, end),
+	}
 }
 
 const (
@@ -1016,22 +2750,84 @@ func Send(method, URL, varSymbol string, opts SendOptions) (SendResult, error) {
 	client.VarOverrides = opts.Vars
 	client.VarPrefix = varSymbol
 	client.Scrapers = opts.Captures
+	client.DeferCaptureErrors = opts.DeferCaptureErrors
+	client.NoSubstituteHeaders = opts.NoSubstituteHeaders
+	client.NoSubstitute = opts.NoSubstitute
+	client.EnvFallback = opts.EnvFallback
+	client.BaseURL = opts.BaseURL
+	if opts.Timeout > 0 {
+		client.http.Timeout = opts.Timeout
+	}
+	for _, name := range opts.Unexpanded {
+		if client.Unexpanded == nil {
+			client.Unexpanded = make(map[string]bool)
+		}
+		client.Unexpanded[name] = true
+	}
 
-	if opts.InsecureSkipVerify {
-		// pick up the old client and assume it's a Transport (because if it's DefaultTransport, it will be)
-		transport, ok := client.http.Transport.(*http.Transport)
-		if !ok {
-			panic("client transport is not an http.Transport")
+	if opts.IPVersion != 0 && opts.IPVersion != 4 && opts.IPVersion != 6 {
+		return SendResult{}, fmt.Errorf("IP version must be 4 or 6, not %d", opts.IPVersion)
+	}
+
+	if opts.HTTPVersion != 0 && opts.HTTPVersion != 1 && opts.HTTPVersion != 2 {
+		return SendResult{}, fmt.Errorf("HTTP version must be 1 or 2, not %d", opts.HTTPVersion)
+	}
+
+	if opts.ClientKeyFile != "" && opts.ClientCertFile == "" {
+		return SendResult{}, fmt.Errorf("client key file cannot be set without a client certificate file")
+	}
+
+	var resolve []string
+	for _, r := range opts.Resolve {
+		if err := r.validate(); err != nil {
+			return SendResult{}, fmt.Errorf("resolve override %s: %w", r, err)
+		}
+		resolve = append(resolve, r.String())
+	}
+	sort.Strings(resolve)
+
+	var localIP string
+	if opts.LocalAddr != "" {
+		ip, err := resolveLocalAddr(opts.LocalAddr, opts.IPVersion)
+		if err != nil {
+			return SendResult{}, err
+		}
+		localIP = ip.String()
+	}
+
+	if opts.InsecureSkipVerify || opts.ClientCertFile != "" || opts.CACertFile != "" || opts.IPVersion != 0 || opts.HTTPVersion != 0 || localIP != "" || len(resolve) > 0 || opts.Pool != (PoolOptions{}) {
+		if opts.Pool.MaxIdleConns < 0 || opts.Pool.MaxConnsPerHost < 0 || opts.Pool.IdleConnTimeout < 0 {
+			return SendResult{}, fmt.Errorf("connection pool options cannot be negative")
 		}
 
-		oldTLS := transport.TLSClientConfig
+		// pick up the old client and assume it's a Transport (because if it's DefaultTransport, it will be)
+		var base *http.Transport
+		if client.http.Transport == nil {
+			base = http.DefaultTransport.(*http.Transport)
+		} else {
+			var ok bool
+			base, ok = client.http.Transport.(*http.Transport)
+			if !ok {
+				panic("client transport is not an http.Transport")
+			}
+		}
 
-		if oldTLS == nil {
-			oldTLS = &tls.Config{}
+		tr, err := sharedTransport(transportConfig{
+			base:        base,
+			insecure:    opts.InsecureSkipVerify,
+			certFile:    opts.ClientCertFile,
+			keyFile:     opts.ClientKeyFile,
+			caCertFile:  opts.CACertFile,
+			ipVersion:   opts.IPVersion,
+			httpVersion: opts.HTTPVersion,
+			localIP:     localIP,
+			resolve:     strings.Join(resolve, ","),
+			pool:        opts.Pool,
+		})
+		if err != nil {
+			return SendResult{}, err
 		}
-		oldTLS.InsecureSkipVerify = true
-		transport.TLSClientConfig = oldTLS
-		client.http.Transport = transport
+		client.http.Transport = tr
 	}
 
 	// if we have been asked to load state, do that now
@@ -1052,11 +2848,79 @@ func Send(method, URL, varSymbol string, opts SendOptions) (SendResult, error) {
 		client.jar.SetCookiesFromCalls(opts.Cookies)
 	}
 
-	req, err := client.CreateRequest(method, URL, opts.Body, opts.Headers)
+	body, headers := opts.Body, opts.Headers
+	if opts.BodyFile != "" {
+		fileBody, err := os.ReadFile(opts.BodyFile)
+		if err != nil {
+			return SendResult{}, fmt.Errorf("read body file: %w", err)
+		}
+		body = fileBody
+	}
+	if len(opts.JSONFields) > 0 {
+		merged, err := client.MergeJSONFields(body, opts.JSONFields)
+		if err != nil {
+			return SendResult{}, fmt.Errorf("set JSON fields: %w", err)
+		}
+		body = merged
+
+		if headers.Get("Content-Type") == "" {
+			headers = headers.Clone()
+			if headers == nil {
+				headers = make(http.Header)
+			}
+			headers.Set("Content-Type", JSONContentType)
+		}
+	}
+	if len(opts.Form) > 0 {
+		encoded, err := client.EncodeForm(opts.Form)
+		if err != nil {
+			return SendResult{}, fmt.Errorf("encode form: %w", err)
+		}
+		body = joinFormBody(body, encoded)
+
+		if headers.Get("Content-Type") == "" {
+			headers = headers.Clone()
+			if headers == nil {
+				headers = make(http.Header)
+			}
+			headers.Set("Content-Type", FormContentType)
+		}
+	}
+	headers = withDefaultHeaders(headers, opts.DefaultHeaders)
+
+	req, err := client.CreateRequest(method, URL, body, headers)
 	if err != nil {
 		return SendResult{}, fmt.Errorf("create request: %w", err)
 	}
 
+	// HTTP/2 is only negotiated over TLS
+	if opts.HTTPVersion == 2 && req.URL.Scheme != "https" {
+		return SendResult{}, fmt.Errorf("HTTP/2 can only be used with https URLs")
+	}
+
+	// extra cookies can only be added once the final URL is known
+	if len(opts.ExtraCookies) > 0 {
+		calls := make([]SetCookiesCall, len(client.jar.calls))
+		copy(calls, client.jar.calls)
+		calls = append(calls, extraCookiesCalls(req.URL, opts.ExtraCookies, time.Now())...)
+		client.jar.SetCookiesFromCalls(calls)
+	}
+
+	if opts.DryRun {
+		if opts.Output.Format == FormatJSON {
+			if err := outputRequestJSON(opts.Output, req); err != nil {
+				return SendResult{}, err
+			}
+			return SendResult{Request: req}, nil
+		}
+
+		opts.Output.Request = true
+		if err := OutputRequest(req, opts.Output); err != nil {
+			return SendResult{}, err
+		}
+		return SendResult{Request: req}, nil
+	}
+
 	// copy request body bytes now because we are about to lose it once we send
 	// the request
 	var reqBodyBytes []byte
@@ -1068,9 +2932,33 @@ func Send(method, URL, varSymbol string, opts SendOptions) (SendResult, error) {
 		req.Body = io.NopCloser(bytes.NewBuffer(reqBodyBytes))
 	}
 
+	trace := &timingTrace{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace.clientTrace()))
+
+	// the body can only be streamed if nothing needs to look at it
+	streamBody := opts.Output.BodyWriter != nil && !opts.Output.SuppressResponseBody && len(opts.Captures) == 0 && len(opts.AssertBodyContains) == 0 && len(opts.AssertJSON) == 0 && !opts.Retry.enabled()
+
 	sendTime := time.Now()
-	resp, caps, err := client.SendRequest(req)
+	var resp *http.Response
+	var caps map[string]string
+	if streamBody {
+		resp, err = client.stream(req, opts.Output.BodyWriter)
+		caps = map[string]string{}
+
+		// the body is already written, so there is nothing more to output
+		opts.Output.BodyWriter = nil
+		opts.Output.SuppressResponseBody = true
+	} else {
+		resp, caps, err = sendWithRetries(client, req, reqBodyBytes, opts.Retry)
+	}
 	recvTime := time.Now() // finer grained time would need to come from client.SendRequest, this is fine for now
+	timings := trace.timings(sendTime, recvTime)
+
+	// deferred capture errors are returned only once everything else is done
+	var capErr *CaptureError
+	if errors.As(err, &capErr) {
+		err = nil
+	}
 
 	// if we had a body, put it back after request
 	if len(reqBodyBytes) > 0 {
@@ -1086,6 +2974,9 @@ func Send(method, URL, varSymbol string, opts SendOptions) (SendResult, error) {
 	if err != nil {
 		return SendResult{}, fmt.Errorf("send request: %w", err)
 	}
+	if opts.HTTPVersion == 2 && resp.ProtoMajor != 2 {
+		return SendResult{}, fmt.Errorf("send request: remote host responded with %s, not HTTP/2", resp.Proto)
+	}
 
 	// if we have been asked to save state, do that now
 	if opts.SaveStateFile != "" {
@@ -1101,23 +2992,554 @@ func Send(method, URL, varSymbol string, opts SendOptions) (SendResult, error) {
 		}
 	}
 
-	if err := OutputResponse(resp, caps, opts.Output); err != nil {
+	if err := OutputResponse(resp, caps, &timings, opts.Output); err != nil {
 		return SendResult{}, err
 	}
 
+	var dumped *State
+	if opts.DumpState {
+		// go through WriteState so that the dump is exactly what would be
+		// saved to a state file
+		var buf bytes.Buffer
+		if err := client.WriteState(&buf); err != nil {
+			return SendResult{}, fmt.Errorf("dump state: %w", err)
+		}
+		state, err := decodeState(&buf)
+		if err != nil {
+			return SendResult{}, fmt.Errorf("dump state: %w", err)
+		}
+		dumped = &state
+	}
+
 	client.jar.evictOld()
 
-	return SendResult{
+	result := SendResult{
 		SendTime: sendTime,
 		RecvTime: recvTime,
 		Request:  req,
 		Response: resp,
 		Captures: caps,
 		Cookies:  client.jar.calls,
-	}, nil
+		State:    dumped,
+		Timings:  timings,
+
+		Redirects: RedirectChain(resp),
+	}
+
+	var failures []string
+	if opts.AssertStatus != 0 && resp.StatusCode != opts.AssertStatus {
+		failures = append(failures, fmt.Sprintf("status: expected %d, got %s", opts.AssertStatus, resp.Status))
+	}
+	for _, a := range opts.AssertHeaders {
+		if err := a.Check(resp.Header); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(opts.AssertBodyContains) > 0 || len(opts.AssertJSON) > 0 {
+		body, err := peekResponseBody(resp)
+		if err != nil {
+			return SendResult{}, fmt.Errorf("check body: %w", err)
+		}
+		for _, text := range opts.AssertBodyContains {
+			if !bytes.Contains(body, []byte(text)) {
+				failures = append(failures, fmt.Sprintf("body: expected to contain %q, but it does not", text))
+			}
+		}
+		for _, a := range opts.AssertJSON {
+			if err := a.Check(body); err != nil {
+				failures = append(failures, err.Error())
+			}
+		}
+	}
+	if len(failures) > 0 {
+		assertErr := &AssertionError{Failures: failures}
+		if capErr != nil {
+			return result, errors.Join(capErr, assertErr)
+		}
+		return result, assertErr
+	}
+
+	if capErr != nil {
+		return result, capErr
+	}
+
+	return result, nil
+}
+
+// peekResponseBody returns the body of resp. The body is restored after it is
+// read so that it can still be read by the caller.
+func peekResponseBody(resp *http.Response) ([]byte, error) {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewBuffer(body))
+	return body, nil
+}
+
+// extraCookiesCalls creates the SetCookiesCalls that would have set the given
+// cookies if they were received in response to a request to reqURL. Cookies
+// with a Domain are treated as though set by the root of that domain.
+func extraCookiesCalls(reqURL *url.URL, cookies []*http.Cookie, t time.Time) []SetCookiesCall {
+	var calls []SetCookiesCall
+	callByURL := map[string]int{}
+
+	for _, c := range cookies {
+		u := reqURL
+		if c.Domain != "" {
+			u = &url.URL{Scheme: reqURL.Scheme, Host: strings.TrimPrefix(c.Domain, "."), Path: "/"}
+		}
+
+		idx, ok := callByURL[u.String()]
+		if !ok {
+			idx = len(calls)
+			callByURL[u.String()] = idx
+			calls = append(calls, SetCookiesCall{Time: t, URL: u})
+		}
+		calls[idx].Cookies = append(calls[idx].Cookies, c)
+	}
+
+	return calls
+}
+
+// RetryOptions controls when a request is sent again based on the response
+// received for it. The zero value never retries.
+type RetryOptions struct {
+	// BodyMatch is text that, if it is anywhere in the body of a response,
+	// causes the request to be sent again. If empty, responses are not retried
+	// based on their body. Retry conditions are OR'd together; a response that
+	// meets any of them is retried.
+	BodyMatch string
+
+	// MaxAttempts is the most times that the request will be sent, including
+	// the first time. If 0 or less, DefaultRetryMaxAttempts is used.
+	MaxAttempts int
+
+	// Backoff is how long to wait before sending the request the second time.
+	// Each later attempt waits twice as long as the one before it. If 0 or
+	// less, DefaultRetryBackoff is used.
+	Backoff time.Duration
+}
+
+const (
+	// DefaultRetryMaxAttempts is the number of times a request is sent at most
+	// when it is being retried and no maximum is given.
+	DefaultRetryMaxAttempts = 5
+
+	// DefaultRetryBackoff is the time waited before the first retry of a
+	// request when no backoff is given.
+	DefaultRetryBackoff = time.Second
+)
+
+// enabled returns whether ro has any condition that would cause a retry.
+func (ro RetryOptions) enabled() bool {
+	return ro.BodyMatch != ""
+}
+
+// shouldRetry returns whether a response with the given body meets any of the
+// retry conditions of ro.
+func (ro RetryOptions) shouldRetry(respBody []byte) bool {
+	if ro.BodyMatch != "" && bytes.Contains(respBody, []byte(ro.BodyMatch)) {
+		return true
+	}
+	return false
+}
+
+// sendWithRetries sends req with client, sending it again after a backoff for
+// as long as the response meets a retry condition in retry and the maximum
+// number of attempts has not been reached. Captures are only taken from the
+// last response, which is the one that is returned. reqBody is the body of req
+// and is restored before each retry.
+func sendWithRetries(client *RESTClient, req *http.Request, reqBody []byte, retry RetryOptions) (*http.Response, map[string]string, error) {
+	if !retry.enabled() {
+		return client.SendRequest(req)
+	}
+
+	maxAttempts := retry.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultRetryMaxAttempts
+	}
+	backoff := retry.Backoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, respBody, err := client.exchange(req)
+		if err != nil {
+			return resp, nil, err
+		}
+
+		if attempt >= maxAttempts || !retry.shouldRetry(respBody) {
+			return client.capture(resp, respBody)
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+
+		if len(reqBody) > 0 {
+			req.Body = io.NopCloser(bytes.NewBuffer(reqBody))
+		}
+	}
+}
+
+// ResolveOverride gives the address to connect to for a host and port in
+// place of the one that the host would be resolved to, like the --resolve
+// option of curl.
+type ResolveOverride struct {
+	Host string
+	Port string
+
+	// Addr is the IP address to connect to. It does not include a port; Port
+	// is used for the connection.
+	Addr string
+}
+
+// ParseResolveOverride parses a ResolveOverride from a string in
+// "HOST:PORT:ADDR" format, the same as curl's --resolve option. An IPv6 ADDR
+// may be enclosed in square brackets.
+func ParseResolveOverride(s string) (ResolveOverride, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 {
+		return ResolveOverride{}, fmt.Errorf("not in HOST:PORT:ADDR format")
+	}
+
+	addr := parts[2]
+	if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		addr = addr[1 : len(addr)-1]
+	}
+
+	r := ResolveOverride{Host: parts[0], Port: parts[1], Addr: addr}
+	if err := r.validate(); err != nil {
+		return ResolveOverride{}, err
+	}
+	return r, nil
+}
+
+// String returns r in the format parsed by ParseResolveOverride.
+func (r ResolveOverride) String() string {
+	addr := r.Addr
+	if strings.Contains(addr, ":") {
+		addr = "[" + addr + "]"
+	}
+	return r.Host + ":" + r.Port + ":" + addr
+}
+
+// hostPort returns the address that r applies to, in the "host:port" form
+// that a transport dials.
+func (r ResolveOverride) hostPort() string {
+	return net.JoinHostPort(strings.ToLower(r.Host), r.Port)
+}
+
+func (r ResolveOverride) validate() error {
+	if r.Host == "" {
+		return fmt.Errorf("HOST cannot be empty")
+	}
+	if strings.ContainsAny(r.Host, ":,") {
+		return fmt.Errorf("%q is not a valid host", r.Host)
+	}
+	port, err := strconv.Atoi(r.Port)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("%q is not a valid port", r.Port)
+	}
+	if net.ParseIP(r.Addr) == nil {
+		return fmt.Errorf("%q is not an IP address", r.Addr)
+	}
+	return nil
+}
+
+// PoolOptions tunes the connection pool of the transport used to send
+// requests. The zero value of each field selects the default for it.
+type PoolOptions struct {
+	// MaxIdleConns is the maximum number of idle connections kept open, both
+	// in total and to any single host. If 0, the defaults of 100 in total and
+	// 2 per host are used.
+	MaxIdleConns int
+
+	// MaxConnsPerHost is the maximum number of connections to a single host,
+	// including those in use. If 0, there is no limit.
+	MaxConnsPerHost int
+
+	// IdleConnTimeout is how long an idle connection is kept open before it
+	// is closed. If 0, the default of 90 seconds is used.
+	IdleConnTimeout time.Duration
+}
+
+// transportConfig is all options that a transport created by Send can be
+// customized with. It is comparable so that it can be used to look up a
+// previously-created transport.
+type transportConfig struct {
+	base        *http.Transport
+	insecure    bool
+	certFile    string
+	keyFile     string
+	caCertFile  string
+	ipVersion   int
+	httpVersion int
+	localIP     string
+	pool        PoolOptions
+
+	// resolve is every ResolveOverride in String form, sorted and joined with
+	// commas so that transportConfig stays comparable.
+	resolve string
+}
+
+// maxSharedTransports is the most transports that sharedTransport keeps at
+// once. It is more than any one send or flow needs, but keeps a long-running
+// process whose options keep changing from piling up transports and the idle
+// connections they hold.
+const maxSharedTransports = 8
+
+var (
+	transportsMtx sync.Mutex
+	transports    = map[transportConfig]*http.Transport{}
+
+	// transportsByUse holds the keys of transports from least to most
+	// recently used.
+	transportsByUse []transportConfig
+)
+
+// sharedTransport returns a transport configured with the given options. The
+// same transport is returned for the same options every time so that its
+// pool of connections is reused. cfg.base is never modified. Any certificate
+// files in cfg are only read the first time a transport is created for it.
+//
+// At most maxSharedTransports are kept; when a new one would go past that,
+// the least recently used one is dropped and its idle connections closed.
+// Requests already using a dropped transport are not affected.
+func sharedTransport(cfg transportConfig) (*http.Transport, error) {
+	transportsMtx.Lock()
+	defer transportsMtx.Unlock()
+
+	if t, ok := transports[cfg]; ok {
+		markTransportUsed(cfg)
+		return t, nil
+	}
+
+	// clone it so that the shared default transport is not modified
+	t := cfg.base.Clone()
+
+	if cfg.insecure || cfg.certFile != "" || cfg.caCertFile != "" {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+	}
+
+	if cfg.insecure {
+		t.TLSClientConfig.InsecureSkipVerify = true
+	}
+
+	if cfg.certFile != "" {
+		keyFile := cfg.keyFile
+		if keyFile == "" {
+			keyFile = cfg.certFile
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		t.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.caCertFile != "" {
+		caPEM, err := os.ReadFile(cfg.caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificates: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("read CA certificates: no certificates found in %s", cfg.caCertFile)
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+
+	switch cfg.httpVersion {
+	case 1:
+		// a non-nil but empty TLSNextProto keeps the transport from ever
+		// setting up HTTP/2
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	case 2:
+		// a custom dialer or TLS config disables HTTP/2 unless it is forced
+		t.ForceAttemptHTTP2 = true
+	}
+
+	if cfg.ipVersion != 0 || cfg.localIP != "" || cfg.resolve != "" {
+		resolve := map[string]string{}
+		if cfg.resolve != "" {
+			for _, r := range strings.Split(cfg.resolve, ",") {
+				// these were already checked by Send
+				ro, _ := ParseResolveOverride(r)
+				resolve[ro.hostPort()] = net.JoinHostPort(ro.Addr, ro.Port)
+			}
+		}
+		t.DialContext = restrictedDialContext(cfg.ipVersion, net.ParseIP(cfg.localIP), resolve)
+	}
+
+	if cfg.pool.MaxIdleConns != 0 {
+		t.MaxIdleConns = cfg.pool.MaxIdleConns
+		t.MaxIdleConnsPerHost = cfg.pool.MaxIdleConns
+	}
+	if cfg.pool.MaxConnsPerHost != 0 {
+		t.MaxConnsPerHost = cfg.pool.MaxConnsPerHost
+	}
+	if cfg.pool.IdleConnTimeout != 0 {
+		t.IdleConnTimeout = cfg.pool.IdleConnTimeout
+	}
+
+	if len(transportsByUse) >= maxSharedTransports {
+		oldest := transportsByUse[0]
+		transportsByUse = transportsByUse[1:]
+		transports[oldest].CloseIdleConnections()
+		delete(transports, oldest)
+	}
+
+	transports[cfg] = t
+	transportsByUse = append(transportsByUse, cfg)
+	return t, nil
+}
+
+// markTransportUsed moves cfg to the end of transportsByUse. transportsMtx
+// must be held by the caller.
+func markTransportUsed(cfg transportConfig) {
+	for i := range transportsByUse {
+		if transportsByUse[i] == cfg {
+			transportsByUse = append(transportsByUse[:i], transportsByUse[i+1:]...)
+			break
+		}
+	}
+	transportsByUse = append(transportsByUse, cfg)
+}
+
+// resolveLocalAddr gets the IP address that addr refers to for use as the
+// local source address of connections. addr may be an IP address or the name
+// of a network interface. If ipVersion is not 0, the returned IP will be of
+// that version or an error is returned. The address is checked to make sure
+// that it can actually be bound before it is returned.
+func resolveLocalAddr(addr string, ipVersion int) (net.IP, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		iface, err := net.InterfaceByName(addr)
+		if err != nil {
+			return nil, fmt.Errorf("local address %q is not an IP address or network interface", addr)
+		}
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("get addresses of interface %s: %w", addr, err)
+		}
+		for _, a := range ifaceAddrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if ipVersion == 0 || ipVersionOf(ipNet.IP) == ipVersion {
+				ip = ipNet.IP
+				break
+			}
+		}
+		if ip == nil {
+			if ipVersion != 0 {
+				return nil, fmt.Errorf("interface %s has no IPv%d address", addr, ipVersion)
+			}
+			return nil, fmt.Errorf("interface %s has no IP address", addr)
+		}
+	}
+
+	if ipVersion != 0 && ipVersionOf(ip) != ipVersion {
+		return nil, fmt.Errorf("local address %s is not an IPv%d address", ip, ipVersion)
+	}
+
+	// make shore we can actually bind to it before trying to send anyfin
+	ln, err := net.Listen("tcp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		return nil, fmt.Errorf("local address %s cannot be bound: %w", ip, err)
+	}
+	ln.Close()
+
+	return ip, nil
+}
+
+// ipVersionOf returns 4 if ip is an IPv4 address and 6 otherwise.
+func ipVersionOf(ip net.IP) int {
+	if ip.To4() != nil {
+		return 4
+	}
+	return 6
+}
+
+// withDefaultHeaders returns headers with every header in defaults added to it
+// whose key is not already present. headers itself is not modified.
+func withDefaultHeaders(headers, defaults http.Header) http.Header {
+	if len(defaults) == 0 {
+		return headers
+	}
+
+	merged := defaults.Clone()
+	for key, vals := range headers {
+		merged.Del(key)
+		merged[key] = vals
+	}
+	return merged
+}
+
+// restrictedDialContext returns a DialContext function for an http.Transport
+// that only connects using the given version of IP and from the given local
+// address. If version is 0, it is taken from localIP; if localIP is also nil,
+// either version may be used and the system selects the source address. Any
+// "host:port" address that is a key in resolve is connected to at the address
+// it maps to instead.
+func restrictedDialContext(version int, localIP net.IP, resolve map[string]string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	if localIP != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: localIP}
+		if version == 0 {
+			version = ipVersionOf(localIP)
+		}
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if resolved, ok := resolve[strings.ToLower(addr)]; ok {
+			addr = resolved
+		}
+
+		if version == 0 {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, fmt.Errorf("connect to %s: %w", addr, err)
+			}
+			return conn, nil
+		}
+
+		// replace the generic "tcp" with the one for our version
+		network = fmt.Sprintf("tcp%d", version)
+
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			if localIP != nil {
+				return nil, fmt.Errorf("connect to %s over IPv%d from %s: %w", addr, version, localIP, err)
+			}
+			return nil, fmt.Errorf("connect to %s over IPv%d: %w", addr, version, err)
+		}
+		return conn, nil
+	}
 }
 
-func OutputResponse(resp *http.Response, caps map[string]string, opts OutputControl) error {
+// OutputResponse writes resp to the writer in opts, along with the captures
+// caps and timings if opts requests them. timings may be nil if they are not
+// known, in which case they are not output even if requested.
+func OutputResponse(resp *http.Response, caps map[string]string, timings *Timings, opts OutputControl) error {
 	// TODO: error check Fprint output
 
 	// get our output writer, if specified, or default to stdout
@@ -1126,6 +3548,19 @@ func OutputResponse(resp *http.Response, caps map[string]string, opts OutputCont
 		w = opts.Writer
 	}
 
+	if opts.Format == FormatJSON {
+		return outputResultJSON(w, resp, caps, timings, opts)
+	}
+
+	if opts.BodyOnly {
+		return outputBodyOnly(w, resp, opts)
+	}
+
+	// output the redirects if requested
+	if opts.Redirects {
+		outputRedirects(w, RedirectChain(resp), opts.Format)
+	}
+
 	// output the captures if requested
 	if opts.Captures {
 		if opts.Format == FormatPretty {
@@ -1157,6 +3592,11 @@ func OutputResponse(resp *http.Response, caps map[string]string, opts OutputCont
 		}
 	}
 
+	// output the timings if requested
+	if opts.Timings && timings != nil {
+		outputTimings(w, *timings, opts.Format)
+	}
+
 	// output the status line
 	fmt.Fprintf(w, "%s %s\n", resp.Proto, resp.Status)
 
@@ -1183,6 +3623,13 @@ func OutputResponse(resp *http.Response, caps map[string]string, opts OutputCont
 			}
 		}
 
+		// the transport removes Content-Encoding and Content-Length when it
+		// transparently decompresses, so make shore the user knows why
+		// they're missing. Not in line format, where every line is a header.
+		if resp.Uncompressed && opts.Format == FormatPretty {
+			fmt.Fprintf(w, "(decompressed from %s)\n", decompressedEncoding(resp))
+		}
+
 		if opts.Format == FormatPretty {
 			fmt.Fprintln(w, "-----------------------------------------------")
 		} else if opts.Format == FormatLine {
@@ -1190,10 +3637,9 @@ func OutputResponse(resp *http.Response, caps map[string]string, opts OutputCont
 		}
 	}
 
-	// output the response body, if any
-	if !opts.SuppressResponseBody {
-		var entireBody string
-
+	// the body is needed both for output and for its size in the stats
+	var entireBody string
+	if !opts.SuppressResponseBody || opts.Stats {
 		if resp.Body != nil && resp.Body != http.NoBody {
 			entireBodyBytes, err := io.ReadAll(resp.Body)
 			if err != nil {
@@ -1205,10 +3651,23 @@ func OutputResponse(resp *http.Response, caps map[string]string, opts OutputCont
 
 			entireBody = string(entireBodyBytes)
 		}
+	}
 
+	// output the response body, if any
+	if !opts.SuppressResponseBody && opts.BodyWriter != nil {
+		if _, err := io.WriteString(opts.BodyWriter, entireBody); err != nil {
+			return fmt.Errorf("write response body: %w", err)
+		}
+	} else if !opts.SuppressResponseBody {
 		if len(entireBody) > 0 {
 			// works for both pretty and line formats
 			fmt.Fprintln(w, string(entireBody))
+		} else if isHeadResponse(resp) {
+			// a HEAD response never has a body, so report the length of the
+			// one that a GET would have gotten instead
+			if opts.Format == FormatPretty && resp.ContentLength >= 0 {
+				fmt.Fprintf(w, "(HEAD response; Content-Length: %d)\n", resp.ContentLength)
+			}
 		} else {
 			if opts.Format == FormatPretty {
 				fmt.Fprintln(w, "(no response body)")
@@ -1216,9 +3675,371 @@ func OutputResponse(resp *http.Response, caps map[string]string, opts OutputCont
 		}
 	}
 
+	// output the stats summary if requested
+	if opts.Stats {
+		// a response without a body, such as to a HEAD request or one that
+		// was streamed elsewhere, can still give its length
+		bodySize := len(entireBody)
+		if resp.Body == http.NoBody && resp.ContentLength > 0 {
+			bodySize = int(resp.ContentLength)
+		}
+		outputStats(w, resp, bodySize, timings, opts.Format)
+	}
+
 	return nil
 }
 
+// RedirectChain returns every redirect response that was followed by the
+// client to get resp, in the order they were received. It is empty if resp
+// was not the result of following any redirects.
+func RedirectChain(resp *http.Response) []*http.Response {
+	var chain []*http.Response
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]*http.Response{req.Response}, chain...)
+	}
+	return chain
+}
+
+// outputRedirects writes a section listing each redirect response in chain,
+// with the request that got it and the Location it redirected to.
+func outputRedirects(w io.Writer, chain []*http.Response, format Format) {
+	if format == FormatPretty {
+		fmt.Fprintln(w, "------------------ REDIRECTS ------------------")
+	} else if format == FormatLine {
+		fmt.Fprintln(w, lineDelimStart+" REDIRECTS")
+	}
+
+	for _, r := range chain {
+		method, reqURL := redirectRequestLine(r)
+		loc := r.Header.Get("Location")
+		if format == FormatPretty {
+			fmt.Fprintf(w, "%s %s -> %s %s\n", method, reqURL, r.Status, loc)
+		} else if format == FormatLine {
+			fmt.Fprintf(w, "%d %s %s %s\n", r.StatusCode, method, reqURL, loc)
+		}
+	}
+
+	if format == FormatPretty {
+		if len(chain) == 0 {
+			fmt.Fprintln(w, "(no redirects)")
+		}
+		fmt.Fprintln(w, "-----------------------------------------------")
+	} else if format == FormatLine {
+		fmt.Fprintln(w, lineDelimEnd)
+	}
+}
+
+// redirectRequestLine returns the method and URL of the request that got the
+// redirect response r, or empty strings if it is not known.
+func redirectRequestLine(r *http.Response) (string, string) {
+	if r.Request == nil {
+		return "", ""
+	}
+	return r.Request.Method, r.Request.URL.String()
+}
+
+// outputBodyOnly writes the body of resp to w, or to the BodyWriter of opts if
+// it is set, exactly as it was received. Nothing is written if opts suppresses
+// the body.
+func outputBodyOnly(w io.Writer, resp *http.Response, opts OutputControl) error {
+	if opts.SuppressResponseBody || resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response body: %w", err)
+	}
+
+	// put the body back into a reader
+	resp.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	if opts.BodyWriter != nil {
+		w = opts.BodyWriter
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("write response body: %w", err)
+	}
+	return nil
+}
+
+// jsonResult is the single JSON object output for a response in FormatJSON.
+type jsonResult struct {
+	Request      *jsonRequest        `json:"request,omitempty"`
+	Proto        string              `json:"proto"`
+	Status       string              `json:"status"`
+	StatusCode   int                 `json:"status_code"`
+	Headers      map[string][]string `json:"headers"`
+	Body         *string             `json:"body"`
+	BodyEncoding string              `json:"body_encoding,omitempty"`
+	Captures     map[string]string   `json:"captures"`
+	Timings      *jsonTimings        `json:"timings,omitempty"`
+	Redirects    []jsonRedirect      `json:"redirects,omitempty"`
+}
+
+// jsonRedirect is a redirect that was followed in FormatJSON output.
+type jsonRedirect struct {
+	Method     string `json:"method"`
+	URL        string `json:"url"`
+	Status     string `json:"status"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// jsonRequest is the summary of a request in FormatJSON output.
+type jsonRequest struct {
+	Method  string              `json:"method"`
+	URL     string              `json:"url"`
+	Headers map[string][]string `json:"headers"`
+
+	// Body is only set when the request is output on its own, such as for a
+	// dry run.
+	Body *string `json:"body,omitempty"`
+
+	// BodyEncoding is "base64" if Body is base64-encoded because it is not
+	// valid UTF-8, and empty otherwise.
+	BodyEncoding string `json:"body_encoding,omitempty"`
+}
+
+// jsonTimings is Timings in FormatJSON output, with every phase given in
+// milliseconds.
+type jsonTimings struct {
+	DNS          float64 `json:"dns_ms"`
+	Connect      float64 `json:"connect_ms"`
+	TLSHandshake float64 `json:"tls_ms"`
+	TTFB         float64 `json:"ttfb_ms"`
+	Total        float64 `json:"total_ms"`
+}
+
+// outputResultJSON writes resp, the request it was sent for, caps, and
+// timings as a single JSON object. The body of resp is omitted if opts
+// suppresses it or if it is written to the BodyWriter of opts instead.
+func outputResultJSON(w io.Writer, resp *http.Response, caps map[string]string, timings *Timings, opts OutputControl) error {
+	result := jsonResult{
+		Proto:      resp.Proto,
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Headers:    jsonHeaders(resp.Header),
+		Captures:   map[string]string{},
+	}
+
+	for k, v := range caps {
+		result.Captures[k] = v
+	}
+
+	for _, r := range RedirectChain(resp) {
+		method, reqURL := redirectRequestLine(r)
+		result.Redirects = append(result.Redirects, jsonRedirect{
+			Method:     method,
+			URL:        reqURL,
+			Status:     r.Status,
+			StatusCode: r.StatusCode,
+			Location:   r.Header.Get("Location"),
+		})
+	}
+
+	if resp.Request != nil {
+		result.Request = &jsonRequest{
+			Method:  resp.Request.Method,
+			URL:     MaskValues(resp.Request.URL.String(), opts.Mask),
+			Headers: maskHeaders(resp.Request.Header, opts.Mask),
+		}
+	}
+
+	if !opts.SuppressResponseBody {
+		var body []byte
+		if resp.Body != nil && resp.Body != http.NoBody {
+			var err error
+			body, err = io.ReadAll(resp.Body)
+			if err != nil {
+				return fmt.Errorf("read response body: %w", err)
+			}
+
+			// put the body back into a reader
+			resp.Body = io.NopCloser(bytes.NewBuffer(body))
+		}
+
+		if opts.BodyWriter != nil {
+			if _, err := opts.BodyWriter.Write(body); err != nil {
+				return fmt.Errorf("write response body: %w", err)
+			}
+		} else {
+			result.Body, result.BodyEncoding = jsonBody(body)
+		}
+	}
+
+	if timings != nil {
+		ms := func(d time.Duration) float64 {
+			return float64(d) / float64(time.Millisecond)
+		}
+		result.Timings = &jsonTimings{
+			DNS:          ms(timings.DNS),
+			Connect:      ms(timings.Connect),
+			TLSHandshake: ms(timings.TLSHandshake),
+			TTFB:         ms(timings.TTFB),
+			Total:        ms(timings.Total),
+		}
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal JSON: %w", err)
+	}
+	fmt.Fprintf(w, "%s\n", data)
+	return nil
+}
+
+// outputRequestJSON writes req, including its body, as a single JSON object to
+// the writer in opts. It is used to output a request that is not being sent.
+func outputRequestJSON(opts OutputControl, req *http.Request) error {
+	var w io.Writer = os.Stdout
+	if opts.Writer != nil {
+		w = opts.Writer
+	}
+
+	summary := jsonRequest{
+		Method:  req.Method,
+		URL:     MaskValues(req.URL.String(), opts.Mask),
+		Headers: maskHeaders(req.Header, opts.Mask),
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return fmt.Errorf("read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewBuffer(body))
+		summary.Body, summary.BodyEncoding = jsonBody(body)
+		if summary.Body != nil && summary.BodyEncoding == "" {
+			masked := MaskValues(*summary.Body, opts.Mask)
+			summary.Body = &masked
+		}
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal JSON: %w", err)
+	}
+	fmt.Fprintf(w, "%s\n", data)
+	return nil
+}
+
+// jsonHeaders returns h as a map that is never nil, so that it is output as
+// an empty object rather than null.
+func jsonHeaders(h http.Header) map[string][]string {
+	m := map[string][]string{}
+	for k, vals := range h {
+		m[k] = vals
+	}
+	return m
+}
+
+// jsonBody returns body as a string for JSON output along with the encoding it
+// is in. If body is valid UTF-8 it is given as-is with an empty encoding;
+// otherwise it is base64-encoded and the encoding is "base64".
+func jsonBody(body []byte) (*string, string) {
+	if utf8.Valid(body) {
+		s := string(body)
+		return &s, ""
+	}
+	s := base64.StdEncoding.EncodeToString(body)
+	return &s, "base64"
+}
+
+// isHeadResponse returns whether resp is the response to a HEAD request.
+func isHeadResponse(resp *http.Response) bool {
+	return resp.Request != nil && resp.Request.Method == http.MethodHead
+}
+
+// outputStats writes a single line summarizing the status of resp, the size of
+// its body, and the total time taken by the request if timings is not nil. In
+// line format, the summary is given as space-separated key=value pairs.
+func outputStats(w io.Writer, resp *http.Response, bodySize int, timings *Timings, format Format) {
+	if format == FormatLine {
+		fmt.Fprintf(w, "STATS status=%d bytes=%d", resp.StatusCode, bodySize)
+		if timings != nil {
+			fmt.Fprintf(w, " elapsed_ms=%d", timings.Total.Milliseconds())
+		}
+		fmt.Fprintln(w)
+		return
+	}
+
+	fmt.Fprintf(w, "%s · %s", resp.Status, formatByteSize(bodySize))
+	if timings != nil {
+		elapsed := timings.Total.Round(time.Millisecond)
+		if timings.Total < time.Millisecond {
+			elapsed = timings.Total.Round(time.Microsecond)
+		}
+		fmt.Fprintf(w, " · %s", elapsed)
+	}
+	fmt.Fprintln(w)
+}
+
+// formatByteSize gives n as a human-readable number of bytes using binary
+// units, e.g. "512 B" or "1.2 KiB".
+func formatByteSize(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	size := float64(n)
+	units := []string{"KiB", "MiB", "GiB", "TiB"}
+	var unit string
+	for _, unit = range units {
+		size /= 1024
+		if size < 1024 {
+			break
+		}
+	}
+	return fmt.Sprintf("%.1f %s", size, unit)
+}
+
+// outputTimings writes a section listing each of t to w in the given format.
+func outputTimings(w io.Writer, t Timings, format Format) {
+	phases := []struct {
+		name     string
+		lineName string
+		d        time.Duration
+	}{
+		{"DNS lookup", "DNS", t.DNS},
+		{"Connect", "CONNECT", t.Connect},
+		{"TLS handshake", "TLS", t.TLSHandshake},
+		{"First byte", "TTFB", t.TTFB},
+		{"Total", "TOTAL", t.Total},
+	}
+
+	if format == FormatPretty {
+		fmt.Fprintln(w, "------------------- TIMINGS -------------------")
+	} else if format == FormatLine {
+		fmt.Fprintln(w, lineDelimStart+" TIMINGS")
+	}
+
+	for _, ph := range phases {
+		if format == FormatPretty {
+			fmt.Fprintf(w, "%-14s %s\n", ph.name+":", ph.d)
+		} else if format == FormatLine {
+			fmt.Fprintf(w, "%s %s\n", ph.lineName, ph.d)
+		}
+	}
+
+	if format == FormatPretty {
+		fmt.Fprintln(w, "-----------------------------------------------")
+	} else if format == FormatLine {
+		fmt.Fprintln(w, lineDelimEnd)
+	}
+}
+
+// decompressedEncoding returns the content encoding that resp was in before
+// being transparently decompressed by the transport.
+func decompressedEncoding(resp *http.Response) string {
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" {
+		return enc
+	}
+
+	// net/http only ever transparently decompresses gzip
+	return "gzip"
+}
+
 func OutputRequest(req *http.Request, opts OutputControl) error {
 	// TODO: error check Fprint output
 
@@ -1228,6 +4049,11 @@ func OutputRequest(req *http.Request, opts OutputControl) error {
 		w = opts.Writer
 	}
 
+	// in JSON format, the request is output along with the response instead
+	if opts.Format == FormatJSON {
+		return nil
+	}
+
 	if opts.Request {
 		reqBytes, err := httputil.DumpRequestOut(req, true)
 		if err != nil {
@@ -1241,9 +4067,9 @@ func OutputRequest(req *http.Request, opts OutputControl) error {
 		}
 
 		// make shore to print the full URL in pretty format
-		fmt.Fprintf(w, "Request URI: %s\n\n", req.URL)
+		fmt.Fprintf(w, "Request URI: %s\n\n", MaskValues(req.URL.String(), opts.Mask))
 
-		fmt.Fprintln(w, string(reqBytes))
+		fmt.Fprintln(w, MaskValues(string(reqBytes), opts.Mask))
 
 		if opts.Format == FormatPretty && req.Body == nil || req.Body == http.NoBody {
 			fmt.Fprintln(w, "(no request body)")
@@ -0,0 +1,3385 @@
+package morc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Send_IPVersion(t *testing.T) {
+	testCases := []struct {
+		name       string
+		ipVersion  int
+		expectErr  string
+		expectCode int
+	}{
+		{
+			name:       "any version",
+			ipVersion:  0,
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "IPv4 to IPv4 host",
+			ipVersion:  4,
+			expectCode: http.StatusOK,
+		},
+		{
+			name:      "IPv6 to IPv4 host",
+			ipVersion: 6,
+			expectErr: "over IPv6",
+		},
+		{
+			name:      "invalid version",
+			ipVersion: 5,
+			expectErr: "IP version must be 4 or 6, not 5",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// httptest servers always listen on 127.0.0.1
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			result, err := Send("GET", srv.URL, "$", SendOptions{
+				Client:    srv.Client(),
+				IPVersion: tc.ipVersion,
+			})
+
+			if tc.expectErr != "" {
+				if !assert.Error(err) {
+					return
+				}
+				assert.True(strings.Contains(err.Error(), tc.expectErr), "expected error to contain %q, got %q", tc.expectErr, err)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectCode, result.Response.StatusCode)
+		})
+	}
+}
+
+func Test_Send_LocalAddr(t *testing.T) {
+	testCases := []struct {
+		name         string
+		localAddr    string
+		ipVersion    int
+		expectErr    string
+		expectRemote string
+	}{
+		{
+			name:         "loopback address",
+			localAddr:    "127.0.0.1",
+			expectRemote: "127.0.0.1",
+		},
+		{
+			name:         "loopback address with IPv4",
+			localAddr:    "127.0.0.1",
+			ipVersion:    4,
+			expectRemote: "127.0.0.1",
+		},
+		{
+			name:      "IPv4 address with IPv6",
+			localAddr: "127.0.0.1",
+			ipVersion: 6,
+			expectErr: "local address 127.0.0.1 is not an IPv6 address",
+		},
+		{
+			name:      "address not on this host",
+			localAddr: "192.0.2.1",
+			expectErr: "local address 192.0.2.1 cannot be bound",
+		},
+		{
+			name:      "not an address or interface",
+			localAddr: "not-a-real-interface",
+			expectErr: `local address "not-a-real-interface" is not an IP address or network interface`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var remote string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				remote, _, _ = net.SplitHostPort(r.RemoteAddr)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			_, err := Send("GET", srv.URL, "$", SendOptions{
+				Client:    srv.Client(),
+				IPVersion: tc.ipVersion,
+				LocalAddr: tc.localAddr,
+			})
+
+			if tc.expectErr != "" {
+				if !assert.Error(err) {
+					return
+				}
+				assert.True(strings.Contains(err.Error(), tc.expectErr), "expected error to contain %q, got %q", tc.expectErr, err)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectRemote, remote)
+		})
+	}
+}
+
+func Test_Send_Timeout(t *testing.T) {
+	testCases := []struct {
+		name       string
+		timeout    time.Duration
+		expectErr  string
+		expectCode int
+	}{
+		{
+			name:       "completes within timeout",
+			timeout:    5 * time.Second,
+			expectCode: http.StatusOK,
+		},
+		{
+			name:      "exceeds timeout",
+			timeout:   10 * time.Millisecond,
+			expectErr: "Client.Timeout exceeded",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(100 * time.Millisecond)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			result, err := Send("GET", srv.URL, "$", SendOptions{
+				Client:  srv.Client(),
+				Timeout: tc.timeout,
+			})
+
+			if tc.expectErr != "" {
+				if !assert.Error(err) {
+					return
+				}
+				assert.True(strings.Contains(err.Error(), tc.expectErr), "expected error to contain %q, got %q", tc.expectErr, err)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectCode, result.Response.StatusCode)
+		})
+	}
+}
+
+func Test_Send_DefaultHeaders(t *testing.T) {
+	testCases := []struct {
+		name           string
+		headers        http.Header
+		defaultHeaders http.Header
+		expect         http.Header
+	}{
+		{
+			name:           "defaults only",
+			defaultHeaders: http.Header{"User-Agent": {"morc-test"}},
+			expect:         http.Header{"User-Agent": {"morc-test"}},
+		},
+		{
+			name:           "defaults and other headers",
+			headers:        http.Header{"Accept": {"application/json"}},
+			defaultHeaders: http.Header{"User-Agent": {"morc-test"}},
+			expect:         http.Header{"User-Agent": {"morc-test"}, "Accept": {"application/json"}},
+		},
+		{
+			name:           "header overrides default",
+			headers:        http.Header{"Accept": {"application/json"}},
+			defaultHeaders: http.Header{"Accept": {"text/plain", "text/html"}, "User-Agent": {"morc-test"}},
+			expect:         http.Header{"User-Agent": {"morc-test"}, "Accept": {"application/json"}},
+		},
+		{
+			name:           "vars in defaults are substituted",
+			defaultHeaders: http.Header{"Authorization": {"Bearer ${TOKEN}"}},
+			expect:         http.Header{"Authorization": {"Bearer 8675309"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var received http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Clone()
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			_, err := Send("GET", srv.URL, "$", SendOptions{
+				Client:         srv.Client(),
+				Vars:           map[string]string{"TOKEN": "8675309"},
+				Headers:        tc.headers,
+				DefaultHeaders: tc.defaultHeaders,
+			})
+			if !assert.NoError(err) {
+				return
+			}
+
+			for key, vals := range tc.expect {
+				assert.Equal(vals, received.Values(key), "header %s", key)
+			}
+		})
+	}
+}
+
+func Test_Send_CaptureDefault(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+
+	testCases := []struct {
+		name      string
+		scraper   VarScraper
+		expect    string
+		expectErr string
+	}{
+		{
+			name:    "value present, default ignored",
+			scraper: VarScraper{Name: "ID", Steps: []TraversalStep{{Key: "id"}}, Default: strPtr("none")},
+			expect:  "413",
+		},
+		{
+			name:    "value missing, default used",
+			scraper: VarScraper{Name: "NEXT", Steps: []TraversalStep{{Key: "next"}}, Default: strPtr("none")},
+			expect:  "none",
+		},
+		{
+			name:    "value missing, empty default used",
+			scraper: VarScraper{Name: "NEXT", Steps: []TraversalStep{{Key: "next"}}, Default: strPtr("")},
+			expect:  "",
+		},
+		{
+			name:    "default is not transformed",
+			scraper: VarScraper{Name: "NEXT", Steps: []TraversalStep{{Key: "next"}}, Transform: TransformUpper, Default: strPtr("none")},
+			expect:  "none",
+		},
+		{
+			name:      "value missing, no default",
+			scraper:   VarScraper{Name: "NEXT", Steps: []TraversalStep{{Key: "next"}}},
+			expectErr: "scrape NEXT",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"id": "413"}`))
+			}))
+			defer srv.Close()
+
+			result, err := Send("GET", srv.URL, "$", SendOptions{
+				Client:   srv.Client(),
+				Captures: []VarScraper{tc.scraper},
+			})
+			if tc.expectErr != "" {
+				if !assert.Error(err) {
+					return
+				}
+				assert.Contains(err.Error(), tc.expectErr)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(map[string]string{tc.scraper.Name: tc.expect}, result.Captures)
+		})
+	}
+}
+
+func Test_Send_HTTPVersion(t *testing.T) {
+	testCases := []struct {
+		name        string
+		httpVersion int
+		noTLS       bool
+		noHTTP2     bool
+		expectErr   string
+		expectProto string
+	}{
+		{
+			name:        "any version",
+			httpVersion: 0,
+			expectProto: "HTTP/2.0",
+		},
+		{
+			name:        "HTTP/1.1 to HTTP/2 host",
+			httpVersion: 1,
+			expectProto: "HTTP/1.1",
+		},
+		{
+			name:        "HTTP/2 to HTTP/2 host",
+			httpVersion: 2,
+			expectProto: "HTTP/2.0",
+		},
+		{
+			name:        "HTTP/2 to HTTP/1.1 host",
+			httpVersion: 2,
+			noHTTP2:     true,
+			expectErr:   "send request: remote host responded with HTTP/1.1, not HTTP/2",
+		},
+		{
+			name:        "HTTP/2 without TLS",
+			httpVersion: 2,
+			noTLS:       true,
+			expectErr:   "HTTP/2 can only be used with https URLs",
+		},
+		{
+			name:        "invalid version",
+			httpVersion: 3,
+			expectErr:   "HTTP version must be 1 or 2, not 3",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotProto string
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotProto = r.Proto
+				w.WriteHeader(http.StatusOK)
+			}))
+			if tc.noTLS {
+				srv.Start()
+			} else {
+				srv.EnableHTTP2 = !tc.noHTTP2
+				srv.StartTLS()
+			}
+			defer srv.Close()
+
+			result, err := Send("GET", srv.URL, "$", SendOptions{
+				Client:      srv.Client(),
+				HTTPVersion: tc.httpVersion,
+			})
+
+			if tc.expectErr != "" {
+				assert.EqualError(err, tc.expectErr)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectProto, gotProto)
+			assert.Equal(tc.expectProto, result.Response.Proto)
+		})
+	}
+}
+
+func Test_ParseResolveOverride(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		expect    ResolveOverride
+		expectErr string
+	}{
+		{
+			name:   "IPv4 address",
+			input:  "example.com:443:10.0.0.8",
+			expect: ResolveOverride{Host: "example.com", Port: "443", Addr: "10.0.0.8"},
+		},
+		{
+			name:   "IPv6 address in brackets",
+			input:  "example.com:8080:[::1]",
+			expect: ResolveOverride{Host: "example.com", Port: "8080", Addr: "::1"},
+		},
+		{
+			name:   "IPv6 address without brackets",
+			input:  "example.com:8080:::1",
+			expect: ResolveOverride{Host: "example.com", Port: "8080", Addr: "::1"},
+		},
+		{
+			name:      "missing address",
+			input:     "example.com:443",
+			expectErr: "not in HOST:PORT:ADDR format",
+		},
+		{
+			name:      "empty host",
+			input:     ":443:10.0.0.8",
+			expectErr: "HOST cannot be empty",
+		},
+		{
+			name:      "invalid port",
+			input:     "example.com:https:10.0.0.8",
+			expectErr: `"https" is not a valid port`,
+		},
+		{
+			name:      "address is a hostname",
+			input:     "example.com:443:lb1.example.com",
+			expectErr: `"lb1.example.com" is not an IP address`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := ParseResolveOverride(tc.input)
+			if tc.expectErr != "" {
+				assert.EqualError(err, tc.expectErr)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+
+			// and it should round-trip
+			again, err := ParseResolveOverride(actual.String())
+			assert.NoError(err)
+			assert.Equal(actual, again)
+		})
+	}
+}
+
+func Test_Send_Resolve(t *testing.T) {
+	testCases := []struct {
+		name             string
+		tls              bool
+		resolve          func(port string) []ResolveOverride
+		expectHost       string
+		expectServerName string
+		expectErr        string
+	}{
+		{
+			name: "host is connected to at resolved address",
+			resolve: func(port string) []ResolveOverride {
+				return []ResolveOverride{{Host: "VRISKA.test", Port: port, Addr: "127.0.0.1"}}
+			},
+			expectHost: "vriska.test",
+		},
+		{
+			name: "TLS server name is from URL",
+			tls:  true,
+			resolve: func(port string) []ResolveOverride {
+				return []ResolveOverride{{Host: "vriska.test", Port: port, Addr: "127.0.0.1"}}
+			},
+			expectHost:       "vriska.test",
+			expectServerName: "vriska.test",
+		},
+		{
+			name: "invalid address",
+			resolve: func(port string) []ResolveOverride {
+				return []ResolveOverride{{Host: "vriska.test", Port: port, Addr: "lb1"}}
+			},
+			expectErr: `is not an IP address`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotHost, gotServerName string
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotHost, _, _ = net.SplitHostPort(r.Host)
+				if r.TLS != nil {
+					gotServerName = r.TLS.ServerName
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			if tc.tls {
+				srv.StartTLS()
+			} else {
+				srv.Start()
+			}
+			defer srv.Close()
+
+			srvURL, _ := url.Parse(srv.URL)
+			port := srvURL.Port()
+			srvURL.Host = net.JoinHostPort("vriska.test", port)
+
+			_, err := Send("GET", srvURL.String(), "$", SendOptions{
+				Client:             srv.Client(),
+				Resolve:            tc.resolve(port),
+				InsecureSkipVerify: tc.tls,
+			})
+
+			if tc.expectErr != "" {
+				if !assert.Error(err) {
+					return
+				}
+				assert.True(strings.Contains(err.Error(), tc.expectErr), "expected error to contain %q, got %q", tc.expectErr, err)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectHost, gotHost)
+			assert.Equal(tc.expectServerName, gotServerName)
+		})
+	}
+}
+
+func Test_Send_ClientCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, clientCAs := writeTestClientCert(t, dir)
+
+	// a single file with both the certificate and the key
+	certPEM, _ := os.ReadFile(certFile)
+	keyPEM, _ := os.ReadFile(keyFile)
+	bothFile := filepath.Join(dir, "both.pem")
+	if err := os.WriteFile(bothFile, append(certPEM, keyPEM...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		certFile  string
+		keyFile   string
+		expectErr string
+	}{
+		{
+			name:     "cert and key",
+			certFile: certFile,
+			keyFile:  keyFile,
+		},
+		{
+			name:     "key in cert file",
+			certFile: bothFile,
+		},
+		{
+			name:      "no cert",
+			expectErr: "send request:",
+		},
+		{
+			name:      "key without cert",
+			keyFile:   keyFile,
+			expectErr: "client key file cannot be set without a client certificate file",
+		},
+		{
+			name:      "cert file does not exist",
+			certFile:  filepath.Join(dir, "missing.pem"),
+			keyFile:   keyFile,
+			expectErr: "load client certificate:",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotCN string
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if len(r.TLS.PeerCertificates) > 0 {
+					gotCN = r.TLS.PeerCertificates[0].Subject.CommonName
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			srv.TLS = &tls.Config{
+				ClientAuth: tls.RequireAndVerifyClientCert,
+				ClientCAs:  clientCAs,
+			}
+			srv.StartTLS()
+			defer srv.Close()
+
+			_, err := Send("GET", srv.URL, "$", SendOptions{
+				Client:         srv.Client(),
+				ClientCertFile: tc.certFile,
+				ClientKeyFile:  tc.keyFile,
+			})
+
+			if tc.expectErr != "" {
+				if !assert.Error(err) {
+					return
+				}
+				assert.True(strings.Contains(err.Error(), tc.expectErr), "expected error to contain %q, got %q", tc.expectErr, err)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal("morc client", gotCN)
+		})
+	}
+}
+
+func Test_Send_CACert(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(emptyFile, []byte("not a cert\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name       string
+		caCertFile string
+		expectErr  string
+	}{
+		{
+			name:       "server is signed by CA",
+			caCertFile: caFile,
+		},
+		{
+			name:      "no CA bundle",
+			expectErr: "certificate signed by unknown authority",
+		},
+		{
+			name:       "no certificates in bundle",
+			caCertFile: emptyFile,
+			expectErr:  "read CA certificates: no certificates found in " + emptyFile,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// a client that does not already trust the server
+			result, err := Send("GET", srv.URL, "$", SendOptions{
+				Client:     &http.Client{},
+				CACertFile: tc.caCertFile,
+			})
+
+			if tc.expectErr != "" {
+				if !assert.Error(err) {
+					return
+				}
+				assert.True(strings.Contains(err.Error(), tc.expectErr), "expected error to contain %q, got %q", tc.expectErr, err)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(http.StatusOK, result.Response.StatusCode)
+		})
+	}
+}
+
+// writeTestClientCert creates a self-signed client certificate and its key in
+// dir. It returns the paths to the certificate and key files, and a pool that
+// trusts the certificate.
+func writeTestClientCert(t *testing.T, dir string) (string, string, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(413),
+		Subject:               pkix.Name{CommonName: "morc client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+func Test_sharedTransport(t *testing.T) {
+	assert := assert.New(t)
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	cfg := transportConfig{
+		base: base,
+		pool: PoolOptions{
+			MaxIdleConns:    413,
+			MaxConnsPerHost: 612,
+			IdleConnTimeout: 8 * time.Second,
+		},
+	}
+
+	tr, err := sharedTransport(cfg)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(413, tr.MaxIdleConns)
+	assert.Equal(413, tr.MaxIdleConnsPerHost)
+	assert.Equal(612, tr.MaxConnsPerHost)
+	assert.Equal(8*time.Second, tr.IdleConnTimeout)
+
+	// base must be left alone
+	assert.NotEqual(413, base.MaxIdleConns)
+	assert.NotEqual(612, base.MaxConnsPerHost)
+
+	// same options gives the same transport so that its pool is reused
+	same, _ := sharedTransport(cfg)
+	assert.Same(tr, same)
+
+	// different options gives a different one
+	cfg.pool.MaxConnsPerHost = 8
+	different, _ := sharedTransport(cfg)
+	assert.NotSame(tr, different)
+}
+
+func Test_sharedTransport_evictsLeastRecentlyUsed(t *testing.T) {
+	assert := assert.New(t)
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	cfgFor := func(n int) transportConfig {
+		return transportConfig{base: base, pool: PoolOptions{MaxConnsPerHost: 9000 + n}}
+	}
+
+	first, _ := sharedTransport(cfgFor(0))
+	second, _ := sharedTransport(cfgFor(1))
+
+	// use the first again so that the second is now the least recently used
+	again, _ := sharedTransport(cfgFor(0))
+	assert.Same(first, again)
+
+	for i := 2; i <= maxSharedTransports; i++ {
+		sharedTransport(cfgFor(i))
+	}
+
+	transportsMtx.Lock()
+	assert.LessOrEqual(len(transports), maxSharedTransports)
+	assert.Len(transportsByUse, len(transports))
+	transportsMtx.Unlock()
+
+	stillFirst, _ := sharedTransport(cfgFor(0))
+	assert.Same(first, stillFirst)
+
+	newSecond, _ := sharedTransport(cfgFor(1))
+	assert.NotSame(second, newSecond)
+}
+
+func Test_TimedCookieJar_evictOld(t *testing.T) {
+	now := time.Now()
+	u, _ := url.Parse("http://example.com/")
+
+	testCases := []struct {
+		name     string
+		lifetime time.Duration
+		calls    []SetCookiesCall
+		expect   [][]string // names of cookies remaining in each remaining call
+	}{
+		{
+			name:     "no calls",
+			lifetime: time.Hour,
+			expect:   [][]string{},
+		},
+		{
+			name:     "live cookies are kept",
+			lifetime: time.Hour,
+			calls: []SetCookiesCall{
+				{Time: now, URL: u, Cookies: []*http.Cookie{
+					{Name: "session"},
+					{Name: "maxage", MaxAge: 60},
+					{Name: "expires", Expires: now.Add(time.Minute)},
+				}},
+			},
+			expect: [][]string{{"session", "maxage", "expires"}},
+		},
+		{
+			name:     "mix of expired and live cookies in one call",
+			lifetime: time.Hour,
+			calls: []SetCookiesCall{
+				{Time: now.Add(-2 * time.Minute), URL: u, Cookies: []*http.Cookie{
+					{Name: "live"},
+					{Name: "deleted", MaxAge: -1},
+					{Name: "maxage-passed", MaxAge: 60},
+					{Name: "maxage-live", MaxAge: 600},
+					{Name: "expires-passed", Expires: now.Add(-time.Minute)},
+					{Name: "expires-live", Expires: now.Add(time.Minute)},
+				}},
+			},
+			expect: [][]string{{"live", "maxage-live", "expires-live"}},
+		},
+		{
+			name:     "call with only expired cookies is dropped",
+			lifetime: time.Hour,
+			calls: []SetCookiesCall{
+				{Time: now, URL: u, Cookies: []*http.Cookie{{Name: "live"}}},
+				{Time: now, URL: u, Cookies: []*http.Cookie{
+					{Name: "deleted", MaxAge: -1},
+					{Name: "expires-passed", Expires: now.Add(-time.Minute)},
+				}},
+			},
+			expect: [][]string{{"live"}},
+		},
+		{
+			name:     "deletion removes cookie set by earlier call",
+			lifetime: time.Hour,
+			calls: []SetCookiesCall{
+				{Time: now.Add(-time.Minute), URL: u, Cookies: []*http.Cookie{{Name: "session"}, {Name: "theme"}}},
+				{Time: now, URL: u, Cookies: []*http.Cookie{{Name: "session", MaxAge: -1}}},
+			},
+			expect: [][]string{{"theme"}},
+		},
+		{
+			name:     "calls older than lifetime are evicted",
+			lifetime: time.Hour,
+			calls: []SetCookiesCall{
+				{Time: now.Add(-2 * time.Hour), URL: u, Cookies: []*http.Cookie{{Name: "old"}}},
+				{Time: now, URL: u, Cookies: []*http.Cookie{{Name: "new"}}},
+			},
+			expect: [][]string{{"new"}},
+		},
+		{
+			name:     "all calls older than lifetime are evicted",
+			lifetime: time.Hour,
+			calls: []SetCookiesCall{
+				{Time: now.Add(-3 * time.Hour), URL: u, Cookies: []*http.Cookie{{Name: "old1"}}},
+				{Time: now.Add(-2 * time.Hour), URL: u, Cookies: []*http.Cookie{{Name: "old2"}}},
+			},
+			expect: [][]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			jar := NewTimedCookieJar(nil, tc.lifetime)
+			jar.calls = tc.calls
+
+			jar.evictOld()
+
+			actual := [][]string{}
+			for _, call := range jar.calls {
+				names := []string{}
+				for _, c := range call.Cookies {
+					names = append(names, c.Name)
+				}
+				actual = append(actual, names)
+			}
+
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_OutputResponse_Decompressed(t *testing.T) {
+	testCases := []struct {
+		name         string
+		uncompressed bool
+		opts         OutputControl
+		expect       string
+	}{
+		{
+			name:         "decompressed, headers",
+			uncompressed: true,
+			opts:         OutputControl{Headers: true, SuppressResponseBody: true},
+			expect: "HTTP/1.1 200 OK\n" +
+				"------------------- HEADERS -------------------\n" +
+				"Content-Type: text/plain\n" +
+				"(decompressed from gzip)\n" +
+				"-----------------------------------------------\n",
+		},
+		{
+			name:         "not decompressed, headers",
+			uncompressed: false,
+			opts:         OutputControl{Headers: true, SuppressResponseBody: true},
+			expect: "HTTP/1.1 200 OK\n" +
+				"------------------- HEADERS -------------------\n" +
+				"Content-Type: text/plain\n" +
+				"-----------------------------------------------\n",
+		},
+		{
+			name:         "decompressed, no headers",
+			uncompressed: true,
+			opts:         OutputControl{SuppressResponseBody: true},
+			expect:       "HTTP/1.1 200 OK\n",
+		},
+		{
+			name:         "decompressed, headers in line format",
+			uncompressed: true,
+			opts:         OutputControl{Headers: true, SuppressResponseBody: true, Format: FormatLine},
+			expect: "HTTP/1.1 200 OK\n" +
+				">>> HEADERS\n" +
+				"Content-Type: text/plain\n" +
+				"<<<\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			resp := &http.Response{
+				Proto:        "HTTP/1.1",
+				Status:       "200 OK",
+				StatusCode:   http.StatusOK,
+				Header:       http.Header{"Content-Type": []string{"text/plain"}},
+				Body:         http.NoBody,
+				Uncompressed: tc.uncompressed,
+			}
+
+			out := &bytes.Buffer{}
+			tc.opts.Writer = out
+
+			err := OutputResponse(resp, nil, nil, tc.opts)
+
+			assert.NoError(err)
+			assert.Equal(tc.expect, out.String())
+		})
+	}
+}
+
+func Test_OutputResponse_Timings(t *testing.T) {
+	timings := &Timings{
+		DNS:          2 * time.Millisecond,
+		Connect:      3 * time.Millisecond,
+		TLSHandshake: 0,
+		TTFB:         10 * time.Millisecond,
+		Total:        12 * time.Millisecond,
+	}
+
+	testCases := []struct {
+		name    string
+		timings *Timings
+		opts    OutputControl
+		expect  string
+	}{
+		{
+			name:    "pretty",
+			timings: timings,
+			opts:    OutputControl{Timings: true, SuppressResponseBody: true},
+			expect: "------------------- TIMINGS -------------------\n" +
+				"DNS lookup:    2ms\n" +
+				"Connect:       3ms\n" +
+				"TLS handshake: 0s\n" +
+				"First byte:    10ms\n" +
+				"Total:         12ms\n" +
+				"-----------------------------------------------\n" +
+				"HTTP/1.1 200 OK\n",
+		},
+		{
+			name:    "line format",
+			timings: timings,
+			opts:    OutputControl{Timings: true, SuppressResponseBody: true, Format: FormatLine},
+			expect: ">>> TIMINGS\n" +
+				"DNS 2ms\n" +
+				"CONNECT 3ms\n" +
+				"TLS 0s\n" +
+				"TTFB 10ms\n" +
+				"TOTAL 12ms\n" +
+				"<<<\n" +
+				"HTTP/1.1 200 OK\n",
+		},
+		{
+			name:    "not requested",
+			timings: timings,
+			opts:    OutputControl{SuppressResponseBody: true},
+			expect:  "HTTP/1.1 200 OK\n",
+		},
+		{
+			name:   "requested but not known",
+			opts:   OutputControl{Timings: true, SuppressResponseBody: true},
+			expect: "HTTP/1.1 200 OK\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			resp := &http.Response{
+				Proto:      "HTTP/1.1",
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				Body:       http.NoBody,
+			}
+
+			out := &bytes.Buffer{}
+			tc.opts.Writer = out
+
+			err := OutputResponse(resp, nil, tc.timings, tc.opts)
+
+			assert.NoError(err)
+			assert.Equal(tc.expect, out.String())
+		})
+	}
+}
+
+func Test_Send_Timings(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("VRISKA"))
+	})
+
+	t.Run("http", func(t *testing.T) {
+		assert := assert.New(t)
+
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		result, err := Send("GET", srv.URL, "$", SendOptions{
+			Client: srv.Client(),
+			Output: OutputControl{Writer: io.Discard},
+		})
+		if !assert.NoError(err) {
+			return
+		}
+
+		// the server is at an IP address so there is no lookup
+		assert.Zero(result.Timings.DNS)
+		assert.Positive(result.Timings.Connect)
+		assert.Zero(result.Timings.TLSHandshake)
+		assert.Positive(result.Timings.TTFB)
+		assert.GreaterOrEqual(result.Timings.Total, result.Timings.TTFB)
+	})
+
+	t.Run("https", func(t *testing.T) {
+		assert := assert.New(t)
+
+		srv := httptest.NewTLSServer(handler)
+		defer srv.Close()
+
+		result, err := Send("GET", srv.URL, "$", SendOptions{
+			Client: srv.Client(),
+			Output: OutputControl{Writer: io.Discard},
+		})
+		if !assert.NoError(err) {
+			return
+		}
+
+		assert.Positive(result.Timings.Connect)
+		assert.Positive(result.Timings.TLSHandshake)
+		assert.GreaterOrEqual(result.Timings.TTFB, result.Timings.TLSHandshake)
+	})
+}
+
+func Test_Send_Redirects(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.Redirect(w, r, "/auth", http.StatusFound)
+		case "/auth":
+			http.Redirect(w, r, "/home", http.StatusMovedPermanently)
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("VRISKA"))
+		}
+	})
+
+	t.Run("chain is recorded and output", func(t *testing.T) {
+		assert := assert.New(t)
+
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		out := &bytes.Buffer{}
+		result, err := Send("GET", srv.URL+"/login", "$", SendOptions{
+			Client: srv.Client(),
+			Output: OutputControl{Writer: out, Redirects: true},
+		})
+		if !assert.NoError(err) {
+			return
+		}
+
+		if assert.Len(result.Redirects, 2) {
+			assert.Equal(http.StatusFound, result.Redirects[0].StatusCode)
+			assert.Equal("/auth", result.Redirects[0].Header.Get("Location"))
+			assert.Equal(http.StatusMovedPermanently, result.Redirects[1].StatusCode)
+			assert.Equal("/home", result.Redirects[1].Header.Get("Location"))
+		}
+
+		expect := "------------------ REDIRECTS ------------------\n" +
+			"GET " + srv.URL + "/login -> 302 Found /auth\n" +
+			"GET " + srv.URL + "/auth -> 301 Moved Permanently /home\n" +
+			"-----------------------------------------------\n" +
+			"HTTP/1.1 200 OK\n" +
+			"VRISKA\n"
+		assert.Equal(expect, out.String())
+	})
+
+	t.Run("line format", func(t *testing.T) {
+		assert := assert.New(t)
+
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		out := &bytes.Buffer{}
+		_, err := Send("GET", srv.URL+"/auth", "$", SendOptions{
+			Client: srv.Client(),
+			Output: OutputControl{Writer: out, Redirects: true, Format: FormatLine, SuppressResponseBody: true},
+		})
+		if !assert.NoError(err) {
+			return
+		}
+
+		expect := lineDelimStart + " REDIRECTS\n" +
+			"301 GET " + srv.URL + "/auth /home\n" +
+			lineDelimEnd + "\n" +
+			"HTTP/1.1 200 OK\n"
+		assert.Equal(expect, out.String())
+	})
+
+	t.Run("no redirects", func(t *testing.T) {
+		assert := assert.New(t)
+
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		out := &bytes.Buffer{}
+		result, err := Send("GET", srv.URL+"/home", "$", SendOptions{
+			Client: srv.Client(),
+			Output: OutputControl{Writer: out, Redirects: true, SuppressResponseBody: true},
+		})
+		if !assert.NoError(err) {
+			return
+		}
+
+		assert.Empty(result.Redirects)
+		expect := "------------------ REDIRECTS ------------------\n" +
+			"(no redirects)\n" +
+			"-----------------------------------------------\n" +
+			"HTTP/1.1 200 OK\n"
+		assert.Equal(expect, out.String())
+	})
+}
+
+func Test_VarScraper_Scrape(t *testing.T) {
+	testCases := []struct {
+		name      string
+		spec      string
+		transform CaptureTransform
+		data      string
+		expect    string
+		expectErr string
+	}{
+		{
+			name:   "single path",
+			spec:   ".data.id",
+			data:   `{"data": {"id": "413"}}`,
+			expect: "413",
+		},
+		{
+			name:      "single path, missing key",
+			spec:      ".data.id",
+			data:      `{"id": "413"}`,
+			expectErr: `key "data" does not exist`,
+		},
+		{
+			name:      "single path, index out of range",
+			spec:      ".items[2]",
+			data:      `{"items": ["a", "b"]}`,
+			expectErr: "index 2 is out of range for array of length 2",
+		},
+		{
+			name:   "alternatives, first matches",
+			spec:   ".data.id || .result.id || .id",
+			data:   `{"data": {"id": "413"}, "id": "612"}`,
+			expect: "413",
+		},
+		{
+			name:   "alternatives, second matches",
+			spec:   ".data.id || .result.id || .id",
+			data:   `{"result": {"id": "1025"}}`,
+			expect: "1025",
+		},
+		{
+			name:   "alternatives, last matches",
+			spec:   ".data.id || .result.id || .id",
+			data:   `{"id": "612"}`,
+			expect: "612",
+		},
+		{
+			name:      "alternatives, none match",
+			spec:      ".data.id || .result.id || .id",
+			data:      `{"uuid": "612"}`,
+			expectErr: `key "id" does not exist`,
+		},
+		{
+			name:      "offset, start past end of empty data",
+			spec:      ":5,8",
+			data:      ``,
+			expectErr: "start offset is 5 but data length is only 0",
+		},
+		{
+			name:   "offset, up to last byte",
+			spec:   ":,-1",
+			data:   `vriska!`,
+			expect: "vriska",
+		},
+		{
+			name:   "offset, negative start to end",
+			spec:   ":-3,",
+			data:   `vriska`,
+			expect: "ska",
+		},
+		{
+			name:   "offset, negative start and end",
+			spec:   ":-4,-1",
+			data:   `vriska!`,
+			expect: "ska",
+		},
+		{
+			name:   "offset, negative start and positive end",
+			spec:   ":-6,3",
+			data:   `vriska`,
+			expect: "vri",
+		},
+		{
+			name:      "offset, negative start before beginning of data",
+			spec:      ":-8,",
+			data:      `vriska`,
+			expectErr: "start offset is -8 but data length is only 6",
+		},
+		{
+			name:      "offset, resolved end not after resolved start",
+			spec:      ":-2,4",
+			data:      `vriska`,
+			expectErr: "effective end offset of 4 (4) is less than or equal to effective start offset of -2 (4)",
+		},
+		{
+			name:      "offset, negative end resolves to start",
+			spec:      ":3,-3",
+			data:      `vriska`,
+			expectErr: "effective end offset of -3 (3) is less than or equal to effective start offset of 3 (3)",
+		},
+		{
+			name:   "rune offset",
+			spec:   ":r2,5",
+			data:   `¡Hé🐱llo`,
+			expect: "é🐱l",
+		},
+		{
+			name:   "rune offset, relative to end",
+			spec:   ":r-3,-1",
+			data:   `naïve!`,
+			expect: "ve",
+		},
+		{
+			name:   "byte offset splits multibyte character",
+			spec:   ":0,3",
+			data:   `naïve`,
+			expect: "na\xc3",
+		},
+		{
+			name:      "rune offset, end past data",
+			spec:      ":r0,6",
+			data:      `naïve`,
+			expectErr: "end offset is 6 but data length is only 5 runes",
+		},
+		{
+			name:      "rune offset, invalid UTF-8",
+			spec:      ":r0,2",
+			data:      "a\xffb",
+			expectErr: "data is not valid UTF-8",
+		},
+		{
+			name:   "alternative is an offset",
+			spec:   ".id || :0,3",
+			data:   `not json`,
+			expect: "not",
+		},
+		{
+			name:   "quoted key containing bars",
+			spec:   `."a||b" || .c`,
+			data:   `{"a||b": "found"}`,
+			expect: "found",
+		},
+		{
+			name:      "transform trim",
+			spec:      ".token",
+			transform: TransformTrim,
+			data:      `{"token": "  abc \n"}`,
+			expect:    "abc",
+		},
+		{
+			name:      "transform base64decode",
+			spec:      ".token",
+			transform: TransformBase64Decode,
+			data:      `{"token": "dnJpc2th"}`,
+			expect:    "vriska",
+		},
+		{
+			name:      "transform base64decode, unpadded URL alphabet",
+			spec:      ".token",
+			transform: TransformBase64Decode,
+			data:      `{"token": "Pz8_"}`,
+			expect:    "???",
+		},
+		{
+			name:      "transform base64decode, invalid data",
+			spec:      ".token",
+			transform: TransformBase64Decode,
+			data:      `{"token": "not base64!"}`,
+			expectErr: "base64decode: illegal base64 data",
+		},
+		{
+			name:      "transform urldecode",
+			spec:      ".next",
+			transform: TransformURLDecode,
+			data:      `{"next": "a%20b%2Fc"}`,
+			expect:    "a b/c",
+		},
+		{
+			name:      "transform lower",
+			spec:      ".code",
+			transform: TransformLower,
+			data:      `{"code": "AbC"}`,
+			expect:    "abc",
+		},
+		{
+			name:      "transform upper",
+			spec:      ".code",
+			transform: TransformUpper,
+			data:      `{"code": "AbC"}`,
+			expect:    "ABC",
+		},
+		{
+			name:      "transform applies to alternative",
+			spec:      ".data.id || :0,5",
+			transform: TransformUpper,
+			data:      `vriska`,
+			expect:    "VRISK",
+		},
+		{
+			name:      "transform not applied on scrape failure",
+			spec:      ".id",
+			transform: TransformBase64Decode,
+			data:      `{"uuid": "612"}`,
+			expectErr: `key "id" does not exist`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			scraper, err := ParseVarScraperSpec("TEST", tc.spec)
+			if !assert.NoError(err) {
+				return
+			}
+			scraper.Transform = tc.transform
+
+			actual, err := scraper.Scrape([]byte(tc.data))
+			if tc.expectErr != "" {
+				if !assert.Error(err) {
+					return
+				}
+				assert.Contains(err.Error(), tc.expectErr)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_ParseVarScraperSpec_RoundTrip(t *testing.T) {
+	testCases := []struct {
+		name       string
+		spec       string
+		expectSpec string
+	}{
+		{
+			name:       "plain keys",
+			spec:       ".data.people[0].name",
+			expectSpec: ".data.people[0].name",
+		},
+		{
+			name:       "quoted key with dot",
+			spec:       `.data."first.name"`,
+			expectSpec: `.data."first.name"`,
+		},
+		{
+			name:       "quoted key with brackets and space",
+			spec:       `."items [all]"[2]`,
+			expectSpec: `."items [all]"[2]`,
+		},
+		{
+			name:       "quoted key with quotes and backslash",
+			spec:       `."say \"hi\" \\ bye"`,
+			expectSpec: `."say \"hi\" \\ bye"`,
+		},
+		{
+			name:       "escaped space in unquoted key is requoted",
+			spec:       `.first\ name`,
+			expectSpec: `."first name"`,
+		},
+		{
+			name:       "quoted key that does not need quotes",
+			spec:       `."name"`,
+			expectSpec: `.name`,
+		},
+		{
+			name:       "quoted key with bars in alternatives",
+			spec:       `."a||b" || .c`,
+			expectSpec: `."a||b" || .c`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			parsed, err := ParseVarScraperSpec("TEST", tc.spec)
+			if !assert.NoError(err) {
+				return
+			}
+
+			rendered := parsed.Spec()
+			assert.Equal(tc.expectSpec, rendered)
+
+			reparsed, err := ParseVarScraperSpec("TEST", rendered)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(parsed, reparsed)
+		})
+	}
+}
+
+func Test_ParseVarScraperSpec_Alternatives(t *testing.T) {
+	testCases := []struct {
+		name       string
+		spec       string
+		expectSpec string
+		expectErr  string
+	}{
+		{
+			name:       "no alternatives",
+			spec:       ".data.id",
+			expectSpec: ".data.id",
+		},
+		{
+			name:       "path alternatives",
+			spec:       ".data.id||.result.id ||  .id",
+			expectSpec: ".data.id || .result.id || .id",
+		},
+		{
+			name:       "mixed alternatives",
+			spec:       ".data.id || :2,8",
+			expectSpec: ".data.id || offset 2,8",
+		},
+		{
+			name:       "rune offset",
+			spec:       ":r28,32",
+			expectSpec: "rune offset 28,32",
+		},
+		{
+			name:       "rune offset alternative",
+			spec:       ".data.id || :r,-1",
+			expectSpec: ".data.id || rune offset 0,<END-1>",
+		},
+		{
+			name:      "rune offset missing comma",
+			spec:      ":r28",
+			expectErr: `":r28" is not in :rSTART,END format`,
+		},
+		{
+			name:      "empty alternative",
+			spec:      ".data.id || ",
+			expectErr: "alternative #1 is empty",
+		},
+		{
+			name:      "invalid alternative",
+			spec:      ".data.id || .id || nope",
+			expectErr: "alternative #2: invalid var scraper spec \"nope\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := ParseVarScraperSpec("TEST", tc.spec)
+			if tc.expectErr != "" {
+				if !assert.Error(err) {
+					return
+				}
+				assert.Contains(err.Error(), tc.expectErr)
+				return
+			}
+
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectSpec, actual.Spec())
+			assert.Equal("TEST from "+tc.expectSpec, actual.String())
+		})
+	}
+}
+
+func Test_VarScraper_Validate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		scraper   VarScraper
+		expectErr string
+	}{
+		{
+			name:    "entire response",
+			scraper: VarScraper{Name: "TEST"},
+		},
+		{
+			name:    "offset to end",
+			scraper: VarScraper{Name: "TEST", OffsetStart: 4, OffsetEnd: -2},
+		},
+		{
+			name:    "JSON path",
+			scraper: VarScraper{Name: "TEST", Steps: []TraversalStep{{Key: "data"}, {Index: 1}}},
+		},
+		{
+			name: "with alternatives",
+			scraper: VarScraper{
+				Name:         "TEST",
+				Steps:        []TraversalStep{{Key: "id"}},
+				Alternatives: []VarScraper{{OffsetStart: 1, OffsetEnd: 3}},
+			},
+		},
+		{
+			name:      "empty name",
+			scraper:   VarScraper{},
+			expectErr: "name is empty",
+		},
+		{
+			name:      "invalid name",
+			scraper:   VarScraper{Name: "TEST VAR"},
+			expectErr: `name "TEST VAR" contains invalid characters`,
+		},
+		{
+			name:    "negative start",
+			scraper: VarScraper{Name: "TEST", OffsetStart: -3},
+		},
+		{
+			name:    "negative start, positive end",
+			scraper: VarScraper{Name: "TEST", OffsetStart: -3, OffsetEnd: 2},
+		},
+		{
+			name:      "negative end before negative start",
+			scraper:   VarScraper{Name: "TEST", OffsetStart: -3, OffsetEnd: -5},
+			expectErr: "end offset -5 is less than or equal to start offset -3",
+		},
+		{
+			name:      "end before start",
+			scraper:   VarScraper{Name: "TEST", OffsetStart: 10, OffsetEnd: 5},
+			expectErr: "end offset 5 is less than or equal to start offset 10",
+		},
+		{
+			name: "invalid alternative",
+			scraper: VarScraper{
+				Name:         "TEST",
+				Alternatives: []VarScraper{{Steps: []TraversalStep{{Key: "id"}}}, {OffsetStart: -1, OffsetEnd: -1}},
+			},
+			expectErr: "alternative #2: end offset -1 is less than or equal to start offset -1",
+		},
+		{
+			name: "nested alternatives",
+			scraper: VarScraper{
+				Name:         "TEST",
+				Alternatives: []VarScraper{{Alternatives: []VarScraper{{}}}},
+			},
+			expectErr: "alternative #1: alternatives cannot themselves have alternatives",
+		},
+		{
+			name:    "with transform",
+			scraper: VarScraper{Name: "TEST", Transform: TransformTrim},
+		},
+		{
+			name:      "unknown transform",
+			scraper:   VarScraper{Name: "TEST", Transform: "rot13"},
+			expectErr: `unknown transform "rot13"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			err := tc.scraper.Validate()
+			if tc.expectErr != "" {
+				if !assert.Error(err) {
+					return
+				}
+				assert.Contains(err.Error(), tc.expectErr)
+				return
+			}
+
+			assert.NoError(err)
+		})
+	}
+}
+
+func Test_RESTClient_Substitute(t *testing.T) {
+	testCases := []struct {
+		name       string
+		vars       map[string]string
+		overrides  map[string]string
+		unexpanded map[string]bool
+		input      string
+		expect     string
+		expectErr  string
+	}{
+		{
+			name:   "no vars",
+			input:  "http://example.com",
+			expect: "http://example.com",
+		},
+		{
+			name:   "multiple vars",
+			vars:   map[string]string{"SCHEME": "https", "HOST": "example.com"},
+			input:  "${SCHEME}://${HOST}/users",
+			expect: "https://example.com/users",
+		},
+		{
+			name:   "nested reference",
+			vars:   map[string]string{"BASE": "https://example.com", "URL_PATH": "${BASE}/users"},
+			input:  "${URL_PATH}/1",
+			expect: "https://example.com/users/1",
+		},
+		{
+			name:   "deeply nested reference",
+			vars:   map[string]string{"A": "${B}-a", "B": "${C}-b", "C": "c"},
+			input:  "${A}",
+			expect: "c-b-a",
+		},
+		{
+			name:      "override is used in nested reference",
+			vars:      map[string]string{"BASE": "https://example.com", "URL_PATH": "${BASE}/users"},
+			overrides: map[string]string{"BASE": "http://localhost"},
+			input:     "${URL_PATH}",
+			expect:    "http://localhost/users",
+		},
+		{
+			name:   "same var referenced twice is not a cycle",
+			vars:   map[string]string{"PAIR": "${X}${X}", "X": "x"},
+			input:  "${PAIR}",
+			expect: "xx",
+		},
+		{
+			name:   "escaped reference in value is left as-is",
+			vars:   map[string]string{"A": "$${B}"},
+			input:  "${A}",
+			expect: "$${B}",
+		},
+		{
+			name:       "unexpanded var in nested reference is left as-is",
+			vars:       map[string]string{"AUTH": "Bearer ${TOKEN}", "TOKEN": "8675309"},
+			unexpanded: map[string]bool{"TOKEN": true},
+			input:      "${AUTH}",
+			expect:     "Bearer ${TOKEN}",
+		},
+		{
+			name:   "transform",
+			vars:   map[string]string{"CREDS": "vriska:8675309"},
+			input:  "Basic ${CREDS|base64}",
+			expect: "Basic dnJpc2thOjg2NzUzMDk=",
+		},
+		{
+			name:   "chained transforms are applied in order",
+			vars:   map[string]string{"NAME": "  Vriska Serket "},
+			input:  "${NAME|trim|upper|urlencode}",
+			expect: "VRISKA%20SERKET",
+		},
+		{
+			name:   "all transforms",
+			vars:   map[string]string{"V": "a?b~c>"},
+			input:  "${V|base64} ${V|base64url} ${V|urlencode} ${V|upper} ${V|lower} ${V|trim}",
+			expect: "YT9ifmM+ YT9ifmM- a%3Fb~c%3E A?B~C> a?b~c> a?b~c>",
+		},
+		{
+			name:   "transform names are case-insensitive",
+			vars:   map[string]string{"V": "Terezi"},
+			input:  "${V|LOWER}",
+			expect: "terezi",
+		},
+		{
+			name:   "transform of nested value applies to expanded value",
+			vars:   map[string]string{"USER": "vriska", "PASS": "8675309", "CREDS": "${USER}:${PASS}"},
+			input:  "${CREDS|base64}",
+			expect: "dnJpc2thOjg2NzUzMDk=",
+		},
+		{
+			name:   "transform in a nested reference",
+			vars:   map[string]string{"USER": "vriska serket", "URL_PATH": "/users/${USER|urlencode}"},
+			input:  "${URL_PATH}",
+			expect: "/users/vriska%20serket",
+		},
+		{
+			name:   "transform on dynamic var",
+			input:  "${@randint:5,5|base64}",
+			expect: "NQ==",
+		},
+		{
+			name:   "escaped reference with transform is left as-is",
+			input:  "$${V|upper}",
+			expect: "$${V|upper}",
+		},
+		{
+			name:      "unknown transform",
+			vars:      map[string]string{"V": "x"},
+			input:     "${V|rot13}",
+			expectErr: `variable V: unknown transform "rot13"`,
+		},
+		{
+			name:      "missing nested var",
+			vars:      map[string]string{"URL_PATH": "${BASE}/users"},
+			input:     "${URL_PATH}",
+			expectErr: "variable BASE not found (referenced by URL_PATH)",
+		},
+		{
+			name:      "self reference",
+			vars:      map[string]string{"A": "${A}"},
+			input:     "${A}",
+			expectErr: "variable reference cycle: A -> A",
+		},
+		{
+			name:      "indirect cycle",
+			vars:      map[string]string{"A": "${B}", "B": "${A}"},
+			input:     "${A}",
+			expectErr: "variable reference cycle: A -> B -> A",
+		},
+		{
+			name: "too deep",
+			vars: map[string]string{
+				"V0": "${V1}", "V1": "${V2}", "V2": "${V3}", "V3": "${V4}", "V4": "${V5}", "V5": "${V6}",
+				"V6": "${V7}", "V7": "${V8}", "V8": "${V9}", "V9": "${V10}", "V10": "${V11}", "V11": "end",
+			},
+			input:     "${V0}",
+			expectErr: "variable V0: references nested more than 10 deep",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			client := NewRESTClient(0, nil)
+			if tc.vars != nil {
+				client.Vars = tc.vars
+			}
+			client.VarOverrides = tc.overrides
+			client.Unexpanded = tc.unexpanded
+
+			actual, err := client.Substitute(tc.input)
+			if tc.expectErr != "" {
+				assert.EqualError(err, tc.expectErr)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_RESTClient_Substitute_EnvFallback(t *testing.T) {
+	t.Setenv("MORC_TEST_TOKEN", "env-token")
+	t.Setenv("MORC_TEST_HOST", "env.example.com")
+
+	testCases := []struct {
+		name        string
+		vars        map[string]string
+		envFallback bool
+		input       string
+		expect      string
+		expectErr   string
+	}{
+		{
+			name:      "off by default",
+			input:     "${MORC_TEST_TOKEN}",
+			expectErr: "variable MORC_TEST_TOKEN not found",
+		},
+		{
+			name:        "var taken from environment",
+			envFallback: true,
+			input:       "Bearer ${MORC_TEST_TOKEN}",
+			expect:      "Bearer env-token",
+		},
+		{
+			name:        "project var takes precedence",
+			vars:        map[string]string{"MORC_TEST_HOST": "example.com"},
+			envFallback: true,
+			input:       "${MORC_TEST_HOST}",
+			expect:      "example.com",
+		},
+		{
+			name:        "nested reference to environment",
+			vars:        map[string]string{"AUTH": "Bearer ${MORC_TEST_TOKEN}"},
+			envFallback: true,
+			input:       "${AUTH}",
+			expect:      "Bearer env-token",
+		},
+		{
+			name:        "not in environment either",
+			envFallback: true,
+			input:       "${MORC_TEST_NOT_SET}",
+			expectErr:   "variable MORC_TEST_NOT_SET not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			client := NewRESTClient(0, nil)
+			if tc.vars != nil {
+				client.Vars = tc.vars
+			}
+			client.EnvFallback = tc.envFallback
+
+			actual, err := client.Substitute(tc.input)
+			if tc.expectErr != "" {
+				assert.EqualError(err, tc.expectErr)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_RESTClient_Substitute_DynamicVars(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expectMatch string // regex that the entire output must match
+		expectErr   string
+	}{
+		{
+			name:        "uuid",
+			input:       "id=${@uuid}",
+			expectMatch: `^id=[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+		},
+		{
+			name:        "now",
+			input:       "${@now}",
+			expectMatch: `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`,
+		},
+		{
+			name:        "unix",
+			input:       "ts=${@unix}",
+			expectMatch: `^ts=\d+$`,
+		},
+		{
+			name:        "randint",
+			input:       "${@randint:3,5}",
+			expectMatch: `^[345]$`,
+		},
+		{
+			name:        "randint up to max int",
+			input:       "${@randint:0,9223372036854775807}",
+			expectMatch: `^\d+$`,
+		},
+		{
+			name:        "randint over every int",
+			input:       "${@randint:-9223372036854775808,9223372036854775807}",
+			expectMatch: `^-?\d+$`,
+		},
+		{
+			name:        "randint with single value",
+			input:       "${@randint:-7,-7}",
+			expectMatch: `^-7$`,
+		},
+		{
+			name:        "name is case-insensitive",
+			input:       "${@UNIX}",
+			expectMatch: `^\d+$`,
+		},
+		{
+			name:        "used within a stored var",
+			input:       "${TRACE}",
+			expectMatch: `^trace-\d+$`,
+		},
+		{
+			name:      "unknown dynamic var",
+			input:     "${@random}",
+			expectErr: "unknown dynamic var @random",
+		},
+		{
+			name:      "args given to no-arg var",
+			input:     "${@uuid:4}",
+			expectErr: "dynamic var @uuid does not take arguments",
+		},
+		{
+			name:      "randint without args",
+			input:     "${@randint}",
+			expectErr: "dynamic var @randint requires arguments in form @randint:MIN,MAX",
+		},
+		{
+			name:      "randint with bad bound",
+			input:     "${@randint:1,x}",
+			expectErr: `dynamic var @randint: MAX is not a valid integer: "x"`,
+		},
+		{
+			name:      "randint with reversed bounds",
+			input:     "${@randint:10,1}",
+			expectErr: "dynamic var @randint: MIN 10 is greater than MAX 1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			client := NewRESTClient(0, nil)
+			client.Vars["TRACE"] = "trace-${@unix}"
+
+			actual, err := client.Substitute(tc.input)
+			if tc.expectErr != "" {
+				assert.EqualError(err, tc.expectErr)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Regexp(tc.expectMatch, actual)
+		})
+	}
+}
+
+func Test_RESTClient_Substitute_DynamicVarsAreFresh(t *testing.T) {
+	assert := assert.New(t)
+
+	client := NewRESTClient(0, nil)
+
+	first, err := client.Substitute("${@uuid}")
+	if !assert.NoError(err) {
+		return
+	}
+	second, err := client.Substitute("${@uuid}")
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.NotEqual(first, second)
+	assert.Empty(client.Vars)
+}
+
+func Test_FormField_Encode(t *testing.T) {
+	testCases := []struct {
+		name   string
+		field  FormField
+		expect string
+	}{
+		{
+			name:   "plain value",
+			field:  FormField{Name: "user", Value: "vriska"},
+			expect: "user=vriska",
+		},
+		{
+			name:   "value only",
+			field:  FormField{Value: "hello world"},
+			expect: "hello%20world",
+		},
+		{
+			name:   "spaces are not encoded as plus",
+			field:  FormField{Name: "q", Value: "a b+c"},
+			expect: "q=a%20b%2Bc",
+		},
+		{
+			name:   "reserved characters",
+			field:  FormField{Name: "data", Value: "a=b&c/d?e#f%g@h"},
+			expect: "data=a%3Db%26c%2Fd%3Fe%23f%25g%40h",
+		},
+		{
+			name:   "unreserved characters are left alone",
+			field:  FormField{Name: "data", Value: "AZaz09-._~"},
+			expect: "data=AZaz09-._~",
+		},
+		{
+			name:   "multi-byte characters and newlines",
+			field:  FormField{Name: "msg", Value: "né\n"},
+			expect: "msg=n%C3%A9%0A",
+		},
+		{
+			name:   "name is not encoded",
+			field:  FormField{Name: "a b", Value: "c d"},
+			expect: "a b=c%20d",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.field.Encode())
+		})
+	}
+}
+
+func Test_Send_Form(t *testing.T) {
+	testCases := []struct {
+		name              string
+		body              []byte
+		headers           http.Header
+		form              []FormField
+		vars              map[string]string
+		expectBody        string
+		expectContentType string
+	}{
+		{
+			name:              "form only",
+			form:              []FormField{{Name: "name", Value: "Vriska Serket"}, {Value: "8&8"}},
+			expectBody:        "name=Vriska%20Serket&8%268",
+			expectContentType: FormContentType,
+		},
+		{
+			name:              "joined to body",
+			body:              []byte("a=1"),
+			form:              []FormField{{Name: "b", Value: "2 3"}},
+			expectBody:        "a=1&b=2%203",
+			expectContentType: FormContentType,
+		},
+		{
+			name:              "vars are substituted before encoding",
+			form:              []FormField{{Name: "${FIELD}", Value: "${NAME} & co"}},
+			vars:              map[string]string{"FIELD": "who", "NAME": "Terezi Pyrope"},
+			expectBody:        "who=Terezi%20Pyrope%20%26%20co",
+			expectContentType: FormContentType,
+		},
+		{
+			name:              "existing content type is kept",
+			headers:           http.Header{"Content-Type": []string{"text/plain"}},
+			form:              []FormField{{Name: "a", Value: "b"}},
+			expectBody:        "a=b",
+			expectContentType: "text/plain",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotBody, gotContentType string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				gotBody = string(data)
+				gotContentType = r.Header.Get("Content-Type")
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			_, err := Send("POST", srv.URL, "$", SendOptions{
+				Client:  srv.Client(),
+				Body:    tc.body,
+				Headers: tc.headers,
+				Form:    tc.form,
+				Vars:    tc.vars,
+			})
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectBody, gotBody)
+			assert.Equal(tc.expectContentType, gotContentType)
+		})
+	}
+}
+
+func Test_Send_JSONFields(t *testing.T) {
+	testCases := []struct {
+		name              string
+		body              []byte
+		headers           http.Header
+		fields            []JSONField
+		vars              map[string]string
+		expectBody        string
+		expectContentType string
+		expectErr         string
+	}{
+		{
+			name:              "fields only, with inferred types",
+			fields:            []JSONField{{Key: "name", Value: "VRISKA"}, {Key: "age", Value: "13"}, {Key: "troll", Value: "true"}, {Key: "lusus", Value: "null"}},
+			expectBody:        `{"age":13,"lusus":null,"name":"VRISKA","troll":true}`,
+			expectContentType: JSONContentType,
+		},
+		{
+			name:              "merged into body",
+			body:              []byte(`{"name": "Vriska", "id": 8888888888888888}`),
+			headers:           http.Header{"Content-Type": []string{"application/vnd.api+json"}},
+			fields:            []JSONField{{Key: "name", Value: "VRISKA"}},
+			expectBody:        `{"id":8888888888888888,"name":"VRISKA"}`,
+			expectContentType: "application/vnd.api+json",
+		},
+		{
+			name:              "raw values are used as-is",
+			fields:            []JSONField{{Key: "age", Value: `"13"`, Raw: true}, {Key: "dice", Value: `[8, 8]`, Raw: true}, {Key: "quoted", Value: `"13"`}},
+			expectBody:        `{"age":"13","dice":[8,8],"quoted":"\"13\""}`,
+			expectContentType: JSONContentType,
+		},
+		{
+			name:              "vars are substituted before types are inferred",
+			body:              []byte(`{"id": ${ID}}`),
+			fields:            []JSONField{{Key: "${FIELD}", Value: "${AGE}"}},
+			vars:              map[string]string{"ID": "413", "FIELD": "age", "AGE": "13"},
+			expectBody:        `{"age":13,"id":413}`,
+			expectContentType: JSONContentType,
+		},
+		{
+			name:      "body is not an object",
+			body:      []byte(`[1, 2]`),
+			fields:    []JSONField{{Key: "a", Value: "b"}},
+			expectErr: "set JSON fields: body is not a JSON object",
+		},
+		{
+			name:      "invalid raw value",
+			fields:    []JSONField{{Key: "a", Value: "{", Raw: true}},
+			expectErr: `set JSON fields: field #1: "{" is not valid JSON`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotBody, gotContentType string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				gotBody = string(data)
+				gotContentType = r.Header.Get("Content-Type")
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			_, err := Send("PATCH", srv.URL, "$", SendOptions{
+				Client:     srv.Client(),
+				Body:       tc.body,
+				Headers:    tc.headers,
+				JSONFields: tc.fields,
+				Vars:       tc.vars,
+			})
+			if tc.expectErr != "" {
+				assert.EqualError(err, tc.expectErr)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectBody, gotBody)
+			assert.Equal(tc.expectContentType, gotContentType)
+		})
+	}
+}
+
+func Test_Send_BaseURL(t *testing.T) {
+	testCases := []struct {
+		name       string
+		url        string
+		baseURL    string
+		vars       map[string]string
+		expectPath string
+	}{
+		{
+			name:       "relative path is joined to base URL",
+			url:        "/users/8",
+			baseURL:    "${SERVER}/api/",
+			expectPath: "/api/users/8",
+		},
+		{
+			name:       "vars in path are substituted before joining",
+			url:        "${USERS}/8",
+			baseURL:    "${SERVER}/api",
+			vars:       map[string]string{"USERS": "/users"},
+			expectPath: "/api/users/8",
+		},
+		{
+			name:       "absolute URL ignores base URL",
+			url:        "${SERVER}/users/8",
+			baseURL:    "http://example.invalid/api",
+			expectPath: "/users/8",
+		},
+		{
+			name:       "no base URL",
+			url:        "${SERVER}/users/8",
+			expectPath: "/users/8",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var gotPath string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			vars := map[string]string{"SERVER": srv.URL}
+			for k, v := range tc.vars {
+				vars[k] = v
+			}
+
+			_, err := Send("GET", tc.url, "$", SendOptions{
+				Client:  srv.Client(),
+				BaseURL: tc.baseURL,
+				Vars:    vars,
+			})
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectPath, gotPath)
+		})
+	}
+}
+
+func Test_HeaderAssertion_Check(t *testing.T) {
+	headers := http.Header{
+		"Content-Type": {"application/json; charset=utf-8"},
+		"Vary":         {"Accept", "Accept-Encoding"},
+	}
+
+	testCases := []struct {
+		name      string
+		assertion HeaderAssertion
+		expectErr string
+	}{
+		{
+			name:      "exact match",
+			assertion: HeaderAssertion{Key: "Content-Type", Value: "application/json; charset=utf-8"},
+		},
+		{
+			name:      "exact mismatch",
+			assertion: HeaderAssertion{Key: "Content-Type", Value: "application/json"},
+			expectErr: `header Content-Type: expected "application/json", got "application/json; charset=utf-8"`,
+		},
+		{
+			name:      "key is case-insensitive",
+			assertion: HeaderAssertion{Key: "CONTENT-type", Value: "application/json", Match: HeaderMatchContains},
+		},
+		{
+			name:      "contains mismatch",
+			assertion: HeaderAssertion{Key: "Content-Type", Value: "xml", Match: HeaderMatchContains},
+			expectErr: `header Content-Type: expected value containing "xml", got "application/json; charset=utf-8"`,
+		},
+		{
+			name:      "regex match",
+			assertion: HeaderAssertion{Key: "Content-Type", Value: `^application/json\b`, Match: HeaderMatchRegex},
+		},
+		{
+			name:      "regex mismatch",
+			assertion: HeaderAssertion{Key: "Content-Type", Value: `^text/`, Match: HeaderMatchRegex},
+			expectErr: `header Content-Type: expected value matching "^text/", got "application/json; charset=utf-8"`,
+		},
+		{
+			name:      "invalid regex",
+			assertion: HeaderAssertion{Key: "Content-Type", Value: `(`, Match: HeaderMatchRegex},
+			expectErr: "header Content-Type: invalid regex \"(\": error parsing regexp: missing closing ): `(`",
+		},
+		{
+			name:      "any of multiple values matches",
+			assertion: HeaderAssertion{Key: "Vary", Value: "Accept-Encoding"},
+		},
+		{
+			name:      "none of multiple values match",
+			assertion: HeaderAssertion{Key: "Vary", Value: "Origin"},
+			expectErr: `header Vary: expected "Origin", got "Accept", "Accept-Encoding"`,
+		},
+		{
+			name:      "missing header",
+			assertion: HeaderAssertion{Key: "X-Request-Id", Value: "1"},
+			expectErr: `header X-Request-Id: expected "1", but it is not present`,
+		},
+		{
+			name:      "present",
+			assertion: HeaderAssertion{Key: "vary", Present: true},
+		},
+		{
+			name:      "not present",
+			assertion: HeaderAssertion{Key: "x-request-id", Present: true},
+			expectErr: "header X-Request-Id: expected to be present, but it is not",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.assertion.Check(headers)
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_ParseJSONAssertion(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		expect    JSONAssertion
+		expectErr string
+	}{
+		{
+			name:   "exists",
+			input:  ".data.id",
+			expect: JSONAssertion{Path: []TraversalStep{{Key: "data"}, {Key: "id"}}, Op: AssertExists},
+		},
+		{
+			name:   "equal to number",
+			input:  ".count == 5",
+			expect: JSONAssertion{Path: []TraversalStep{{Key: "count"}}, Op: AssertEqual, Value: float64(5)},
+		},
+		{
+			name:   "not equal to null without spaces",
+			input:  ".data.id!=null",
+			expect: JSONAssertion{Path: []TraversalStep{{Key: "data"}, {Key: "id"}}, Op: AssertNotEqual},
+		},
+		{
+			name:   "string with operator characters",
+			input:  `.items[0]."a=b" >= "x<y"`,
+			expect: JSONAssertion{Path: []TraversalStep{{Key: "items"}, {Index: 0}, {Key: "a=b"}}, Op: AssertGreaterEqual, Value: "x<y"},
+		},
+		{
+			name:   "root of body",
+			input:  ". == []",
+			expect: JSONAssertion{Op: AssertEqual, Value: []interface{}{}},
+		},
+		{
+			name:      "unknown operator",
+			input:     ".count =< 5",
+			expectErr: `unknown operator "=<"`,
+		},
+		{
+			name:      "value is not JSON",
+			input:     ".name == vriska",
+			expectErr: `value vriska is not a JSON literal such as 5, "text", true, or null`,
+		},
+		{
+			name:      "ordering a bool",
+			input:     ".ok > true",
+			expectErr: "> can only compare numbers or strings",
+		},
+		{
+			name:      "path without dot",
+			input:     "count == 5",
+			expectErr: `path "count" must start with '.' or '['`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := ParseJSONAssertion(tc.input)
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expect, actual)
+			}
+		})
+	}
+}
+
+func Test_JSONAssertion_Check(t *testing.T) {
+	body := []byte(`{"count": 3, "name": "vriska", "data": {"id": null, "tags": ["a", "b"]}}`)
+
+	testCases := []struct {
+		name      string
+		expr      string
+		expectErr string
+	}{
+		{name: "exists", expr: ".data.tags[1]"},
+		{name: "exists with null value", expr: ".data.id"},
+		{name: "does not exist", expr: ".data.owner", expectErr: `json .data.owner: expected to exist, but it does not (at .data.owner: key "owner" does not exist)`},
+		{name: "equal number", expr: ".count == 3"},
+		{name: "unequal number", expr: ".count == 5", expectErr: "json .count: expected == 5, got 3"},
+		{name: "equal string", expr: `.name == "vriska"`},
+		{name: "equal array", expr: `.data.tags == ["a", "b"]`},
+		{name: "null is not set", expr: ".data.id != null", expectErr: "json .data.id: expected != null, got null"},
+		{name: "missing is null", expr: ".data.owner == null"},
+		{name: "missing is reported", expr: ".data.owner != null", expectErr: "json .data.owner: expected != null, got missing"},
+		{name: "less than", expr: ".count < 5"},
+		{name: "greater or equal fails", expr: ".count >= 5", expectErr: "json .count: expected >= 5, got 3"},
+		{name: "string ordering", expr: `.name > "terezi"`},
+		{name: "cannot compare", expr: ".name < 5", expectErr: `json .name: cannot compare "vriska" to 5`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := ParseJSONAssertion(tc.expr)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			err = a.Check(body)
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("body is not JSON", func(t *testing.T) {
+		a, _ := ParseJSONAssertion(".count == 3")
+		assert.EqualError(t, a.Check([]byte("OK")), "json .count: body is not valid JSON")
+	})
+}
+
+func Test_Send_AssertHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("VRISKA"))
+	}))
+	defer srv.Close()
+
+	result, err := Send("GET", srv.URL, "$", SendOptions{
+		Client:   srv.Client(),
+		Captures: []VarScraper{{Name: "NAME"}},
+		AssertHeaders: []HeaderAssertion{
+			{Key: "Content-Type", Value: "text/plain"},
+			{Key: "Content-Type", Value: "application/json"},
+		},
+	})
+
+	var assertErr *AssertionError
+	if !assert.ErrorAs(err, &assertErr) {
+		return
+	}
+	assert.Equal([]string{`header Content-Type: expected "application/json", got "text/plain"`}, assertErr.Failures)
+
+	// the rest of the result is still available
+	if assert.NotNil(result.Response) {
+		assert.Equal(http.StatusOK, result.Response.StatusCode)
+	}
+	assert.Equal(map[string]string{"NAME": "VRISKA"}, result.Captures)
+}
+
+func Test_Send_AssertStatusAndBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer srv.Close()
+
+	t.Run("passing assertions", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var out bytes.Buffer
+		_, err := Send("GET", srv.URL, "$", SendOptions{
+			Client:             srv.Client(),
+			Output:             OutputControl{Writer: &out},
+			AssertStatus:       http.StatusOK,
+			AssertBodyContains: []string{`"ok"`},
+		})
+
+		assert.NoError(err)
+		assert.Contains(out.String(), `{"status": "ok"}`)
+	})
+
+	t.Run("every failure is reported", func(t *testing.T) {
+		assert := assert.New(t)
+
+		result, err := Send("GET", srv.URL, "$", SendOptions{
+			Client:             srv.Client(),
+			Output:             OutputControl{Writer: io.Discard},
+			AssertStatus:       http.StatusCreated,
+			AssertHeaders:      []HeaderAssertion{{Key: "Content-Type", Value: "text/plain"}},
+			AssertBodyContains: []string{"ok", "error"},
+		})
+
+		var assertErr *AssertionError
+		if !assert.ErrorAs(err, &assertErr) {
+			return
+		}
+		assert.Equal([]string{
+			`status: expected 201, got 200 OK`,
+			`header Content-Type: expected "text/plain", got "application/json"`,
+			`body: expected to contain "error", but it does not`,
+		}, assertErr.Failures)
+
+		// the body can still be read from the result
+		if assert.NotNil(result.Response) {
+			body, _ := io.ReadAll(result.Response.Body)
+			assert.Equal(`{"status": "ok"}`, string(body))
+		}
+	})
+
+	t.Run("body is not streamed when checked", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var bodyOut bytes.Buffer
+		_, err := Send("GET", srv.URL, "$", SendOptions{
+			Client:             srv.Client(),
+			Output:             OutputControl{Writer: io.Discard, BodyWriter: &bodyOut},
+			AssertBodyContains: []string{"missing"},
+		})
+
+		var assertErr *AssertionError
+		assert.ErrorAs(err, &assertErr)
+		assert.Equal(`{"status": "ok"}`, bodyOut.String())
+	})
+}
+
+func Test_Send_DeferCaptureErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"name": "VRISKA"}`))
+	}))
+	defer srv.Close()
+
+	caps := func() []VarScraper {
+		return []VarScraper{
+			{Name: "NAME", Steps: []TraversalStep{{Key: "name"}}},
+			{Name: "MISSING", Steps: []TraversalStep{{Key: "missing"}}},
+		}
+	}
+
+	t.Run("capture error stops send without option", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var out bytes.Buffer
+		_, err := Send("GET", srv.URL, "$", SendOptions{
+			Client:   srv.Client(),
+			Captures: caps(),
+			Output:   OutputControl{Writer: &out},
+		})
+
+		var capErr *CaptureError
+		assert.False(errors.As(err, &capErr))
+		assert.ErrorContains(err, "scrape MISSING")
+		assert.Empty(out.String())
+	})
+
+	t.Run("capture error is returned after output with option", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var out bytes.Buffer
+		result, err := Send("GET", srv.URL, "$", SendOptions{
+			Client:             srv.Client(),
+			Captures:           caps(),
+			Output:             OutputControl{Writer: &out},
+			DeferCaptureErrors: true,
+		})
+
+		var capErr *CaptureError
+		if !assert.ErrorAs(err, &capErr) {
+			return
+		}
+		assert.Len(capErr.Failures, 1)
+		assert.Contains(capErr.Failures[0], "scrape MISSING")
+		assert.Contains(out.String(), `{"name": "VRISKA"}`)
+		assert.Equal(map[string]string{"NAME": "VRISKA"}, result.Captures)
+	})
+
+	t.Run("capture and assertion errors are both returned", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := Send("GET", srv.URL, "$", SendOptions{
+			Client:             srv.Client(),
+			Captures:           caps(),
+			Output:             OutputControl{Writer: io.Discard},
+			DeferCaptureErrors: true,
+			AssertHeaders:      []HeaderAssertion{{Key: "Content-Type", Value: "text/plain"}},
+		})
+
+		var capErr *CaptureError
+		var assertErr *AssertionError
+		assert.ErrorAs(err, &capErr)
+		assert.ErrorAs(err, &assertErr)
+	})
+}
+
+func Test_Send_NoSubstituteHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(r.URL.Path + " " + r.Header.Get("X-Template")))
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		name    string
+		noSubst bool
+		expect  string
+	}{
+		{name: "headers are substituted by default", noSubst: false, expect: "/413 413"},
+		{name: "headers are sent verbatim", noSubst: true, expect: "/413 ${X}"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			result, err := Send("GET", srv.URL+"/${X}", "$", SendOptions{
+				Client:              srv.Client(),
+				Vars:                map[string]string{"X": "413"},
+				Headers:             http.Header{"X-Template": {"${X}"}},
+				Output:              OutputControl{Writer: io.Discard},
+				NoSubstituteHeaders: tc.noSubst,
+			})
+			if !assert.NoError(err) {
+				return
+			}
+
+			body, err := io.ReadAll(result.Response.Body)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, string(body))
+		})
+	}
+}
+
+func Test_Send_NoSubstitute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(r.URL.RawQuery + " " + r.Header.Get("X-Template") + " " + string(body)))
+	}))
+	defer srv.Close()
+
+	result, err := Send("POST", srv.URL+"/?q=${X}", "$", SendOptions{
+		Client:       srv.Client(),
+		Vars:         map[string]string{"X": "413"},
+		Headers:      http.Header{"X-Template": {"${X}"}},
+		Body:         []byte("${X}"),
+		Output:       OutputControl{Writer: io.Discard},
+		NoSubstitute: true,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	body, err := io.ReadAll(result.Response.Body)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "q=${X} ${X} ${X}", string(body))
+}
+
+func Test_OutputResponse_Stats(t *testing.T) {
+	timings := &Timings{Total: 342 * time.Millisecond}
+
+	testCases := []struct {
+		name    string
+		body    string
+		timings *Timings
+		opts    OutputControl
+		expect  string
+	}{
+		{
+			name:    "pretty",
+			body:    strings.Repeat("a", 1229),
+			timings: timings,
+			opts:    OutputControl{Stats: true, SuppressResponseBody: true},
+			expect:  "HTTP/1.1 200 OK\n200 OK · 1.2 KiB · 342ms\n",
+		},
+		{
+			name:    "pretty - after body",
+			body:    "VRISKA",
+			timings: timings,
+			opts:    OutputControl{Stats: true},
+			expect:  "HTTP/1.1 200 OK\nVRISKA\n200 OK · 6 B · 342ms\n",
+		},
+		{
+			name:   "pretty - no timings",
+			body:   "VRISKA",
+			opts:   OutputControl{Stats: true, SuppressResponseBody: true},
+			expect: "HTTP/1.1 200 OK\n200 OK · 6 B\n",
+		},
+		{
+			name:    "line format",
+			body:    "VRISKA",
+			timings: timings,
+			opts:    OutputControl{Stats: true, Format: FormatLine},
+			expect:  "HTTP/1.1 200 OK\nVRISKA\nSTATS status=200 bytes=6 elapsed_ms=342\n",
+		},
+		{
+			name:    "not requested",
+			body:    "VRISKA",
+			timings: timings,
+			opts:    OutputControl{SuppressResponseBody: true},
+			expect:  "HTTP/1.1 200 OK\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			resp := &http.Response{
+				Proto:      "HTTP/1.1",
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(tc.body)),
+			}
+
+			out := &bytes.Buffer{}
+			tc.opts.Writer = out
+
+			err := OutputResponse(resp, nil, tc.timings, tc.opts)
+
+			assert.NoError(err)
+			assert.Equal(tc.expect, out.String())
+		})
+	}
+}
+
+func Test_OutputResponse_JSON(t *testing.T) {
+	req := &http.Request{
+		Method: "POST",
+		URL:    &url.URL{Scheme: "http", Host: "example.com", Path: "/users"},
+		Header: http.Header{"Content-Type": {"application/json"}},
+	}
+
+	testCases := []struct {
+		name     string
+		body     string
+		caps     map[string]string
+		timings  *Timings
+		opts     OutputControl
+		noReq    bool
+		expect   string
+		expectBW string
+	}{
+		{
+			name:    "full result",
+			body:    `{"id": 8}`,
+			caps:    map[string]string{"ID": "8"},
+			timings: &Timings{DNS: 1500 * time.Microsecond, Total: 342 * time.Millisecond},
+			opts:    OutputControl{Format: FormatJSON},
+			expect: `{
+  "request": {
+    "method": "POST",
+    "url": "http://example.com/users",
+    "headers": {
+      "Content-Type": [
+        "application/json"
+      ]
+    }
+  },
+  "proto": "HTTP/1.1",
+  "status": "201 Created",
+  "status_code": 201,
+  "headers": {
+    "Location": [
+      "/users/8"
+    ]
+  },
+  "body": "{\"id\": 8}",
+  "captures": {
+    "ID": "8"
+  },
+  "timings": {
+    "dns_ms": 1.5,
+    "connect_ms": 0,
+    "tls_ms": 0,
+    "ttfb_ms": 0,
+    "total_ms": 342
+  }
+}
+`,
+		},
+		{
+			name:  "binary body without request or timings",
+			body:  "\xff\xfe",
+			opts:  OutputControl{Format: FormatJSON},
+			noReq: true,
+			expect: `{
+  "proto": "HTTP/1.1",
+  "status": "201 Created",
+  "status_code": 201,
+  "headers": {
+    "Location": [
+      "/users/8"
+    ]
+  },
+  "body": "//4=",
+  "body_encoding": "base64",
+  "captures": {}
+}
+`,
+		},
+		{
+			name:  "body suppressed",
+			body:  "VRISKA",
+			opts:  OutputControl{Format: FormatJSON, SuppressResponseBody: true},
+			noReq: true,
+			expect: `{
+  "proto": "HTTP/1.1",
+  "status": "201 Created",
+  "status_code": 201,
+  "headers": {
+    "Location": [
+      "/users/8"
+    ]
+  },
+  "body": null,
+  "captures": {}
+}
+`,
+		},
+		{
+			name:  "body written to body writer",
+			body:  "VRISKA",
+			opts:  OutputControl{Format: FormatJSON, BodyWriter: &bytes.Buffer{}},
+			noReq: true,
+			expect: `{
+  "proto": "HTTP/1.1",
+  "status": "201 Created",
+  "status_code": 201,
+  "headers": {
+    "Location": [
+      "/users/8"
+    ]
+  },
+  "body": null,
+  "captures": {}
+}
+`,
+			expectBW: "VRISKA",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			resp := &http.Response{
+				Proto:      "HTTP/1.1",
+				Status:     "201 Created",
+				StatusCode: http.StatusCreated,
+				Header:     http.Header{"Location": {"/users/8"}},
+				Body:       io.NopCloser(strings.NewReader(tc.body)),
+			}
+			if !tc.noReq {
+				resp.Request = req
+			}
+
+			out := &bytes.Buffer{}
+			tc.opts.Writer = out
+
+			err := OutputResponse(resp, tc.caps, tc.timings, tc.opts)
+
+			assert.NoError(err)
+			assert.Equal(tc.expect, out.String())
+			if tc.expectBW != "" {
+				assert.Equal(tc.expectBW, tc.opts.BodyWriter.(*bytes.Buffer).String())
+			}
+		})
+	}
+}
+
+func Test_OutputResponse_BodyOnly(t *testing.T) {
+	testCases := []struct {
+		name   string
+		body   string
+		opts   OutputControl
+		expect string
+	}{
+		{
+			name:   "body is output as-is",
+			body:   "VRISKA",
+			opts:   OutputControl{BodyOnly: true},
+			expect: "VRISKA",
+		},
+		{
+			name:   "other output is suppressed",
+			body:   "VRISKA",
+			opts:   OutputControl{BodyOnly: true, Headers: true, Captures: true, Stats: true, Format: FormatLine},
+			expect: "VRISKA",
+		},
+		{
+			name:   "no body",
+			opts:   OutputControl{BodyOnly: true},
+			expect: "",
+		},
+		{
+			name:   "body suppressed",
+			body:   "VRISKA",
+			opts:   OutputControl{BodyOnly: true, SuppressResponseBody: true},
+			expect: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			resp := &http.Response{
+				Proto:      "HTTP/1.1",
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"text/plain"}},
+				Body:       io.NopCloser(strings.NewReader(tc.body)),
+			}
+
+			out := &bytes.Buffer{}
+			tc.opts.Writer = out
+
+			err := OutputResponse(resp, map[string]string{"NAME": "VRISKA"}, &Timings{}, tc.opts)
+
+			assert.NoError(err)
+			assert.Equal(tc.expect, out.String())
+		})
+	}
+}
+
+func Test_OutputRequest_JSONOutputsNothing(t *testing.T) {
+	req := &http.Request{
+		Method: "GET",
+		URL:    &url.URL{Scheme: "http", Host: "example.com", Path: "/"},
+	}
+	out := &bytes.Buffer{}
+
+	err := OutputRequest(req, OutputControl{Format: FormatJSON, Request: true, Writer: out})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", out.String())
+}
+
+func Test_OutputRequest_Mask(t *testing.T) {
+	req, err := http.NewRequest("POST", "http://example.com/users?key=8b5f2c9e", strings.NewReader(`{"token":"8b5f2c9e"}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer 8b5f2c9e")
+	out := &bytes.Buffer{}
+
+	err = OutputRequest(req, OutputControl{Request: true, Writer: out, Mask: []string{"8b5f2c9e"}})
+
+	assert.NoError(t, err)
+	assert.NotContains(t, out.String(), "8b5f2c9e")
+	assert.Contains(t, out.String(), "Request URI: http://example.com/users?key=***\n")
+	assert.Contains(t, out.String(), "Authorization: Bearer ***\r\n")
+	assert.Contains(t, out.String(), `{"token":"***"}`)
+}
+
+func Test_MaskValues(t *testing.T) {
+	testCases := []struct {
+		name   string
+		s      string
+		values []string
+		expect string
+	}{
+		{
+			name:   "no values",
+			s:      "Bearer 8b5f2c9e",
+			expect: "Bearer 8b5f2c9e",
+		},
+		{
+			name:   "every occurrence is masked",
+			s:      "8b5f2c9e and 8b5f2c9e",
+			values: []string{"8b5f2c9e"},
+			expect: "*** and ***",
+		},
+		{
+			name:   "longer value masked in full",
+			s:      "pass=hunter2-extra",
+			values: []string{"hunter2", "hunter2-extra"},
+			expect: "pass=***",
+		},
+		{
+			name:   "empty value is ignored",
+			s:      "nothing secret",
+			values: []string{""},
+			expect: "nothing secret",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, MaskValues(tc.s, tc.values))
+		})
+	}
+}
+
+func Test_SecretForms(t *testing.T) {
+	testCases := []struct {
+		name   string
+		s      string
+		values []string
+		expect string
+	}{
+		{
+			name:   "raw value",
+			s:      "pass=hunter2&x",
+			values: []string{"hunter2&x"},
+			expect: "pass=***",
+		},
+		{
+			name:   "one transform",
+			s:      "Authorization: Basic aHVudGVyMiZ4, q=hunter2%26x",
+			values: []string{"hunter2&x"},
+			expect: "Authorization: Basic ***, q=***",
+		},
+		{
+			name:   "two transforms",
+			s:      "q=HUNTER2%26X",
+			values: []string{"hunter2&x"},
+			expect: "q=***",
+		},
+		{
+			name:   "empty value is ignored",
+			s:      "nothing secret",
+			values: []string{""},
+			expect: "nothing secret",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, MaskValues(tc.s, SecretForms(tc.values)))
+		})
+	}
+}
+
+func Test_formatByteSize(t *testing.T) {
+	testCases := []struct {
+		n      int
+		expect string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1229, "1.2 KiB"},
+		{5 * 1024 * 1024, "5.0 MiB"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expect, func(t *testing.T) {
+			assert.Equal(t, tc.expect, formatByteSize(tc.n))
+		})
+	}
+}
+
+func Test_Send_RetryOnBodyMatch(t *testing.T) {
+	testCases := []struct {
+		name         string
+		pendingCount int
+		retry        RetryOptions
+		expectCalls  int
+		expectBody   string
+	}{
+		{
+			name:         "no retry by default",
+			pendingCount: 2,
+			expectCalls:  1,
+			expectBody:   `{"status":"pending"}`,
+		},
+		{
+			name:         "retries until body no longer matches",
+			pendingCount: 2,
+			retry:        RetryOptions{BodyMatch: `"pending"`, Backoff: time.Millisecond},
+			expectCalls:  3,
+			expectBody:   `{"status":"done"}`,
+		},
+		{
+			name:         "no retry when first body does not match",
+			pendingCount: 0,
+			retry:        RetryOptions{BodyMatch: `"pending"`, Backoff: time.Millisecond},
+			expectCalls:  1,
+			expectBody:   `{"status":"done"}`,
+		},
+		{
+			name:         "last response is returned when attempts are exhausted",
+			pendingCount: 5,
+			retry:        RetryOptions{BodyMatch: `"pending"`, MaxAttempts: 2, Backoff: time.Millisecond},
+			expectCalls:  2,
+			expectBody:   `{"status":"pending"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			calls := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+
+				// the request body must be sent in full on every attempt
+				reqBody, _ := io.ReadAll(r.Body)
+				if string(reqBody) != "VRISKA" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				if calls <= tc.pendingCount {
+					_, _ = w.Write([]byte(`{"status":"pending"}`))
+				} else {
+					_, _ = w.Write([]byte(`{"status":"done"}`))
+				}
+			}))
+			defer srv.Close()
+
+			result, err := Send("POST", srv.URL, "$", SendOptions{
+				Client: srv.Client(),
+				Body:   []byte("VRISKA"),
+				Output: OutputControl{Writer: io.Discard},
+				Retry:  tc.retry,
+			})
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(tc.expectCalls, calls)
+			assert.Equal(http.StatusOK, result.Response.StatusCode)
+
+			body, err := io.ReadAll(result.Response.Body)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectBody, string(body))
+		})
+	}
+}
+
+func Test_OutputResponse_Head(t *testing.T) {
+	testCases := []struct {
+		name          string
+		method        string
+		contentLength int64
+		opts          OutputControl
+		expect        string
+	}{
+		{
+			name:          "GET with no body",
+			method:        http.MethodGet,
+			contentLength: 0,
+			expect:        "HTTP/1.1 200 OK\n(no response body)\n",
+		},
+		{
+			name:          "HEAD with known length",
+			method:        http.MethodHead,
+			contentLength: 1229,
+			expect:        "HTTP/1.1 200 OK\n(HEAD response; Content-Length: 1229)\n",
+		},
+		{
+			name:          "HEAD with unknown length",
+			method:        http.MethodHead,
+			contentLength: -1,
+			expect:        "HTTP/1.1 200 OK\n",
+		},
+		{
+			name:          "HEAD in line format",
+			method:        http.MethodHead,
+			contentLength: 1229,
+			opts:          OutputControl{Format: FormatLine},
+			expect:        "HTTP/1.1 200 OK\n",
+		},
+		{
+			name:          "HEAD stats use Content-Length",
+			method:        http.MethodHead,
+			contentLength: 1229,
+			opts:          OutputControl{Stats: true, SuppressResponseBody: true},
+			expect:        "HTTP/1.1 200 OK\n200 OK · 1.2 KiB\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			resp := &http.Response{
+				Proto:         "HTTP/1.1",
+				Status:        "200 OK",
+				StatusCode:    http.StatusOK,
+				Body:          http.NoBody,
+				ContentLength: tc.contentLength,
+				Request:       &http.Request{Method: tc.method},
+			}
+
+			out := &bytes.Buffer{}
+			tc.opts.Writer = out
+
+			err := OutputResponse(resp, nil, nil, tc.opts)
+
+			assert.NoError(err)
+			assert.Equal(tc.expect, out.String())
+		})
+	}
+}
+
+func Test_Send_BodyWriter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"name":"VRISKA"}`))
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		name             string
+		captures         []VarScraper
+		expectCaps       map[string]string
+		expectRespBody   string
+		expectRespLength int64
+	}{
+		{
+			name:             "streamed without captures",
+			expectCaps:       map[string]string{},
+			expectRespBody:   "",
+			expectRespLength: 17,
+		},
+		{
+			name:             "buffered with captures",
+			captures:         []VarScraper{{Name: "NAME", Steps: []TraversalStep{{Key: "name"}}}},
+			expectCaps:       map[string]string{"NAME": "VRISKA"},
+			expectRespBody:   `{"name":"VRISKA"}`,
+			expectRespLength: 17,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			out := &bytes.Buffer{}
+			bodyOut := &bytes.Buffer{}
+
+			result, err := Send("GET", srv.URL, "$", SendOptions{
+				Client:   srv.Client(),
+				Captures: tc.captures,
+				Output:   OutputControl{Writer: out, BodyWriter: bodyOut},
+			})
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal(`{"name":"VRISKA"}`, bodyOut.String())
+			assert.Equal("HTTP/1.1 200 OK\n", out.String())
+			assert.Equal(tc.expectCaps, result.Captures)
+			assert.Equal(tc.expectRespLength, result.Response.ContentLength)
+
+			body, err := io.ReadAll(result.Response.Body)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectRespBody, string(body))
+		})
+	}
+}
+
+func Test_Send_BodyWriter_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-headers" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			_, _ = w.Write([]byte("chunk;"))
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("slow body is not cut off", func(t *testing.T) {
+		assert := assert.New(t)
+
+		bodyOut := &bytes.Buffer{}
+		_, err := Send("GET", srv.URL+"/slow-body", "$", SendOptions{
+			Client:  srv.Client(),
+			Timeout: 150 * time.Millisecond,
+			Output:  OutputControl{Writer: io.Discard, BodyWriter: bodyOut},
+		})
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal("chunk;chunk;chunk;", bodyOut.String())
+	})
+
+	t.Run("slow headers time out", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := Send("GET", srv.URL+"/slow-headers", "$", SendOptions{
+			Client:  srv.Client(),
+			Timeout: 150 * time.Millisecond,
+			Output:  OutputControl{Writer: io.Discard, BodyWriter: &bytes.Buffer{}},
+		})
+		if assert.Error(err) {
+			assert.Contains(err.Error(), "no response within 150ms")
+		}
+	})
+
+	t.Run("failed write is reported as such", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := Send("GET", srv.URL+"/slow-body", "$", SendOptions{
+			Client: srv.Client(),
+			Output: OutputControl{Writer: io.Discard, BodyWriter: failingWriter{}},
+		})
+		if assert.Error(err) {
+			assert.Contains(err.Error(), "write response body")
+		}
+	})
+}
+
+// failingWriter is an io.Writer whose every write fails.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
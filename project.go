@@ -12,6 +12,9 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -22,6 +25,11 @@ import (
 const (
 	ProjDirVar = "::PROJ_DIR::"
 
+	// DefaultEnvName is the reserved name that refers to the default
+	// environment wherever "" would instead mean the current one, such as in
+	// VarScraper.Env.
+	DefaultEnvName = "<DEFAULT>"
+
 	DefaultProjectPath = ".morc/project.json"
 	DefaultSessionPath = ProjDirVar + "/session.json"
 	DefaultHistoryPath = ProjDirVar + "/history.json"
@@ -33,17 +41,66 @@ const (
 	CurFileVersion = 1
 )
 
+// DefaultRequestTimeout is the request timeout used by a project that does not
+// have one set.
+const DefaultRequestTimeout = 30 * time.Second
+
+// DefaultCookieLifetime is the lifetime of cookie records used by a project
+// that does not have one set.
+const DefaultCookieLifetime = 24 * time.Hour
+
+// DefaultVarPrefix is the variable prefix used by a project that does not have
+// one set.
+const DefaultVarPrefix = "$"
+
 type Settings struct {
 	ProjFile       string        `json:"-"`
 	HistFile       string        `json:"history_file"`
 	SeshFile       string        `json:"session_file"`
 	CookieLifetime time.Duration `json:"cookie_lifetime"`
-	RecordHistory  bool          `json:"record_history"`
-	RecordSession  bool          `json:"record_cookies"`
+
+	// RequestTimeout could be 0 if not set. To get the default when not set,
+	// use Project.RequestTimeout() instead.
+	RequestTimeout time.Duration `json:"request_timeout"`
+
+	RecordHistory bool `json:"record_history"`
+	RecordSession bool `json:"record_cookies"`
 
 	// VarPrefix could be empty if not set. To get the default when not set,
 	// use Project.VarPrefix() instead.
 	VarPrefix string `json:"var_prefix"`
+
+	// DefaultHeaders is headers that are sent with every request template in
+	// the project. A template's own headers take precedence over any default
+	// header with the same key.
+	DefaultHeaders http.Header `json:"default_headers,omitempty"`
+
+	// CompactFiles is whether the project, history, and session files are
+	// written as compact single-line JSON instead of indented JSON.
+	CompactFiles bool `json:"compact_files,omitempty"`
+
+	// EnvFallback is whether a var that is not defined in the project is taken
+	// from the OS environment variable of the same name when a request is
+	// sent.
+	EnvFallback bool `json:"env_fallback,omitempty"`
+
+	// MaxHistoryEntries is the most entries that history is allowed to hold.
+	// When adding an entry with Project.AddHistory would go over it, the
+	// oldest entries are dropped. If 0 or less, history is unlimited.
+	MaxHistoryEntries int `json:"max_history_entries,omitempty"`
+
+	// ClientCertFile is the path to a PEM-encoded client certificate that is
+	// presented to servers that request one, for mutual TLS. If ClientKeyFile
+	// is not set, the private key is read from this file as well.
+	ClientCertFile string `json:"client_cert,omitempty"`
+
+	// ClientKeyFile is the path to the PEM-encoded private key of the client
+	// certificate in ClientCertFile.
+	ClientKeyFile string `json:"client_key,omitempty"`
+
+	// CACertFile is the path to a PEM bundle of CA certificates that are used
+	// to verify servers in place of the system's.
+	CACertFile string `json:"ca_cert,omitempty"`
 }
 
 // HistoryFSPath returns the file-system compatible path to the history file. If
@@ -51,24 +108,7 @@ type Settings struct {
 // the project file is in. If s.HistFile is empty, or if s.ProjFile is referred
 // to with ProjDirVar and is itself empty, this will return the empty string.
 func (s Settings) HistoryFSPath() string {
-	if strings.Contains(s.HistFile, ProjDirVar) {
-		if s.ProjFile == "" {
-			return ""
-		}
-
-		projDir := filepath.Dir(s.ProjFile)
-
-		fullDir := strings.ReplaceAll(s.HistFile, ProjDirVar, projDir)
-		if fullDir == projDir {
-			// if it is ONLY the proj dir, that is not valid. return empty
-			// string
-			return ""
-		}
-
-		return fullDir
-	}
-
-	return s.HistFile
+	return s.fsPath(s.HistFile)
 }
 
 // SessionFSPath returns the file-system compatible path to the session file. If
@@ -76,14 +116,30 @@ func (s Settings) HistoryFSPath() string {
 // the project file is in. If s.SeshFile is empty, or if s.ProjFile is referred
 // to with ProjDirVar and is itself empty, this will return the empty string.
 func (s Settings) SessionFSPath() string {
-	if strings.Contains(s.SeshFile, ProjDirVar) {
-		if s.ProjFile == "" {
+	return s.fsPath(s.SeshFile)
+}
+
+// fsPath returns the file-system compatible version of path, with ProjDirVar
+// replaced with the directory that the project file is in. See
+// ExpandProjDir for details.
+func (s Settings) fsPath(path string) string {
+	return ExpandProjDir(path, s.ProjFile)
+}
+
+// ExpandProjDir returns the file-system compatible version of path, with
+// ProjDirVar replaced with the directory that projFile is in. If path refers to
+// ProjDirVar and projFile is empty, or if path is ONLY the project directory,
+// this will return the empty string. If path does not contain ProjDirVar, it is
+// returned unchanged.
+func ExpandProjDir(path, projFile string) string {
+	if strings.Contains(path, ProjDirVar) {
+		if projFile == "" {
 			return ""
 		}
 
-		projDir := filepath.Dir(s.ProjFile)
+		projDir := filepath.Dir(projFile)
 
-		fullDir := strings.ReplaceAll(s.SeshFile, ProjDirVar, projDir)
+		fullDir := strings.ReplaceAll(path, ProjDirVar, projDir)
 		if fullDir == projDir {
 			// if it is ONLY the proj dir, that is not valid. return empty
 			// string
@@ -93,7 +149,7 @@ func (s Settings) SessionFSPath() string {
 		return fullDir
 	}
 
-	return s.SeshFile
+	return path
 }
 
 type Project struct {
@@ -106,16 +162,78 @@ type Project struct {
 	Config    Settings
 }
 
+// Copy returns a deep copy of p that shares no memory with it, so that either
+// can be modified without affecting the other. The entries in its history and
+// the calls in its session are not modified in place by anything, so only the
+// lists of them are copied.
+func (p Project) Copy() Project {
+	c := p
+	if p.Templates != nil {
+		c.Templates = make(map[string]RequestTemplate, len(p.Templates))
+		for k, v := range p.Templates {
+			c.Templates[k] = v.Copy()
+		}
+	}
+	if p.Flows != nil {
+		c.Flows = make(map[string]Flow, len(p.Flows))
+		for k, v := range p.Flows {
+			c.Flows[k] = v.Copy()
+		}
+	}
+	c.Vars = p.Vars.Copy()
+	if p.History != nil {
+		c.History = make([]HistoryEntry, len(p.History))
+		copy(c.History, p.History)
+	}
+	if p.Session.Cookies != nil {
+		c.Session.Cookies = make([]SetCookiesCall, len(p.Session.Cookies))
+		copy(c.Session.Cookies, p.Session.Cookies)
+	}
+	c.Config.DefaultHeaders = p.Config.DefaultHeaders.Clone()
+	return c
+}
+
 func (p Project) WithConfig(cfg Settings) Project {
 	p.Config = cfg
 	return p
 }
 
-func (p Project) VarPrefix() string {
-	if p.Config.VarPrefix != "" {
-		return p.Config.VarPrefix
+// EffectiveConfig returns the settings of the project as they are actually
+// used when sending requests. Every setting that is not set is given its
+// default, and the history, session, and certificate file paths are resolved
+// against the directory of the project file. All other settings are returned
+// as-is.
+func (p Project) EffectiveConfig() Settings {
+	cfg := p.Config
+
+	if cfg.VarPrefix == "" {
+		cfg.VarPrefix = DefaultVarPrefix
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = DefaultRequestTimeout
+	}
+	if cfg.CookieLifetime <= 0 {
+		cfg.CookieLifetime = DefaultCookieLifetime
 	}
-	return "$"
+	cfg.HistFile = cfg.HistoryFSPath()
+	cfg.SeshFile = cfg.SessionFSPath()
+	cfg.ClientCertFile = cfg.fsPath(cfg.ClientCertFile)
+	cfg.ClientKeyFile = cfg.fsPath(cfg.ClientKeyFile)
+	cfg.CACertFile = cfg.fsPath(cfg.CACertFile)
+
+	return cfg
+}
+
+// VarPrefix returns the variable prefix of the project. If it is not set,
+// DefaultVarPrefix is returned.
+func (p Project) VarPrefix() string {
+	return p.EffectiveConfig().VarPrefix
+}
+
+// RequestTimeout returns the timeout of requests sent from the project. If it
+// is not set to a positive duration, DefaultRequestTimeout is returned.
+func (p Project) RequestTimeout() time.Duration {
+	return p.EffectiveConfig().RequestTimeout
 }
 
 // Dump writes the contents of the project in "project-file" format to the given
@@ -132,7 +250,7 @@ func (p Project) Dump(w io.Writer) error {
 		Config:    p.Config,
 	}
 
-	projDataBytes, err := json.MarshalIndent(m, "", "  ")
+	projDataBytes, err := marshalFileData(m, p.Config.CompactFiles)
 	if err != nil {
 		return fmt.Errorf("marshal project data: %w", err)
 	}
@@ -163,6 +281,20 @@ func (p Project) CookiesForURL(u *url.URL) []*http.Cookie {
 	return client.jar.Cookies(u)
 }
 
+// AddHistory appends entry to the history of the project. If this puts the
+// history over p.Config.MaxHistoryEntries, the oldest entries are dropped until
+// it is at the cap.
+func (p *Project) AddHistory(entry HistoryEntry) {
+	p.History = append(p.History, entry)
+
+	max := p.Config.MaxHistoryEntries
+	if max > 0 && len(p.History) > max {
+		// copy to a new slice so the dropped entries are not kept alive by
+		// the backing array
+		p.History = append([]HistoryEntry(nil), p.History[len(p.History)-max:]...)
+	}
+}
+
 // EvictOldCookies immediately applies the eviction of old cookie sets using the
 // project's current cookie lifetime. If the project has not loaded its session,
 // or if the session has no cookies, this method will do nothing.
@@ -230,6 +362,88 @@ func (p Project) IsExecableFlow(name string) bool {
 	return true
 }
 
+// UnusedVars returns the names of the variables that are defined in any
+// environment of p but are never referenced by p, sorted. A variable is
+// referenced if it is used in the URL, headers, or body of a request template,
+// in a default header, in the base URL of an environment, or in the value of
+// another variable that is itself referenced. Files that bodies are read from
+// are not checked.
+func (p Project) UnusedVars() []string {
+	prefix := p.VarPrefix()
+
+	used := map[string]bool{}
+	var pending []string
+	addRefs := func(refTmpl RequestTemplate) {
+		for _, name := range refTmpl.ReferencedVars(prefix) {
+			if !used[name] {
+				used[name] = true
+				pending = append(pending, name)
+			}
+		}
+	}
+
+	for _, tmpl := range p.Templates {
+		addRefs(tmpl)
+	}
+	addRefs(RequestTemplate{Headers: p.Config.DefaultHeaders})
+	for _, u := range p.Vars.baseURLs {
+		addRefs(RequestTemplate{URL: u})
+	}
+
+	// vars referred to by the value of a used var are used as well
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		for _, env := range p.Vars.envs {
+			if val, ok := env[name]; ok {
+				addRefs(RequestTemplate{URL: val})
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	var unused []string
+	for _, env := range p.Vars.envs {
+		for name := range env {
+			if !used[name] && !seen[name] {
+				seen[name] = true
+				unused = append(unused, name)
+			}
+		}
+	}
+	sort.Strings(unused)
+
+	return unused
+}
+
+// RenameVarInConfig changes every reference to the variable oldName in the
+// default headers of p and in the base URL of each environment to refer to
+// newName instead. It returns whether the default headers were changed and the
+// upper-case names of the environments whose base URLs were, sorted; the
+// default environment is the empty string.
+func (p *Project) RenameVarInConfig(oldName, newName string) (bool, []string) {
+	prefix := p.VarPrefix()
+	oldName = strings.ToUpper(oldName)
+	newName = strings.ToUpper(newName)
+
+	headers, headersChanged := renameHeaderVarRefs(p.Config.DefaultHeaders, prefix, oldName, newName)
+	if headersChanged {
+		p.Config.DefaultHeaders = headers
+	}
+
+	var envs []string
+	for env, u := range p.Vars.baseURLs {
+		if renamed, changed := RenameVarRefs(u, prefix, oldName, newName); changed {
+			p.Vars.baseURLs[env] = renamed
+			envs = append(envs, env)
+		}
+	}
+	sort.Strings(envs)
+
+	return headersChanged, envs
+}
+
 // DumpHistory writes the contents of the history in "history-file" format to
 // the given io.Writer.
 func (p Project) DumpHistory(w io.Writer) error {
@@ -239,7 +453,7 @@ func (p Project) DumpHistory(w io.Writer) error {
 		Entries:  p.History,
 	}
 
-	histDataBytes, err := json.MarshalIndent(m, "", "  ")
+	histDataBytes, err := marshalFileData(m, p.Config.CompactFiles)
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
@@ -252,6 +466,21 @@ func (p Project) DumpHistory(w io.Writer) error {
 	return nil
 }
 
+// DumpSession writes the contents of the session in "session-file" format to
+// the given io.Writer, formatted according to the project's settings.
+func (p Project) DumpSession(w io.Writer) error {
+	return p.Session.DumpFormatted(w, p.Config.CompactFiles)
+}
+
+// marshalFileData encodes v as the JSON contents of a MORC file. If compact is
+// set, it is encoded on a single line; otherwise it is indented.
+func marshalFileData(v interface{}, compact bool) ([]byte, error) {
+	if compact {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
 func (p Project) PersistHistoryToDisk() error {
 	histPath := p.Config.HistoryFSPath()
 	if histPath == "" {
@@ -267,7 +496,7 @@ func (p Project) PersistSessionToDisk() error {
 		return fmt.Errorf("session file path is not set")
 	}
 
-	return dumpToFile(seshPath, p.Session.Dump)
+	return dumpToFile(seshPath, p.DumpSession)
 }
 
 // PersistToDisk writes up to 3 files; one for the suite, one for the session,
@@ -539,9 +768,16 @@ type Session struct {
 }
 
 // Dump writes the contents of the session in "session-file" format to the given
-// io.Writer.
+// io.Writer as indented JSON.
 func (s Session) Dump(w io.Writer) error {
-	seshDataBytes, err := json.MarshalIndent(s, "", "  ")
+	return s.DumpFormatted(w, false)
+}
+
+// DumpFormatted writes the contents of the session in "session-file" format to
+// the given io.Writer. If compact is set, the JSON is written on a single line
+// instead of being indented.
+func (s Session) DumpFormatted(w io.Writer, compact bool) error {
+	seshDataBytes, err := marshalFileData(s, compact)
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
@@ -643,12 +879,778 @@ type RequestTemplate struct {
 	Method   string
 	Headers  http.Header
 	AuthFlow string
+
+	// BodyFile is the path to a file that the body is read from each time the
+	// request is sent, instead of using Body. Relative paths are resolved
+	// against the current working directory at send time.
+	BodyFile string
+
+	// Form is fields that are URL-encoded and joined to the end of the body
+	// when the request is sent, as by SendOptions.Form. They are kept
+	// unencoded so that vars in them are substituted before encoding.
+	Form []FormField
+
+	// HeaderOrder is the canonical keys of Headers in the order they were
+	// first added to the template. It is used only for display; it may be
+	// missing keys, such as for templates created before the order was
+	// recorded.
+	HeaderOrder []string
+
+	// Description is a human-readable note on what the request does and
+	// anything to watch out for when using it. It is not sent.
+	Description string
+
+	// Tags is labels used to group the request with others. Each is in lower
+	// case and they are kept sorted with no duplicates.
+	Tags []string
+
+	// EnvOverrides is changes made to the request when it is sent while a
+	// particular environment is current, keyed by the upper-case name of the
+	// environment. Use ForEnv to get the request with them applied.
+	EnvOverrides map[string]TemplateOverride
+}
+
+// TemplateOverride is a set of changes made to a RequestTemplate when it is
+// sent in a particular environment. Vars can already differ between
+// environments; a TemplateOverride is for when the structure of the request
+// itself needs to.
+type TemplateOverride struct {
+	// Headers is set on the request in place of any headers in the template
+	// with the same key. Headers in the template with other keys are kept.
+	Headers http.Header
+
+	// Body replaces the body of the template, including any body file and
+	// form fields, if it is not nil.
+	Body []byte
+}
+
+// Empty returns whether o makes no changes to a request.
+func (o TemplateOverride) Empty() bool {
+	return len(o.Headers) == 0 && o.Body == nil
+}
+
+// Copy returns a deep copy of o that shares no memory with it.
+func (o TemplateOverride) Copy() TemplateOverride {
+	c := o
+	if o.Headers != nil {
+		c.Headers = o.Headers.Clone()
+	}
+	if o.Body != nil {
+		c.Body = make([]byte, len(o.Body))
+		copy(c.Body, o.Body)
+	}
+	return c
+}
+
+// ForEnv returns a copy of r with the override for the given environment
+// applied to it. Each header key in the override replaces all values of that
+// key in r, and a body in the override replaces the body, the body file, and
+// the form fields of r. If r has no override for env, r is returned as-is. Either way,
+// the returned template has no environment overrides of its own. Case does not
+// matter for env.
+func (r RequestTemplate) ForEnv(env string) RequestTemplate {
+	o, ok := r.EnvOverrides[strings.ToUpper(env)]
+	if !ok || o.Empty() {
+		r.EnvOverrides = nil
+		return r
+	}
+
+	c := r.Copy()
+	c.EnvOverrides = nil
+	if len(o.Headers) > 0 {
+		if c.Headers == nil {
+			c.Headers = make(http.Header)
+		}
+
+		keys := make([]string, 0, len(o.Headers))
+		for k := range o.Headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			canonKey := http.CanonicalHeaderKey(k)
+			if len(c.Headers.Values(canonKey)) == 0 {
+				c.HeaderOrder = append(c.HeaderOrder, canonKey)
+			}
+			c.Headers[canonKey] = append([]string(nil), o.Headers[k]...)
+		}
+	}
+	if o.Body != nil {
+		c.Body = make([]byte, len(o.Body))
+		copy(c.Body, o.Body)
+		c.BodyFile = ""
+		c.Form = nil
+	}
+	return c
 }
 
 func (r RequestTemplate) Sendable() bool {
 	return r.URL != "" && r.Method != ""
 }
 
+// Copy returns a deep copy of r that shares no memory with it, so that either
+// can be modified without affecting the other.
+func (r RequestTemplate) Copy() RequestTemplate {
+	c := r
+	if r.Captures != nil {
+		c.Captures = make(map[string]VarScraper, len(r.Captures))
+		for k, v := range r.Captures {
+			c.Captures[k] = v.Copy()
+		}
+	}
+	if r.Body != nil {
+		c.Body = make([]byte, len(r.Body))
+		copy(c.Body, r.Body)
+	}
+	if r.Form != nil {
+		c.Form = make([]FormField, len(r.Form))
+		copy(c.Form, r.Form)
+	}
+	if r.Headers != nil {
+		c.Headers = r.Headers.Clone()
+	}
+	if r.HeaderOrder != nil {
+		c.HeaderOrder = make([]string, len(r.HeaderOrder))
+		copy(c.HeaderOrder, r.HeaderOrder)
+	}
+	if r.Tags != nil {
+		c.Tags = make([]string, len(r.Tags))
+		copy(c.Tags, r.Tags)
+	}
+	if r.EnvOverrides != nil {
+		c.EnvOverrides = make(map[string]TemplateOverride, len(r.EnvOverrides))
+		for k, v := range r.EnvOverrides {
+			c.EnvOverrides[k] = v.Copy()
+		}
+	}
+	return c
+}
+
+// HasTag returns whether r has the given tag. Case does not matter.
+func (r RequestTemplate) HasTag(tag string) bool {
+	tag = strings.ToLower(tag)
+	for _, t := range r.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ReferencedVars returns the names of the variables that are referenced with
+// the given prefix in the URL, headers, body, and form fields of r and of all
+// of its environment overrides, in upper case and in the order they first
+// appear. Dynamic vars and references escaped by doubling the prefix are not
+// included. To get only those needed to send r in a single environment, call
+// it on the result of ForEnv.
+func (r RequestTemplate) ReferencedVars(varPrefix string) []string {
+	rx := regexp.MustCompile(varRefPattern(varPrefix))
+
+	var names []string
+	seen := map[string]bool{}
+	addRefs := func(s string) {
+		for _, m := range rx.FindAllStringSubmatchIndex(s, -1) {
+			// skip it if it begins with a doubled prefix
+			if m[0]-len(varPrefix) >= 0 && s[m[0]-len(varPrefix):m[0]] == varPrefix {
+				continue
+			}
+
+			name := strings.ToUpper(s[m[2]:m[3]])
+			if strings.HasPrefix(name, "@") || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	addRefs(r.URL)
+	for _, k := range r.AuthoredHeaderKeys() {
+		for _, v := range r.Headers[k] {
+			addRefs(v)
+		}
+	}
+	addRefs(string(r.Body))
+	for _, f := range r.Form {
+		addRefs(f.Name)
+		addRefs(f.Value)
+	}
+
+	for _, env := range r.overrideEnvs() {
+		o := r.EnvOverrides[env]
+
+		keys := make([]string, 0, len(o.Headers))
+		for k := range o.Headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			for _, v := range o.Headers[k] {
+				addRefs(v)
+			}
+		}
+		addRefs(string(o.Body))
+	}
+
+	return names
+}
+
+// overrideEnvs returns the names of the environments that r has overrides
+// for, sorted.
+func (r RequestTemplate) overrideEnvs() []string {
+	envs := make([]string, 0, len(r.EnvOverrides))
+	for env := range r.EnvOverrides {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+// RenameVar returns a copy of r with every reference to the variable oldName
+// in its URL, headers, body, form fields, and environment overrides changed to
+// refer to newName instead, along with whether anything was changed. A capture
+// into oldName is likewise changed to capture into newName. Any transforms on
+// a reference are kept.
+func (r RequestTemplate) RenameVar(varPrefix, oldName, newName string) (RequestTemplate, bool) {
+	oldName = strings.ToUpper(oldName)
+	newName = strings.ToUpper(newName)
+
+	var changed, ok bool
+	r.URL, ok = RenameVarRefs(r.URL, varPrefix, oldName, newName)
+	changed = changed || ok
+
+	r.Headers, ok = renameHeaderVarRefs(r.Headers, varPrefix, oldName, newName)
+	changed = changed || ok
+
+	r.Body, ok = renameBodyVarRefs(r.Body, varPrefix, oldName, newName)
+	changed = changed || ok
+
+	if r.Form != nil {
+		newForm := make([]FormField, len(r.Form))
+		for i, f := range r.Form {
+			var nameOK, valueOK bool
+			f.Name, nameOK = RenameVarRefs(f.Name, varPrefix, oldName, newName)
+			f.Value, valueOK = RenameVarRefs(f.Value, varPrefix, oldName, newName)
+			changed = changed || nameOK || valueOK
+			newForm[i] = f
+		}
+		r.Form = newForm
+	}
+
+	if r.EnvOverrides != nil {
+		newOverrides := make(map[string]TemplateOverride, len(r.EnvOverrides))
+		for env, o := range r.EnvOverrides {
+			o.Headers, ok = renameHeaderVarRefs(o.Headers, varPrefix, oldName, newName)
+			changed = changed || ok
+
+			o.Body, ok = renameBodyVarRefs(o.Body, varPrefix, oldName, newName)
+			changed = changed || ok
+
+			newOverrides[env] = o
+		}
+		r.EnvOverrides = newOverrides
+	}
+
+	if scraper, ok := r.Captures[oldName]; ok {
+		newCaps := make(map[string]VarScraper, len(r.Captures))
+		for k, v := range r.Captures {
+			newCaps[k] = v
+		}
+		delete(newCaps, oldName)
+		scraper.Name = newName
+		newCaps[newName] = scraper
+		r.Captures = newCaps
+		changed = true
+	}
+
+	return r, changed
+}
+
+// renameHeaderVarRefs returns a copy of headers with every reference to the
+// variable oldName changed to refer to newName, along with whether any were
+// changed.
+func renameHeaderVarRefs(headers http.Header, varPrefix, oldName, newName string) (http.Header, bool) {
+	if headers == nil {
+		return nil, false
+	}
+
+	var changed, ok bool
+	newHeaders := make(http.Header, len(headers))
+	for k, vals := range headers {
+		newVals := make([]string, len(vals))
+		for i := range vals {
+			newVals[i], ok = RenameVarRefs(vals[i], varPrefix, oldName, newName)
+			changed = changed || ok
+		}
+		newHeaders[k] = newVals
+	}
+	return newHeaders, changed
+}
+
+// renameBodyVarRefs returns body with every reference to the variable oldName
+// changed to refer to newName, along with whether any were changed. If none
+// were, body itself is returned.
+func renameBodyVarRefs(body []byte, varPrefix, oldName, newName string) ([]byte, bool) {
+	if body == nil {
+		return nil, false
+	}
+
+	renamed, ok := RenameVarRefs(string(body), varPrefix, oldName, newName)
+	if !ok {
+		return body, false
+	}
+	return []byte(renamed), true
+}
+
+// RenameVarRefs returns s with every reference to the variable oldName that
+// uses the given prefix changed to refer to newName instead, along with whether
+// any were changed. Names are matched without regard to case, transforms on a
+// reference are kept, and references escaped by doubling the prefix are left
+// alone.
+func RenameVarRefs(s, varPrefix, oldName, newName string) (string, bool) {
+	rx := regexp.MustCompile(varRefPattern(varPrefix))
+
+	var sb strings.Builder
+	var lastEnd int
+	changed := false
+	for _, m := range rx.FindAllStringSubmatchIndex(s, -1) {
+		// skip it if it begins with a doubled prefix
+		if m[0]-len(varPrefix) >= 0 && s[m[0]-len(varPrefix):m[0]] == varPrefix {
+			continue
+		}
+		if !strings.EqualFold(s[m[2]:m[3]], oldName) {
+			continue
+		}
+
+		sb.WriteString(s[lastEnd:m[2]])
+		sb.WriteString(newName)
+		lastEnd = m[3]
+		changed = true
+	}
+
+	if !changed {
+		return s, false
+	}
+
+	sb.WriteString(s[lastEnd:])
+	return sb.String(), true
+}
+
+// AuthoredHeaderKeys returns the keys of all headers in the template in the
+// order they were first added. Any keys that do not have a recorded order are
+// placed after the rest in alphabetical order.
+func (r RequestTemplate) AuthoredHeaderKeys() []string {
+	keys := make([]string, 0, len(r.Headers))
+	added := map[string]bool{}
+	for _, k := range r.HeaderOrder {
+		if _, ok := r.Headers[k]; ok && !added[k] {
+			keys = append(keys, k)
+			added[k] = true
+		}
+	}
+
+	var unordered []string
+	for k := range r.Headers {
+		if !added[k] {
+			unordered = append(unordered, k)
+		}
+	}
+	sort.Strings(unordered)
+
+	return append(keys, unordered...)
+}
+
+// HTTPFile returns r in the .http file format used by editors such as the VS
+// Code REST Client and the JetBrains HTTP Client. The request is named with a
+// '# @name' comment and is followed by the request line, then every header in
+// the order it was added with one line per value, and then the body after a
+// blank line if r has one. If r has a BodyFile, it is written as a "< FILE"
+// line in place of the body. Any form fields are URL-encoded and joined to the
+// end of the body with '&'; references to vars in them are left unencoded, as
+// a .http file has no way to say that a value is encoded after it is filled in.
+//
+// If editorVars is set, references to vars that use varPrefix are converted to
+// the {{NAME}} syntax of those editors, and references escaped by doubling the
+// prefix are unescaped. References with transforms and to dynamic vars have no
+// equivalent in the editors and are left as-is. Otherwise, the URL, headers,
+// and body are written exactly as they are in r.
+func (r RequestTemplate) HTTPFile(varPrefix string, editorVars bool) []byte {
+	conv := func(s string) string {
+		if editorVars {
+			return editorVarRefs(s, varPrefix)
+		}
+		return s
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# @name %s\n", r.Name)
+	fmt.Fprintf(&buf, "%s %s\n", r.Method, conv(r.URL))
+	for _, k := range r.AuthoredHeaderKeys() {
+		for _, v := range r.Headers[k] {
+			fmt.Fprintf(&buf, "%s: %s\n", k, conv(v))
+		}
+	}
+
+	if r.BodyFile != "" {
+		fmt.Fprintf(&buf, "\n< %s\n", r.BodyFile)
+	} else if len(r.Body) > 0 || len(r.Form) > 0 {
+		body := r.Body
+		if len(r.Form) > 0 {
+			body = joinFormBody(body, httpFileForm(r.Form, varPrefix))
+		}
+
+		buf.WriteString("\n")
+		buf.WriteString(conv(string(body)))
+		if body[len(body)-1] != '\n' {
+			buf.WriteString("\n")
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// httpFileForm URL-encodes the given fields and joins them with '&' for use in
+// a .http file. Every reference to a var that uses varPrefix is kept exactly as
+// it is, along with any doubled prefix that escapes it.
+func httpFileForm(fields []FormField, varPrefix string) string {
+	rx := regexp.MustCompile(varRefPattern(varPrefix))
+
+	escape := func(s string) string {
+		var sb strings.Builder
+		var lastEnd int
+		for _, pair := range rx.FindAllStringIndex(s, -1) {
+			start := pair[0]
+			if start-len(varPrefix) >= lastEnd && s[start-len(varPrefix):start] == varPrefix {
+				start -= len(varPrefix)
+			}
+
+			sb.WriteString(formEscape(s[lastEnd:start]))
+			sb.WriteString(s[start:pair[1]])
+			lastEnd = pair[1]
+		}
+		sb.WriteString(formEscape(s[lastEnd:]))
+		return sb.String()
+	}
+
+	encoded := make([]string, len(fields))
+	for i, f := range fields {
+		if f.Name == "" {
+			encoded[i] = escape(f.Value)
+		} else {
+			encoded[i] = f.Name + "=" + escape(f.Value)
+		}
+	}
+
+	return strings.Join(encoded, "&")
+}
+
+// editorVarRefs returns s with every reference to a var that uses the given
+// prefix converted to the {{NAME}} syntax used by .http files. References that
+// are escaped by doubling the prefix are unescaped, and references with
+// transforms or to dynamic vars are left as-is.
+func editorVarRefs(s, varPrefix string) string {
+	rx := regexp.MustCompile(varRefPattern(varPrefix))
+
+	var sb strings.Builder
+	var lastEnd int
+	for _, m := range rx.FindAllStringSubmatchIndex(s, -1) {
+		// a doubled prefix means the reference is meant literally
+		if m[0]-len(varPrefix) >= 0 && s[m[0]-len(varPrefix):m[0]] == varPrefix {
+			sb.WriteString(s[lastEnd : m[0]-len(varPrefix)])
+			sb.WriteString(s[m[0]:m[1]])
+			lastEnd = m[1]
+			continue
+		}
+
+		name := s[m[2]:m[3]]
+		if m[4] != m[5] || strings.HasPrefix(name, "@") {
+			continue
+		}
+
+		sb.WriteString(s[lastEnd:m[0]])
+		sb.WriteString("{{" + strings.ToUpper(name) + "}}")
+		lastEnd = m[1]
+	}
+
+	sb.WriteString(s[lastEnd:])
+	return sb.String()
+}
+
+// HTTPFileBlockError is returned by ParseHTTPFile for a request in a .http
+// file that could not be parsed.
+type HTTPFileBlockError struct {
+	// Line is the 1-based line number that the request begins on.
+	Line int
+	Err  error
+}
+
+func (e HTTPFileBlockError) Error() string {
+	return fmt.Sprintf("request at line %d: %v", e.Line, e.Err)
+}
+
+func (e HTTPFileBlockError) Unwrap() error {
+	return e.Err
+}
+
+// ParseHTTPFile parses data as a .http file of the sort used by the VS Code
+// REST Client and the JetBrains HTTP Client and returns a request template for
+// each request in it. Requests are separated by lines beginning with "###".
+//
+// Each request is named by a "# @name NAME" or "// @name NAME" comment in it.
+// Requests with no name are named "request-N", where N is the 1-based position
+// of the request in the file.
+//
+// The request line is the first line in a block that is not blank or a
+// comment. It is either "METHOD URL" or just "URL", in which case the method
+// is GET; a trailing HTTP version is ignored. Lines directly after it that
+// begin with '?' or '&' continue the URL. Header lines follow until the first
+// blank line, and everything after that is the body. A body that is only a
+// "< FILE" line sets BodyFile of the template to FILE instead.
+//
+// References to editor variables in the {{NAME}} syntax are converted to var
+// references that use varPrefix, and any text that already looks like a var
+// reference is escaped by doubling the prefix. References to the editors'
+// system or request variables have no MORC equivalent and are left as-is.
+//
+// Blocks that contain no request line, such as those holding only file-level
+// variable definitions, are ignored. Any block that cannot be parsed is left
+// out of the returned templates and an HTTPFileBlockError for it is included
+// in the returned errors instead.
+func ParseHTTPFile(data []byte, varPrefix string) ([]RequestTemplate, []error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	var templates []RequestTemplate
+	var errs []error
+
+	blockStart := 0
+	reqNum := 0
+	flush := func(end int) {
+		tmpl, startLine, err := parseHTTPFileBlock(lines[blockStart:end], varPrefix)
+		if startLine < 0 {
+			return
+		}
+		reqNum++
+		if err != nil {
+			errs = append(errs, HTTPFileBlockError{Line: blockStart + startLine + 1, Err: err})
+			return
+		}
+		if tmpl.Name == "" {
+			tmpl.Name = fmt.Sprintf("request-%d", reqNum)
+		}
+		templates = append(templates, tmpl)
+	}
+
+	for i, line := range lines {
+		if strings.HasPrefix(line, "###") {
+			flush(i)
+			blockStart = i + 1
+		}
+	}
+	flush(len(lines))
+
+	return templates, errs
+}
+
+// parseHTTPFileBlock parses the lines of a single request in a .http file. The
+// returned int is the index within lines of the request line, or -1 if there
+// is none, in which case the block does not hold a request.
+func parseHTTPFileBlock(lines []string, varPrefix string) (RequestTemplate, int, error) {
+	var tmpl RequestTemplate
+	conv := func(s string) string {
+		return morcVarRefs(s, varPrefix)
+	}
+
+	// skip to the request line, picking up the name along the way
+	reqLine := -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if comment, ok := httpFileComment(trimmed); ok {
+			if name, ok := strings.CutPrefix(comment, "@name"); ok {
+				tmpl.Name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "="))
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "@") {
+			// file-level variable definition
+			continue
+		}
+		reqLine = i
+		break
+	}
+	if reqLine < 0 {
+		return tmpl, -1, nil
+	}
+
+	fields := strings.Fields(lines[reqLine])
+	if len(fields) > 1 && strings.HasPrefix(strings.ToUpper(fields[len(fields)-1]), "HTTP/") {
+		fields = fields[:len(fields)-1]
+	}
+	switch len(fields) {
+	case 1:
+		tmpl.Method = "GET"
+		tmpl.URL = fields[0]
+	case 2:
+		tmpl.Method = strings.ToUpper(fields[0])
+		tmpl.URL = fields[1]
+	default:
+		return tmpl, reqLine, fmt.Errorf("request line is not in the form METHOD URL: %q", lines[reqLine])
+	}
+	tmpl.URL = conv(tmpl.URL)
+
+	i := reqLine + 1
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(trimmed, "?") && !strings.HasPrefix(trimmed, "&") {
+			break
+		}
+		tmpl.URL += conv(trimmed)
+	}
+
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			i++
+			break
+		}
+		if _, ok := httpFileComment(trimmed); ok {
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return tmpl, reqLine, fmt.Errorf("header line is not in the form KEY: VALUE: %q", lines[i])
+		}
+
+		if tmpl.Headers == nil {
+			tmpl.Headers = make(http.Header)
+		}
+		canonKey := http.CanonicalHeaderKey(key)
+		if _, exists := tmpl.Headers[canonKey]; !exists {
+			tmpl.HeaderOrder = append(tmpl.HeaderOrder, canonKey)
+		}
+		tmpl.Headers.Add(canonKey, conv(strings.TrimSpace(value)))
+	}
+
+	if i < len(lines) {
+		body := strings.TrimRight(strings.Join(lines[i:], "\n"), " \t\n")
+		if file, ok := strings.CutPrefix(body, "< "); ok && !strings.Contains(file, "\n") {
+			tmpl.BodyFile = strings.TrimSpace(file)
+		} else if body != "" {
+			tmpl.Body = []byte(conv(body))
+		}
+	}
+
+	return tmpl, reqLine, nil
+}
+
+// httpFileComment returns the text of trimmed with its comment marker removed
+// if it is a comment line in a .http file.
+func httpFileComment(trimmed string) (string, bool) {
+	if c, ok := strings.CutPrefix(trimmed, "#"); ok {
+		return strings.TrimSpace(c), true
+	}
+	if c, ok := strings.CutPrefix(trimmed, "//"); ok {
+		return strings.TrimSpace(c), true
+	}
+	return "", false
+}
+
+// morcVarRefs is the inverse of editorVarRefs. It returns s with every {{NAME}}
+// reference to an editor variable converted to a reference to a var that uses
+// the given prefix. Text in s that would already be taken as a var reference is
+// escaped by doubling the prefix, and {{...}} references to anything other than
+// a plain variable are left as-is.
+func morcVarRefs(s, varPrefix string) string {
+	refRx := regexp.MustCompile(varRefPattern(varPrefix))
+	s = refRx.ReplaceAllStringFunc(s, func(ref string) string {
+		return varPrefix + ref
+	})
+
+	editorRx := regexp.MustCompile(`\{\{\s*(` + varNamePattern + `)\s*\}\}`)
+	return editorRx.ReplaceAllStringFunc(s, func(ref string) string {
+		name := editorRx.FindStringSubmatch(ref)[1]
+		return varPrefix + "{" + strings.ToUpper(name) + "}"
+	})
+}
+
+// EnvBundleFormat is the value of the "format" key of every env bundle file.
+const EnvBundleFormat = "morc-env-bundle"
+
+// EnvBundleVersion is the current version of the env bundle format.
+const EnvBundleVersion = 1
+
+// EnvBundle is a portable package of the variables defined in a single
+// environment, for handing that environment to another project. It is a
+// MORC-specific format and is written as a JSON object of the form:
+//
+//	{
+//	  "format": "morc-env-bundle",
+//	  "version": 1,
+//	  "env": "PROD",
+//	  "vars": {"HOST": "example.com", "SCHEME": "https"}
+//	}
+//
+// An empty env denotes the default environment.
+type EnvBundle struct {
+	Format  string            `json:"format"`
+	Version int               `json:"version"`
+	Env     string            `json:"env"`
+	Vars    map[string]string `json:"vars"`
+}
+
+// NewEnvBundle creates an EnvBundle from the variables defined in env. Values
+// filled from the default environment are not included.
+func NewEnvBundle(v VarStore, env string) EnvBundle {
+	b := EnvBundle{
+		Format:  EnvBundleFormat,
+		Version: EnvBundleVersion,
+		Env:     strings.ToUpper(env),
+		Vars:    map[string]string{},
+	}
+
+	for _, name := range v.DefinedIn(env) {
+		b.Vars[name] = v.GetFrom(name, env)
+	}
+
+	return b
+}
+
+// ReadEnvBundle reads an EnvBundle from r and checks that it is in a format
+// that can be used.
+func ReadEnvBundle(r io.Reader) (EnvBundle, error) {
+	var b EnvBundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return EnvBundle{}, fmt.Errorf("decode env bundle: %w", err)
+	}
+
+	if b.Format != EnvBundleFormat {
+		return EnvBundle{}, fmt.Errorf("not a MORC env bundle")
+	}
+	if b.Version < 1 || b.Version > EnvBundleVersion {
+		return EnvBundle{}, fmt.Errorf("unsupported env bundle version %d", b.Version)
+	}
+
+	return b, nil
+}
+
+// WriteTo writes b to w as indented JSON.
+func (b EnvBundle) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("encode env bundle: %w", err)
+	}
+	data = append(data, '\n')
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
 // VarStore is a collection of variables that can be accessed by name within
 // multiple environments. The zero value of this type is not valid; create a
 // new VarStore with NewVarStore().
@@ -656,6 +1658,14 @@ type VarStore struct {
 	Environment string
 
 	envs map[string]map[string]string
+
+	// secrets is the names of the variables whose values are masked in
+	// output. It applies to the variable in every environment.
+	secrets map[string]bool
+
+	// baseURLs is the base URL of each environment that has one, keyed by the
+	// upper-case name of the environment. The default environment is "".
+	baseURLs map[string]string
 }
 
 func NewVarStore() VarStore {
@@ -664,15 +1674,52 @@ func NewVarStore() VarStore {
 	}
 }
 
+// Copy returns a deep copy of v that shares no memory with it, so that either
+// can be modified without affecting the other.
+func (v VarStore) Copy() VarStore {
+	c := v
+	if v.envs != nil {
+		c.envs = make(map[string]map[string]string, len(v.envs))
+		for envName, vars := range v.envs {
+			if vars == nil {
+				c.envs[envName] = nil
+				continue
+			}
+			c.envs[envName] = make(map[string]string, len(vars))
+			for k, val := range vars {
+				c.envs[envName][k] = val
+			}
+		}
+	}
+	if v.secrets != nil {
+		c.secrets = make(map[string]bool, len(v.secrets))
+		for k, val := range v.secrets {
+			c.secrets[k] = val
+		}
+	}
+	if v.baseURLs != nil {
+		c.baseURLs = make(map[string]string, len(v.baseURLs))
+		for k, val := range v.baseURLs {
+			c.baseURLs[k] = val
+		}
+	}
+	return c
+}
+
 type marshaledVarStore struct {
-	Current string                       `json:"current_environment"`
-	Envs    map[string]map[string]string `json:"environments"`
+	Current  string                       `json:"current_environment"`
+	Envs     map[string]map[string]string `json:"environments"`
+	Secrets  []string                     `json:"secrets,omitempty"`
+	BaseURLs map[string]string            `json:"base_urls,omitempty"`
 }
 
 func (v VarStore) MarshalJSON() ([]byte, error) {
 	m := marshaledVarStore{
 		Current: v.Environment,
 		Envs:    v.envs,
+		Secrets: v.Secrets(),
+
+		BaseURLs: v.baseURLs,
 	}
 
 	return json.Marshal(m)
@@ -686,10 +1733,113 @@ func (v *VarStore) UnmarshalJSON(data []byte) error {
 
 	v.Environment = m.Current
 	v.envs = m.Envs
+	v.secrets = nil
+	for _, name := range m.Secrets {
+		v.SetSecret(name, true)
+	}
+	v.baseURLs = nil
+	for env, u := range m.BaseURLs {
+		v.SetBaseURLIn(u, env)
+	}
 
 	return nil
 }
 
+// SetBaseURLIn sets the base URL of the given environment. Request template
+// URLs that are only a path, such as "/users", are sent to the path on the
+// base URL of the current environment. If u is empty, the base URL of the
+// environment is removed.
+func (v *VarStore) SetBaseURLIn(u, env string) {
+	envUpper := strings.ToUpper(env)
+
+	if u != "" {
+		if v.baseURLs == nil {
+			v.baseURLs = make(map[string]string)
+		}
+		v.baseURLs[envUpper] = u
+		return
+	}
+
+	delete(v.baseURLs, envUpper)
+	if len(v.baseURLs) == 0 {
+		v.baseURLs = nil
+	}
+}
+
+// BaseURLIn returns the base URL set in the given environment. It does not
+// fall back to the base URL of the default environment; use BaseURL for that.
+func (v VarStore) BaseURLIn(env string) string {
+	return v.baseURLs[strings.ToUpper(env)]
+}
+
+// BaseURL returns the base URL of the current environment. If the current
+// environment does not have one, the base URL of the default environment is
+// returned. If neither has one, the empty string is returned.
+func (v VarStore) BaseURL() string {
+	if u, ok := v.baseURLs[strings.ToUpper(v.Environment)]; ok {
+		return u
+	}
+	return v.baseURLs[""]
+}
+
+// SetSecret sets whether the variable is a secret. The values of secret
+// variables are still used as normal when a request is sent, but are masked
+// wherever they would be shown. It applies to the variable in every
+// environment.
+func (v *VarStore) SetSecret(key string, secret bool) {
+	k := strings.ToUpper(key)
+
+	if secret {
+		if v.secrets == nil {
+			v.secrets = make(map[string]bool)
+		}
+		v.secrets[k] = true
+		return
+	}
+
+	delete(v.secrets, k)
+	if len(v.secrets) == 0 {
+		v.secrets = nil
+	}
+}
+
+// IsSecret returns whether the variable is a secret.
+func (v VarStore) IsSecret(key string) bool {
+	return v.secrets[strings.ToUpper(key)]
+}
+
+// Secrets returns the names of all secret variables in alphabetical order.
+func (v VarStore) Secrets() []string {
+	if len(v.secrets) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(v.secrets))
+	for k := range v.secrets {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SecretValues returns every non-empty value that a secret variable has in any
+// environment. It is suitable for use as OutputControl.Mask.
+func (v VarStore) SecretValues() []string {
+	var values []string
+	seen := map[string]bool{}
+	for _, name := range v.Secrets() {
+		for _, env := range v.envs {
+			val, ok := env[name]
+			if ok && val != "" && !seen[val] {
+				seen[val] = true
+				values = append(values, val)
+			}
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
 func (v VarStore) NonDefaultEnvsWith(name string) []string {
 	if v.envs == nil {
 		return nil
@@ -1005,6 +2155,21 @@ func (v *VarStore) SetIn(key, value, env string) {
 	}
 }
 
+// SetCaptured stores value, as captured by scraper, in the variable that
+// scraper captures to. It is set in the environment given by scraper.Env, or in
+// the current environment if that is empty. If the environment does not yet
+// exist, it is created.
+func (v *VarStore) SetCaptured(scraper VarScraper, value string) {
+	switch scraper.Env {
+	case "":
+		v.Set(scraper.Name, value)
+	case DefaultEnvName:
+		v.SetIn(scraper.Name, value, "")
+	default:
+		v.SetIn(scraper.Name, value, scraper.Env)
+	}
+}
+
 // DeleteEnv immediately removes the given environment and all of its variables.
 // The given environment must not be the default environment.
 func (v *VarStore) DeleteEnv(env string) {
@@ -1018,6 +2183,7 @@ func (v *VarStore) DeleteEnv(env string) {
 
 	envUpper := strings.ToUpper(env)
 	delete(v.envs, envUpper)
+	v.SetBaseURLIn("", envUpper)
 }
 
 // Unset removes the variable from the current environemnt. If the current
@@ -1065,11 +2231,13 @@ func (v *VarStore) UnsetIn(key, env string) {
 }
 
 // Remove removes the variable from all environments, including the default one.
+// If it was a secret, it no longer is.
 func (v *VarStore) Remove(key string) {
 	if v.envs == nil {
 		return
 	}
 
+	v.SetSecret(key, false)
 	for _, env := range v.envs {
 		if env != nil {
 			k := strings.ToUpper(key)
@@ -1078,11 +2246,70 @@ func (v *VarStore) Remove(key string) {
 	}
 }
 
+// Rename moves the variable oldName to newName in every environment it is
+// defined in, keeping its value in each and whether it is a secret. Any
+// existing variable named newName is replaced.
+func (v *VarStore) Rename(oldName, newName string) {
+	if v.envs == nil {
+		return
+	}
+
+	oldName = strings.ToUpper(oldName)
+	newName = strings.ToUpper(newName)
+
+	secret := v.IsSecret(oldName)
+	v.SetSecret(oldName, false)
+	v.SetSecret(newName, secret)
+
+	for _, env := range v.envs {
+		if env == nil {
+			continue
+		}
+		if val, ok := env[oldName]; ok {
+			delete(env, oldName)
+			env[newName] = val
+		}
+	}
+}
+
 type Flow struct {
 	Name  string     `json:"name"`
 	Steps []FlowStep `json:"steps"`
 }
 
+// Copy returns a deep copy of flow that shares no memory with it, so that
+// either can be modified without affecting the other.
+func (flow Flow) Copy() Flow {
+	c := flow
+	if flow.Steps != nil {
+		c.Steps = make([]FlowStep, len(flow.Steps))
+		for i, step := range flow.Steps {
+			c.Steps[i] = step.Copy()
+		}
+	}
+	return c
+}
+
+// RenameVar returns a copy of flow with every step that requires the variable
+// oldName changed to require newName instead, along with whether anything was
+// changed.
+func (flow Flow) RenameVar(oldName, newName string) (Flow, bool) {
+	oldName = strings.ToUpper(oldName)
+	newName = strings.ToUpper(newName)
+
+	var changed bool
+	flow = flow.Copy()
+	for i := range flow.Steps {
+		for j, req := range flow.Steps[i].Requires {
+			if strings.ToUpper(req) == oldName {
+				flow.Steps[i].Requires[j] = newName
+				changed = true
+			}
+		}
+	}
+	return flow, changed
+}
+
 func (flow *Flow) InsertStep(idx int, step FlowStep) error {
 	var err error
 	flow.Steps, err = sliceops.Insert(flow.Steps, idx, step)
@@ -1095,6 +2322,33 @@ func (flow *Flow) RemoveStep(idx int) error {
 	return err
 }
 
+// ReorderSteps rearranges the steps of flow so that the step that was at index
+// order[i] becomes step i. order must have every index of flow.Steps exactly
+// once.
+func (flow *Flow) ReorderSteps(order []int) error {
+	if len(order) != len(flow.Steps) {
+		return fmt.Errorf("order has %d indexes but flow has %d steps", len(order), len(flow.Steps))
+	}
+
+	seen := make([]bool, len(flow.Steps))
+	for _, idx := range order {
+		if idx < 0 || idx >= len(flow.Steps) {
+			return fmt.Errorf("%d is not a step index; must be between 0 and %d", idx, len(flow.Steps)-1)
+		}
+		if seen[idx] {
+			return fmt.Errorf("step %d is given more than once", idx)
+		}
+		seen[idx] = true
+	}
+
+	reordered := make([]FlowStep, len(order))
+	for i, idx := range order {
+		reordered[i] = flow.Steps[idx]
+	}
+	flow.Steps = reordered
+	return nil
+}
+
 func (flow *Flow) MoveStep(from, to int) error {
 	var err error
 	flow.Steps, err = sliceops.Move(flow.Steps, from, to)
@@ -1103,6 +2357,71 @@ func (flow *Flow) MoveStep(from, to int) error {
 
 type FlowStep struct {
 	Template string `json:"template"`
+
+	// Requires is the names of variables that must be set before the step is
+	// executed. If any are not set, either from the var store, from one-time
+	// overrides, or from a capture in a prior step, the flow will not be
+	// executed.
+	Requires []string `json:"requires,omitempty"`
+
+	// Group is the parallel group the step is in. Consecutive steps with the
+	// same non-zero Group are executed concurrently, and the flow does not
+	// proceed past them until all have completed. A Group of 0 means the step
+	// is executed on its own.
+	Group int `json:"group,omitempty"`
+
+	// AssertStatus is the status code that the response to the step must
+	// have. If 0, the status is not checked.
+	AssertStatus int `json:"assert_status,omitempty"`
+
+	// AssertBodyContains is text that the body of the response to the step
+	// must contain. If any assertion on a step fails, the flow stops.
+	AssertBodyContains []string `json:"assert_body_contains,omitempty"`
+
+	// AssertJSON is assertion expressions that are checked against the JSON
+	// body of the response to the step, in the format given to
+	// ParseJSONAssertion.
+	AssertJSON []string `json:"assert_json,omitempty"`
+}
+
+// Copy returns a deep copy of step that shares no memory with it.
+func (step FlowStep) Copy() FlowStep {
+	c := step
+	if step.Requires != nil {
+		c.Requires = make([]string, len(step.Requires))
+		copy(c.Requires, step.Requires)
+	}
+	if step.AssertBodyContains != nil {
+		c.AssertBodyContains = make([]string, len(step.AssertBodyContains))
+		copy(c.AssertBodyContains, step.AssertBodyContains)
+	}
+	if step.AssertJSON != nil {
+		c.AssertJSON = make([]string, len(step.AssertJSON))
+		copy(c.AssertJSON, step.AssertJSON)
+	}
+	return c
+}
+
+// Batches returns the indexes of the steps in the flow grouped into batches
+// that are executed together. Each batch is either a single step or a run of
+// consecutive steps that share the same non-zero Group. Batches are returned in
+// the order they are executed.
+func (flow Flow) Batches() [][]int {
+	var batches [][]int
+
+	for i, step := range flow.Steps {
+		if step.Group != 0 && len(batches) > 0 {
+			last := batches[len(batches)-1]
+			if flow.Steps[last[0]].Group == step.Group {
+				batches[len(batches)-1] = append(last, i)
+				continue
+			}
+		}
+
+		batches = append(batches, []int{i})
+	}
+
+	return batches
 }
 
 type marshaledHistory struct {
@@ -1118,6 +2437,60 @@ type HistoryEntry struct {
 	Request  *http.Request
 	Response *http.Response
 	Captures map[string]string
+
+	// Redact is values that are replaced with SecretMask in the request,
+	// response, and captures of the entry when it is marshaled, such as the
+	// values of secret variables. It is not itself marshaled.
+	Redact []string
+
+	// Redacted is whether any values in the request of the entry were
+	// replaced with SecretMask when it was marshaled. If set, the request can
+	// no longer be sent again exactly as it was.
+	Redacted bool
+}
+
+// HistoryFilter selects entries from a project's history. Every criterion
+// that is set must match for an entry to be selected; the zero value selects
+// every entry.
+type HistoryFilter struct {
+	// StatusCode is the status code of the response. If 0, entries are not
+	// filtered on status.
+	StatusCode int
+
+	// Template is the name of the request template the entry was sent from,
+	// compared case-insensitively. If empty, entries are not filtered on
+	// template.
+	Template string
+
+	// Since excludes all entries whose request was sent before it. If zero,
+	// entries are not filtered on time.
+	Since time.Time
+}
+
+// Matches returns whether h meets every criterion set in f.
+func (f HistoryFilter) Matches(h HistoryEntry) bool {
+	if f.StatusCode != 0 && (h.Response == nil || h.Response.StatusCode != f.StatusCode) {
+		return false
+	}
+	if f.Template != "" && !strings.EqualFold(h.Template, f.Template) {
+		return false
+	}
+	if !f.Since.IsZero() && h.ReqTime.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// Filter returns the indexes within entries of every entry that f matches, in
+// order.
+func (f HistoryFilter) Filter(entries []HistoryEntry) []int {
+	var matched []int
+	for i := range entries {
+		if f.Matches(entries[i]) {
+			matched = append(matched, i)
+		}
+	}
+	return matched
 }
 
 type marshaledHistoryEntry struct {
@@ -1127,13 +2500,31 @@ type marshaledHistoryEntry struct {
 	Request  clientRequestRecord  `json:"request"`
 	Response clientResponseRecord `json:"response"`
 	Captures map[string]string    `json:"captures,omitempty"`
+	Redacted bool                 `json:"redacted,omitempty"`
 }
 
 func (h HistoryEntry) MarshalJSON() ([]byte, error) {
 	// convert the http.Request and http.Response into marshaledHistoryEntry
 	// structs
-	reqRec := httpRequestToRecord(h.Request)
-	respRec := httpResponseToRecord(h.Response)
+	reqRec := httpRequestToRecord(h.Request, h.Redact)
+	respRec := httpResponseToRecord(h.Response, h.Redact)
+
+	redacted := h.Redacted
+	if !redacted && len(h.Redact) > 0 {
+		// content length is recalculated whenever anything is redacted, so it
+		// can't be used to tell whether anything actually was.
+		plainRec := httpRequestToRecord(h.Request, nil)
+		plainRec.ContentLength = reqRec.ContentLength
+		redacted = !reflect.DeepEqual(reqRec, plainRec)
+	}
+
+	captures := h.Captures
+	if len(h.Redact) > 0 && len(captures) > 0 {
+		captures = make(map[string]string, len(h.Captures))
+		for k, v := range h.Captures {
+			captures[k] = MaskValues(v, h.Redact)
+		}
+	}
 
 	// marshal the marshaledHistoryEntry struct
 	m := marshaledHistoryEntry{
@@ -1142,7 +2533,8 @@ func (h HistoryEntry) MarshalJSON() ([]byte, error) {
 		RespTime: h.RespTime.Unix(),
 		Request:  reqRec,
 		Response: respRec,
-		Captures: h.Captures,
+		Captures: captures,
+		Redacted: redacted,
 	}
 
 	return json.Marshal(m)
@@ -1173,6 +2565,7 @@ func (h *HistoryEntry) UnmarshalJSON(data []byte) error {
 	h.Request = req
 	h.Response = resp
 	h.Captures = m.Captures
+	h.Redacted = m.Redacted
 
 	return nil
 }
@@ -1191,27 +2584,33 @@ type clientRequestRecord struct {
 	Trailers          http.Header `json:"trailers,omitempty"`
 }
 
-func httpRequestToRecord(req *http.Request) clientRequestRecord {
+// httpRequestToRecord converts req into a record. Each of redact is replaced
+// with SecretMask in the URL, headers, and body of the record; req itself is
+// left as it is.
+func httpRequestToRecord(req *http.Request, redact []string) clientRequestRecord {
 	var body string
+	contentLength := req.ContentLength
 	if req.Body != nil && req.Body != http.NoBody {
 		bodyBytes, err := io.ReadAll(req.Body)
 		if err != nil {
 			panic(fmt.Sprintf("failed to read request body: %s", err))
 		}
-		body = base64.StdEncoding.EncodeToString(bodyBytes)
 		req.Body.Close()
 		req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+
+		bodyBytes, contentLength = redactBody(bodyBytes, contentLength, redact)
+		body = base64.StdEncoding.EncodeToString(bodyBytes)
 	}
 
 	return clientRequestRecord{
 		Method:            req.Method,
-		URL:               req.URL.String(),
+		URL:               MaskValues(req.URL.String(), redact),
 		Proto:             req.Proto,
 		ProtoMajor:        req.ProtoMajor,
 		ProtoMinor:        req.ProtoMinor,
-		Headers:           req.Header,
+		Headers:           redactHeaders(req.Header, redact),
 		Body:              body,
-		ContentLength:     req.ContentLength,
+		ContentLength:     contentLength,
 		TransferEncodings: req.TransferEncoding,
 		Host:              req.Host,
 		Trailers:          req.Trailer,
@@ -1269,16 +2668,22 @@ type clientResponseRecord struct {
 	TLS               bool        `json:"tls"`
 }
 
-func httpResponseToRecord(resp *http.Response) clientResponseRecord {
+// httpResponseToRecord converts resp into a record. Each of redact is replaced
+// with SecretMask in the headers and body of the record; resp itself is left as
+// it is.
+func httpResponseToRecord(resp *http.Response, redact []string) clientResponseRecord {
 	var body string
+	contentLength := resp.ContentLength
 	if resp.Body != nil && resp.Body != http.NoBody {
 		bodyBytes, err := io.ReadAll(resp.Body)
 		if err != nil {
 			panic(fmt.Sprintf("failed to read response body: %s", err))
 		}
-		body = base64.StdEncoding.EncodeToString(bodyBytes)
 		resp.Body.Close()
 		resp.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+
+		bodyBytes, contentLength = redactBody(bodyBytes, contentLength, redact)
+		body = base64.StdEncoding.EncodeToString(bodyBytes)
 	}
 
 	return clientResponseRecord{
@@ -1287,15 +2692,45 @@ func httpResponseToRecord(resp *http.Response) clientResponseRecord {
 		Proto:             resp.Proto,
 		ProtoMajor:        resp.ProtoMajor,
 		ProtoMinor:        resp.ProtoMinor,
-		Headers:           resp.Header,
+		Headers:           redactHeaders(resp.Header, redact),
 		Body:              body,
-		ContentLength:     resp.ContentLength,
+		ContentLength:     contentLength,
 		TransferEncodings: resp.TransferEncoding,
 		Uncompressed:      resp.Uncompressed,
 		TLS:               resp.TLS != nil,
 	}
 }
 
+// redactBody returns body with each of redact replaced with SecretMask, along
+// with contentLength updated to match if it was known.
+func redactBody(body []byte, contentLength int64, redact []string) ([]byte, int64) {
+	if len(redact) == 0 {
+		return body, contentLength
+	}
+	redacted := []byte(MaskValues(string(body), redact))
+	if contentLength > 0 {
+		contentLength = int64(len(redacted))
+	}
+	return redacted, contentLength
+}
+
+// redactHeaders returns a copy of h with each of redact replaced with
+// SecretMask in its values. If there is nothing to redact, h is returned as-is.
+func redactHeaders(h http.Header, redact []string) http.Header {
+	if len(redact) == 0 || h == nil {
+		return h
+	}
+	redacted := make(http.Header, len(h))
+	for k, vals := range h {
+		masked := make([]string, len(vals))
+		for i := range vals {
+			masked[i] = MaskValues(vals[i], redact)
+		}
+		redacted[k] = masked
+	}
+	return redacted
+}
+
 func respRecordToHTTPResponse(rec clientResponseRecord) (*http.Response, error) {
 	var bodyReader io.ReadCloser
 	if len(rec.Body) > 0 {
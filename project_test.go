@@ -1,12 +1,15 @@
 package morc
 
 import (
+	"bytes"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -77,6 +80,8 @@ func Test_LoadProjectFromDisk(t *testing.T) {
 		},
 		Config: Settings{
 			CookieLifetime: 24,
+			RequestTimeout: 45 * time.Second,
+			DefaultHeaders: http.Header{"User-Agent": {"morc-test"}},
 			ProjFile:       "project.json",
 			HistFile:       "::PROJ_DIR::/history.json",
 			SeshFile:       "::PROJ_DIR::/session.json",
@@ -97,6 +102,80 @@ func Test_LoadProjectFromDisk(t *testing.T) {
 	AssertProjectInFileMatches(assert, p, projFilePath)
 }
 
+func Test_Project_Dump_CompactFiles(t *testing.T) {
+	testCases := []struct {
+		name    string
+		compact bool
+	}{
+		{name: "indented", compact: false},
+		{name: "compact", compact: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			p := Project{
+				Name: "test",
+				Templates: map[string]RequestTemplate{
+					"get": {Name: "get", Method: "GET", URL: "http://example.com"},
+				},
+				Vars: NewVarStore(),
+				History: []HistoryEntry{
+					{
+						Template: "get",
+						Request: &http.Request{
+							URL:    mustParseURL("http://example.com"),
+							Method: "GET",
+							Body:   http.NoBody,
+						},
+						Response: &http.Response{
+							StatusCode: 200,
+							Body:       http.NoBody,
+						},
+					},
+				},
+				Session: Session{
+					Cookies: []SetCookiesCall{{URL: mustParseURL("http://example.com")}},
+				},
+				Config: Settings{
+					HistFile:     "::PROJ_DIR::/history.json",
+					SeshFile:     "::PROJ_DIR::/session.json",
+					CompactFiles: tc.compact,
+				},
+			}
+
+			projBuf, histBuf, seshBuf := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+			if !assert.NoError(p.Dump(projBuf)) {
+				return
+			}
+			if !assert.NoError(p.DumpHistory(histBuf)) {
+				return
+			}
+			if !assert.NoError(p.DumpSession(seshBuf)) {
+				return
+			}
+
+			for name, buf := range map[string]*bytes.Buffer{"project": projBuf, "history": histBuf, "session": seshBuf} {
+				if tc.compact {
+					assert.NotContains(buf.String(), "\n", "%s file is not compact", name)
+				} else {
+					assert.Contains(buf.String(), "\n  ", "%s file is not indented", name)
+				}
+			}
+
+			// and make sure it can still be read back in
+			loaded, err := LoadProject(projBuf, seshBuf, histBuf)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.compact, loaded.Config.CompactFiles)
+			assert.Len(loaded.History, 1)
+			assert.Len(loaded.Session.Cookies, 1)
+		})
+	}
+}
+
 func mustParseURL(s string) *url.URL {
 	u, err := url.Parse(s)
 	if err != nil {
@@ -166,7 +245,7 @@ func createTestProjectFiles(t *testing.T, p Project) string {
 		}
 		defer sf.Close()
 
-		if err := p.Session.Dump(sf); err != nil {
+		if err := p.DumpSession(sf); err != nil {
 			t.Fatal(err)
 			return ""
 		}
@@ -174,3 +253,987 @@ func createTestProjectFiles(t *testing.T, p Project) string {
 
 	return projFilePath
 }
+
+func Test_ExpandProjDir(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		projFile string
+		expect   string
+	}{
+		{
+			name:     "no proj dir var",
+			path:     "data/body.json",
+			projFile: filepath.Join("home", "jade", ".morc", "project.json"),
+			expect:   "data/body.json",
+		},
+		{
+			name:     "proj dir var is replaced",
+			path:     ProjDirVar + "/body.json",
+			projFile: filepath.Join("home", "jade", ".morc", "project.json"),
+			expect:   filepath.Join("home", "jade", ".morc") + "/body.json",
+		},
+		{
+			name:   "proj dir var with no project file",
+			path:   ProjDirVar + "/body.json",
+			expect: "",
+		},
+		{
+			name:     "only proj dir",
+			path:     ProjDirVar,
+			projFile: filepath.Join("home", "jade", ".morc", "project.json"),
+			expect:   "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual := ExpandProjDir(tc.path, tc.projFile)
+
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_VarStore_Secrets(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := NewVarStore()
+	vs.Set("TOKEN", "8b5f2c9e")
+	vs.SetIn("TOKEN", "413", "PROD")
+	vs.Set("USER", "vriska")
+	vs.SetSecret("token", true)
+
+	assert.True(vs.IsSecret("TOKEN"))
+	assert.False(vs.IsSecret("USER"))
+	assert.Equal([]string{"413", "8b5f2c9e"}, vs.SecretValues())
+
+	// secrets survive a round trip through JSON
+	data, err := vs.MarshalJSON()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(string(data), `"secrets":["TOKEN"]`)
+
+	var loaded VarStore
+	if !assert.NoError(loaded.UnmarshalJSON(data)) {
+		return
+	}
+	assert.Equal(vs, loaded)
+
+	// and follow the var when it is renamed
+	vs.Rename("TOKEN", "API_TOKEN")
+	assert.False(vs.IsSecret("TOKEN"))
+	assert.True(vs.IsSecret("API_TOKEN"))
+
+	// and are gone once the var is
+	vs.Remove("API_TOKEN")
+	assert.Nil(vs.Secrets())
+	assert.Nil(vs.SecretValues())
+
+	data, err = vs.MarshalJSON()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NotContains(string(data), "secrets")
+}
+
+func Test_VarStore_BaseURL(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := NewVarStore()
+	assert.Equal("", vs.BaseURL())
+
+	vs.SetBaseURLIn("http://localhost:8080", "")
+	vs.SetBaseURLIn("https://api.example.com", "prod")
+
+	// default env's base URL is used until the current env has its own
+	assert.Equal("http://localhost:8080", vs.BaseURL())
+	vs.Environment = "STAGING"
+	assert.Equal("http://localhost:8080", vs.BaseURL())
+	assert.Equal("", vs.BaseURLIn("STAGING"))
+	vs.Environment = "PROD"
+	assert.Equal("https://api.example.com", vs.BaseURL())
+
+	// base URLs survive a round trip through JSON
+	data, err := vs.MarshalJSON()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Contains(string(data), `"base_urls"`)
+
+	var loaded VarStore
+	if !assert.NoError(loaded.UnmarshalJSON(data)) {
+		return
+	}
+	assert.Equal("https://api.example.com", loaded.BaseURLIn("PROD"))
+	assert.Equal("http://localhost:8080", loaded.BaseURLIn(""))
+
+	// and are gone once removed
+	vs.SetBaseURLIn("", "PROD")
+	vs.SetBaseURLIn("", "")
+	assert.Equal("", vs.BaseURL())
+
+	data, err = vs.MarshalJSON()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NotContains(string(data), "base_urls")
+}
+
+func Test_Project_UnusedVars(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := NewVarStore()
+	vs.Set("URL_PATH", "${BASE}/users")
+	vs.Set("BASE", "${SCHEME}://localhost")
+	vs.Set("SCHEME", "http")
+	vs.Set("HOST", "example.com")
+	vs.Set("API_KEY", "8b5f2c9e")
+	vs.Set("LOOP_A", "${LOOP_B}")
+	vs.Set("LOOP_B", "${LOOP_A}")
+	vs.SetIn("STALE", "", "PROD")
+	vs.SetBaseURLIn("https://${HOST}", "PROD")
+
+	p := Project{
+		Templates: map[string]RequestTemplate{
+			"get-user": {Name: "get-user", Method: "GET", URL: "${url_path}/8"},
+			"escaped":  {Name: "escaped", Method: "GET", URL: "/$${SCHEME}/${@uuid}"},
+		},
+		Vars: vs,
+		Config: Settings{
+			DefaultHeaders: http.Header{"X-Api-Key": []string{"${API_KEY}"}},
+		},
+	}
+
+	assert.Equal([]string{"LOOP_A", "LOOP_B", "STALE"}, p.UnusedVars())
+}
+
+func Test_Project_RenameVarInConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := NewVarStore()
+	vs.Set("TOKEN", "8b5f2c9e")
+	vs.SetBaseURLIn("https://example.com/${TOKEN}", "")
+	vs.SetBaseURLIn("https://${HOST}", "PROD")
+	vs.SetBaseURLIn("https://staging.example.com/${token|upper}", "STAGING")
+
+	p := Project{
+		Vars: vs,
+		Config: Settings{
+			DefaultHeaders: http.Header{"Authorization": {"Bearer ${TOKEN}"}, "Accept": {"application/json"}},
+		},
+	}
+
+	headersChanged, envs := p.RenameVarInConfig("token", "auth")
+
+	assert.True(headersChanged)
+	assert.Equal([]string{"", "STAGING"}, envs)
+	assert.Equal(http.Header{"Authorization": {"Bearer ${AUTH}"}, "Accept": {"application/json"}}, p.Config.DefaultHeaders)
+	assert.Equal("https://example.com/${AUTH}", p.Vars.BaseURLIn(""))
+	assert.Equal("https://${HOST}", p.Vars.BaseURLIn("PROD"))
+	assert.Equal("https://staging.example.com/${AUTH|upper}", p.Vars.BaseURLIn("STAGING"))
+
+	headersChanged, envs = p.RenameVarInConfig("PASSWORD", "SECRET")
+	assert.False(headersChanged)
+	assert.Nil(envs)
+}
+
+func Test_VarStore_SetCaptured(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := NewVarStore()
+	vs.Environment = "PROD"
+
+	vs.SetCaptured(VarScraper{Name: "ID"}, "413")
+	vs.SetCaptured(VarScraper{Name: "TOKEN", Env: DefaultEnvName}, "8b5f2c9e")
+	vs.SetCaptured(VarScraper{Name: "USER", Env: "staging"}, "vriska")
+
+	assert.Equal("413", vs.GetFrom("ID", "PROD"))
+	assert.Equal("", vs.GetFrom("ID", ""))
+	assert.Equal("8b5f2c9e", vs.GetFrom("TOKEN", ""))
+	assert.False(vs.IsDefinedIn("TOKEN", "PROD"))
+	assert.Equal("8b5f2c9e", vs.Get("TOKEN"))
+
+	// the target env is created if it does not yet exist
+	assert.Equal("vriska", vs.GetFrom("USER", "STAGING"))
+	assert.Contains(vs.EnvNames(), "STAGING")
+}
+
+func Test_Flow_Batches(t *testing.T) {
+	testCases := []struct {
+		name   string
+		groups []int
+		expect [][]int
+	}{
+		{
+			name:   "no steps",
+			groups: nil,
+			expect: nil,
+		},
+		{
+			name:   "no groups",
+			groups: []int{0, 0, 0},
+			expect: [][]int{{0}, {1}, {2}},
+		},
+		{
+			name:   "one group in middle",
+			groups: []int{0, 1, 1, 0},
+			expect: [][]int{{0}, {1, 2}, {3}},
+		},
+		{
+			name:   "adjacent different groups",
+			groups: []int{2, 2, 1, 1, 1},
+			expect: [][]int{{0, 1}, {2, 3, 4}},
+		},
+		{
+			name:   "same group split by ungrouped step",
+			groups: []int{1, 0, 1},
+			expect: [][]int{{0}, {1}, {2}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			flow := Flow{Name: "test"}
+			for _, g := range tc.groups {
+				flow.Steps = append(flow.Steps, FlowStep{Template: "req", Group: g})
+			}
+
+			actual := flow.Batches()
+
+			assert.Equal(t, tc.expect, actual)
+		})
+	}
+}
+
+func Test_Flow_Copy(t *testing.T) {
+	assert := assert.New(t)
+
+	flow := Flow{
+		Name: "login",
+		Steps: []FlowStep{
+			{Template: "auth", Requires: []string{"USER"}, AssertStatus: 200},
+			{Template: "get-user", Group: 1, AssertBodyContains: []string{"id"}, AssertJSON: []string{".id != null"}},
+		},
+	}
+
+	actual := flow.Copy()
+	assert.Equal(flow, actual)
+
+	// modifying the copy leaves the original alone
+	actual.Steps[0].Template = "other"
+	actual.Steps[0].Requires[0] = "PASS"
+	actual.Steps[1].AssertBodyContains[0] = "name"
+	actual.Steps[1].AssertJSON[0] = ".name != null"
+
+	assert.Equal("auth", flow.Steps[0].Template)
+	assert.Equal([]string{"USER"}, flow.Steps[0].Requires)
+	assert.Equal([]string{"id"}, flow.Steps[1].AssertBodyContains)
+	assert.Equal([]string{".id != null"}, flow.Steps[1].AssertJSON)
+}
+
+func Test_Flow_RenameVar(t *testing.T) {
+	assert := assert.New(t)
+
+	flow := Flow{
+		Name: "login",
+		Steps: []FlowStep{
+			{Template: "auth", Requires: []string{"USER", "pass"}},
+			{Template: "get-user", Requires: []string{"ID"}},
+		},
+	}
+
+	actual, changed := flow.RenameVar("pass", "password")
+
+	assert.True(changed)
+	assert.Equal([]string{"USER", "PASSWORD"}, actual.Steps[0].Requires)
+	assert.Equal([]string{"ID"}, actual.Steps[1].Requires)
+
+	// original is left alone
+	assert.Equal([]string{"USER", "pass"}, flow.Steps[0].Requires)
+
+	_, changed = flow.RenameVar("TOKEN", "SECRET")
+	assert.False(changed)
+}
+
+func Test_Project_Copy(t *testing.T) {
+	assert := assert.New(t)
+
+	vars := NewVarStore()
+	vars.Set("USER", "vriska")
+	vars.SetIn("USER", "terezi", "PROD")
+	vars.SetSecret("USER", true)
+	vars.SetBaseURLIn("https://example.com", "PROD")
+
+	p := Project{
+		Name:      "test",
+		Templates: map[string]RequestTemplate{"get-user": {Name: "get-user", URL: "/users"}},
+		Flows:     map[string]Flow{"login": {Name: "login", Steps: []FlowStep{{Template: "get-user"}}}},
+		Vars:      vars,
+		History:   []HistoryEntry{{Template: "get-user"}},
+		Session:   Session{Cookies: []SetCookiesCall{{Cookies: []*http.Cookie{{Name: "session"}}}}},
+		Config:    Settings{DefaultHeaders: http.Header{"Accept": {"*/*"}}},
+	}
+
+	actual := p.Copy()
+	assert.Equal(p, actual)
+
+	// modifying the copy leaves the original alone
+	actual.Templates["get-user"] = RequestTemplate{Name: "get-user", URL: "/other"}
+	actual.Flows["login"].Steps[0].Template = "other"
+	actual.Vars.Set("USER", "karkat")
+	actual.Vars.SetIn("USER", "nepeta", "PROD")
+	actual.Vars.SetSecret("USER", false)
+	actual.Vars.SetBaseURLIn("https://example.org", "PROD")
+	actual.History[0].Template = "other"
+	actual.Session.Cookies[0] = SetCookiesCall{}
+	actual.Config.DefaultHeaders.Set("Accept", "text/plain")
+
+	assert.Equal("/users", p.Templates["get-user"].URL)
+	assert.Equal("get-user", p.Flows["login"].Steps[0].Template)
+	assert.Equal("vriska", p.Vars.GetFrom("USER", ""))
+	assert.Equal("terezi", p.Vars.GetFrom("USER", "PROD"))
+	assert.True(p.Vars.IsSecret("USER"))
+	assert.Equal("https://example.com", p.Vars.BaseURLIn("PROD"))
+	assert.Equal("get-user", p.History[0].Template)
+	assert.Len(p.Session.Cookies[0].Cookies, 1)
+	assert.Equal("*/*", p.Config.DefaultHeaders.Get("Accept"))
+}
+
+func Test_RequestTemplate_ReferencedVars(t *testing.T) {
+	testCases := []struct {
+		name   string
+		tmpl   RequestTemplate
+		prefix string
+		expect []string
+	}{
+		{
+			name:   "no vars",
+			tmpl:   RequestTemplate{URL: "https://example.com"},
+			prefix: "$",
+			expect: nil,
+		},
+		{
+			name: "vars in URL, headers, and body in order",
+			tmpl: RequestTemplate{
+				URL:         "${scheme}://${HOST}/users",
+				Headers:     http.Header{"X-B": {"${B}"}, "X-A": {"${A}", "${HOST}"}},
+				HeaderOrder: []string{"X-B", "X-A"},
+				Body:        []byte(`{"name": "${NAME|upper}"}`),
+			},
+			prefix: "$",
+			expect: []string{"SCHEME", "HOST", "B", "A", "NAME"},
+		},
+		{
+			name: "vars in env overrides come after the rest",
+			tmpl: RequestTemplate{
+				URL: "${HOST}/users",
+				EnvOverrides: map[string]TemplateOverride{
+					"PROD":    {Body: []byte(`{"key": "${KEY}"}`)},
+					"STAGING": {Headers: http.Header{"X-Debug": {"${DEBUG}"}}},
+				},
+			},
+			prefix: "$",
+			expect: []string{"HOST", "KEY", "DEBUG"},
+		},
+		{
+			name: "vars in form fields come after body",
+			tmpl: RequestTemplate{
+				Body: []byte("a=${A}"),
+				Form: []FormField{{Name: "${FIELD}", Value: "${VALUE} & ${A}"}},
+			},
+			prefix: "$",
+			expect: []string{"A", "FIELD", "VALUE"},
+		},
+		{
+			name:   "dynamic and escaped vars are skipped",
+			tmpl:   RequestTemplate{URL: "/users/${@uuid}?q=$${LITERAL}&id=${ID}"},
+			prefix: "$",
+			expect: []string{"ID"},
+		},
+		{
+			name:   "other prefix",
+			tmpl:   RequestTemplate{URL: "^{HOST}/${NOT_A_VAR}"},
+			prefix: "^",
+			expect: []string{"HOST"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := tc.tmpl.ReferencedVars(tc.prefix)
+
+			assert.Equal(t, tc.expect, actual)
+		})
+	}
+}
+
+func Test_RenameVarRefs(t *testing.T) {
+	testCases := []struct {
+		name          string
+		s             string
+		prefix        string
+		expect        string
+		expectChanged bool
+	}{
+		{name: "no refs", s: "https://example.com", prefix: "$", expect: "https://example.com"},
+		{name: "other var", s: "${HOST}/users", prefix: "$", expect: "${HOST}/users"},
+		{name: "single ref", s: "/users/${ID}", prefix: "$", expect: "/users/${USER_ID}", expectChanged: true},
+		{name: "many refs, any case", s: "${id}-${ID}", prefix: "$", expect: "${USER_ID}-${USER_ID}", expectChanged: true},
+		{name: "transforms are kept", s: "${ID|trim|upper}", prefix: "$", expect: "${USER_ID|trim|upper}", expectChanged: true},
+		{name: "escaped ref is skipped", s: "$${ID}/${ID}", prefix: "$", expect: "$${ID}/${USER_ID}", expectChanged: true},
+		{name: "var with same start is skipped", s: "${IDENT}", prefix: "$", expect: "${IDENT}"},
+		{name: "other prefix", s: "^{ID}/${ID}", prefix: "^", expect: "^{USER_ID}/${ID}", expectChanged: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, changed := RenameVarRefs(tc.s, tc.prefix, "ID", "USER_ID")
+
+			assert.Equal(t, tc.expect, actual)
+			assert.Equal(t, tc.expectChanged, changed)
+		})
+	}
+}
+
+func Test_RequestTemplate_RenameVar(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpl := RequestTemplate{
+		Name:    "get-user",
+		URL:     "${HOST}/users/${ID}",
+		Headers: http.Header{"X-User": {"${ID}"}, "X-Host": {"${HOST}"}},
+		Body:    []byte(`{"id": "${ID}"}`),
+		Form:    []FormField{{Name: "id", Value: "${ID}"}, {Name: "host", Value: "${HOST}"}},
+		Captures: map[string]VarScraper{
+			"ID":   {Name: "ID", Steps: []TraversalStep{{Key: "id"}}},
+			"NAME": {Name: "NAME", Steps: []TraversalStep{{Key: "name"}}},
+		},
+		EnvOverrides: map[string]TemplateOverride{
+			"PROD": {Headers: http.Header{"X-Audit": {"${ID}"}}, Body: []byte(`{"user": "${ID}"}`)},
+		},
+	}
+
+	actual, changed := tmpl.RenameVar("$", "id", "user_id")
+
+	assert.True(changed)
+	assert.Equal("${HOST}/users/${USER_ID}", actual.URL)
+	assert.Equal(http.Header{"X-User": {"${USER_ID}"}, "X-Host": {"${HOST}"}}, actual.Headers)
+	assert.Equal(`{"id": "${USER_ID}"}`, string(actual.Body))
+	assert.Equal([]FormField{{Name: "id", Value: "${USER_ID}"}, {Name: "host", Value: "${HOST}"}}, actual.Form)
+	assert.Equal(map[string]VarScraper{
+		"USER_ID": {Name: "USER_ID", Steps: []TraversalStep{{Key: "id"}}},
+		"NAME":    {Name: "NAME", Steps: []TraversalStep{{Key: "name"}}},
+	}, actual.Captures)
+	assert.Equal(TemplateOverride{
+		Headers: http.Header{"X-Audit": {"${USER_ID}"}},
+		Body:    []byte(`{"user": "${USER_ID}"}`),
+	}, actual.EnvOverrides["PROD"])
+
+	// original is left alone
+	assert.Equal("${ID}", tmpl.Headers.Get("X-User"))
+	assert.Equal("${ID}", tmpl.Form[0].Value)
+	assert.Equal("${ID}", tmpl.EnvOverrides["PROD"].Headers.Get("X-Audit"))
+	assert.Contains(tmpl.Captures, "ID")
+
+	_, changed = tmpl.RenameVar("$", "PASSWORD", "SECRET")
+	assert.False(changed)
+}
+
+func Test_RequestTemplate_Copy(t *testing.T) {
+	assert := assert.New(t)
+
+	def := "none"
+	tmpl := RequestTemplate{
+		Name:        "get-user",
+		URL:         "/users/${ID}",
+		Headers:     http.Header{"X-User": {"${ID}"}},
+		HeaderOrder: []string{"X-User"},
+		Body:        []byte(`{"id": 1}`),
+		Form:        []FormField{{Name: "q", Value: "${ID}"}},
+		Captures: map[string]VarScraper{
+			"ID": {Name: "ID", Steps: []TraversalStep{{Key: "id"}}, Default: &def},
+		},
+		Tags: []string{"users"},
+		EnvOverrides: map[string]TemplateOverride{
+			"PROD": {Headers: http.Header{"X-Debug": {"0"}}, Body: []byte(`{"id": 2}`)},
+		},
+	}
+
+	actual := tmpl.Copy()
+	assert.Equal(tmpl, actual)
+
+	// modifying the copy leaves the original alone
+	actual.Headers.Add("X-User", "2")
+	actual.HeaderOrder[0] = "X-Other"
+	actual.Body[0] = '['
+	actual.Form[0].Value = "changed"
+	actual.Captures["ID"].Steps[0].Key = "user_id"
+	*actual.Captures["ID"].Default = "changed"
+	actual.Captures["NAME"] = VarScraper{Name: "NAME"}
+	actual.Tags[0] = "other"
+	actual.EnvOverrides["PROD"].Headers.Set("X-Debug", "1")
+	actual.EnvOverrides["PROD"].Body[0] = '['
+
+	assert.Equal(http.Header{"X-User": {"${ID}"}}, tmpl.Headers)
+	assert.Equal([]string{"X-User"}, tmpl.HeaderOrder)
+	assert.Equal(`{"id": 1}`, string(tmpl.Body))
+	assert.Equal([]FormField{{Name: "q", Value: "${ID}"}}, tmpl.Form)
+	assert.Equal("id", tmpl.Captures["ID"].Steps[0].Key)
+	assert.Equal("none", *tmpl.Captures["ID"].Default)
+	assert.NotContains(tmpl.Captures, "NAME")
+	assert.Equal([]string{"users"}, tmpl.Tags)
+	assert.Equal(http.Header{"X-Debug": {"0"}}, tmpl.EnvOverrides["PROD"].Headers)
+	assert.Equal(`{"id": 2}`, string(tmpl.EnvOverrides["PROD"].Body))
+}
+
+func Test_RequestTemplate_ForEnv(t *testing.T) {
+	tmpl := RequestTemplate{
+		Name:        "create-user",
+		Method:      "POST",
+		URL:         "/users",
+		Headers:     http.Header{"Content-Type": {"application/json"}, "X-Debug": {"1", "2"}},
+		HeaderOrder: []string{"Content-Type", "X-Debug"},
+		Body:        []byte(`{"name": "vriska"}`),
+		EnvOverrides: map[string]TemplateOverride{
+			"PROD":    {Headers: http.Header{"X-Debug": {"0"}, "X-Api-Key": {"${KEY}"}}},
+			"STAGING": {Body: []byte(`{"name": "test"}`)},
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		tmpl        RequestTemplate
+		env         string
+		expectHdrs  http.Header
+		expectOrder []string
+		expectBody  string
+		expectFile  string
+		expectForm  []FormField
+	}{
+		{
+			name:        "no override for env",
+			tmpl:        tmpl,
+			env:         "DEV",
+			expectHdrs:  http.Header{"Content-Type": {"application/json"}, "X-Debug": {"1", "2"}},
+			expectOrder: []string{"Content-Type", "X-Debug"},
+			expectBody:  `{"name": "vriska"}`,
+		},
+		{
+			name:        "headers replace those with same key and add others",
+			tmpl:        tmpl,
+			env:         "prod",
+			expectHdrs:  http.Header{"Content-Type": {"application/json"}, "X-Debug": {"0"}, "X-Api-Key": {"${KEY}"}},
+			expectOrder: []string{"Content-Type", "X-Debug", "X-Api-Key"},
+			expectBody:  `{"name": "vriska"}`,
+		},
+		{
+			name:        "body replaces body",
+			tmpl:        tmpl,
+			env:         "STAGING",
+			expectHdrs:  http.Header{"Content-Type": {"application/json"}, "X-Debug": {"1", "2"}},
+			expectOrder: []string{"Content-Type", "X-Debug"},
+			expectBody:  `{"name": "test"}`,
+		},
+		{
+			name: "body replaces body file",
+			tmpl: RequestTemplate{
+				BodyFile:     "user.json",
+				EnvOverrides: map[string]TemplateOverride{"PROD": {Body: []byte(`{}`)}},
+			},
+			env:        "PROD",
+			expectBody: `{}`,
+		},
+		{
+			name: "body replaces form fields",
+			tmpl: RequestTemplate{
+				Form:         []FormField{{Name: "q", Value: "1"}},
+				EnvOverrides: map[string]TemplateOverride{"PROD": {Body: []byte(`q=2`)}},
+			},
+			env:        "PROD",
+			expectBody: `q=2`,
+		},
+		{
+			name: "headers keep form fields",
+			tmpl: RequestTemplate{
+				Form:         []FormField{{Name: "q", Value: "1"}},
+				EnvOverrides: map[string]TemplateOverride{"PROD": {Headers: http.Header{"X-Debug": {"0"}}}},
+			},
+			env:         "PROD",
+			expectHdrs:  http.Header{"X-Debug": {"0"}},
+			expectOrder: []string{"X-Debug"},
+			expectForm:  []FormField{{Name: "q", Value: "1"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual := tc.tmpl.ForEnv(tc.env)
+
+			assert.Equal(tc.expectHdrs, actual.Headers)
+			assert.Equal(tc.expectOrder, actual.HeaderOrder)
+			assert.Equal(tc.expectBody, string(actual.Body))
+			assert.Equal(tc.expectFile, actual.BodyFile)
+			assert.Equal(tc.expectForm, actual.Form)
+			assert.Nil(actual.EnvOverrides)
+		})
+	}
+
+	// the original is left alone
+	assert.Len(t, tmpl.EnvOverrides, 2)
+	assert.Equal(t, []string{"1", "2"}, tmpl.Headers.Values("X-Debug"))
+	assert.Equal(t, []string{"Content-Type", "X-Debug"}, tmpl.HeaderOrder)
+}
+
+func Test_RequestTemplate_HTTPFile(t *testing.T) {
+	testCases := []struct {
+		name       string
+		tmpl       RequestTemplate
+		editorVars bool
+		expect     string
+	}{
+		{
+			name:   "no headers or body",
+			tmpl:   RequestTemplate{Name: "ping", Method: "GET", URL: "https://example.com/ping"},
+			expect: "# @name ping\nGET https://example.com/ping\n",
+		},
+		{
+			name: "multi-valued headers in authored order",
+			tmpl: RequestTemplate{
+				Name:        "get-user",
+				Method:      "GET",
+				URL:         "https://example.com/users/1",
+				Headers:     http.Header{"Accept": {"application/json", "text/plain"}, "X-Trace": {"413"}},
+				HeaderOrder: []string{"X-Trace", "Accept"},
+			},
+			expect: "# @name get-user\n" +
+				"GET https://example.com/users/1\n" +
+				"X-Trace: 413\n" +
+				"Accept: application/json\n" +
+				"Accept: text/plain\n",
+		},
+		{
+			name:   "body file",
+			tmpl:   RequestTemplate{Name: "create-user", Method: "POST", URL: "https://example.com/users", BodyFile: "user.json"},
+			expect: "# @name create-user\nPOST https://example.com/users\n\n< user.json\n",
+		},
+		{
+			name: "body gets a trailing newline",
+			tmpl: RequestTemplate{
+				Name:    "create-user",
+				Method:  "POST",
+				URL:     "https://example.com/users",
+				Headers: http.Header{"Content-Type": {"application/json"}},
+				Body:    []byte(`{"name": "VRISKA"}`),
+			},
+			expect: "# @name create-user\n" +
+				"POST https://example.com/users\n" +
+				"Content-Type: application/json\n" +
+				"\n" +
+				`{"name": "VRISKA"}` + "\n",
+		},
+		{
+			name: "form fields are encoded and joined to body",
+			tmpl: RequestTemplate{
+				Name:   "login",
+				Method: "POST",
+				URL:    "https://example.com/login",
+				Body:   []byte("a=1"),
+				Form:   []FormField{{Name: "user", Value: "${USER} (admin)"}, {Value: "$${COST} & tax"}},
+			},
+			expect: "# @name login\n" +
+				"POST https://example.com/login\n" +
+				"\n" +
+				"a=1&user=${USER}%20%28admin%29&$${COST}%20%26%20tax\n",
+		},
+		{
+			name: "vars left intact",
+			tmpl: RequestTemplate{
+				Name:    "get-user",
+				Method:  "GET",
+				URL:     "${HOST}/users/${ID}",
+				Headers: http.Header{"Authorization": {"Bearer ${TOKEN}"}},
+			},
+			expect: "# @name get-user\n" +
+				"GET ${HOST}/users/${ID}\n" +
+				"Authorization: Bearer ${TOKEN}\n",
+		},
+		{
+			name: "vars converted for editors",
+			tmpl: RequestTemplate{
+				Name:    "get-user",
+				Method:  "POST",
+				URL:     "${HOST}/users/${id}",
+				Headers: http.Header{"Authorization": {"Bearer ${TOKEN|trim}"}},
+				Body:    []byte(`{"req": "${@uuid}", "literal": "$${ID}"}` + "\n"),
+			},
+			editorVars: true,
+			expect: "# @name get-user\n" +
+				"POST {{HOST}}/users/{{ID}}\n" +
+				"Authorization: Bearer ${TOKEN|trim}\n" +
+				"\n" +
+				`{"req": "${@uuid}", "literal": "${ID}"}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, string(tc.tmpl.HTTPFile("$", tc.editorVars)))
+		})
+	}
+}
+
+func Test_HistoryFilter_Matches(t *testing.T) {
+	entry := HistoryEntry{
+		Template: "login",
+		ReqTime:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Response: &http.Response{StatusCode: http.StatusInternalServerError},
+	}
+
+	testCases := []struct {
+		name   string
+		filter HistoryFilter
+		expect bool
+	}{
+		{name: "zero filter", filter: HistoryFilter{}, expect: true},
+		{name: "status matches", filter: HistoryFilter{StatusCode: 500}, expect: true},
+		{name: "status differs", filter: HistoryFilter{StatusCode: 200}, expect: false},
+		{name: "template matches case-insensitively", filter: HistoryFilter{Template: "LOGIN"}, expect: true},
+		{name: "template differs", filter: HistoryFilter{Template: "logout"}, expect: false},
+		{name: "since exactly at request time", filter: HistoryFilter{Since: entry.ReqTime}, expect: true},
+		{name: "since after request time", filter: HistoryFilter{Since: entry.ReqTime.Add(time.Second)}, expect: false},
+		{name: "all match", filter: HistoryFilter{StatusCode: 500, Template: "login", Since: entry.ReqTime.Add(-time.Hour)}, expect: true},
+		{name: "one of several differs", filter: HistoryFilter{StatusCode: 500, Template: "logout"}, expect: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.filter.Matches(entry))
+		})
+	}
+}
+
+func Test_ParseHTTPFile(t *testing.T) {
+	testCases := []struct {
+		name         string
+		input        string
+		expect       []RequestTemplate
+		expectErrors []string
+	}{
+		{
+			name: "single named request",
+			input: "# @name create-user\n" +
+				"POST https://example.com/users HTTP/1.1\n" +
+				"Content-Type: application/json\n" +
+				"X-Id: {{ID}}\n" +
+				"\n" +
+				"{\"name\": \"{{name}}\"}\n",
+			expect: []RequestTemplate{{
+				Name:        "create-user",
+				Method:      "POST",
+				URL:         "https://example.com/users",
+				Headers:     http.Header{"Content-Type": {"application/json"}, "X-Id": {"${ID}"}},
+				HeaderOrder: []string{"Content-Type", "X-Id"},
+				Body:        []byte(`{"name": "${NAME}"}`),
+			}},
+		},
+		{
+			name: "body from file",
+			input: "POST https://example.com/users\n" +
+				"\n" +
+				"< ./user.json\n",
+			expect: []RequestTemplate{{
+				Name:     "request-1",
+				Method:   "POST",
+				URL:      "https://example.com/users",
+				BodyFile: "./user.json",
+			}},
+		},
+		{
+			name: "multiple requests with generated names",
+			input: "@host = example.com\n" +
+				"\n" +
+				"###\n" +
+				"// @name=list\n" +
+				"GET https://{{host}}/users\n" +
+				"    ?page=1\n" +
+				"    &size={{ size }}\n" +
+				"\n" +
+				"### delete one\n" +
+				"DELETE https://{{host}}/users/1\n" +
+				"\n" +
+				"###\n" +
+				"https://{{host}}/health\n",
+			expect: []RequestTemplate{
+				{Name: "list", Method: "GET", URL: "https://${HOST}/users?page=1&size=${SIZE}"},
+				{Name: "request-2", Method: "DELETE", URL: "https://${HOST}/users/1"},
+				{Name: "request-3", Method: "GET", URL: "https://${HOST}/health"},
+			},
+		},
+		{
+			name:  "existing var syntax and editor-only vars",
+			input: "GET https://example.com/${ID}/{{$guid}}/{{login.response.body.$.token}}\n",
+			expect: []RequestTemplate{
+				{Name: "request-1", Method: "GET", URL: "https://example.com/$${ID}/{{$guid}}/{{login.response.body.$.token}}"},
+			},
+		},
+		{
+			name: "malformed blocks are skipped",
+			input: "GET https://example.com/1\n" +
+				"###\n" +
+				"GET https://example.com/2 extra HTTP/1.1\n" +
+				"###\n" +
+				"\n" +
+				"GET https://example.com/3\n" +
+				"not a header\n" +
+				"###\n" +
+				"GET https://example.com/4\n",
+			expect: []RequestTemplate{
+				{Name: "request-1", Method: "GET", URL: "https://example.com/1"},
+				{Name: "request-4", Method: "GET", URL: "https://example.com/4"},
+			},
+			expectErrors: []string{
+				`request at line 3: request line is not in the form METHOD URL: "GET https://example.com/2 extra HTTP/1.1"`,
+				`request at line 6: header line is not in the form KEY: VALUE: "not a header"`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, errs := ParseHTTPFile([]byte(tc.input), "$")
+
+			var errStrs []string
+			for _, err := range errs {
+				errStrs = append(errStrs, err.Error())
+			}
+
+			assert.Equal(tc.expect, actual)
+			assert.Equal(tc.expectErrors, errStrs)
+		})
+	}
+}
+
+func Test_ParseHTTPFile_RoundTrip(t *testing.T) {
+	tmpl := RequestTemplate{
+		Name:        "req1",
+		Method:      "PATCH",
+		URL:         "https://example.com/${ID}",
+		Headers:     http.Header{"Authorization": {"Bearer ${TOKEN}"}, "Accept": {"a", "b"}},
+		HeaderOrder: []string{"Authorization", "Accept"},
+		Body:        []byte("line1\n\nline3"),
+	}
+
+	actual, errs := ParseHTTPFile(tmpl.HTTPFile("$", true), "$")
+
+	assert.Empty(t, errs)
+	assert.Equal(t, []RequestTemplate{tmpl}, actual)
+}
+
+func Test_Project_AddHistory(t *testing.T) {
+	testCases := []struct {
+		name     string
+		max      int
+		existing []string
+		expect   []string
+	}{
+		{name: "unlimited", max: 0, existing: []string{"a", "b", "c"}, expect: []string{"a", "b", "c", "new"}},
+		{name: "under cap", max: 5, existing: []string{"a", "b"}, expect: []string{"a", "b", "new"}},
+		{name: "at cap drops oldest", max: 3, existing: []string{"a", "b", "c"}, expect: []string{"b", "c", "new"}},
+		{name: "over cap from lowered setting", max: 2, existing: []string{"a", "b", "c", "d"}, expect: []string{"d", "new"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Project{Config: Settings{MaxHistoryEntries: tc.max}}
+			for _, name := range tc.existing {
+				p.History = append(p.History, HistoryEntry{Template: name})
+			}
+
+			p.AddHistory(HistoryEntry{Template: "new"})
+
+			var actual []string
+			for _, h := range p.History {
+				actual = append(actual, h.Template)
+			}
+			assert.Equal(t, tc.expect, actual)
+		})
+	}
+}
+
+func Test_HistoryEntry_MarshalJSON_Redact(t *testing.T) {
+	assert := assert.New(t)
+
+	req, err := http.NewRequest("POST", "http://example.com/login?key=8b5f2c9e", strings.NewReader(`{"password":"hunter2"}`))
+	if !assert.NoError(err) {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer 8b5f2c9e")
+
+	resp := &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Set-Cookie": {"session=8b5f2c9e"}},
+		Body:          io.NopCloser(strings.NewReader(`{"token":"8b5f2c9e"}`)),
+		ContentLength: 20,
+	}
+
+	entry := HistoryEntry{
+		Template: "login",
+		Request:  req,
+		Response: resp,
+		Captures: map[string]string{"TOKEN": "8b5f2c9e"},
+		Redact:   []string{"8b5f2c9e", "hunter2"},
+	}
+
+	data, err := entry.MarshalJSON()
+	if !assert.NoError(err) {
+		return
+	}
+
+	var loaded HistoryEntry
+	if !assert.NoError(loaded.UnmarshalJSON(data)) {
+		return
+	}
+
+	assert.Equal("http://example.com/login?key=***", loaded.Request.URL.String())
+	assert.Equal("Bearer ***", loaded.Request.Header.Get("Authorization"))
+	reqBody, _ := io.ReadAll(loaded.Request.Body)
+	assert.Equal(`{"password":"***"}`, string(reqBody))
+	assert.Equal(int64(len(reqBody)), loaded.Request.ContentLength)
+
+	assert.Equal("session=***", loaded.Response.Header.Get("Set-Cookie"))
+	respBody, _ := io.ReadAll(loaded.Response.Body)
+	assert.Equal(`{"token":"***"}`, string(respBody))
+	assert.Equal(int64(len(respBody)), loaded.Response.ContentLength)
+
+	assert.Equal(map[string]string{"TOKEN": "***"}, loaded.Captures)
+	assert.True(loaded.Redacted)
+
+	// the entry itself is left unredacted
+	assert.Equal("Bearer 8b5f2c9e", entry.Request.Header.Get("Authorization"))
+	origBody, _ := io.ReadAll(entry.Request.Body)
+	assert.Equal(`{"password":"hunter2"}`, string(origBody))
+	assert.Equal("8b5f2c9e", entry.Captures["TOKEN"])
+
+	// an entry is only marked redacted if its request had something masked
+	entry.Request.Header.Del("Authorization")
+	entry.Request.URL = mustParseURL("http://example.com/login")
+	entry.Request.Body = io.NopCloser(strings.NewReader(`{}`))
+	data, err = entry.MarshalJSON()
+	if !assert.NoError(err) {
+		return
+	}
+	loaded = HistoryEntry{}
+	if !assert.NoError(loaded.UnmarshalJSON(data)) {
+		return
+	}
+	assert.False(loaded.Redacted)
+}